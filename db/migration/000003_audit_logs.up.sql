@@ -0,0 +1,26 @@
+-- Create audit_logs table
+CREATE TABLE "audit_logs" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "actor_id" UUID NOT NULL,
+  "action" VARCHAR(255) NOT NULL,
+  "resource_type" VARCHAR(255) NOT NULL,
+  "resource_id" VARCHAR(255) NOT NULL DEFAULT '',
+  "before" JSONB,
+  "after" JSONB,
+  "source_ip" VARCHAR(255) NOT NULL DEFAULT '',
+  "user_agent" TEXT NOT NULL DEFAULT '',
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX ON "audit_logs" ("actor_id", "created_at");
+CREATE INDEX ON "audit_logs" ("resource_type", "resource_id");
+
+-- Audit entries are append-only
+CREATE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only
+BEFORE UPDATE OR DELETE ON "audit_logs"
+FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
@@ -0,0 +1,35 @@
+-- Create retention_policies table; a NULL model_id applies to all of the user's models
+CREATE TABLE "retention_policies" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" UUID NOT NULL,
+  "model_id" UUID,
+  "payload_ttl_days" INTEGER NOT NULL,
+  "row_ttl_days" INTEGER,
+  "archive" BOOLEAN NOT NULL DEFAULT FALSE,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT retention_policies_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT retention_policies_ttl_check CHECK (payload_ttl_days >= 0 AND (row_ttl_days IS NULL OR row_ttl_days >= payload_ttl_days))
+);
+CREATE UNIQUE INDEX retention_policies_user_default_idx ON "retention_policies" ("user_id") WHERE "model_id" IS NULL;
+CREATE UNIQUE INDEX retention_policies_user_model_idx ON "retention_policies" ("user_id", "model_id") WHERE "model_id" IS NOT NULL;
+
+-- Payloads are dropped after their TTL while the row is kept for accounting
+ALTER TABLE "logs" ADD COLUMN "payload_purged_at" TIMESTAMPTZ;
+CREATE INDEX ON "logs" ("created_at");
+
+-- Create log_daily_usage table holding usage of rolled-up logs
+CREATE TABLE "log_daily_usage" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" UUID NOT NULL,
+  "model_id" UUID NOT NULL,
+  "connection_id" UUID,
+  "type" VARCHAR(255) NOT NULL,
+  "day" DATE NOT NULL,
+  "request_count" BIGINT NOT NULL DEFAULT 0,
+  "prompt_tokens" BIGINT NOT NULL DEFAULT 0,
+  "completion_tokens" BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT log_daily_usage_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX log_daily_usage_bucket_idx ON "log_daily_usage" (
+  "user_id", "model_id", (COALESCE("connection_id", '00000000-0000-0000-0000-000000000000'::uuid)), "type", "day"
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS logs_user_created_at_id_idx;
+DROP INDEX IF EXISTS logs_search_vector_idx;
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "search_vector";
+DROP FUNCTION IF EXISTS log_search_vector(JSONB, JSONB);
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "status_code";
+ALTER TABLE "logs" DROP CONSTRAINT IF EXISTS logs_api_key_id_fkey;
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "api_key_id";
@@ -0,0 +1,29 @@
+-- Record which API key made the call and what the upstream answered
+ALTER TABLE "logs" ADD COLUMN "api_key_id" UUID;
+ALTER TABLE "logs" ADD CONSTRAINT logs_api_key_id_fkey FOREIGN KEY ("api_key_id") REFERENCES "api_keys" ("id") ON DELETE SET NULL;
+ALTER TABLE "logs" ADD COLUMN "status_code" INTEGER;
+
+-- Full-text search over prompt and completion text. Covers OpenAI and Ollama
+-- chat messages, embedding inputs, and OpenAI/Ollama completions. The vector is
+-- derived from the payloads, so it is emptied when a payload is purged.
+-- Support for more payload formats is added by replacing log_search_vector;
+-- logs stored before keep their vector until reindexed with
+-- "gen-ai-proxy logs reindex-search".
+CREATE FUNCTION log_search_vector(request JSONB, response JSONB) RETURNS TSVECTOR
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+  SELECT jsonb_to_tsvector(
+    'simple'::regconfig,
+    jsonb_path_query_array(request, '$.messages[*].content') ||
+    jsonb_path_query_array(request, '$.input') ||
+    jsonb_path_query_array(response, '$.choices[*].message.content') ||
+    jsonb_path_query_array(response, '$.message.content'),
+    '["string"]'
+  )
+$$;
+ALTER TABLE "logs" ADD COLUMN "search_vector" TSVECTOR GENERATED ALWAYS AS (
+  log_search_vector("request_payload", "response_payload")
+) STORED;
+CREATE INDEX logs_search_vector_idx ON "logs" USING GIN ("search_vector");
+
+-- Keyset pagination for ListLogs
+CREATE INDEX logs_user_created_at_id_idx ON "logs" ("user_id", "created_at" DESC, "id" DESC);
@@ -0,0 +1,6 @@
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "monthly_budget";
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "allowed_models";
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "managed";
+ALTER TABLE "models" DROP COLUMN IF EXISTS "managed";
+ALTER TABLE "connections" DROP COLUMN IF EXISTS "managed";
+ALTER TABLE "providers" DROP COLUMN IF EXISTS "managed";
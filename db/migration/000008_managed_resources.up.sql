@@ -0,0 +1,10 @@
+-- Resources declared in the configuration file are reconciled at startup and
+-- are read-only through the API
+ALTER TABLE "providers" ADD COLUMN "managed" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "connections" ADD COLUMN "managed" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "models" ADD COLUMN "managed" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "api_keys" ADD COLUMN "managed" BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- API key policies: NULL allows every model, NULL budget is unlimited
+ALTER TABLE "api_keys" ADD COLUMN "allowed_models" TEXT[];
+ALTER TABLE "api_keys" ADD COLUMN "monthly_budget" NUMERIC(12, 4);
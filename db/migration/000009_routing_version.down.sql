@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS models_routing_version ON "models";
+DROP TRIGGER IF EXISTS connections_routing_version ON "connections";
+DROP TRIGGER IF EXISTS providers_routing_version ON "providers";
+DROP FUNCTION IF EXISTS routing_version_bump();
+DROP TABLE IF EXISTS "routing_version";
@@ -0,0 +1,29 @@
+-- Every change to providers, connections or models bumps the routing version
+-- and notifies listeners, so each replica can refresh its routing table
+CREATE TABLE "routing_version" (
+  "id" INTEGER PRIMARY KEY CHECK ("id" = 1),
+  "version" BIGINT NOT NULL
+);
+INSERT INTO "routing_version" ("id", "version") VALUES (1, 1);
+
+CREATE FUNCTION routing_version_bump() RETURNS TRIGGER AS $$
+DECLARE
+  v BIGINT;
+BEGIN
+  UPDATE "routing_version" SET "version" = "version" + 1 WHERE "id" = 1 RETURNING "version" INTO v;
+  PERFORM pg_notify('routing_changed', v::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER providers_routing_version
+AFTER INSERT OR UPDATE OR DELETE ON "providers"
+FOR EACH STATEMENT EXECUTE FUNCTION routing_version_bump();
+
+CREATE TRIGGER connections_routing_version
+AFTER INSERT OR UPDATE OR DELETE ON "connections"
+FOR EACH STATEMENT EXECUTE FUNCTION routing_version_bump();
+
+CREATE TRIGGER models_routing_version
+AFTER INSERT OR UPDATE OR DELETE ON "models"
+FOR EACH STATEMENT EXECUTE FUNCTION routing_version_bump();
@@ -0,0 +1,3 @@
+-- Per-model request parameter policy (defaults, overrides and allowed
+-- ranges); NULL forwards client parameters unchanged
+ALTER TABLE "models" ADD COLUMN "param_policy" JSONB;
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS prompt_template_versions_routing_version ON "prompt_template_versions";
+DROP TRIGGER IF EXISTS prompt_templates_routing_version ON "prompt_templates";
+DROP INDEX IF EXISTS logs_prompt_template_idx;
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "prompt_template_version";
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "prompt_template_id";
+ALTER TABLE "models" DROP COLUMN IF EXISTS "prompt_template_id";
+DROP TABLE IF EXISTS "prompt_template_versions";
+DROP TABLE IF EXISTS "prompt_templates";
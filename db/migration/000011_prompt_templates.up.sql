@@ -0,0 +1,45 @@
+-- Prompt templates hold a managed system prompt and few-shot messages that are
+-- added to chat requests for the models they are attached to. Every edit is a
+-- new immutable version; active_version selects the one in use, so rolling
+-- back is pointing it at an earlier version.
+CREATE TABLE "prompt_templates" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" UUID NOT NULL,
+  "name" VARCHAR(255) NOT NULL,
+  "active_version" INTEGER NOT NULL DEFAULT 1,
+  "managed" BOOLEAN NOT NULL DEFAULT FALSE,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "deleted_at" TIMESTAMPTZ,
+  CONSTRAINT prompt_templates_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX prompt_templates_user_name_idx ON "prompt_templates" ("user_id", "name") WHERE "deleted_at" IS NULL;
+
+-- messages is a JSON array of {role, content}; variables maps each variable
+-- name to its default value, or null when the client must supply it
+CREATE TABLE "prompt_template_versions" (
+  "template_id" UUID NOT NULL,
+  "version" INTEGER NOT NULL,
+  "mode" VARCHAR(16) NOT NULL DEFAULT 'prepend',
+  "system_prompt" TEXT NOT NULL DEFAULT '',
+  "messages" JSONB NOT NULL DEFAULT '[]',
+  "variables" JSONB NOT NULL DEFAULT '{}',
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY ("template_id", "version"),
+  CONSTRAINT prompt_template_versions_template_id_fkey FOREIGN KEY (template_id) REFERENCES prompt_templates(id) ON DELETE CASCADE
+);
+
+ALTER TABLE "models" ADD COLUMN "prompt_template_id" UUID REFERENCES prompt_templates(id);
+
+-- Which template version a request was sent with, to compare versions
+ALTER TABLE "logs" ADD COLUMN "prompt_template_id" UUID;
+ALTER TABLE "logs" ADD COLUMN "prompt_template_version" INTEGER;
+CREATE INDEX logs_prompt_template_idx ON "logs" ("prompt_template_id", "prompt_template_version");
+
+-- Templates are part of the routing table
+CREATE TRIGGER prompt_templates_routing_version
+AFTER INSERT OR UPDATE OR DELETE ON "prompt_templates"
+FOR EACH STATEMENT EXECUTE FUNCTION routing_version_bump();
+
+CREATE TRIGGER prompt_template_versions_routing_version
+AFTER INSERT OR UPDATE OR DELETE ON "prompt_template_versions"
+FOR EACH STATEMENT EXECUTE FUNCTION routing_version_bump();
@@ -0,0 +1,4 @@
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "schema_errors";
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "schema_attempt";
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "schema_validation";
+ALTER TABLE "models" DROP COLUMN IF EXISTS "structured_output";
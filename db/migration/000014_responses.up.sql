@@ -0,0 +1,31 @@
+-- Conversations of responses the proxy answered by translating a Responses API
+-- request to a chat API, so previous_response_id can continue them. OpenAI
+-- keeps the state of native responses itself. messages is the full chat
+-- history including the answer.
+CREATE TABLE "responses" (
+  "id" VARCHAR(64) PRIMARY KEY,
+  "user_id" UUID NOT NULL,
+  "messages" JSONB NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT responses_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Output tokens spent on reasoning, as reported by the upstream; already
+-- included in completion_tokens
+ALTER TABLE "logs" ADD COLUMN "reasoning_tokens" BIGINT;
+
+-- Extend log search to Responses API instructions, inputs and outputs.
+-- Existing logs are matched on them once reindexed.
+CREATE OR REPLACE FUNCTION log_search_vector(request JSONB, response JSONB) RETURNS TSVECTOR
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+  SELECT jsonb_to_tsvector(
+    'simple'::regconfig,
+    jsonb_path_query_array(request, '$.messages[*].content') ||
+    jsonb_path_query_array(request, '$.input') ||
+    jsonb_path_query_array(request, '$.instructions') ||
+    jsonb_path_query_array(response, '$.choices[*].message.content') ||
+    jsonb_path_query_array(response, '$.message.content') ||
+    jsonb_path_query_array(response, '$.output[*].content[*].text'),
+    '["string"]'
+  )
+$$;
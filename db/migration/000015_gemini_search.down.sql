@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION log_search_vector(request JSONB, response JSONB) RETURNS TSVECTOR
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+  SELECT jsonb_to_tsvector(
+    'simple'::regconfig,
+    jsonb_path_query_array(request, '$.messages[*].content') ||
+    jsonb_path_query_array(request, '$.input') ||
+    jsonb_path_query_array(request, '$.instructions') ||
+    jsonb_path_query_array(response, '$.choices[*].message.content') ||
+    jsonb_path_query_array(response, '$.message.content') ||
+    jsonb_path_query_array(response, '$.output[*].content[*].text'),
+    '["string"]'
+  )
+$$;
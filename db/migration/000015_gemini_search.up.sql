@@ -0,0 +1,20 @@
+-- Extend log search to Gemini requests and responses, which are logged in
+-- Gemini's own format.
+-- Existing logs are matched on them once reindexed.
+CREATE OR REPLACE FUNCTION log_search_vector(request JSONB, response JSONB) RETURNS TSVECTOR
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+  SELECT jsonb_to_tsvector(
+    'simple'::regconfig,
+    jsonb_path_query_array(request, '$.messages[*].content') ||
+    jsonb_path_query_array(request, '$.input') ||
+    jsonb_path_query_array(request, '$.instructions') ||
+    jsonb_path_query_array(request, '$.contents[*].parts[*].text') ||
+    jsonb_path_query_array(request, '$.systemInstruction.parts[*].text') ||
+    jsonb_path_query_array(request, '$.content.parts[*].text') ||
+    jsonb_path_query_array(response, '$.choices[*].message.content') ||
+    jsonb_path_query_array(response, '$.message.content') ||
+    jsonb_path_query_array(response, '$.output[*].content[*].text') ||
+    jsonb_path_query_array(response, '$.candidates[*].content.parts[*].text'),
+    '["string"]'
+  )
+$$;
@@ -0,0 +1,2 @@
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "content_filter";
+ALTER TABLE "providers" DROP COLUMN IF EXISTS "api_version";
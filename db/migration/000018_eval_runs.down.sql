@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS logs_eval_run_id_idx;
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "eval_run_id";
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "latency_ms";
+DROP TABLE IF EXISTS "eval_results";
+DROP TABLE IF EXISTS "eval_runs";
@@ -0,0 +1,61 @@
+-- An evaluation run replays a sample of logged chat requests against a
+-- candidate model, optionally scored by a judge model. status is
+-- in_progress, then completed, or cancelled through cancelling. filter keeps
+-- the criteria the sample was drawn with, and candidate_model_id the model
+-- the candidate resolved to, whose prices give the cost of its results.
+CREATE TABLE "eval_runs" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" UUID NOT NULL,
+  "name" VARCHAR(255) NOT NULL,
+  "candidate_model" VARCHAR(255) NOT NULL,
+  "candidate_model_id" UUID,
+  "judge_model" VARCHAR(255),
+  "judge_prompt" TEXT,
+  "filter" JSONB NOT NULL,
+  "status" VARCHAR(16) NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "cancelling_at" TIMESTAMPTZ,
+  "finished_at" TIMESTAMPTZ,
+  CONSTRAINT eval_runs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX eval_runs_user_created_at_idx ON "eval_runs" ("user_id", "created_at" DESC, "id" DESC);
+
+-- One row per sampled log, holding the logged (baseline) outcome next to the
+-- candidate's. Logs can be purged, so what is compared is copied here.
+-- Workers claim pending rows like batch requests; status is pending,
+-- running, succeeded, failed or cancelled.
+CREATE TABLE "eval_results" (
+  "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "run_id" UUID NOT NULL,
+  "line" INTEGER NOT NULL,
+  "log_id" UUID NOT NULL,
+  "baseline_model_id" UUID,
+  "request" JSONB NOT NULL,
+  "baseline_output" TEXT,
+  "baseline_prompt_tokens" BIGINT,
+  "baseline_completion_tokens" BIGINT,
+  "baseline_latency_ms" BIGINT,
+  "status" VARCHAR(16) NOT NULL DEFAULT 'pending',
+  "attempts" INTEGER NOT NULL DEFAULT 0,
+  "next_attempt_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "claimed_at" TIMESTAMPTZ,
+  "status_code" INTEGER,
+  "request_id" VARCHAR(128),
+  "output" TEXT,
+  "prompt_tokens" BIGINT,
+  "completion_tokens" BIGINT,
+  "latency_ms" BIGINT,
+  "judge_score" INTEGER,
+  "judge_reason" TEXT,
+  "error" TEXT,
+  CONSTRAINT eval_results_run_id_fkey FOREIGN KEY (run_id) REFERENCES eval_runs(id) ON DELETE CASCADE,
+  CONSTRAINT eval_results_run_line_key UNIQUE ("run_id", "line")
+);
+CREATE INDEX eval_results_pending_idx ON "eval_results" ("next_attempt_at") WHERE "status" = 'pending';
+CREATE INDEX eval_results_running_idx ON "eval_results" ("claimed_at") WHERE "status" = 'running';
+
+-- Time from the request to its log, to compare latencies; and the
+-- evaluation run a logged request was made for, so runs do not sample them.
+ALTER TABLE "logs" ADD COLUMN "latency_ms" BIGINT;
+ALTER TABLE "logs" ADD COLUMN "eval_run_id" UUID;
+CREATE INDEX logs_eval_run_id_idx ON "logs" ("eval_run_id") WHERE "eval_run_id" IS NOT NULL;
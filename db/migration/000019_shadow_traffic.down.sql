@@ -0,0 +1,7 @@
+ALTER TABLE "log_daily_usage" DROP COLUMN IF EXISTS "cost";
+DROP INDEX IF EXISTS logs_shadow_of_idx;
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "price_output";
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "price_input";
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "provider_model_id";
+ALTER TABLE "logs" DROP COLUMN IF EXISTS "shadow_of";
+ALTER TABLE "models" DROP COLUMN IF EXISTS "shadow";
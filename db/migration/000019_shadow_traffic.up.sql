@@ -0,0 +1,19 @@
+-- Per-model shadow traffic: the connection and provider model a percentage of
+-- requests is mirrored to, with the prices of mirrored requests, as JSON;
+-- NULL mirrors nothing
+ALTER TABLE "models" ADD COLUMN "shadow" JSONB;
+
+-- Logs of mirrored requests point to the log of the request they mirror.
+-- They are left out of API key budgets but not of cost reports. Their model
+-- is the mirrored one, while the provider model that answered and its prices
+-- come from the shadow policy; other logs leave them NULL and take the
+-- model's.
+ALTER TABLE "logs" ADD COLUMN "shadow_of" UUID;
+ALTER TABLE "logs" ADD COLUMN "provider_model_id" VARCHAR;
+ALTER TABLE "logs" ADD COLUMN "price_input" NUMERIC;
+ALTER TABLE "logs" ADD COLUMN "price_output" NUMERIC;
+CREATE INDEX logs_shadow_of_idx ON "logs" ("shadow_of") WHERE "shadow_of" IS NOT NULL;
+
+-- Cost of rolled-up mirrored requests, which are not priced by their model;
+-- NULL for other buckets
+ALTER TABLE "log_daily_usage" ADD COLUMN "cost" NUMERIC;
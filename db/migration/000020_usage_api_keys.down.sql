@@ -0,0 +1,17 @@
+-- Merge the buckets that only differ by API key or shadow flag.
+CREATE TEMP TABLE merged_usage AS
+SELECT user_id, model_id, connection_id, type, day,
+  SUM(request_count) AS request_count, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens
+FROM "log_daily_usage"
+GROUP BY user_id, model_id, connection_id, type, day;
+DELETE FROM "log_daily_usage";
+DROP INDEX IF EXISTS log_daily_usage_api_key_idx;
+DROP INDEX IF EXISTS log_daily_usage_bucket_idx;
+ALTER TABLE "log_daily_usage" DROP COLUMN IF EXISTS "shadow";
+ALTER TABLE "log_daily_usage" DROP COLUMN IF EXISTS "api_key_id";
+INSERT INTO "log_daily_usage" (user_id, model_id, connection_id, type, day, request_count, prompt_tokens, completion_tokens)
+SELECT user_id, model_id, connection_id, type, day, request_count, prompt_tokens, completion_tokens FROM merged_usage;
+DROP TABLE merged_usage;
+CREATE UNIQUE INDEX log_daily_usage_bucket_idx ON "log_daily_usage" (
+  "user_id", "model_id", (COALESCE("connection_id", '00000000-0000-0000-0000-000000000000'::uuid)), "type", "day"
+);
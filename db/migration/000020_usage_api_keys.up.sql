@@ -0,0 +1,12 @@
+-- Roll up usage per API key as well, so monthly budgets still count the
+-- spend of logs purged by retention, and keep mirrored (shadow) requests in
+-- buckets of their own since they are not charged to keys. Existing buckets
+-- have no API key.
+ALTER TABLE "log_daily_usage" ADD COLUMN "api_key_id" UUID;
+ALTER TABLE "log_daily_usage" ADD COLUMN "shadow" BOOLEAN NOT NULL DEFAULT FALSE;
+DROP INDEX IF EXISTS log_daily_usage_bucket_idx;
+CREATE UNIQUE INDEX log_daily_usage_bucket_idx ON "log_daily_usage" (
+  "user_id", "model_id", (COALESCE("connection_id", '00000000-0000-0000-0000-000000000000'::uuid)),
+  (COALESCE("api_key_id", '00000000-0000-0000-0000-000000000000'::uuid)), "shadow", "type", "day"
+);
+CREATE INDEX log_daily_usage_api_key_idx ON "log_daily_usage" ("api_key_id", "day") WHERE "api_key_id" IS NOT NULL;
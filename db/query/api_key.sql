@@ -1,22 +1,25 @@
 -- name: GetAPIKeyByHash :one
-SELECT id, user_id, key_hash, name, created_at, last_used_at FROM api_keys
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
 WHERE key_hash = $1;
 
 -- name: GetAPIKey :one
-SELECT id, user_id, key_hash, name, created_at, last_used_at FROM api_keys
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
 WHERE key_hash = $1 AND user_id = $2;
 
 -- name: CreateAPIKey :one
 INSERT INTO api_keys (
     user_id,
     key_hash,
-    name
+    name,
+    managed,
+    allowed_models,
+    monthly_budget
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, user_id, key_hash, name, created_at, last_used_at;
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget;
 
 -- name: ListAPIKeys :many
-SELECT id, user_id, name, created_at, last_used_at FROM api_keys
+SELECT id, user_id, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
 WHERE user_id = $1;
 
 -- name: UpdateAPIKey :one
@@ -24,7 +27,7 @@ UPDATE api_keys
 SET
     name = $2
 WHERE name = $1 AND user_id = $3
-RETURNING id, user_id, key_hash, name, created_at, last_used_at;
+RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget;
 
 -- name: UpdateAPIKeyLastUsed :exec
 UPDATE api_keys
@@ -35,3 +38,20 @@ WHERE id = $1;
 -- name: DeleteAPIKey :exec
 DELETE FROM api_keys
 WHERE id = $1 AND user_id = $2;
+
+-- name: GetAPIKeyByID :one
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListManagedAPIKeys :many
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE user_id = $1 AND managed;
+
+-- name: UpdateAPIKeyPolicy :one
+UPDATE api_keys
+SET
+    key_hash = $3,
+    allowed_models = $4,
+    monthly_budget = $5
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget;
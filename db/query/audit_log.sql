@@ -0,0 +1,35 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (
+    actor_id,
+    action,
+    resource_type,
+    resource_id,
+    before,
+    after,
+    source_ip,
+    user_agent
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: ListAuditLogs :many
+SELECT * FROM audit_logs
+WHERE
+    actor_id = sqlc.arg('actor_id') AND
+    (sqlc.narg('action')::TEXT IS NULL OR action = sqlc.narg('action')) AND
+    (sqlc.narg('resource_type')::TEXT IS NULL OR resource_type = sqlc.narg('resource_type')) AND
+    (sqlc.narg('resource_id')::TEXT IS NULL OR resource_id = sqlc.narg('resource_id')) AND
+    (sqlc.narg('since')::TIMESTAMPTZ IS NULL OR created_at >= sqlc.narg('since')) AND
+    (sqlc.narg('until')::TIMESTAMPTZ IS NULL OR created_at < sqlc.narg('until'))
+ORDER BY created_at DESC
+LIMIT sqlc.narg('limit')::BIGINT OFFSET sqlc.narg('offset')::BIGINT;
+
+-- name: CountAuditLogs :one
+SELECT COUNT(*) FROM audit_logs
+WHERE
+    actor_id = sqlc.arg('actor_id') AND
+    (sqlc.narg('action')::TEXT IS NULL OR action = sqlc.narg('action')) AND
+    (sqlc.narg('resource_type')::TEXT IS NULL OR resource_type = sqlc.narg('resource_type')) AND
+    (sqlc.narg('resource_id')::TEXT IS NULL OR resource_id = sqlc.narg('resource_id')) AND
+    (sqlc.narg('since')::TIMESTAMPTZ IS NULL OR created_at >= sqlc.narg('since')) AND
+    (sqlc.narg('until')::TIMESTAMPTZ IS NULL OR created_at < sqlc.narg('until'));
@@ -0,0 +1,125 @@
+-- name: CreateBatch :one
+INSERT INTO batches (
+    id,
+    user_id,
+    api_key_id,
+    endpoint,
+    input_file_id,
+    completion_window,
+    status,
+    metadata,
+    errors,
+    expires_at,
+    finished_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: CreateBatchRequest :exec
+INSERT INTO batch_requests (
+    batch_id,
+    line,
+    custom_id,
+    body
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: GetBatch :one
+SELECT * FROM batches WHERE id = $1 AND user_id = $2;
+
+-- name: GetBatchByID :one
+SELECT * FROM batches WHERE id = $1;
+
+-- name: ListBatches :many
+SELECT * FROM batches
+WHERE
+    user_id = sqlc.arg('user_id') AND
+    (sqlc.narg('cursor_created_at')::TIMESTAMPTZ IS NULL OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::UUID))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit')::BIGINT;
+
+-- name: CountBatchRequests :many
+SELECT status, COUNT(*) AS count
+FROM batch_requests
+WHERE batch_id = $1
+GROUP BY status;
+
+-- Lines already running finish; the batch is finalized once they have.
+
+-- name: CancelBatch :one
+UPDATE batches
+SET status = 'cancelling', cancelling_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status = 'in_progress'
+RETURNING *;
+
+-- name: CancelBatchRequests :execrows
+UPDATE batch_requests
+SET status = 'cancelled'
+WHERE batch_id = $1 AND status = 'pending';
+
+-- Replicas skip lines claimed by others instead of waiting for their lock.
+
+-- name: ClaimBatchRequest :one
+UPDATE batch_requests
+SET status = 'running', attempts = attempts + 1, claimed_at = NOW()
+WHERE id = (
+    SELECT r.id FROM batch_requests r
+    JOIN batches b ON b.id = r.batch_id
+    WHERE r.status = 'pending' AND r.next_attempt_at <= NOW() AND b.status = 'in_progress'
+    ORDER BY r.next_attempt_at, r.line
+    LIMIT 1
+    FOR UPDATE OF r SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishBatchRequest :exec
+UPDATE batch_requests
+SET
+    status = sqlc.arg('status'),
+    next_attempt_at = sqlc.arg('next_attempt_at'),
+    claimed_at = NULL,
+    status_code = sqlc.arg('status_code'),
+    request_id = sqlc.arg('request_id'),
+    response = sqlc.arg('response'),
+    error = sqlc.arg('error')
+WHERE id = sqlc.arg('id') AND status = 'running';
+
+-- name: ReleaseBatchRequest :exec
+UPDATE batch_requests
+SET status = 'pending', attempts = attempts - 1, claimed_at = NULL
+WHERE id = $1 AND status = 'running';
+
+-- name: RequeueStaleBatchRequests :execrows
+UPDATE batch_requests
+SET status = 'pending', claimed_at = NULL
+WHERE status = 'running' AND claimed_at < sqlc.arg('claimed_before');
+
+-- name: ExpireBatchRequests :execrows
+UPDATE batch_requests
+SET status = 'expired'
+WHERE status = 'pending' AND batch_id IN (
+    SELECT id FROM batches WHERE status = 'in_progress' AND expires_at <= NOW()
+);
+
+-- name: ListFinishedBatches :many
+SELECT * FROM batches b
+WHERE b.status IN ('in_progress', 'cancelling') AND NOT EXISTS (
+    SELECT 1 FROM batch_requests r
+    WHERE r.batch_id = b.id AND r.status IN ('pending', 'running')
+);
+
+-- name: ListBatchRequests :many
+SELECT * FROM batch_requests WHERE batch_id = $1 ORDER BY line;
+
+-- The status guard makes finalization happen once when replicas race.
+
+-- name: FinishBatch :one
+UPDATE batches
+SET
+    status = sqlc.arg('status'),
+    output_file_id = sqlc.arg('output_file_id'),
+    error_file_id = sqlc.arg('error_file_id'),
+    finished_at = NOW()
+WHERE id = sqlc.arg('id') AND status = sqlc.arg('current_status')
+RETURNING *;
@@ -0,0 +1,177 @@
+-- name: CreateEvalRun :one
+INSERT INTO eval_runs (
+    id,
+    user_id,
+    name,
+    candidate_model,
+    candidate_model_id,
+    judge_model,
+    judge_prompt,
+    filter,
+    status
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: CreateEvalResult :exec
+INSERT INTO eval_results (
+    run_id,
+    line,
+    log_id,
+    baseline_model_id,
+    request,
+    baseline_output,
+    baseline_prompt_tokens,
+    baseline_completion_tokens,
+    baseline_latency_ms
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+);
+
+-- Successful chat logs with their payloads, in random order. Requests made
+-- by evaluation runs are left out.
+
+-- name: SampleEvalLogs :many
+SELECT id, model_id, request_payload, response_payload, prompt_tokens, completion_tokens, latency_ms
+FROM logs
+WHERE
+    user_id = sqlc.arg('user_id') AND
+    type = 'llm' AND
+    status_code < 400 AND
+    eval_run_id IS NULL AND
+    shadow_of IS NULL AND
+    payload_purged_at IS NULL AND
+    (sqlc.narg('model_id')::UUID IS NULL OR model_id = sqlc.narg('model_id')) AND
+    (sqlc.narg('api_key_id')::UUID IS NULL OR api_key_id = sqlc.narg('api_key_id')) AND
+    (sqlc.narg('since')::TIMESTAMPTZ IS NULL OR created_at >= sqlc.narg('since')) AND
+    (sqlc.narg('until')::TIMESTAMPTZ IS NULL OR created_at < sqlc.narg('until'))
+ORDER BY random()
+LIMIT sqlc.arg('limit')::BIGINT;
+
+-- name: GetEvalRun :one
+SELECT * FROM eval_runs WHERE id = $1 AND user_id = $2;
+
+-- name: GetEvalRunByID :one
+SELECT * FROM eval_runs WHERE id = $1;
+
+-- name: ListEvalRuns :many
+SELECT * FROM eval_runs
+WHERE
+    user_id = sqlc.arg('user_id') AND
+    (sqlc.narg('cursor_created_at')::TIMESTAMPTZ IS NULL OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::UUID))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit')::BIGINT;
+
+-- name: CountEvalResults :many
+SELECT status, COUNT(*) AS count
+FROM eval_results
+WHERE run_id = $1
+GROUP BY status;
+
+-- Costs are priced like conversation logs, with the models' current prices.
+
+-- name: ListEvalResults :many
+SELECT
+    r.id,
+    r.line,
+    r.log_id,
+    r.baseline_model_id,
+    bm.proxy_model_id AS baseline_model,
+    r.request,
+    r.baseline_output,
+    r.baseline_prompt_tokens,
+    r.baseline_completion_tokens,
+    r.baseline_latency_ms,
+    (COALESCE(r.baseline_prompt_tokens, 0) * COALESCE(bm.price_input, 0) + COALESCE(r.baseline_completion_tokens, 0) * COALESCE(bm.price_output, 0))::NUMERIC AS baseline_cost,
+    r.status,
+    r.attempts,
+    r.status_code,
+    r.request_id,
+    r.output,
+    r.prompt_tokens,
+    r.completion_tokens,
+    r.latency_ms,
+    (COALESCE(r.prompt_tokens, 0) * COALESCE(cm.price_input, 0) + COALESCE(r.completion_tokens, 0) * COALESCE(cm.price_output, 0))::NUMERIC AS cost,
+    r.judge_score,
+    r.judge_reason,
+    r.error
+FROM eval_results r
+JOIN eval_runs run ON run.id = r.run_id
+LEFT JOIN models bm ON bm.id = r.baseline_model_id
+LEFT JOIN models cm ON cm.id = run.candidate_model_id
+WHERE
+    r.run_id = sqlc.arg('run_id') AND
+    r.line > sqlc.arg('after_line') AND
+    (sqlc.narg('status')::TEXT IS NULL OR r.status = sqlc.narg('status'))
+ORDER BY r.line
+LIMIT sqlc.arg('limit')::BIGINT;
+
+-- Results already running finish; the run is finalized once they have.
+
+-- name: CancelEvalRun :one
+UPDATE eval_runs
+SET status = 'cancelling', cancelling_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status = 'in_progress'
+RETURNING *;
+
+-- name: CancelEvalResults :execrows
+UPDATE eval_results
+SET status = 'cancelled'
+WHERE run_id = $1 AND status = 'pending';
+
+-- Replicas skip results claimed by others instead of waiting for their lock.
+
+-- name: ClaimEvalResult :one
+UPDATE eval_results
+SET status = 'running', attempts = attempts + 1, claimed_at = NOW()
+WHERE id = (
+    SELECT r.id FROM eval_results r
+    JOIN eval_runs run ON run.id = r.run_id
+    WHERE r.status = 'pending' AND r.next_attempt_at <= NOW() AND run.status = 'in_progress'
+    ORDER BY r.next_attempt_at, r.line
+    LIMIT 1
+    FOR UPDATE OF r SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishEvalResult :exec
+UPDATE eval_results
+SET
+    status = sqlc.arg('status'),
+    next_attempt_at = sqlc.arg('next_attempt_at'),
+    claimed_at = NULL,
+    status_code = sqlc.arg('status_code'),
+    request_id = sqlc.arg('request_id'),
+    output = sqlc.arg('output'),
+    prompt_tokens = sqlc.arg('prompt_tokens'),
+    completion_tokens = sqlc.arg('completion_tokens'),
+    latency_ms = sqlc.arg('latency_ms'),
+    judge_score = sqlc.arg('judge_score'),
+    judge_reason = sqlc.arg('judge_reason'),
+    error = sqlc.arg('error')
+WHERE id = sqlc.arg('id') AND status = 'running';
+
+-- name: ReleaseEvalResult :exec
+UPDATE eval_results
+SET status = 'pending', attempts = attempts - 1, claimed_at = NULL
+WHERE id = $1 AND status = 'running';
+
+-- name: RequeueStaleEvalResults :execrows
+UPDATE eval_results
+SET status = 'pending', claimed_at = NULL
+WHERE status = 'running' AND claimed_at < sqlc.arg('claimed_before');
+
+-- name: ListFinishedEvalRuns :many
+SELECT * FROM eval_runs run
+WHERE run.status IN ('in_progress', 'cancelling') AND NOT EXISTS (
+    SELECT 1 FROM eval_results r
+    WHERE r.run_id = run.id AND r.status IN ('pending', 'running')
+);
+
+-- The status guard makes finalization happen once when replicas race.
+
+-- name: FinishEvalRun :one
+UPDATE eval_runs
+SET status = sqlc.arg('status'), finished_at = NOW()
+WHERE id = sqlc.arg('id') AND status = sqlc.arg('current_status')
+RETURNING *;
@@ -0,0 +1,34 @@
+-- name: CreateFile :one
+INSERT INTO files (
+    id,
+    user_id,
+    filename,
+    purpose,
+    bytes
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: CreateFileContent :exec
+INSERT INTO file_contents (file_id, content) VALUES ($1, $2);
+
+-- name: GetFile :one
+SELECT * FROM files WHERE id = $1 AND user_id = $2;
+
+-- name: GetFileContent :one
+SELECT c.content
+FROM file_contents c
+JOIN files f ON f.id = c.file_id
+WHERE f.id = $1 AND f.user_id = $2;
+
+-- name: ListFiles :many
+SELECT * FROM files
+WHERE
+    user_id = sqlc.arg('user_id') AND
+    (sqlc.narg('purpose')::TEXT IS NULL OR purpose = sqlc.narg('purpose')) AND
+    (sqlc.narg('cursor_created_at')::TIMESTAMPTZ IS NULL OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::UUID))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit')::BIGINT;
+
+-- name: DeleteFile :execrows
+DELETE FROM files WHERE id = $1 AND user_id = $2;
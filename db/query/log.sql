@@ -1,5 +1,6 @@
 -- name: CreateLog :one
 INSERT INTO logs (
+    id,
     user_id,
     model_id,
     request_payload,
@@ -7,29 +8,123 @@ INSERT INTO logs (
     prompt_tokens,
     completion_tokens,
     connection_id,
-    type
+    type,
+    api_key_id,
+    status_code,
+    request_id,
+    prompt_template_id,
+    prompt_template_version,
+    schema_validation,
+    schema_attempt,
+    schema_errors,
+    batch_id,
+    reasoning_tokens,
+    content_filter,
+    latency_ms,
+    eval_run_id,
+    shadow_of,
+    provider_model_id,
+    price_input,
+    price_output
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
-) RETURNING id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type;
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
+) RETURNING id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type, api_key_id, status_code, request_id, prompt_template_id, prompt_template_version, schema_validation, schema_attempt, schema_errors, batch_id, reasoning_tokens, content_filter;
 
--- name: ListLogs :many
-SELECT id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type
+-- name: GetLog :one
+SELECT id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type, api_key_id, status_code, request_id, prompt_template_id, prompt_template_version, schema_validation, schema_attempt, schema_errors, batch_id, reasoning_tokens, content_filter
 FROM logs
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListLogs :many
+SELECT
+    l.id,
+    l.user_id,
+    l.model_id,
+    l.request_payload,
+    l.response_payload,
+    l.created_at,
+    l.prompt_tokens,
+    l.completion_tokens,
+    l.connection_id,
+    l.type,
+    l.api_key_id,
+    l.status_code,
+    l.request_id,
+    l.prompt_template_id,
+    l.prompt_template_version,
+    l.schema_validation,
+    l.schema_attempt,
+    l.schema_errors,
+    l.batch_id,
+    l.reasoning_tokens,
+    l.content_filter,
+    l.shadow_of,
+    l.provider_model_id,
+    conn.provider_id,
+    (COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0))::NUMERIC AS cost
+FROM logs l
+LEFT JOIN models m ON m.id = l.model_id
+LEFT JOIN connections conn ON conn.id = l.connection_id
 WHERE
-    (sqlc.narg('user_id')::UUID IS NULL OR user_id = sqlc.narg('user_id')) AND
-    (sqlc.narg('model_id')::UUID IS NULL OR model_id = sqlc.narg('model_id')) AND
-    (sqlc.narg('connection_id')::UUID IS NULL OR connection_id = sqlc.narg('connection_id')) AND
-    (sqlc.narg('type')::TEXT IS NULL OR type = sqlc.narg('type'))
-ORDER BY created_at DESC
-LIMIT sqlc.narg('limit')::BIGINT OFFSET sqlc.narg('offset')::BIGINT;
+    (sqlc.narg('user_id')::UUID IS NULL OR l.user_id = sqlc.narg('user_id')) AND
+    (sqlc.narg('model_id')::UUID IS NULL OR l.model_id = sqlc.narg('model_id')) AND
+    (sqlc.narg('connection_id')::UUID IS NULL OR l.connection_id = sqlc.narg('connection_id')) AND
+    (sqlc.narg('provider_id')::UUID IS NULL OR conn.provider_id = sqlc.narg('provider_id')::UUID::TEXT) AND
+    (sqlc.narg('api_key_id')::UUID IS NULL OR l.api_key_id = sqlc.narg('api_key_id')) AND
+    (sqlc.narg('type')::TEXT IS NULL OR l.type = sqlc.narg('type')) AND
+    (sqlc.narg('request_id')::TEXT IS NULL OR l.request_id = sqlc.narg('request_id')) AND
+    (sqlc.narg('prompt_template_id')::UUID IS NULL OR l.prompt_template_id = sqlc.narg('prompt_template_id')) AND
+    (sqlc.narg('prompt_template_version')::INTEGER IS NULL OR l.prompt_template_version = sqlc.narg('prompt_template_version')) AND
+    (sqlc.narg('schema_validation')::TEXT IS NULL OR l.schema_validation = sqlc.narg('schema_validation')) AND
+    (sqlc.narg('batch_id')::UUID IS NULL OR l.batch_id = sqlc.narg('batch_id')) AND
+    (sqlc.narg('since')::TIMESTAMPTZ IS NULL OR l.created_at >= sqlc.narg('since')) AND
+    (sqlc.narg('until')::TIMESTAMPTZ IS NULL OR l.created_at < sqlc.narg('until')) AND
+    (sqlc.narg('status')::TEXT IS NULL OR
+        (sqlc.narg('status') = 'success' AND l.status_code < 400) OR
+        (sqlc.narg('status') = 'error' AND l.status_code >= 400)) AND
+    (sqlc.narg('min_tokens')::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) >= sqlc.narg('min_tokens')) AND
+    (sqlc.narg('max_tokens')::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) <= sqlc.narg('max_tokens')) AND
+    (sqlc.narg('min_cost')::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) >= sqlc.narg('min_cost')) AND
+    (sqlc.narg('max_cost')::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) <= sqlc.narg('max_cost')) AND
+    (sqlc.narg('search')::TEXT IS NULL OR l.search_vector @@ websearch_to_tsquery('simple', sqlc.narg('search'))) AND
+    (sqlc.narg('cursor_created_at')::TIMESTAMPTZ IS NULL OR (l.created_at, l.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::UUID))
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT sqlc.arg('limit')::BIGINT;
 
 -- name: CountLogs :one
 SELECT COUNT(*)
-FROM logs
+FROM logs l
+LEFT JOIN models m ON m.id = l.model_id
+LEFT JOIN connections conn ON conn.id = l.connection_id
 WHERE
-    (sqlc.narg('user_id')::UUID IS NULL OR user_id = sqlc.narg('user_id')) AND
-    (sqlc.narg('model_id')::UUID IS NULL OR model_id = sqlc.narg('model_id')) AND
-    (sqlc.narg('connection_id')::UUID IS NULL OR connection_id = sqlc.narg('connection_id'));
+    (sqlc.narg('user_id')::UUID IS NULL OR l.user_id = sqlc.narg('user_id')) AND
+    (sqlc.narg('model_id')::UUID IS NULL OR l.model_id = sqlc.narg('model_id')) AND
+    (sqlc.narg('connection_id')::UUID IS NULL OR l.connection_id = sqlc.narg('connection_id')) AND
+    (sqlc.narg('provider_id')::UUID IS NULL OR conn.provider_id = sqlc.narg('provider_id')::UUID::TEXT) AND
+    (sqlc.narg('api_key_id')::UUID IS NULL OR l.api_key_id = sqlc.narg('api_key_id')) AND
+    (sqlc.narg('type')::TEXT IS NULL OR l.type = sqlc.narg('type')) AND
+    (sqlc.narg('request_id')::TEXT IS NULL OR l.request_id = sqlc.narg('request_id')) AND
+    (sqlc.narg('prompt_template_id')::UUID IS NULL OR l.prompt_template_id = sqlc.narg('prompt_template_id')) AND
+    (sqlc.narg('prompt_template_version')::INTEGER IS NULL OR l.prompt_template_version = sqlc.narg('prompt_template_version')) AND
+    (sqlc.narg('schema_validation')::TEXT IS NULL OR l.schema_validation = sqlc.narg('schema_validation')) AND
+    (sqlc.narg('batch_id')::UUID IS NULL OR l.batch_id = sqlc.narg('batch_id')) AND
+    (sqlc.narg('since')::TIMESTAMPTZ IS NULL OR l.created_at >= sqlc.narg('since')) AND
+    (sqlc.narg('until')::TIMESTAMPTZ IS NULL OR l.created_at < sqlc.narg('until')) AND
+    (sqlc.narg('status')::TEXT IS NULL OR
+        (sqlc.narg('status') = 'success' AND l.status_code < 400) OR
+        (sqlc.narg('status') = 'error' AND l.status_code >= 400)) AND
+    (sqlc.narg('min_tokens')::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) >= sqlc.narg('min_tokens')) AND
+    (sqlc.narg('max_tokens')::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) <= sqlc.narg('max_tokens')) AND
+    (sqlc.narg('min_cost')::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) >= sqlc.narg('min_cost')) AND
+    (sqlc.narg('max_cost')::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) <= sqlc.narg('max_cost')) AND
+    (sqlc.narg('search')::TEXT IS NULL OR l.search_vector @@ websearch_to_tsquery('simple', sqlc.narg('search')));
+
+-- name: ReindexLogSearch :many
+-- Rewrites the batch of logs following after in ID order, so their search
+-- vector is computed again by the current log_search_vector.
+UPDATE logs l SET request_payload = l.request_payload
+WHERE l.id IN (SELECT b.id FROM logs b WHERE b.id > sqlc.arg('after')::UUID ORDER BY b.id LIMIT sqlc.arg('batch_size')::INTEGER)
+RETURNING l.id;
 
 -- name: GetTotalTokensByProviderModelConnection :many
 SELECT
@@ -41,13 +136,17 @@ SELECT
     conn.name AS connection_name,
     SUM(cl.prompt_tokens + cl.completion_tokens) AS total_tokens
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -61,6 +160,7 @@ ORDER BY
     cl.connection_id;
 
 -- name: GetTotalPriceByProviderModelConnection :many
+-- Mirrored requests are priced by their shadow policy, not their model.
 SELECT
     p.id AS provider_id,
     p.name AS provider_name,
@@ -69,17 +169,22 @@ SELECT
     cl.connection_id,
     conn.name AS connection_name,
     SUM(
-        (cl.prompt_tokens * m.price_input) +
-        (cl.completion_tokens * m.price_output)
+        COALESCE(cl.cost,
+            (cl.prompt_tokens * m.price_input) +
+            (cl.completion_tokens * m.price_output))
     )::NUMERIC AS total_price
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens, prompt_tokens * price_input + completion_tokens * price_output AS cost FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens, cost FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -102,13 +207,17 @@ SELECT
     conn.name AS connection_name,
     SUM(cl.prompt_tokens) AS total_input_tokens
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -131,13 +240,17 @@ SELECT
     conn.name AS connection_name,
     SUM(cl.completion_tokens) AS total_output_tokens
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -149,3 +262,44 @@ ORDER BY
     p.id,
     m.id,
     cl.connection_id;
+
+-- name: GetAPIKeySpend :one
+-- Spend of logs purged by retention is read from their daily rollup. since
+-- is expected at the start of a UTC day. Mirrored (shadow) requests are not
+-- charged to the key.
+SELECT COALESCE(SUM(u.prompt_tokens * COALESCE(m.price_input, 0) + u.completion_tokens * COALESCE(m.price_output, 0)), 0)::NUMERIC AS spend
+FROM
+    (
+        SELECT l.model_id, COALESCE(l.prompt_tokens, 0) AS prompt_tokens, COALESCE(l.completion_tokens, 0) AS completion_tokens
+        FROM logs l
+        WHERE l.api_key_id = sqlc.arg('api_key_id') AND l.created_at >= sqlc.arg('since') AND l.shadow_of IS NULL
+        UNION ALL
+        SELECT d.model_id, d.prompt_tokens, d.completion_tokens
+        FROM log_daily_usage d
+        WHERE d.api_key_id = sqlc.arg('api_key_id') AND d.day >= sqlc.arg('since')::DATE AND NOT d.shadow
+    ) u
+LEFT JOIN models m ON m.id = u.model_id;
+
+-- name: GetUsageReport :many
+-- Mirrored requests are priced by their shadow policy, not their model.
+SELECT
+    m.proxy_model_id AS model_name,
+    u.type,
+    SUM(u.request_count)::BIGINT AS request_count,
+    SUM(u.prompt_tokens)::BIGINT AS prompt_tokens,
+    SUM(u.completion_tokens)::BIGINT AS completion_tokens,
+    SUM(COALESCE(u.cost, u.prompt_tokens * m.price_input + u.completion_tokens * m.price_output))::NUMERIC AS cost
+FROM
+    (
+        SELECT l.model_id, l.type, 1::BIGINT AS request_count, COALESCE(l.prompt_tokens, 0) AS prompt_tokens, COALESCE(l.completion_tokens, 0) AS completion_tokens,
+            COALESCE(l.prompt_tokens, 0) * l.price_input + COALESCE(l.completion_tokens, 0) * l.price_output AS cost
+        FROM logs l
+        WHERE l.user_id = sqlc.arg('user_id') AND l.created_at >= sqlc.arg('since') AND l.created_at < sqlc.arg('until')
+        UNION ALL
+        SELECT d.model_id, d.type, d.request_count, d.prompt_tokens, d.completion_tokens, d.cost
+        FROM log_daily_usage d
+        WHERE d.user_id = sqlc.arg('user_id') AND d.day >= sqlc.arg('since')::DATE AND d.day < sqlc.arg('until')::DATE
+    ) u
+JOIN models m ON m.id = u.model_id
+GROUP BY m.proxy_model_id, u.type
+ORDER BY m.proxy_model_id, u.type;
@@ -12,9 +12,15 @@ INSERT INTO models (
     tools_usage,
     price_input,
     price_output,
-    type
+    type,
+    log_policy,
+    managed,
+    param_policy,
+    prompt_template_id,
+    structured_output,
+    shadow
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 ) RETURNING *;
 
 -- name: GetModel :one
@@ -23,6 +29,9 @@ SELECT * FROM models WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 -- name: ListModels :many
 SELECT * FROM models WHERE user_id = $1 AND deleted_at IS NULL;
 
+-- name: ListManagedModels :many
+SELECT * FROM models WHERE user_id = $1 AND managed AND deleted_at IS NULL;
+
 -- name: UpdateModel :one
 UPDATE models
 SET
@@ -32,7 +41,13 @@ SET
     tools_usage = $6,
     price_input = $7,
     price_output = $8,
-    type = $9
+    type = $9,
+    log_policy = $10,
+    connection_id = $11,
+    param_policy = $12,
+    prompt_template_id = $13,
+    structured_output = $14,
+    shadow = $15
 WHERE id = $1 AND user_id = $2
 RETURNING *;
 
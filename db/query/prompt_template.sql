@@ -0,0 +1,62 @@
+-- name: CreatePromptTemplate :one
+INSERT INTO prompt_templates (
+    id,
+    user_id,
+    name,
+    managed
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetPromptTemplate :one
+SELECT * FROM prompt_templates WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: GetPromptTemplateByName :one
+SELECT * FROM prompt_templates WHERE name = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ListPromptTemplates :many
+SELECT * FROM prompt_templates WHERE user_id = $1 AND deleted_at IS NULL ORDER BY name;
+
+-- name: ListManagedPromptTemplates :many
+SELECT * FROM prompt_templates WHERE user_id = $1 AND managed AND deleted_at IS NULL;
+
+-- name: SetPromptTemplateActiveVersion :one
+UPDATE prompt_templates
+SET active_version = $3
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: SoftDeletePromptTemplate :exec
+UPDATE prompt_templates
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2;
+
+-- name: CountModelsUsingPromptTemplate :one
+SELECT COUNT(*) FROM models WHERE prompt_template_id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- Versions are numbered per template; two concurrent writers get a unique
+-- violation rather than the same number.
+
+-- name: CreatePromptTemplateVersion :one
+INSERT INTO prompt_template_versions (
+    template_id,
+    version,
+    mode,
+    system_prompt,
+    messages,
+    variables
+) VALUES (
+    $1, (SELECT COALESCE(MAX(version), 0) + 1 FROM prompt_template_versions WHERE template_id = $1), $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetPromptTemplateVersion :one
+SELECT * FROM prompt_template_versions WHERE template_id = $1 AND version = $2;
+
+-- name: ListPromptTemplateVersions :many
+SELECT * FROM prompt_template_versions WHERE template_id = $1 ORDER BY version DESC;
+
+-- name: ListActivePromptTemplates :many
+SELECT t.name, sqlc.embed(v)
+FROM prompt_templates t
+JOIN prompt_template_versions v ON v.template_id = t.id AND v.version = t.active_version
+WHERE t.deleted_at IS NULL;
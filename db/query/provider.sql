@@ -4,18 +4,35 @@ INSERT INTO providers (
     user_id,
     name,
     base_url,
-    type
+    type,
+    managed,
+    api_version,
+    region
 ) VALUES (
-    $1, $2, $3, $4, $5
-) RETURNING id, user_id, name, base_url, type;
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, user_id, name, base_url, type, managed, api_version, region;
 
 -- name: GetProvider :one
-SELECT id, user_id, name, base_url, type, deleted_at FROM providers WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
 -- name: ListProviders :many
-SELECT id, user_id, name, base_url, type, deleted_at FROM providers WHERE user_id = $1 AND deleted_at IS NULL;
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE user_id = $1 AND deleted_at IS NULL;
+
+-- name: ListManagedProviders :many
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE user_id = $1 AND managed AND deleted_at IS NULL;
 
 -- name: SoftDeleteProvider :exec
 UPDATE providers
 SET deleted_at = NOW()
-WHERE id = $1 AND user_id = $2;
\ No newline at end of file
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateProvider :one
+UPDATE providers
+SET
+    name = $3,
+    base_url = $4,
+    type = $5,
+    api_version = $6,
+    region = $7
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, base_url, type, deleted_at, managed, api_version, region;
@@ -0,0 +1,7 @@
+-- name: CreateResponse :exec
+INSERT INTO responses (id, user_id, messages) VALUES ($1, $2, $3);
+
+-- name: GetResponse :one
+SELECT id, user_id, messages, created_at
+FROM responses
+WHERE id = $1 AND user_id = $2;
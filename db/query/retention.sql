@@ -0,0 +1,108 @@
+-- name: CreateRetentionPolicy :one
+INSERT INTO retention_policies (
+    user_id,
+    model_id,
+    payload_ttl_days,
+    row_ttl_days,
+    archive
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetRetentionPolicy :one
+SELECT * FROM retention_policies
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListRetentionPolicies :many
+SELECT * FROM retention_policies
+WHERE user_id = $1
+ORDER BY model_id NULLS FIRST, created_at;
+
+-- name: DeleteRetentionPolicy :exec
+DELETE FROM retention_policies
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListLogsWithExpiredPayloads :many
+SELECT l.id, l.user_id, l.model_id, l.connection_id, l.request_payload, l.response_payload, l.prompt_tokens, l.completion_tokens, l.created_at, l.type, rp.archive
+FROM logs l
+JOIN LATERAL (
+    SELECT p.archive, p.payload_ttl_days FROM retention_policies p
+    WHERE p.user_id = l.user_id AND (p.model_id = l.model_id OR p.model_id IS NULL)
+    ORDER BY p.model_id NULLS LAST
+    LIMIT 1
+) rp ON TRUE
+WHERE
+    l.payload_purged_at IS NULL AND
+    l.created_at < NOW() - make_interval(days => rp.payload_ttl_days)
+ORDER BY l.created_at
+LIMIT $1
+FOR UPDATE OF l SKIP LOCKED;
+
+-- name: ListLogsPastRowTTL :many
+-- A row whose payload was purged was archived at the payload stage, if at
+-- all, so it is only rolled up.
+SELECT l.id, l.user_id, l.model_id, l.connection_id, l.request_payload, l.response_payload, l.prompt_tokens, l.completion_tokens, l.created_at, l.type,
+    (rp.archive AND l.payload_purged_at IS NULL)::boolean AS archive
+FROM logs l
+JOIN LATERAL (
+    SELECT p.archive, p.row_ttl_days FROM retention_policies p
+    WHERE p.user_id = l.user_id AND (p.model_id = l.model_id OR p.model_id IS NULL)
+    ORDER BY p.model_id NULLS LAST
+    LIMIT 1
+) rp ON TRUE
+WHERE
+    rp.row_ttl_days IS NOT NULL AND
+    l.created_at < NOW() - make_interval(days => rp.row_ttl_days)
+ORDER BY l.created_at
+LIMIT $1
+FOR UPDATE OF l SKIP LOCKED;
+
+-- name: PurgeLogPayloads :execrows
+UPDATE logs
+SET
+    request_payload = '{}'::jsonb,
+    response_payload = '{}'::jsonb,
+    payload_purged_at = NOW()
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: RollupLogs :exec
+-- Only the logs of mirrored requests carry prices, so only shadow buckets
+-- get a cost; other buckets are priced by their model when read.
+INSERT INTO log_daily_usage (
+    user_id,
+    model_id,
+    connection_id,
+    api_key_id,
+    shadow,
+    type,
+    day,
+    request_count,
+    prompt_tokens,
+    completion_tokens,
+    cost
+)
+SELECT
+    user_id,
+    model_id,
+    connection_id,
+    api_key_id,
+    shadow_of IS NOT NULL,
+    type,
+    (created_at AT TIME ZONE 'UTC')::date,
+    COUNT(*),
+    COALESCE(SUM(prompt_tokens), 0),
+    COALESCE(SUM(completion_tokens), 0),
+    SUM(COALESCE(prompt_tokens, 0) * price_input + COALESCE(completion_tokens, 0) * price_output)
+FROM logs
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+GROUP BY user_id, model_id, connection_id, api_key_id, shadow_of IS NOT NULL, type, (created_at AT TIME ZONE 'UTC')::date
+ON CONFLICT (user_id, model_id, (COALESCE(connection_id, '00000000-0000-0000-0000-000000000000'::uuid)), (COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'::uuid)), shadow, type, day)
+DO UPDATE SET
+    request_count = log_daily_usage.request_count + EXCLUDED.request_count,
+    prompt_tokens = log_daily_usage.prompt_tokens + EXCLUDED.prompt_tokens,
+    completion_tokens = log_daily_usage.completion_tokens + EXCLUDED.completion_tokens,
+    cost = log_daily_usage.cost + EXCLUDED.cost;
+
+-- name: DeleteLogs :execrows
+DELETE FROM logs
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
@@ -0,0 +1,33 @@
+-- name: ListShadowComparisons :many
+-- Mirrored requests next to the requests they mirror, per model and shadow
+-- target. Only the first upstream attempt of either side is linked, so
+-- structured output retries are not compared.
+SELECT
+    p.model_id AS primary_model_id,
+    pm.proxy_model_id AS primary_model,
+    s.connection_id AS shadow_connection_id,
+    sc.name AS shadow_connection,
+    COALESCE(s.provider_model_id, '') AS shadow_provider_model,
+    COUNT(*) AS requests,
+    COUNT(*) FILTER (WHERE p.status_code >= 400) AS primary_errors,
+    COUNT(*) FILTER (WHERE s.status_code >= 400) AS shadow_errors,
+    COALESCE(SUM(p.prompt_tokens), 0)::BIGINT AS primary_prompt_tokens,
+    COALESCE(SUM(p.completion_tokens), 0)::BIGINT AS primary_completion_tokens,
+    COALESCE(SUM(s.prompt_tokens), 0)::BIGINT AS shadow_prompt_tokens,
+    COALESCE(SUM(s.completion_tokens), 0)::BIGINT AS shadow_completion_tokens,
+    COALESCE(AVG(p.latency_ms), 0)::FLOAT8 AS primary_avg_latency_ms,
+    COALESCE(AVG(s.latency_ms), 0)::FLOAT8 AS shadow_avg_latency_ms,
+    COALESCE(SUM(COALESCE(p.prompt_tokens, 0) * pm.price_input + COALESCE(p.completion_tokens, 0) * pm.price_output), 0)::NUMERIC AS primary_cost,
+    COALESCE(SUM(COALESCE(s.prompt_tokens, 0) * s.price_input + COALESCE(s.completion_tokens, 0) * s.price_output), 0)::NUMERIC AS shadow_cost
+FROM logs s
+JOIN logs p ON p.id = s.shadow_of
+JOIN models pm ON pm.id = p.model_id
+JOIN connections sc ON sc.id = s.connection_id
+WHERE
+    s.user_id = sqlc.arg('user_id') AND
+    COALESCE(s.schema_attempt, 1) = 1 AND
+    (sqlc.narg('model_id')::UUID IS NULL OR p.model_id = sqlc.narg('model_id')) AND
+    (sqlc.narg('since')::TIMESTAMPTZ IS NULL OR s.created_at >= sqlc.narg('since')) AND
+    (sqlc.narg('until')::TIMESTAMPTZ IS NULL OR s.created_at < sqlc.narg('until'))
+GROUP BY p.model_id, pm.proxy_model_id, s.connection_id, sc.name, s.provider_model_id
+ORDER BY pm.proxy_model_id, sc.name, s.provider_model_id;
@@ -16,4 +16,9 @@ WHERE
 
 -- name: GetUserByUsername :one
 SELECT * FROM users
-WHERE username = $1;
\ No newline at end of file
+WHERE username = $1;
+
+-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2
+WHERE username = $1;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS logs;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS models;
+DROP TABLE IF EXISTS connections;
+DROP TABLE IF EXISTS providers;
+DROP TABLE IF EXISTS users;
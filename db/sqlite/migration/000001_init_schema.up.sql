@@ -0,0 +1,82 @@
+-- SQLite has no UUID type or generator: ids are stored as text and default
+-- to a random version 4 UUID. Timestamps are stored as UTC text in a fixed
+-- layout so they sort chronologically.
+
+-- Create users table
+CREATE TABLE users (
+  id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  username VARCHAR NOT NULL UNIQUE,
+  password_hash VARCHAR NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- Create providers table
+CREATE TABLE providers (
+  id UUID NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  user_id UUID NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  base_url VARCHAR(255) NOT NULL,
+  type VARCHAR(255) NOT NULL,
+  deleted_at TIMESTAMP,
+  PRIMARY KEY (id, user_id),
+  CONSTRAINT fk_providers_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX providers_user_id_idx ON providers (user_id);
+
+-- Create connections table
+CREATE TABLE connections (
+  id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  user_id UUID NOT NULL,
+  provider_id VARCHAR NOT NULL,
+  encrypted_api_key TEXT NOT NULL,
+  name VARCHAR NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  deleted_at TIMESTAMP,
+  CONSTRAINT connections_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX connections_user_id_idx ON connections (user_id);
+
+-- Create models table. Prices are decimal text so they round-trip exactly.
+CREATE TABLE models (
+  id UUID NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  user_id UUID NOT NULL,
+  connection_id UUID,
+  proxy_model_id VARCHAR(255) NOT NULL,
+  provider_model_id VARCHAR(255) NOT NULL DEFAULT '',
+  thinking BOOLEAN NOT NULL DEFAULT FALSE,
+  tools_usage BOOLEAN NOT NULL DEFAULT FALSE,
+  price_input TEXT NOT NULL DEFAULT '0',
+  price_output TEXT NOT NULL DEFAULT '0',
+  deleted_at TIMESTAMP,
+  PRIMARY KEY (id, user_id),
+  CONSTRAINT fk_models_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT models_connection_id_fkey FOREIGN KEY (connection_id) REFERENCES connections(id) ON DELETE CASCADE
+);
+CREATE INDEX models_user_id_idx ON models (user_id);
+
+-- Create api_keys table
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  user_id UUID NOT NULL,
+  key_hash VARCHAR NOT NULL UNIQUE,
+  name VARCHAR NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  last_used_at TIMESTAMP,
+  CONSTRAINT api_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+
+-- Create logs table. Payloads are JSON documents.
+CREATE TABLE logs (
+    id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    user_id UUID NOT NULL,
+    model_id UUID NOT NULL,
+    connection_id UUID,
+    request_payload BLOB NOT NULL,
+    response_payload BLOB NOT NULL,
+    prompt_tokens BIGINT,
+    completion_tokens BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT logs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_connection FOREIGN KEY (connection_id) REFERENCES connections(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE logs DROP COLUMN type;
+
+ALTER TABLE models DROP COLUMN type;
@@ -0,0 +1,3 @@
+ALTER TABLE logs ADD COLUMN type VARCHAR(255) NOT NULL DEFAULT 'llm';
+
+ALTER TABLE models ADD COLUMN type VARCHAR(255) NOT NULL DEFAULT 'llm';
@@ -0,0 +1,26 @@
+-- Create audit_logs table
+CREATE TABLE audit_logs (
+  id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  actor_id UUID NOT NULL,
+  action VARCHAR(255) NOT NULL,
+  resource_type VARCHAR(255) NOT NULL,
+  resource_id VARCHAR(255) NOT NULL DEFAULT '',
+  before BLOB,
+  after BLOB,
+  source_ip VARCHAR(255) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+CREATE INDEX audit_logs_actor_id_created_at_idx ON audit_logs (actor_id, created_at);
+CREATE INDEX audit_logs_resource_type_resource_id_idx ON audit_logs (resource_type, resource_id);
+
+-- Audit entries are append-only
+CREATE TRIGGER audit_logs_no_update BEFORE UPDATE ON audit_logs
+BEGIN
+  SELECT RAISE(ABORT, 'audit_logs is append-only');
+END;
+
+CREATE TRIGGER audit_logs_no_delete BEFORE DELETE ON audit_logs
+BEGIN
+  SELECT RAISE(ABORT, 'audit_logs is append-only');
+END;
@@ -0,0 +1,2 @@
+-- Per-model payload logging policy; empty means the server default applies
+ALTER TABLE models ADD COLUMN log_policy VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS log_daily_usage;
+DROP INDEX IF EXISTS logs_created_at_idx;
+ALTER TABLE logs DROP COLUMN payload_purged_at;
+DROP TABLE IF EXISTS retention_policies;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS logs_user_created_at_id_idx;
+ALTER TABLE logs DROP COLUMN status_code;
+ALTER TABLE logs DROP COLUMN api_key_id;
@@ -0,0 +1,9 @@
+-- Record which API key made the call and what the upstream answered
+ALTER TABLE logs ADD COLUMN api_key_id UUID REFERENCES api_keys (id) ON DELETE SET NULL;
+ALTER TABLE logs ADD COLUMN status_code INTEGER;
+
+-- SQLite has no full-text index over JSON paths, so log search is a
+-- case-insensitive substring match over the payloads instead.
+
+-- Keyset pagination for ListLogs
+CREATE INDEX logs_user_created_at_id_idx ON logs (user_id, created_at DESC, id DESC);
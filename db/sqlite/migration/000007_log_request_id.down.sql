@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS logs_request_id_idx;
+ALTER TABLE logs DROP COLUMN request_id;
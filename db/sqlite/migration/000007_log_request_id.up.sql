@@ -0,0 +1,3 @@
+-- Correlates a conversation log with access logs and upstream provider logs
+ALTER TABLE logs ADD COLUMN request_id VARCHAR;
+CREATE INDEX logs_request_id_idx ON logs (request_id);
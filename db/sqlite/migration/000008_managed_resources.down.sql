@@ -0,0 +1,6 @@
+ALTER TABLE api_keys DROP COLUMN monthly_budget;
+ALTER TABLE api_keys DROP COLUMN allowed_models;
+ALTER TABLE api_keys DROP COLUMN managed;
+ALTER TABLE models DROP COLUMN managed;
+ALTER TABLE connections DROP COLUMN managed;
+ALTER TABLE providers DROP COLUMN managed;
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS models_routing_version_delete;
+DROP TRIGGER IF EXISTS models_routing_version_update;
+DROP TRIGGER IF EXISTS models_routing_version_insert;
+DROP TRIGGER IF EXISTS connections_routing_version_delete;
+DROP TRIGGER IF EXISTS connections_routing_version_update;
+DROP TRIGGER IF EXISTS connections_routing_version_insert;
+DROP TRIGGER IF EXISTS providers_routing_version_delete;
+DROP TRIGGER IF EXISTS providers_routing_version_update;
+DROP TRIGGER IF EXISTS providers_routing_version_insert;
+DROP TABLE IF EXISTS routing_version;
@@ -0,0 +1,52 @@
+-- Every change to providers, connections or models bumps the routing version,
+-- so each replica can refresh its routing table
+CREATE TABLE routing_version (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  version BIGINT NOT NULL
+);
+INSERT INTO routing_version (id, version) VALUES (1, 1);
+
+CREATE TRIGGER providers_routing_version_insert AFTER INSERT ON providers
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER providers_routing_version_update AFTER UPDATE ON providers
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER providers_routing_version_delete AFTER DELETE ON providers
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER connections_routing_version_insert AFTER INSERT ON connections
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER connections_routing_version_update AFTER UPDATE ON connections
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER connections_routing_version_delete AFTER DELETE ON connections
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER models_routing_version_insert AFTER INSERT ON models
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER models_routing_version_update AFTER UPDATE ON models
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
+
+CREATE TRIGGER models_routing_version_delete AFTER DELETE ON models
+BEGIN
+  UPDATE routing_version SET version = version + 1 WHERE id = 1;
+END;
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS prompt_template_versions_routing_version_delete;
+DROP TRIGGER IF EXISTS prompt_template_versions_routing_version_update;
+DROP TRIGGER IF EXISTS prompt_template_versions_routing_version_insert;
+DROP TRIGGER IF EXISTS prompt_templates_routing_version_delete;
+DROP TRIGGER IF EXISTS prompt_templates_routing_version_update;
+DROP TRIGGER IF EXISTS prompt_templates_routing_version_insert;
+DROP INDEX IF EXISTS logs_prompt_template_idx;
+ALTER TABLE logs DROP COLUMN prompt_template_version;
+ALTER TABLE logs DROP COLUMN prompt_template_id;
+ALTER TABLE models DROP COLUMN prompt_template_id;
+DROP TABLE IF EXISTS prompt_template_versions;
+DROP TABLE IF EXISTS prompt_templates;
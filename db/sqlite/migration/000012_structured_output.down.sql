@@ -0,0 +1,4 @@
+ALTER TABLE logs DROP COLUMN schema_errors;
+ALTER TABLE logs DROP COLUMN schema_attempt;
+ALTER TABLE logs DROP COLUMN schema_validation;
+ALTER TABLE models DROP COLUMN structured_output;
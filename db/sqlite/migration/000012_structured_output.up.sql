@@ -0,0 +1,11 @@
+-- Per-model JSON Schema that chat completion outputs must match, with the
+-- number of retries, as JSON; NULL leaves outputs unchecked unless the request
+-- sends a schema
+ALTER TABLE models ADD COLUMN structured_output BLOB;
+
+-- Outcome of the schema check of each upstream attempt: 'valid' or 'invalid'
+-- (NULL when no schema applied), the attempt number starting at 1 and the
+-- validation errors of invalid outputs
+ALTER TABLE logs ADD COLUMN schema_validation VARCHAR;
+ALTER TABLE logs ADD COLUMN schema_attempt INTEGER;
+ALTER TABLE logs ADD COLUMN schema_errors VARCHAR;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS logs_batch_id_idx;
+ALTER TABLE logs DROP COLUMN batch_id;
+DROP TABLE IF EXISTS batch_requests;
+DROP TABLE IF EXISTS batches;
+DROP TABLE IF EXISTS file_contents;
+DROP TABLE IF EXISTS files;
@@ -0,0 +1,70 @@
+-- Files uploaded for the batch API and the result files batches produce. The
+-- content lives in its own table so listing files does not read it.
+CREATE TABLE files (
+  id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  user_id UUID NOT NULL,
+  filename VARCHAR(255) NOT NULL,
+  purpose VARCHAR(32) NOT NULL,
+  bytes BIGINT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  CONSTRAINT files_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX files_user_created_at_idx ON files (user_id, created_at DESC, id DESC);
+
+CREATE TABLE file_contents (
+  file_id UUID PRIMARY KEY NOT NULL,
+  content BLOB NOT NULL,
+  CONSTRAINT file_contents_file_id_fkey FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE
+);
+
+-- A batch runs the lines of an input file with the API key that created it.
+-- status follows the OpenAI batch lifecycle: in_progress, then completed,
+-- expired or cancelled (through cancelling), or failed when the input is
+-- invalid. finished_at is set with the final status.
+CREATE TABLE batches (
+  id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  user_id UUID NOT NULL,
+  api_key_id UUID,
+  endpoint VARCHAR(64) NOT NULL,
+  input_file_id UUID NOT NULL,
+  output_file_id UUID,
+  error_file_id UUID,
+  completion_window VARCHAR(16) NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  metadata BLOB,
+  errors BLOB,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  expires_at TIMESTAMP NOT NULL,
+  cancelling_at TIMESTAMP,
+  finished_at TIMESTAMP,
+  CONSTRAINT batches_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX batches_user_created_at_idx ON batches (user_id, created_at DESC, id DESC);
+CREATE INDEX batches_active_idx ON batches (status) WHERE status IN ('in_progress', 'cancelling');
+
+-- One row per input line. Workers claim pending lines whose next_attempt_at
+-- has passed; status is pending, running, succeeded, failed, cancelled or
+-- expired. The response of the last attempt is kept for the result files.
+CREATE TABLE batch_requests (
+  id UUID PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+  batch_id UUID NOT NULL,
+  line INTEGER NOT NULL,
+  custom_id VARCHAR(512) NOT NULL,
+  body BLOB NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  claimed_at TIMESTAMP,
+  status_code INTEGER,
+  request_id VARCHAR,
+  response BLOB,
+  error VARCHAR,
+  CONSTRAINT batch_requests_batch_id_fkey FOREIGN KEY (batch_id) REFERENCES batches(id) ON DELETE CASCADE,
+  CONSTRAINT batch_requests_batch_line_key UNIQUE (batch_id, line)
+);
+CREATE INDEX batch_requests_pending_idx ON batch_requests (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX batch_requests_running_idx ON batch_requests (claimed_at) WHERE status = 'running';
+
+-- The batch a logged request belongs to
+ALTER TABLE logs ADD COLUMN batch_id UUID;
+CREATE INDEX logs_batch_id_idx ON logs (batch_id) WHERE batch_id IS NOT NULL;
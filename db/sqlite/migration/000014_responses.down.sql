@@ -0,0 +1,2 @@
+ALTER TABLE logs DROP COLUMN reasoning_tokens;
+DROP TABLE IF EXISTS responses;
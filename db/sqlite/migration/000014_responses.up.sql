@@ -0,0 +1,15 @@
+-- Conversations of responses the proxy answered by translating a Responses API
+-- request to a chat API, so previous_response_id can continue them. OpenAI
+-- keeps the state of native responses itself. messages is the full chat
+-- history including the answer, as JSON.
+CREATE TABLE responses (
+  id VARCHAR(64) PRIMARY KEY NOT NULL,
+  user_id UUID NOT NULL,
+  messages BLOB NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  CONSTRAINT responses_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Output tokens spent on reasoning, as reported by the upstream; already
+-- included in completion_tokens
+ALTER TABLE logs ADD COLUMN reasoning_tokens BIGINT;
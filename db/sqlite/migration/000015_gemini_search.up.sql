@@ -0,0 +1,3 @@
+-- Log search already matches anywhere in the payloads, so Gemini's format
+-- needs no change here. This keeps the versions in step with Postgres.
+SELECT 1;
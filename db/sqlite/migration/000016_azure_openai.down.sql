@@ -0,0 +1,2 @@
+ALTER TABLE logs DROP COLUMN content_filter;
+ALTER TABLE providers DROP COLUMN api_version;
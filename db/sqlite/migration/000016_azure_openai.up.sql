@@ -0,0 +1,7 @@
+-- API version sent with every request to the provider, as Azure OpenAI
+-- requires; empty for other provider types
+ALTER TABLE providers ADD COLUMN api_version VARCHAR(32) NOT NULL DEFAULT '';
+
+-- Content filter results reported by the upstream (Azure OpenAI), for the
+-- prompt and each choice, as JSON
+ALTER TABLE logs ADD COLUMN content_filter BLOB;
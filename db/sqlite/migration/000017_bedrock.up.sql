@@ -0,0 +1,3 @@
+-- AWS region requests to the provider are signed for, as Bedrock requires;
+-- empty for other provider types
+ALTER TABLE providers ADD COLUMN region VARCHAR(32) NOT NULL DEFAULT '';
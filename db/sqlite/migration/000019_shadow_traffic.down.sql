@@ -0,0 +1,7 @@
+ALTER TABLE log_daily_usage DROP COLUMN cost;
+DROP INDEX IF EXISTS logs_shadow_of_idx;
+ALTER TABLE logs DROP COLUMN price_output;
+ALTER TABLE logs DROP COLUMN price_input;
+ALTER TABLE logs DROP COLUMN provider_model_id;
+ALTER TABLE logs DROP COLUMN shadow_of;
+ALTER TABLE models DROP COLUMN shadow;
@@ -0,0 +1,57 @@
+-- name: GetAPIKeyByHash :one
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE key_hash = ?;
+
+-- name: GetAPIKey :one
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE key_hash = ? AND user_id = ?;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+    user_id,
+    key_hash,
+    name,
+    managed,
+    allowed_models,
+    monthly_budget
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+) RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget;
+
+-- name: ListAPIKeys :many
+SELECT id, user_id, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE user_id = ?;
+
+-- name: UpdateAPIKey :one
+UPDATE api_keys
+SET
+    name = ?2
+WHERE name = ?1 AND user_id = ?3
+RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget;
+
+-- name: UpdateAPIKeyLastUsed :exec
+UPDATE api_keys
+SET
+    last_used_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = ?;
+
+-- name: DeleteAPIKey :exec
+DELETE FROM api_keys
+WHERE id = ? AND user_id = ?;
+
+-- name: GetAPIKeyByID :one
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE id = ? AND user_id = ?;
+
+-- name: ListManagedAPIKeys :many
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE user_id = ? AND managed;
+
+-- name: UpdateAPIKeyPolicy :one
+UPDATE api_keys
+SET
+    key_hash = ?3,
+    allowed_models = ?4,
+    monthly_budget = ?5
+WHERE id = ?1 AND user_id = ?2
+RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget;
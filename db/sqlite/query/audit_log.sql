@@ -0,0 +1,35 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (
+    actor_id,
+    action,
+    resource_type,
+    resource_id,
+    before,
+    after,
+    source_ip,
+    user_agent
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+) RETURNING *;
+
+-- name: ListAuditLogs :many
+SELECT * FROM audit_logs
+WHERE
+    actor_id = sqlc.arg('actor_id') AND
+    (action = sqlc.narg('action') OR sqlc.narg('action') IS NULL) AND
+    (resource_type = sqlc.narg('resource_type') OR sqlc.narg('resource_type') IS NULL) AND
+    (resource_id = sqlc.narg('resource_id') OR sqlc.narg('resource_id') IS NULL) AND
+    (created_at >= strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('since')) OR sqlc.narg('since') IS NULL) AND
+    (created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('until')) OR sqlc.narg('until') IS NULL)
+ORDER BY created_at DESC
+LIMIT COALESCE(sqlc.narg('limit'), -1) OFFSET COALESCE(sqlc.narg('offset'), 0);
+
+-- name: CountAuditLogs :one
+SELECT COUNT(*) FROM audit_logs
+WHERE
+    actor_id = sqlc.arg('actor_id') AND
+    (action = sqlc.narg('action') OR sqlc.narg('action') IS NULL) AND
+    (resource_type = sqlc.narg('resource_type') OR sqlc.narg('resource_type') IS NULL) AND
+    (resource_id = sqlc.narg('resource_id') OR sqlc.narg('resource_id') IS NULL) AND
+    (created_at >= strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('since')) OR sqlc.narg('since') IS NULL) AND
+    (created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('until')) OR sqlc.narg('until') IS NULL);
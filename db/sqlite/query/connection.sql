@@ -0,0 +1,49 @@
+-- name: CreateConnection :one
+INSERT INTO connections (
+    user_id,
+    provider_id,
+    encrypted_api_key,
+    name,
+    managed
+) VALUES (
+    ?, ?, ?, ?, ?
+) RETURNING id, user_id, provider_id, encrypted_api_key, name, created_at, managed;
+
+-- name: GetConnection :one
+SELECT c.id, c.user_id, c.provider_id, c.encrypted_api_key, c.name, c.created_at, p.type as provider_type, c.deleted_at, c.managed
+FROM connections c
+JOIN providers p ON p.id = c.provider_id
+WHERE c.id = ? AND c.user_id = ? AND c.deleted_at IS NULL;
+
+-- name: GetConnectionByProvider :one
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
+WHERE user_id = ? AND provider_id = ? AND deleted_at IS NULL;
+
+-- name: ListConnections :many
+SELECT id, user_id, provider_id, name, created_at, deleted_at, managed FROM connections
+WHERE user_id = ? AND deleted_at IS NULL;
+
+-- name: ListAllConnections :many
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
+ORDER BY created_at;
+
+-- name: ListConnectionsByProviderID :many
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
+WHERE provider_id = ? AND user_id = ? AND deleted_at IS NULL;
+
+-- name: ListManagedConnections :many
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
+WHERE user_id = ? AND managed AND deleted_at IS NULL;
+
+-- name: SoftDeleteConnection :exec
+UPDATE connections
+SET deleted_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = ? AND user_id = ?;
+
+-- name: UpdateConnection :one
+UPDATE connections
+SET
+    provider_id = ?3,
+    encrypted_api_key = ?4
+WHERE id = ?1 AND user_id = ?2
+RETURNING id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed;
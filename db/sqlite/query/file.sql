@@ -0,0 +1,36 @@
+-- name: CreateFile :one
+INSERT INTO files (
+    id,
+    user_id,
+    filename,
+    purpose,
+    bytes
+) VALUES (
+    ?1, ?2, ?3, ?4, ?5
+) RETURNING *;
+
+-- name: CreateFileContent :exec
+INSERT INTO file_contents (file_id, content) VALUES (?1, ?2);
+
+-- name: GetFile :one
+SELECT * FROM files WHERE id = ?1 AND user_id = ?2;
+
+-- name: GetFileContent :one
+SELECT c.content
+FROM file_contents c
+JOIN files f ON f.id = c.file_id
+WHERE f.id = ?1 AND f.user_id = ?2;
+
+-- name: ListFiles :many
+SELECT * FROM files
+WHERE
+    user_id = sqlc.arg('user_id') AND
+    (purpose = sqlc.narg('purpose') OR sqlc.narg('purpose') IS NULL) AND
+    (sqlc.narg('cursor_created_at') IS NULL OR
+        created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('cursor_created_at')) OR
+        (created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('cursor_created_at')) AND id < sqlc.narg('cursor_id')))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteFile :execrows
+DELETE FROM files WHERE id = ?1 AND user_id = ?2;
@@ -0,0 +1,308 @@
+-- name: CreateLog :one
+INSERT INTO logs (
+    id,
+    user_id,
+    model_id,
+    request_payload,
+    response_payload,
+    prompt_tokens,
+    completion_tokens,
+    connection_id,
+    type,
+    api_key_id,
+    status_code,
+    request_id,
+    prompt_template_id,
+    prompt_template_version,
+    schema_validation,
+    schema_attempt,
+    schema_errors,
+    batch_id,
+    reasoning_tokens,
+    content_filter,
+    latency_ms,
+    eval_run_id,
+    shadow_of,
+    provider_model_id,
+    price_input,
+    price_output
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+) RETURNING id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type, api_key_id, status_code, request_id, prompt_template_id, prompt_template_version, schema_validation, schema_attempt, schema_errors, batch_id, reasoning_tokens, content_filter;
+
+-- name: GetLog :one
+SELECT id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type, api_key_id, status_code, request_id, prompt_template_id, prompt_template_version, schema_validation, schema_attempt, schema_errors, batch_id, reasoning_tokens, content_filter
+FROM logs
+WHERE id = ? AND user_id = ?;
+
+-- Timestamps are compared as text, so arguments are first brought to the
+-- stored layout. Search is a case-insensitive substring match over the raw
+-- payloads; SQLite has no full-text index over JSON paths.
+
+-- name: ListLogs :many
+SELECT
+    l.id,
+    l.user_id,
+    l.model_id,
+    l.request_payload,
+    l.response_payload,
+    l.created_at,
+    l.prompt_tokens,
+    l.completion_tokens,
+    l.connection_id,
+    l.type,
+    l.api_key_id,
+    l.status_code,
+    l.request_id,
+    l.prompt_template_id,
+    l.prompt_template_version,
+    l.schema_validation,
+    l.schema_attempt,
+    l.schema_errors,
+    l.batch_id,
+    l.reasoning_tokens,
+    l.content_filter,
+    l.shadow_of,
+    l.provider_model_id,
+    conn.provider_id,
+    CAST(COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) AS REAL) AS cost
+FROM logs l
+LEFT JOIN models m ON m.id = l.model_id
+LEFT JOIN connections conn ON conn.id = l.connection_id
+WHERE
+    (l.user_id = sqlc.narg('user_id') OR sqlc.narg('user_id') IS NULL) AND
+    (l.model_id = sqlc.narg('model_id') OR sqlc.narg('model_id') IS NULL) AND
+    (l.connection_id = sqlc.narg('connection_id') OR sqlc.narg('connection_id') IS NULL) AND
+    (conn.provider_id = sqlc.narg('provider_id') OR sqlc.narg('provider_id') IS NULL) AND
+    (l.api_key_id = sqlc.narg('api_key_id') OR sqlc.narg('api_key_id') IS NULL) AND
+    (l.type = sqlc.narg('type') OR sqlc.narg('type') IS NULL) AND
+    (l.request_id = sqlc.narg('request_id') OR sqlc.narg('request_id') IS NULL) AND
+    (l.prompt_template_id = sqlc.narg('prompt_template_id') OR sqlc.narg('prompt_template_id') IS NULL) AND
+    (l.prompt_template_version = sqlc.narg('prompt_template_version') OR sqlc.narg('prompt_template_version') IS NULL) AND
+    (l.schema_validation = sqlc.narg('schema_validation') OR sqlc.narg('schema_validation') IS NULL) AND
+    (l.batch_id = sqlc.narg('batch_id') OR sqlc.narg('batch_id') IS NULL) AND
+    (l.created_at >= strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('since')) OR sqlc.narg('since') IS NULL) AND
+    (l.created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('until')) OR sqlc.narg('until') IS NULL) AND
+    (sqlc.narg('status') IS NULL OR
+        (sqlc.narg('status') = 'success' AND l.status_code < 400) OR
+        (sqlc.narg('status') = 'error' AND l.status_code >= 400)) AND
+    (COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) >= sqlc.narg('min_tokens') OR sqlc.narg('min_tokens') IS NULL) AND
+    (COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) <= sqlc.narg('max_tokens') OR sqlc.narg('max_tokens') IS NULL) AND
+    (COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) >= CAST(sqlc.narg('min_cost') AS REAL) OR sqlc.narg('min_cost') IS NULL) AND
+    (COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) <= CAST(sqlc.narg('max_cost') AS REAL) OR sqlc.narg('max_cost') IS NULL) AND
+    (sqlc.narg('search') IS NULL OR
+        instr(lower(CAST(l.request_payload AS TEXT)), lower(sqlc.narg('search'))) > 0 OR
+        instr(lower(CAST(l.response_payload AS TEXT)), lower(sqlc.narg('search'))) > 0) AND
+    (sqlc.narg('cursor_created_at') IS NULL OR
+        l.created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('cursor_created_at')) OR
+        (l.created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('cursor_created_at')) AND l.id < sqlc.narg('cursor_id')))
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountLogs :one
+SELECT COUNT(*)
+FROM logs l
+LEFT JOIN models m ON m.id = l.model_id
+LEFT JOIN connections conn ON conn.id = l.connection_id
+WHERE
+    (l.user_id = sqlc.narg('user_id') OR sqlc.narg('user_id') IS NULL) AND
+    (l.model_id = sqlc.narg('model_id') OR sqlc.narg('model_id') IS NULL) AND
+    (l.connection_id = sqlc.narg('connection_id') OR sqlc.narg('connection_id') IS NULL) AND
+    (conn.provider_id = sqlc.narg('provider_id') OR sqlc.narg('provider_id') IS NULL) AND
+    (l.api_key_id = sqlc.narg('api_key_id') OR sqlc.narg('api_key_id') IS NULL) AND
+    (l.type = sqlc.narg('type') OR sqlc.narg('type') IS NULL) AND
+    (l.request_id = sqlc.narg('request_id') OR sqlc.narg('request_id') IS NULL) AND
+    (l.prompt_template_id = sqlc.narg('prompt_template_id') OR sqlc.narg('prompt_template_id') IS NULL) AND
+    (l.prompt_template_version = sqlc.narg('prompt_template_version') OR sqlc.narg('prompt_template_version') IS NULL) AND
+    (l.schema_validation = sqlc.narg('schema_validation') OR sqlc.narg('schema_validation') IS NULL) AND
+    (l.batch_id = sqlc.narg('batch_id') OR sqlc.narg('batch_id') IS NULL) AND
+    (l.created_at >= strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('since')) OR sqlc.narg('since') IS NULL) AND
+    (l.created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('until')) OR sqlc.narg('until') IS NULL) AND
+    (sqlc.narg('status') IS NULL OR
+        (sqlc.narg('status') = 'success' AND l.status_code < 400) OR
+        (sqlc.narg('status') = 'error' AND l.status_code >= 400)) AND
+    (COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) >= sqlc.narg('min_tokens') OR sqlc.narg('min_tokens') IS NULL) AND
+    (COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) <= sqlc.narg('max_tokens') OR sqlc.narg('max_tokens') IS NULL) AND
+    (COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) >= CAST(sqlc.narg('min_cost') AS REAL) OR sqlc.narg('min_cost') IS NULL) AND
+    (COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) <= CAST(sqlc.narg('max_cost') AS REAL) OR sqlc.narg('max_cost') IS NULL) AND
+    (sqlc.narg('search') IS NULL OR
+        instr(lower(CAST(l.request_payload AS TEXT)), lower(sqlc.narg('search'))) > 0 OR
+        instr(lower(CAST(l.response_payload AS TEXT)), lower(sqlc.narg('search'))) > 0);
+
+-- name: GetTotalTokensByProviderModelConnection :many
+SELECT
+    p.id AS provider_id,
+    p.name AS provider_name,
+    m.id AS model_id,
+    m.proxy_model_id AS model_name,
+    cl.connection_id,
+    conn.name AS connection_name,
+    CAST(SUM(cl.prompt_tokens + cl.completion_tokens) AS BIGINT) AS total_tokens
+FROM
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
+JOIN
+    models m ON cl.model_id = m.id
+JOIN
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
+JOIN
+    providers p ON p.id = conn.provider_id
+GROUP BY
+    p.id,
+    p.name,
+    m.id,
+    m.proxy_model_id,
+    cl.connection_id,
+    conn.name
+ORDER BY
+    p.id,
+    m.id,
+    cl.connection_id;
+
+-- name: GetTotalPriceByProviderModelConnection :many
+-- Mirrored requests are priced by their shadow policy, not their model.
+SELECT
+    p.id AS provider_id,
+    p.name AS provider_name,
+    m.id AS model_id,
+    m.proxy_model_id AS model_name,
+    cl.connection_id,
+    conn.name AS connection_name,
+    CAST(SUM(
+        COALESCE(cl.cost,
+            (cl.prompt_tokens * m.price_input) +
+            (cl.completion_tokens * m.price_output))
+    ) AS REAL) AS total_price
+FROM
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens, prompt_tokens * price_input + completion_tokens * price_output AS cost FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens, cost FROM log_daily_usage
+    ) cl
+JOIN
+    models m ON cl.model_id = m.id
+JOIN
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
+JOIN
+    providers p ON p.id = conn.provider_id
+GROUP BY
+    p.id,
+    p.name,
+    m.id,
+    m.proxy_model_id,
+    cl.connection_id,
+    conn.name
+ORDER BY
+    p.id,
+    m.id,
+    cl.connection_id;
+
+-- name: GetTotalInputTokensByProviderModelConnection :many
+SELECT
+    p.id AS provider_id,
+    p.name AS provider_name,
+    m.id AS model_id,
+    m.proxy_model_id AS model_name,
+    cl.connection_id,
+    conn.name AS connection_name,
+    CAST(SUM(cl.prompt_tokens) AS BIGINT) AS total_input_tokens
+FROM
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
+JOIN
+    models m ON cl.model_id = m.id
+JOIN
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
+JOIN
+    providers p ON p.id = conn.provider_id
+GROUP BY
+    p.id,
+    p.name,
+    m.id,
+    m.proxy_model_id,
+    cl.connection_id,
+    conn.name
+ORDER BY
+    p.id,
+    m.id,
+    cl.connection_id;
+
+-- name: GetTotalOutputTokensByProviderModelConnection :many
+SELECT
+    p.id AS provider_id,
+    p.name AS provider_name,
+    m.id AS model_id,
+    m.proxy_model_id AS model_name,
+    cl.connection_id,
+    conn.name AS connection_name,
+    CAST(SUM(cl.completion_tokens) AS BIGINT) AS total_output_tokens
+FROM
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
+JOIN
+    models m ON cl.model_id = m.id
+JOIN
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
+JOIN
+    providers p ON p.id = conn.provider_id
+GROUP BY
+    p.id,
+    p.name,
+    m.id,
+    m.proxy_model_id,
+    cl.connection_id,
+    conn.name
+ORDER BY
+    p.id,
+    m.id,
+    cl.connection_id;
+
+-- name: GetAPIKeySpend :one
+-- Spend of logs purged by retention is read from their daily rollup. since
+-- is expected at the start of a UTC day. Mirrored (shadow) requests are not
+-- charged to the key.
+SELECT CAST(COALESCE(SUM(u.prompt_tokens * COALESCE(m.price_input, 0) + u.completion_tokens * COALESCE(m.price_output, 0)), 0) AS REAL) AS spend
+FROM
+    (
+        SELECT l.model_id, COALESCE(l.prompt_tokens, 0) AS prompt_tokens, COALESCE(l.completion_tokens, 0) AS completion_tokens
+        FROM logs l
+        WHERE l.api_key_id = sqlc.arg('api_key_id') AND l.created_at >= strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.arg('since')) AND l.shadow_of IS NULL
+        UNION ALL
+        SELECT d.model_id, d.prompt_tokens, d.completion_tokens
+        FROM log_daily_usage d
+        WHERE d.api_key_id = sqlc.arg('api_key_id') AND d.day >= date(sqlc.arg('since')) AND NOT d.shadow
+    ) u
+LEFT JOIN models m ON m.id = u.model_id;
+
+-- name: GetUsageReport :many
+-- Mirrored requests are priced by their shadow policy, not their model.
+SELECT
+    m.proxy_model_id AS model_name,
+    u.type,
+    CAST(SUM(u.request_count) AS BIGINT) AS request_count,
+    CAST(SUM(u.prompt_tokens) AS BIGINT) AS prompt_tokens,
+    CAST(SUM(u.completion_tokens) AS BIGINT) AS completion_tokens,
+    CAST(SUM(COALESCE(u.cost, u.prompt_tokens * m.price_input + u.completion_tokens * m.price_output)) AS REAL) AS cost
+FROM
+    (
+        SELECT l.model_id, l.type, 1 AS request_count, COALESCE(l.prompt_tokens, 0) AS prompt_tokens, COALESCE(l.completion_tokens, 0) AS completion_tokens,
+            COALESCE(l.prompt_tokens, 0) * l.price_input + COALESCE(l.completion_tokens, 0) * l.price_output AS cost
+        FROM logs l
+        WHERE l.user_id = sqlc.arg('user_id') AND l.created_at >= strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.arg('since')) AND l.created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.arg('until'))
+        UNION ALL
+        SELECT d.model_id, d.type, d.request_count, d.prompt_tokens, d.completion_tokens, d.cost
+        FROM log_daily_usage d
+        WHERE d.user_id = sqlc.arg('user_id') AND d.day >= date(sqlc.arg('since')) AND d.day < date(sqlc.arg('until'))
+    ) u
+JOIN models m ON m.id = u.model_id
+GROUP BY m.proxy_model_id, u.type
+ORDER BY m.proxy_model_id, u.type;
@@ -0,0 +1,57 @@
+-- name: GetModelByProxyModelID :one
+SELECT * FROM models WHERE proxy_model_id = ? AND user_id = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: CreateModel :one
+INSERT INTO models (
+    id,
+    user_id,
+    connection_id,
+    proxy_model_id,
+    provider_model_id,
+    thinking,
+    tools_usage,
+    price_input,
+    price_output,
+    type,
+    log_policy,
+    managed,
+    param_policy,
+    prompt_template_id,
+    structured_output,
+    shadow
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+) RETURNING *;
+
+-- name: GetModel :one
+SELECT * FROM models WHERE id = ? AND user_id = ? AND deleted_at IS NULL;
+
+-- name: ListModels :many
+SELECT * FROM models WHERE user_id = ? AND deleted_at IS NULL;
+
+-- name: ListManagedModels :many
+SELECT * FROM models WHERE user_id = ? AND managed AND deleted_at IS NULL;
+
+-- name: UpdateModel :one
+UPDATE models
+SET
+    proxy_model_id = ?3,
+    provider_model_id = ?4,
+    thinking = ?5,
+    tools_usage = ?6,
+    price_input = ?7,
+    price_output = ?8,
+    type = ?9,
+    log_policy = ?10,
+    connection_id = ?11,
+    param_policy = ?12,
+    prompt_template_id = ?13,
+    structured_output = ?14,
+    shadow = ?15
+WHERE id = ?1 AND user_id = ?2
+RETURNING *;
+
+-- name: SoftDeleteModel :exec
+UPDATE models
+SET deleted_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = ? AND user_id = ?;
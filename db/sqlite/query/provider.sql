@@ -0,0 +1,38 @@
+-- name: CreateProvider :one
+INSERT INTO providers (
+    id,
+    user_id,
+    name,
+    base_url,
+    type,
+    managed,
+    api_version,
+    region
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+) RETURNING id, user_id, name, base_url, type, managed, api_version, region;
+
+-- name: GetProvider :one
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE id = ? AND user_id = ? AND deleted_at IS NULL;
+
+-- name: ListProviders :many
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE user_id = ? AND deleted_at IS NULL;
+
+-- name: ListManagedProviders :many
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE user_id = ? AND managed AND deleted_at IS NULL;
+
+-- name: SoftDeleteProvider :exec
+UPDATE providers
+SET deleted_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = ? AND user_id = ?;
+
+-- name: UpdateProvider :one
+UPDATE providers
+SET
+    name = ?3,
+    base_url = ?4,
+    type = ?5,
+    api_version = ?6,
+    region = ?7
+WHERE id = ?1 AND user_id = ?2
+RETURNING id, user_id, name, base_url, type, deleted_at, managed, api_version, region;
@@ -0,0 +1,7 @@
+-- name: CreateResponse :exec
+INSERT INTO responses (id, user_id, messages) VALUES (?, ?, ?);
+
+-- name: GetResponse :one
+SELECT id, user_id, messages, created_at
+FROM responses
+WHERE id = ? AND user_id = ?;
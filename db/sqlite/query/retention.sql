@@ -0,0 +1,109 @@
+-- name: CreateRetentionPolicy :one
+INSERT INTO retention_policies (
+    user_id,
+    model_id,
+    payload_ttl_days,
+    row_ttl_days,
+    archive
+) VALUES (
+    ?, ?, ?, ?, ?
+) RETURNING *;
+
+-- name: GetRetentionPolicy :one
+SELECT * FROM retention_policies
+WHERE id = ? AND user_id = ?;
+
+-- name: ListRetentionPolicies :many
+SELECT * FROM retention_policies
+WHERE user_id = ?
+ORDER BY model_id NULLS FIRST, created_at;
+
+-- name: DeleteRetentionPolicy :exec
+DELETE FROM retention_policies
+WHERE id = ? AND user_id = ?;
+
+-- SQLite has no LATERAL joins or row locks: the most specific policy is
+-- picked with a correlated subquery, and writers are serialised anyway.
+
+-- name: ListLogsWithExpiredPayloads :many
+SELECT l.id, l.user_id, l.model_id, l.connection_id, l.request_payload, l.response_payload, l.prompt_tokens, l.completion_tokens, l.created_at, l.type, rp.archive
+FROM logs l
+JOIN retention_policies rp ON rp.id = (
+    SELECT p.id FROM retention_policies p
+    WHERE p.user_id = l.user_id AND (p.model_id = l.model_id OR p.model_id IS NULL)
+    ORDER BY p.model_id NULLS LAST
+    LIMIT 1
+)
+WHERE
+    l.payload_purged_at IS NULL AND
+    l.created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-' || rp.payload_ttl_days || ' days')
+ORDER BY l.created_at
+LIMIT ?;
+
+-- name: ListLogsPastRowTTL :many
+-- A row whose payload was purged was archived at the payload stage, if at
+-- all, so it is only rolled up.
+SELECT l.id, l.user_id, l.model_id, l.connection_id, l.request_payload, l.response_payload, l.prompt_tokens, l.completion_tokens, l.created_at, l.type,
+    CAST(rp.archive AND l.payload_purged_at IS NULL AS BOOLEAN) AS archive
+FROM logs l
+JOIN retention_policies rp ON rp.id = (
+    SELECT p.id FROM retention_policies p
+    WHERE p.user_id = l.user_id AND (p.model_id = l.model_id OR p.model_id IS NULL)
+    ORDER BY p.model_id NULLS LAST
+    LIMIT 1
+)
+WHERE
+    rp.row_ttl_days IS NOT NULL AND
+    l.created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-' || rp.row_ttl_days || ' days')
+ORDER BY l.created_at
+LIMIT ?;
+
+-- name: PurgeLogPayloads :execrows
+UPDATE logs
+SET
+    request_payload = '{}',
+    response_payload = '{}',
+    payload_purged_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id IN (sqlc.slice('ids'));
+
+-- name: RollupLogs :exec
+-- Only the logs of mirrored requests carry prices, so only shadow buckets
+-- get a cost; other buckets are priced by their model when read.
+INSERT INTO log_daily_usage (
+    user_id,
+    model_id,
+    connection_id,
+    api_key_id,
+    shadow,
+    type,
+    day,
+    request_count,
+    prompt_tokens,
+    completion_tokens,
+    cost
+)
+SELECT
+    l.user_id,
+    l.model_id,
+    l.connection_id,
+    l.api_key_id,
+    l.shadow_of IS NOT NULL,
+    l.type,
+    date(l.created_at),
+    COUNT(*),
+    COALESCE(SUM(l.prompt_tokens), 0),
+    COALESCE(SUM(l.completion_tokens), 0),
+    SUM(COALESCE(l.prompt_tokens, 0) * l.price_input + COALESCE(l.completion_tokens, 0) * l.price_output)
+FROM logs l
+WHERE l.id IN (sqlc.slice('ids'))
+GROUP BY l.user_id, l.model_id, l.connection_id, l.api_key_id, l.shadow_of IS NOT NULL, l.type, date(l.created_at)
+ON CONFLICT (user_id, model_id, COALESCE(connection_id, '00000000-0000-0000-0000-000000000000'), COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'), shadow, type, day)
+DO UPDATE SET
+    request_count = log_daily_usage.request_count + excluded.request_count,
+    prompt_tokens = log_daily_usage.prompt_tokens + excluded.prompt_tokens,
+    completion_tokens = log_daily_usage.completion_tokens + excluded.completion_tokens,
+    cost = log_daily_usage.cost + excluded.cost;
+
+-- name: DeleteLogs :execrows
+DELETE FROM logs
+WHERE id IN (sqlc.slice('ids'));
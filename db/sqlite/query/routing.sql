@@ -0,0 +1,18 @@
+-- name: ListRoutes :many
+SELECT sqlc.embed(m), sqlc.embed(p), c.name AS connection_name, c.encrypted_api_key
+FROM models m
+JOIN connections c ON c.id = m.connection_id AND c.user_id = m.user_id
+JOIN providers p ON p.id = c.provider_id AND p.user_id = m.user_id
+WHERE m.deleted_at IS NULL AND c.deleted_at IS NULL AND p.deleted_at IS NULL
+ORDER BY m.user_id, m.proxy_model_id, m.id;
+
+-- name: ListRouteConnections :many
+-- Connections shadow traffic can be mirrored to, with their provider.
+SELECT c.id, c.user_id, c.name AS connection_name, c.encrypted_api_key, sqlc.embed(p)
+FROM connections c
+JOIN providers p ON p.id = c.provider_id AND p.user_id = c.user_id
+WHERE c.deleted_at IS NULL AND p.deleted_at IS NULL
+ORDER BY c.id;
+
+-- name: GetRoutingVersion :one
+SELECT version FROM routing_version WHERE id = 1;
@@ -0,0 +1,33 @@
+-- name: ListShadowComparisons :many
+-- Mirrored requests next to the requests they mirror, per model and shadow
+-- target. Only the first upstream attempt of either side is linked, so
+-- structured output retries are not compared.
+SELECT
+    p.model_id AS primary_model_id,
+    pm.proxy_model_id AS primary_model,
+    s.connection_id AS shadow_connection_id,
+    sc.name AS shadow_connection,
+    COALESCE(s.provider_model_id, '') AS shadow_provider_model,
+    COUNT(*) AS requests,
+    CAST(SUM(CASE WHEN p.status_code >= 400 THEN 1 ELSE 0 END) AS BIGINT) AS primary_errors,
+    CAST(SUM(CASE WHEN s.status_code >= 400 THEN 1 ELSE 0 END) AS BIGINT) AS shadow_errors,
+    CAST(COALESCE(SUM(p.prompt_tokens), 0) AS BIGINT) AS primary_prompt_tokens,
+    CAST(COALESCE(SUM(p.completion_tokens), 0) AS BIGINT) AS primary_completion_tokens,
+    CAST(COALESCE(SUM(s.prompt_tokens), 0) AS BIGINT) AS shadow_prompt_tokens,
+    CAST(COALESCE(SUM(s.completion_tokens), 0) AS BIGINT) AS shadow_completion_tokens,
+    CAST(COALESCE(AVG(p.latency_ms), 0) AS REAL) AS primary_avg_latency_ms,
+    CAST(COALESCE(AVG(s.latency_ms), 0) AS REAL) AS shadow_avg_latency_ms,
+    CAST(COALESCE(SUM(COALESCE(p.prompt_tokens, 0) * pm.price_input + COALESCE(p.completion_tokens, 0) * pm.price_output), 0) AS REAL) AS primary_cost,
+    CAST(COALESCE(SUM(COALESCE(s.prompt_tokens, 0) * s.price_input + COALESCE(s.completion_tokens, 0) * s.price_output), 0) AS REAL) AS shadow_cost
+FROM logs s
+JOIN logs p ON p.id = s.shadow_of
+JOIN models pm ON pm.id = p.model_id
+JOIN connections sc ON sc.id = s.connection_id
+WHERE
+    s.user_id = sqlc.arg('user_id') AND
+    COALESCE(s.schema_attempt, 1) = 1 AND
+    (p.model_id = sqlc.narg('model_id') OR sqlc.narg('model_id') IS NULL) AND
+    (s.created_at >= strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('since')) OR sqlc.narg('since') IS NULL) AND
+    (s.created_at < strftime('%Y-%m-%d %H:%M:%f+00:00', sqlc.narg('until')) OR sqlc.narg('until') IS NULL)
+GROUP BY p.model_id, pm.proxy_model_id, s.connection_id, sc.name, s.provider_model_id
+ORDER BY pm.proxy_model_id, sc.name, s.provider_model_id;
@@ -0,0 +1,24 @@
+-- name: CreateUser :one
+INSERT INTO users (
+    username,
+    password_hash
+) VALUES (
+    ?, ?
+) RETURNING id, username, password_hash, created_at;
+
+-- name: GetUserPasswordHash :one
+SELECT
+    password_hash as hash_value
+FROM
+    users
+WHERE
+    username = ?;
+
+-- name: GetUserByUsername :one
+SELECT * FROM users
+WHERE username = ?;
+
+-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = ?2
+WHERE username = ?1;
@@ -0,0 +1,12 @@
+package main
+
+import "embed"
+
+// embedded holds everything the binary reads at runtime, so it does not
+// depend on the working directory. See package assets.
+//
+//go:embed db/migration/*.sql
+//go:embed db/sqlite/migration/*.sql
+//go:embed src/templates
+//go:embed docs/swagger.yaml
+var embedded embed.FS
@@ -2,26 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gen-ai-proxy/src/api"
+	"gen-ai-proxy/src/assets"
+	"gen-ai-proxy/src/batch"
+	"gen-ai-proxy/src/cli"
 	"gen-ai-proxy/src/config"
-	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/declarative"
+	"gen-ai-proxy/src/eval"
+	"gen-ai-proxy/src/logging"
 	"gen-ai-proxy/src/metrics"
+	"gen-ai-proxy/src/retention"
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/telemetry"
 	"io"
-	"log"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
-	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-
 	_ "gen-ai-proxy/docs"
 	"html/template"
 	"net/http"
@@ -42,52 +52,111 @@ import (
 // @security      BearerAuth
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, relying on environment variables")
+	os.Exit(cli.Run(os.Args[1:], embedded, serve))
+}
+
+// serve runs migrations and the HTTP server until ctx is cancelled, then
+// shuts down gracefully.
+func serve(ctx context.Context, env *cli.Env, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected argument %q", args[0])
 	}
-	cfg, err := config.LoadConfig(".")
+	cfg := env.Config
+
+	shutdownTracing, err := telemetry.Setup(ctx, cfg)
 	if err != nil {
-		log.Fatalf("could not load config: %v", err)
+		return fmt.Errorf("could not configure tracing: %w", err)
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
 
-	log.Printf("Viper settings: %+v", viper.AllSettings())
-
-	conn, err := database.Connect(&cfg)
+	db, err := env.Store()
 	if err != nil {
-		log.Fatalf("could not connect to database: %v", err)
+		return fmt.Errorf("could not connect to database: %w", err)
 	}
-	defer conn.Close(context.Background())
 
-	log.Println("Database connection successful")
+	slog.Info("Database connection successful")
 
 	// Run database migrations
-	databaseURL := fmt.Sprintf("pgx5://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
-
-	m, err := migrate.New(
-		"file://db/migration",
-		databaseURL,
-	)
-	if err != nil {
-		log.Fatalf("could not create migrate instance: %v", err)
+	if err := cli.MigrateUp(cfg, env.Assets); err != nil {
+		return err
 	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("could not run migrations: %v", err)
+	migrations, err := cli.NewMigrationChecker(cfg, env.Assets)
+	if err != nil {
+		return err
 	}
-	log.Println("Database migrations applied successfully!")
+	defer migrations.Close()
 
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	// The server span must wrap everything else so request logs carry its trace ID.
+	e.Use(otelecho.Middleware(cfg.OTelServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		switch c.Path() {
+		case "/metrics", "/ping", "/healthz", "/readyz":
+			return true
+		}
+		return false
+	})))
+
+	// Reconcile the declarative configuration file
+	var reconciler *declarative.Reconciler
+	if cfg.ResourcesFile != "" {
+		reconciler, err = declarative.NewReconciler(db, cfg.EncryptionKey)
+		if err != nil {
+			return fmt.Errorf("could not create configuration reconciler: %w", err)
+		}
+		if err := reconciler.Sync(ctx, cfg.ResourcesFile, cfg.ResourcesDryRun); err != nil {
+			return fmt.Errorf("could not apply configuration file: %w", err)
+		}
+	}
 
-	db := database.New(conn)
-	s, err := api.NewService(db, &cfg)
+	// Route proxied requests from memory, reloading on changes
+	routes, err := routing.NewTable(ctx, db, cfg.EncryptionKey, cfg.RoutingPollInterval)
 	if err != nil {
-		log.Fatalf("could not create API service: %v", err)
+		return err
 	}
+	go routes.Run(ctx)
+
+	s, err := api.NewService(db, cfg, routes)
+	if err != nil {
+		return fmt.Errorf("could not create API service: %w", err)
+	}
+	s.AddReadinessCheck("migrations", migrations.Check)
 	api.RegisterRoutes(e, s)
 
+	go reloadOnSIGHUP(env, s, reconciler)
+
 	// Register Prometheus metrics collector
 	collector := metrics.NewMetricsCollector(db)
-	prometheus.MustRegister(collector)
+	prometheus.MustRegister(collector, metrics.RedactionsTotal, metrics.LogPayloadsTotal,
+		metrics.StructuredOutputValidationsTotal, metrics.StructuredOutputFailuresTotal, metrics.BatchRequestsTotal,
+		metrics.EvalRequestsTotal, metrics.ShadowRequestsTotal)
+
+	// Start the conversation log retention worker
+	if cfg.RetentionEnabled {
+		archiveStore, err := retention.NewStoreFromConfig(cfg)
+		if err != nil {
+			return fmt.Errorf("could not configure log archive: %w", err)
+		}
+		worker := retention.NewWorker(db, archiveStore, cfg.ArchivePrefix, cfg.RetentionBatchSize, cfg.RetentionInterval)
+		go worker.Run(ctx)
+	}
+
+	// Start the batch API workers
+	if cfg.BatchWorkers > 0 {
+		worker := batch.NewWorker(db, s.ExecuteBatchRequest, cfg.BatchWorkers, cfg.BatchMaxAttempts)
+		go worker.Run(ctx)
+	}
+
+	// Start the evaluation run workers
+	if cfg.EvalWorkers > 0 {
+		worker := eval.NewWorker(db, s.ExecuteEvalRequest, cfg.EvalWorkers, cfg.EvalMaxAttempts)
+		go worker.Run(ctx)
+	}
 
 	// Setup template renderer
 	funcMap := template.FuncMap{
@@ -96,23 +165,24 @@ func main() {
 		},
 	}
 
-	t := &Template{
-		templates: template.Must(template.New("main").Funcs(funcMap).ParseFiles(
-			"src/templates/base.html",
-			"src/templates/index.html",
-			"src/templates/endpoint.html",
-			"src/templates/dashboard.html",
-			"src/templates/ui/index.html",
-		)),
+	templates, err := template.New("main").Funcs(funcMap).ParseFS(env.Assets,
+		assets.TemplatesDir+"/base.html",
+		assets.TemplatesDir+"/index.html",
+		assets.TemplatesDir+"/endpoint.html",
+		assets.TemplatesDir+"/dashboard.html",
+		assets.TemplatesDir+"/ui/index.html",
+	)
+	if err != nil {
+		return fmt.Errorf("could not parse templates: %w", err)
 	}
-	log.Println("Templates parsed successfully.")
-	e.Renderer = t
+	slog.Debug("Templates parsed successfully")
+	e.Renderer = &Template{templates: templates}
 
 	// Register static file serving routes
-	registerStaticRoutes(e)
+	registerStaticRoutes(e, env.Assets)
 
 	// Register UI routes
-	registerUIRoutes(e)
+	registerUIRoutes(e, env.Assets)
 
 	e.GET("/ping", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{
@@ -122,27 +192,100 @@ func main() {
 
 	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 
-	e.GET("/swagger.yaml", func(c echo.Context) error {
-		return c.File("swagger.yaml")
-	})
+	e.FileFS("/swagger.yaml", assets.OpenAPISpec, env.Assets)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "port", cfg.ServerPort)
+		serverErr <- e.Start(":" + cfg.ServerPort)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("could not start server: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
 
-	log.Printf("Starting server on port %s", cfg.ServerPort)
-	if err := e.Start(":" + cfg.ServerPort); err != nil {
-		log.Fatalf("could not start server: %v", err)
+	// A second SIGINT or SIGTERM kills the process without waiting.
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+	shutdown(e, s, cfg)
+	return nil
+}
+
+// shutdown fails readiness, then stops accepting connections and waits up to
+// SHUTDOWN_TIMEOUT for active requests, streams included, and their
+// conversation logs to finish.
+func shutdown(e *echo.Echo, s *api.Service, cfg *config.Config) {
+	slog.Info("Shutting down", "delay", cfg.ShutdownDelay, "timeout", cfg.ShutdownTimeout)
+	s.SetDraining()
+	// Give load balancers time to see the failing readiness probe.
+	time.Sleep(cfg.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		slog.Warn("Requests still active after the shutdown timeout, closing them", "error", err)
+		if err := e.Close(); err != nil {
+			slog.Error("Failed to close server", "error", err)
+		}
+	}
+	if err := s.DrainBackground(ctx); err != nil {
+		slog.Error("Conversation logs still being written after the shutdown timeout", "error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// reloadableSettings are applied by reloadOnSIGHUP; other changed settings
+// only take effect after a restart.
+var reloadableSettings = []string{"LOG_LEVEL", "LOG_FORMAT", "LOG_PAYLOADS", "UPSTREAM_TIMEOUT", "STRUCTURED_OUTPUT_MAX_RETRIES", "MOCK_PROVIDERS", "MOCK_FIXTURES_DIR"}
+
+// reloadOnSIGHUP reloads the settings that can change at runtime and
+// re-applies the declarative configuration file, if any, on every SIGHUP.
+// A broken .env or configuration file is logged and leaves the current
+// configuration in place.
+func reloadOnSIGHUP(env *cli.Env, s *api.Service, reconciler *declarative.Reconciler) {
+	startup := env.Config
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		slog.Info("Reloading configuration")
+		if cfg, err := env.ReloadConfig(); err != nil {
+			slog.Error("Failed to reload configuration", "error", err)
+		} else if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+			slog.Error("Failed to reload configuration", "error", err)
+		} else {
+			s.ApplyConfig(cfg)
+			for _, name := range config.Changed(*startup, *cfg) {
+				if !slices.Contains(reloadableSettings, name) {
+					slog.Warn("Setting changed but requires a restart", "setting", name)
+				}
+			}
+		}
+
+		if reconciler != nil {
+			slog.Info("Reloading configuration file", "file", startup.ResourcesFile)
+			if err := reconciler.Sync(context.Background(), startup.ResourcesFile, startup.ResourcesDryRun); err != nil {
+				slog.Error("Failed to reload configuration file", "file", startup.ResourcesFile, "error", err)
+			}
+		}
 	}
 }
 
 // registerStaticRoutes sets up routes for serving static files.
-func registerStaticRoutes(e *echo.Echo) {
-	e.Static("/js", "src/templates/js")
-	e.Static("/ui/css", "src/templates/ui/css")
+func registerStaticRoutes(e *echo.Echo, files fs.FS) {
+	e.StaticFS("/js", echo.MustSubFS(files, assets.TemplatesDir+"/js"))
+	e.StaticFS("/ui/css", echo.MustSubFS(files, assets.TemplatesDir+"/ui/css"))
 
 	// Apply custom middleware for /ui/js to ensure correct Content-Type
-	e.Static("/ui/js", "src/templates/ui/js")
+	e.StaticFS("/ui/js", echo.MustSubFS(files, assets.TemplatesDir+"/ui/js"))
 }
 
 // registerUIRoutes sets up routes for the main UI pages.
-func registerUIRoutes(e *echo.Echo) {
+func registerUIRoutes(e *echo.Echo, files fs.FS) {
 	e.GET("/", func(c echo.Context) error {
 		return c.Render(http.StatusOK, "index.html", nil)
 	})
@@ -151,9 +294,7 @@ func registerUIRoutes(e *echo.Echo) {
 		return c.Render(http.StatusOK, "dashboard.html", nil)
 	})
 
-	e.GET("/ui", func(c echo.Context) error {
-		return c.File("src/templates/ui/index.html")
-	})
+	e.FileFS("/ui", assets.TemplatesDir+"/ui/index.html", files)
 }
 
 // Template is a custom html/template renderer for Echo framework
@@ -163,10 +304,10 @@ type Template struct {
 
 // Render renders a template document
 func (t *Template) Render(w io.Writer, name string, data any, c echo.Context) error {
-	log.Printf("Attempting to render template: %s", name)
+	slog.DebugContext(c.Request().Context(), "Rendering template", "template", name)
 	err := t.templates.ExecuteTemplate(w, name, data)
 	if err != nil {
-		log.Printf("Error rendering template %s: %v", name, err)
+		slog.ErrorContext(c.Request().Context(), "Error rendering template", "template", name, "error", err)
 	}
 	return err
 }
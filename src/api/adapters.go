@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/logging"
+	"gen-ai-proxy/src/routing"
+)
+
+// providerAdapter returns the adapter of a provider when it speaks the
+// protocol of an endpoint, so requests pass through untranslated.
+func providerAdapter(provider database.Provider, protocol llm.Protocol) (llm.Adapter, bool) {
+	adapter, ok := llm.AdapterFor(llm.ProviderType(provider.Type))
+	if !ok || adapter.Protocol() != protocol {
+		return nil, false
+	}
+	return adapter, true
+}
+
+// unsupportedProvider is the error of an endpoint that cannot reach a
+// route's provider.
+func unsupportedProvider(provider database.Provider) ErrorResponse {
+	return ErrorResponse{Error: fmt.Sprintf("This endpoint does not support %s providers", provider.Type)}
+}
+
+// adapterTarget is where a route's upstream calls go.
+func adapterTarget(provider database.Provider, model database.Model, apiKey string) llm.Target {
+	return llm.Target{
+		BaseURL:    provider.BaseUrl,
+		APIVersion: provider.ApiVersion,
+		Model:      model.ProviderModelID,
+		APIKey:     apiKey,
+	}
+}
+
+// newUpstreamRequest builds the request of an operation for a route,
+// carrying the request ID.
+func newUpstreamRequest(ctx context.Context, adapter llm.Adapter, route routing.Route, op llm.Operation, body []byte) (*http.Request, error) {
+	req, err := adapter.NewRequest(ctx, adapterTarget(route.Provider, route.Model, route.APIKey), op, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(RequestIDHeader, logging.RequestID(ctx))
+	return req, nil
+}
+
+// upstreamClient is the HTTP client of an adapter's requests: the proxy's
+// client, or one answering in process for adapters such as the mock
+// provider's. Either way UPSTREAM_TIMEOUT applies.
+func (s *Service) upstreamClient(adapter llm.Adapter) *http.Client {
+	client := s.httpClient.Load()
+	transporter, ok := adapter.(llm.Transporter)
+	if !ok {
+		return client
+	}
+	inProcess := *client
+	inProcess.Transport = transporter.Transport()
+	return &inProcess
+}
+
+// upstreamErrorBody logs an upstream error and returns the body to pass on:
+// the provider's own JSON error, or the normalized message of anything else.
+func upstreamErrorBody(ctx context.Context, adapter llm.Adapter, status int, body []byte) []byte {
+	upstreamErr := adapter.NormalizeError(status, body)
+	slog.WarnContext(ctx, "Upstream provider error", "status", status, "type", upstreamErr.Type, "message", upstreamErr.Message)
+	if json.Valid(body) {
+		return body
+	}
+	normalized, _ := json.Marshal(ErrorResponse{Error: upstreamErr.Message})
+	return normalized
+}
@@ -3,7 +3,7 @@ package api
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -37,6 +37,11 @@ type APIKeyResponse struct {
 	Name       string    `json:"name"`
 	CreatedAt  time.Time `json:"created_at"`
 	LastUsedAt time.Time `json:"last_used_at"`
+	Managed    bool      `json:"managed"`
+	// AllowedModels is empty when the key may use every model.
+	AllowedModels []string `json:"allowed_models"`
+	// MonthlyBudget is null when the key's spend is unlimited.
+	MonthlyBudget *float64 `json:"monthly_budget"`
 }
 
 
@@ -65,7 +70,6 @@ func (s *Service) ListAPIKeys(c echo.Context) error {
 
 	apiKeys := make([]APIKeyResponse, len(dbAPIKeys))
 	for i, dbAPIKey := range dbAPIKeys {
-		// log.Printf("ListAPIKeys: Processing API Key ID: %v, Name: %s", dbAPIKey.ID, dbAPIKey.Name) // Debug log
 		apiKeys[i] = APIKeyResponse{
 			ID:         dbAPIKey.ID,
 			Name:       dbAPIKey.Name,
@@ -81,6 +85,13 @@ func (s *Service) ListAPIKeys(c echo.Context) error {
 				}
 				return time.Time{}
 			}(),
+			Managed:       dbAPIKey.Managed,
+			AllowedModels: dbAPIKey.AllowedModels,
+		}
+		if dbAPIKey.MonthlyBudget.Valid {
+			if budget, err := dbAPIKey.MonthlyBudget.Float64Value(); err == nil {
+				apiKeys[i].MonthlyBudget = &budget.Float64
+			}
 		}
 	}
 
@@ -132,6 +143,8 @@ func (s *Service) CreateAPIKey(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create api key"})
 	}
 
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceAPIKey, dbAPIKey.ID.String(), nil, dbAPIKey)
+
 	return c.JSON(http.StatusCreated, CreateAPIKeyResponse{
 		APIKey: apiKey,
 		Name:   dbAPIKey.Name,
@@ -151,16 +164,17 @@ func (s *Service) CreateAPIKey(c echo.Context) error {
 // @Failure 400 {object} api.ErrorResponse
 // @Failure 401 {object} api.ErrorResponse
 // @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Security BearerAuth
 // @Router /api/api-keys/{id} [delete]
 func (s *Service) DeleteAPIKey(c echo.Context) error {
 	idStr := c.Param("id")
-	log.Printf("DeleteAPIKey: Received ID string: %s", idStr) // Debug log
+	slog.DebugContext(c.Request().Context(), "DeleteAPIKey: received ID", "id", idStr)
 
 	parsedID, err := uuid.Parse(idStr)
 	if err != nil {
-		log.Printf("DeleteAPIKey: Error parsing ID '%s': %v", idStr, err) // Debug log
+		slog.InfoContext(c.Request().Context(), "DeleteAPIKey: invalid ID", "id", idStr, "error", err)
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid API Key ID format"})
 	}
 
@@ -171,6 +185,17 @@ func (s *Service) DeleteAPIKey(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
 	}
 
+	before, err := s.db.GetAPIKeyByID(c.Request().Context(), database.GetAPIKeyByIDParams{
+		ID:     apiKeyID,
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "API key not found"})
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("API key"))
+	}
+
 	err = s.db.DeleteAPIKey(c.Request().Context(), database.DeleteAPIKeyParams{
 		ID: apiKeyID,
 		UserID: userID,
@@ -179,5 +204,7 @@ func (s *Service) DeleteAPIKey(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete api key"})
 	}
 
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourceAPIKey, apiKeyID.String(), before, nil)
+
 	return c.NoContent(http.StatusNoContent)
 }
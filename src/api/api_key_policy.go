@@ -0,0 +1,54 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// apiKeyPolicyViolation enforces the model allow-list and monthly budget of
+// the API key that authenticated the request. It returns a status of 0 when
+// the request may proceed.
+func (s *Service) apiKeyPolicyViolation(c echo.Context, model database.Model) (int, string) {
+	apiKey, ok := c.Get(apiKeyRecordContextKey).(database.ApiKey)
+	if !ok {
+		return 0, ""
+	}
+
+	if len(apiKey.AllowedModels) > 0 && !slices.Contains(apiKey.AllowedModels, model.ProxyModelID) {
+		return http.StatusForbidden, "API key is not allowed to use model " + model.ProxyModelID
+	}
+
+	if !apiKey.MonthlyBudget.Valid {
+		return 0, ""
+	}
+	budget, err := apiKey.MonthlyBudget.Float64Value()
+	if err != nil {
+		return http.StatusInternalServerError, "failed to read API key budget"
+	}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	spend, err := s.db.GetAPIKeySpend(c.Request().Context(), database.GetAPIKeySpendParams{
+		ApiKeyID: apiKey.ID,
+		Since:    pgtype.Timestamptz{Time: monthStart, Valid: true},
+	})
+	if err != nil {
+		// Fail closed: a budget that cannot be checked must not be exceeded.
+		slog.ErrorContext(c.Request().Context(), "Failed to compute API key spend", "api_key_id", apiKey.ID.String(), "error", err)
+		return http.StatusInternalServerError, "failed to check API key budget"
+	}
+	spent, err := spend.Float64Value()
+	if err != nil {
+		return http.StatusInternalServerError, "failed to check API key budget"
+	}
+	if spent.Float64 >= budget.Float64 {
+		return http.StatusTooManyRequests, "monthly budget of this API key is exhausted"
+	}
+	return 0, ""
+}
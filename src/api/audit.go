@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"gen-ai-proxy/src/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionCancel = "cancel"
+
+	AuditResourceUser            = "user"
+	AuditResourceAPIKey          = "api_key"
+	AuditResourceConnection      = "connection"
+	AuditResourceProvider        = "provider"
+	AuditResourceModel           = "model"
+	AuditResourceRetentionPolicy = "retention_policy"
+	AuditResourceConversationLog = "conversation_log"
+	AuditResourcePromptTemplate  = "prompt_template"
+	AuditResourceEvalRun         = "eval_run"
+	AuditResourceFile            = "file"
+	AuditResourceBatch           = "batch"
+
+	redactedValue = "[REDACTED]"
+)
+
+// auditSecretFields lists JSON keys whose values never reach the audit trail.
+var auditSecretFields = map[string]bool{
+	"api_key":           true,
+	"encrypted_api_key": true,
+	"key_hash":          true,
+	"password":          true,
+	"password_hash":     true,
+}
+
+// recordAudit appends an entry to the audit trail for a mutating call made by actorID.
+// before and after are snapshots of the resource and may be nil. Failures are logged
+// and never fail the request, because the change itself has already been applied.
+func (s *Service) recordAudit(c echo.Context, actorID pgtype.UUID, action, resourceType, resourceID string, before, after any) {
+	params := database.CreateAuditLogParams{
+		ActorID:      actorID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       redactAuditSnapshot(before),
+		After:        redactAuditSnapshot(after),
+		SourceIp:     c.RealIP(),
+		UserAgent:    c.Request().UserAgent(),
+	}
+
+	if _, err := s.db.CreateAuditLog(context.Background(), params); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Error writing audit log", "action", action, "resource_type", resourceType, "resource_id", resourceID, "error", err)
+	}
+}
+
+// redactAuditSnapshot serializes v to JSON with all secret fields masked.
+func redactAuditSnapshot(v any) []byte {
+	if v == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Error marshaling audit snapshot", "error", err)
+		return nil
+	}
+
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+
+	redacted, err := json.Marshal(redactSecretFields(data))
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+func redactSecretFields(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, field := range val {
+			if auditSecretFields[k] {
+				val[k] = redactedValue
+				continue
+			}
+			val[k] = redactSecretFields(field)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = redactSecretFields(item)
+		}
+		return val
+	default:
+		return val
+	}
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+type AuditLogResponse struct {
+	ID           pgtype.UUID     `json:"id"`
+	ActorID      pgtype.UUID     `json:"actor_id"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After        json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	SourceIP     string          `json:"source_ip"`
+	UserAgent    string          `json:"user_agent"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+type ListAuditLogsRequest struct {
+	Page         int64  `query:"page"`
+	Limit        int64  `query:"limit"`
+	Action       string `query:"action"`
+	ResourceType string `query:"resource_type"`
+	ResourceID   string `query:"resource_id"`
+	Since        string `query:"since"`
+	Until        string `query:"until"`
+}
+
+type ListAuditLogsResponse struct {
+	AuditLogs []AuditLogResponse `json:"audit_logs"`
+	Total     int64              `json:"total"`
+}
+
+// ListAuditLogs godoc
+// @Summary List audit logs
+// @Schemes
+// @Description List management-plane changes made by the authenticated user, newest first.
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(50)
+// @Param action query string false "Filter by action (create, update, delete, cancel)"
+// @Param resource_type query string false "Filter by resource type"
+// @Param resource_id query string false "Filter by resource ID"
+// @Param since query string false "Only entries at or after this RFC3339 timestamp"
+// @Param until query string false "Only entries before this RFC3339 timestamp"
+// @Success 200 {object} ListAuditLogsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/audit [get]
+func (s *Service) ListAuditLogs(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req ListAuditLogsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Limit == 0 {
+		req.Limit = 50
+	}
+
+	filter, err := buildAuditFilter(userID, req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	params := database.ListAuditLogsParams{
+		ActorID:      filter.ActorID,
+		Action:       filter.Action,
+		ResourceType: filter.ResourceType,
+		ResourceID:   filter.ResourceID,
+		Since:        filter.Since,
+		Until:        filter.Until,
+		Limit:        pgtype.Int8{Int64: req.Limit, Valid: true},
+		Offset:       pgtype.Int8{Int64: (req.Page - 1) * req.Limit, Valid: true},
+	}
+
+	entries, err := s.db.ListAuditLogs(c.Request().Context(), params)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve audit logs"})
+	}
+
+	total, err := s.db.CountAuditLogs(c.Request().Context(), filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to count audit logs"})
+	}
+
+	respEntries := make([]AuditLogResponse, len(entries))
+	for i, entry := range entries {
+		respEntries[i] = toAuditLogResponse(entry)
+	}
+
+	return c.JSON(http.StatusOK, ListAuditLogsResponse{
+		AuditLogs: respEntries,
+		Total:     total,
+	})
+}
+
+// ExportAuditLogs godoc
+// @Summary Export audit logs as JSONL
+// @Schemes
+// @Description Export all matching audit log entries as newline-delimited JSON, newest first.
+// @Tags Audit
+// @Produce application/x-ndjson
+// @Param action query string false "Filter by action (create, update, delete, cancel)"
+// @Param resource_type query string false "Filter by resource type"
+// @Param resource_id query string false "Filter by resource ID"
+// @Param since query string false "Only entries at or after this RFC3339 timestamp"
+// @Param until query string false "Only entries before this RFC3339 timestamp"
+// @Success 200 {string} string "JSONL stream of audit log entries"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/audit/export [get]
+func (s *Service) ExportAuditLogs(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req ListAuditLogsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	filter, err := buildAuditFilter(userID, req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	// A NULL limit exports every matching entry.
+	entries, err := s.db.ListAuditLogs(c.Request().Context(), database.ListAuditLogsParams{
+		ActorID:      filter.ActorID,
+		Action:       filter.Action,
+		ResourceType: filter.ResourceType,
+		ResourceID:   filter.ResourceID,
+		Since:        filter.Since,
+		Until:        filter.Until,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve audit logs"})
+	}
+
+	filename := "audit-" + time.Now().UTC().Format("20060102T150405Z") + ".jsonl"
+	c.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=\""+filename+"\"")
+	c.Response().WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(c.Response())
+	for _, entry := range entries {
+		if err := enc.Encode(toAuditLogResponse(entry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildAuditFilter converts query parameters into the shared audit filter.
+func buildAuditFilter(userID pgtype.UUID, req ListAuditLogsRequest) (database.CountAuditLogsParams, error) {
+	filter := database.CountAuditLogsParams{
+		ActorID:      userID,
+		Action:       pgtype.Text{String: req.Action, Valid: req.Action != ""},
+		ResourceType: pgtype.Text{String: req.ResourceType, Valid: req.ResourceType != ""},
+		ResourceID:   pgtype.Text{String: req.ResourceID, Valid: req.ResourceID != ""},
+	}
+
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return filter, errors.New("since must be an RFC3339 timestamp")
+		}
+		filter.Since = pgtype.Timestamptz{Time: since, Valid: true}
+	}
+
+	if req.Until != "" {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			return filter, errors.New("until must be an RFC3339 timestamp")
+		}
+		filter.Until = pgtype.Timestamptz{Time: until, Valid: true}
+	}
+
+	return filter, nil
+}
+
+func toAuditLogResponse(entry database.AuditLog) AuditLogResponse {
+	return AuditLogResponse{
+		ID:           entry.ID,
+		ActorID:      entry.ActorID,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		Before:       json.RawMessage(entry.Before),
+		After:        json.RawMessage(entry.After),
+		SourceIP:     entry.SourceIp,
+		UserAgent:    entry.UserAgent,
+		CreatedAt:    entry.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/llm"
+)
+
+// azureFilterResults are the content filter results of one Azure OpenAI
+// response or stream chunk. A request rejected by the filter reports them in
+// its error instead.
+type azureFilterResults struct {
+	PromptFilterResults []json.RawMessage `json:"prompt_filter_results"`
+	Choices             []struct {
+		Index                int             `json:"index"`
+		ContentFilterResults json.RawMessage `json:"content_filter_results"`
+	} `json:"choices"`
+	Error *struct {
+		InnerError *struct {
+			ContentFilterResult json.RawMessage `json:"content_filter_result"`
+		} `json:"innererror"`
+	} `json:"error"`
+}
+
+// contentFilter returns the content filter results of an Azure OpenAI
+// response, streamed or not, for the conversation log: the prompt's results,
+// the last results of each choice, or the results the request was rejected
+// with. It is nil for other providers and when nothing was reported.
+func contentFilter(provider database.Provider, body []byte) []byte {
+	if llm.ProviderType(provider.Type) != llm.ProviderAzureOpenAI {
+		return nil
+	}
+	var prompt []json.RawMessage
+	var rejected json.RawMessage
+	choices := map[int]json.RawMessage{}
+	add := func(data []byte) {
+		var r azureFilterResults
+		if json.Unmarshal(data, &r) != nil {
+			return
+		}
+		prompt = append(prompt, r.PromptFilterResults...)
+		for _, choice := range r.Choices {
+			if len(choice.ContentFilterResults) > 0 && string(choice.ContentFilterResults) != "{}" {
+				choices[choice.Index] = choice.ContentFilterResults
+			}
+		}
+		if r.Error != nil && r.Error.InnerError != nil && len(r.Error.InnerError.ContentFilterResult) > 0 {
+			rejected = r.Error.InnerError.ContentFilterResult
+		}
+	}
+
+	if json.Valid(body) {
+		add(body)
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+				add([]byte(strings.TrimSpace(data)))
+			}
+		}
+	}
+
+	if len(prompt) == 0 && len(choices) == 0 && rejected == nil {
+		return nil
+	}
+	results := map[string]any{}
+	if len(prompt) > 0 {
+		results["prompt"] = prompt
+	}
+	if len(choices) > 0 {
+		results["choices"] = choices
+	}
+	if rejected != nil {
+		results["rejected"] = rejected
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gen-ai-proxy/src/batch"
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+type batchKey struct{}
+
+// withBatch records the batch a request is executed for, so saveLog can
+// store it with the conversation.
+func withBatch(ctx context.Context, batchID pgtype.UUID) context.Context {
+	return context.WithValue(ctx, batchKey{}, batchID)
+}
+
+// batchLogField returns the log column identifying the batch in ctx.
+func batchLogField(ctx context.Context) pgtype.UUID {
+	batchID, _ := ctx.Value(batchKey{}).(pgtype.UUID)
+	return batchID
+}
+
+// ExecuteBatchRequest runs one line of a batch through the same handler as a
+// live request, authenticated as the API key that created the batch, so it is
+// routed, checked against the key's policy and logged the same way.
+func (s *Service) ExecuteBatchRequest(ctx context.Context, b database.Batch, r database.BatchRequest) (batch.Response, error) {
+	var handler echo.HandlerFunc
+	switch b.Endpoint {
+	case batch.EndpointChatCompletions:
+		handler = s.ProxyOpenAIChat
+	case batch.EndpointEmbeddings:
+		handler = s.ProxyOpenAIEmbedding
+	case batch.EndpointResponses:
+		handler = s.ProxyOpenAIResponses
+	default:
+		return batch.Response{}, fmt.Errorf("unsupported batch endpoint %q", b.Endpoint)
+	}
+
+	requestID := uuid.NewString()
+	apiKey, err := s.db.GetAPIKeyByID(ctx, database.GetAPIKeyByIDParams{ID: b.ApiKeyID, UserID: b.UserID})
+	if errors.Is(err, sql.ErrNoRows) {
+		// The key was deleted after the batch was created.
+		return batch.Response{StatusCode: http.StatusUnauthorized, RequestID: requestID, Body: []byte(`{"error":"Invalid API Key"}`)}, nil
+	}
+	if err != nil {
+		return batch.Response{}, fmt.Errorf("load API key: %w", err)
+	}
+
+	ctx = logging.WithRequestID(withBatch(withRequestStart(ctx, time.Now()), b.ID), requestID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/api"+b.Endpoint, bytes.NewReader(r.Body))
+	if err != nil {
+		return batch.Response{}, err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(RequestIDHeader, requestID)
+
+	rec := &responseRecorder{header: http.Header{}}
+	c := s.batchEcho.NewContext(req, rec)
+	c.Set(userContextKey, apiKey.UserID)
+	c.Set(apiKeyContextKey, apiKey.ID)
+	c.Set(apiKeyRecordContextKey, apiKey)
+	if err := handler(c); err != nil {
+		s.batchEcho.HTTPErrorHandler(err, c)
+	}
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return batch.Response{StatusCode: rec.status, RequestID: requestID, Body: rec.body.Bytes()}, nil
+}
+
+// responseRecorder captures the response of a handler run for a batch.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+// Flush is a no-op; the response is only read once the handler returns.
+func (r *responseRecorder) Flush() {}
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gen-ai-proxy/src/batch"
+	"gen-ai-proxy/src/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// maxBatchMetadata is the number of metadata pairs OpenAI accepts.
+const maxBatchMetadata = 16
+
+type CreateBatchRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// OpenAIBatch is a batch as returned by the OpenAI batch API. Timestamps are
+// Unix seconds.
+type OpenAIBatch struct {
+	ID               pgtype.UUID        `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileID      pgtype.UUID        `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileID     pgtype.UUID        `json:"output_file_id"`
+	ErrorFileID      pgtype.UUID        `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        int64              `json:"expires_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+// BatchErrors lists why the input file of a failed batch was rejected.
+type BatchErrors struct {
+	Object string            `json:"object"`
+	Data   []batch.LineError `json:"data"`
+}
+
+type BatchRequestCounts struct {
+	Total     int64 `json:"total"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+}
+
+type ListBatchesRequest struct {
+	After string `query:"after"`
+	Limit int64  `query:"limit"`
+}
+
+type ListBatchesResponse struct {
+	Object  string        `json:"object"`
+	Data    []OpenAIBatch `json:"data"`
+	FirstID *pgtype.UUID  `json:"first_id"`
+	LastID  *pgtype.UUID  `json:"last_id"`
+	HasMore bool          `json:"has_more"`
+}
+
+// CreateBatch godoc
+// @Summary Create a batch
+// @Schemes
+// @Description Run the requests of an uploaded JSONL file in the background, as with the OpenAI batch API. Each line is executed through the proxy with the API key that created the batch, retried on errors, 429 and 5xx responses, and logged with the batch ID. A file with invalid lines creates a failed batch listing them.
+// @Tags Batches
+// @Accept json
+// @Produce json
+// @Param batch body CreateBatchRequest true "Input file, endpoint (/v1/chat/completions, /v1/embeddings or /v1/responses) and completion window (24h)"
+// @Success 200 {object} OpenAIBatch
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/batches [post]
+func (s *Service) CreateBatch(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req CreateBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if !batch.ValidEndpoint(req.Endpoint) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("endpoint must be %s, %s or %s", batch.EndpointChatCompletions, batch.EndpointEmbeddings, batch.EndpointResponses)})
+	}
+	if req.CompletionWindow != batch.CompletionWindow {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "completion_window must be " + batch.CompletionWindow})
+	}
+	if len(req.Metadata) > maxBatchMetadata {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("metadata can have at most %d keys", maxBatchMetadata)})
+	}
+	var metadata []byte
+	if req.Metadata != nil {
+		metadata, _ = json.Marshal(req.Metadata)
+	}
+
+	ctx := c.Request().Context()
+	file, err := s.getFile(c, userID, req.InputFileID)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && file.Purpose != purposeBatch {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "input_file_id must be the ID of a file uploaded with purpose batch"})
+	}
+	if err != nil {
+		return fileError(c, err)
+	}
+	content, err := s.db.GetFileContent(ctx, database.GetFileContentParams{ID: file.ID, UserID: userID})
+	if err != nil {
+		return fileError(c, err)
+	}
+
+	now := time.Now()
+	params := database.CreateBatchParams{
+		ID:               pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:           userID,
+		ApiKeyID:         GetAPIKeyIDFromContext(c),
+		Endpoint:         req.Endpoint,
+		InputFileID:      file.ID,
+		CompletionWindow: req.CompletionWindow,
+		Status:           batch.StatusInProgress,
+		Metadata:         metadata,
+		ExpiresAt:        pgtype.Timestamptz{Time: now.Add(24 * time.Hour), Valid: true},
+	}
+
+	requests, lineErrors := batch.ParseInput(content, req.Endpoint)
+	if len(lineErrors) > 0 {
+		params.Status = batch.StatusFailed
+		params.Errors, _ = json.Marshal(lineErrors)
+		params.FinishedAt = pgtype.Timestamptz{Time: now, Valid: true}
+		requests = nil
+	}
+
+	var created database.Batch
+	err = s.db.ExecTx(ctx, func(q database.Querier) error {
+		var err error
+		created, err = q.CreateBatch(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, r := range requests {
+			err := q.CreateBatchRequest(ctx, database.CreateBatchRequestParams{
+				BatchID:  created.ID,
+				Line:     int32(r.Line),
+				CustomID: r.CustomID,
+				Body:     r.Body,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating batch", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create batch"})
+	}
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceBatch, created.ID.String(), nil, created)
+
+	return c.JSON(http.StatusOK, toOpenAIBatch(created, BatchRequestCounts{Total: int64(len(requests))}))
+}
+
+// ListBatches godoc
+// @Summary List batches
+// @Schemes
+// @Description List batches, newest first, as with the OpenAI batch API.
+// @Tags Batches
+// @Produce json
+// @Param after query string false "ID of the last batch of the previous page"
+// @Param limit query int false "Number of batches per page (max 100)" default(20)
+// @Success 200 {object} ListBatchesResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/batches [get]
+func (s *Service) ListBatches(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req ListBatchesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	req.Limit = min(req.Limit, maxFilesPageSize)
+
+	ctx := c.Request().Context()
+	params := database.ListBatchesParams{UserID: userID, Limit: req.Limit + 1}
+	if req.After != "" {
+		after, err := s.getBatch(c, userID, req.After)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "after must be the ID of a batch"})
+		}
+		params.CursorCreatedAt, params.CursorID = after.CreatedAt, after.ID
+	}
+
+	batches, err := s.db.ListBatches(ctx, params)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve batches"})
+	}
+
+	resp := ListBatchesResponse{Object: "list", Data: []OpenAIBatch{}}
+	if int64(len(batches)) > req.Limit {
+		batches, resp.HasMore = batches[:req.Limit], true
+	}
+	for _, b := range batches {
+		counts, err := s.batchRequestCounts(c, b.ID)
+		if err != nil {
+			return batchError(c, err)
+		}
+		resp.Data = append(resp.Data, toOpenAIBatch(b, counts))
+	}
+	if len(batches) > 0 {
+		resp.FirstID, resp.LastID = &batches[0].ID, &batches[len(batches)-1].ID
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetBatch godoc
+// @Summary Get a batch
+// @Schemes
+// @Description Get a batch with its progress. Once it has finished, its results are in the output and error files.
+// @Tags Batches
+// @Produce json
+// @Param id path string true "Batch ID"
+// @Success 200 {object} OpenAIBatch
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/batches/{id} [get]
+func (s *Service) GetBatch(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	b, err := s.getBatch(c, userID, c.Param("id"))
+	if err != nil {
+		return batchError(c, err)
+	}
+	counts, err := s.batchRequestCounts(c, b.ID)
+	if err != nil {
+		return batchError(c, err)
+	}
+	return c.JSON(http.StatusOK, toOpenAIBatch(b, counts))
+}
+
+// CancelBatch godoc
+// @Summary Cancel a batch
+// @Schemes
+// @Description Cancel a batch in progress. Requests not started yet are skipped; the batch is cancelled once the running ones have finished, with the results obtained so far.
+// @Tags Batches
+// @Produce json
+// @Param id path string true "Batch ID"
+// @Success 200 {object} OpenAIBatch
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/batches/{id}/cancel [post]
+func (s *Service) CancelBatch(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	b, err := s.getBatch(c, userID, c.Param("id"))
+	if err != nil {
+		return batchError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	switch b.Status {
+	case batch.StatusCancelling, batch.StatusCancelled:
+	case batch.StatusInProgress:
+		before := b
+		err = s.db.ExecTx(ctx, func(q database.Querier) error {
+			var err error
+			b, err = q.CancelBatch(ctx, database.CancelBatchParams{ID: b.ID, UserID: userID})
+			if err != nil {
+				return err
+			}
+			_, err = q.CancelBatchRequests(ctx, b.ID)
+			return err
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: "the batch finished before it could be cancelled"})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error cancelling batch", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to cancel batch"})
+		}
+		s.recordAudit(c, userID, AuditActionCancel, AuditResourceBatch, b.ID.String(), before, b)
+	default:
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("cannot cancel a batch with status %s", b.Status)})
+	}
+
+	counts, err := s.batchRequestCounts(c, b.ID)
+	if err != nil {
+		return batchError(c, err)
+	}
+	return c.JSON(http.StatusOK, toOpenAIBatch(b, counts))
+}
+
+// getBatch loads a batch of the user by its ID as given by the client.
+func (s *Service) getBatch(c echo.Context, userID pgtype.UUID, rawID string) (database.Batch, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return database.Batch{}, sql.ErrNoRows
+	}
+	return s.db.GetBatch(c.Request().Context(), database.GetBatchParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		UserID: userID,
+	})
+}
+
+func (s *Service) batchRequestCounts(c echo.Context, batchID pgtype.UUID) (BatchRequestCounts, error) {
+	rows, err := s.db.CountBatchRequests(c.Request().Context(), batchID)
+	if err != nil {
+		return BatchRequestCounts{}, err
+	}
+	var counts BatchRequestCounts
+	for _, row := range rows {
+		counts.Total += row.Count
+		switch row.Status {
+		case batch.RequestSucceeded:
+			counts.Completed += row.Count
+		case batch.RequestFailed:
+			counts.Failed += row.Count
+		}
+	}
+	return counts, nil
+}
+
+func batchError(c echo.Context, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "batch not found"})
+	}
+	slog.ErrorContext(c.Request().Context(), "Error reading batch", "error", err)
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve batch"})
+}
+
+func toOpenAIBatch(b database.Batch, counts BatchRequestCounts) OpenAIBatch {
+	resp := OpenAIBatch{
+		ID:               b.ID,
+		Object:           "batch",
+		Endpoint:         b.Endpoint,
+		InputFileID:      b.InputFileID,
+		CompletionWindow: b.CompletionWindow,
+		Status:           b.Status,
+		OutputFileID:     b.OutputFileID,
+		ErrorFileID:      b.ErrorFileID,
+		CreatedAt:        b.CreatedAt.Time.Unix(),
+		ExpiresAt:        b.ExpiresAt.Time.Unix(),
+		CancellingAt:     unixTime(b.CancellingAt),
+		RequestCounts:    counts,
+	}
+	if b.Status != batch.StatusFailed {
+		// Batches start as soon as they are created.
+		resp.InProgressAt = unixTime(b.CreatedAt)
+	}
+	switch b.Status {
+	case batch.StatusCompleted:
+		resp.CompletedAt = unixTime(b.FinishedAt)
+	case batch.StatusFailed:
+		resp.FailedAt = unixTime(b.FinishedAt)
+	case batch.StatusExpired:
+		resp.ExpiredAt = unixTime(b.FinishedAt)
+	case batch.StatusCancelled:
+		resp.CancelledAt = unixTime(b.FinishedAt)
+	}
+	if len(b.Errors) > 0 {
+		resp.Errors = &BatchErrors{Object: "list"}
+		if err := json.Unmarshal(b.Errors, &resp.Errors.Data); err != nil {
+			resp.Errors = nil
+		}
+	}
+	if len(b.Metadata) > 0 {
+		_ = json.Unmarshal(b.Metadata, &resp.Metadata)
+	}
+	return resp
+}
+
+func unixTime(t pgtype.Timestamptz) *int64 {
+	if !t.Valid {
+		return nil
+	}
+	unix := t.Time.Unix()
+	return &unix
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+)
+
+type bedrockRequest struct {
+	Messages        []bedrockMessage   `json:"messages"`
+	System          []bedrockContent   `json:"system,omitempty"`
+	InferenceConfig map[string]any     `json:"inferenceConfig,omitempty"`
+	ToolConfig      *bedrockToolConfig `json:"toolConfig,omitempty"`
+}
+
+type bedrockMessage struct {
+	Role    string           `json:"role"`
+	Content []bedrockContent `json:"content"`
+}
+
+// bedrockContent is a text or tool use content block. Other kinds of blocks,
+// such as reasoning, are ignored.
+type bedrockContent struct {
+	Text    string          `json:"text,omitempty"`
+	ToolUse *bedrockToolUse `json:"toolUse,omitempty"`
+}
+
+type bedrockToolUse struct {
+	ToolUseID string `json:"toolUseId"`
+	Name      string `json:"name"`
+	// Input is a JSON object, or a part of one encoded as a string in the
+	// deltas of a stream.
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type bedrockToolConfig struct {
+	Tools      []bedrockTool  `json:"tools"`
+	ToolChoice map[string]any `json:"toolChoice,omitempty"`
+}
+
+type bedrockTool struct {
+	ToolSpec bedrockToolSpec `json:"toolSpec"`
+}
+
+type bedrockToolSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema struct {
+		JSON map[string]any `json:"json"`
+	} `json:"inputSchema"`
+}
+
+type bedrockResponse struct {
+	Output struct {
+		Message bedrockMessage `json:"message"`
+	} `json:"output"`
+	StopReason string        `json:"stopReason"`
+	Usage      *bedrockUsage `json:"usage"`
+}
+
+type bedrockUsage struct {
+	InputTokens  int64 `json:"inputTokens"`
+	OutputTokens int64 `json:"outputTokens"`
+	TotalTokens  int64 `json:"totalTokens"`
+}
+
+// bedrockStreamEvent is the payload of any ConverseStream event; which
+// fields are set depends on the event type.
+type bedrockStreamEvent struct {
+	ContentBlockIndex int `json:"contentBlockIndex"`
+	Start             struct {
+		ToolUse *bedrockToolUse `json:"toolUse"`
+	} `json:"start"`
+	Delta struct {
+		Text    string `json:"text"`
+		ToolUse *struct {
+			Input string `json:"input"`
+		} `json:"toolUse"`
+	} `json:"delta"`
+	StopReason string        `json:"stopReason"`
+	Usage      *bedrockUsage `json:"usage"`
+	Message    string        `json:"message"`
+}
+
+// bedrockParams maps parameter policy names to Converse's inferenceConfig.
+var bedrockParams = map[string]string{
+	"temperature": "temperature",
+	"top_p":       "topP",
+	"max_tokens":  "maxTokens",
+}
+
+// bedrockURL returns the URL of a Converse operation of a model.
+func bedrockURL(provider database.Provider, model database.Model, operation string) string {
+	return provider.BaseUrl + "/model/" + url.PathEscape(model.ProviderModelID) + "/" + operation
+}
+
+// bedrockChatRequest translates a chat completion request to Converse. System
+// messages become the system prompt; consecutive messages of the same role
+// are merged, as Converse expects turns to alternate. The returned error is
+// meant for the client.
+func bedrockChatRequest(call *chatCall, messages []ChatCompletionMessage) (bedrockRequest, error) {
+	var req bedrockRequest
+	for _, m := range messages {
+		if m.Content == "" {
+			continue
+		}
+		block := bedrockContent{Text: m.Content}
+		role := "user"
+		switch m.Role {
+		case "system", "developer":
+			req.System = append(req.System, block)
+			continue
+		case "assistant":
+			role = "assistant"
+		}
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, block)
+			continue
+		}
+		req.Messages = append(req.Messages, bedrockMessage{Role: role, Content: []bedrockContent{block}})
+	}
+
+	config := map[string]any{}
+	for name, v := range call.params {
+		switch name {
+		case "tools", "tool_choice":
+		case "stop":
+			if stop, ok := v.(string); ok {
+				config["stopSequences"] = []string{stop}
+			} else {
+				config["stopSequences"] = v
+			}
+		default:
+			field, ok := bedrockParams[name]
+			if !ok {
+				return bedrockRequest{}, fmt.Errorf("%s is not supported with Bedrock providers", name)
+			}
+			config[field] = v
+		}
+	}
+	if len(config) > 0 {
+		req.InferenceConfig = config
+	}
+
+	var err error
+	req.ToolConfig, err = bedrockTools(call.params["tools"], call.params["tool_choice"])
+	if err != nil {
+		return bedrockRequest{}, err
+	}
+	return req, nil
+}
+
+// bedrockTools translates OpenAI function tools and tool_choice. Converse
+// cannot forbid tool use, so tool_choice none leaves the tools out.
+func bedrockTools(tools, choice any) (*bedrockToolConfig, error) {
+	functions, err := functionTools(tools)
+	if err != nil || len(functions) == 0 {
+		return nil, err
+	}
+	mode, name, err := parseToolChoice(choice)
+	if err != nil || mode == "none" {
+		return nil, err
+	}
+
+	config := &bedrockToolConfig{}
+	for _, function := range functions {
+		spec := bedrockToolSpec{Name: function.Name, Description: function.Description}
+		spec.InputSchema.JSON = function.Parameters
+		if spec.InputSchema.JSON == nil {
+			spec.InputSchema.JSON = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		config.Tools = append(config.Tools, bedrockTool{ToolSpec: spec})
+	}
+	switch mode {
+	case "auto":
+		config.ToolChoice = map[string]any{"auto": map[string]any{}}
+	case "required":
+		config.ToolChoice = map[string]any{"any": map[string]any{}}
+	case "function":
+		config.ToolChoice = map[string]any{"tool": map[string]any{"name": name}}
+	}
+	return config, nil
+}
+
+// chatCompletion translates a Converse response.
+func (r bedrockResponse) chatCompletion(model string, created int64) ChatCompletionResponse {
+	var text strings.Builder
+	var toolCalls []ChatCompletionToolCall
+	for _, block := range r.Output.Message.Content {
+		if block.ToolUse != nil {
+			index := len(toolCalls)
+			toolCalls = append(toolCalls, bedrockToolCall(index, *block.ToolUse, toolUseArguments(block.ToolUse.Input)))
+			continue
+		}
+		text.WriteString(block.Text)
+	}
+	return ChatCompletionResponse{
+		ID:      completionID(""),
+		Object:  "chat.completion",
+		Created: created,
+		Model:   model,
+		Choices: []ChatCompletionChoice{{
+			Message:      ChatCompletionResponseMessage{Role: "assistant", Content: text.String(), ToolCalls: toolCalls},
+			FinishReason: bedrockFinishReason(r.StopReason),
+		}},
+		Usage: r.Usage.chatUsage(),
+	}
+}
+
+func bedrockToolCall(index int, toolUse bedrockToolUse, arguments string) ChatCompletionToolCall {
+	return ChatCompletionToolCall{
+		Index:    &index,
+		ID:       toolUse.ToolUseID,
+		Type:     "function",
+		Function: ChatCompletionFunctionCall{Name: toolUse.Name, Arguments: arguments},
+	}
+}
+
+func toolUseArguments(input json.RawMessage) string {
+	if len(input) == 0 || string(input) == "null" {
+		return "{}"
+	}
+	return string(input)
+}
+
+func (u *bedrockUsage) chatUsage() *ChatCompletionUsage {
+	if u == nil {
+		return nil
+	}
+	return &ChatCompletionUsage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
+
+// bedrockFinishReason maps a Converse stop reason to OpenAI's finish reason.
+func bedrockFinishReason(reason string) string {
+	switch reason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens", "model_context_window_exceeded":
+		return "length"
+	case "guardrail_intervened", "content_filtered":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+// bedrockStreamError is the error of an exception event, which ends a stream.
+func bedrockStreamError(exceptionType string, payload []byte) error {
+	var event bedrockStreamEvent
+	if json.Unmarshal(payload, &event) == nil && event.Message != "" {
+		return fmt.Errorf("%s: %s", exceptionType, event.Message)
+	}
+	if exceptionType == "" {
+		return errors.New("bedrock stream error")
+	}
+	return errors.New(exceptionType)
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gen-ai-proxy/src/awssig"
+	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/encryption"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+var bedrockTestCreds = awssig.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+const bedrockTestModel = "anthropic.claude-3-haiku-20240307-v1:0"
+
+// newBedrockUpstream stands in for the Bedrock runtime API. It rejects a
+// request whose signature does not verify, and answers Converse with
+// converse and ConverseStream with the events of stream.
+func newBedrockUpstream(t *testing.T, converse string, stream [][2]string) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := awssig.Verify(r, body, bedrockTestCreds, "us-west-2", "bedrock"); err != nil {
+			t.Errorf("%s: %v", r.URL.Path, err)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"The request signature we calculated does not match the signature you provided."}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/model/" + bedrockTestModel + "/converse":
+			w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			w.Write([]byte(converse))
+		case "/model/" + bedrockTestModel + "/converse-stream":
+			w.Header().Set(echo.HeaderContentType, "application/vnd.amazon.eventstream")
+			for _, event := range stream {
+				w.Write(eventStreamMessage(event[0], event[1]))
+			}
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// eventStreamMessage frames a ConverseStream event.
+func eventStreamMessage(eventType, payload string) []byte {
+	var headers []byte
+	for _, h := range [][2]string{{":event-type", eventType}, {":content-type", "application/json"}, {":message-type", "event"}} {
+		headers = append(headers, byte(len(h[0])))
+		headers = append(headers, h[0]...)
+		headers = append(headers, 7)
+		headers = binary.BigEndian.AppendUint16(headers, uint16(len(h[1])))
+		headers = append(headers, h[1]...)
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(12+len(headers)+len(payload)+4))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(headers)))
+	b = binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b))
+	b = append(b, headers...)
+	b = append(b, payload...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b))
+}
+
+// addBedrockModel adds a model served by a Bedrock provider in us-west-2 at
+// baseURL, and reloads the routing table.
+func (env *testEnv) addBedrockModel(baseURL, proxyModelID string) {
+	env.t.Helper()
+	ctx := context.Background()
+	provider, err := env.store.CreateProvider(ctx, database.CreateProviderParams{
+		ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, UserID: env.user, Name: "bedrock", BaseUrl: baseURL,
+		Type: "bedrock", Region: "us-west-2",
+	})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	apiKey, err := encryption.Encrypt(env.key, []byte(bedrockTestCreds.AccessKeyID+":"+bedrockTestCreds.SecretAccessKey))
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	conn, err := env.store.CreateConnection(ctx, database.CreateConnectionParams{
+		UserID: env.user, ProviderID: provider.ID.String(), EncryptedApiKey: apiKey, Name: "bedrock",
+	})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	if _, err := env.store.CreateModel(ctx, database.CreateModelParams{
+		ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, UserID: env.user, ConnectionID: conn.ID,
+		ProxyModelID: proxyModelID, ProviderModelID: bedrockTestModel, Type: "llm",
+		PriceInput:  pgtype.Numeric{Int: big.NewInt(1), Exp: -6, Valid: true},
+		PriceOutput: pgtype.Numeric{Int: big.NewInt(2), Exp: -6, Valid: true},
+	}); err != nil {
+		env.t.Fatal(err)
+	}
+	if _, err := env.routes.Refresh(ctx); err != nil {
+		env.t.Fatal(err)
+	}
+}
+
+func TestBedrockConverse(t *testing.T) {
+	upstream := newBedrockUpstream(t, `{"output":{"message":{"role":"assistant","content":[{"text":"Hello! How can I help?"}]}},"stopReason":"end_turn","usage":{"inputTokens":12,"outputTokens":6,"totalTokens":18}}`, nil)
+	env := newTestEnv(t, config.Config{LogPolicyDefault: "full"})
+	env.addBedrockModel(upstream.URL, "claude")
+
+	rec := env.serve(echo.New(), env.s.ProxyOpenAIChat, "/v1/chat/completions",
+		`{"model":"claude","messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "claude" || len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Hello! How can I help?" ||
+		resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("unexpected answer %s", rec.Body)
+	}
+
+	log := env.onlyLog()
+	if log.PromptTokens.Int64 != 12 || log.CompletionTokens.Int64 != 6 || log.StatusCode.Int32 != http.StatusOK {
+		t.Errorf("logged %d prompt and %d completion tokens with status %d, want 12, 6 and 200",
+			log.PromptTokens.Int64, log.CompletionTokens.Int64, log.StatusCode.Int32)
+	}
+}
+
+func TestBedrockConverseStream(t *testing.T) {
+	upstream := newBedrockUpstream(t, "", [][2]string{
+		{"messageStart", `{"role":"assistant"}`},
+		{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hello"}}`},
+		{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":" there!"}}`},
+		{"contentBlockStop", `{"contentBlockIndex":0}`},
+		{"messageStop", `{"stopReason":"end_turn"}`},
+		{"metadata", `{"usage":{"inputTokens":12,"outputTokens":3,"totalTokens":15},"metrics":{"latencyMs":210}}`},
+	})
+	env := newTestEnv(t, config.Config{LogPolicyDefault: "full"})
+	env.addBedrockModel(upstream.URL, "claude")
+
+	rec := env.serve(echo.New(), env.s.ProxyOpenAIChat, "/v1/chat/completions",
+		`{"model":"claude","stream":true,"messages":[{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `"content":"Hello"`) || !strings.Contains(body, `"content":" there!"`) ||
+		!strings.Contains(body, `"prompt_tokens":12`) || !strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]") {
+		t.Errorf("unexpected stream %s", body)
+	}
+
+	log := env.onlyLog()
+	if log.PromptTokens.Int64 != 12 || log.CompletionTokens.Int64 != 3 {
+		t.Errorf("logged %d prompt and %d completion tokens, want 12 and 3", log.PromptTokens.Int64, log.CompletionTokens.Int64)
+	}
+	var received []map[string]json.RawMessage
+	if err := json.Unmarshal(log.ResponsePayload, &received); err != nil || len(received) != 6 || received[5]["metadata"] == nil {
+		t.Errorf("logged response %s, want the six events received", log.ResponsePayload)
+	}
+}
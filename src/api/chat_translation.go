@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/routing"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// chatTranslators serve chat completions for providers that do not speak
+// the OpenAI API, by translating requests and responses.
+var chatTranslators = map[llm.ProviderType]func(*Service, echo.Context, *chatCall) error{
+	llm.ProviderGemini:  (*Service).proxyGeminiChat,
+	llm.ProviderBedrock: (*Service).proxyBedrockChat,
+}
+
+// chatCall is a chat completion request after routing and policies, for
+// providers whose API is translated from and to OpenAI's.
+type chatCall struct {
+	req      ChatCompletionRequest
+	route    routing.Route
+	messages []ChatCompletionMessage
+	params   map[string]any
+	output   *outputCheck
+
+	userID   pgtype.UUID
+	apiKeyID pgtype.UUID
+	logCtx   context.Context
+}
+
+// ChatCompletionResponse is an OpenAI chat completion translated from another
+// provider's response.
+type ChatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []ChatCompletionChoice `json:"choices"`
+	Usage   *ChatCompletionUsage   `json:"usage,omitempty"`
+}
+
+type ChatCompletionChoice struct {
+	Index        int                           `json:"index"`
+	Message      ChatCompletionResponseMessage `json:"message"`
+	FinishReason string                        `json:"finish_reason"`
+}
+
+type ChatCompletionResponseMessage struct {
+	Role      string                   `json:"role"`
+	Content   string                   `json:"content"`
+	ToolCalls []ChatCompletionToolCall `json:"tool_calls,omitempty"`
+}
+
+type ChatCompletionToolCall struct {
+	// Index identifies the tool call across the chunks of a stream, whose
+	// later chunks only carry more arguments.
+	Index    *int                       `json:"index,omitempty"`
+	ID       string                     `json:"id,omitempty"`
+	Type     string                     `json:"type,omitempty"`
+	Function ChatCompletionFunctionCall `json:"function"`
+}
+
+type ChatCompletionFunctionCall struct {
+	Name string `json:"name,omitempty"`
+	// Arguments is a JSON object, encoded as a string.
+	Arguments string `json:"arguments"`
+}
+
+type ChatCompletionUsage struct {
+	PromptTokens            int64                            `json:"prompt_tokens"`
+	CompletionTokens        int64                            `json:"completion_tokens"`
+	TotalTokens             int64                            `json:"total_tokens"`
+	CompletionTokensDetails *ChatCompletionCompletionDetails `json:"completion_tokens_details,omitempty"`
+}
+
+type ChatCompletionCompletionDetails struct {
+	ReasoningTokens int64 `json:"reasoning_tokens"`
+}
+
+// ChatCompletionChunk is one server-sent event of a translated stream.
+type ChatCompletionChunk struct {
+	ID      string                      `json:"id"`
+	Object  string                      `json:"object"`
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model"`
+	Choices []ChatCompletionChunkChoice `json:"choices"`
+	Usage   *ChatCompletionUsage        `json:"usage,omitempty"`
+}
+
+type ChatCompletionChunkChoice struct {
+	Index        int                 `json:"index"`
+	Delta        ChatCompletionDelta `json:"delta"`
+	FinishReason *string             `json:"finish_reason"`
+}
+
+type ChatCompletionDelta struct {
+	Role      string                   `json:"role,omitempty"`
+	Content   string                   `json:"content,omitempty"`
+	ToolCalls []ChatCompletionToolCall `json:"tool_calls,omitempty"`
+}
+
+// functionTool is an OpenAI function tool.
+type functionTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// functionTools reads the tools of a request; only function tools can be
+// translated.
+func functionTools(tools any) ([]functionTool, error) {
+	if tools == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(tools)
+	if err != nil {
+		return nil, err
+	}
+	var openAITools []struct {
+		Type     string       `json:"type"`
+		Function functionTool `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &openAITools); err != nil {
+		return nil, fmt.Errorf("tools: %w", err)
+	}
+	functions := make([]functionTool, len(openAITools))
+	for i, tool := range openAITools {
+		if tool.Type != "function" {
+			return nil, fmt.Errorf("tools[%d]: only function tools are supported with this provider", i)
+		}
+		functions[i] = tool.Function
+	}
+	return functions, nil
+}
+
+// parseToolChoice reads tool_choice as its mode: auto, none, required or
+// function, with the function's name. The mode is empty when unset.
+func parseToolChoice(choice any) (mode, name string, err error) {
+	switch choice := choice.(type) {
+	case nil:
+		return "", "", nil
+	case string:
+		if choice == "auto" || choice == "none" || choice == "required" {
+			return choice, "", nil
+		}
+	case map[string]any:
+		if function, ok := choice["function"].(map[string]any); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return "function", name, nil
+			}
+		}
+	}
+	return "", "", errors.New("tool_choice must be auto, none, required or a function")
+}
+
+// outputs returns the content of the choices that are final answers; tool
+// calls are not checked against the schema.
+func (r ChatCompletionResponse) outputs() []string {
+	var outputs []string
+	for _, choice := range r.Choices {
+		if choice.FinishReason != "tool_calls" {
+			outputs = append(outputs, choice.Message.Content)
+		}
+	}
+	return outputs
+}
+
+// logParams builds the conversation log of one upstream call. usage is nil
+// when the upstream did not report it.
+func (call *chatCall) logParams(requestBody, responseBody []byte, statusCode int, usage *ChatCompletionUsage) database.CreateLogParams {
+	params := database.CreateLogParams{
+		UserID:          call.userID,
+		ModelID:         call.route.Model.ID,
+		RequestPayload:  json.RawMessage(requestBody),
+		ResponsePayload: json.RawMessage(responseBody),
+		ConnectionID:    call.route.Model.ConnectionID,
+		Type:            "llm",
+		ApiKeyID:        call.apiKeyID,
+		StatusCode:      pgtype.Int4{Int32: int32(statusCode), Valid: true},
+	}
+	if usage != nil {
+		params.PromptTokens = pgtype.Int8{Int64: usage.PromptTokens, Valid: true}
+		params.CompletionTokens = pgtype.Int8{Int64: usage.CompletionTokens, Valid: true}
+		if usage.CompletionTokensDetails != nil {
+			params.ReasoningTokens = pgtype.Int8{Int64: usage.CompletionTokensDetails.ReasoningTokens, Valid: true}
+		}
+	}
+	return params
+}
+
+func (s *Service) saveChatLog(call *chatCall, stage string, params database.CreateLogParams) {
+	if _, err := s.saveLog(call.logCtx, call.route.Model, params); err != nil {
+		slog.ErrorContext(call.logCtx, "Error logging conversation", "stage", stage, "error", err)
+	}
+}
+
+// upstreamChatError passes the error of a translated chat completion
+// through to the client, as JSON.
+func (s *Service) upstreamChatError(c echo.Context, call *chatCall, jsonBody []byte, statusCode int, respBody []byte) error {
+	if !json.Valid(respBody) {
+		respBody, _ = json.Marshal(ErrorResponse{Error: strings.TrimSpace(string(respBody))})
+	}
+	s.saveChatLog(call, "response_complete", call.logParams(jsonBody, respBody, statusCode, nil))
+	return c.JSONBlob(statusCode, respBody)
+}
+
+// chunkWriter writes the server-sent events of a translated stream.
+type chunkWriter struct {
+	res *echo.Response
+}
+
+func (w chunkWriter) send(chunk ChatCompletionChunk) error {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w.res, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	w.res.Flush()
+	return nil
+}
+
+// done ends the stream as OpenAI does.
+func (w chunkWriter) done() error {
+	if _, err := fmt.Fprint(w.res, "data: [DONE]\n\n"); err != nil {
+		return err
+	}
+	w.res.Flush()
+	return nil
+}
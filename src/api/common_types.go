@@ -1,6 +1,9 @@
 package api
 
 import (
+	"gen-ai-proxy/src/parampolicy"
+	"gen-ai-proxy/src/shadow"
+	"gen-ai-proxy/src/structuredoutput"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -12,6 +15,11 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// managedResourceError rejects API changes to resources owned by the declarative configuration file.
+func managedResourceError(resource string) ErrorResponse {
+	return ErrorResponse{Error: resource + " is managed by the configuration file and is read-only"}
+}
+
 // OpenAI Compatible LLM Request
 type ChatCompletionRequest struct {
 	Model        string                  `json:"model"`
@@ -19,6 +27,31 @@ type ChatCompletionRequest struct {
 	Messages     []ChatCompletionMessage `json:"messages"`
 	Stream       bool                    `json:"stream,omitempty"`
 	Tools        any                     `json:"tools,omitempty"`
+	ToolChoice   any                     `json:"tool_choice,omitempty"`
+
+	// StreamOptions are passed to OpenAI compatible providers, so a stream
+	// can end with its usage.
+	StreamOptions any `json:"stream_options,omitempty"`
+
+	// Sampling and reasoning parameters, subject to the model's parameter policy
+	Temperature         *float64 `json:"temperature,omitempty"`
+	TopP                *float64 `json:"top_p,omitempty"`
+	MaxTokens           *int     `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int     `json:"max_completion_tokens,omitempty"`
+	Seed                *int64   `json:"seed,omitempty"`
+	PresencePenalty     *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64 `json:"frequency_penalty,omitempty"`
+	Stop                any      `json:"stop,omitempty"`
+	ReasoningEffort     string   `json:"reasoning_effort,omitempty"`
+
+	// ResponseFormat with a json_schema is enforced on the output, see StructuredOutputError.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// PromptVariables fill in the model's prompt template; they are not sent upstream.
+	PromptVariables map[string]string `json:"prompt_variables,omitempty"`
+
+	// SafetySettings are sent to Gemini providers only.
+	SafetySettings []GeminiSafetySetting `json:"safety_settings,omitempty"`
 }
 
 type ChatCompletionMessage struct {
@@ -40,6 +73,11 @@ type Provider struct {
 	Name    string      `json:"name"`
 	BaseURL string      `json:"base_url"`
 	Type    string      `json:"type"`
+	Managed bool        `json:"managed"`
+	// APIVersion is sent with every request; required for Azure OpenAI.
+	APIVersion string `json:"api_version,omitempty"`
+	// Region requests are signed for; required for Bedrock.
+	Region string `json:"region,omitempty"`
 }
 
 type Model struct {
@@ -52,4 +90,15 @@ type Model struct {
 	PriceInput      float64     `json:"price_input"`
 	PriceOutput     float64     `json:"price_output"`
 	Type            string      `json:"type"`
+	LogPolicy       string      `json:"log_policy"`
+	// ParamPolicy sets defaults, overrides and allowed ranges for request parameters.
+	ParamPolicy *parampolicy.Policy `json:"param_policy,omitempty"`
+	// PromptTemplateID is the prompt template added to chat requests, if any.
+	PromptTemplateID pgtype.UUID `json:"prompt_template_id"`
+	// StructuredOutput is the JSON Schema chat outputs must match, if any.
+	StructuredOutput *structuredoutput.Policy `json:"structured_output,omitempty"`
+	// Shadow mirrors a percentage of requests to a candidate provider model,
+	// if set.
+	Shadow  *shadow.Policy `json:"shadow,omitempty"`
+	Managed bool           `json:"managed"`
 }
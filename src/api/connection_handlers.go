@@ -2,11 +2,14 @@ package api
 
 import (
 	"encoding/base64"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"gen-ai-proxy/src/awssig"
 	"gen-ai-proxy/src/database"
 	"gen-ai-proxy/src/encryption"
+	"gen-ai-proxy/src/llm"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
@@ -17,6 +20,7 @@ type ConnectionResponse struct {
 	Provider  string      `json:"provider"`
 	Name      string      `json:"name"`
 	CreatedAt time.Time   `json:"created_at"`
+	Managed   bool        `json:"managed"`
 }
 
 type ListConnectionsResponse struct {
@@ -29,6 +33,10 @@ type CreateConnectionRequest struct {
 	ProviderID string `json:"provider_id" binding:"required"`
 }
 
+type ListConnectionModelsResponse struct {
+	Models []llm.RemoteModel `json:"models"`
+}
+
 type UpdateConnectionRequest struct {
 	Name   string `json:"name" binding:"required"`
 	APIKey string `json:"api_key" binding:"required"`
@@ -64,6 +72,7 @@ func (s *Service) ListConnections(c echo.Context) error {
 			Provider:  dbConnection.ProviderID,
 			Name:      dbConnection.Name,
 			CreatedAt: dbConnection.CreatedAt.Time,
+			Managed:   dbConnection.Managed,
 		}
 	}
 
@@ -100,9 +109,16 @@ func (s *Service) CreateConnection(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ProviderID format"})
 	}
 
-	if _, err := s.db.GetProvider(c.Request().Context(), database.GetProviderParams{ID: pgtype.UUID{Bytes: providerUUID, Valid: true}, UserID: userID}); err != nil {
+	provider, err := s.db.GetProvider(c.Request().Context(), database.GetProviderParams{ID: pgtype.UUID{Bytes: providerUUID, Valid: true}, UserID: userID})
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ProviderID"})
 	}
+	// Bedrock connections hold AWS access keys instead of an API key
+	if llm.ProviderType(provider.Type) == llm.ProviderBedrock {
+		if _, err := awssig.ParseCredentials(req.APIKey); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+	}
 
 	decodedEncryptionKey, err := base64.StdEncoding.DecodeString(s.cfg.EncryptionKey)
 	if err != nil {
@@ -127,18 +143,90 @@ func (s *Service) CreateConnection(c echo.Context) error {
 
 	dbConnection, err := s.db.CreateConnection(c.Request().Context(), params)
 	if err != nil {
-		c.Logger().Errorf("Failed to create connection: %v", err)
+		slog.ErrorContext(c.Request().Context(), "Failed to create connection", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create connection"})
 	}
 
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceConnection, dbConnection.ID.String(), nil, dbConnection)
+
+	s.refreshRoutes(c)
+
 	return c.JSON(http.StatusCreated, ConnectionResponse{
 		ID:        dbConnection.ID,
 		Provider:  dbConnection.ProviderID,
 		Name:      dbConnection.Name,
 		CreatedAt: dbConnection.CreatedAt.Time,
+		Managed:   dbConnection.Managed,
 	})
 }
 
+// ListConnectionModels godoc
+// @Summary List the models of a connection
+// @Schemes
+// @Description List the models the connection's provider serves, as reported by the provider.
+// @Tags Connections
+// @Accept json
+// @Produce json
+// @Param id path string true "Connection ID"
+// @Success 200 {object} ListConnectionModelsResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 502 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/connections/{id}/models [get]
+func (s *Service) ListConnectionModels(c echo.Context) error {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid Connection ID format"})
+	}
+
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	ctx := c.Request().Context()
+	connection, err := s.db.GetConnection(ctx, database.GetConnectionParams{
+		ID:     pgtype.UUID{Bytes: parsedID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Connection not found"})
+	}
+	providerUUID, err := uuid.Parse(connection.ProviderID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Invalid ProviderID"})
+	}
+	provider, err := s.db.GetProvider(ctx, database.GetProviderParams{ID: pgtype.UUID{Bytes: providerUUID, Valid: true}, UserID: userID})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Provider not found"})
+	}
+	adapter, ok := llm.AdapterFor(llm.ProviderType(provider.Type))
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Listing models is not supported for " + provider.Type + " providers"})
+	}
+
+	decodedEncryptionKey, err := base64.StdEncoding.DecodeString(s.cfg.EncryptionKey)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to decode encryption key"})
+	}
+	apiKey, err := encryption.Decrypt(decodedEncryptionKey, connection.EncryptedApiKey)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to decrypt api key"})
+	}
+
+	models, err := adapter.ListModels(ctx, s.upstreamClient(adapter), adapterTarget(provider, database.Model{}, string(apiKey)))
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to list provider models", "connection_id", connection.ID.String(), "error", err)
+		return c.JSON(http.StatusBadGateway, ErrorResponse{Error: "failed to list models: " + err.Error()})
+	}
+	if models == nil {
+		models = []llm.RemoteModel{}
+	}
+	return c.JSON(http.StatusOK, ListConnectionModelsResponse{Models: models})
+}
+
 // DeleteConnection godoc
 // @Summary Delete a connection
 // @Schemes
@@ -151,6 +239,7 @@ func (s *Service) CreateConnection(c echo.Context) error {
 // @Failure 400 {object} api.ErrorResponse
 // @Failure 401 {object} api.ErrorResponse
 // @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Security BearerAuth
 // @Router /api/connections/{id} [delete]
@@ -169,6 +258,17 @@ func (s *Service) DeleteConnection(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
 	}
 
+	before, err := s.db.GetConnection(c.Request().Context(), database.GetConnectionParams{
+		ID:     connectionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Connection not found"})
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("Connection"))
+	}
+
 	err = s.db.SoftDeleteConnection(c.Request().Context(), database.SoftDeleteConnectionParams{
 		ID:     connectionID,
 		UserID: userID,
@@ -177,5 +277,9 @@ func (s *Service) DeleteConnection(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete connection"})
 	}
 
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourceConnection, connectionID.String(), before, nil)
+
+	s.refreshRoutes(c)
+
 	return c.NoContent(http.StatusNoContent)
 }
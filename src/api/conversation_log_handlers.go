@@ -1,58 +1,122 @@
 package api
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"gen-ai-proxy/src/database"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"strings"
 	"time"
 )
 
 type RawJSON string // @name RawJSON
 
 type LogResponse struct {
-	ID               pgtype.UUID `json:"id"`
-	ModelID          pgtype.UUID `json:"model_id"`
-	ConnectionID     pgtype.UUID `json:"connection_id"`
+	ID           pgtype.UUID `json:"id"`
+	ModelID      pgtype.UUID `json:"model_id"`
+	ConnectionID pgtype.UUID `json:"connection_id"`
+	ProviderID   string      `json:"provider_id,omitempty"`
+	APIKeyID     pgtype.UUID `json:"api_key_id"`
+	StatusCode   int32       `json:"status_code,omitempty"`
+	RequestID    string      `json:"request_id,omitempty"`
+	// PromptTemplateID and PromptTemplateVersion identify the prompt template
+	// version the request was sent with.
+	PromptTemplateID      pgtype.UUID `json:"prompt_template_id"`
+	PromptTemplateVersion int32       `json:"prompt_template_version,omitempty"`
+	// SchemaValidation is valid or invalid when the output was checked against
+	// a JSON Schema; SchemaAttempt numbers the attempts of one request.
+	SchemaValidation string `json:"schema_validation,omitempty"`
+	SchemaAttempt    int32  `json:"schema_attempt,omitempty"`
+	SchemaErrors     string `json:"schema_errors,omitempty"`
+	// BatchID is the batch the request was executed for.
+	BatchID pgtype.UUID `json:"batch_id"`
+	// ShadowOf is set on the logs of mirrored requests to the log of the
+	// request they mirror, and ProviderModelID to the candidate that answered.
+	ShadowOf         pgtype.UUID `json:"shadow_of"`
+	ProviderModelID  string      `json:"provider_model_id,omitempty"`
 	RequestPayload   RawJSON     `json:"request_payload"`
 	ResponsePayload  RawJSON     `json:"response_payload"`
 	CreatedAt        time.Time   `json:"created_at"`
 	PromptTokens     int64       `json:"prompt_tokens"`
 	CompletionTokens int64       `json:"completion_tokens"`
-	Type             string      `json:"type"`
+	// ReasoningTokens is the part of CompletionTokens spent on reasoning,
+	// when the upstream reports it.
+	ReasoningTokens int64   `json:"reasoning_tokens,omitempty"`
+	Cost            float64 `json:"cost"`
+	Type            string  `json:"type"`
+	// ContentFilter holds the content filter results the upstream (Azure
+	// OpenAI) reported for the prompt and each choice.
+	ContentFilter RawJSON `json:"content_filter,omitempty"`
 }
 
 type ListLogsRequest struct {
-	Page         int64       `query:"page"`
-	Limit        int64       `query:"limit"`
-	ModelID      pgtype.UUID `query:"model_id"`
-	ProviderID   pgtype.UUID `query:"provider_id"`
-	ConnectionID pgtype.UUID `query:"connection_id"`
-	Type         string      `query:"type"`
+	Cursor                string `query:"cursor"`
+	Limit                 int64  `query:"limit"`
+	ModelID               string `query:"model_id"`
+	ProviderID            string `query:"provider_id"`
+	ConnectionID          string `query:"connection_id"`
+	APIKeyID              string `query:"api_key_id"`
+	Type                  string `query:"type"`
+	RequestID             string `query:"request_id"`
+	PromptTemplateID      string `query:"prompt_template_id"`
+	PromptTemplateVersion *int32 `query:"prompt_template_version"`
+	SchemaValidation      string `query:"schema_validation"`
+	BatchID               string `query:"batch_id"`
+	Since                 string `query:"since"`
+	Until                 string `query:"until"`
+	Status                string `query:"status"`
+	MinTokens             *int64 `query:"min_tokens"`
+	MaxTokens             *int64 `query:"max_tokens"`
+	MinCost               string `query:"min_cost"`
+	MaxCost               string `query:"max_cost"`
+	Query                 string `query:"q"`
 }
 
 type ListLogsResponse struct {
-	Logs  []LogResponse `json:"logs"`
-	Total int64         `json:"total"`
+	Logs       []LogResponse `json:"logs"`
+	Total      int64         `json:"total"`
+	NextCursor string        `json:"next_cursor,omitempty"`
 }
 
+const maxLogsPageSize = 200
+
 // ListLogs godoc
 // @Summary List logs
 // @Schemes
-// @Description List all logs with pagination and filtering.
+// @Description List conversation logs, newest first, with filtering, full-text search and cursor pagination. Total counts all logs matching the filters.
 // @Tags Logs
 // @Accept json
 // @Produce json
-// @Param page query int false "Page number" default(1)
-// @Param limit query int false "Number of items per page" default(10)
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Number of items per page (max 200)" default(10)
 // @Param model_id query string false "Filter by model ID"
-// @Param connection_id query int false "Filter by connection ID"
+// @Param provider_id query string false "Filter by provider ID"
+// @Param connection_id query string false "Filter by connection ID"
+// @Param api_key_id query string false "Filter by API key ID"
+// @Param type query string false "Filter by log type (llm, embedding)"
+// @Param request_id query string false "Filter by X-Request-ID"
+// @Param prompt_template_id query string false "Filter by prompt template ID"
+// @Param prompt_template_version query int false "Filter by prompt template version"
+// @Param schema_validation query string false "Filter by structured output validation (valid, invalid)"
+// @Param batch_id query string false "Filter by batch ID"
+// @Param since query string false "Only logs at or after this RFC3339 timestamp"
+// @Param until query string false "Only logs before this RFC3339 timestamp"
+// @Param status query string false "Filter by upstream outcome (success, error)"
+// @Param min_tokens query int false "Minimum total tokens"
+// @Param max_tokens query int false "Maximum total tokens"
+// @Param min_cost query number false "Minimum cost"
+// @Param max_cost query number false "Maximum cost"
+// @Param q query string false "Full-text search over prompt and completion text"
 // @Success 200 {object} ListLogsResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
-// @Router /api/logs [get]
+// @Router /api/conversation_logs [get]
 func (s *Service) ListLogs(c echo.Context) error {
 	userID, err := GetUserIDFromContext(c)
 	if err != nil {
@@ -64,28 +128,46 @@ func (s *Service) ListLogs(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	// Default pagination values
-	if req.Page == 0 {
-		req.Page = 1
-	}
-	if req.Limit == 0 {
+	if req.Limit <= 0 {
 		req.Limit = 10
 	}
-
-	offset := (req.Page - 1) * req.Limit
-
-	params := database.ListLogsParams{
-		Limit:  pgtype.Int8{Int64: req.Limit, Valid: true},
-		Offset: pgtype.Int8{Int64: offset, Valid: true},
-		UserID: userID,
+	if req.Limit > maxLogsPageSize {
+		req.Limit = maxLogsPageSize
 	}
 
-	if req.ModelID.Valid {
-		params.ModelID = req.ModelID
+	filter, err := buildLogFilter(userID, req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	if req.Type != "" {
-		params.Type.String = req.Type
+	params := database.ListLogsParams{
+		UserID:                filter.UserID,
+		ModelID:               filter.ModelID,
+		ConnectionID:          filter.ConnectionID,
+		ProviderID:            filter.ProviderID,
+		ApiKeyID:              filter.ApiKeyID,
+		Type:                  filter.Type,
+		RequestID:             filter.RequestID,
+		PromptTemplateID:      filter.PromptTemplateID,
+		PromptTemplateVersion: filter.PromptTemplateVersion,
+		SchemaValidation:      filter.SchemaValidation,
+		BatchID:               filter.BatchID,
+		Since:                 filter.Since,
+		Until:                 filter.Until,
+		Status:                filter.Status,
+		MinTokens:             filter.MinTokens,
+		MaxTokens:             filter.MaxTokens,
+		MinCost:               filter.MinCost,
+		MaxCost:               filter.MaxCost,
+		Search:                filter.Search,
+		// Fetch one extra row to know whether there is a next page.
+		Limit: req.Limit + 1,
+	}
+	if req.Cursor != "" {
+		params.CursorCreatedAt, params.CursorID, err = decodeLogCursor(req.Cursor)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
 	}
 
 	logs, err := s.db.ListLogs(c.Request().Context(), params)
@@ -93,65 +175,174 @@ func (s *Service) ListLogs(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve logs"})
 	}
 
-	filteredLogs := []database.ListLogsRow{}
-	for _, log := range logs {
-		// Filter by ProviderID
-		if req.ProviderID.Valid {
-			model, err := s.GetModelFromDB(c.Request().Context(), log.ModelID, userID)
-			if err != nil {
-				continue
-			}
-			if !model.ConnectionID.Valid {
-				continue
-			}
-			if !model.ConnectionID.Valid {
-				continue
-			}
-			connection, err := s.db.GetConnection(c.Request().Context(), database.GetConnectionParams{
-				ID:     model.ConnectionID,
-				UserID: userID,
-			})
-			if err != nil || (connection.ProviderID != req.ProviderID.String()) {
-				continue
-			}
+	total, err := s.db.CountLogs(c.Request().Context(), filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to count logs"})
+	}
+
+	var nextCursor string
+	if int64(len(logs)) > req.Limit {
+		logs = logs[:req.Limit]
+		last := logs[len(logs)-1]
+		nextCursor = encodeLogCursor(last.CreatedAt.Time, last.ID)
+	}
+
+	respLogs := make([]LogResponse, len(logs))
+	for i, log := range logs {
+		respLogs[i] = toLogResponse(log)
+	}
+
+	return c.JSON(http.StatusOK, ListLogsResponse{
+		Logs:       respLogs,
+		Total:      total,
+		NextCursor: nextCursor,
+	})
+}
+
+// buildLogFilter converts query parameters into the shared log filter.
+func buildLogFilter(userID pgtype.UUID, req ListLogsRequest) (database.CountLogsParams, error) {
+	filter := database.CountLogsParams{
+		UserID:    userID,
+		Type:      pgtype.Text{String: req.Type, Valid: req.Type != ""},
+		RequestID: pgtype.Text{String: req.RequestID, Valid: req.RequestID != ""},
+		Search:    pgtype.Text{String: strings.TrimSpace(req.Query), Valid: strings.TrimSpace(req.Query) != ""},
+	}
+
+	ids := []struct {
+		name  string
+		value string
+		dst   *pgtype.UUID
+	}{
+		{"model_id", req.ModelID, &filter.ModelID},
+		{"provider_id", req.ProviderID, &filter.ProviderID},
+		{"connection_id", req.ConnectionID, &filter.ConnectionID},
+		{"api_key_id", req.APIKeyID, &filter.ApiKeyID},
+		{"prompt_template_id", req.PromptTemplateID, &filter.PromptTemplateID},
+		{"batch_id", req.BatchID, &filter.BatchID},
+	}
+	for _, id := range ids {
+		if id.value == "" {
+			continue
+		}
+		parsed, err := uuid.Parse(id.value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be a UUID", id.name)
 		}
+		*id.dst = pgtype.UUID{Bytes: parsed, Valid: true}
+	}
+
+	switch req.Status {
+	case "":
+	case "success", "error":
+		filter.Status = pgtype.Text{String: req.Status, Valid: true}
+	default:
+		return filter, errors.New("status must be success or error")
+	}
+
+	switch req.SchemaValidation {
+	case "":
+	case schemaValid, schemaInvalid:
+		filter.SchemaValidation = pgtype.Text{String: req.SchemaValidation, Valid: true}
+	default:
+		return filter, errors.New("schema_validation must be valid or invalid")
+	}
 
-		// Filter by ConnectionID
-		if req.ConnectionID.Valid {
-			if !log.ConnectionID.Valid || (log.ConnectionID.Valid && req.ConnectionID.Valid && log.ConnectionID != req.ConnectionID) {
-				continue
-			}
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return filter, errors.New("since must be an RFC3339 timestamp")
 		}
-		filteredLogs = append(filteredLogs, log)
-	}
-
-	respLogs := make([]LogResponse, len(filteredLogs))
-	for i, log := range filteredLogs {
-		respLogs[i] = LogResponse{
-			ID:              log.ID,
-			ModelID:         log.ModelID,
-			ConnectionID:    log.ConnectionID,
-			RequestPayload:  RawJSON(log.RequestPayload),
-			ResponsePayload: RawJSON(log.ResponsePayload),
-			CreatedAt:       log.CreatedAt.Time,
-			PromptTokens: func() int64 {
-				if log.PromptTokens.Valid {
-					return log.PromptTokens.Int64
-				}
-				return 0
-			}(),
-			CompletionTokens: func() int64 {
-				if log.CompletionTokens.Valid {
-					return log.CompletionTokens.Int64
-				}
-				return 0
-			}(),
-			Type: log.Type,
+		filter.Since = pgtype.Timestamptz{Time: since, Valid: true}
+	}
+	if req.Until != "" {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			return filter, errors.New("until must be an RFC3339 timestamp")
 		}
+		filter.Until = pgtype.Timestamptz{Time: until, Valid: true}
 	}
 
-	return c.JSON(http.StatusOK, ListLogsResponse{
-		Logs:  respLogs,
-		Total: int64(len(filteredLogs)),
-	})
+	if req.PromptTemplateVersion != nil {
+		filter.PromptTemplateVersion = pgtype.Int4{Int32: *req.PromptTemplateVersion, Valid: true}
+	}
+
+	if req.MinTokens != nil {
+		filter.MinTokens = pgtype.Int8{Int64: *req.MinTokens, Valid: true}
+	}
+	if req.MaxTokens != nil {
+		filter.MaxTokens = pgtype.Int8{Int64: *req.MaxTokens, Valid: true}
+	}
+
+	if req.MinCost != "" {
+		if err := filter.MinCost.Scan(req.MinCost); err != nil {
+			return filter, errors.New("min_cost must be a number")
+		}
+	}
+	if req.MaxCost != "" {
+		if err := filter.MaxCost.Scan(req.MaxCost); err != nil {
+			return filter, errors.New("max_cost must be a number")
+		}
+	}
+
+	return filter, nil
+}
+
+// encodeLogCursor builds an opaque cursor pointing just past the given log.
+func encodeLogCursor(createdAt time.Time, id pgtype.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeLogCursor(cursor string) (pgtype.Timestamptz, pgtype.UUID, error) {
+	errInvalid := errors.New("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pgtype.Timestamptz{}, pgtype.UUID{}, errInvalid
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return pgtype.Timestamptz{}, pgtype.UUID{}, errInvalid
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return pgtype.Timestamptz{}, pgtype.UUID{}, errInvalid
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return pgtype.Timestamptz{}, pgtype.UUID{}, errInvalid
+	}
+	return pgtype.Timestamptz{Time: createdAt, Valid: true}, pgtype.UUID{Bytes: id, Valid: true}, nil
+}
+
+func toLogResponse(log database.ListLogsRow) LogResponse {
+	resp := LogResponse{
+		ID:                    log.ID,
+		ModelID:               log.ModelID,
+		ConnectionID:          log.ConnectionID,
+		ProviderID:            log.ProviderID.String,
+		APIKeyID:              log.ApiKeyID,
+		StatusCode:            log.StatusCode.Int32,
+		RequestID:             log.RequestID.String,
+		PromptTemplateID:      log.PromptTemplateID,
+		PromptTemplateVersion: log.PromptTemplateVersion.Int32,
+		SchemaValidation:      log.SchemaValidation.String,
+		SchemaAttempt:         log.SchemaAttempt.Int32,
+		SchemaErrors:          log.SchemaErrors.String,
+		BatchID:               log.BatchID,
+		ShadowOf:              log.ShadowOf,
+		ProviderModelID:       log.ProviderModelID.String,
+		RequestPayload:        RawJSON(log.RequestPayload),
+		ResponsePayload:       RawJSON(log.ResponsePayload),
+		CreatedAt:             log.CreatedAt.Time,
+		PromptTokens:          log.PromptTokens.Int64,
+		CompletionTokens:      log.CompletionTokens.Int64,
+		ReasoningTokens:       log.ReasoningTokens.Int64,
+		Type:                  log.Type,
+		ContentFilter:         RawJSON(log.ContentFilter),
+	}
+	if cost, err := log.Cost.Float64Value(); err == nil {
+		resp.Cost = cost.Float64
+	}
+	return resp
 }
@@ -3,25 +3,106 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"gen-ai-proxy/src/config"
 	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/parampolicy"
+	"gen-ai-proxy/src/recording"
+	"gen-ai-proxy/src/redaction"
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/shadow"
+	"gen-ai-proxy/src/structuredoutput"
+	"gen-ai-proxy/src/telemetry"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
 )
 
 type Service struct {
-	db  *database.Queries
-	cfg *config.Config
+	db     database.Store
+	cfg    *config.Config
+	routes *routing.Table
+
+	// httpClient carries trace context to upstream providers. It is replaced,
+	// not modified, when UPSTREAM_TIMEOUT is reloaded.
+	httpClient  atomic.Pointer[http.Client]
+	logPayloads atomic.Bool
+	// mockProviders allows creating providers of the mock type.
+	mockProviders atomic.Bool
+	// outputRetries is the default number of structured output retries.
+	outputRetries atomic.Int32
+
+	redactor         *redaction.Redactor
+	defaultLogPolicy redaction.Policy
+
+	// background tracks log writes still running after their response was sent.
+	background sync.WaitGroup
+	// draining is set on shutdown so readiness fails while requests finish.
+	draining        atomic.Bool
+	readinessChecks []ReadinessCheck
+
+	// batchEcho creates the contexts batch and evaluation requests are
+	// executed with.
+	batchEcho *echo.Echo
 }
 
-func NewService(db *database.Queries, cfg *config.Config) (*Service, error) {
+func NewService(db database.Store, cfg *config.Config, routes *routing.Table) (*Service, error) {
+	defaultLogPolicy, err := redaction.ParsePolicy(cfg.LogPolicyDefault, redaction.PolicyFull)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_POLICY_DEFAULT: %w", err)
+	}
+
+	redactor, err := redaction.New(strings.Split(cfg.RedactionDetectors, ","), strings.Split(cfg.RedactionRules, ";"))
+	if err != nil {
+		return nil, err
+	}
+
 	s := &Service{
-		db:  db,
-		cfg: cfg,
+		db:               db,
+		cfg:              cfg,
+		routes:           routes,
+		redactor:         redactor,
+		defaultLogPolicy: defaultLogPolicy,
+		batchEcho:        echo.New(),
+	}
+	client := telemetry.NewHTTPClient()
+	switch cfg.UpstreamMode {
+	case "":
+	case "record":
+		client.Transport = &recording.Recorder{Dir: cfg.RecordingsDir, Next: client.Transport}
+	case "replay":
+		client.Transport = &recording.Replayer{Dir: cfg.RecordingsDir, Timing: cfg.ReplayTiming}
+	default:
+		return nil, fmt.Errorf("invalid UPSTREAM_MODE %q: expected record or replay", cfg.UpstreamMode)
+	}
+	s.httpClient.Store(client)
+	s.ApplyConfig(cfg)
+	s.readinessChecks = append(s.readinessChecks, ReadinessCheck{Name: "database", Check: db.Ping})
+	for _, target := range strings.Split(cfg.ReadinessUpstreams, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			s.readinessChecks = append(s.readinessChecks, ReadinessCheck{Name: "upstream " + target, Check: s.upstreamCheck(target)})
+		}
 	}
 	return s, nil
 }
 
+// ApplyConfig takes over the settings that can change without a restart:
+// LOG_PAYLOADS, UPSTREAM_TIMEOUT, STRUCTURED_OUTPUT_MAX_RETRIES,
+// MOCK_PROVIDERS and MOCK_FIXTURES_DIR.
+func (s *Service) ApplyConfig(cfg *config.Config) {
+	s.logPayloads.Store(cfg.LogPayloads)
+	s.mockProviders.Store(cfg.MockProviders)
+	llm.ConfigureMock(cfg.MockProviders, cfg.MockFixturesDir)
+	s.outputRetries.Store(int32(min(max(cfg.StructuredOutputMaxRetries, 0), structuredoutput.MaxRetriesLimit)))
+	client := *s.httpClient.Load()
+	client.Timeout = cfg.UpstreamTimeout
+	s.httpClient.Store(&client)
+}
+
 func (s *Service) GetProviderFromDB(ctx context.Context, providerID pgtype.UUID, userID pgtype.UUID) (Provider, error) {
 	dbProvider, err := s.db.GetProvider(ctx, database.GetProviderParams{
 		ID:     providerID,
@@ -31,10 +112,13 @@ func (s *Service) GetProviderFromDB(ctx context.Context, providerID pgtype.UUID,
 		return Provider{}, err
 	}
 	return Provider{
-		ID:      dbProvider.ID,
-		Name:    dbProvider.Name,
-		BaseURL: dbProvider.BaseUrl,
-		Type:    dbProvider.Type,
+		ID:         dbProvider.ID,
+		Name:       dbProvider.Name,
+		BaseURL:    dbProvider.BaseUrl,
+		Type:       dbProvider.Type,
+		Managed:    dbProvider.Managed,
+		APIVersion: dbProvider.ApiVersion,
+		Region:     dbProvider.Region,
 	}, nil
 }
 
@@ -62,14 +146,20 @@ func (s *Service) GetModelFromDB(ctx context.Context, modelID pgtype.UUID, userI
 		priceOutput = f.Float64
 	}
 	return Model{
-		ID:              dbModel.ID,
-		ConnectionID:    dbModel.ConnectionID,
-		ProxyModelID:    dbModel.ProxyModelID,
-		ProviderModelID: dbModel.ProviderModelID,
-		Thinking:        dbModel.Thinking,
-		ToolsUsage:      dbModel.ToolsUsage,
-		PriceInput:      priceInput,
-		PriceOutput:     priceOutput,
-		Type:            dbModel.Type,
+		ID:               dbModel.ID,
+		ConnectionID:     dbModel.ConnectionID,
+		ProxyModelID:     dbModel.ProxyModelID,
+		ProviderModelID:  dbModel.ProviderModelID,
+		Thinking:         dbModel.Thinking,
+		ToolsUsage:       dbModel.ToolsUsage,
+		PriceInput:       priceInput,
+		PriceOutput:      priceOutput,
+		Type:             dbModel.Type,
+		LogPolicy:        dbModel.LogPolicy,
+		ParamPolicy:      parampolicy.Decode(dbModel.ParamPolicy),
+		PromptTemplateID: dbModel.PromptTemplateID,
+		StructuredOutput: structuredoutput.Decode(dbModel.StructuredOutput),
+		Shadow:           shadow.Decode(dbModel.Shadow),
+		Managed:          dbModel.Managed,
 	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,502 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/eval"
+	"gen-ai-proxy/src/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultJudgePrompt is the system prompt of judge models when the run does
+// not set one. The answers to compare are sent as the user message.
+const defaultJudgePrompt = `You compare the answer of an AI assistant (the candidate) with a reference answer to the same conversation. Score the candidate from 1 to 10: 1 when it is wrong, unhelpful or much worse than the reference, 10 when it is at least as good. Give the reason for your score in one sentence.`
+
+// judgeFormat makes judge models answer with a score and a reason; the
+// proxy's structured output check enforces it.
+var judgeFormat = &ResponseFormat{
+	Type: "json_schema",
+	JSONSchema: &ResponseFormatJSONSchema{
+		Name: "evaluation",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"score":  map[string]any{"type": "integer", "minimum": 1, "maximum": 10},
+				"reason": map[string]any{"type": "string"},
+			},
+			"required":             []string{"score", "reason"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+type evalRunKey struct{}
+
+// withEvalRun records the evaluation run a request is made for, so saveLog
+// can store it with the conversation.
+func withEvalRun(ctx context.Context, runID pgtype.UUID) context.Context {
+	return context.WithValue(ctx, evalRunKey{}, runID)
+}
+
+// evalRunLogField returns the log column identifying the evaluation run in ctx.
+func evalRunLogField(ctx context.Context) pgtype.UUID {
+	runID, _ := ctx.Value(evalRunKey{}).(pgtype.UUID)
+	return runID
+}
+
+// ExecuteEvalRequest replays a logged request against the candidate model of
+// a run, through the same handler as a live chat completion and as the
+// run's owner, and has the judge model score the answer if the run has one.
+func (s *Service) ExecuteEvalRequest(ctx context.Context, run database.EvalRun, r database.EvalResult) (eval.Result, error) {
+	var req ChatCompletionRequest
+	if err := json.Unmarshal(r.Request, &req); err != nil {
+		return eval.Result{}, fmt.Errorf("decode request: %w", err)
+	}
+	req.Model = run.CandidateModel
+
+	start := time.Now()
+	statusCode, requestID, body, err := s.executeEvalChat(ctx, run, req)
+	if err != nil {
+		return eval.Result{}, err
+	}
+	res := eval.Result{StatusCode: statusCode, RequestID: requestID, LatencyMs: time.Since(start).Milliseconds()}
+	if statusCode >= 300 {
+		res.Error = errorMessage(body)
+		return res, nil
+	}
+
+	var resp ChatCompletionResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return eval.Result{}, fmt.Errorf("decode candidate response: %w", err)
+	}
+	if outputs := resp.outputs(); len(outputs) > 0 {
+		res.Output = outputs[0]
+	}
+	if resp.Usage != nil {
+		res.PromptTokens = pgtype.Int8{Int64: resp.Usage.PromptTokens, Valid: true}
+		res.CompletionTokens = pgtype.Int8{Int64: resp.Usage.CompletionTokens, Valid: true}
+	}
+
+	if run.JudgeModel.Valid {
+		score, reason, err := s.judge(ctx, run, req.Messages, r.BaselineOutput.String, res.Output)
+		if err != nil {
+			res.JudgeError = err.Error()
+		} else {
+			res.JudgeScore, res.JudgeReason = pgtype.Int4{Int32: score, Valid: true}, reason
+		}
+	}
+	return res, nil
+}
+
+// judge asks the run's judge model to score the candidate's answer against
+// the logged one.
+func (s *Service) judge(ctx context.Context, run database.EvalRun, messages []ChatCompletionMessage, baseline, candidate string) (int32, string, error) {
+	var conversation strings.Builder
+	for _, m := range messages {
+		fmt.Fprintf(&conversation, "[%s]\n%s\n\n", m.Role, m.Content)
+	}
+	if baseline == "" {
+		baseline = "(not available)"
+	}
+	prompt := run.JudgePrompt.String
+	if prompt == "" {
+		prompt = defaultJudgePrompt
+	}
+	temperature := 0.0
+	req := ChatCompletionRequest{
+		Model: run.JudgeModel.String,
+		Messages: []ChatCompletionMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: fmt.Sprintf("Conversation:\n\n%sReference answer:\n%s\n\nCandidate answer:\n%s", conversation.String(), baseline, candidate)},
+		},
+		Temperature:    &temperature,
+		ResponseFormat: judgeFormat,
+	}
+
+	statusCode, _, body, err := s.executeEvalChat(ctx, run, req)
+	if err != nil {
+		return 0, "", err
+	}
+	if statusCode >= 300 {
+		return 0, "", fmt.Errorf("status %d: %s", statusCode, errorMessage(body))
+	}
+	var resp ChatCompletionResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, "", fmt.Errorf("decode response: %w", err)
+	}
+	outputs := resp.outputs()
+	if len(outputs) == 0 {
+		return 0, "", errors.New("no answer")
+	}
+	var verdict struct {
+		Score  int32  `json:"score"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(outputs[0]), &verdict); err != nil || verdict.Score < 1 || verdict.Score > 10 {
+		return 0, "", errors.New("answer is not a score from 1 to 10")
+	}
+	return verdict.Score, verdict.Reason, nil
+}
+
+// executeEvalChat runs a chat completion through ProxyOpenAIChat for a run,
+// logged with the run's ID so later runs do not sample it.
+func (s *Service) executeEvalChat(ctx context.Context, run database.EvalRun, req ChatCompletionRequest) (int, string, []byte, error) {
+	req.Stream = false
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	requestID := uuid.NewString()
+	ctx = logging.WithRequestID(withEvalRun(withRequestStart(ctx, time.Now()), run.ID), requestID)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "/api/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return 0, "", nil, err
+	}
+	httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	httpReq.Header.Set(RequestIDHeader, requestID)
+
+	rec := &responseRecorder{header: http.Header{}}
+	c := s.batchEcho.NewContext(httpReq, rec)
+	c.Set(userContextKey, run.UserID)
+	if err := s.ProxyOpenAIChat(c); err != nil {
+		s.batchEcho.HTTPErrorHandler(err, c)
+	}
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.status, requestID, rec.body.Bytes(), nil
+}
+
+// errorMessage is the message of an error response, as the proxy or the
+// upstream wrote it.
+func errorMessage(body []byte) string {
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error != nil {
+		var message string
+		if json.Unmarshal(resp.Error, &message) == nil {
+			return message
+		}
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(resp.Error, &nested) == nil && nested.Message != "" {
+			return nested.Message
+		}
+	}
+	const maxLen = 1000
+	if len(body) > maxLen {
+		return string(body[:maxLen])
+	}
+	return string(body)
+}
+
+// loggedRequest is what evalRequest reads from the upstream request of a
+// conversation log, in any of the formats the proxy sends.
+type loggedRequest struct {
+	// OpenAI compatible providers, Ollama and Bedrock
+	Messages []struct {
+		Role      string          `json:"role"`
+		Content   json.RawMessage `json:"content"`
+		ToolCalls json.RawMessage `json:"tool_calls"`
+	} `json:"messages"`
+	Tools json.RawMessage `json:"tools"`
+
+	// The Responses API
+	Input        json.RawMessage `json:"input"`
+	Instructions string          `json:"instructions"`
+
+	// Gemini
+	Contents []struct {
+		Role  string          `json:"role"`
+		Parts json.RawMessage `json:"parts"`
+	} `json:"contents"`
+	SystemInstruction *struct {
+		Parts json.RawMessage `json:"parts"`
+	} `json:"systemInstruction"`
+	GenerationConfig *struct {
+		Temperature     *float64 `json:"temperature"`
+		TopP            *float64 `json:"topP"`
+		MaxOutputTokens *int     `json:"maxOutputTokens"`
+	} `json:"generationConfig"`
+
+	// Bedrock
+	System          json.RawMessage `json:"system"`
+	ToolConfig      json.RawMessage `json:"toolConfig"`
+	InferenceConfig *struct {
+		Temperature *float64 `json:"temperature"`
+		TopP        *float64 `json:"topP"`
+		MaxTokens   *int     `json:"maxTokens"`
+	} `json:"inferenceConfig"`
+
+	// Ollama
+	Options *struct {
+		Temperature *float64 `json:"temperature"`
+		TopP        *float64 `json:"top_p"`
+		NumPredict  *int     `json:"num_predict"`
+	} `json:"options"`
+
+	// OpenAI
+	Temperature         *float64 `json:"temperature"`
+	TopP                *float64 `json:"top_p"`
+	MaxTokens           *int     `json:"max_tokens"`
+	MaxCompletionTokens *int     `json:"max_completion_tokens"`
+	MaxOutputTokens     *int     `json:"max_output_tokens"`
+	Stop                any      `json:"stop"`
+}
+
+// evalRequest turns the logged upstream request of a conversation into a
+// chat completion request for the candidate model. It reports false for
+// requests that cannot be replayed as text chats: those with tools or
+// content other than text, and logs that kept no payloads.
+func evalRequest(payload []byte) (ChatCompletionRequest, bool) {
+	var logged loggedRequest
+	if err := json.Unmarshal(payload, &logged); err != nil {
+		return ChatCompletionRequest{}, false
+	}
+	if hasJSON(logged.Tools) || hasJSON(logged.ToolConfig) {
+		return ChatCompletionRequest{}, false
+	}
+
+	var req ChatCompletionRequest
+	add := func(role string, content json.RawMessage) bool {
+		text, ok := contentText(content)
+		if !ok {
+			return false
+		}
+		if role == "developer" {
+			role = "system"
+		}
+		req.Messages = append(req.Messages, ChatCompletionMessage{Role: role, Content: text})
+		return true
+	}
+
+	if logged.SystemInstruction != nil && !add("system", logged.SystemInstruction.Parts) {
+		return ChatCompletionRequest{}, false
+	}
+	if hasJSON(logged.System) && !add("system", logged.System) {
+		return ChatCompletionRequest{}, false
+	}
+	if logged.Instructions != "" {
+		req.Messages = append(req.Messages, ChatCompletionMessage{Role: "system", Content: logged.Instructions})
+	}
+	for _, m := range logged.Messages {
+		if hasJSON(m.ToolCalls) || !chatRole(m.Role) || !add(m.Role, m.Content) {
+			return ChatCompletionRequest{}, false
+		}
+	}
+	for _, content := range logged.Contents {
+		role := content.Role
+		if role == "model" {
+			role = "assistant"
+		}
+		if !chatRole(role) || !add(role, content.Parts) {
+			return ChatCompletionRequest{}, false
+		}
+	}
+	if hasJSON(logged.Input) && !responsesInput(logged.Input, add) {
+		return ChatCompletionRequest{}, false
+	}
+	if !slices.ContainsFunc(req.Messages, func(m ChatCompletionMessage) bool { return m.Role == "user" }) {
+		return ChatCompletionRequest{}, false
+	}
+
+	req.Temperature, req.TopP, req.Stop = logged.Temperature, logged.TopP, logged.Stop
+	req.MaxTokens, req.MaxCompletionTokens = logged.MaxTokens, logged.MaxCompletionTokens
+	if logged.MaxOutputTokens != nil {
+		req.MaxTokens = logged.MaxOutputTokens
+	}
+	if c := logged.GenerationConfig; c != nil {
+		req.Temperature, req.TopP, req.MaxTokens = c.Temperature, c.TopP, c.MaxOutputTokens
+	}
+	if c := logged.InferenceConfig; c != nil {
+		req.Temperature, req.TopP, req.MaxTokens = c.Temperature, c.TopP, c.MaxTokens
+	}
+	if o := logged.Options; o != nil {
+		req.Temperature, req.TopP, req.MaxTokens = o.Temperature, o.TopP, o.NumPredict
+	}
+	return req, true
+}
+
+// responsesInput adds the messages of a Responses API input: a string or a
+// list of message items.
+func responsesInput(input json.RawMessage, add func(string, json.RawMessage) bool) bool {
+	var text string
+	if json.Unmarshal(input, &text) == nil {
+		return add("user", input)
+	}
+	var items []struct {
+		Type    string          `json:"type"`
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(input, &items) != nil {
+		return false
+	}
+	for _, item := range items {
+		if item.Type != "" && item.Type != "message" || !chatRole(item.Role) || !add(item.Role, item.Content) {
+			return false
+		}
+	}
+	return true
+}
+
+// contentText flattens message content to text: a string, or a list of text
+// parts in the OpenAI, Responses API, Gemini or Bedrock shape.
+func contentText(content json.RawMessage) (string, bool) {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text, true
+	}
+	var parts []map[string]json.RawMessage
+	if json.Unmarshal(content, &parts) != nil {
+		return "", false
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		var partType, partText string
+		_ = json.Unmarshal(part["type"], &partType)
+		switch partType {
+		case "", "text", "input_text", "output_text":
+		default:
+			return "", false
+		}
+		if json.Unmarshal(part["text"], &partText) != nil {
+			return "", false
+		}
+		b.WriteString(partText)
+	}
+	return b.String(), true
+}
+
+// chatRole reports whether a role has a place in a text chat. Developer
+// messages are sent as system messages; tool results are not replayed.
+func chatRole(role string) bool {
+	switch role {
+	case "system", "developer", "user", "assistant":
+		return true
+	}
+	return false
+}
+
+func hasJSON(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && !bytes.Equal(raw, []byte("null")) && !bytes.Equal(raw, []byte("[]"))
+}
+
+// loggedOutput extracts the answer from the upstream response of a
+// conversation log, whatever the provider and whether it was streamed.
+// Streams are logged as they were received, possibly as a JSON string, or
+// as a JSON array of events for Gemini and Bedrock.
+func loggedOutput(payload []byte) (string, bool) {
+	payload = bytes.TrimSpace(payload)
+	var text string
+	if json.Unmarshal(payload, &text) == nil {
+		payload = []byte(text)
+	}
+
+	var events []json.RawMessage
+	if json.Unmarshal(payload, &events) == nil {
+		return eventsOutput(events)
+	}
+	if json.Valid(payload) {
+		return responseOutput(payload)
+	}
+	if final, ok := responsesStreamResult(payload); ok {
+		return responseOutput(final)
+	}
+	if outputs := openAIStreamOutputs(payload); len(outputs) > 0 {
+		return outputs[0], true
+	}
+	if outputs := ollamaStreamOutputs(payload); len(outputs) > 0 {
+		return outputs[0], true
+	}
+	return "", false
+}
+
+// responseOutput extracts the answer of a response that was not streamed.
+func responseOutput(payload []byte) (string, bool) {
+	var resp struct {
+		Choices    json.RawMessage `json:"choices"`
+		Message    json.RawMessage `json:"message"`
+		Candidates json.RawMessage `json:"candidates"`
+		Output     json.RawMessage `json:"output"`
+	}
+	if json.Unmarshal(payload, &resp) != nil {
+		return "", false
+	}
+	var outputs []string
+	switch {
+	case resp.Choices != nil:
+		var r ChatCompletionResponse
+		if json.Unmarshal(payload, &r) == nil {
+			outputs = r.outputs()
+		}
+	case resp.Message != nil:
+		outputs = ollamaOutputs(payload)
+	case resp.Candidates != nil:
+		var r geminiResponse
+		if json.Unmarshal(payload, &r) == nil {
+			outputs = r.chatCompletion("", 0).outputs()
+		}
+	case bytes.HasPrefix(bytes.TrimSpace(resp.Output), []byte("[")):
+		var r ResponsesResponse
+		if json.Unmarshal(payload, &r) == nil {
+			outputs = r.outputs()
+		}
+	case resp.Output != nil:
+		var r bedrockResponse
+		if json.Unmarshal(payload, &r) == nil {
+			outputs = r.chatCompletion("", 0).outputs()
+		}
+	}
+	if len(outputs) == 0 {
+		return "", false
+	}
+	return outputs[0], true
+}
+
+// eventsOutput reassembles the answer of a Gemini or Bedrock stream: Gemini
+// events are responses, Bedrock events objects keyed by event type.
+func eventsOutput(events []json.RawMessage) (string, bool) {
+	var b strings.Builder
+	found := false
+	for _, raw := range events {
+		var event struct {
+			Candidates        []geminiCandidate   `json:"candidates"`
+			ContentBlockDelta *bedrockStreamEvent `json:"contentBlockDelta"`
+		}
+		if json.Unmarshal(raw, &event) != nil {
+			continue
+		}
+		if len(event.Candidates) > 0 {
+			text, toolCalls := event.Candidates[0].Content.output(0)
+			if len(toolCalls) > 0 {
+				return "", false
+			}
+			b.WriteString(text)
+			found = true
+		}
+		if event.ContentBlockDelta != nil {
+			if event.ContentBlockDelta.Delta.ToolUse != nil {
+				return "", false
+			}
+			b.WriteString(event.ContentBlockDelta.Delta.Text)
+			found = true
+		}
+	}
+	return b.String(), found
+}
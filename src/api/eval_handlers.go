@@ -0,0 +1,683 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/eval"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultEvalSampleSize = 50
+	maxEvalSampleSize     = 1000
+	// evalSampleFactor oversamples logs, since some cannot be replayed.
+	evalSampleFactor = 2
+)
+
+// EvalFilter selects the conversation logs an evaluation run samples.
+type EvalFilter struct {
+	ModelID  string `json:"model_id,omitempty"`
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// Since and Until are RFC3339 timestamps.
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+}
+
+type CreateEvalRunRequest struct {
+	Name           string `json:"name"`
+	CandidateModel string `json:"candidate_model"`
+	// JudgeModel scores each answer of the candidate against the logged one,
+	// with JudgePrompt as its system prompt if set.
+	JudgeModel  string     `json:"judge_model,omitempty"`
+	JudgePrompt string     `json:"judge_prompt,omitempty"`
+	Filter      EvalFilter `json:"filter"`
+	SampleSize  int        `json:"sample_size"`
+}
+
+type EvalRunResponse struct {
+	ID             pgtype.UUID      `json:"id"`
+	Name           string           `json:"name"`
+	CandidateModel string           `json:"candidate_model"`
+	JudgeModel     string           `json:"judge_model,omitempty"`
+	JudgePrompt    string           `json:"judge_prompt,omitempty"`
+	Filter         EvalFilter       `json:"filter"`
+	Status         string           `json:"status"`
+	CreatedAt      time.Time        `json:"created_at"`
+	CancellingAt   *time.Time       `json:"cancelling_at,omitempty"`
+	FinishedAt     *time.Time       `json:"finished_at,omitempty"`
+	Counts         EvalResultCounts `json:"counts"`
+	// Summary compares the succeeded results; it is only returned for a
+	// single run.
+	Summary *EvalSummary `json:"summary,omitempty"`
+}
+
+type EvalResultCounts struct {
+	Total     int64 `json:"total"`
+	Pending   int64 `json:"pending"`
+	Succeeded int64 `json:"succeeded"`
+	Failed    int64 `json:"failed"`
+	Cancelled int64 `json:"cancelled"`
+}
+
+type EvalSummary struct {
+	Baseline  EvalTotals `json:"baseline"`
+	Candidate EvalTotals `json:"candidate"`
+	// AverageJudgeScore is over the results the judge scored.
+	AverageJudgeScore *float64 `json:"average_judge_score"`
+	Judged            int64    `json:"judged"`
+}
+
+// EvalTotals adds up one side of the compared results. Costs use the
+// models' current prices.
+type EvalTotals struct {
+	PromptTokens     int64    `json:"prompt_tokens"`
+	CompletionTokens int64    `json:"completion_tokens"`
+	Cost             float64  `json:"cost"`
+	AverageLatencyMs *float64 `json:"average_latency_ms"`
+}
+
+type EvalResultResponse struct {
+	Line  int32       `json:"line"`
+	LogID pgtype.UUID `json:"log_id"`
+	// Request is the chat completion request replayed against the candidate.
+	Request     json.RawMessage `json:"request" swaggertype:"object"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	Baseline    EvalAnswer      `json:"baseline"`
+	Candidate   EvalAnswer      `json:"candidate"`
+	JudgeScore  *int32          `json:"judge_score,omitempty"`
+	JudgeReason string          `json:"judge_reason,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// EvalAnswer is the answer to a request, as logged (baseline) or given by
+// the candidate. Latency and token counts are omitted when unknown.
+type EvalAnswer struct {
+	Model            string  `json:"model,omitempty"`
+	StatusCode       int32   `json:"status_code,omitempty"`
+	RequestID        string  `json:"request_id,omitempty"`
+	Output           *string `json:"output"`
+	PromptTokens     *int64  `json:"prompt_tokens,omitempty"`
+	CompletionTokens *int64  `json:"completion_tokens,omitempty"`
+	LatencyMs        *int64  `json:"latency_ms,omitempty"`
+	Cost             float64 `json:"cost"`
+}
+
+type ListEvalRunsRequest struct {
+	After string `query:"after"`
+	Limit int64  `query:"limit"`
+}
+
+type ListEvalRunsResponse struct {
+	Runs    []EvalRunResponse `json:"runs"`
+	HasMore bool              `json:"has_more"`
+}
+
+type ListEvalResultsRequest struct {
+	After  int32  `query:"after"`
+	Limit  int64  `query:"limit"`
+	Status string `query:"status"`
+}
+
+type ListEvalResultsResponse struct {
+	Results []EvalResultResponse `json:"results"`
+	// LastLine is the after of the next page.
+	LastLine int32 `json:"last_line,omitempty"`
+	HasMore  bool  `json:"has_more"`
+}
+
+// CreateEvalRun godoc
+// @Summary Create an evaluation run
+// @Schemes
+// @Description Replay a random sample of logged chat requests against a candidate model in the background, to compare its answers, cost, latency and token counts with the logged ones. Logs without payloads, with tools or with content other than text are not sampled. A judge model, if given, scores each answer from 1 to 10 against the logged one.
+// @Tags Evaluation
+// @Accept json
+// @Produce json
+// @Param run body CreateEvalRunRequest true "Candidate and judge models, log filter and sample size (default 50, max 1000)"
+// @Success 201 {object} EvalRunResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/eval-runs [post]
+func (s *Service) CreateEvalRun(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req CreateEvalRunRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Name == "" || req.CandidateModel == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "name and candidate_model are required"})
+	}
+	if req.JudgePrompt != "" && req.JudgeModel == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "judge_prompt requires a judge_model"})
+	}
+	if req.SampleSize == 0 {
+		req.SampleSize = defaultEvalSampleSize
+	}
+	if req.SampleSize < 0 || req.SampleSize > maxEvalSampleSize {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("sample_size must be between 1 and %d", maxEvalSampleSize)})
+	}
+
+	ctx := c.Request().Context()
+	candidate, ok := s.routes.Lookup(ctx, userID, req.CandidateModel)
+	if !ok || candidate.Model.Type != "llm" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "candidate_model must be a chat model"})
+	}
+	if req.JudgeModel != "" {
+		if judge, ok := s.routes.Lookup(ctx, userID, req.JudgeModel); !ok || judge.Model.Type != "llm" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "judge_model must be a chat model"})
+		}
+	}
+	sample, err := buildEvalSample(userID, req.Filter)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	sample.Limit = int64(req.SampleSize * evalSampleFactor)
+
+	logs, err := s.db.SampleEvalLogs(ctx, sample)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sampling logs for evaluation run", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to sample logs"})
+	}
+	var results []database.CreateEvalResultParams
+	for _, l := range logs {
+		if len(results) == req.SampleSize {
+			break
+		}
+		chat, ok := evalRequest(l.RequestPayload)
+		if !ok {
+			continue
+		}
+		chat.Model = req.CandidateModel
+		body, _ := json.Marshal(chat)
+		output, ok := loggedOutput(l.ResponsePayload)
+		results = append(results, database.CreateEvalResultParams{
+			Line:                     int32(len(results) + 1),
+			LogID:                    l.ID,
+			BaselineModelID:          l.ModelID,
+			Request:                  body,
+			BaselineOutput:           pgtype.Text{String: output, Valid: ok},
+			BaselinePromptTokens:     l.PromptTokens,
+			BaselineCompletionTokens: l.CompletionTokens,
+			BaselineLatencyMs:        l.LatencyMs,
+		})
+	}
+	if len(results) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "no logged chat requests that can be replayed match the filter"})
+	}
+
+	filter, _ := json.Marshal(req.Filter)
+	var created database.EvalRun
+	err = s.db.ExecTx(ctx, func(q database.Querier) error {
+		var err error
+		created, err = q.CreateEvalRun(ctx, database.CreateEvalRunParams{
+			ID:               pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			UserID:           userID,
+			Name:             req.Name,
+			CandidateModel:   req.CandidateModel,
+			CandidateModelID: candidate.Model.ID,
+			JudgeModel:       pgtype.Text{String: req.JudgeModel, Valid: req.JudgeModel != ""},
+			JudgePrompt:      pgtype.Text{String: req.JudgePrompt, Valid: req.JudgePrompt != ""},
+			Filter:           filter,
+			Status:           eval.StatusInProgress,
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			r.RunID = created.ID
+			if err := q.CreateEvalResult(ctx, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating evaluation run", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create evaluation run"})
+	}
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceEvalRun, created.ID.String(), nil, created)
+
+	return c.JSON(http.StatusCreated, toEvalRunResponse(created, EvalResultCounts{Total: int64(len(results)), Pending: int64(len(results))}))
+}
+
+// ListEvalRuns godoc
+// @Summary List evaluation runs
+// @Schemes
+// @Description List the evaluation runs of the authenticated user, newest first, with their progress.
+// @Tags Evaluation
+// @Produce json
+// @Param after query string false "ID of the last run of the previous page"
+// @Param limit query int false "Number of runs per page (max 100)" default(20)
+// @Success 200 {object} ListEvalRunsResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/eval-runs [get]
+func (s *Service) ListEvalRuns(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req ListEvalRunsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	req.Limit = min(req.Limit, maxFilesPageSize)
+
+	params := database.ListEvalRunsParams{UserID: userID, Limit: req.Limit + 1}
+	if req.After != "" {
+		after, err := s.getEvalRun(c, userID, req.After)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "after must be the ID of an evaluation run"})
+		}
+		params.CursorCreatedAt, params.CursorID = after.CreatedAt, after.ID
+	}
+
+	runs, err := s.db.ListEvalRuns(c.Request().Context(), params)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve evaluation runs"})
+	}
+
+	resp := ListEvalRunsResponse{Runs: []EvalRunResponse{}}
+	if int64(len(runs)) > req.Limit {
+		runs, resp.HasMore = runs[:req.Limit], true
+	}
+	for _, run := range runs {
+		counts, err := s.evalResultCounts(c, run.ID)
+		if err != nil {
+			return evalRunError(c, err)
+		}
+		resp.Runs = append(resp.Runs, toEvalRunResponse(run, counts))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetEvalRun godoc
+// @Summary Get an evaluation run
+// @Schemes
+// @Description Get an evaluation run with its progress and a summary comparing the logged answers with the candidate's on the results that succeeded: token counts, costs, average latencies and the average judge score.
+// @Tags Evaluation
+// @Produce json
+// @Param id path string true "Evaluation run ID"
+// @Success 200 {object} EvalRunResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/eval-runs/{id} [get]
+func (s *Service) GetEvalRun(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	run, err := s.getEvalRun(c, userID, c.Param("id"))
+	if err != nil {
+		return evalRunError(c, err)
+	}
+	counts, err := s.evalResultCounts(c, run.ID)
+	if err != nil {
+		return evalRunError(c, err)
+	}
+	summary, err := s.evalSummary(c, run.ID)
+	if err != nil {
+		return evalRunError(c, err)
+	}
+	resp := toEvalRunResponse(run, counts)
+	resp.Summary = &summary
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ListEvalResults godoc
+// @Summary List the results of an evaluation run
+// @Schemes
+// @Description List the replayed requests of an evaluation run in order, each with the logged answer, the candidate's and the judge's score.
+// @Tags Evaluation
+// @Produce json
+// @Param id path string true "Evaluation run ID"
+// @Param after query int false "last_line of the previous page"
+// @Param limit query int false "Number of results per page (max 100)" default(20)
+// @Param status query string false "Filter by status (pending, running, succeeded, failed, cancelled)"
+// @Success 200 {object} ListEvalResultsResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/eval-runs/{id}/results [get]
+func (s *Service) ListEvalResults(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req ListEvalResultsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	req.Limit = min(req.Limit, maxFilesPageSize)
+
+	run, err := s.getEvalRun(c, userID, c.Param("id"))
+	if err != nil {
+		return evalRunError(c, err)
+	}
+	rows, err := s.db.ListEvalResults(c.Request().Context(), database.ListEvalResultsParams{
+		RunID:     run.ID,
+		AfterLine: req.After,
+		Status:    pgtype.Text{String: req.Status, Valid: req.Status != ""},
+		Limit:     req.Limit + 1,
+	})
+	if err != nil {
+		return evalRunError(c, err)
+	}
+
+	resp := ListEvalResultsResponse{Results: []EvalResultResponse{}}
+	if int64(len(rows)) > req.Limit {
+		rows, resp.HasMore = rows[:req.Limit], true
+	}
+	for _, row := range rows {
+		resp.Results = append(resp.Results, toEvalResultResponse(run, row))
+	}
+	if len(rows) > 0 {
+		resp.LastLine = rows[len(rows)-1].Line
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CancelEvalRun godoc
+// @Summary Cancel an evaluation run
+// @Schemes
+// @Description Cancel an evaluation run in progress. Requests not replayed yet are skipped; the run is cancelled once the running ones have finished, keeping their results.
+// @Tags Evaluation
+// @Produce json
+// @Param id path string true "Evaluation run ID"
+// @Success 200 {object} EvalRunResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/eval-runs/{id}/cancel [post]
+func (s *Service) CancelEvalRun(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	run, err := s.getEvalRun(c, userID, c.Param("id"))
+	if err != nil {
+		return evalRunError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	switch run.Status {
+	case eval.StatusCancelling, eval.StatusCancelled:
+	case eval.StatusInProgress:
+		before := run
+		err = s.db.ExecTx(ctx, func(q database.Querier) error {
+			var err error
+			run, err = q.CancelEvalRun(ctx, database.CancelEvalRunParams{ID: run.ID, UserID: userID})
+			if err != nil {
+				return err
+			}
+			_, err = q.CancelEvalResults(ctx, run.ID)
+			return err
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: "the evaluation run finished before it could be cancelled"})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error cancelling evaluation run", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to cancel evaluation run"})
+		}
+		s.recordAudit(c, userID, AuditActionCancel, AuditResourceEvalRun, run.ID.String(), before, run)
+	default:
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("cannot cancel an evaluation run with status %s", run.Status)})
+	}
+
+	counts, err := s.evalResultCounts(c, run.ID)
+	if err != nil {
+		return evalRunError(c, err)
+	}
+	return c.JSON(http.StatusOK, toEvalRunResponse(run, counts))
+}
+
+// buildEvalSample parses the filter of a new run into sampling parameters.
+func buildEvalSample(userID pgtype.UUID, filter EvalFilter) (database.SampleEvalLogsParams, error) {
+	params := database.SampleEvalLogsParams{UserID: userID}
+	ids := []struct {
+		name  string
+		value string
+		dst   *pgtype.UUID
+	}{
+		{"filter.model_id", filter.ModelID, &params.ModelID},
+		{"filter.api_key_id", filter.APIKeyID, &params.ApiKeyID},
+	}
+	for _, id := range ids {
+		if id.value == "" {
+			continue
+		}
+		parsed, err := uuid.Parse(id.value)
+		if err != nil {
+			return params, fmt.Errorf("%s must be a UUID", id.name)
+		}
+		*id.dst = pgtype.UUID{Bytes: parsed, Valid: true}
+	}
+	if filter.Since != "" {
+		since, err := time.Parse(time.RFC3339, filter.Since)
+		if err != nil {
+			return params, errors.New("filter.since must be an RFC3339 timestamp")
+		}
+		params.Since = pgtype.Timestamptz{Time: since, Valid: true}
+	}
+	if filter.Until != "" {
+		until, err := time.Parse(time.RFC3339, filter.Until)
+		if err != nil {
+			return params, errors.New("filter.until must be an RFC3339 timestamp")
+		}
+		params.Until = pgtype.Timestamptz{Time: until, Valid: true}
+	}
+	return params, nil
+}
+
+// getEvalRun loads an evaluation run of the user by its ID as given by the
+// client.
+func (s *Service) getEvalRun(c echo.Context, userID pgtype.UUID, rawID string) (database.EvalRun, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return database.EvalRun{}, sql.ErrNoRows
+	}
+	return s.db.GetEvalRun(c.Request().Context(), database.GetEvalRunParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		UserID: userID,
+	})
+}
+
+func (s *Service) evalResultCounts(c echo.Context, runID pgtype.UUID) (EvalResultCounts, error) {
+	rows, err := s.db.CountEvalResults(c.Request().Context(), runID)
+	if err != nil {
+		return EvalResultCounts{}, err
+	}
+	var counts EvalResultCounts
+	for _, row := range rows {
+		counts.Total += row.Count
+		switch row.Status {
+		case eval.ResultPending, eval.ResultRunning:
+			counts.Pending += row.Count
+		case eval.ResultSucceeded:
+			counts.Succeeded += row.Count
+		case eval.ResultFailed:
+			counts.Failed += row.Count
+		case eval.ResultCancelled:
+			counts.Cancelled += row.Count
+		}
+	}
+	return counts, nil
+}
+
+// evalSummary adds up the succeeded results of a run, so both sides are
+// compared on the same requests.
+func (s *Service) evalSummary(c echo.Context, runID pgtype.UUID) (EvalSummary, error) {
+	var summary EvalSummary
+	var baselineLatency, candidateLatency, judgeScores average
+	params := database.ListEvalResultsParams{
+		RunID:  runID,
+		Status: pgtype.Text{String: eval.ResultSucceeded, Valid: true},
+		Limit:  maxEvalSampleSize,
+	}
+	for {
+		rows, err := s.db.ListEvalResults(c.Request().Context(), params)
+		if err != nil {
+			return EvalSummary{}, err
+		}
+		for _, row := range rows {
+			summary.Baseline.PromptTokens += row.BaselinePromptTokens.Int64
+			summary.Baseline.CompletionTokens += row.BaselineCompletionTokens.Int64
+			summary.Baseline.Cost += numericValue(row.BaselineCost)
+			summary.Candidate.PromptTokens += row.PromptTokens.Int64
+			summary.Candidate.CompletionTokens += row.CompletionTokens.Int64
+			summary.Candidate.Cost += numericValue(row.Cost)
+			baselineLatency.add(row.BaselineLatencyMs.Int64, row.BaselineLatencyMs.Valid)
+			candidateLatency.add(row.LatencyMs.Int64, row.LatencyMs.Valid)
+			judgeScores.add(int64(row.JudgeScore.Int32), row.JudgeScore.Valid)
+		}
+		if int64(len(rows)) < params.Limit {
+			break
+		}
+		params.AfterLine = rows[len(rows)-1].Line
+	}
+	summary.Baseline.AverageLatencyMs = baselineLatency.value()
+	summary.Candidate.AverageLatencyMs = candidateLatency.value()
+	summary.AverageJudgeScore, summary.Judged = judgeScores.value(), judgeScores.n
+	return summary, nil
+}
+
+// average is the mean of the known values added to it.
+type average struct {
+	sum, n int64
+}
+
+func (a *average) add(v int64, known bool) {
+	if known {
+		a.sum += v
+		a.n++
+	}
+}
+
+func (a average) value() *float64 {
+	if a.n == 0 {
+		return nil
+	}
+	v := float64(a.sum) / float64(a.n)
+	return &v
+}
+
+func numericValue(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil {
+		return 0
+	}
+	return f.Float64
+}
+
+func evalRunError(c echo.Context, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "evaluation run not found"})
+	}
+	slog.ErrorContext(c.Request().Context(), "Error reading evaluation run", "error", err)
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve evaluation run"})
+}
+
+func toEvalRunResponse(run database.EvalRun, counts EvalResultCounts) EvalRunResponse {
+	resp := EvalRunResponse{
+		ID:             run.ID,
+		Name:           run.Name,
+		CandidateModel: run.CandidateModel,
+		JudgeModel:     run.JudgeModel.String,
+		JudgePrompt:    run.JudgePrompt.String,
+		Status:         run.Status,
+		CreatedAt:      run.CreatedAt.Time,
+		CancellingAt:   timePtr(run.CancellingAt),
+		FinishedAt:     timePtr(run.FinishedAt),
+		Counts:         counts,
+	}
+	_ = json.Unmarshal(run.Filter, &resp.Filter)
+	return resp
+}
+
+func toEvalResultResponse(run database.EvalRun, row database.ListEvalResultsRow) EvalResultResponse {
+	resp := EvalResultResponse{
+		Line:     row.Line,
+		LogID:    row.LogID,
+		Request:  row.Request,
+		Status:   row.Status,
+		Attempts: row.Attempts,
+		Baseline: EvalAnswer{
+			Model:            row.BaselineModel.String,
+			Output:           textPtr(row.BaselineOutput),
+			PromptTokens:     int8Ptr(row.BaselinePromptTokens),
+			CompletionTokens: int8Ptr(row.BaselineCompletionTokens),
+			LatencyMs:        int8Ptr(row.BaselineLatencyMs),
+			Cost:             numericValue(row.BaselineCost),
+		},
+		Candidate: EvalAnswer{
+			Model:            run.CandidateModel,
+			StatusCode:       row.StatusCode.Int32,
+			RequestID:        row.RequestID.String,
+			Output:           textPtr(row.Output),
+			PromptTokens:     int8Ptr(row.PromptTokens),
+			CompletionTokens: int8Ptr(row.CompletionTokens),
+			LatencyMs:        int8Ptr(row.LatencyMs),
+			Cost:             numericValue(row.Cost),
+		},
+		JudgeReason: row.JudgeReason.String,
+		Error:       row.Error.String,
+	}
+	if row.JudgeScore.Valid {
+		resp.JudgeScore = &row.JudgeScore.Int32
+	}
+	return resp
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func textPtr(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+func int8Ptr(n pgtype.Int8) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"gen-ai-proxy/src/batch"
+	"gen-ai-proxy/src/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// purposeBatch is the only purpose files can be uploaded with.
+const purposeBatch = "batch"
+
+// maxFilesPageSize bounds the limit of OpenAI-style list endpoints.
+const maxFilesPageSize = 100
+
+// OpenAIFile is a file as returned by the OpenAI files API.
+type OpenAIFile struct {
+	ID        pgtype.UUID `json:"id"`
+	Object    string      `json:"object"`
+	Bytes     int64       `json:"bytes"`
+	CreatedAt int64       `json:"created_at"`
+	Filename  string      `json:"filename"`
+	Purpose   string      `json:"purpose"`
+	Status    string      `json:"status"`
+}
+
+type ListFilesRequest struct {
+	Purpose string `query:"purpose"`
+	After   string `query:"after"`
+	Limit   int64  `query:"limit"`
+}
+
+type ListFilesResponse struct {
+	Object  string       `json:"object"`
+	Data    []OpenAIFile `json:"data"`
+	FirstID *pgtype.UUID `json:"first_id"`
+	LastID  *pgtype.UUID `json:"last_id"`
+	HasMore bool         `json:"has_more"`
+}
+
+type DeleteFileResponse struct {
+	ID      pgtype.UUID `json:"id"`
+	Object  string      `json:"object"`
+	Deleted bool        `json:"deleted"`
+}
+
+// UploadFile godoc
+// @Summary Upload a file
+// @Schemes
+// @Description Upload a JSONL input file for the batch API, as with the OpenAI files API. Only the batch purpose is supported.
+// @Tags Batches
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "JSONL file with one request per line"
+// @Param purpose formData string true "Must be batch"
+// @Success 200 {object} OpenAIFile
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 413 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/files [post]
+func (s *Service) UploadFile(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	if purpose := c.FormValue("purpose"); purpose != purposeBatch {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "purpose must be batch"})
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "file is required"})
+	}
+	if header.Size > batch.MaxFileBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: fmt.Sprintf("file must be at most %d bytes", batch.MaxFileBytes)})
+	}
+	src, err := header.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "failed to read file"})
+	}
+	defer src.Close()
+	content, err := io.ReadAll(io.LimitReader(src, batch.MaxFileBytes+1))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "failed to read file"})
+	}
+	if len(content) > batch.MaxFileBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: fmt.Sprintf("file must be at most %d bytes", batch.MaxFileBytes)})
+	}
+
+	filename := header.Filename
+	if len(filename) > 255 {
+		filename = filename[:255]
+	}
+
+	ctx := c.Request().Context()
+	var file database.File
+	err = s.db.ExecTx(ctx, func(q database.Querier) error {
+		var err error
+		file, err = q.CreateFile(ctx, database.CreateFileParams{
+			ID:       pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			UserID:   userID,
+			Filename: filename,
+			Purpose:  purposeBatch,
+			Bytes:    int64(len(content)),
+		})
+		if err != nil {
+			return err
+		}
+		return q.CreateFileContent(ctx, database.CreateFileContentParams{FileID: file.ID, Content: content})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error storing file", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to store file"})
+	}
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceFile, file.ID.String(), nil, file)
+
+	return c.JSON(http.StatusOK, toOpenAIFile(file))
+}
+
+// ListFiles godoc
+// @Summary List files
+// @Schemes
+// @Description List uploaded files and batch result files, newest first, as with the OpenAI files API.
+// @Tags Batches
+// @Produce json
+// @Param purpose query string false "Filter by purpose (batch, batch_output)"
+// @Param after query string false "ID of the last file of the previous page"
+// @Param limit query int false "Number of files per page (max 100)" default(20)
+// @Success 200 {object} ListFilesResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/files [get]
+func (s *Service) ListFiles(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req ListFilesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	req.Limit = min(req.Limit, maxFilesPageSize)
+
+	ctx := c.Request().Context()
+	params := database.ListFilesParams{
+		UserID:  userID,
+		Purpose: pgtype.Text{String: req.Purpose, Valid: req.Purpose != ""},
+		Limit:   req.Limit + 1,
+	}
+	if req.After != "" {
+		after, err := s.getFile(c, userID, req.After)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "after must be the ID of a file"})
+		}
+		params.CursorCreatedAt, params.CursorID = after.CreatedAt, after.ID
+	}
+
+	files, err := s.db.ListFiles(ctx, params)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve files"})
+	}
+
+	resp := ListFilesResponse{Object: "list", Data: []OpenAIFile{}}
+	if int64(len(files)) > req.Limit {
+		files, resp.HasMore = files[:req.Limit], true
+	}
+	for _, f := range files {
+		resp.Data = append(resp.Data, toOpenAIFile(f))
+	}
+	if len(files) > 0 {
+		resp.FirstID, resp.LastID = &files[0].ID, &files[len(files)-1].ID
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetFile godoc
+// @Summary Get a file
+// @Schemes
+// @Description Get a file's metadata, as with the OpenAI files API.
+// @Tags Batches
+// @Produce json
+// @Param id path string true "File ID"
+// @Success 200 {object} OpenAIFile
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/files/{id} [get]
+func (s *Service) GetFile(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	file, err := s.getFile(c, userID, c.Param("id"))
+	if err != nil {
+		return fileError(c, err)
+	}
+	return c.JSON(http.StatusOK, toOpenAIFile(file))
+}
+
+// GetFileContent godoc
+// @Summary Download a file
+// @Schemes
+// @Description Download the content of a file, such as the output or error file of a batch.
+// @Tags Batches
+// @Produce application/jsonl
+// @Param id path string true "File ID"
+// @Success 200 {string} string "File content"
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/files/{id}/content [get]
+func (s *Service) GetFileContent(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return fileError(c, sql.ErrNoRows)
+	}
+	content, err := s.db.GetFileContent(c.Request().Context(), database.GetFileContentParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		return fileError(c, err)
+	}
+	return c.Blob(http.StatusOK, "application/jsonl", content)
+}
+
+// DeleteFile godoc
+// @Summary Delete a file
+// @Schemes
+// @Description Delete a file. Batches created from it are not affected.
+// @Tags Batches
+// @Produce json
+// @Param id path string true "File ID"
+// @Success 200 {object} DeleteFileResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/files/{id} [delete]
+func (s *Service) DeleteFile(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	before, err := s.getFile(c, userID, c.Param("id"))
+	if err != nil {
+		return fileError(c, err)
+	}
+	n, err := s.db.DeleteFile(c.Request().Context(), database.DeleteFileParams{ID: before.ID, UserID: userID})
+	if err != nil {
+		return fileError(c, err)
+	}
+	if n == 0 {
+		return fileError(c, sql.ErrNoRows)
+	}
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourceFile, before.ID.String(), before, nil)
+
+	return c.JSON(http.StatusOK, DeleteFileResponse{ID: before.ID, Object: "file", Deleted: true})
+}
+
+// getFile loads a file of the user by its ID as given by the client.
+func (s *Service) getFile(c echo.Context, userID pgtype.UUID, rawID string) (database.File, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return database.File{}, sql.ErrNoRows
+	}
+	return s.db.GetFile(c.Request().Context(), database.GetFileParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		UserID: userID,
+	})
+}
+
+func fileError(c echo.Context, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "file not found"})
+	}
+	slog.ErrorContext(c.Request().Context(), "Error reading file", "error", err)
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve file"})
+}
+
+func toOpenAIFile(f database.File) OpenAIFile {
+	return OpenAIFile{
+		ID:        f.ID,
+		Object:    "file",
+		Bytes:     f.Bytes,
+		CreatedAt: f.CreatedAt.Time.Unix(),
+		Filename:  f.Filename,
+		Purpose:   f.Purpose,
+		Status:    "processed",
+	}
+}
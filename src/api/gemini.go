@@ -0,0 +1,350 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+	"github.com/google/uuid"
+)
+
+// GeminiSafetySetting overrides a Gemini harm category's blocking threshold,
+// e.g. HARM_CATEGORY_HARASSMENT with BLOCK_ONLY_HIGH.
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent       `json:"contents"`
+	SystemInstruction *geminiContent        `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool          `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig     `json:"toolConfig,omitempty"`
+	SafetySettings    []GeminiSafetySetting `json:"safetySettings,omitempty"`
+	GenerationConfig  map[string]any        `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart is a text part, possibly a thought summary, or a function call.
+// Other kinds of parts are ignored.
+type geminiPart struct {
+	Text         string              `json:"text,omitempty"`
+	Thought      bool                `json:"thought,omitempty"`
+	FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parametersJsonSchema,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *geminiUsage `json:"usageMetadata"`
+	ModelVersion  string       `json:"modelVersion"`
+	ResponseID    string       `json:"responseId"`
+}
+
+type geminiCandidate struct {
+	Index        int           `json:"index"`
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiUsage struct {
+	PromptTokenCount     int64 `json:"promptTokenCount"`
+	CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int64 `json:"thoughtsTokenCount"`
+	TotalTokenCount      int64 `json:"totalTokenCount"`
+}
+
+type geminiEmbedRequest struct {
+	Content geminiContent `json:"content"`
+}
+
+type geminiEmbedResponse struct {
+	Embedding struct {
+		Values []float32 `json:"values"`
+	} `json:"embedding"`
+}
+
+// geminiParams maps parameter policy names to Gemini's generationConfig.
+// stop and reasoning_effort are translated separately.
+var geminiParams = map[string]string{
+	"temperature":       "temperature",
+	"top_p":             "topP",
+	"max_tokens":        "maxOutputTokens",
+	"seed":              "seed",
+	"presence_penalty":  "presencePenalty",
+	"frequency_penalty": "frequencyPenalty",
+}
+
+// geminiThinkingBudgets maps reasoning_effort to a thinking budget in tokens,
+// as Gemini's own OpenAI compatibility does.
+var geminiThinkingBudgets = map[string]int{
+	"none":    0,
+	"minimal": 512,
+	"low":     1024,
+	"medium":  8192,
+	"high":    24576,
+}
+
+// geminiURL returns the URL of a Gemini model method such as generateContent.
+func geminiURL(provider database.Provider, model database.Model, method string) string {
+	return provider.BaseUrl + "/models/" + strings.TrimPrefix(model.ProviderModelID, "models/") + ":" + method
+}
+
+// geminiChatRequest translates a chat completion request. System messages
+// become the system instruction and assistant messages the model's turns.
+// The returned error is meant for the client.
+func geminiChatRequest(call *chatCall, messages []ChatCompletionMessage) (geminiRequest, error) {
+	var req geminiRequest
+	var system []geminiPart
+	for _, m := range messages {
+		if m.Content == "" {
+			continue
+		}
+		part := geminiPart{Text: m.Content}
+		switch m.Role {
+		case "system", "developer":
+			system = append(system, part)
+		case "assistant":
+			req.Contents = append(req.Contents, geminiContent{Role: "model", Parts: []geminiPart{part}})
+		default:
+			req.Contents = append(req.Contents, geminiContent{Role: "user", Parts: []geminiPart{part}})
+		}
+	}
+	if len(system) > 0 {
+		req.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	var err error
+	if req.Tools, err = geminiTools(call.params["tools"]); err != nil {
+		return geminiRequest{}, err
+	}
+	if req.ToolConfig, err = geminiToolChoice(call.params["tool_choice"]); err != nil {
+		return geminiRequest{}, err
+	}
+	req.SafetySettings = call.req.SafetySettings
+	if req.GenerationConfig, err = geminiGenerationConfig(call.params, call.req.upstreamResponseFormat(call.output)); err != nil {
+		return geminiRequest{}, err
+	}
+	return req, nil
+}
+
+// geminiTools translates OpenAI function tools to function declarations.
+func geminiTools(tools any) ([]geminiTool, error) {
+	functions, err := functionTools(tools)
+	if err != nil || len(functions) == 0 {
+		return nil, err
+	}
+	declarations := make([]geminiFunctionDeclaration, len(functions))
+	for i, function := range functions {
+		declarations[i] = geminiFunctionDeclaration(function)
+	}
+	return []geminiTool{{FunctionDeclarations: declarations}}, nil
+}
+
+// geminiToolChoice translates tool_choice to a function calling mode.
+func geminiToolChoice(choice any) (*geminiToolConfig, error) {
+	mode, name, err := parseToolChoice(choice)
+	if err != nil || mode == "" {
+		return nil, err
+	}
+	config := geminiFunctionCallingConfig{Mode: "ANY"}
+	switch mode {
+	case "auto":
+		config.Mode = "AUTO"
+	case "none":
+		config.Mode = "NONE"
+	case "function":
+		config.AllowedFunctionNames = []string{name}
+	}
+	return &geminiToolConfig{FunctionCallingConfig: config}, nil
+}
+
+// geminiGenerationConfig translates sampling and reasoning parameters and the
+// response format.
+func geminiGenerationConfig(params map[string]any, format *ResponseFormat) (map[string]any, error) {
+	config := map[string]any{}
+	for name, field := range geminiParams {
+		if v, ok := params[name]; ok {
+			config[field] = v
+		}
+	}
+	switch stop := params["stop"].(type) {
+	case nil:
+	case string:
+		config["stopSequences"] = []string{stop}
+	default:
+		config["stopSequences"] = stop
+	}
+	if effort, ok := params["reasoning_effort"]; ok {
+		budget, ok := geminiThinkingBudgets[fmt.Sprint(effort)]
+		if !ok {
+			return nil, errors.New("reasoning_effort must be none, minimal, low, medium or high")
+		}
+		config["thinkingConfig"] = map[string]any{"thinkingBudget": budget}
+	}
+	if format != nil {
+		switch format.Type {
+		case "json_object":
+			config["responseMimeType"] = "application/json"
+		case "json_schema":
+			config["responseMimeType"] = "application/json"
+			if format.JSONSchema != nil {
+				config["responseJsonSchema"] = format.JSONSchema.Schema
+			}
+		}
+	}
+	if len(config) == 0 {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// chatCompletion translates a Gemini response. Thoughts are left out of the
+// content; their tokens count as completion tokens, as with OpenAI.
+func (r geminiResponse) chatCompletion(model string, created int64) ChatCompletionResponse {
+	resp := ChatCompletionResponse{
+		ID:      completionID(r.ResponseID),
+		Object:  "chat.completion",
+		Created: created,
+		Model:   model,
+		Choices: []ChatCompletionChoice{},
+		Usage:   r.usage(),
+	}
+	for _, candidate := range r.Candidates {
+		text, toolCalls := candidate.Content.output(0)
+		resp.Choices = append(resp.Choices, ChatCompletionChoice{
+			Index:        candidate.Index,
+			Message:      ChatCompletionResponseMessage{Role: "assistant", Content: text, ToolCalls: toolCalls},
+			FinishReason: geminiFinishReason(candidate.FinishReason, len(toolCalls) > 0),
+		})
+	}
+	if len(resp.Choices) == 0 && r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		// The prompt itself was blocked.
+		resp.Choices = append(resp.Choices, ChatCompletionChoice{
+			Message:      ChatCompletionResponseMessage{Role: "assistant"},
+			FinishReason: "content_filter",
+		})
+	}
+	return resp
+}
+
+func (r geminiResponse) usage() *ChatCompletionUsage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	u := r.UsageMetadata
+	completion := u.CandidatesTokenCount + u.ThoughtsTokenCount
+	return &ChatCompletionUsage{
+		PromptTokens:            u.PromptTokenCount,
+		CompletionTokens:        completion,
+		TotalTokens:             u.PromptTokenCount + completion,
+		CompletionTokensDetails: &ChatCompletionCompletionDetails{ReasoningTokens: u.ThoughtsTokenCount},
+	}
+}
+
+// output returns the text and function calls of a candidate's content. Tool
+// calls are numbered from firstIndex, for streams.
+func (c geminiContent) output(firstIndex int) (string, []ChatCompletionToolCall) {
+	var text strings.Builder
+	var toolCalls []ChatCompletionToolCall
+	for _, part := range c.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			if part.FunctionCall.Args == nil {
+				args = []byte("{}")
+			}
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = "call_" + randomHex()
+			}
+			index := firstIndex + len(toolCalls)
+			toolCalls = append(toolCalls, ChatCompletionToolCall{
+				Index:    &index,
+				ID:       id,
+				Type:     "function",
+				Function: ChatCompletionFunctionCall{Name: part.FunctionCall.Name, Arguments: string(args)},
+			})
+		case !part.Thought:
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String(), toolCalls
+}
+
+// geminiFinishReason maps a Gemini finish reason to OpenAI's.
+func geminiFinishReason(reason string, toolCalls bool) string {
+	if toolCalls {
+		return "tool_calls"
+	}
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+func completionID(responseID string) string {
+	if responseID == "" {
+		responseID = randomHex()
+	}
+	return "chatcmpl-" + responseID
+}
+
+func randomHex() string {
+	id := uuid.New()
+	return hex.EncodeToString(id[:])
+}
+
+// encodeEmbedding returns an embedding as OpenAI does for encoding_format:
+// floats, or base64 of little-endian float32s.
+func encodeEmbedding(values []float32, format string) any {
+	if format != "base64" {
+		return values
+	}
+	buf := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// readinessTimeout bounds each readiness check so a hung dependency cannot
+// stall the probe past the orchestrator's own timeout.
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck is one dependency /readyz verifies.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// AddReadinessCheck registers a dependency /readyz must find healthy.
+func (s *Service) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	s.readinessChecks = append(s.readinessChecks, ReadinessCheck{Name: name, Check: check})
+}
+
+// SetDraining makes /readyz fail so load balancers stop sending new requests
+// while the server shuts down.
+func (s *Service) SetDraining() {
+	s.draining.Store(true)
+}
+
+// Healthz godoc
+// @Summary Liveness probe
+// @Schemes
+// @Description Reports that the process is running. It does not check dependencies, so a database outage does not get the pod restarted.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (s *Service) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Schemes
+// @Description Reports whether the proxy can serve traffic: the database is reachable, migrations are current and the configured upstreams answer. Fails while the server is shutting down.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
+// @Router /readyz [get]
+func (s *Service) Readyz(c echo.Context) error {
+	if s.draining.Load() {
+		return c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: "shutting down"})
+	}
+
+	ctx := c.Request().Context()
+	results := make([]error, len(s.readinessChecks))
+	var wg sync.WaitGroup
+	for i, check := range s.readinessChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			results[i] = check.Check(checkCtx)
+		}()
+	}
+	wg.Wait()
+
+	resp := HealthResponse{Status: "ok", Checks: map[string]string{}}
+	status := http.StatusOK
+	for i, check := range s.readinessChecks {
+		if err := results[i]; err != nil {
+			slog.WarnContext(ctx, "Readiness check failed", "check", check.Name, "error", err)
+			resp.Checks[check.Name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[check.Name] = "ok"
+	}
+	return c.JSON(status, resp)
+}
+
+// upstreamCheck reports an upstream as reachable when it answers without a
+// server error. Client errors are expected because the probe sends no credentials.
+func (s *Service) upstreamCheck(target string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.httpClient.Load().Do(req)
+		if err != nil {
+			return fmt.Errorf("unreachable: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("answered %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
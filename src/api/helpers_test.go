@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/database/sqlite"
+	"gen-ai-proxy/src/encryption"
+	"gen-ai-proxy/src/routing"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// testEnv is a Service on a fresh SQLite database with one user.
+type testEnv struct {
+	t      *testing.T
+	s      *Service
+	store  *sqlite.Store
+	routes *routing.Table
+	user   pgtype.UUID
+	key    []byte
+}
+
+// newTestEnv migrates a new SQLite database and builds a Service on it. cfg
+// may set the upstream mode; the encryption key is filled in.
+func newTestEnv(t *testing.T, cfg config.Config) *testEnv {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "proxy.db")
+
+	src, err := iofs.New(os.DirFS("../.."), "db/sqlite/migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, "sqlite://"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	db, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	env := &testEnv{t: t, store: sqlite.NewStore(db), key: make([]byte, 32)}
+	user, err := env.store.CreateUser(ctx, database.CreateUserParams{Username: "test", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.user = user.ID
+
+	cfg.EncryptionKey = base64.StdEncoding.EncodeToString(env.key)
+	if env.routes, err = routing.NewTable(ctx, env.store, cfg.EncryptionKey, 0); err != nil {
+		t.Fatal(err)
+	}
+	if env.s, err = NewService(env.store, &cfg, env.routes); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+// addConnection adds a connection named name to a new provider at baseURL.
+func (env *testEnv) addConnection(providerType, baseURL, name string) database.CreateConnectionRow {
+	env.t.Helper()
+	ctx := context.Background()
+	provider, err := env.store.CreateProvider(ctx, database.CreateProviderParams{
+		ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, UserID: env.user, Name: name, BaseUrl: baseURL, Type: providerType,
+	})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	apiKey, err := encryption.Encrypt(env.key, []byte("sk-test"))
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	conn, err := env.store.CreateConnection(ctx, database.CreateConnectionParams{
+		UserID: env.user, ProviderID: provider.ID.String(), EncryptedApiKey: apiKey, Name: name,
+	})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return conn
+}
+
+// addModel adds a model of type typ, served by a new provider at baseURL, and
+// reloads the routing table.
+func (env *testEnv) addModel(providerType, baseURL, proxyModelID, providerModelID, typ string) database.Model {
+	env.t.Helper()
+	ctx := context.Background()
+	conn := env.addConnection(providerType, baseURL, proxyModelID)
+	model, err := env.store.CreateModel(ctx, database.CreateModelParams{
+		ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, UserID: env.user, ConnectionID: conn.ID,
+		ProxyModelID: proxyModelID, ProviderModelID: providerModelID, Type: typ,
+		PriceInput:  pgtype.Numeric{Int: big.NewInt(1), Exp: -6, Valid: true},
+		PriceOutput: pgtype.Numeric{Int: big.NewInt(2), Exp: -6, Valid: true},
+	})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	if _, err := env.routes.Refresh(ctx); err != nil {
+		env.t.Fatal(err)
+	}
+	return model
+}
+
+// serve sends a JSON request to handler on e as the test user, and waits for
+// the work the handler left in the background, such as saving its log.
+func (env *testEnv) serve(e *echo.Echo, handler echo.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	env.t.Helper()
+	e.POST(path, handler, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(userContextKey, env.user)
+			return next(c)
+		}
+	})
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	env.s.background.Wait()
+	return rec
+}
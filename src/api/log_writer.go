@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/logging"
+	"gen-ai-proxy/src/metrics"
+	"gen-ai-proxy/src/redaction"
+	"gen-ai-proxy/src/telemetry"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// saveLog persists a conversation log after applying the model's log policy.
+// Token counts in params must already be parsed from the original payloads, so
+// they stay accurate whatever ends up being stored. The request ID is taken
+// from ctx, so callers running after the response should pass a context
+// derived from the request with context.WithoutCancel. The latency, prompt
+// template version, batch, evaluation run and shadow traffic link, if any,
+// are taken from ctx as well.
+func (s *Service) saveLog(ctx context.Context, model database.Model, params database.CreateLogParams) (database.CreateLogRow, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "persist conversation log")
+	defer span.End()
+
+	policy, err := redaction.ParsePolicy(model.LogPolicy, s.defaultLogPolicy)
+	if err != nil {
+		// Fail closed: an unreadable policy must not leak payloads.
+		slog.WarnContext(ctx, "Invalid log policy, storing metadata only", "model", model.ProxyModelID, "error", err)
+		policy = redaction.PolicyMetadata
+	}
+
+	if id := logging.RequestID(ctx); id != "" {
+		params.RequestID = pgtype.Text{String: id, Valid: true}
+	}
+	params.PromptTemplateID, params.PromptTemplateVersion = promptTemplateLogFields(ctx)
+	params.BatchID = batchLogField(ctx)
+	params.EvalRunID = evalRunLogField(ctx)
+	params.LatencyMs = requestLatency(ctx)
+	shadowLogFields(ctx, model, &params)
+
+	switch policy {
+	case redaction.PolicyRedacted:
+		counts := redaction.Counts{}
+		params.RequestPayload = s.redactor.RedactPayload(params.RequestPayload, counts)
+		params.ResponsePayload = s.redactor.RedactPayload(params.ResponsePayload, counts)
+		for detector, n := range counts {
+			metrics.RedactionsTotal.WithLabelValues(detector, model.ProxyModelID).Add(float64(n))
+		}
+	case redaction.PolicyMetadata:
+		params.RequestPayload = redaction.MetadataPayload(params.RequestPayload)
+		params.ResponsePayload = redaction.MetadataPayload(params.ResponsePayload)
+	}
+
+	metrics.LogPayloadsTotal.WithLabelValues(string(policy)).Inc()
+	row, err := s.db.CreateLog(ctx, params)
+	if err != nil {
+		telemetry.RecordError(span, err)
+	}
+	return row, err
+}
+
+// inBackground runs fn after the response has been sent, typically to write
+// its conversation log. DrainBackground waits for it on shutdown.
+func (s *Service) inBackground(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// DrainBackground waits until every background log write has finished, or
+// returns ctx's error if they do not finish in time.
+func (s *Service) DrainBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// logPayload writes a raw upstream payload at debug level when LOG_PAYLOADS is
+// enabled. Payloads can contain personal data, so this is off by default.
+func (s *Service) logPayload(ctx context.Context, msg string, payload []byte) {
+	if !s.logPayloads.Load() {
+		return
+	}
+	slog.DebugContext(ctx, msg, "payload", string(payload))
+}
@@ -4,21 +4,33 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/logging"
 	"gen-ai-proxy/src/models"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
 
+// RequestIDHeader carries the request ID between clients, the proxy and upstream providers.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied IDs before they reach logs and the database.
+const maxRequestIDLength = 128
+
 const (
-	userContextKey = "userID"
+	userContextKey         = "userID"
+	apiKeyContextKey       = "apiKeyID"
+	apiKeyRecordContextKey = "apiKey"
 )
 
-func APIKeyAuthMiddleware(db *database.Queries) echo.MiddlewareFunc {
+func APIKeyAuthMiddleware(db database.Querier) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			apiKey := c.Request().Header.Get("X-API-Key")
@@ -37,19 +49,21 @@ func APIKeyAuthMiddleware(db *database.Queries) echo.MiddlewareFunc {
 			hash := sha256.Sum256([]byte(apiKey))
 			apiKeyHash := hex.EncodeToString(hash[:])
 
-			apiKeyRecord, err := db.GetAPIKeyByHash(context.Background(), apiKeyHash)
+			apiKeyRecord, err := db.GetAPIKeyByHash(c.Request().Context(), apiKeyHash)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid API Key"})
 			}
 
 			// Update last_used_at timestamp
-			err = db.UpdateAPIKeyLastUsed(context.Background(), apiKeyRecord.ID)
+			err = db.UpdateAPIKeyLastUsed(c.Request().Context(), apiKeyRecord.ID)
 			if err != nil {
 				// Log the error but don't block the request
-				c.Logger().Errorf("Failed to update API key last_used_at: %v", err)
+				slog.ErrorContext(c.Request().Context(), "Failed to update API key last_used_at", "error", err)
 			}
 
 			c.Set(userContextKey, apiKeyRecord.UserID)
+			c.Set(apiKeyContextKey, apiKeyRecord.ID)
+			c.Set(apiKeyRecordContextKey, apiKeyRecord)
 
 			return next(c)
 		}
@@ -98,3 +112,88 @@ func GetUserIDFromContext(c echo.Context) (pgtype.UUID, error) {
 	}
 	return userID, nil
 }
+
+// GetAPIKeyIDFromContext returns the API key that authenticated the request,
+// or an invalid UUID for requests authenticated another way.
+func GetAPIKeyIDFromContext(c echo.Context) pgtype.UUID {
+	apiKeyID, _ := c.Get(apiKeyContextKey).(pgtype.UUID)
+	return apiKeyID
+}
+
+// RequestIDMiddleware reuses a valid incoming X-Request-ID or generates one,
+// stores it in the request context and echoes it back to the client. The
+// time the request arrived is stored as well, for the latency of its log.
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+			}
+
+			ctx := withRequestStart(req.Context(), time.Now())
+			c.SetRequest(req.WithContext(logging.WithRequestID(ctx, id)))
+			c.Response().Header().Set(RequestIDHeader, id)
+			return next(c)
+		}
+	}
+}
+
+type requestStartKey struct{}
+
+func withRequestStart(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, requestStartKey{}, start)
+}
+
+// requestLatency is the time since the request in ctx arrived, or NULL when
+// it is not known.
+func requestLatency(ctx context.Context) pgtype.Int8 {
+	start, ok := ctx.Value(requestStartKey{}).(time.Time)
+	if !ok {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: time.Since(start).Milliseconds(), Valid: true}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLogMiddleware writes one structured log line per HTTP request.
+func AccessLogMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			level := slog.LevelInfo
+			if res.Status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			slog.Log(req.Context(), level, "HTTP request",
+				"method", req.Method,
+				"path", req.URL.Path,
+				"status", res.Status,
+				"latency_ms", time.Since(start).Milliseconds(),
+				"bytes_out", res.Size,
+				"remote_ip", c.RealIP(),
+				"user_agent", req.UserAgent(),
+			)
+			return err
+		}
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 
 	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/parampolicy"
+	"gen-ai-proxy/src/redaction"
+	"gen-ai-proxy/src/shadow"
+	"gen-ai-proxy/src/structuredoutput"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
@@ -44,14 +48,19 @@ func (s *Service) CreateModel(c echo.Context) error {
 	}
 
 	var req struct {
-		ConnectionID    string  `json:"connection_id"`
-		ProviderModelID string  `json:"provider_model_id"`
-		ProxyModelID    string  `json:"proxy_model_id"`
-		PriceInput      float64 `json:"price_input"`
-		PriceOutput     float64 `json:"price_output"`
-		Thinking        bool    `json:"thinking"`
-		ToolsUsage      bool    `json:"tools_usage"`
-		Type            string  `json:"type"`
+		ConnectionID     string                   `json:"connection_id"`
+		ProviderModelID  string                   `json:"provider_model_id"`
+		ProxyModelID     string                   `json:"proxy_model_id"`
+		PriceInput       float64                  `json:"price_input"`
+		PriceOutput      float64                  `json:"price_output"`
+		Thinking         bool                     `json:"thinking"`
+		ToolsUsage       bool                     `json:"tools_usage"`
+		Type             string                   `json:"type"`
+		LogPolicy        string                   `json:"log_policy"`
+		ParamPolicy      *parampolicy.Policy      `json:"param_policy"`
+		PromptTemplateID string                   `json:"prompt_template_id"`
+		StructuredOutput *structuredoutput.Policy `json:"structured_output"`
+		Shadow           *shadow.Policy           `json:"shadow"`
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -66,6 +75,31 @@ func (s *Service) CreateModel(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "type is required"})
 	}
 
+	if _, err := redaction.ParsePolicy(req.LogPolicy, ""); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := req.ParamPolicy.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	paramPolicy, err := req.ParamPolicy.Marshal()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := req.StructuredOutput.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	structuredOutput, err := req.StructuredOutput.Marshal()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	promptTemplateID, err := s.promptTemplateRef(c.Request().Context(), userID, req.PromptTemplateID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
 	// Check if model with the same proxy_model_id already exists for this user
 	_, err = s.db.GetModelByProxyModelID(c.Request().Context(), database.GetModelByProxyModelIDParams{
 		ProxyModelID: req.ProxyModelID,
@@ -75,7 +109,7 @@ func (s *Service) CreateModel(c echo.Context) error {
 		return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Model with proxy_model_id '%s' already exists for this user", req.ProxyModelID)})
 	} else if !errors.Is(err, sql.ErrNoRows) {
 		// Handle unexpected errors from the database
-		log.Printf("Error checking for existing model: %v", err)
+		slog.ErrorContext(c.Request().Context(), "Error checking for existing model", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Error checking for existing model"})
 	}
 
@@ -92,40 +126,60 @@ func (s *Service) CreateModel(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, ErrorResponse{Error: fmt.Sprintf("Connection with ID %s not found for this user", req.ConnectionID)})
 	}
 
+	shadowPolicy, err := s.shadowPolicy(c.Request().Context(), userID, req.Shadow, pgtype.UUID{Bytes: connectionID, Valid: true}, req.ProviderModelID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
 	modelPK := pgtype.UUID{Bytes: uuid.New(), Valid: true}
 
 	createdModel, err := s.db.CreateModel(c.Request().Context(), database.CreateModelParams{
-		ID:              modelPK,
-		UserID:          userID,
-		ConnectionID:    pgtype.UUID{Bytes: connectionID, Valid: true},
-		ProxyModelID:    req.ProxyModelID,
-		ProviderModelID: req.ProviderModelID,
-		Thinking:        req.Thinking,
-		ToolsUsage:      req.ToolsUsage,
-		PriceInput:      mustNumeric(req.PriceInput),
-		PriceOutput:     mustNumeric(req.PriceOutput),
-		Type:            req.Type,
+		ID:               modelPK,
+		UserID:           userID,
+		ConnectionID:     pgtype.UUID{Bytes: connectionID, Valid: true},
+		ProxyModelID:     req.ProxyModelID,
+		ProviderModelID:  req.ProviderModelID,
+		Thinking:         req.Thinking,
+		ToolsUsage:       req.ToolsUsage,
+		PriceInput:       mustNumeric(req.PriceInput),
+		PriceOutput:      mustNumeric(req.PriceOutput),
+		Type:             req.Type,
+		LogPolicy:        req.LogPolicy,
+		ParamPolicy:      paramPolicy,
+		PromptTemplateID: promptTemplateID,
+		StructuredOutput: structuredOutput,
+		Shadow:           shadowPolicy,
 	})
 	if err != nil {
-		fmt.Println("Error creating model in DB:", err)
+		slog.ErrorContext(c.Request().Context(), "Error creating model in DB", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create model"})
 	}
 
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceModel, createdModel.ID.String(), nil, createdModel)
+
 	priceInputFloat, _ := createdModel.PriceInput.Float64Value()
 	priceOutputFloat, _ := createdModel.PriceOutput.Float64Value()
 
 	resp := Model{
-		ID:              createdModel.ID,
-		ConnectionID:    createdModel.ConnectionID,
-		ProviderModelID: createdModel.ProviderModelID,
-		ProxyModelID:    createdModel.ProxyModelID,
-		Thinking:        createdModel.Thinking,
-		ToolsUsage:      createdModel.ToolsUsage,
-		PriceInput:      priceInputFloat.Float64,
-		PriceOutput:     priceOutputFloat.Float64,
-		Type:            createdModel.Type,
+		ID:               createdModel.ID,
+		ConnectionID:     createdModel.ConnectionID,
+		ProviderModelID:  createdModel.ProviderModelID,
+		ProxyModelID:     createdModel.ProxyModelID,
+		Thinking:         createdModel.Thinking,
+		ToolsUsage:       createdModel.ToolsUsage,
+		PriceInput:       priceInputFloat.Float64,
+		PriceOutput:      priceOutputFloat.Float64,
+		Type:             createdModel.Type,
+		LogPolicy:        createdModel.LogPolicy,
+		ParamPolicy:      parampolicy.Decode(createdModel.ParamPolicy),
+		PromptTemplateID: createdModel.PromptTemplateID,
+		StructuredOutput: structuredoutput.Decode(createdModel.StructuredOutput),
+		Shadow:           shadow.Decode(createdModel.Shadow),
+		Managed:          createdModel.Managed,
 	}
 
+	s.refreshRoutes(c)
+
 	return c.JSON(http.StatusCreated, resp)
 }
 
@@ -142,6 +196,7 @@ func (s *Service) CreateModel(c echo.Context) error {
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /api/models/{id} [put]
@@ -158,49 +213,111 @@ func (s *Service) UpdateModel(c echo.Context) error {
 	}
 
 	var req struct {
-		ProviderModelID string  `json:"provider_model_id"`
-		ProxyModelID    string  `json:"proxy_model_id"`
-		PriceInput      float64 `json:"price_input"`
-		PriceOutput     float64 `json:"price_output"`
-		Thinking        bool    `json:"thinking"`
-		ToolsUsage      bool    `json:"tools_usage"`
-		Type            string  `json:"type"`
+		ProviderModelID  string                   `json:"provider_model_id"`
+		ProxyModelID     string                   `json:"proxy_model_id"`
+		PriceInput       float64                  `json:"price_input"`
+		PriceOutput      float64                  `json:"price_output"`
+		Thinking         bool                     `json:"thinking"`
+		ToolsUsage       bool                     `json:"tools_usage"`
+		Type             string                   `json:"type"`
+		LogPolicy        string                   `json:"log_policy"`
+		ParamPolicy      *parampolicy.Policy      `json:"param_policy"`
+		PromptTemplateID string                   `json:"prompt_template_id"`
+		StructuredOutput *structuredoutput.Policy `json:"structured_output"`
+		Shadow           *shadow.Policy           `json:"shadow"`
 	}
 
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
+	if _, err := redaction.ParsePolicy(req.LogPolicy, ""); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := req.ParamPolicy.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	paramPolicy, err := req.ParamPolicy.Marshal()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := req.StructuredOutput.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	structuredOutput, err := req.StructuredOutput.Marshal()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	promptTemplateID, err := s.promptTemplateRef(c.Request().Context(), userID, req.PromptTemplateID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	before, err := s.db.GetModel(c.Request().Context(), database.GetModelParams{
+		ID:     pgtype.UUID{Bytes: modelID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Model not found"})
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("Model"))
+	}
+
+	shadowPolicy, err := s.shadowPolicy(c.Request().Context(), userID, req.Shadow, before.ConnectionID, req.ProviderModelID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
 	updatedModel, err := s.db.UpdateModel(c.Request().Context(), database.UpdateModelParams{
-		ID:              pgtype.UUID{Bytes: modelID, Valid: true},
-		UserID:          userID,
-		ProxyModelID:    req.ProxyModelID,
-		ProviderModelID: req.ProviderModelID,
-		Thinking:        req.Thinking,
-		ToolsUsage:      req.ToolsUsage,
-		PriceInput:      mustNumeric(req.PriceInput),
-		PriceOutput:     mustNumeric(req.PriceOutput),
-		Type:            req.Type,
+		ID:               pgtype.UUID{Bytes: modelID, Valid: true},
+		UserID:           userID,
+		ProxyModelID:     req.ProxyModelID,
+		ProviderModelID:  req.ProviderModelID,
+		Thinking:         req.Thinking,
+		ToolsUsage:       req.ToolsUsage,
+		PriceInput:       mustNumeric(req.PriceInput),
+		PriceOutput:      mustNumeric(req.PriceOutput),
+		Type:             req.Type,
+		LogPolicy:        req.LogPolicy,
+		ConnectionID:     before.ConnectionID,
+		ParamPolicy:      paramPolicy,
+		PromptTemplateID: promptTemplateID,
+		StructuredOutput: structuredOutput,
+		Shadow:           shadowPolicy,
 	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to update model"})
 	}
 
+	s.recordAudit(c, userID, AuditActionUpdate, AuditResourceModel, updatedModel.ID.String(), before, updatedModel)
+
 	priceInputFloat, _ := updatedModel.PriceInput.Float64Value()
 	priceOutputFloat, _ := updatedModel.PriceOutput.Float64Value()
 
 	resp := Model{
-		ID:              updatedModel.ID,
-		ConnectionID:    updatedModel.ConnectionID,
-		ProviderModelID: updatedModel.ProviderModelID,
-		ProxyModelID:    updatedModel.ProxyModelID,
-		Thinking:        updatedModel.Thinking,
-		ToolsUsage:      updatedModel.ToolsUsage,
-		PriceInput:      priceInputFloat.Float64,
-		PriceOutput:     priceOutputFloat.Float64,
-		Type:            updatedModel.Type,
+		ID:               updatedModel.ID,
+		ConnectionID:     updatedModel.ConnectionID,
+		ProviderModelID:  updatedModel.ProviderModelID,
+		ProxyModelID:     updatedModel.ProxyModelID,
+		Thinking:         updatedModel.Thinking,
+		ToolsUsage:       updatedModel.ToolsUsage,
+		PriceInput:       priceInputFloat.Float64,
+		PriceOutput:      priceOutputFloat.Float64,
+		Type:             updatedModel.Type,
+		LogPolicy:        updatedModel.LogPolicy,
+		ParamPolicy:      parampolicy.Decode(updatedModel.ParamPolicy),
+		PromptTemplateID: updatedModel.PromptTemplateID,
+		StructuredOutput: structuredoutput.Decode(updatedModel.StructuredOutput),
+		Shadow:           shadow.Decode(updatedModel.Shadow),
+		Managed:          updatedModel.Managed,
 	}
 
+	s.refreshRoutes(c)
+
 	return c.JSON(http.StatusOK, resp)
 }
 
@@ -232,15 +349,21 @@ func (s *Service) ListModels(c echo.Context) error {
 		priceOutputFloat, _ := m.PriceOutput.Float64Value()
 
 		respModels[i] = Model{
-			ID:              m.ID,
-			ConnectionID:    m.ConnectionID,
-			ProviderModelID: m.ProviderModelID,
-			ProxyModelID:    m.ProxyModelID,
-			Thinking:        m.Thinking,
-			ToolsUsage:      m.ToolsUsage,
-			PriceInput:      priceInputFloat.Float64,
-			PriceOutput:     priceOutputFloat.Float64,
-			Type:            m.Type,
+			ID:               m.ID,
+			ConnectionID:     m.ConnectionID,
+			ProviderModelID:  m.ProviderModelID,
+			ProxyModelID:     m.ProxyModelID,
+			Thinking:         m.Thinking,
+			ToolsUsage:       m.ToolsUsage,
+			PriceInput:       priceInputFloat.Float64,
+			PriceOutput:      priceOutputFloat.Float64,
+			Type:             m.Type,
+			LogPolicy:        m.LogPolicy,
+			ParamPolicy:      parampolicy.Decode(m.ParamPolicy),
+			PromptTemplateID: m.PromptTemplateID,
+			StructuredOutput: structuredoutput.Decode(m.StructuredOutput),
+			Shadow:           shadow.Decode(m.Shadow),
+			Managed:          m.Managed,
 		}
 	}
 	return c.JSON(http.StatusOK, respModels)
@@ -258,15 +381,16 @@ func (s *Service) ListModels(c echo.Context) error {
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /api/models/{id} [delete]
 func (s *Service) SoftDeleteModel(c echo.Context) error {
 	modelIDStr := c.Param("id")
-	log.Printf("SoftDeleteModel: Received ID string: %s", modelIDStr)
+	slog.DebugContext(c.Request().Context(), "SoftDeleteModel: received ID", "id", modelIDStr)
 	modelID, err := uuid.Parse(modelIDStr)
 	if err != nil {
-		log.Printf("SoftDeleteModel: Error parsing ID '%s': %v", modelIDStr, err)
+		slog.InfoContext(c.Request().Context(), "SoftDeleteModel: invalid ID", "id", modelIDStr, "error", err)
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid Model ID"})
 	}
 
@@ -275,6 +399,17 @@ func (s *Service) SoftDeleteModel(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
 	}
 
+	before, err := s.db.GetModel(c.Request().Context(), database.GetModelParams{
+		ID:     pgtype.UUID{Bytes: modelID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Model not found"})
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("Model"))
+	}
+
 	err = s.db.SoftDeleteModel(c.Request().Context(), database.SoftDeleteModelParams{
 		ID:     pgtype.UUID{Bytes: modelID, Valid: true},
 		UserID: userID,
@@ -283,5 +418,9 @@ func (s *Service) SoftDeleteModel(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to soft delete model"})
 	}
 
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourceModel, before.ID.String(), before, nil)
+
+	s.refreshRoutes(c)
+
 	return c.NoContent(http.StatusNoContent)
 }
\ No newline at end of file
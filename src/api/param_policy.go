@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	"gen-ai-proxy/src/routing"
+)
+
+// ollamaOptions maps parameter policy names to Ollama's options.
+var ollamaOptions = map[string]string{
+	"temperature":       "temperature",
+	"top_p":             "top_p",
+	"max_tokens":        "num_predict",
+	"seed":              "seed",
+	"presence_penalty":  "presence_penalty",
+	"frequency_penalty": "frequency_penalty",
+	"stop":              "stop",
+}
+
+// applyParamPolicy applies the model's parameter policy to params in place. The
+// returned error is meant for the client.
+func (s *Service) applyParamPolicy(ctx context.Context, route routing.Route, params map[string]any) error {
+	removed, err := route.ParamPolicy.Apply(params, route.Model.ToolsUsage, route.Model.Thinking)
+	if err != nil {
+		return err
+	}
+	if len(removed) > 0 {
+		slog.DebugContext(ctx, "Removed parameters the model is not configured for", "model", route.Model.ProxyModelID, "parameters", removed)
+	}
+	return nil
+}
+
+// Params returns the parameters of the request a parameter policy applies to.
+func (r ChatCompletionRequest) Params() map[string]any {
+	params := map[string]any{}
+	setIfPresent(params, "temperature", r.Temperature)
+	setIfPresent(params, "top_p", r.TopP)
+	setIfPresent(params, "max_tokens", r.MaxTokens)
+	// Newer clients send max_completion_tokens; it takes precedence.
+	setIfPresent(params, "max_tokens", r.MaxCompletionTokens)
+	setIfPresent(params, "seed", r.Seed)
+	setIfPresent(params, "presence_penalty", r.PresencePenalty)
+	setIfPresent(params, "frequency_penalty", r.FrequencyPenalty)
+	if r.Stop != nil {
+		params["stop"] = r.Stop
+	}
+	if r.Tools != nil {
+		params["tools"] = r.Tools
+	}
+	if r.ToolChoice != nil {
+		params["tool_choice"] = r.ToolChoice
+	}
+	if r.ReasoningEffort != "" {
+		params["reasoning_effort"] = r.ReasoningEffort
+	}
+	return params
+}
+
+// upstreamParams names max_tokens the way the client did.
+func (r ChatCompletionRequest) upstreamParams(params map[string]any) map[string]any {
+	if v, ok := params["max_tokens"]; ok && r.MaxCompletionTokens != nil {
+		delete(params, "max_tokens")
+		params["max_completion_tokens"] = v
+	}
+	return params
+}
+
+// Params returns the parameters of the request a parameter policy applies to,
+// translated from Ollama's options. think maps to reasoning_effort.
+func (r OllamaChatRequest) Params() map[string]any {
+	params := map[string]any{}
+	for name, option := range ollamaOptions {
+		if v, ok := r.Options[option]; ok {
+			params[name] = v
+		}
+	}
+	if r.Think {
+		params["reasoning_effort"] = "medium"
+	}
+	return params
+}
+
+// upstreamFields translates params back to Ollama's options and think flag,
+// keeping options no policy applies to.
+func (r OllamaChatRequest) upstreamFields(params map[string]any) (options map[string]any, think *bool) {
+	options = map[string]any{}
+	for option, v := range r.Options {
+		options[option] = v
+	}
+	for name, option := range ollamaOptions {
+		delete(options, option)
+		if v, ok := params[name]; ok {
+			options[option] = v
+		}
+	}
+	if effort, ok := params["reasoning_effort"]; ok {
+		enabled := effort != "none"
+		think = &enabled
+	}
+	return options, think
+}
+
+// setIfPresent stores a pointer field as a float64, the type JSON decoding
+// gives policy values.
+func setIfPresent[T int | int64 | float64](params map[string]any, name string, value *T) {
+	if value != nil {
+		params[name] = float64(*value)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+
+	"gen-ai-proxy/src/prompttemplate"
+	"gen-ai-proxy/src/routing"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type promptTemplateKey struct{}
+
+// withPromptTemplate records the template version a request was sent with, so
+// saveLog can store it with the conversation.
+func withPromptTemplate(ctx context.Context, template *prompttemplate.Template) context.Context {
+	if template == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, promptTemplateKey{}, template)
+}
+
+func promptTemplateFrom(ctx context.Context) *prompttemplate.Template {
+	template, _ := ctx.Value(promptTemplateKey{}).(*prompttemplate.Template)
+	return template
+}
+
+// applyPromptTemplate adds the model's prompt template to messages. The
+// returned error is meant for the client.
+func applyPromptTemplate(route routing.Route, messages []ChatCompletionMessage, values map[string]string) ([]ChatCompletionMessage, error) {
+	if route.PromptTemplate == nil {
+		return messages, nil
+	}
+	in := make([]prompttemplate.Message, len(messages))
+	for i, m := range messages {
+		in[i] = prompttemplate.Message(m)
+	}
+	rendered, err := route.PromptTemplate.Apply(in, values)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ChatCompletionMessage, len(rendered))
+	for i, m := range rendered {
+		out[i] = ChatCompletionMessage(m)
+	}
+	return out, nil
+}
+
+// promptTemplateLogFields returns the log columns identifying the template
+// version in ctx.
+func promptTemplateLogFields(ctx context.Context) (pgtype.UUID, pgtype.Int4) {
+	template := promptTemplateFrom(ctx)
+	if template == nil {
+		return pgtype.UUID{}, pgtype.Int4{}
+	}
+	return pgtype.UUID{Bytes: template.ID, Valid: true}, pgtype.Int4{Int32: template.Version, Valid: true}
+}
@@ -0,0 +1,473 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/prompttemplate"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// PromptTemplateVersion is one immutable version of a prompt template.
+type PromptTemplateVersion struct {
+	Version int32 `json:"version"`
+	prompttemplate.Content
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PromptTemplate struct {
+	ID            pgtype.UUID `json:"id"`
+	Name          string      `json:"name"`
+	ActiveVersion int32       `json:"active_version"`
+	Managed       bool        `json:"managed"`
+	CreatedAt     time.Time   `json:"created_at"`
+	// Active is the content of the active version; omitted in lists.
+	Active *PromptTemplateVersion `json:"active,omitempty"`
+}
+
+type CreatePromptTemplateRequest struct {
+	Name string `json:"name"`
+	prompttemplate.Content
+}
+
+type CreatePromptTemplateVersionRequest struct {
+	prompttemplate.Content
+	// Activate makes the new version the active one; defaults to true.
+	Activate *bool `json:"activate,omitempty"`
+}
+
+type RollbackPromptTemplateRequest struct {
+	Version int32 `json:"version"`
+}
+
+// CreatePromptTemplate godoc
+// @Summary Create a prompt template
+// @Schemes
+// @Description Create a prompt template with its first version. Attach it to models with prompt_template_id to add its system prompt and example messages to their chat requests.
+// @Tags Prompt Templates
+// @Accept json
+// @Produce json
+// @Param template body CreatePromptTemplateRequest true "Template name and first version"
+// @Success 201 {object} PromptTemplate
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/prompt-templates [post]
+func (s *Service) CreatePromptTemplate(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req CreatePromptTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "name is required"})
+	}
+	if err := req.Content.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	params, err := versionParams(req.Content)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	var template database.PromptTemplate
+	var version database.PromptTemplateVersion
+	err = s.db.ExecTx(c.Request().Context(), func(q database.Querier) error {
+		var err error
+		template, err = q.CreatePromptTemplate(c.Request().Context(), database.CreatePromptTemplateParams{
+			ID:     pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			UserID: userID,
+			Name:   req.Name,
+		})
+		if err != nil {
+			return err
+		}
+		params.TemplateID = template.ID
+		version, err = q.CreatePromptTemplateVersion(c.Request().Context(), params)
+		return err
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Prompt template '%s' already exists", req.Name)})
+		}
+		slog.ErrorContext(c.Request().Context(), "Error creating prompt template", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create prompt template"})
+	}
+
+	resp := toPromptTemplate(template)
+	resp.Active = toPromptTemplateVersion(version)
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourcePromptTemplate, template.ID.String(), nil, resp)
+
+	s.refreshRoutes(c)
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// ListPromptTemplates godoc
+// @Summary List prompt templates
+// @Schemes
+// @Description List the prompt templates of the authenticated user, without their content.
+// @Tags Prompt Templates
+// @Accept json
+// @Produce json
+// @Success 200 {array} PromptTemplate
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/prompt-templates [get]
+func (s *Service) ListPromptTemplates(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	templates, err := s.db.ListPromptTemplates(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve prompt templates"})
+	}
+
+	resp := make([]PromptTemplate, len(templates))
+	for i, t := range templates {
+		resp[i] = toPromptTemplate(t)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetPromptTemplate godoc
+// @Summary Get a prompt template
+// @Schemes
+// @Description Get a prompt template with the content of its active version.
+// @Tags Prompt Templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Success 200 {object} PromptTemplate
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/prompt-templates/{id} [get]
+func (s *Service) GetPromptTemplate(c echo.Context) error {
+	template, _, status, errResp := s.promptTemplateFromPath(c)
+	if status != 0 {
+		return c.JSON(status, errResp)
+	}
+
+	version, err := s.db.GetPromptTemplateVersion(c.Request().Context(), database.GetPromptTemplateVersionParams{
+		TemplateID: template.ID,
+		Version:    template.ActiveVersion,
+	})
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Error retrieving active prompt template version", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve prompt template"})
+	}
+
+	resp := toPromptTemplate(template)
+	resp.Active = toPromptTemplateVersion(version)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ListPromptTemplateVersions godoc
+// @Summary List prompt template versions
+// @Schemes
+// @Description List every version of a prompt template, newest first.
+// @Tags Prompt Templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Success 200 {array} PromptTemplateVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/prompt-templates/{id}/versions [get]
+func (s *Service) ListPromptTemplateVersions(c echo.Context) error {
+	template, _, status, errResp := s.promptTemplateFromPath(c)
+	if status != 0 {
+		return c.JSON(status, errResp)
+	}
+
+	versions, err := s.db.ListPromptTemplateVersions(c.Request().Context(), template.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve prompt template versions"})
+	}
+
+	resp := make([]PromptTemplateVersion, 0, len(versions))
+	for _, v := range versions {
+		if version := toPromptTemplateVersion(v); version != nil {
+			resp = append(resp, *version)
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CreatePromptTemplateVersion godoc
+// @Summary Add a prompt template version
+// @Schemes
+// @Description Add a new version to a prompt template. It becomes the active version unless activate is false.
+// @Tags Prompt Templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Param version body CreatePromptTemplateVersionRequest true "Version content"
+// @Success 201 {object} PromptTemplate
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/prompt-templates/{id}/versions [post]
+func (s *Service) CreatePromptTemplateVersion(c echo.Context) error {
+	before, userID, status, errResp := s.promptTemplateFromPath(c)
+	if status != 0 {
+		return c.JSON(status, errResp)
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("Prompt template"))
+	}
+
+	var req CreatePromptTemplateVersionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if err := req.Content.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	params, err := versionParams(req.Content)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	params.TemplateID = before.ID
+
+	template := before
+	var version database.PromptTemplateVersion
+	err = s.db.ExecTx(c.Request().Context(), func(q database.Querier) error {
+		var err error
+		if version, err = q.CreatePromptTemplateVersion(c.Request().Context(), params); err != nil {
+			return err
+		}
+		if req.Activate != nil && !*req.Activate {
+			return nil
+		}
+		template, err = q.SetPromptTemplateActiveVersion(c.Request().Context(), database.SetPromptTemplateActiveVersionParams{
+			ID:            before.ID,
+			UserID:        userID,
+			ActiveVersion: version.Version,
+		})
+		return err
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: "another version was added concurrently, retry"})
+		}
+		slog.ErrorContext(c.Request().Context(), "Error creating prompt template version", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create prompt template version"})
+	}
+
+	resp := toPromptTemplate(template)
+	resp.Active = toPromptTemplateVersion(version)
+	s.recordAudit(c, userID, AuditActionUpdate, AuditResourcePromptTemplate, template.ID.String(), toPromptTemplate(before), resp)
+
+	s.refreshRoutes(c)
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// RollbackPromptTemplate godoc
+// @Summary Activate an earlier prompt template version
+// @Schemes
+// @Description Make an existing version the active version of a prompt template. Models using the template pick it up with the next routing table refresh.
+// @Tags Prompt Templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Param request body RollbackPromptTemplateRequest true "Version to activate"
+// @Success 200 {object} PromptTemplate
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/prompt-templates/{id}/rollback [post]
+func (s *Service) RollbackPromptTemplate(c echo.Context) error {
+	before, userID, status, errResp := s.promptTemplateFromPath(c)
+	if status != 0 {
+		return c.JSON(status, errResp)
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("Prompt template"))
+	}
+
+	var req RollbackPromptTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	version, err := s.db.GetPromptTemplateVersion(c.Request().Context(), database.GetPromptTemplateVersionParams{
+		TemplateID: before.ID,
+		Version:    req.Version,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: fmt.Sprintf("Version %d not found", req.Version)})
+	} else if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Error retrieving prompt template version", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve prompt template version"})
+	}
+
+	template, err := s.db.SetPromptTemplateActiveVersion(c.Request().Context(), database.SetPromptTemplateActiveVersionParams{
+		ID:            before.ID,
+		UserID:        userID,
+		ActiveVersion: version.Version,
+	})
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Error activating prompt template version", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to activate prompt template version"})
+	}
+
+	resp := toPromptTemplate(template)
+	resp.Active = toPromptTemplateVersion(version)
+	s.recordAudit(c, userID, AuditActionUpdate, AuditResourcePromptTemplate, template.ID.String(), toPromptTemplate(before), resp)
+
+	s.refreshRoutes(c)
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeletePromptTemplate godoc
+// @Summary Delete a prompt template
+// @Schemes
+// @Description Delete a prompt template. Templates still attached to a model cannot be deleted.
+// @Tags Prompt Templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/prompt-templates/{id} [delete]
+func (s *Service) DeletePromptTemplate(c echo.Context) error {
+	before, userID, status, errResp := s.promptTemplateFromPath(c)
+	if status != 0 {
+		return c.JSON(status, errResp)
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("Prompt template"))
+	}
+
+	inUse, err := s.db.CountModelsUsingPromptTemplate(c.Request().Context(), database.CountModelsUsingPromptTemplateParams{
+		PromptTemplateID: before.ID,
+		UserID:           userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete prompt template"})
+	}
+	if inUse > 0 {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Prompt template is used by %d model(s)", inUse)})
+	}
+
+	if err := s.db.SoftDeletePromptTemplate(c.Request().Context(), database.SoftDeletePromptTemplateParams{
+		ID:     before.ID,
+		UserID: userID,
+	}); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete prompt template"})
+	}
+
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourcePromptTemplate, before.ID.String(), toPromptTemplate(before), nil)
+
+	s.refreshRoutes(c)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// promptTemplateFromPath loads the template named by the :id path parameter.
+// A non-zero status means the request failed with errResp.
+func (s *Service) promptTemplateFromPath(c echo.Context) (template database.PromptTemplate, userID pgtype.UUID, status int, errResp ErrorResponse) {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return template, userID, http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"}
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return template, userID, http.StatusBadRequest, ErrorResponse{Error: "Invalid Prompt Template ID"}
+	}
+	template, err = s.db.GetPromptTemplate(c.Request().Context(), database.GetPromptTemplateParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		return template, userID, http.StatusNotFound, ErrorResponse{Error: "Prompt template not found"}
+	}
+	return template, userID, 0, ErrorResponse{}
+}
+
+// promptTemplateRef resolves the prompt_template_id of a model request; an
+// empty id attaches no template.
+func (s *Service) promptTemplateRef(ctx context.Context, userID pgtype.UUID, id string) (pgtype.UUID, error) {
+	if id == "" {
+		return pgtype.UUID{}, nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return pgtype.UUID{}, errors.New("Invalid Prompt Template ID")
+	}
+	ref := pgtype.UUID{Bytes: parsed, Valid: true}
+	if _, err := s.db.GetPromptTemplate(ctx, database.GetPromptTemplateParams{ID: ref, UserID: userID}); err != nil {
+		return pgtype.UUID{}, fmt.Errorf("Prompt template with ID %s not found for this user", id)
+	}
+	return ref, nil
+}
+
+func versionParams(content prompttemplate.Content) (database.CreatePromptTemplateVersionParams, error) {
+	content = content.Normalize()
+	messages, variables, err := content.Encode()
+	if err != nil {
+		return database.CreatePromptTemplateVersionParams{}, err
+	}
+	return database.CreatePromptTemplateVersionParams{
+		Mode:         string(content.Mode),
+		SystemPrompt: content.SystemPrompt,
+		Messages:     messages,
+		Variables:    variables,
+	}, nil
+}
+
+func toPromptTemplate(t database.PromptTemplate) PromptTemplate {
+	return PromptTemplate{
+		ID:            t.ID,
+		Name:          t.Name,
+		ActiveVersion: t.ActiveVersion,
+		Managed:       t.Managed,
+		CreatedAt:     t.CreatedAt.Time,
+	}
+}
+
+// toPromptTemplateVersion returns nil for a version that no longer decodes.
+func toPromptTemplateVersion(v database.PromptTemplateVersion) *PromptTemplateVersion {
+	content, err := prompttemplate.Decode(v.Mode, v.SystemPrompt, v.Messages, v.Variables)
+	if err != nil {
+		return nil
+	}
+	return &PromptTemplateVersion{Version: v.Version, Content: content, CreatedAt: v.CreatedAt.Time}
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/mockprovider"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
@@ -36,34 +38,61 @@ func (s *Service) CreateProvider(c echo.Context) error {
 	name, _ := reqMap["name"].(string)
 	baseURL, _ := reqMap["base_url"].(string)
 	providerType, _ := reqMap["type"].(string)
+	apiVersion, _ := reqMap["api_version"].(string)
+	region, _ := reqMap["region"].(string)
 
-	log.Printf("CreateProvider: Received base_url: %s", baseURL)
+	slog.DebugContext(c.Request().Context(), "CreateProvider: received base_url", "base_url", baseURL)
 
 	if providerType == "" {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Provider type cannot be empty"})
 	}
+	if llm.ProviderType(providerType) == llm.ProviderAzureOpenAI && apiVersion == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "api_version is required for Azure OpenAI providers"})
+	}
+	if llm.ProviderType(providerType) == llm.ProviderBedrock && region == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "region is required for Bedrock providers"})
+	}
+	if llm.ProviderType(providerType) == llm.ProviderMock {
+		if !s.mockProviders.Load() {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "mock providers are disabled on this server"})
+		}
+		if baseURL != "" {
+			if err := mockprovider.ValidateURL(baseURL); err != nil {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			}
+		}
+	}
 
 	providerID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
 
 	createdProvider, err := s.db.CreateProvider(c.Request().Context(), database.CreateProviderParams{
-		ID:      providerID,
-		UserID:  userID,
-		Name:    name,
-		BaseUrl: baseURL,
-		Type:    providerType,
+		ID:         providerID,
+		UserID:     userID,
+		Name:       name,
+		BaseUrl:    baseURL,
+		Type:       providerType,
+		ApiVersion: apiVersion,
+		Region:     region,
 	})
 	if err != nil {
-		log.Printf("CreateProvider: Failed to create provider in DB: %v", err)
+		slog.ErrorContext(c.Request().Context(), "CreateProvider: failed to create provider in DB", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create provider"})
 	}
 
 	resp := Provider{
-		ID:      createdProvider.ID,
-		Name:    createdProvider.Name,
-		BaseURL: createdProvider.BaseUrl,
-		Type:    createdProvider.Type,
+		ID:         createdProvider.ID,
+		Name:       createdProvider.Name,
+		BaseURL:    createdProvider.BaseUrl,
+		Type:       createdProvider.Type,
+		Managed:    createdProvider.Managed,
+		APIVersion: createdProvider.ApiVersion,
+		Region:     createdProvider.Region,
 	}
-	log.Printf("CreateProvider: Created provider with BaseURL: %s", resp.BaseURL)
+	slog.InfoContext(c.Request().Context(), "CreateProvider: created provider", "provider_id", resp.ID.String(), "base_url", resp.BaseURL)
+
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceProvider, createdProvider.ID.String(), nil, createdProvider)
+
+	s.refreshRoutes(c)
 
 	return c.JSON(http.StatusCreated, resp)
 }
@@ -88,7 +117,7 @@ func (s *Service) ListProviders(c echo.Context) error {
 
 	dbProviders, err := s.db.ListProviders(c.Request().Context(), userID)
 	if err != nil {
-		log.Printf("ListProviders: Failed to retrieve providers from DB: %v", err)
+		slog.ErrorContext(c.Request().Context(), "ListProviders: failed to retrieve providers from DB", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve providers"})
 	}
 
@@ -96,11 +125,11 @@ func (s *Service) ListProviders(c echo.Context) error {
 	for i, p := range dbProviders {
 		provider, err := s.GetProviderFromDB(c.Request().Context(), p.ID, userID)
 		if err != nil {
-			log.Printf("ListProviders: Failed to retrieve provider from DB: %v", err)
+			slog.ErrorContext(c.Request().Context(), "ListProviders: failed to retrieve provider from DB", "error", err)
 			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve provider from DB"})
 		}
 		respProviders[i] = provider
-		log.Printf("ListProviders: Retrieved provider %s with BaseURL: %s", provider.Name, provider.BaseURL)
+		slog.DebugContext(c.Request().Context(), "ListProviders: retrieved provider", "name", provider.Name, "base_url", provider.BaseURL)
 	}
 	return c.JSON(http.StatusOK, respProviders)
 }
@@ -116,6 +145,8 @@ func (s *Service) ListProviders(c echo.Context) error {
 // @Success 204 "No Content"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /api/providers/{id} [delete]
@@ -131,6 +162,17 @@ func (s *Service) DeleteProvider(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
 	}
 
+	before, err := s.db.GetProvider(c.Request().Context(), database.GetProviderParams{
+		ID:     pgtype.UUID{Bytes: providerID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Provider not found"})
+	}
+	if before.Managed {
+		return c.JSON(http.StatusConflict, managedResourceError("Provider"))
+	}
+
 	err = s.db.SoftDeleteProvider(c.Request().Context(), database.SoftDeleteProviderParams{
 		ID:     pgtype.UUID{Bytes: providerID, Valid: true},
 		UserID: userID,
@@ -139,13 +181,15 @@ func (s *Service) DeleteProvider(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to soft delete provider"})
 	}
 
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourceProvider, before.ID.String(), before, nil)
+
 	// Soft delete all connections associated with this provider
 	connections, err := s.db.ListConnectionsByProviderID(c.Request().Context(), database.ListConnectionsByProviderIDParams{
 		ProviderID: providerIDStr,
 		UserID:     userID,
 	})
 	if err != nil {
-		log.Printf("Error listing connections for provider %s: %v", providerIDStr, err)
+		slog.ErrorContext(c.Request().Context(), "Error listing connections for provider", "provider_id", providerIDStr, "error", err)
 		// Continue with provider deletion even if connections cannot be listed
 	}
 
@@ -155,10 +199,14 @@ func (s *Service) DeleteProvider(c echo.Context) error {
 			UserID: userID,
 		})
 		if err != nil {
-			log.Printf("Error soft deleting connection %s for provider %s: %v", conn.ID.String(), providerIDStr, err)
+			slog.ErrorContext(c.Request().Context(), "Error deleting connection for provider", "connection_id", conn.ID.String(), "provider_id", providerIDStr, "error", err)
 			// Continue with other connections even if one fails
+			continue
 		}
+		s.recordAudit(c, userID, AuditActionDelete, AuditResourceConnection, conn.ID.String(), conn, nil)
 	}
+
+	s.refreshRoutes(c)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete provider"})
 	}
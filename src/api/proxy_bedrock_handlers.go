@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"gen-ai-proxy/src/awssig"
+	"gen-ai-proxy/src/eventstream"
+	"gen-ai-proxy/src/logging"
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/telemetry"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// proxyBedrockChat translates a chat completion to Bedrock's Converse, or
+// ConverseStream for a stream. Converse's requests and responses are what is
+// logged.
+func (s *Service) proxyBedrockChat(c echo.Context, call *chatCall) error {
+	model, provider := call.route.Model, call.route.Provider
+	logCtx := call.logCtx
+
+	creds, err := awssig.ParseCredentials(call.route.APIKey)
+	if err != nil {
+		slog.ErrorContext(logCtx, "Invalid Bedrock connection credentials", "connection_id", model.ConnectionID, "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "invalid connection credentials"})
+	}
+	bedrockReq, err := bedrockChatRequest(call, call.messages)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	jsonBody, err := json.Marshal(bedrockReq)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+	}
+
+	requestURL := bedrockURL(provider, model, "converse")
+	if call.req.Stream {
+		requestURL = bedrockURL(provider, model, "converse-stream")
+	}
+	slog.DebugContext(logCtx, "Translating chat completion to Bedrock", "url", requestURL)
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationChat, provider, model)
+	defer span.End()
+
+	send := func(body []byte) (*http.Response, error) {
+		proxyReq, err := bedrockRequestTo(ctx, call.route, creds, requestURL, body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.httpClient.Load().Do(proxyReq)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			slog.ErrorContext(logCtx, "Error sending proxy request to Bedrock", "error", err)
+			return nil, err
+		}
+		telemetry.SetHTTPStatus(span, resp.StatusCode)
+		return resp, nil
+	}
+
+	resp, err := send(jsonBody)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+	}
+	defer resp.Body.Close()
+
+	if call.req.Stream && resp.StatusCode < 300 {
+		return s.streamBedrockChat(c, call, resp, jsonBody, span)
+	}
+
+	created := time.Now().Unix()
+	messages := call.messages
+	// An output that does not match the JSON Schema is sent back to the
+	// model with the validation errors. Every attempt is logged.
+	for attempt := 1; ; attempt++ {
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+		}
+		slog.DebugContext(logCtx, "Bedrock API response", "status", resp.StatusCode)
+		s.logPayload(logCtx, "Bedrock API response body", respBody)
+		if resp.StatusCode >= 300 {
+			return s.upstreamChatError(c, call, jsonBody, resp.StatusCode, respBody)
+		}
+
+		var bedrockResp bedrockResponse
+		if err := json.Unmarshal(respBody, &bedrockResp); err != nil {
+			slog.ErrorContext(logCtx, "Failed to unmarshal Bedrock proxy response", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
+		}
+		result := bedrockResp.chatCompletion(call.req.Model, created)
+		if result.Usage != nil {
+			telemetry.SetResponse(span, model.ProviderModelID, result.Usage.PromptTokens, result.Usage.CompletionTokens)
+		}
+
+		logParams := call.logParams(jsonBody, respBody, resp.StatusCode, result.Usage)
+		outputs := result.outputs()
+		var invalidOutput string
+		var validationErr error
+		if call.output.checked(resp.StatusCode, outputs) {
+			invalidOutput, validationErr = call.output.validate(outputs)
+			setLogFields(&logParams, attempt, validationErr)
+		}
+		s.saveChatLog(call, "response_complete", logParams)
+
+		if validationErr == nil {
+			return c.JSON(http.StatusOK, result)
+		}
+		if !call.output.retry(attempt) {
+			return c.JSON(http.StatusUnprocessableEntity, call.output.failure(attempt, invalidOutput, validationErr))
+		}
+
+		slog.InfoContext(logCtx, "Output does not match the JSON Schema, retrying", "model", model.ProxyModelID, "attempt", attempt, "error", validationErr)
+		messages = append(messages, call.output.feedback(invalidOutput, validationErr)...)
+		if bedrockReq, err = bedrockChatRequest(call, messages); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+		if jsonBody, err = json.Marshal(bedrockReq); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+		}
+		if resp, err = send(jsonBody); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+		}
+	}
+}
+
+// streamBedrockChat translates ConverseStream's event stream to chat
+// completion chunks. The log holds the events received as a JSON array of
+// objects keyed by event type, the way the events are documented.
+func (s *Service) streamBedrockChat(c echo.Context, call *chatCall, resp *http.Response, jsonBody []byte, span trace.Span) error {
+	logCtx := call.logCtx
+	model := call.route.Model
+	chunks := chunkWriter{res: c.Response()}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+
+	id := completionID("")
+	created := time.Now().Unix()
+	received := []map[string]json.RawMessage{}
+	var usage *ChatCompletionUsage
+	var content strings.Builder
+	// The index of the tool call of each content block.
+	toolCalls := map[int]int{}
+
+	logReceived := func(stage string, checked bool, validationErr error) {
+		payload, _ := json.Marshal(received)
+		s.inBackground(func() {
+			logParams := call.logParams(jsonBody, payload, resp.StatusCode, usage)
+			if checked {
+				setLogFields(&logParams, 1, validationErr)
+			}
+			s.saveChatLog(call, stage, logParams)
+		})
+	}
+	chunk := func(delta ChatCompletionDelta, finishReason *string) ChatCompletionChunk {
+		return ChatCompletionChunk{
+			ID: id, Object: "chat.completion.chunk", Created: created, Model: call.req.Model,
+			Choices: []ChatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}},
+		}
+	}
+
+	decoder := eventstream.NewDecoder(resp.Body)
+	for {
+		msg, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			slog.ErrorContext(logCtx, "Error reading Bedrock event stream", "error", err)
+			logReceived("upstream_read_error", false, nil)
+			return err
+		}
+
+		eventType := msg.Header(":event-type")
+		if msg.Header(":message-type") == "exception" {
+			eventType = msg.Header(":exception-type")
+		}
+		payload := json.RawMessage(msg.Payload)
+		if !json.Valid(payload) {
+			payload, _ = json.Marshal(string(msg.Payload))
+		}
+		received = append(received, map[string]json.RawMessage{eventType: payload})
+
+		if msg.Header(":message-type") != "event" {
+			// An exception ends the stream; it is passed on as an error event.
+			streamErr := bedrockStreamError(eventType, msg.Payload)
+			slog.ErrorContext(logCtx, "Bedrock stream exception", "error", streamErr)
+			data, _ := json.Marshal(map[string]any{"error": map[string]string{"type": eventType, "message": streamErr.Error()}})
+			if _, err := c.Response().Write([]byte("data: " + string(data) + "\n\n")); err == nil {
+				c.Response().Flush()
+			}
+			logReceived("upstream_read_error", false, nil)
+			return nil
+		}
+
+		var event bedrockStreamEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			slog.WarnContext(logCtx, "Skipping unparseable Bedrock stream event", "event_type", eventType, "error", err)
+			continue
+		}
+
+		var delta ChatCompletionDelta
+		var finishReason *string
+		switch eventType {
+		case "messageStart":
+			delta.Role = "assistant"
+		case "contentBlockStart":
+			if event.Start.ToolUse == nil {
+				continue
+			}
+			index := len(toolCalls)
+			toolCalls[event.ContentBlockIndex] = index
+			delta.ToolCalls = []ChatCompletionToolCall{bedrockToolCall(index, *event.Start.ToolUse, "")}
+		case "contentBlockDelta":
+			if event.Delta.ToolUse != nil {
+				index := toolCalls[event.ContentBlockIndex]
+				delta.ToolCalls = []ChatCompletionToolCall{{
+					Index:    &index,
+					Function: ChatCompletionFunctionCall{Arguments: event.Delta.ToolUse.Input},
+				}}
+			} else if event.Delta.Text != "" {
+				content.WriteString(event.Delta.Text)
+				delta.Content = event.Delta.Text
+			} else {
+				continue
+			}
+		case "messageStop":
+			reason := bedrockFinishReason(event.StopReason)
+			finishReason = &reason
+		case "metadata":
+			usage = event.Usage.chatUsage()
+			continue
+		default:
+			continue
+		}
+		if err := chunks.send(chunk(delta, finishReason)); err != nil {
+			logReceived("client_write_error", false, nil)
+			return err
+		}
+	}
+
+	if usage != nil {
+		telemetry.SetResponse(span, model.ProviderModelID, usage.PromptTokens, usage.CompletionTokens)
+		final := ChatCompletionChunk{ID: id, Object: "chat.completion.chunk", Created: created, Model: call.req.Model, Choices: []ChatCompletionChunkChoice{}}
+		final.Usage = usage
+		if err := chunks.send(final); err != nil {
+			logReceived("client_write_error", false, nil)
+			return err
+		}
+	}
+	if err := chunks.done(); err != nil {
+		logReceived("client_write_error", false, nil)
+		return err
+	}
+
+	// A streamed output has already been sent, so it is validated for the
+	// log and metrics but cannot be retried.
+	var outputs []string
+	if len(toolCalls) == 0 {
+		outputs = []string{content.String()}
+	}
+	checked := call.output.checked(resp.StatusCode, outputs)
+	var validationErr error
+	if checked {
+		_, validationErr = call.output.validate(outputs)
+	}
+	logReceived("stream_complete", checked, validationErr)
+	return nil
+}
+
+// bedrockRequestTo builds a request to the Bedrock runtime API, signed with
+// the connection's access keys for the provider's region.
+func bedrockRequestTo(ctx context.Context, route routing.Route, creds awssig.Credentials, requestURL string, body []byte) (*http.Request, error) {
+	proxyReq, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	proxyReq.Header.Set("Content-Type", "application/json")
+	proxyReq.Header.Set(RequestIDHeader, logging.RequestID(ctx))
+	awssig.Sign(proxyReq, body, creds, route.Provider.Region, "bedrock", time.Now())
+	return proxyReq, nil
+}
@@ -0,0 +1,344 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/logging"
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/telemetry"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// proxyGeminiChat translates a chat completion to Gemini's generateContent,
+// or streamGenerateContent for a stream. Gemini's requests and responses
+// are what is logged.
+func (s *Service) proxyGeminiChat(c echo.Context, call *chatCall) error {
+	model, provider := call.route.Model, call.route.Provider
+	logCtx := call.logCtx
+
+	geminiReq, err := geminiChatRequest(call, call.messages)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	jsonBody, err := json.Marshal(geminiReq)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+	}
+
+	requestURL := geminiURL(provider, model, "generateContent")
+	if call.req.Stream {
+		requestURL = geminiURL(provider, model, "streamGenerateContent") + "?alt=sse"
+	}
+	slog.DebugContext(logCtx, "Translating chat completion to Gemini", "url", requestURL)
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationChat, provider, model)
+	defer span.End()
+
+	send := func(body []byte) (*http.Response, error) {
+		proxyReq, err := geminiRequestTo(ctx, call.route, requestURL, body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.httpClient.Load().Do(proxyReq)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			slog.ErrorContext(logCtx, "Error sending proxy request to Gemini", "error", err)
+			return nil, err
+		}
+		telemetry.SetHTTPStatus(span, resp.StatusCode)
+		return resp, nil
+	}
+
+	resp, err := send(jsonBody)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+	}
+	defer resp.Body.Close()
+
+	if call.req.Stream && resp.StatusCode < 300 {
+		return s.streamGeminiChat(c, call, resp, jsonBody, span)
+	}
+
+	created := time.Now().Unix()
+	messages := call.messages
+	// An output that does not match the JSON Schema is sent back to the
+	// model with the validation errors. Every attempt is logged.
+	for attempt := 1; ; attempt++ {
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+		}
+		slog.DebugContext(logCtx, "Gemini API response", "status", resp.StatusCode)
+		s.logPayload(logCtx, "Gemini API response body", respBody)
+		if resp.StatusCode >= 300 {
+			return s.upstreamChatError(c, call, jsonBody, resp.StatusCode, respBody)
+		}
+
+		var geminiResp geminiResponse
+		if err := json.Unmarshal(respBody, &geminiResp); err != nil {
+			slog.ErrorContext(logCtx, "Failed to unmarshal Gemini proxy response", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
+		}
+		result := geminiResp.chatCompletion(call.req.Model, created)
+		if result.Usage != nil {
+			telemetry.SetResponse(span, geminiResp.ModelVersion, result.Usage.PromptTokens, result.Usage.CompletionTokens)
+		}
+
+		logParams := call.logParams(jsonBody, respBody, resp.StatusCode, result.Usage)
+		outputs := result.outputs()
+		var invalidOutput string
+		var validationErr error
+		if call.output.checked(resp.StatusCode, outputs) {
+			invalidOutput, validationErr = call.output.validate(outputs)
+			setLogFields(&logParams, attempt, validationErr)
+		}
+		s.saveChatLog(call, "response_complete", logParams)
+
+		if validationErr == nil {
+			return c.JSON(http.StatusOK, result)
+		}
+		if !call.output.retry(attempt) {
+			return c.JSON(http.StatusUnprocessableEntity, call.output.failure(attempt, invalidOutput, validationErr))
+		}
+
+		slog.InfoContext(logCtx, "Output does not match the JSON Schema, retrying", "model", model.ProxyModelID, "attempt", attempt, "error", validationErr)
+		messages = append(messages, call.output.feedback(invalidOutput, validationErr)...)
+		if geminiReq, err = geminiChatRequest(call, messages); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+		if jsonBody, err = json.Marshal(geminiReq); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+		}
+		if resp, err = send(jsonBody); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+		}
+	}
+}
+
+// streamGeminiChat translates Gemini's server-sent events to chat completion
+// chunks. The log holds the Gemini responses received, as a JSON array.
+func (s *Service) streamGeminiChat(c echo.Context, call *chatCall, resp *http.Response, jsonBody []byte, span trace.Span) error {
+	logCtx := call.logCtx
+	chunks := chunkWriter{res: c.Response()}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+
+	id := completionID("")
+	created := time.Now().Unix()
+	received := []json.RawMessage{}
+	var usage *ChatCompletionUsage
+	var modelVersion string
+	// Per candidate: the content so far, once its role was sent, and the
+	// number of tool calls.
+	content := map[int]*strings.Builder{}
+	toolCalls := map[int]int{}
+
+	logReceived := func(stage string, checked bool, validationErr error) {
+		payload, _ := json.Marshal(received)
+		s.inBackground(func() {
+			logParams := call.logParams(jsonBody, payload, resp.StatusCode, usage)
+			if checked {
+				setLogFields(&logParams, 1, validationErr)
+			}
+			s.saveChatLog(call, stage, logParams)
+		})
+	}
+	chunk := func(choices []ChatCompletionChunkChoice) ChatCompletionChunk {
+		return ChatCompletionChunk{ID: id, Object: "chat.completion.chunk", Created: created, Model: call.req.Model, Choices: choices}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		var geminiResp geminiResponse
+		if err := json.Unmarshal([]byte(data), &geminiResp); err != nil {
+			slog.WarnContext(logCtx, "Skipping unparseable Gemini stream event", "error", err)
+			continue
+		}
+		received = append(received, json.RawMessage(strings.TrimSpace(data)))
+		if u := geminiResp.usage(); u != nil {
+			usage = u
+		}
+		if geminiResp.ModelVersion != "" {
+			modelVersion = geminiResp.ModelVersion
+		}
+
+		var choices []ChatCompletionChunkChoice
+		for _, candidate := range geminiResp.Candidates {
+			choice := ChatCompletionChunkChoice{Index: candidate.Index}
+			if content[candidate.Index] == nil {
+				content[candidate.Index] = &strings.Builder{}
+				choice.Delta.Role = "assistant"
+			}
+			text, calls := candidate.Content.output(toolCalls[candidate.Index])
+			content[candidate.Index].WriteString(text)
+			toolCalls[candidate.Index] += len(calls)
+			choice.Delta.Content = text
+			choice.Delta.ToolCalls = calls
+			if candidate.FinishReason != "" {
+				reason := geminiFinishReason(candidate.FinishReason, toolCalls[candidate.Index] > 0)
+				choice.FinishReason = &reason
+			}
+			choices = append(choices, choice)
+		}
+		if len(geminiResp.Candidates) == 0 && geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+			// The prompt itself was blocked.
+			reason := "content_filter"
+			choices = append(choices, ChatCompletionChunkChoice{Delta: ChatCompletionDelta{Role: "assistant"}, FinishReason: &reason})
+		}
+		if len(choices) == 0 {
+			continue
+		}
+		if err := chunks.send(chunk(choices)); err != nil {
+			logReceived("client_write_error", false, nil)
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logReceived("upstream_read_error", false, nil)
+		return err
+	}
+
+	if usage != nil {
+		telemetry.SetResponse(span, modelVersion, usage.PromptTokens, usage.CompletionTokens)
+		final := chunk([]ChatCompletionChunkChoice{})
+		final.Usage = usage
+		if err := chunks.send(final); err != nil {
+			logReceived("client_write_error", false, nil)
+			return err
+		}
+	}
+	if err := chunks.done(); err != nil {
+		logReceived("client_write_error", false, nil)
+		return err
+	}
+
+	// A streamed output has already been sent, so it is validated for the
+	// log and metrics but cannot be retried.
+	var outputs []string
+	for index, text := range content {
+		if toolCalls[index] == 0 {
+			outputs = append(outputs, text.String())
+		}
+	}
+	checked := call.output.checked(resp.StatusCode, outputs)
+	var validationErr error
+	if checked {
+		_, validationErr = call.output.validate(outputs)
+	}
+	logReceived("stream_complete", checked, validationErr)
+	return nil
+}
+
+// proxyGeminiEmbedding translates an embedding request to Gemini's
+// embedContent.
+func (s *Service) proxyGeminiEmbedding(c echo.Context, req EmbeddingRequest, route routing.Route, userID, apiKeyID pgtype.UUID) error {
+	model, provider := route.Model, route.Provider
+	logCtx := context.WithoutCancel(c.Request().Context())
+
+	jsonBody, err := json.Marshal(geminiEmbedRequest{Content: geminiContent{Parts: []geminiPart{{Text: req.Input}}}})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+	}
+
+	requestURL := geminiURL(provider, model, "embedContent")
+	slog.DebugContext(logCtx, "Translating embedding request to Gemini", "url", requestURL)
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationEmbeddings, provider, model)
+	defer span.End()
+
+	proxyReq, err := geminiRequestTo(ctx, route, requestURL, jsonBody)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create proxy request"})
+	}
+	resp, err := s.httpClient.Load().Do(proxyReq)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+	}
+	defer resp.Body.Close()
+	telemetry.SetHTTPStatus(span, resp.StatusCode)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+	}
+	slog.DebugContext(logCtx, "Gemini API response", "status", resp.StatusCode)
+	s.logPayload(logCtx, "Gemini API response body", respBody)
+
+	logParams := database.CreateLogParams{
+		UserID:          userID,
+		ModelID:         model.ID,
+		RequestPayload:  json.RawMessage(jsonBody),
+		ResponsePayload: json.RawMessage(respBody),
+		ConnectionID:    model.ConnectionID,
+		Type:            "embedding",
+		ApiKeyID:        apiKeyID,
+		StatusCode:      pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+	}
+	if resp.StatusCode >= 300 || !json.Valid(respBody) {
+		if !json.Valid(respBody) {
+			respBody, _ = json.Marshal(ErrorResponse{Error: strings.TrimSpace(string(respBody))})
+			logParams.ResponsePayload = json.RawMessage(respBody)
+		}
+		if _, logErr := s.saveLog(logCtx, model, logParams); logErr != nil {
+			slog.ErrorContext(logCtx, "Error logging embedding request", "error", logErr)
+		}
+		return c.JSONBlob(resp.StatusCode, respBody)
+	}
+
+	var geminiResp geminiEmbedResponse
+	if err := json.Unmarshal(respBody, &geminiResp); err != nil {
+		slog.ErrorContext(logCtx, "Failed to unmarshal Gemini proxy response", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
+	}
+	// embedContent does not report usage, so no tokens are counted.
+	logParams.PromptTokens = pgtype.Int8{Int64: 0, Valid: true}
+	logParams.CompletionTokens = pgtype.Int8{Int64: 0, Valid: true}
+	telemetry.SetResponse(span, model.ProviderModelID, 0, 0)
+	if _, logErr := s.saveLog(logCtx, model, logParams); logErr != nil {
+		slog.ErrorContext(logCtx, "Error logging embedding request", "error", logErr)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{{
+			"object":    "embedding",
+			"embedding": encodeEmbedding(geminiResp.Embedding.Values, req.EncodingFormat),
+			"index":     0,
+		}},
+		"model": req.Model,
+		"usage": OpenAIEmbeddingUsage{},
+	})
+}
+
+// geminiRequestTo builds a request to the Gemini API, authenticated with the
+// connection's API key.
+func geminiRequestTo(ctx context.Context, route routing.Route, requestURL string, body []byte) (*http.Request, error) {
+	proxyReq, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	proxyReq.Header.Set("Content-Type", "application/json")
+	proxyReq.Header.Set(RequestIDHeader, logging.RequestID(ctx))
+	proxyReq.Header.Set("x-goog-api-key", route.APIKey)
+	return proxyReq, nil
+}
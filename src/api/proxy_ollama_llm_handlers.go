@@ -5,13 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"gen-ai-proxy/src/database"
 	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/telemetry"
 
-	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
@@ -21,6 +21,12 @@ type OllamaChatRequest struct {
 	Messages []ChatCompletionMessage `json:"messages"`
 	Stream   bool                    `json:"stream"`
 	Think    bool                    `json:"think,omitempty"`
+	Options  map[string]any          `json:"options,omitempty"`
+	// Format is "json" or a JSON Schema; a schema is enforced on the output.
+	Format json.RawMessage `json:"format,omitempty" swaggertype:"object"`
+
+	// PromptVariables fill in the model's prompt template; they are not sent upstream.
+	PromptVariables map[string]string `json:"prompt_variables,omitempty"`
 }
 
 type OllamaResponse struct {
@@ -31,7 +37,7 @@ type OllamaResponse struct {
 	TotalDuration   int64   `json:"total_duration"`
 	LoadDuration    int64   `json:"load_duration"`
 	PromptEvalCount int32   `json:"prompt_eval_count"`
-	EvalCount       int32   `json:"eval_eval_count"`
+	EvalCount       int32   `json:"eval_count"`
 }
 
 type Message struct {
@@ -60,59 +66,67 @@ func (s *Service) ProxyOllamaChat(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
 	}
+	apiKeyID := GetAPIKeyIDFromContext(c)
+	// Conversation logs are written after the response, so keep the request's
+	// values (request ID) but not its cancellation.
+	logCtx := context.WithoutCancel(c.Request().Context())
 
 	var req OllamaChatRequest
 	if err = c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	model, err := s.db.GetModelByProxyModelID(c.Request().Context(), database.GetModelByProxyModelIDParams{
-		ProxyModelID: req.Model,
-		UserID:       userID,
-	})
-	if err != nil {
+	route, ok := s.lookupRoute(c.Request().Context(), userID, req.Model)
+	if !ok {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Model not found"})
 	}
+	model, provider := route.Model, route.Provider
+	if status, msg := s.apiKeyPolicyViolation(c, model); status != 0 {
+		return c.JSON(status, ErrorResponse{Error: msg})
+	}
 
-	connection, err := s.db.GetConnection(c.Request().Context(), database.GetConnectionParams{
-		ID:     model.ConnectionID,
-		UserID: userID,
-	})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to get connection"})
+	// Only providers speaking the Ollama API
+	adapter, ok := providerAdapter(provider, llm.ProtocolOllama)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, unsupportedProvider(provider))
 	}
 
-	providerUUID, err := uuid.Parse(connection.ProviderID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to parse provider id"})
+	if model.Type != "llm" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports LLM models"})
 	}
 
-	provider, err := s.db.GetProvider(c.Request().Context(), database.GetProviderParams{
-		ID:     pgtype.UUID{Bytes: providerUUID, Valid: true},
-		UserID: userID,
-	})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Provider not found for model"})
+	params := req.Params()
+	if err := s.applyParamPolicy(c.Request().Context(), route, params); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	// Only allow Ollama providers for this endpoint
-	if llm.ProviderType(provider.Type) != llm.ProviderOllama {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports Ollama providers"})
+	messages, err := applyPromptTemplate(route, req.Messages, req.PromptVariables)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
+	logCtx = withPromptTemplate(logCtx, route.PromptTemplate)
 
-	if model.Type != "llm" {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports LLM models"})
+	requestedSchema, err := req.outputSchema()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
+	output := s.outputCheck(route, requestedSchema)
 
 	// Build Ollama request structure
 	ollamaReq := make(map[string]any)
 	ollamaReq["model"] = model.ProviderModelID
-	ollamaReq["messages"] = req.Messages
+	ollamaReq["messages"] = messages
 	ollamaReq["stream"] = req.Stream
+	if format := req.upstreamFormat(output); format != nil {
+		ollamaReq["format"] = format
+	}
 
-	// Add think parameter if specified
-	if req.Think {
-		ollamaReq["think"] = true
+	options, think := req.upstreamFields(params)
+	if len(options) > 0 {
+		ollamaReq["options"] = options
+	}
+	if think != nil {
+		ollamaReq["think"] = *think
 	}
 
 	// Marshal the request body to JSON
@@ -121,23 +135,26 @@ func (s *Service) ProxyOllamaChat(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
 	}
 
-	requestURL := provider.BaseUrl + "/api/chat"
-	log.Printf("Proxying Ollama request to: %s", requestURL)
-	proxyReq, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(jsonBody))
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationChat, provider, model)
+	defer span.End()
+
+	newProxyRequest := func(body []byte) (*http.Request, error) {
+		return newUpstreamRequest(ctx, adapter, route, llm.OperationChat, body)
+	}
+	proxyReq, err := newProxyRequest(jsonBody)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create proxy request"})
 	}
+	slog.DebugContext(logCtx, "Proxying Ollama request", "url", proxyReq.URL.String())
 
-	proxyReq.Header.Set("Content-Type", "application/json")
-	// Note: Ollama typically doesn't require Authorization header
-
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+	resp, err := s.upstreamClient(adapter).Do(proxyReq)
 	if err != nil {
-		log.Printf("Error sending proxy request to Ollama: %v", err)
+		telemetry.RecordError(span, err)
+		slog.ErrorContext(logCtx, "Error sending proxy request to Ollama", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
 	}
 	defer resp.Body.Close()
+	telemetry.SetHTTPStatus(span, resp.StatusCode)
 
 	// Capture response body for logging
 	var responseBody bytes.Buffer
@@ -155,18 +172,20 @@ func (s *Service) ProxyOllamaChat(c echo.Context) error {
 			if n > 0 {
 				if _, writeErr := c.Response().Write(buf[:n]); writeErr != nil {
 					// Log the conversation even if there's a write error to the client
-					go func() {
-						_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
+					s.inBackground(func() {
+						_, logErr := s.saveLog(logCtx, model, database.CreateLogParams{
 							UserID:          userID,
 							ModelID:         model.ID,
 							RequestPayload:  json.RawMessage(jsonBody),
 							ResponsePayload: json.RawMessage(responseBody.Bytes()),
 							Type:            "llm",
+							ApiKeyID:        apiKeyID,
+							StatusCode:      pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
 						})
 						if logErr != nil {
-							log.Printf("Error logging conversation (write error path): %v", logErr)
+							slog.ErrorContext(logCtx, "Error logging conversation", "stage", "client_write_error", "error", logErr)
 						}
-					}()
+					})
 					return writeErr
 				}
 				c.Response().Flush()
@@ -176,52 +195,62 @@ func (s *Service) ProxyOllamaChat(c echo.Context) error {
 			}
 			if err != nil {
 				// Log the conversation even if there's a read error from the proxy
-				go func() {
-					_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
+				s.inBackground(func() {
+					_, logErr := s.saveLog(logCtx, model, database.CreateLogParams{
 						UserID:          userID,
 						ModelID:         model.ID,
 						RequestPayload:  json.RawMessage(jsonBody),
 						ResponsePayload: json.RawMessage(responseBody.Bytes()),
 						Type:            "llm",
+						ApiKeyID:        apiKeyID,
+						StatusCode:      pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
 					})
 					if logErr != nil {
-						log.Printf("Error logging conversation (read error path): %v", logErr)
+						slog.ErrorContext(logCtx, "Error logging conversation", "stage", "upstream_read_error", "error", logErr)
 					}
-				}()
+				})
 				return err
 			}
 		}
-		// After streaming is complete, parse the full response body for token counts
-		var finalOllamaResp OllamaResponse
-		var promptTokens int
-		var completionTokens int
-
-		if err := json.Unmarshal(responseBody.Bytes(), &finalOllamaResp); err == nil {
-			promptTokens = int(finalOllamaResp.PromptEvalCount)
-			completionTokens = int(finalOllamaResp.EvalCount)
+		// After streaming is complete, read the token counts of the final message
+		usage, ok := llm.StreamUsage(adapter, responseBody.Bytes())
+		if ok {
+			telemetry.SetResponse(span, usage.Model, usage.PromptTokens, usage.CompletionTokens)
 		} else {
-			log.Printf("Error unmarshaling final Ollama streaming response for token counts: %v", err)
+			slog.WarnContext(logCtx, "Ollama stream ended without token counts")
 		}
 
-		// Log the conversation after successful streaming
-		go func() {
-			pt := int64(promptTokens)
-			ct := int64(completionTokens)
+		// A streamed output has already been sent, so it is validated for the
+		// log and metrics but cannot be retried.
+		outputs := ollamaStreamOutputs(responseBody.Bytes())
+		checked := output.checked(resp.StatusCode, outputs)
+		var validationErr error
+		if checked {
+			_, validationErr = output.validate(outputs)
+		}
 
-			_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
+		// Log the conversation after successful streaming
+		s.inBackground(func() {
+			logParams := database.CreateLogParams{
 				UserID:           userID,
 				ModelID:          model.ID,
 				RequestPayload:   json.RawMessage(jsonBody),
 				ResponsePayload:  json.RawMessage(responseBody.Bytes()),
-				PromptTokens:     pgtype.Int8{Int64: pt, Valid: true},
-				CompletionTokens: pgtype.Int8{Int64: ct, Valid: true},
+				PromptTokens:     pgtype.Int8{Int64: usage.PromptTokens, Valid: true},
+				CompletionTokens: pgtype.Int8{Int64: usage.CompletionTokens, Valid: true},
 				ConnectionID:     model.ConnectionID,
 				Type:             "llm",
-			})
+				ApiKeyID:         apiKeyID,
+				StatusCode:       pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+			}
+			if checked {
+				setLogFields(&logParams, 1, validationErr)
+			}
+			_, logErr := s.saveLog(logCtx, model, logParams)
 			if logErr != nil {
-				log.Printf("Error logging conversation (streaming success path): %v", logErr)
+				slog.ErrorContext(logCtx, "Error logging conversation", "stage", "stream_complete", "error", logErr)
 			}
-		}()
+		})
 		return nil
 	} else {
 		respBody, err := io.ReadAll(teeReader)
@@ -229,38 +258,74 @@ func (s *Service) ProxyOllamaChat(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
 		}
 
-		log.Printf("Raw Ollama non-streaming response: %s", string(respBody))
-		var data any
-		if err := json.Unmarshal(respBody, &data); err != nil {
-			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
-		}
+		// An output that does not match the JSON Schema is sent back to the
+		// model with the validation errors. Every attempt is logged.
+		for attempt := 1; ; attempt++ {
+			s.logPayload(logCtx, "Ollama response body", respBody)
+			if resp.StatusCode >= 300 {
+				respBody = upstreamErrorBody(logCtx, adapter, resp.StatusCode, respBody)
+			}
+			var data any
+			if err := json.Unmarshal(respBody, &data); err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
+			}
 
-		var ollamaResp OllamaResponse
-		var promptTokens int
-		var completionTokens int
+			usage, ok := adapter.ParseUsage(respBody)
+			if ok {
+				telemetry.SetResponse(span, usage.Model, usage.PromptTokens, usage.CompletionTokens)
+			}
 
-		if err := json.Unmarshal(respBody, &ollamaResp); err == nil {
-			promptTokens = int(ollamaResp.PromptEvalCount)
-			completionTokens = int(ollamaResp.EvalCount)
-		}
+			logParams := database.CreateLogParams{
+				UserID:           userID,
+				ModelID:          model.ID,
+				RequestPayload:   json.RawMessage(jsonBody),
+				ResponsePayload:  json.RawMessage(respBody),
+				PromptTokens:     pgtype.Int8{Int64: usage.PromptTokens, Valid: true},
+				CompletionTokens: pgtype.Int8{Int64: usage.CompletionTokens, Valid: true},
+				ConnectionID:     model.ConnectionID,
+				Type:             "llm",
+				ApiKeyID:         apiKeyID,
+				StatusCode:       pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+			}
+			outputs := ollamaOutputs(respBody)
+			var invalidOutput string
+			var validationErr error
+			if output.checked(resp.StatusCode, outputs) {
+				invalidOutput, validationErr = output.validate(outputs)
+				setLogFields(&logParams, attempt, validationErr)
+			}
+			_, logErr := s.saveLog(logCtx, model, logParams)
+			if logErr != nil {
+				slog.ErrorContext(logCtx, "Error logging conversation", "stage", "response_complete", "error", logErr)
+			}
 
-		pt := int64(promptTokens)
-		ct := int64(completionTokens)
-
-		_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
-			UserID:           userID,
-			ModelID:          model.ID,
-			RequestPayload:   json.RawMessage(jsonBody),
-			ResponsePayload:  json.RawMessage(respBody),
-			PromptTokens:     pgtype.Int8{Int64: pt, Valid: true},
-			CompletionTokens: pgtype.Int8{Int64: ct, Valid: true},
-			ConnectionID:     model.ConnectionID,
-			Type:             "llm",
-		})
-		if logErr != nil {
-			log.Printf("Error logging conversation (non-streaming success path): %v", logErr)
-		}
+			if validationErr == nil {
+				return c.JSON(resp.StatusCode, data)
+			}
+			if !output.retry(attempt) {
+				return c.JSON(http.StatusUnprocessableEntity, output.failure(attempt, invalidOutput, validationErr))
+			}
 
-		return c.JSON(resp.StatusCode, data)
+			slog.InfoContext(logCtx, "Output does not match the JSON Schema, retrying", "model", model.ProxyModelID, "attempt", attempt, "error", validationErr)
+			messages = append(messages, output.feedback(invalidOutput, validationErr)...)
+			ollamaReq["messages"] = messages
+			if jsonBody, err = json.Marshal(ollamaReq); err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+			}
+			if proxyReq, err = newProxyRequest(jsonBody); err == nil {
+				resp, err = s.upstreamClient(adapter).Do(proxyReq)
+			}
+			if err != nil {
+				telemetry.RecordError(span, err)
+				slog.ErrorContext(logCtx, "Error sending proxy request to Ollama", "error", err)
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+			}
+			respBody, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+			}
+			telemetry.SetHTTPStatus(span, resp.StatusCode)
+		}
 	}
 }
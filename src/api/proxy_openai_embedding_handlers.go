@@ -1,19 +1,17 @@
 package api
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"gen-ai-proxy/src/database"
-	"gen-ai-proxy/src/encryption"
 	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/telemetry"
 
-	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
@@ -36,6 +34,12 @@ type OpenAIEmbeddingResponse struct {
 	Usage  OpenAIEmbeddingUsage `json:"usage"`
 }
 
+// embeddingTranslators serve embeddings for providers that do not speak the
+// OpenAI API.
+var embeddingTranslators = map[llm.ProviderType]func(*Service, echo.Context, EmbeddingRequest, routing.Route, pgtype.UUID, pgtype.UUID) error{
+	llm.ProviderGemini: (*Service).proxyGeminiEmbedding,
+}
+
 // ProxyOpenAIEmbedding godoc
 // @Summary Proxy embedding request to OpenAI Compatible endpoint
 // @Schemes
@@ -57,65 +61,39 @@ func (s *Service) ProxyOpenAIEmbedding(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
 	}
+	apiKeyID := GetAPIKeyIDFromContext(c)
+	// Conversation logs are written after the response, so keep the request's
+	// values (request ID) but not its cancellation.
+	logCtx := context.WithoutCancel(c.Request().Context())
 
 	var req EmbeddingRequest
 	if err = c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	model, err := s.db.GetModelByProxyModelID(c.Request().Context(), database.GetModelByProxyModelIDParams{
-		ProxyModelID: req.Model,
-		UserID:       userID,
-	})
-	if err != nil {
+	route, ok := s.lookupRoute(c.Request().Context(), userID, req.Model)
+	if !ok {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Model not found"})
 	}
-
-	connection, err := s.db.GetConnection(c.Request().Context(), database.GetConnectionParams{
-		ID:     model.ConnectionID,
-		UserID: userID,
-	})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to get connection"})
-	}
-
-	providerUUID, err := uuid.Parse(connection.ProviderID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to parse provider id"})
-	}
-
-	provider, err := s.db.GetProvider(c.Request().Context(), database.GetProviderParams{
-		ID:     pgtype.UUID{Bytes: providerUUID, Valid: true},
-		UserID: userID,
-	})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Provider not found for model"})
+	model, provider := route.Model, route.Provider
+	if status, msg := s.apiKeyPolicyViolation(c, model); status != 0 {
+		return c.JSON(status, ErrorResponse{Error: msg})
 	}
 
-	if llm.ProviderType(provider.Type) != llm.ProviderOpenAI {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports OpenAI providers"})
+	adapter, native := providerAdapter(provider, llm.ProtocolOpenAI)
+	translate, translated := embeddingTranslators[llm.ProviderType(provider.Type)]
+	if !native && !translated {
+		return c.JSON(http.StatusBadRequest, unsupportedProvider(provider))
 	}
 
 	if model.Type != "embedding" {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports embedding models"})
 	}
 
-	decodedEncryptionKey, err := base64.StdEncoding.DecodeString(s.cfg.EncryptionKey)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to decode encryption key"})
-	}
-
-	if len(decodedEncryptionKey) != 32 {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Encryption key must be 32 bytes long after base64 decoding"})
-	}
-
-	decryptedAPIKey, err := encryption.Decrypt(decodedEncryptionKey, connection.EncryptedApiKey)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to decrypt API key"})
+	if !native {
+		return translate(s, c, req, route, userID, apiKeyID)
 	}
 
-	apiKey := string(decryptedAPIKey)
-
 	openAIReq := make(map[string]any)
 	openAIReq["model"] = model.ProviderModelID
 	openAIReq["input"] = req.Input
@@ -128,60 +106,62 @@ func (s *Service) ProxyOpenAIEmbedding(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
 	}
 
-	requestURL := provider.BaseUrl + "/embeddings"
-	log.Printf("Proxying OpenAI embedding request to: %s", requestURL)
-	proxyReq, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(jsonBody))
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationEmbeddings, provider, model)
+	defer span.End()
+
+	proxyReq, err := newUpstreamRequest(ctx, adapter, route, llm.OperationEmbeddings, jsonBody)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create proxy request"})
 	}
+	slog.DebugContext(logCtx, "Proxying OpenAI embedding request", "url", proxyReq.URL.String())
 
-	proxyReq.Header.Set("Content-Type", "application/json")
-	proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+	resp, err := s.upstreamClient(adapter).Do(proxyReq)
 	if err != nil {
+		telemetry.RecordError(span, err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
 	}
 	defer resp.Body.Close()
+	telemetry.SetHTTPStatus(span, resp.StatusCode)
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
 	}
 
-	log.Printf("OpenAI API Response Status: %d", resp.StatusCode)
-	log.Printf("OpenAI API Response Body: %s", string(respBody))
+	slog.DebugContext(logCtx, "OpenAI API response", "status", resp.StatusCode)
+	s.logPayload(logCtx, "OpenAI API response body", respBody)
+	if resp.StatusCode >= 300 {
+		respBody = upstreamErrorBody(logCtx, adapter, resp.StatusCode, respBody)
+	}
 
 	var data any
 	if err := json.Unmarshal(respBody, &data); err != nil {
-		log.Printf("Failed to unmarshal OpenAI proxy response: %v, Raw Body: %s", err, string(respBody))
+		slog.ErrorContext(logCtx, "Failed to unmarshal OpenAI proxy response", "error", err)
+		s.logPayload(logCtx, "Unparseable OpenAI response body", respBody)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
 	}
 
-	var openAIResp OpenAIEmbeddingResponse
-	promptTokens := 0
-
-	if err := json.Unmarshal(respBody, &openAIResp); err == nil {
-		promptTokens = openAIResp.Usage.PromptTokens
-	} else {
-		log.Printf("Error unmarshaling OpenAI embedding response for token counts: %v", err)
+	usage, ok := adapter.ParseUsage(respBody)
+	if ok {
+		telemetry.SetResponse(span, usage.Model, usage.PromptTokens, 0)
+	} else if resp.StatusCode < 300 {
+		slog.WarnContext(logCtx, "OpenAI embedding response without token counts")
 	}
 
-	pt := int64(promptTokens)
-
-	_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
+	_, logErr := s.saveLog(logCtx, model, database.CreateLogParams{
 		UserID:           userID,
 		ModelID:          model.ID,
 		RequestPayload:   json.RawMessage(jsonBody),
 		ResponsePayload:  json.RawMessage(respBody),
-		PromptTokens:     pgtype.Int8{Int64: pt, Valid: true},
+		PromptTokens:     pgtype.Int8{Int64: usage.PromptTokens, Valid: true},
 		CompletionTokens: pgtype.Int8{Int64: 0, Valid: true}, // Embeddings does not generate completion tokens
 		ConnectionID:     model.ConnectionID,
 		Type:             "embedding",
+		ApiKeyID:         apiKeyID,
+		StatusCode:       pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
 	})
 	if logErr != nil {
-		log.Printf("Error logging embedding request: %v", logErr)
+		slog.ErrorContext(logCtx, "Error logging embedding request", "error", logErr)
 	}
 
 	return c.JSON(resp.StatusCode, data)
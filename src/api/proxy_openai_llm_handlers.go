@@ -3,17 +3,15 @@ package api
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"gen-ai-proxy/src/database"
-	"gen-ai-proxy/src/encryption"
 	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/telemetry"
 
-	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
@@ -60,83 +58,77 @@ func (s *Service) ProxyOpenAIChat(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
 	}
+	apiKeyID := GetAPIKeyIDFromContext(c)
+	// Conversation logs are written after the response, so keep the request's
+	// values (request ID) but not its cancellation.
+	logCtx := context.WithoutCancel(c.Request().Context())
 
 	var req ChatCompletionRequest
 	if err = c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	model, err := s.db.GetModelByProxyModelID(c.Request().Context(), database.GetModelByProxyModelIDParams{
-		ProxyModelID: req.Model,
-		UserID:       userID,
-	})
-	if err != nil {
+	route, ok := s.lookupRoute(c.Request().Context(), userID, req.Model)
+	if !ok {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Model not found"})
 	}
-
-	connection, err := s.db.GetConnection(c.Request().Context(), database.GetConnectionParams{
-		ID:     model.ConnectionID,
-		UserID: userID,
-	})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to get connection"})
-	}
-
-	providerUUID, err := uuid.Parse(connection.ProviderID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to parse provider id"})
-	}
-
-	provider, err := s.db.GetProvider(c.Request().Context(), database.GetProviderParams{
-		ID:     pgtype.UUID{Bytes: providerUUID, Valid: true},
-		UserID: userID,
-	})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Provider not found for model"})
+	model, provider := route.Model, route.Provider
+	if status, msg := s.apiKeyPolicyViolation(c, model); status != 0 {
+		return c.JSON(status, ErrorResponse{Error: msg})
 	}
 
-	// Only allow OpenAI providers for this endpoint
-	if llm.ProviderType(provider.Type) != llm.ProviderOpenAI {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports OpenAI providers"})
+	// Providers speaking the OpenAI API, and those whose API is translated
+	adapter, native := providerAdapter(provider, llm.ProtocolOpenAI)
+	translate, translated := chatTranslators[llm.ProviderType(provider.Type)]
+	if !native && !translated {
+		return c.JSON(http.StatusBadRequest, unsupportedProvider(provider))
 	}
 
 	if model.Type != "llm" {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports LLM models"})
 	}
 
-	decodedEncryptionKey, err := base64.StdEncoding.DecodeString(s.cfg.EncryptionKey)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to decode encryption key"})
+	params := req.Params()
+	if err := s.applyParamPolicy(c.Request().Context(), route, params); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	if len(decodedEncryptionKey) != 32 {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Encryption key must be 32 bytes long after base64 decoding"})
+	messages, err := applyPromptTemplate(route, req.Messages, req.PromptVariables)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
+	logCtx = withPromptTemplate(logCtx, route.PromptTemplate)
 
-	decryptedAPIKey, err := encryption.Decrypt(decodedEncryptionKey, connection.EncryptedApiKey)
+	requestedSchema, err := req.outputSchema()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to decrypt API key"})
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	output := s.outputCheck(route, requestedSchema)
+
+	if !native {
+		return translate(s, c, &chatCall{
+			req:      req,
+			route:    route,
+			messages: messages,
+			params:   params,
+			output:   output,
+			userID:   userID,
+			apiKeyID: apiKeyID,
+			logCtx:   logCtx,
+		})
 	}
 
-	apiKey := string(decryptedAPIKey)
-
-	openAIReq := make(map[string]any)
+	openAIReq := req.upstreamParams(params)
 	openAIReq["model"] = model.ProviderModelID
 	openAIReq["stream"] = req.Stream
-
-	openAIReq["messages"] = req.Messages
-
-	if req.Tools != nil {
-		toolsBytes, err := json.Marshal(req.Tools)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal tools"})
-		}
-		var toolsData any
-		if err := json.Unmarshal(toolsBytes, &toolsData); err != nil {
-			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal tools"})
-		}
-		openAIReq["tools"] = toolsData
+	if req.Stream && req.StreamOptions != nil {
+		openAIReq["stream_options"] = req.StreamOptions
 	}
+	if responseFormat := req.upstreamResponseFormat(output); responseFormat != nil {
+		openAIReq["response_format"] = responseFormat
+	}
+
+	openAIReq["messages"] = messages
 
 	// Marshal the request body to JSON
 	jsonBody, err = json.Marshal(openAIReq)
@@ -144,22 +136,25 @@ func (s *Service) ProxyOpenAIChat(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
 	}
 
-	requestURL := provider.BaseUrl + "/chat/completions"
-	log.Printf("Proxying OpenAI request to: %s", requestURL)
-	proxyReq, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(jsonBody))
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationChat, provider, model)
+	defer span.End()
+
+	newProxyRequest := func(body []byte) (*http.Request, error) {
+		return newUpstreamRequest(ctx, adapter, route, llm.OperationChat, body)
+	}
+	proxyReq, err := newProxyRequest(jsonBody)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create proxy request"})
 	}
+	slog.DebugContext(logCtx, "Proxying OpenAI request", "url", proxyReq.URL.String())
 
-	proxyReq.Header.Set("Content-Type", "application/json")
-	proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+	resp, err := s.upstreamClient(adapter).Do(proxyReq)
 	if err != nil {
+		telemetry.RecordError(span, err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
 	}
 	defer resp.Body.Close()
+	telemetry.SetHTTPStatus(span, resp.StatusCode)
 
 	// Capture response body for logging
 	var responseBody bytes.Buffer
@@ -177,18 +172,21 @@ func (s *Service) ProxyOpenAIChat(c echo.Context) error {
 			if n > 0 {
 				if _, writeErr := c.Response().Write(buf[:n]); writeErr != nil {
 					// Log the conversation even if there's a write error to the client
-					go func() {
-						_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
+					s.inBackground(func() {
+						_, logErr := s.saveLog(logCtx, model, database.CreateLogParams{
 							UserID:          userID,
 							ModelID:         model.ID,
 							RequestPayload:  json.RawMessage(jsonBody),
 							ResponsePayload: json.RawMessage(responseBody.Bytes()),
 							Type:            "llm",
+							ContentFilter:   contentFilter(provider, responseBody.Bytes()),
+							ApiKeyID:        apiKeyID,
+							StatusCode:      pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
 						})
 						if logErr != nil {
-							log.Printf("Error logging conversation (write error path): %v", logErr)
+							slog.ErrorContext(logCtx, "Error logging conversation", "stage", "client_write_error", "error", logErr)
 						}
-					}()
+					})
 					return writeErr
 				}
 				c.Response().Flush()
@@ -198,52 +196,65 @@ func (s *Service) ProxyOpenAIChat(c echo.Context) error {
 			}
 			if err != nil {
 				// Log the conversation even if there's a read error from the proxy
-				go func() {
-					_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
+				s.inBackground(func() {
+					_, logErr := s.saveLog(logCtx, model, database.CreateLogParams{
 						UserID:          userID,
 						ModelID:         model.ID,
 						RequestPayload:  json.RawMessage(jsonBody),
 						ResponsePayload: json.RawMessage(responseBody.Bytes()),
 						Type:            "llm",
+						ContentFilter:   contentFilter(provider, responseBody.Bytes()),
+						ApiKeyID:        apiKeyID,
+						StatusCode:      pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
 					})
 					if logErr != nil {
-						log.Printf("Error logging conversation (read error path): %v", logErr)
+						slog.ErrorContext(logCtx, "Error logging conversation", "stage", "upstream_read_error", "error", logErr)
 					}
-				}()
+				})
 				return err
 			}
 		}
-		// Log the conversation after successful streaming
-		go func() {
-			var finalOpenAIResp OpenAILLMResponse
-			promptTokens := 0
-			completionTokens := 0
-
-			if err := json.Unmarshal(responseBody.Bytes(), &finalOpenAIResp); err == nil {
-				promptTokens = int(finalOpenAIResp.Usage.PromptTokens)
-				completionTokens = int(finalOpenAIResp.Usage.CompletionTokens)
-			} else {
-				log.Printf("Error unmarshaling final OpenAI streaming response for token counts: %v", err)
-			}
-
-			pt := int64(promptTokens)
+		// After streaming is complete, read the token counts from the events;
+		// they are only reported when the client asked for them.
+		usage, ok := llm.StreamUsage(adapter, responseBody.Bytes())
+		if ok {
+			telemetry.SetResponse(span, usage.Model, usage.PromptTokens, usage.CompletionTokens)
+		} else {
+			slog.DebugContext(logCtx, "OpenAI stream did not report token counts")
+		}
 
-			ct := int64(completionTokens)
+		// A streamed output has already been sent, so it is validated for the
+		// log and metrics but cannot be retried.
+		outputs := openAIStreamOutputs(responseBody.Bytes())
+		checked := output.checked(resp.StatusCode, outputs)
+		var validationErr error
+		if checked {
+			_, validationErr = output.validate(outputs)
+		}
 
-			_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
+		// Log the conversation after successful streaming
+		s.inBackground(func() {
+			logParams := database.CreateLogParams{
 				UserID:           userID,
 				ModelID:          model.ID,
 				RequestPayload:   json.RawMessage(jsonBody),
 				ResponsePayload:  json.RawMessage(responseBody.Bytes()),
-				PromptTokens:     pgtype.Int8{Int64: pt, Valid: true},
-				CompletionTokens: pgtype.Int8{Int64: ct, Valid: true},
+				PromptTokens:     pgtype.Int8{Int64: usage.PromptTokens, Valid: true},
+				CompletionTokens: pgtype.Int8{Int64: usage.CompletionTokens, Valid: true},
 				ConnectionID:     model.ConnectionID,
 				Type:             "llm",
-			})
+				ApiKeyID:         apiKeyID,
+				StatusCode:       pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+				ContentFilter:    contentFilter(provider, responseBody.Bytes()),
+			}
+			if checked {
+				setLogFields(&logParams, 1, validationErr)
+			}
+			_, logErr := s.saveLog(logCtx, model, logParams)
 			if logErr != nil {
-				log.Printf("Error logging conversation (streaming success path): %v", logErr)
+				slog.ErrorContext(logCtx, "Error logging conversation", "stage", "stream_complete", "error", logErr)
 			}
-		}()
+		})
 		return nil
 	} else {
 		respBody, err := io.ReadAll(teeReader)
@@ -251,44 +262,80 @@ func (s *Service) ProxyOpenAIChat(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
 		}
 
-		log.Printf("OpenAI API Response Status: %d", resp.StatusCode)
-		log.Printf("OpenAI API Response Body: %s", string(respBody))
-
-		var data any
-		if err := json.Unmarshal(respBody, &data); err != nil {
-			log.Printf("Failed to unmarshal OpenAI proxy response: %v, Raw Body: %s", err, string(respBody))
-			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
-		}
+		// An output that does not match the JSON Schema is sent back to the
+		// model with the validation errors. Every attempt is logged.
+		for attempt := 1; ; attempt++ {
+			slog.DebugContext(logCtx, "OpenAI API response", "status", resp.StatusCode)
+			s.logPayload(logCtx, "OpenAI API response body", respBody)
+			if resp.StatusCode >= 300 {
+				respBody = upstreamErrorBody(logCtx, adapter, resp.StatusCode, respBody)
+			}
 
-		var openAIResp OpenAILLMResponse
-		promptTokens := 0
-		completionTokens := 0
+			var data any
+			if err := json.Unmarshal(respBody, &data); err != nil {
+				slog.ErrorContext(logCtx, "Failed to unmarshal OpenAI proxy response", "error", err)
+				s.logPayload(logCtx, "Unparseable OpenAI response body", respBody)
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
+			}
 
-		if err := json.Unmarshal(respBody, &openAIResp); err == nil {
-			promptTokens = int(openAIResp.Usage.PromptTokens)
-			completionTokens = int(openAIResp.Usage.CompletionTokens)
-		} else {
-			log.Printf("Error unmarshaling final OpenAI streaming response for token counts: %v", err)
-		}
+			usage, ok := adapter.ParseUsage(respBody)
+			if ok {
+				telemetry.SetResponse(span, usage.Model, usage.PromptTokens, usage.CompletionTokens)
+			}
 
-		pt := int64(promptTokens)
+			logParams := database.CreateLogParams{
+				UserID:           userID,
+				ModelID:          model.ID,
+				RequestPayload:   json.RawMessage(jsonBody),
+				ResponsePayload:  json.RawMessage(respBody),
+				PromptTokens:     pgtype.Int8{Int64: usage.PromptTokens, Valid: true},
+				CompletionTokens: pgtype.Int8{Int64: usage.CompletionTokens, Valid: true},
+				ConnectionID:     model.ConnectionID,
+				Type:             "llm",
+				ApiKeyID:         apiKeyID,
+				StatusCode:       pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+				ContentFilter:    contentFilter(provider, respBody),
+			}
+			var openAIResp OpenAILLMResponse
+			_ = json.Unmarshal(respBody, &openAIResp)
+			outputs := openAIResp.outputs()
+			var invalidOutput string
+			var validationErr error
+			if output.checked(resp.StatusCode, outputs) {
+				invalidOutput, validationErr = output.validate(outputs)
+				setLogFields(&logParams, attempt, validationErr)
+			}
+			_, logErr := s.saveLog(logCtx, model, logParams)
+			if logErr != nil {
+				slog.ErrorContext(logCtx, "Error logging conversation", "stage", "response_complete", "error", logErr)
+			}
 
-		ct := int64(completionTokens)
+			if validationErr == nil {
+				return c.JSON(resp.StatusCode, data)
+			}
+			if !output.retry(attempt) {
+				return c.JSON(http.StatusUnprocessableEntity, output.failure(attempt, invalidOutput, validationErr))
+			}
 
-		_, logErr := s.db.CreateLog(context.Background(), database.CreateLogParams{
-			UserID:           userID,
-			ModelID:          model.ID,
-			RequestPayload:   json.RawMessage(jsonBody),
-			ResponsePayload:  json.RawMessage(respBody),
-			PromptTokens:     pgtype.Int8{Int64: pt, Valid: true},
-			CompletionTokens: pgtype.Int8{Int64: ct, Valid: true},
-			ConnectionID:     model.ConnectionID,
-			Type:             "llm",
-		})
-		if logErr != nil {
-			log.Printf("Error logging conversation (non-streaming success path): %v", logErr)
+			slog.InfoContext(logCtx, "Output does not match the JSON Schema, retrying", "model", model.ProxyModelID, "attempt", attempt, "error", validationErr)
+			messages = append(messages, output.feedback(invalidOutput, validationErr)...)
+			openAIReq["messages"] = messages
+			if jsonBody, err = json.Marshal(openAIReq); err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+			}
+			if proxyReq, err = newProxyRequest(jsonBody); err == nil {
+				resp, err = s.upstreamClient(adapter).Do(proxyReq)
+			}
+			if err != nil {
+				telemetry.RecordError(span, err)
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+			}
+			respBody, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+			}
+			telemetry.SetHTTPStatus(span, resp.StatusCode)
 		}
-
-		return c.JSON(resp.StatusCode, data)
 	}
 }
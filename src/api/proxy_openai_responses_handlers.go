@@ -0,0 +1,764 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/llm"
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/telemetry"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// responsesCall is a Responses API request after routing and policies.
+type responsesCall struct {
+	req    ResponsesRequest
+	body   []byte
+	route  routing.Route
+	params map[string]any
+	output *outputCheck
+
+	userID   pgtype.UUID
+	apiKeyID pgtype.UUID
+	logCtx   context.Context
+
+	// history is the conversation of previous_response_id when the proxy
+	// answered it by translation; nil when OpenAI keeps it.
+	history []ChatCompletionMessage
+}
+
+// ollamaChatChunk is an Ollama chat response, or one line of a streamed one.
+type ollamaChatChunk struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int64  `json:"prompt_eval_count"`
+	EvalCount       int64  `json:"eval_count"`
+}
+
+// ProxyOpenAIResponses godoc
+// @Summary Proxy a request to the OpenAI Responses API
+// @Schemes
+// @Description Proxy a request to the OpenAI Responses API. Ollama models are supported by translating to chat requests: the input must be text messages, tools are not supported, and the conversation of stored responses is kept by the proxy for previous_response_id.
+// @Tags Proxy
+// @Accept json
+// @Produce json
+// @Param request body ResponsesRequest true "OpenAI Responses API Request"
+// @Success 200 {object} ResponsesResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 422 {object} api.StructuredOutputError
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/v1/responses [post]
+func (s *Service) ProxyOpenAIResponses(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+	ctx := c.Request().Context()
+	call := &responsesCall{
+		userID:   userID,
+		apiKeyID: GetAPIKeyIDFromContext(c),
+		// Conversation logs are written after the response, so keep the
+		// request's values (request ID) but not its cancellation.
+		logCtx: context.WithoutCancel(ctx),
+	}
+
+	// OpenAI receives the fields the proxy does not know as they were sent.
+	if call.body, err = io.ReadAll(c.Request().Body); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "failed to read request body"})
+	}
+	if err := json.Unmarshal(call.body, &call.req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	req := call.req
+
+	route, ok := s.routes.Lookup(ctx, userID, req.Model)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Model not found"})
+	}
+	call.route = route
+	if status, msg := s.apiKeyPolicyViolation(c, route.Model); status != 0 {
+		return c.JSON(status, ErrorResponse{Error: msg})
+	}
+
+	// Providers serving the Responses API, and Ollama which is translated
+	adapter, ok := llm.AdapterFor(llm.ProviderType(route.Provider.Type))
+	translated := ok && adapter.Protocol() == llm.ProtocolOllama
+	if !ok || (!translated && !adapter.Supports(llm.OperationResponses)) {
+		return c.JSON(http.StatusBadRequest, unsupportedProvider(route.Provider))
+	}
+
+	if route.Model.Type != "llm" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This endpoint only supports LLM models"})
+	}
+
+	call.params = req.Params()
+	if err := s.applyParamPolicy(ctx, route, call.params); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	requestedSchema, err := req.outputSchema()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	call.output = s.outputCheck(route, requestedSchema)
+
+	// Responses the proxy translated are continued from their stored
+	// conversation, whatever the provider; others are left to OpenAI.
+	if req.PreviousResponseID != "" {
+		previous, err := s.db.GetResponse(ctx, database.GetResponseParams{ID: req.PreviousResponseID, UserID: userID})
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(previous.Messages, &call.history); err != nil {
+				slog.ErrorContext(ctx, "Invalid stored response conversation", "response_id", previous.ID, "error", err)
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve previous response"})
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			if translated {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "previous_response_id: response not found"})
+			}
+		default:
+			slog.ErrorContext(ctx, "Error retrieving previous response", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to retrieve previous response"})
+		}
+	}
+	call.logCtx = withPromptTemplate(call.logCtx, route.PromptTemplate)
+
+	if translated {
+		return s.translateResponses(c, call, adapter)
+	}
+	return s.proxyResponses(c, call, adapter)
+}
+
+// proxyResponses sends a Responses API request to an OpenAI provider.
+func (s *Service) proxyResponses(c echo.Context, call *responsesCall, adapter llm.Adapter) error {
+	req, model, provider := call.req, call.route.Model, call.route.Provider
+	logCtx := call.logCtx
+
+	var openAIReq map[string]any
+	if err := json.Unmarshal(call.body, &openAIReq); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	delete(openAIReq, "prompt_variables")
+	openAIReq["model"] = model.ProviderModelID
+	setResponsesParams(openAIReq, call.params)
+	req.setResponsesTextFormat(openAIReq, call.output)
+
+	items, err := req.inputItems()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if call.history != nil {
+		// OpenAI does not know the previous response, so send its conversation.
+		items = append(chatItems(call.history), items...)
+		delete(openAIReq, "previous_response_id")
+		req.PreviousResponseID = ""
+	}
+	instructions, items, err := applyResponsesPromptTemplate(call.route, req, items)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if instructions != "" {
+		openAIReq["instructions"] = instructions
+	}
+	openAIReq["input"] = items
+
+	jsonBody, err := json.Marshal(openAIReq)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+	}
+
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationChat, provider, model)
+	defer span.End()
+
+	newProxyRequest := func(body []byte) (*http.Request, error) {
+		return newUpstreamRequest(ctx, adapter, call.route, llm.OperationResponses, body)
+	}
+	proxyReq, err := newProxyRequest(jsonBody)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create proxy request"})
+	}
+	slog.DebugContext(logCtx, "Proxying OpenAI Responses request", "url", proxyReq.URL.String())
+
+	resp, err := s.upstreamClient(adapter).Do(proxyReq)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+	}
+	defer resp.Body.Close()
+	telemetry.SetHTTPStatus(span, resp.StatusCode)
+
+	// Errors are answered as JSON even to streaming requests.
+	if req.Stream && resp.StatusCode < 300 {
+		var responseBody bytes.Buffer
+		teeReader := io.TeeReader(resp.Body, &responseBody)
+
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+		c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
+		c.Response().WriteHeader(http.StatusOK)
+
+		buf := make([]byte, 4096)
+		for {
+			n, err := teeReader.Read(buf)
+			if n > 0 {
+				if _, writeErr := c.Response().Write(buf[:n]); writeErr != nil {
+					// Log the conversation even if there's a write error to the client
+					s.inBackground(func() {
+						s.saveResponsesLog(call, "client_write_error", call.logParams(jsonBody, streamLogPayload(responseBody.Bytes()), resp.StatusCode, nil))
+					})
+					return writeErr
+				}
+				c.Response().Flush()
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				// Log the conversation even if there's a read error from the proxy
+				s.inBackground(func() {
+					s.saveResponsesLog(call, "upstream_read_error", call.logParams(jsonBody, streamLogPayload(responseBody.Bytes()), resp.StatusCode, nil))
+				})
+				return err
+			}
+		}
+
+		// The final event carries the whole response with its usage; it is
+		// logged instead of the events.
+		final, ok := responsesStreamResult(responseBody.Bytes())
+		var finalResp ResponsesResponse
+		if ok {
+			if err := json.Unmarshal(final, &finalResp); err != nil {
+				slog.WarnContext(logCtx, "Error unmarshaling final OpenAI Responses event for token counts", "error", err)
+			}
+		} else {
+			slog.WarnContext(logCtx, "OpenAI Responses stream ended without a final event")
+			final = streamLogPayload(responseBody.Bytes())
+		}
+		if finalResp.Usage != nil {
+			telemetry.SetResponse(span, finalResp.Model, finalResp.Usage.InputTokens, finalResp.Usage.OutputTokens)
+		}
+
+		// A streamed output has already been sent, so it is validated for the
+		// log and metrics but cannot be retried.
+		outputs := finalResp.outputs()
+		checked := call.output.checked(resp.StatusCode, outputs)
+		var validationErr error
+		if checked {
+			_, validationErr = call.output.validate(outputs)
+		}
+
+		s.inBackground(func() {
+			logParams := call.logParams(jsonBody, final, resp.StatusCode, finalResp.Usage)
+			if checked {
+				setLogFields(&logParams, 1, validationErr)
+			}
+			s.saveResponsesLog(call, "stream_complete", logParams)
+		})
+		return nil
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+	}
+
+	// An output that does not match the JSON Schema is sent back to the
+	// model with the validation errors. Every attempt is logged.
+	for attempt := 1; ; attempt++ {
+		slog.DebugContext(logCtx, "OpenAI Responses API response", "status", resp.StatusCode)
+		s.logPayload(logCtx, "OpenAI Responses API response body", respBody)
+
+		var data any
+		if err := json.Unmarshal(respBody, &data); err != nil {
+			slog.ErrorContext(logCtx, "Failed to unmarshal OpenAI Responses proxy response", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
+		}
+
+		var openAIResp ResponsesResponse
+		if err := json.Unmarshal(respBody, &openAIResp); err != nil {
+			slog.WarnContext(logCtx, "Error unmarshaling OpenAI Responses response for token counts", "error", err)
+		}
+		if openAIResp.Usage != nil {
+			telemetry.SetResponse(span, openAIResp.Model, openAIResp.Usage.InputTokens, openAIResp.Usage.OutputTokens)
+		}
+
+		logParams := call.logParams(jsonBody, respBody, resp.StatusCode, openAIResp.Usage)
+		outputs := openAIResp.outputs()
+		var invalidOutput string
+		var validationErr error
+		if call.output.checked(resp.StatusCode, outputs) {
+			invalidOutput, validationErr = call.output.validate(outputs)
+			setLogFields(&logParams, attempt, validationErr)
+		}
+		s.saveResponsesLog(call, "response_complete", logParams)
+
+		if validationErr == nil {
+			return c.JSON(resp.StatusCode, data)
+		}
+		if !call.output.retry(attempt) {
+			return c.JSON(http.StatusUnprocessableEntity, call.output.failure(attempt, invalidOutput, validationErr))
+		}
+
+		slog.InfoContext(logCtx, "Output does not match the JSON Schema, retrying", "model", model.ProxyModelID, "attempt", attempt, "error", validationErr)
+		items = append(items, chatItems(call.output.feedback(invalidOutput, validationErr))...)
+		openAIReq["input"] = items
+		if jsonBody, err = json.Marshal(openAIReq); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+		}
+		if proxyReq, err = newProxyRequest(jsonBody); err == nil {
+			resp, err = s.upstreamClient(adapter).Do(proxyReq)
+		}
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+		}
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+		}
+		telemetry.SetHTTPStatus(span, resp.StatusCode)
+	}
+}
+
+// translateResponses answers a Responses API request with an Ollama chat
+// request. Stored responses keep their conversation in the database so
+// previous_response_id can continue it.
+func (s *Service) translateResponses(c echo.Context, call *responsesCall, adapter llm.Adapter) error {
+	req, model, provider := call.req, call.route.Model, call.route.Provider
+	logCtx := call.logCtx
+
+	if _, ok := call.params["tools"]; ok {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "tools are only supported with OpenAI providers"})
+	}
+	input, err := req.inputMessages()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	// Instructions and the prompt template apply to this response only, as
+	// with OpenAI, so they are not part of the stored conversation.
+	conversation := append(append([]ChatCompletionMessage{}, call.history...), input...)
+	var messages []ChatCompletionMessage
+	if req.Instructions != "" {
+		messages = append(messages, ChatCompletionMessage{Role: "system", Content: req.Instructions})
+	}
+	messages, err = applyPromptTemplate(call.route, append(messages, conversation...), req.PromptVariables)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	ollamaReq := make(map[string]any)
+	ollamaReq["model"] = model.ProviderModelID
+	ollamaReq["messages"] = messages
+	ollamaReq["stream"] = req.Stream
+	if format := req.ollamaFormat(call.output); format != nil {
+		ollamaReq["format"] = format
+	}
+	options, think := OllamaChatRequest{}.upstreamFields(call.params)
+	if len(options) > 0 {
+		ollamaReq["options"] = options
+	}
+	if think != nil {
+		ollamaReq["think"] = *think
+	}
+
+	jsonBody, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+	}
+
+	ctx, span := startModelCall(c.Request().Context(), telemetry.OperationChat, provider, model)
+	defer span.End()
+
+	send := func(body []byte) (*http.Response, error) {
+		proxyReq, err := newUpstreamRequest(ctx, adapter, call.route, llm.OperationChat, body)
+		if err != nil {
+			return nil, err
+		}
+		slog.DebugContext(logCtx, "Translating Responses request to Ollama", "url", proxyReq.URL.String())
+		resp, err := s.upstreamClient(adapter).Do(proxyReq)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			slog.ErrorContext(logCtx, "Error sending proxy request to Ollama", "error", err)
+			return nil, err
+		}
+		telemetry.SetHTTPStatus(span, resp.StatusCode)
+		return resp, nil
+	}
+
+	result := ResponsesResponse{
+		ID:        responseID("resp"),
+		Object:    "response",
+		CreatedAt: time.Now().Unix(),
+		Status:    responseInProgress,
+		Model:     req.Model,
+		Output:    []ResponsesOutputItem{},
+		Store:     req.store(),
+	}
+	if req.Instructions != "" {
+		result.Instructions = &req.Instructions
+	}
+	if req.PreviousResponseID != "" {
+		result.PreviousResponseID = &req.PreviousResponseID
+	}
+
+	resp, err := send(jsonBody)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+	}
+	defer resp.Body.Close()
+
+	if req.Stream && resp.StatusCode < 300 {
+		return s.streamTranslatedResponses(c, call, resp, jsonBody, result, conversation)
+	}
+
+	// An output that does not match the JSON Schema is sent back to the
+	// model with the validation errors. Every attempt is logged.
+	for attempt := 1; ; attempt++ {
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to read proxy response body"})
+		}
+		s.logPayload(logCtx, "Ollama response body", respBody)
+		if resp.StatusCode >= 300 {
+			return s.translatedResponsesError(c, call, jsonBody, resp.StatusCode, respBody)
+		}
+
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal(respBody, &chunk); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to unmarshal proxy response"})
+		}
+		telemetry.SetResponse(span, chunk.Model, chunk.PromptEvalCount, chunk.EvalCount)
+		attemptResult := translatedResult(result, newOutputIDs(), chunk.Message.Content, chunk.Message.Thinking, chunk)
+
+		payload, err := json.Marshal(attemptResult)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal response"})
+		}
+		logParams := call.logParams(jsonBody, payload, resp.StatusCode, attemptResult.Usage)
+		// Ollama does not count reasoning tokens apart.
+		logParams.ReasoningTokens = pgtype.Int8{}
+		outputs := []string{chunk.Message.Content}
+		var invalidOutput string
+		var validationErr error
+		if call.output.checked(resp.StatusCode, outputs) {
+			invalidOutput, validationErr = call.output.validate(outputs)
+			setLogFields(&logParams, attempt, validationErr)
+		}
+		s.saveResponsesLog(call, "response_complete", logParams)
+
+		if validationErr == nil {
+			s.storeResponse(call, attemptResult, conversation, chunk.Message.Content)
+			return c.JSON(http.StatusOK, attemptResult)
+		}
+		if !call.output.retry(attempt) {
+			return c.JSON(http.StatusUnprocessableEntity, call.output.failure(attempt, invalidOutput, validationErr))
+		}
+
+		slog.InfoContext(logCtx, "Output does not match the JSON Schema, retrying", "model", model.ProxyModelID, "attempt", attempt, "error", validationErr)
+		messages = append(messages, call.output.feedback(invalidOutput, validationErr)...)
+		ollamaReq["messages"] = messages
+		if jsonBody, err = json.Marshal(ollamaReq); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to marshal request body"})
+		}
+		if resp, err = send(jsonBody); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send proxy request"})
+		}
+	}
+}
+
+// streamTranslatedResponses turns a streamed Ollama chat response into the
+// server-sent events of the Responses API. Thinking is sent as a reasoning
+// summary before the message.
+func (s *Service) streamTranslatedResponses(c echo.Context, call *responsesCall, resp *http.Response, jsonBody []byte, result ResponsesResponse, conversation []ChatCompletionMessage) error {
+	logCtx := call.logCtx
+	events := &responseEvents{res: c.Response()}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+
+	var content, thinking bytes.Buffer
+	var last ollamaChatChunk
+	ids := newOutputIDs()
+	reasoning := ResponsesOutputItem{Type: "reasoning", ID: ids.reasoning, Summary: []ResponsesContent{}}
+	message := ResponsesOutputItem{Type: "message", ID: ids.message, Status: responseInProgress, Role: "assistant", Content: []ResponsesContent{}}
+	reasoningIndex, messageIndex := -1, -1
+	nextIndex := 0
+
+	// fail logs what was received so far, since the response did not finish.
+	fail := func(stage string, err error) error {
+		payload, _ := json.Marshal(translatedResult(result, ids, content.String(), thinking.String(), last))
+		s.inBackground(func() {
+			s.saveResponsesLog(call, stage, call.logParams(jsonBody, payload, resp.StatusCode, nil))
+		})
+		return err
+	}
+	closeReasoning := func() error {
+		if reasoningIndex < 0 || messageIndex >= 0 {
+			return nil
+		}
+		part := ResponsesContent{Type: "summary_text", Text: thinking.String()}
+		reasoning.Summary = []ResponsesContent{part}
+		if err := events.send("response.reasoning_summary_text.done", map[string]any{"item_id": reasoning.ID, "output_index": reasoningIndex, "summary_index": 0, "text": part.Text}); err != nil {
+			return err
+		}
+		if err := events.send("response.reasoning_summary_part.done", map[string]any{"item_id": reasoning.ID, "output_index": reasoningIndex, "summary_index": 0, "part": part}); err != nil {
+			return err
+		}
+		return events.send("response.output_item.done", map[string]any{"output_index": reasoningIndex, "item": reasoning})
+	}
+	openMessage := func() error {
+		if messageIndex >= 0 {
+			return nil
+		}
+		if err := closeReasoning(); err != nil {
+			return err
+		}
+		messageIndex = nextIndex
+		nextIndex++
+		if err := events.send("response.output_item.added", map[string]any{"output_index": messageIndex, "item": message}); err != nil {
+			return err
+		}
+		return events.send("response.content_part.added", map[string]any{"item_id": message.ID, "output_index": messageIndex, "content_index": 0, "part": ResponsesContent{Type: "output_text"}})
+	}
+
+	if err := events.send("response.created", map[string]any{"response": result}); err != nil {
+		return fail("client_write_error", err)
+	}
+	if err := events.send("response.in_progress", map[string]any{"response": result}); err != nil {
+		return fail("client_write_error", err)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			slog.WarnContext(logCtx, "Skipping unparseable Ollama stream line", "error", err)
+			continue
+		}
+		last = chunk
+		if delta := chunk.Message.Thinking; delta != "" && messageIndex < 0 {
+			if reasoningIndex < 0 {
+				reasoningIndex = nextIndex
+				nextIndex++
+				if err := events.send("response.output_item.added", map[string]any{"output_index": reasoningIndex, "item": reasoning}); err != nil {
+					return fail("client_write_error", err)
+				}
+				if err := events.send("response.reasoning_summary_part.added", map[string]any{"item_id": reasoning.ID, "output_index": reasoningIndex, "summary_index": 0, "part": ResponsesContent{Type: "summary_text"}}); err != nil {
+					return fail("client_write_error", err)
+				}
+			}
+			thinking.WriteString(delta)
+			if err := events.send("response.reasoning_summary_text.delta", map[string]any{"item_id": reasoning.ID, "output_index": reasoningIndex, "summary_index": 0, "delta": delta}); err != nil {
+				return fail("client_write_error", err)
+			}
+		}
+		if delta := chunk.Message.Content; delta != "" {
+			if err := openMessage(); err != nil {
+				return fail("client_write_error", err)
+			}
+			content.WriteString(delta)
+			if err := events.send("response.output_text.delta", map[string]any{"item_id": message.ID, "output_index": messageIndex, "content_index": 0, "delta": delta}); err != nil {
+				return fail("client_write_error", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fail("upstream_read_error", err)
+	}
+
+	// A response without text still has its (empty) message.
+	if err := openMessage(); err != nil {
+		return fail("client_write_error", err)
+	}
+	final := translatedResult(result, ids, content.String(), thinking.String(), last)
+	message = final.Output[len(final.Output)-1]
+	part := message.Content[0]
+	if err := events.send("response.output_text.done", map[string]any{"item_id": message.ID, "output_index": messageIndex, "content_index": 0, "text": part.Text}); err != nil {
+		return fail("client_write_error", err)
+	}
+	if err := events.send("response.content_part.done", map[string]any{"item_id": message.ID, "output_index": messageIndex, "content_index": 0, "part": part}); err != nil {
+		return fail("client_write_error", err)
+	}
+	if err := events.send("response.output_item.done", map[string]any{"output_index": messageIndex, "item": message}); err != nil {
+		return fail("client_write_error", err)
+	}
+
+	// Store before completing, so the client can continue right away.
+	s.storeResponse(call, final, conversation, content.String())
+	finalEvent := "response.completed"
+	if final.Status == responseIncomplete {
+		finalEvent = "response.incomplete"
+	}
+	if err := events.send(finalEvent, map[string]any{"response": final}); err != nil {
+		return fail("client_write_error", err)
+	}
+
+	// A streamed output has already been sent, so it is validated for the
+	// log and metrics but cannot be retried.
+	outputs := []string{content.String()}
+	checked := call.output.checked(resp.StatusCode, outputs)
+	var validationErr error
+	if checked {
+		_, validationErr = call.output.validate(outputs)
+	}
+	s.inBackground(func() {
+		payload, err := json.Marshal(final)
+		if err != nil {
+			slog.ErrorContext(logCtx, "Error logging conversation", "stage", "stream_complete", "error", err)
+			return
+		}
+		logParams := call.logParams(jsonBody, payload, resp.StatusCode, final.Usage)
+		logParams.ReasoningTokens = pgtype.Int8{}
+		if checked {
+			setLogFields(&logParams, 1, validationErr)
+		}
+		s.saveResponsesLog(call, "stream_complete", logParams)
+	})
+	return nil
+}
+
+// outputIDs are the IDs of the output items of a translated response.
+type outputIDs struct {
+	reasoning, message string
+}
+
+func newOutputIDs() outputIDs {
+	return outputIDs{reasoning: responseID("rs"), message: responseID("msg")}
+}
+
+// translatedResult completes a translated response with its output and the
+// usage of the Ollama response.
+func translatedResult(result ResponsesResponse, ids outputIDs, content, thinking string, chunk ollamaChatChunk) ResponsesResponse {
+	result.Status = responseCompleted
+	if chunk.DoneReason == "length" {
+		result.Status = responseIncomplete
+		result.IncompleteDetails = &ResponsesIncompleteDetails{Reason: "max_output_tokens"}
+	}
+	result.Output = []ResponsesOutputItem{}
+	if thinking != "" {
+		result.Output = append(result.Output, ResponsesOutputItem{
+			Type:    "reasoning",
+			ID:      ids.reasoning,
+			Summary: []ResponsesContent{{Type: "summary_text", Text: thinking}},
+		})
+	}
+	result.Output = append(result.Output, ResponsesOutputItem{
+		Type:    "message",
+		ID:      ids.message,
+		Status:  result.Status,
+		Role:    "assistant",
+		Content: []ResponsesContent{{Type: "output_text", Text: content}},
+	})
+	result.Usage = &ResponsesUsage{
+		InputTokens:  chunk.PromptEvalCount,
+		OutputTokens: chunk.EvalCount,
+		TotalTokens:  chunk.PromptEvalCount + chunk.EvalCount,
+	}
+	return result
+}
+
+// translatedResponsesError logs and returns an Ollama error response.
+func (s *Service) translatedResponsesError(c echo.Context, call *responsesCall, jsonBody []byte, statusCode int, respBody []byte) error {
+	var data any
+	if err := json.Unmarshal(respBody, &data); err != nil {
+		data = ErrorResponse{Error: string(respBody)}
+		respBody, _ = json.Marshal(data)
+	}
+	s.saveResponsesLog(call, "response_complete", call.logParams(jsonBody, respBody, statusCode, nil))
+	return c.JSON(statusCode, data)
+}
+
+// storeResponse keeps the conversation of a translated response so
+// previous_response_id can continue it. A failure only prevents that.
+func (s *Service) storeResponse(call *responsesCall, result ResponsesResponse, conversation []ChatCompletionMessage, output string) {
+	if !result.Store {
+		return
+	}
+	conversation = append(conversation, ChatCompletionMessage{Role: "assistant", Content: output})
+	messages, err := json.Marshal(conversation)
+	if err == nil {
+		err = s.db.CreateResponse(call.logCtx, database.CreateResponseParams{ID: result.ID, UserID: call.userID, Messages: messages})
+	}
+	if err != nil {
+		slog.ErrorContext(call.logCtx, "Error storing response", "response_id", result.ID, "error", err)
+	}
+}
+
+// logParams builds the conversation log of one upstream call. usage is nil
+// when the upstream did not report it.
+func (call *responsesCall) logParams(requestBody, responseBody []byte, statusCode int, usage *ResponsesUsage) database.CreateLogParams {
+	params := database.CreateLogParams{
+		UserID:          call.userID,
+		ModelID:         call.route.Model.ID,
+		RequestPayload:  json.RawMessage(requestBody),
+		ResponsePayload: json.RawMessage(responseBody),
+		ConnectionID:    call.route.Model.ConnectionID,
+		Type:            "llm",
+		ApiKeyID:        call.apiKeyID,
+		StatusCode:      pgtype.Int4{Int32: int32(statusCode), Valid: true},
+	}
+	if usage != nil {
+		params.PromptTokens = pgtype.Int8{Int64: usage.InputTokens, Valid: true}
+		params.CompletionTokens = pgtype.Int8{Int64: usage.OutputTokens, Valid: true}
+		params.ReasoningTokens = pgtype.Int8{Int64: usage.OutputTokensDetails.ReasoningTokens, Valid: true}
+	}
+	return params
+}
+
+func (s *Service) saveResponsesLog(call *responsesCall, stage string, params database.CreateLogParams) {
+	if _, err := s.saveLog(call.logCtx, call.route.Model, params); err != nil {
+		slog.ErrorContext(call.logCtx, "Error logging conversation", "stage", stage, "error", err)
+	}
+}
+
+// streamLogPayload stores the raw events of a stream that did not finish as a
+// JSON string, since payloads are JSON.
+func streamLogPayload(body []byte) json.RawMessage {
+	payload, _ := json.Marshal(string(body))
+	return payload
+}
+
+// responseEvents writes the server-sent events of a Responses API stream.
+type responseEvents struct {
+	res *echo.Response
+	seq int
+}
+
+func (e *responseEvents) send(eventType string, fields map[string]any) error {
+	fields["type"] = eventType
+	fields["sequence_number"] = e.seq
+	e.seq++
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(e.res, "event: %s\ndata: %s\n\n", eventType, data); err != nil {
+		return err
+	}
+	e.res.Flush()
+	return nil
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/database"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The upstreams of these tests are answered from the fixtures in
+// testdata/recordings, one file per host; nothing is sent over the network.
+
+func newReplayEnv(t *testing.T) *testEnv {
+	t.Helper()
+	return newTestEnv(t, config.Config{
+		LogPolicyDefault: "full",
+		UpstreamMode:     "replay",
+		RecordingsDir:    "testdata/recordings",
+	})
+}
+
+// onlyLog returns the single conversation log of the test user.
+func (env *testEnv) onlyLog() database.ListLogsRow {
+	env.t.Helper()
+	logs, err := env.store.ListLogs(context.Background(), database.ListLogsParams{UserID: env.user, Limit: 10})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		env.t.Fatalf("got %d logs, want 1", len(logs))
+	}
+	return logs[0]
+}
+
+func TestReplayOpenAIChat(t *testing.T) {
+	env := newReplayEnv(t)
+	env.addModel("openai", "http://openai.test/v1", "gpt", "gpt-4o-mini", "llm")
+
+	rec := env.serve(echo.New(), env.s.ProxyOpenAIChat, "/v1/chat/completions",
+		`{"model":"gpt","messages":[{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Hello! How can I help you today?" {
+		t.Errorf("unexpected answer %s", rec.Body)
+	}
+
+	log := env.onlyLog()
+	if log.PromptTokens.Int64 != 9 || log.CompletionTokens.Int64 != 9 || log.StatusCode.Int32 != http.StatusOK {
+		t.Errorf("logged %d prompt and %d completion tokens with status %d, want 9, 9 and 200",
+			log.PromptTokens.Int64, log.CompletionTokens.Int64, log.StatusCode.Int32)
+	}
+}
+
+func TestReplayOpenAIChatStream(t *testing.T) {
+	env := newReplayEnv(t)
+	env.addModel("openai", "http://openai.test/v1", "gpt", "gpt-4o-mini", "llm")
+
+	rec := env.serve(echo.New(), env.s.ProxyOpenAIChat, "/v1/chat/completions",
+		`{"model":"gpt","stream":true,"messages":[{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `"content":"Hello"`) || !strings.Contains(body, `"content":" there!"`) || !strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]") {
+		t.Errorf("unexpected stream %s", body)
+	}
+
+	log := env.onlyLog()
+	if log.PromptTokens.Int64 != 9 || log.CompletionTokens.Int64 != 3 {
+		t.Errorf("logged %d prompt and %d completion tokens, want 9 and 3", log.PromptTokens.Int64, log.CompletionTokens.Int64)
+	}
+}
+
+func TestReplayOllamaChat(t *testing.T) {
+	env := newReplayEnv(t)
+	env.addModel("ollama", "http://ollama.test", "llama", "llama3.2", "llm")
+
+	rec := env.serve(echo.New(), env.s.ProxyOllamaChat, "/chat",
+		`{"model":"llama","stream":false,"messages":[{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Done bool `json:"done"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "Hello! Nice to meet you." || !resp.Done {
+		t.Errorf("unexpected answer %s", rec.Body)
+	}
+
+	log := env.onlyLog()
+	if log.PromptTokens.Int64 != 11 || log.CompletionTokens.Int64 != 7 {
+		t.Errorf("logged %d prompt and %d completion tokens, want 11 and 7", log.PromptTokens.Int64, log.CompletionTokens.Int64)
+	}
+}
+
+func TestReplayOpenAIEmbedding(t *testing.T) {
+	env := newReplayEnv(t)
+	env.addModel("openai", "http://openai.test/v1", "embed", "text-embedding-3-small", "embedding")
+
+	rec := env.serve(echo.New(), env.s.ProxyOpenAIEmbedding, "/v1/embeddings", `{"model":"embed","input":"hello world"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 1 || len(resp.Data[0].Embedding) != 4 {
+		t.Errorf("unexpected answer %s", rec.Body)
+	}
+
+	log := env.onlyLog()
+	if log.PromptTokens.Int64 != 2 || log.Type != "embedding" {
+		t.Errorf("logged %d prompt tokens for a %q request, want 2 for an embedding", log.PromptTokens.Int64, log.Type)
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	env := newReplayEnv(t)
+	env.addModel("openai", "http://openai.test/v1", "gpt", "gpt-4o-mini", "llm")
+
+	rec := env.serve(echo.New(), env.s.ProxyOpenAIChat, "/v1/chat/completions",
+		`{"model":"gpt","messages":[{"role":"user","content":"Not recorded"}]}`)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "replay_miss") {
+		t.Errorf("status %d: %s, want 404 with a replay_miss error", rec.Code, rec.Body)
+	}
+}
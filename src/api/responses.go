@@ -0,0 +1,401 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/structuredoutput"
+	"github.com/google/uuid"
+)
+
+// ResponsesRequest is an OpenAI Responses API request. Only the fields the
+// proxy reads are listed; OpenAI providers receive every field the client sent.
+type ResponsesRequest struct {
+	Model string `json:"model"`
+	// Input is a string or a list of input items.
+	Input              json.RawMessage `json:"input" swaggertype:"object"`
+	Instructions       string          `json:"instructions,omitempty"`
+	PreviousResponseID string          `json:"previous_response_id,omitempty"`
+	Stream             bool            `json:"stream,omitempty"`
+	// Store defaults to true; stored responses can be continued with previous_response_id.
+	Store      *bool `json:"store,omitempty"`
+	Tools      any   `json:"tools,omitempty"`
+	ToolChoice any   `json:"tool_choice,omitempty"`
+
+	// Sampling and reasoning parameters, subject to the model's parameter policy
+	Temperature     *float64            `json:"temperature,omitempty"`
+	TopP            *float64            `json:"top_p,omitempty"`
+	MaxOutputTokens *int                `json:"max_output_tokens,omitempty"`
+	Reasoning       *ResponsesReasoning `json:"reasoning,omitempty"`
+
+	// Text with a json_schema format is enforced on the output, see StructuredOutputError.
+	Text *ResponsesText `json:"text,omitempty"`
+
+	// PromptVariables fill in the model's prompt template; they are not sent upstream.
+	PromptVariables map[string]string `json:"prompt_variables,omitempty"`
+}
+
+type ResponsesReasoning struct {
+	Effort  string `json:"effort,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type ResponsesText struct {
+	Format *ResponsesTextFormat `json:"format,omitempty"`
+}
+
+// ResponsesTextFormat is response_format of chat completions, flattened.
+type ResponsesTextFormat struct {
+	Type        string         `json:"type"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
+}
+
+// ResponsesResponse is an OpenAI Responses API response.
+type ResponsesResponse struct {
+	ID                 string                      `json:"id"`
+	Object             string                      `json:"object"`
+	CreatedAt          int64                       `json:"created_at"`
+	Status             string                      `json:"status"`
+	IncompleteDetails  *ResponsesIncompleteDetails `json:"incomplete_details"`
+	Model              string                      `json:"model"`
+	Instructions       *string                     `json:"instructions"`
+	PreviousResponseID *string                     `json:"previous_response_id"`
+	Output             []ResponsesOutputItem       `json:"output"`
+	Store              bool                        `json:"store"`
+	Usage              *ResponsesUsage             `json:"usage"`
+}
+
+type ResponsesIncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+// ResponsesOutputItem is a message or, with summary, a reasoning item.
+type ResponsesOutputItem struct {
+	Type    string             `json:"type"`
+	ID      string             `json:"id"`
+	Status  string             `json:"status,omitempty"`
+	Role    string             `json:"role,omitempty"`
+	Content []ResponsesContent `json:"content,omitempty"`
+	Summary []ResponsesContent `json:"summary,omitempty"`
+}
+
+type ResponsesContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type ResponsesUsage struct {
+	InputTokens         int64                        `json:"input_tokens"`
+	InputTokensDetails  ResponsesInputTokensDetails  `json:"input_tokens_details"`
+	OutputTokens        int64                        `json:"output_tokens"`
+	OutputTokensDetails ResponsesOutputTokensDetails `json:"output_tokens_details"`
+	TotalTokens         int64                        `json:"total_tokens"`
+}
+
+type ResponsesInputTokensDetails struct {
+	CachedTokens int64 `json:"cached_tokens"`
+}
+
+type ResponsesOutputTokensDetails struct {
+	ReasoningTokens int64 `json:"reasoning_tokens"`
+}
+
+// Values of ResponsesResponse.Status.
+const (
+	responseInProgress = "in_progress"
+	responseCompleted  = "completed"
+	responseIncomplete = "incomplete"
+)
+
+// responsesParams maps parameter policy names to Responses API fields.
+// reasoning_effort is reasoning.effort; the other policy parameters have no
+// Responses API equivalent and are not sent to OpenAI.
+var responsesParams = map[string]string{
+	"temperature": "temperature",
+	"top_p":       "top_p",
+	"max_tokens":  "max_output_tokens",
+	"tools":       "tools",
+	"tool_choice": "tool_choice",
+}
+
+// Params returns the parameters of the request a parameter policy applies to.
+func (r ResponsesRequest) Params() map[string]any {
+	params := map[string]any{}
+	setIfPresent(params, "temperature", r.Temperature)
+	setIfPresent(params, "top_p", r.TopP)
+	setIfPresent(params, "max_tokens", r.MaxOutputTokens)
+	if r.Tools != nil {
+		params["tools"] = r.Tools
+	}
+	if r.ToolChoice != nil {
+		params["tool_choice"] = r.ToolChoice
+	}
+	if r.Reasoning != nil && r.Reasoning.Effort != "" {
+		params["reasoning_effort"] = r.Reasoning.Effort
+	}
+	return params
+}
+
+// setResponsesParams writes params back to the request body sent to OpenAI.
+func setResponsesParams(body map[string]any, params map[string]any) {
+	for name, field := range responsesParams {
+		delete(body, field)
+		if v, ok := params[name]; ok {
+			body[field] = v
+		}
+	}
+	reasoning, _ := body["reasoning"].(map[string]any)
+	if reasoning == nil {
+		reasoning = map[string]any{}
+	}
+	delete(reasoning, "effort")
+	if effort, ok := params["reasoning_effort"]; ok {
+		reasoning["effort"] = effort
+	}
+	if len(reasoning) == 0 {
+		delete(body, "reasoning")
+	} else {
+		body["reasoning"] = reasoning
+	}
+}
+
+// inputItems returns the request's input as a list of items; a string input
+// is a single user message.
+func (r ResponsesRequest) inputItems() ([]any, error) {
+	var input any
+	if len(r.Input) > 0 {
+		if err := json.Unmarshal(r.Input, &input); err != nil {
+			return nil, fmt.Errorf("input: %w", err)
+		}
+	}
+	switch input := input.(type) {
+	case nil:
+		return nil, errors.New("input is required")
+	case string:
+		return []any{map[string]any{"role": "user", "content": input}}, nil
+	case []any:
+		return input, nil
+	default:
+		return nil, errors.New("input must be a string or a list of items")
+	}
+}
+
+// inputMessages translates the request's input to chat messages, for
+// providers without a Responses API. Only message items with text content can
+// be translated. The returned error is meant for the client.
+func (r ResponsesRequest) inputMessages() ([]ChatCompletionMessage, error) {
+	items, err := r.inputItems()
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]ChatCompletionMessage, 0, len(items))
+	for i, item := range items {
+		var msg struct {
+			Type    string          `json:"type"`
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		}
+		raw, _ := json.Marshal(item)
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return nil, fmt.Errorf("input[%d]: %w", i, err)
+		}
+		if msg.Type != "" && msg.Type != "message" {
+			return nil, fmt.Errorf("input[%d]: items of type %s are only supported with OpenAI providers", i, msg.Type)
+		}
+		content, err := textContent(msg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("input[%d]: %w", i, err)
+		}
+		switch msg.Role {
+		case "user", "assistant", "system":
+		case "developer":
+			msg.Role = "system"
+		default:
+			return nil, fmt.Errorf("input[%d]: unknown role %q", i, msg.Role)
+		}
+		messages = append(messages, ChatCompletionMessage{Role: msg.Role, Content: content})
+	}
+	return messages, nil
+}
+
+// textContent joins the text parts of a message's content, which is a string
+// or a list of parts.
+func textContent(raw json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", errors.New("content must be a string or a list of content parts")
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "input_text", "output_text", "text":
+			texts = append(texts, part.Text)
+		default:
+			return "", fmt.Errorf("content parts of type %s are only supported with OpenAI providers", part.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// chatItems converts chat messages to Responses API input items.
+func chatItems(messages []ChatCompletionMessage) []any {
+	items := make([]any, len(messages))
+	for i, m := range messages {
+		items[i] = map[string]any{"role": m.Role, "content": m.Content}
+	}
+	return items
+}
+
+// applyResponsesPromptTemplate adds the model's prompt template to a request
+// sent to OpenAI: its system prompt goes to the instructions and its examples
+// before the input. With previous_response_id the upstream already has the
+// examples, so only the instructions are set.
+func applyResponsesPromptTemplate(route routing.Route, req ResponsesRequest, items []any) (string, []any, error) {
+	if route.PromptTemplate == nil {
+		return req.Instructions, items, nil
+	}
+	var system []ChatCompletionMessage
+	if req.Instructions != "" {
+		system = []ChatCompletionMessage{{Role: "system", Content: req.Instructions}}
+	}
+	rendered, err := applyPromptTemplate(route, system, req.PromptVariables)
+	if err != nil {
+		return "", nil, err
+	}
+	var instructions []string
+	var examples []ChatCompletionMessage
+	for _, m := range rendered {
+		if m.Role == "system" {
+			instructions = append(instructions, m.Content)
+		} else {
+			examples = append(examples, m)
+		}
+	}
+	if req.PreviousResponseID == "" {
+		items = append(chatItems(examples), items...)
+	}
+	return strings.Join(instructions, "\n\n"), items, nil
+}
+
+// outputSchema compiles the json_schema format of the request's text.
+// The returned error is meant for the client.
+func (r ResponsesRequest) outputSchema() (*structuredoutput.Schema, error) {
+	format := r.textFormat()
+	if format == nil || format.Type != "json_schema" {
+		return nil, nil
+	}
+	if len(format.Schema) == 0 {
+		return nil, errors.New("text.format: schema is required")
+	}
+	schema, err := structuredoutput.Compile(format.Name, format.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("text.format: %w", err)
+	}
+	return schema, nil
+}
+
+func (r ResponsesRequest) textFormat() *ResponsesTextFormat {
+	if r.Text == nil {
+		return nil
+	}
+	return r.Text.Format
+}
+
+// setResponsesTextFormat sends the model's schema to OpenAI when the client
+// did not send one of its own.
+func (r ResponsesRequest) setResponsesTextFormat(body map[string]any, check *outputCheck) {
+	if check == nil || r.textFormat() != nil && r.textFormat().Type == "json_schema" {
+		return
+	}
+	text, _ := body["text"].(map[string]any)
+	if text == nil {
+		text = map[string]any{}
+	}
+	text["format"] = map[string]any{"type": "json_schema", "name": check.schema.Name, "schema": check.schema.Document}
+	body["text"] = text
+}
+
+// ollamaFormat translates the request's text format to Ollama's format. nil
+// leaves format out.
+func (r ResponsesRequest) ollamaFormat(check *outputCheck) any {
+	if check != nil {
+		return check.schema.Document
+	}
+	if format := r.textFormat(); format != nil && format.Type == "json_object" {
+		return "json"
+	}
+	return nil
+}
+
+// store reports whether the response is stored; OpenAI stores by default.
+func (r ResponsesRequest) store() bool {
+	return r.Store == nil || *r.Store
+}
+
+// outputs returns the text of the message items; function calls are not
+// checked against the schema.
+func (r ResponsesResponse) outputs() []string {
+	var outputs []string
+	for _, item := range r.Output {
+		if item.Type != "message" {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range item.Content {
+			if part.Type == "output_text" {
+				text.WriteString(part.Text)
+			}
+		}
+		outputs = append(outputs, text.String())
+	}
+	return outputs
+}
+
+// responsesStreamResult returns the response carried by the final event of a
+// streamed Responses API response, if any.
+func responsesStreamResult(body []byte) (json.RawMessage, bool) {
+	var final json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type     string          `json:"type"`
+			Response json.RawMessage `json:"response"`
+		}
+		if json.Unmarshal([]byte(strings.TrimSpace(data)), &event) != nil {
+			continue
+		}
+		switch event.Type {
+		case "response.completed", "response.incomplete", "response.failed":
+			final = event.Response
+		}
+	}
+	return final, final != nil
+}
+
+// responseID returns a new ID for an item of the given prefix, such as resp
+// or msg, shaped like OpenAI's.
+func responseID(prefix string) string {
+	id := uuid.New()
+	return prefix + "_" + hex.EncodeToString(id[:])
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"gen-ai-proxy/src/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+type CreateRetentionPolicyRequest struct {
+	// ModelID scopes the policy to one model; omit it for the user-wide default.
+	ModelID        *string `json:"model_id"`
+	PayloadTTLDays int32   `json:"payload_ttl_days"`
+	RowTTLDays     *int32  `json:"row_ttl_days"`
+	Archive        bool    `json:"archive"`
+}
+
+type ListRetentionPoliciesResponse struct {
+	Policies []database.RetentionPolicy `json:"policies"`
+}
+
+// ListRetentionPolicies godoc
+// @Summary List retention policies
+// @Schemes
+// @Description List the conversation log retention policies of the authenticated user.
+// @Tags Logs
+// @Accept json
+// @Produce json
+// @Success 200 {object} ListRetentionPoliciesResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/retention-policies [get]
+func (s *Service) ListRetentionPolicies(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	policies, err := s.db.ListRetentionPolicies(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to list retention policies"})
+	}
+	if policies == nil {
+		policies = []database.RetentionPolicy{}
+	}
+
+	return c.JSON(http.StatusOK, ListRetentionPoliciesResponse{Policies: policies})
+}
+
+// CreateRetentionPolicy godoc
+// @Summary Create a retention policy
+// @Schemes
+// @Description Create a default or per-model conversation log retention policy. Payloads are purged after payload_ttl_days; when row_ttl_days is set, rows are rolled up into daily usage aggregates and deleted after that many days.
+// @Tags Logs
+// @Accept json
+// @Produce json
+// @Param policy body CreateRetentionPolicyRequest true "Retention policy"
+// @Success 201 {object} database.RetentionPolicy
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/retention-policies [post]
+func (s *Service) CreateRetentionPolicy(c echo.Context) error {
+	var req CreateRetentionPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	if req.PayloadTTLDays < 1 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "payload_ttl_days must be at least 1"})
+	}
+	if req.RowTTLDays != nil && *req.RowTTLDays < req.PayloadTTLDays {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "row_ttl_days must not be shorter than payload_ttl_days"})
+	}
+	if req.Archive && s.cfg.ArchiveTarget == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "archival requested but ARCHIVE_TARGET is not configured"})
+	}
+
+	params := database.CreateRetentionPolicyParams{
+		UserID:         userID,
+		PayloadTtlDays: req.PayloadTTLDays,
+		Archive:        req.Archive,
+	}
+	if req.RowTTLDays != nil {
+		params.RowTtlDays = pgtype.Int4{Int32: *req.RowTTLDays, Valid: true}
+	}
+	if req.ModelID != nil {
+		parsedID, err := uuid.Parse(*req.ModelID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid model ID format"})
+		}
+		params.ModelID = pgtype.UUID{Bytes: parsedID, Valid: true}
+		if _, err := s.GetModelFromDB(c.Request().Context(), params.ModelID, userID); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "model not found"})
+		}
+	}
+
+	policy, err := s.db.CreateRetentionPolicy(c.Request().Context(), params)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: "a retention policy already exists for this scope"})
+		}
+		slog.ErrorContext(c.Request().Context(), "CreateRetentionPolicy: failed to create policy", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create retention policy"})
+	}
+
+	s.recordAudit(c, userID, AuditActionCreate, AuditResourceRetentionPolicy, policy.ID.String(), nil, policy)
+
+	return c.JSON(http.StatusCreated, policy)
+}
+
+// DeleteRetentionPolicy godoc
+// @Summary Delete a retention policy
+// @Schemes
+// @Description Delete a conversation log retention policy. Logs it covered fall back to the default policy, or are kept indefinitely if there is none.
+// @Tags Logs
+// @Accept json
+// @Produce json
+// @Param id path string true "Retention policy ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/retention-policies/{id} [delete]
+func (s *Service) DeleteRetentionPolicy(c echo.Context) error {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid retention policy ID format"})
+	}
+	policyID := pgtype.UUID{Bytes: parsedID, Valid: true}
+
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	before, err := s.db.GetRetentionPolicy(c.Request().Context(), database.GetRetentionPolicyParams{
+		ID:     policyID,
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "retention policy not found"})
+	}
+
+	err = s.db.DeleteRetentionPolicy(c.Request().Context(), database.DeleteRetentionPolicyParams{
+		ID:     policyID,
+		UserID: userID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete retention policy"})
+	}
+
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourceRetentionPolicy, policyID.String(), before, nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteLog godoc
+// @Summary Delete a conversation log
+// @Schemes
+// @Description Delete a single conversation log on request (e.g. a GDPR erasure). Its token usage is kept in the daily usage aggregates so metrics stay accurate.
+// @Tags Logs
+// @Accept json
+// @Produce json
+// @Param id path string true "Log ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/conversation_logs/{id} [delete]
+func (s *Service) DeleteLog(c echo.Context) error {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid log ID format"})
+	}
+	logID := pgtype.UUID{Bytes: parsedID, Valid: true}
+
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	ctx := c.Request().Context()
+	before, err := s.db.GetLog(ctx, database.GetLogParams{ID: logID, UserID: userID})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "log not found"})
+	}
+
+	ids := []pgtype.UUID{logID}
+	err = s.db.ExecTx(ctx, func(q database.Querier) error {
+		if err := q.RollupLogs(ctx, ids); err != nil {
+			return err
+		}
+		_, err := q.DeleteLogs(ctx, ids)
+		return err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "DeleteLog: failed to delete log", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to delete log"})
+	}
+
+	// Only metadata goes into the audit trail; the point of the erasure is
+	// that the payload is gone.
+	s.recordAudit(c, userID, AuditActionDelete, AuditResourceConversationLog, logID.String(), map[string]any{
+		"model_id":          before.ModelID,
+		"connection_id":     before.ConnectionID,
+		"type":              before.Type,
+		"created_at":        before.CreatedAt,
+		"prompt_tokens":     before.PromptTokens,
+		"completion_tokens": before.CompletionTokens,
+	}, nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -2,17 +2,22 @@ package api
 
 import (
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
 func RegisterRoutes(e *echo.Echo, s *Service) {
+	e.Use(RequestIDMiddleware())
+	e.Use(AccessLogMiddleware())
+
+	// Health probes
+	e.GET("/healthz", s.Healthz)
+	e.GET("/readyz", s.Readyz)
+
 	// Docs
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	// All gen-ai-proxy routes
 	apiGroup := e.Group("/api")
-	apiGroup.Use(middleware.Logger())
 	apiGroup.Use(JWTAuthMiddleware([]byte(s.cfg.JWTSecret)))
 
 	// User Authentication
@@ -27,6 +32,7 @@ func RegisterRoutes(e *echo.Echo, s *Service) {
 	// Connections
 	apiGroup.POST("/connections", s.CreateConnection)
 	apiGroup.GET("/connections", s.ListConnections)
+	apiGroup.GET("/connections/:id/models", s.ListConnectionModels)
 	apiGroup.DELETE("/connections/:id", s.DeleteConnection)
 
 	// Providers
@@ -37,18 +43,62 @@ func RegisterRoutes(e *echo.Echo, s *Service) {
 	// Models
 	apiGroup.POST("/models", s.CreateModel)
 	apiGroup.GET("/models", s.ListModels)
+	apiGroup.PUT("/models/:id", s.UpdateModel)
 	apiGroup.DELETE("/models/:id", s.SoftDeleteModel)
 
+	// Prompt templates
+	apiGroup.POST("/prompt-templates", s.CreatePromptTemplate)
+	apiGroup.GET("/prompt-templates", s.ListPromptTemplates)
+	apiGroup.GET("/prompt-templates/:id", s.GetPromptTemplate)
+	apiGroup.DELETE("/prompt-templates/:id", s.DeletePromptTemplate)
+	apiGroup.GET("/prompt-templates/:id/versions", s.ListPromptTemplateVersions)
+	apiGroup.POST("/prompt-templates/:id/versions", s.CreatePromptTemplateVersion)
+	apiGroup.POST("/prompt-templates/:id/rollback", s.RollbackPromptTemplate)
+
+	// Routing
+	apiGroup.GET("/routing", s.GetRoutingSnapshot)
+
 	// Logs
 	apiGroup.GET("/conversation_logs", s.ListLogs)
+	apiGroup.DELETE("/conversation_logs/:id", s.DeleteLog)
+
+	// Retention
+	apiGroup.GET("/retention-policies", s.ListRetentionPolicies)
+	apiGroup.POST("/retention-policies", s.CreateRetentionPolicy)
+	apiGroup.DELETE("/retention-policies/:id", s.DeleteRetentionPolicy)
+
+	// Audit
+	apiGroup.GET("/audit", s.ListAuditLogs)
+	apiGroup.GET("/audit/export", s.ExportAuditLogs)
+
+	// Evaluation runs
+	apiGroup.POST("/eval-runs", s.CreateEvalRun)
+	apiGroup.GET("/eval-runs", s.ListEvalRuns)
+	apiGroup.GET("/eval-runs/:id", s.GetEvalRun)
+	apiGroup.GET("/eval-runs/:id/results", s.ListEvalResults)
+	apiGroup.POST("/eval-runs/:id/cancel", s.CancelEvalRun)
+
+	// Shadow traffic
+	apiGroup.GET("/shadow-comparisons", s.ListShadowComparisons)
 
 	// Proxies
 	apiKeyGroup := e.Group("/api")
-	apiKeyGroup.Use(middleware.Logger())
 	apiKeyGroup.Use(APIKeyAuthMiddleware(s.db))
 
-	apiKeyGroup.POST("/chat", s.ProxyOllamaChat)
-	apiKeyGroup.POST("/v1/chat/completions", s.ProxyOpenAIChat)
-	apiKeyGroup.POST("/v1/embeddings", s.ProxyOpenAIEmbedding)
+	apiKeyGroup.POST("/chat", s.ProxyOllamaChat, s.ShadowTrafficMiddleware())
+	apiKeyGroup.POST("/v1/chat/completions", s.ProxyOpenAIChat, s.ShadowTrafficMiddleware())
+	apiKeyGroup.POST("/v1/embeddings", s.ProxyOpenAIEmbedding, s.ShadowTrafficMiddleware())
+	apiKeyGroup.POST("/v1/responses", s.ProxyOpenAIResponses)
+
+	// Batches
+	apiKeyGroup.POST("/v1/files", s.UploadFile)
+	apiKeyGroup.GET("/v1/files", s.ListFiles)
+	apiKeyGroup.GET("/v1/files/:id", s.GetFile)
+	apiKeyGroup.GET("/v1/files/:id/content", s.GetFileContent)
+	apiKeyGroup.DELETE("/v1/files/:id", s.DeleteFile)
+	apiKeyGroup.POST("/v1/batches", s.CreateBatch)
+	apiKeyGroup.GET("/v1/batches", s.ListBatches)
+	apiKeyGroup.GET("/v1/batches/:id", s.GetBatch)
+	apiKeyGroup.POST("/v1/batches/:id/cancel", s.CancelBatch)
 
 }
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// RouteResponse describes where requests for one proxy model are sent. The
+// connection credential is never included.
+type RouteResponse struct {
+	ProxyModelID    string      `json:"proxy_model_id"`
+	ProviderModelID string      `json:"provider_model_id"`
+	ModelID         pgtype.UUID `json:"model_id"`
+	Type            string      `json:"type"`
+	ConnectionID    pgtype.UUID `json:"connection_id"`
+	ConnectionName  string      `json:"connection_name"`
+	ProviderID      pgtype.UUID `json:"provider_id"`
+	ProviderName    string      `json:"provider_name"`
+	ProviderType    string      `json:"provider_type"`
+	BaseURL         string      `json:"base_url"`
+}
+
+type RoutingSnapshotResponse struct {
+	// Version is the routing version the snapshot was loaded at.
+	Version  int64           `json:"version"`
+	LoadedAt time.Time       `json:"loaded_at"`
+	Routes   []RouteResponse `json:"routes"`
+}
+
+// GetRoutingSnapshot godoc
+// @Summary Show the routing table
+// @Schemes
+// @Description Show the in-memory routing table this instance uses for the authenticated user's proxy models. With refresh=true the database is checked for changes first.
+// @Tags Models
+// @Accept json
+// @Produce json
+// @Param refresh query bool false "Reload the table if the database has changed"
+// @Success 200 {object} RoutingSnapshotResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/routing [get]
+func (s *Service) GetRoutingSnapshot(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	if c.QueryParam("refresh") == "true" {
+		if _, err := s.routes.Refresh(c.Request().Context()); err != nil {
+			slog.ErrorContext(c.Request().Context(), "Failed to refresh routing table", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to refresh routing table"})
+		}
+	}
+
+	snapshot := s.routes.Snapshot()
+	resp := RoutingSnapshotResponse{
+		Version:  snapshot.Version,
+		LoadedAt: snapshot.LoadedAt,
+		Routes:   []RouteResponse{},
+	}
+	for _, route := range snapshot.Routes(userID) {
+		resp.Routes = append(resp.Routes, RouteResponse{
+			ProxyModelID:    route.Model.ProxyModelID,
+			ProviderModelID: route.Model.ProviderModelID,
+			ModelID:         route.Model.ID,
+			Type:            route.Model.Type,
+			ConnectionID:    route.Model.ConnectionID,
+			ConnectionName:  route.ConnectionName,
+			ProviderID:      route.Provider.ID,
+			ProviderName:    route.Provider.Name,
+			ProviderType:    route.Provider.Type,
+			BaseURL:         route.Provider.BaseUrl,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// refreshRoutes reloads the routing table after a provider, connection, model
+// or prompt template changed, so this instance serves the change at once
+// rather than after the next notification or poll. The change is already
+// committed, so a failure is only logged.
+func (s *Service) refreshRoutes(c echo.Context) {
+	if _, err := s.routes.Refresh(c.Request().Context()); err != nil {
+		slog.WarnContext(c.Request().Context(), "Failed to refresh routing table", "error", err)
+	}
+}
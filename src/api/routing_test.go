@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/database/sqlite"
+	"gen-ai-proxy/src/routing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+func TestCreatedModelRoutesAtOnce(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`))
+	}))
+	defer upstream.Close()
+
+	env := newTestEnv(t, config.Config{})
+	conn := env.addConnection("openai", upstream.URL+"/v1", "openai")
+	// A miss just before the model exists uses up the miss check.
+	if _, ok := env.routes.Lookup(context.Background(), env.user, "gpt"); ok {
+		t.Fatal("found a model that does not exist yet")
+	}
+
+	e := echo.New()
+	rec := env.serve(e, env.s.CreateModel, "/api/models",
+		`{"connection_id":"`+conn.ID.String()+`","provider_model_id":"gpt-4o-mini","proxy_model_id":"gpt","type":"llm"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", rec.Code, rec.Body)
+	}
+	rec = env.serve(e, env.s.ProxyOpenAIChat, "/v1/chat/completions",
+		`{"model":"gpt","messages":[{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("chat status %d right after creating the model: %s", rec.Code, rec.Body)
+	}
+}
+
+// slowVersionStore takes a while to read the routing version, so that lookups
+// overlap a refresh in flight.
+type slowVersionStore struct {
+	*sqlite.Store
+}
+
+func (s slowVersionStore) GetRoutingVersion(ctx context.Context) (int64, error) {
+	time.Sleep(20 * time.Millisecond)
+	return s.Store.GetRoutingVersion(ctx)
+}
+
+func TestConcurrentLookupMissesShareRefresh(t *testing.T) {
+	env := newTestEnv(t, config.Config{})
+	routes, err := routing.NewTable(context.Background(), slowVersionStore{env.store}, base64.StdEncoding.EncodeToString(env.key), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := env.addConnection("openai", "http://openai.test/v1", "openai")
+	// Created behind the table's back, as on another replica.
+	if _, err := env.store.CreateModel(context.Background(), database.CreateModelParams{
+		ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, UserID: env.user, ConnectionID: conn.ID,
+		ProxyModelID: "gpt", ProviderModelID: "gpt-4o-mini", Type: "llm",
+		PriceInput: pgtype.Numeric{Int: big.NewInt(0), Valid: true}, PriceOutput: pgtype.Numeric{Int: big.NewInt(0), Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	found := make([]bool, 8)
+	for i := range found {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, found[i] = routes.Lookup(context.Background(), env.user, "gpt")
+		}()
+	}
+	close(start)
+	wg.Wait()
+	for i, ok := range found {
+		if !ok {
+			t.Errorf("lookup %d missed the new model", i)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/logging"
+	"gen-ai-proxy/src/metrics"
+	"gen-ai-proxy/src/routing"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// shadowTimeout bounds a mirrored request, which no client waits for.
+const shadowTimeout = 5 * time.Minute
+
+type shadowLinkKey struct{}
+
+type shadowMirrorKey struct{}
+
+// shadowLink is the ID the log of a mirrored request is created with, so its
+// mirror can point to it whenever either is written. Only the first log of
+// the request, that of its first upstream attempt, takes it.
+type shadowLink struct {
+	logID   pgtype.UUID
+	claimed atomic.Bool
+}
+
+// shadowMirror marks a request as the mirror of the request logged as of,
+// served by route, the shadow target of the mirrored model.
+type shadowMirror struct {
+	of    pgtype.UUID
+	route routing.Route
+}
+
+func withShadowLink(ctx context.Context, link *shadowLink) context.Context {
+	return context.WithValue(ctx, shadowLinkKey{}, link)
+}
+
+func withShadowMirror(ctx context.Context, mirror *shadowMirror) context.Context {
+	return context.WithValue(ctx, shadowMirrorKey{}, mirror)
+}
+
+// lookupRoute finds the route of a user's proxy model. A mirrored request
+// names the model it mirrors and takes that model's shadow target instead.
+func (s *Service) lookupRoute(ctx context.Context, userID pgtype.UUID, proxyModelID string) (routing.Route, bool) {
+	if mirror, ok := ctx.Value(shadowMirrorKey{}).(*shadowMirror); ok {
+		return mirror.route, mirror.route.Model.ProxyModelID == proxyModelID
+	}
+	return s.routes.Lookup(ctx, userID, proxyModelID)
+}
+
+// shadowLogFields sets the ID of a new conversation log and, for the log of
+// a mirror, the log of the request it mirrors with the provider model and
+// prices of model, the shadow target.
+func shadowLogFields(ctx context.Context, model database.Model, params *database.CreateLogParams) {
+	params.ID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	if link, ok := ctx.Value(shadowLinkKey{}).(*shadowLink); ok && link.claimed.CompareAndSwap(false, true) {
+		params.ID = link.logID
+	}
+	if mirror, ok := ctx.Value(shadowMirrorKey{}).(*shadowMirror); ok {
+		params.ShadowOf = mirror.of
+		params.ProviderModelID = pgtype.Text{String: model.ProviderModelID, Valid: true}
+		params.PriceInput, params.PriceOutput = model.PriceInput, model.PriceOutput
+	}
+}
+
+// ShadowTrafficMiddleware mirrors the share of requests set by the shadow
+// policy of their model to its shadow target. The mirror runs in the
+// background once the client has its response, through the same handler, as
+// the same user and API key; its answer is logged and discarded. Requests
+// answered with a client error, such as invalid requests or exhausted
+// budgets, are not mirrored.
+func (s *Service) ShadowTrafficMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route, body, ok := s.shadowRoute(c)
+			if !ok {
+				return next(c)
+			}
+
+			link := &shadowLink{logID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}
+			req := c.Request()
+			c.SetRequest(req.WithContext(withShadowLink(req.Context(), link)))
+			err := next(c)
+			if status := c.Response().Status; err != nil || (status >= 400 && status < 500) {
+				return err
+			}
+
+			// The echo context is reused once the handler returns, so take
+			// what the mirror needs now.
+			ctx := withShadowMirror(context.WithoutCancel(req.Context()), &shadowMirror{of: link.logID, route: *route.ShadowTarget})
+			userID, _ := c.Get(userContextKey).(pgtype.UUID)
+			apiKeyID := GetAPIKeyIDFromContext(c)
+			path := req.URL.Path
+			s.inBackground(func() {
+				s.mirror(ctx, next, path, route, body, userID, apiKeyID)
+			})
+			return nil
+		}
+	}
+}
+
+// shadowRoute returns the route of the request, with the request body, when
+// the request is sampled for mirroring. The body is put back for the
+// handler.
+func (s *Service) shadowRoute(c echo.Context) (routing.Route, []byte, bool) {
+	userID, ok := c.Get(userContextKey).(pgtype.UUID)
+	if !ok {
+		return routing.Route{}, nil, false
+	}
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return routing.Route{}, nil, false
+	}
+
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return routing.Route{}, nil, false
+	}
+	route, ok := s.routes.Lookup(req.Context(), userID, payload.Model)
+	if !ok || route.ShadowTarget == nil || !route.Shadow.Sample() {
+		return routing.Route{}, nil, false
+	}
+	return route, body, true
+}
+
+// mirror sends body to handler again. The body still names the mirrored
+// model, so the API key's allowed models held for it already; the handler
+// takes the model's shadow target from ctx.
+func (s *Service) mirror(ctx context.Context, handler echo.HandlerFunc, path string, route routing.Route, body []byte, userID, apiKeyID pgtype.UUID) {
+	ctx, cancel := context.WithTimeout(withRequestStart(ctx, time.Now()), shadowTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(RequestIDHeader, logging.RequestID(ctx))
+
+	// Without the API key record, the key's budget does not apply; the key is
+	// still logged for cost reports.
+	rec := &responseRecorder{header: http.Header{}}
+	c := s.batchEcho.NewContext(req, rec)
+	c.Set(userContextKey, userID)
+	c.Set(apiKeyContextKey, apiKeyID)
+	if err := handler(c); err != nil {
+		s.batchEcho.HTTPErrorHandler(err, c)
+	}
+
+	target := route.ShadowTarget
+	result := "success"
+	if rec.status >= 400 {
+		result = "error"
+		slog.InfoContext(ctx, "Mirrored request failed", "model", route.Model.ProxyModelID,
+			"shadow_connection", target.ConnectionName, "shadow_model", target.Model.ProviderModelID,
+			"status", rec.status, "error", errorMessage(rec.body.Bytes()))
+	}
+	metrics.ShadowRequestsTotal.WithLabelValues(route.Model.ProxyModelID, target.ConnectionName, target.Model.ProviderModelID, result).Inc()
+}
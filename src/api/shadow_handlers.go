@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/shadow"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// ListShadowComparisonsRequest filters the mirrored requests compared. Since
+// and Until are RFC3339 timestamps.
+type ListShadowComparisonsRequest struct {
+	ModelID string `query:"model_id"`
+	Since   string `query:"since"`
+	Until   string `query:"until"`
+}
+
+// ShadowComparison compares a model with its shadow target, a provider model
+// on one of the user's connections, on the requests mirrored from one to the
+// other.
+type ShadowComparison struct {
+	PrimaryModelID      pgtype.UUID `json:"primary_model_id"`
+	PrimaryModel        string      `json:"primary_model"`
+	ShadowConnectionID  pgtype.UUID `json:"shadow_connection_id"`
+	ShadowConnection    string      `json:"shadow_connection"`
+	ShadowProviderModel string      `json:"shadow_provider_model"`
+	// Requests is the number of mirrored requests.
+	Requests int64                `json:"requests"`
+	Primary  ShadowComparisonSide `json:"primary"`
+	Shadow   ShadowComparisonSide `json:"shadow"`
+}
+
+// ShadowComparisonSide is the outcome of the mirrored requests on one side.
+type ShadowComparisonSide struct {
+	Errors           int64   `json:"errors"`
+	ErrorRate        float64 `json:"error_rate"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	Cost             float64 `json:"cost"`
+}
+
+type ListShadowComparisonsResponse struct {
+	Comparisons []ShadowComparison `json:"comparisons"`
+}
+
+// ListShadowComparisons godoc
+// @Summary Compare models with their shadow targets
+// @Schemes
+// @Description Compare each model with its shadow target on the requests mirrored to it: error rates, token counts, average latencies and costs, per model, shadow connection and shadow provider model. Only the first upstream attempt of a request is compared.
+// @Tags Shadow traffic
+// @Produce json
+// @Param model_id query string false "Only compare the shadow targets of this model"
+// @Param since query string false "Only requests mirrored at or after this RFC3339 timestamp"
+// @Param until query string false "Only requests mirrored before this RFC3339 timestamp"
+// @Success 200 {object} ListShadowComparisonsResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Security BearerAuth
+// @Router /api/shadow-comparisons [get]
+func (s *Service) ListShadowComparisons(c echo.Context) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+	}
+
+	var req ListShadowComparisonsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	params := database.ListShadowComparisonsParams{UserID: userID}
+	if req.ModelID != "" {
+		modelID, err := uuid.Parse(req.ModelID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid Model ID"})
+		}
+		params.ModelID = pgtype.UUID{Bytes: modelID, Valid: true}
+	}
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "since must be an RFC3339 timestamp"})
+		}
+		params.Since = pgtype.Timestamptz{Time: since, Valid: true}
+	}
+	if req.Until != "" {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "until must be an RFC3339 timestamp"})
+		}
+		params.Until = pgtype.Timestamptz{Time: until, Valid: true}
+	}
+
+	rows, err := s.db.ListShadowComparisons(c.Request().Context(), params)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Error comparing shadow traffic", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to compare shadow traffic"})
+	}
+
+	resp := ListShadowComparisonsResponse{Comparisons: []ShadowComparison{}}
+	for _, row := range rows {
+		resp.Comparisons = append(resp.Comparisons, ShadowComparison{
+			PrimaryModelID:      row.PrimaryModelID,
+			PrimaryModel:        row.PrimaryModel,
+			ShadowConnectionID:  row.ShadowConnectionID,
+			ShadowConnection:    row.ShadowConnection,
+			ShadowProviderModel: row.ShadowProviderModel,
+			Requests:            row.Requests,
+			Primary: ShadowComparisonSide{
+				Errors:           row.PrimaryErrors,
+				ErrorRate:        float64(row.PrimaryErrors) / float64(row.Requests),
+				PromptTokens:     row.PrimaryPromptTokens,
+				CompletionTokens: row.PrimaryCompletionTokens,
+				AvgLatencyMs:     row.PrimaryAvgLatencyMs,
+				Cost:             numericValue(row.PrimaryCost),
+			},
+			Shadow: ShadowComparisonSide{
+				Errors:           row.ShadowErrors,
+				ErrorRate:        float64(row.ShadowErrors) / float64(row.Requests),
+				PromptTokens:     row.ShadowPromptTokens,
+				CompletionTokens: row.ShadowCompletionTokens,
+				AvgLatencyMs:     row.ShadowAvgLatencyMs,
+				Cost:             numericValue(row.ShadowCost),
+			},
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// shadowPolicy validates the shadow policy of a model served by
+// providerModelID on connectionID and encodes it for storage. Requests are
+// only mirrored to connections of the model's user.
+func (s *Service) shadowPolicy(ctx context.Context, userID pgtype.UUID, policy *shadow.Policy, connectionID pgtype.UUID, providerModelID string) (json.RawMessage, error) {
+	if err := policy.Validate(connectionID, providerModelID); err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, nil
+	}
+	if _, err := s.db.GetConnection(ctx, database.GetConnectionParams{ID: policy.ConnectionID, UserID: userID}); err != nil {
+		return nil, fmt.Errorf("Shadow connection with ID %s not found for this user", policy.ConnectionID.String())
+	}
+	return policy.Marshal()
+}
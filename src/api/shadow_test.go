@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/shadow"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestShadowTrafficMirrorsToProviderModel(t *testing.T) {
+	var mu sync.Mutex
+	var upstreamModels []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		upstreamModels = append(upstreamModels, req.Model)
+		mu.Unlock()
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"` + req.Model + `","choices":[{"index":0,"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`))
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	env := newTestEnv(t, config.Config{LogPolicyDefault: "full"})
+	model := env.addModel("openai", upstream.URL+"/v1", "gpt", "gpt-4o-mini", "llm")
+	candidate := env.addConnection("openai", upstream.URL+"/v1", "candidate")
+
+	policy, err := (&shadow.Policy{
+		ConnectionID: candidate.ID, ProviderModelID: "gpt-4o-next", PriceInput: 0.001, PriceOutput: 0.002, Percent: 100,
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.store.UpdateModel(ctx, database.UpdateModelParams{
+		ID: model.ID, UserID: env.user, ProxyModelID: model.ProxyModelID, ProviderModelID: model.ProviderModelID,
+		PriceInput: model.PriceInput, PriceOutput: model.PriceOutput, Type: model.Type, LogPolicy: model.LogPolicy,
+		ConnectionID: model.ConnectionID, Shadow: policy,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.routes.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	rec := env.serve(e, env.s.ShadowTrafficMiddleware()(env.s.ProxyOpenAIChat), "/v1/chat/completions",
+		`{"model":"gpt","messages":[{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	slices.Sort(upstreamModels)
+	if !slices.Equal(upstreamModels, []string{"gpt-4o-mini", "gpt-4o-next"}) {
+		t.Errorf("upstream got models %v, want gpt-4o-mini and gpt-4o-next", upstreamModels)
+	}
+
+	logs, err := env.store.ListLogs(ctx, database.ListLogsParams{UserID: env.user, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d logs, want 2", len(logs))
+	}
+	primary, mirrored := logs[0], logs[1]
+	if primary.ShadowOf.Valid {
+		primary, mirrored = mirrored, primary
+	}
+	if mirrored.ShadowOf != primary.ID || mirrored.ModelID != model.ID || mirrored.ConnectionID != candidate.ID ||
+		mirrored.ProviderModelID.String != "gpt-4o-next" {
+		t.Errorf("mirrored log points to %v on connection %v for %q, want %v on %v for gpt-4o-next",
+			mirrored.ShadowOf, mirrored.ConnectionID, mirrored.ProviderModelID.String, primary.ID, candidate.ID)
+	}
+	if cost := numericValue(mirrored.Cost); cost < 0.0199 || cost > 0.0201 {
+		t.Errorf("mirrored request cost %v, want 0.02 at the shadow prices", cost)
+	}
+
+	comparisons, err := env.store.ListShadowComparisons(ctx, database.ListShadowComparisonsParams{UserID: env.user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparisons) != 1 || comparisons[0].ShadowConnection != "candidate" ||
+		comparisons[0].ShadowProviderModel != "gpt-4o-next" || comparisons[0].Requests != 1 {
+		t.Errorf("unexpected comparisons %+v", comparisons)
+	}
+
+	// Evaluation runs sample what clients sent, not the mirrored copies.
+	samples, err := env.store.SampleEvalLogs(ctx, database.SampleEvalLogsParams{UserID: env.user, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].ID != primary.ID {
+		t.Errorf("sampled %d logs for evaluation, want only the primary one", len(samples))
+	}
+
+	// The candidate is no proxy model, so clients cannot call it.
+	rec = env.serve(e, env.s.ProxyOpenAIChat, "/v1/chat/completions/direct",
+		`{"model":"gpt-4o-next","messages":[{"role":"user","content":"Say hello"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("direct request for the candidate got status %d, want 400", rec.Code)
+	}
+}
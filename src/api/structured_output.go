@@ -0,0 +1,304 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/metrics"
+	"gen-ai-proxy/src/routing"
+	"gen-ai-proxy/src/structuredoutput"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ResponseFormat is OpenAI's response_format. Only json_schema outputs are
+// validated by the proxy.
+type ResponseFormat struct {
+	Type       string                    `json:"type"`
+	JSONSchema *ResponseFormatJSONSchema `json:"json_schema,omitempty"`
+}
+
+type ResponseFormatJSONSchema struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
+}
+
+// StructuredOutputError is returned with 422 when the output still does not
+// match its JSON Schema after the allowed retries.
+type StructuredOutputError struct {
+	Error            string   `json:"error"`
+	ValidationErrors []string `json:"validation_errors"`
+	Attempts         int      `json:"attempts"`
+	// Output is the last invalid output.
+	Output string `json:"output"`
+}
+
+// Values of logs.schema_validation.
+const (
+	schemaValid   = "valid"
+	schemaInvalid = "invalid"
+)
+
+// outputCheck enforces a JSON Schema on the output of one chat request. A nil
+// check accepts every output.
+type outputCheck struct {
+	model      string
+	schema     *structuredoutput.Schema
+	maxRetries int
+}
+
+// outputCheck returns the check for a request: its own schema, else the
+// model's. Retries come from the model's policy, else STRUCTURED_OUTPUT_MAX_RETRIES.
+func (s *Service) outputCheck(route routing.Route, requested *structuredoutput.Schema) *outputCheck {
+	check := &outputCheck{model: route.Model.ProxyModelID, schema: requested, maxRetries: int(s.outputRetries.Load())}
+	if p := route.StructuredOutput; p != nil {
+		if check.schema == nil {
+			check.schema = p.Compiled()
+		}
+		if p.MaxRetries != nil {
+			check.maxRetries = *p.MaxRetries
+		}
+	}
+	if check.schema == nil {
+		return nil
+	}
+	return check
+}
+
+// validate checks every output of one attempt and counts the result. It
+// returns the first invalid output with its *structuredoutput.ValidationError.
+func (c *outputCheck) validate(outputs []string) (string, error) {
+	if c == nil || len(outputs) == 0 {
+		return "", nil
+	}
+	for _, output := range outputs {
+		if err := c.schema.Validate(output); err != nil {
+			metrics.StructuredOutputValidationsTotal.WithLabelValues(c.model, schemaInvalid).Inc()
+			return output, err
+		}
+	}
+	metrics.StructuredOutputValidationsTotal.WithLabelValues(c.model, schemaValid).Inc()
+	return "", nil
+}
+
+// checked reports whether an upstream response carries outputs to validate.
+func (c *outputCheck) checked(statusCode int, outputs []string) bool {
+	return c != nil && statusCode < 300 && len(outputs) > 0
+}
+
+// retry reports whether another attempt is allowed after the given one.
+func (c *outputCheck) retry(attempt int) bool {
+	return attempt <= c.maxRetries
+}
+
+// feedback returns the messages to append so the model can correct output.
+func (c *outputCheck) feedback(output string, err error) []ChatCompletionMessage {
+	return []ChatCompletionMessage{
+		{Role: "assistant", Content: output},
+		{Role: "user", Content: c.schema.Feedback(err)},
+	}
+}
+
+// failure counts a request given up on and builds its error response.
+func (c *outputCheck) failure(attempts int, output string, err error) StructuredOutputError {
+	metrics.StructuredOutputFailuresTotal.WithLabelValues(c.model).Inc()
+	resp := StructuredOutputError{
+		Error:    "the output does not match the JSON Schema",
+		Attempts: attempts,
+		Output:   output,
+	}
+	var verr *structuredoutput.ValidationError
+	if errors.As(err, &verr) {
+		resp.ValidationErrors = verr.Errors
+	} else {
+		resp.ValidationErrors = []string{err.Error()}
+	}
+	return resp
+}
+
+// setLogFields records the validation outcome of an attempt on its log.
+func setLogFields(params *database.CreateLogParams, attempt int, err error) {
+	params.SchemaAttempt = pgtype.Int4{Int32: int32(attempt), Valid: true}
+	if err == nil {
+		params.SchemaValidation = pgtype.Text{String: schemaValid, Valid: true}
+		return
+	}
+	params.SchemaValidation = pgtype.Text{String: schemaInvalid, Valid: true}
+	params.SchemaErrors = pgtype.Text{String: err.Error(), Valid: true}
+}
+
+// outputSchema compiles the json_schema of the request's response_format.
+// The returned error is meant for the client.
+func (r ChatCompletionRequest) outputSchema() (*structuredoutput.Schema, error) {
+	if r.ResponseFormat == nil || r.ResponseFormat.Type != "json_schema" {
+		return nil, nil
+	}
+	if r.ResponseFormat.JSONSchema == nil || len(r.ResponseFormat.JSONSchema.Schema) == 0 {
+		return nil, errors.New("response_format: json_schema.schema is required")
+	}
+	schema, err := structuredoutput.Compile(r.ResponseFormat.JSONSchema.Name, r.ResponseFormat.JSONSchema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("response_format: %w", err)
+	}
+	return schema, nil
+}
+
+// upstreamResponseFormat is the client's response_format, or the model's schema
+// when the client did not send one of its own.
+func (r ChatCompletionRequest) upstreamResponseFormat(check *outputCheck) *ResponseFormat {
+	if check == nil || r.ResponseFormat != nil && r.ResponseFormat.Type == "json_schema" {
+		return r.ResponseFormat
+	}
+	return &ResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &ResponseFormatJSONSchema{Name: check.schema.Name, Schema: check.schema.Document},
+	}
+}
+
+// outputSchema compiles the request's format when it is a schema rather than
+// "json". The returned error is meant for the client.
+func (r OllamaChatRequest) outputSchema() (*structuredoutput.Schema, error) {
+	if !isJSONObject(r.Format) {
+		return nil, nil
+	}
+	var document map[string]any
+	if err := json.Unmarshal(r.Format, &document); err != nil {
+		return nil, fmt.Errorf("format: %w", err)
+	}
+	schema, err := structuredoutput.Compile("", document)
+	if err != nil {
+		return nil, fmt.Errorf("format: %w", err)
+	}
+	return schema, nil
+}
+
+// upstreamFormat is the client's format, or the model's schema when the
+// client did not send a schema of its own. nil leaves format out.
+func (r OllamaChatRequest) upstreamFormat(check *outputCheck) any {
+	if check != nil && !isJSONObject(r.Format) {
+		return check.schema.Document
+	}
+	if len(r.Format) == 0 {
+		return nil
+	}
+	return r.Format
+}
+
+func isJSONObject(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && raw[0] == '{'
+}
+
+// outputs returns the content of the choices that are final answers; tool
+// calls are not checked against the schema.
+func (r OpenAILLMResponse) outputs() []string {
+	var outputs []string
+	for _, choice := range r.Choices {
+		if choice.FinishReason != "tool_calls" {
+			outputs = append(outputs, choice.Message.Content)
+		}
+	}
+	return outputs
+}
+
+// ollamaOutputs returns the content of an Ollama chat response unless it is a
+// tool call.
+func ollamaOutputs(body []byte) []string {
+	content, toolCall, err := ollamaMessage(body)
+	if err != nil || toolCall {
+		return nil
+	}
+	return []string{content}
+}
+
+func ollamaMessage(body []byte) (content string, toolCall bool, err error) {
+	var resp struct {
+		Message struct {
+			Content   string            `json:"content"`
+			ToolCalls []json.RawMessage `json:"tool_calls"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", false, err
+	}
+	return resp.Message.Content, len(resp.Message.ToolCalls) > 0, nil
+}
+
+// openAIStreamOutputs reassembles the content of each choice from the
+// server-sent events of a streamed completion.
+func openAIStreamOutputs(body []byte) []string {
+	contents := map[int]*strings.Builder{}
+	toolCalls := map[int]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Index int `json:"index"`
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk) != nil {
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			if contents[choice.Index] == nil {
+				contents[choice.Index] = &strings.Builder{}
+			}
+			contents[choice.Index].WriteString(choice.Delta.Content)
+			if choice.FinishReason == "tool_calls" {
+				toolCalls[choice.Index] = true
+			}
+		}
+	}
+	indexes := make([]int, 0, len(contents))
+	for index := range contents {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var outputs []string
+	for _, index := range indexes {
+		if !toolCalls[index] {
+			outputs = append(outputs, contents[index].String())
+		}
+	}
+	return outputs
+}
+
+// ollamaStreamOutputs reassembles the content of a streamed Ollama chat
+// response, one JSON object per line.
+func ollamaStreamOutputs(body []byte) []string {
+	var content strings.Builder
+	parsed := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		chunk, toolCall, err := ollamaMessage(scanner.Bytes())
+		if err != nil {
+			continue
+		}
+		if toolCall {
+			return nil
+		}
+		content.WriteString(chunk)
+		parsed = true
+	}
+	if !parsed {
+		return nil
+	}
+	return []string{content.String()}
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"context"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/telemetry"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startModelCall starts the GenAI client span around one upstream call. The
+// caller must end the span and should pass the returned context to the
+// upstream request so the provider receives the trace context.
+func startModelCall(ctx context.Context, operation string, provider database.Provider, model database.Model) (context.Context, trace.Span) {
+	return telemetry.StartModelCall(ctx, operation, provider.Type, model.ProviderModelID,
+		telemetry.ProxyModelKey.String(model.ProxyModelID),
+		telemetry.ModelIDKey.String(model.ID.String()),
+		telemetry.ConnectionIDKey.String(model.ConnectionID.String()),
+		telemetry.ProviderIDKey.String(provider.ID.String()),
+	)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/telemetry"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestProxiedChatSpansShareTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := telemetry.SetupWithExporter(exporter, "gen-ai-proxy-test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() {
+		shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("traceparent") == "" {
+			t.Error("upstream request carries no traceparent header")
+		}
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini-2024-07-18","choices":[{"index":0,"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":3,"total_tokens":12}}`))
+	}))
+	defer upstream.Close()
+
+	env := newTestEnv(t, config.Config{LogPolicyDefault: "full"})
+	env.addModel("openai", upstream.URL, "gpt", "gpt-4o-mini", "llm")
+
+	provider := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	exporter.Reset()
+
+	e := echo.New()
+	e.Use(otelecho.Middleware("gen-ai-proxy-test"))
+	rec := env.serve(e, env.s.ProxyOpenAIChat, "/v1/chat/completions",
+		`{"model":"gpt","messages":[{"role":"user","content":"Hi"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := exporter.GetSpans()
+
+	var server, upstreamCall, persist *tracetest.SpanStub
+	var dbSpans []tracetest.SpanStub
+	for i, span := range spans {
+		switch {
+		case span.SpanKind == trace.SpanKindServer:
+			server = &spans[i]
+		case hasAttribute(span.Attributes, semconv.GenAISystemKey.String("openai")):
+			upstreamCall = &spans[i]
+		case span.Name == "persist conversation log":
+			persist = &spans[i]
+		case hasAttribute(span.Attributes, attribute.String("db.system", "sqlite")):
+			dbSpans = append(dbSpans, span)
+		}
+	}
+	if server == nil || upstreamCall == nil || persist == nil || len(dbSpans) == 0 {
+		t.Fatalf("missing spans: server %v, upstream %v, persist %v, db %d; recorded %d spans",
+			server != nil, upstreamCall != nil, persist != nil, len(dbSpans), len(spans))
+	}
+
+	traceID := server.SpanContext.TraceID()
+	if got := upstreamCall.SpanContext.TraceID(); got != traceID {
+		t.Errorf("upstream span in trace %s, want %s", got, traceID)
+	}
+	if got := persist.SpanContext.TraceID(); got != traceID {
+		t.Errorf("persist span in trace %s, want %s", got, traceID)
+	}
+	var createLog bool
+	for _, span := range dbSpans {
+		if got := span.SpanContext.TraceID(); got != traceID {
+			t.Errorf("db span %q in trace %s, want %s", span.Name, got, traceID)
+		}
+		createLog = createLog || span.Name == "CreateLog"
+	}
+	if !createLog {
+		t.Error("no CreateLog db span")
+	}
+
+	for _, want := range []attribute.KeyValue{
+		semconv.GenAIOperationNameKey.String(telemetry.OperationChat),
+		semconv.GenAIRequestModelKey.String("gpt-4o-mini"),
+		semconv.GenAIResponseModelKey.String("gpt-4o-mini-2024-07-18"),
+		semconv.GenAIUsageInputTokensKey.Int64(9),
+		semconv.GenAIUsageOutputTokensKey.Int64(3),
+		telemetry.ProxyModelKey.String("gpt"),
+	} {
+		if !hasAttribute(upstreamCall.Attributes, want) {
+			t.Errorf("upstream span lacks %s=%s", want.Key, want.Value.Emit())
+		}
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
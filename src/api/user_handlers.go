@@ -3,7 +3,7 @@ package api
 import (
 	"database/sql"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"gen-ai-proxy/src"
@@ -48,25 +48,25 @@ type UserResponse struct {
 func (s *Service) Register(c echo.Context) error {
 	var req RegisterRequest
 	if err := c.Bind(&req); err != nil {
-		log.Printf("Error binding request: %v", err)
+		slog.WarnContext(c.Request().Context(), "Error binding request", "error", err)
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("Error hashing password: %v", err)
+		slog.ErrorContext(c.Request().Context(), "Error hashing password", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to hash password"})
 	}
 
 	// Check if user already exists
 	_, err = s.db.GetUserByUsername(c.Request().Context(), req.Username)
 	if err == nil {
-		log.Printf("User with username %s already exists", req.Username)
+		slog.InfoContext(c.Request().Context(), "User already exists", "username", req.Username)
 		return c.JSON(http.StatusConflict, ErrorResponse{Error: "User with this username already exists"})
 	} else if errors.Is(err, sql.ErrNoRows) {
 		// User does not exist, proceed to create
 	} else {
-		log.Printf("Error checking for existing user: %v", err)
+		slog.ErrorContext(c.Request().Context(), "Error checking for existing user", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 	}
 
@@ -75,7 +75,7 @@ func (s *Service) Register(c echo.Context) error {
 		PasswordHash: string(hashedPassword),
 	})
 	if err != nil {
-		log.Printf("Error creating user in DB: %v", err)
+		slog.ErrorContext(c.Request().Context(), "Error creating user in DB", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to create user"})
 	}
 
@@ -83,10 +83,12 @@ func (s *Service) Register(c echo.Context) error {
 	if user.ID.Valid {
 		uuidStr = uuid.UUID(user.ID.Bytes).String()
 	} else {
-		log.Printf("User ID is not valid")
+		slog.ErrorContext(c.Request().Context(), "User ID is not valid")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "invalid user ID"})
 	}
 
+	s.recordAudit(c, user.ID, AuditActionCreate, AuditResourceUser, uuidStr, nil, user)
+
 	return c.JSON(http.StatusCreated, UserResponse{
 		ID:       uuidStr,
 		Username: user.Username,
@@ -117,7 +119,7 @@ func (s *Service) Login(c echo.Context) error {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid credentials"})
 		}
-		log.Printf("Error getting user by username during login: %v", err)
+		slog.InfoContext(c.Request().Context(), "Login failed: user lookup", "username", req.Username, "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 	}
 
@@ -127,10 +129,10 @@ func (s *Service) Login(c echo.Context) error {
 
 	token, err := internal.CreateToken(user.ID, []byte(s.cfg.JWTSecret))
 	if err != nil {
-		log.Printf("Error generating token during login: %v", err)
+		slog.ErrorContext(c.Request().Context(), "Error generating token during login", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to generate token"})
 	}
 
-	log.Printf("Login successful for user %s, token generated.", req.Username)
+	slog.InfoContext(c.Request().Context(), "Login successful", "username", req.Username)
 	return c.JSON(http.StatusOK, LoginResponse{AccessToken: token})
 }
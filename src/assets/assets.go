@@ -0,0 +1,77 @@
+// Package assets gives access to the files the binary needs at runtime:
+// migrations, HTML templates, static UI files and the OpenAPI spec. They are
+// embedded at build time so the binary runs from any directory; a directory
+// on disk can override them file by file during development.
+package assets
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Paths of the asset trees, relative to the repository root.
+const (
+	MigrationsDir       = "db/migration"
+	SQLiteMigrationsDir = "db/sqlite/migration"
+	TemplatesDir        = "src/templates"
+	OpenAPISpec         = "docs/swagger.yaml"
+)
+
+// New returns embedded, overlaid by overrideDir when it is set: a file present
+// under overrideDir is served from disk, anything else from the binary.
+// Pointing overrideDir at a checkout lets templates and scripts be edited
+// without rebuilding.
+func New(embedded fs.FS, overrideDir string) fs.FS {
+	if overrideDir == "" {
+		return embedded
+	}
+	return overlay{upper: os.DirFS(overrideDir), lower: embedded}
+}
+
+// overlay serves files from upper and falls back to lower. Directory
+// listings merge both layers.
+type overlay struct {
+	upper, lower fs.FS
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.lower.Open(name)
+}
+
+func (o overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := fs.ReadDir(o.upper, name)
+	if upperErr != nil && !errors.Is(upperErr, fs.ErrNotExist) {
+		return nil, upperErr
+	}
+	lower, lowerErr := fs.ReadDir(o.lower, name)
+	if lowerErr != nil && !errors.Is(lowerErr, fs.ErrNotExist) {
+		return nil, lowerErr
+	}
+	if upperErr != nil && lowerErr != nil {
+		return nil, upperErr
+	}
+
+	entries := map[string]fs.DirEntry{}
+	for _, e := range lower {
+		entries[e.Name()] = e
+	}
+	for _, e := range upper {
+		entries[e.Name()] = e
+	}
+	merged := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		merged = append(merged, e)
+	}
+	slices.SortFunc(merged, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return merged, nil
+}
@@ -0,0 +1,38 @@
+package awssig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "token"}
+	body := []byte(`{"messages":[]}`)
+	var got error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = Verify(r, body, creds, "us-west-2", "bedrock")
+	}))
+	defer server.Close()
+
+	for _, secret := range []string{"secret", "wrong"} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/model/m:0/converse?a=b", strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		signer := creds
+		signer.SecretAccessKey = secret
+		Sign(req, body, signer, "us-west-2", "bedrock", time.Now())
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if (got == nil) != (secret == "secret") {
+			t.Errorf("signed with %q: Verify returned %v", secret, got)
+		}
+	}
+}
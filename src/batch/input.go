@@ -0,0 +1,155 @@
+// Package batch emulates the OpenAI Batch API: the lines of an uploaded JSONL
+// file are stored as batch requests, executed by a pool of workers through
+// the regular proxy endpoints, and their responses written to result files.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Endpoints that batches can target, as in OpenAI batch input files.
+const (
+	EndpointChatCompletions = "/v1/chat/completions"
+	EndpointEmbeddings      = "/v1/embeddings"
+	EndpointResponses       = "/v1/responses"
+)
+
+// CompletionWindow is the only completion window OpenAI accepts; a batch
+// that has not finished by then expires.
+const CompletionWindow = "24h"
+
+// Limits on input files, as enforced by OpenAI.
+const (
+	MaxFileBytes   = 200 << 20
+	MaxRequests    = 50000
+	maxCustomIDLen = 512
+)
+
+// Batch statuses. Failed batches never ran: their input was invalid.
+const (
+	StatusInProgress = "in_progress"
+	StatusCancelling = "cancelling"
+	StatusCompleted  = "completed"
+	StatusCancelled  = "cancelled"
+	StatusExpired    = "expired"
+	StatusFailed     = "failed"
+)
+
+// Batch request statuses.
+const (
+	RequestPending   = "pending"
+	RequestRunning   = "running"
+	RequestSucceeded = "succeeded"
+	RequestFailed    = "failed"
+	RequestCancelled = "cancelled"
+	RequestExpired   = "expired"
+)
+
+// Request is one valid line of an input file.
+type Request struct {
+	// Line is the 1-based line number in the input file.
+	Line     int
+	CustomID string
+	Body     json.RawMessage
+}
+
+// LineError explains why a line of an input file was rejected.
+type LineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+type inputLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// ValidEndpoint reports whether batches can target endpoint.
+func ValidEndpoint(endpoint string) bool {
+	switch endpoint {
+	case EndpointChatCompletions, EndpointEmbeddings, EndpointResponses:
+		return true
+	}
+	return false
+}
+
+// ParseInput reads the requests of an input file for endpoint. A file with
+// any invalid line is rejected as a whole, with an error per invalid line.
+func ParseInput(content []byte, endpoint string) ([]Request, []LineError) {
+	var requests []Request
+	var errs []LineError
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if len(requests)+len(errs) >= MaxRequests {
+			errs = append(errs, LineError{Code: "too_many_requests", Message: fmt.Sprintf("an input file can contain at most %d requests", MaxRequests), Line: line})
+			break
+		}
+
+		var in inputLine
+		if err := json.Unmarshal(raw, &in); err != nil {
+			errs = append(errs, LineError{Code: "invalid_json_line", Message: "line is not a valid JSON object: " + err.Error(), Line: line})
+			continue
+		}
+		switch {
+		case in.CustomID == "":
+			errs = append(errs, LineError{Code: "missing_required_parameter", Message: "custom_id is required", Param: "custom_id", Line: line})
+		case len(in.CustomID) > maxCustomIDLen:
+			errs = append(errs, LineError{Code: "invalid_value", Message: fmt.Sprintf("custom_id must be at most %d characters", maxCustomIDLen), Param: "custom_id", Line: line})
+		case seen[in.CustomID]:
+			errs = append(errs, LineError{Code: "duplicate_custom_id", Message: fmt.Sprintf("custom_id %q is used by another line", in.CustomID), Param: "custom_id", Line: line})
+		case in.Method != "POST":
+			errs = append(errs, LineError{Code: "invalid_value", Message: "method must be POST", Param: "method", Line: line})
+		case in.URL != endpoint:
+			errs = append(errs, LineError{Code: "mismatched_endpoint", Message: fmt.Sprintf("url must be the batch endpoint %s", endpoint), Param: "url", Line: line})
+		default:
+			if err := validateBody(in.Body); err != nil {
+				errs = append(errs, LineError{Code: "invalid_value", Message: err.Error(), Param: "body", Line: line})
+				continue
+			}
+			seen[in.CustomID] = true
+			requests = append(requests, Request{Line: line, CustomID: in.CustomID, Body: in.Body})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, LineError{Code: "invalid_file", Message: err.Error()})
+	}
+	if len(requests) == 0 && len(errs) == 0 {
+		errs = append(errs, LineError{Code: "empty_file", Message: "the input file contains no requests"})
+	}
+	return requests, errs
+}
+
+// validateBody checks the body is an object naming a model. Streaming is
+// refused since the response is written to a file.
+func validateBody(raw json.RawMessage) error {
+	var body struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	if len(raw) == 0 || raw[0] != '{' || json.Unmarshal(raw, &body) != nil {
+		return fmt.Errorf("body must be a JSON object")
+	}
+	if body.Model == "" {
+		return fmt.Errorf("body.model is required")
+	}
+	if body.Stream {
+		return fmt.Errorf("body.stream is not supported in batches")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gen-ai-proxy/src/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PurposeOutput marks the result files written for finished batches.
+const PurposeOutput = "batch_output"
+
+// outputLine is one line of an output or error file, as written by OpenAI.
+type outputLine struct {
+	ID       pgtype.UUID     `json:"id"`
+	CustomID string          `json:"custom_id"`
+	Response *outputResponse `json:"response"`
+	Error    *outputError    `json:"error"`
+}
+
+type outputResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type outputError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// EncodeResults renders the finished requests of a batch in line order:
+// successful responses go to output, everything else to errors.
+func EncodeResults(requests []database.BatchRequest) (output, errors []byte, err error) {
+	var out, errs bytes.Buffer
+	outEnc, errEnc := json.NewEncoder(&out), json.NewEncoder(&errs)
+	for _, r := range requests {
+		line := outputLine{ID: r.ID, CustomID: r.CustomID}
+		enc := errEnc
+		if r.StatusCode.Valid {
+			line.Response = &outputResponse{
+				StatusCode: int(r.StatusCode.Int32),
+				RequestID:  r.RequestID.String,
+				Body:       r.Response,
+			}
+			if r.Status == RequestSucceeded {
+				enc = outEnc
+			}
+		} else {
+			line.Error = requestError(r)
+		}
+		if err := enc.Encode(line); err != nil {
+			return nil, nil, fmt.Errorf("encode line %d: %w", r.Line, err)
+		}
+	}
+	return out.Bytes(), errs.Bytes(), nil
+}
+
+// requestError explains why a request has no response.
+func requestError(r database.BatchRequest) *outputError {
+	switch r.Status {
+	case RequestCancelled:
+		return &outputError{Code: "batch_cancelled", Message: "the batch was cancelled before this request ran"}
+	case RequestExpired:
+		return &outputError{Code: "batch_expired", Message: "this request could not be executed before the completion window expired"}
+	}
+	message := r.Error.String
+	if message == "" {
+		message = "the request failed"
+	}
+	return &outputError{Code: "request_failed", Message: message}
+}
+
+// responseBody stores a response body as JSON, quoting bodies that are not.
+func responseBody(body []byte) []byte {
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
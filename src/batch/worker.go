@@ -0,0 +1,206 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/metrics"
+	"gen-ai-proxy/src/workerpool"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Response is the outcome of one request executed through the proxy.
+type Response struct {
+	StatusCode int
+	RequestID  string
+	Body       []byte
+}
+
+// Executor runs one batch request. An error means the request could not be
+// sent at all and is retried like a 5xx response.
+type Executor func(ctx context.Context, b database.Batch, r database.BatchRequest) (Response, error)
+
+// errAlreadyFinished rolls back a finalization another replica completed first.
+var errAlreadyFinished = errors.New("batch already finished")
+
+// Worker executes pending batch requests and finalizes finished batches.
+// Several replicas can run workers against the same database.
+type Worker struct {
+	db          database.Store
+	exec        Executor
+	workers     int
+	maxAttempts int32
+}
+
+// NewWorker creates a worker running up to workers requests at once, each
+// attempted up to maxAttempts times on errors, 429 and 5xx responses.
+func NewWorker(db database.Store, exec Executor, workers, maxAttempts int) *Worker {
+	if workers <= 0 {
+		workers = 1
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &Worker{db: db, exec: exec, workers: workers, maxAttempts: int32(maxAttempts)}
+}
+
+// Run executes requests and finalizes batches until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	workerpool.Pool{Name: "Batch", Workers: w.workers, RunOnce: w.RunOnce, Maintain: w.Maintain}.Run(ctx)
+}
+
+// RunOnce claims and executes one pending request. It reports false when
+// there was nothing to run.
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	r, err := w.db.ClaimBatchRequest(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claim batch request: %w", err)
+	}
+
+	b, err := w.db.GetBatchByID(ctx, r.BatchID)
+	if err != nil {
+		workerpool.Release(w.db.ReleaseBatchRequest, r.ID, "batch_request_id")
+		return true, fmt.Errorf("load batch: %w", err)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, workerpool.RequestTimeout)
+	resp, execErr := w.exec(execCtx, b, r)
+	cancel()
+	if ctx.Err() != nil {
+		// Shutting down: leave the request to the next worker.
+		workerpool.Release(w.db.ReleaseBatchRequest, r.ID, "batch_request_id")
+		return true, ctx.Err()
+	}
+
+	params := database.FinishBatchRequestParams{
+		ID:            r.ID,
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	result := RequestSucceeded
+	switch {
+	case execErr == nil && resp.StatusCode < 300:
+		params.Status = RequestSucceeded
+	case workerpool.Retry(resp.StatusCode, execErr, r.Attempts, w.maxAttempts):
+		params.Status = RequestPending
+		params.NextAttemptAt.Time = params.NextAttemptAt.Time.Add(workerpool.Backoff(r.Attempts))
+		result = "retried"
+	default:
+		params.Status = RequestFailed
+		result = RequestFailed
+	}
+	if execErr != nil {
+		params.Error = pgtype.Text{String: execErr.Error(), Valid: true}
+	} else if params.Status != RequestPending {
+		params.StatusCode = pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true}
+		params.RequestID = pgtype.Text{String: resp.RequestID, Valid: resp.RequestID != ""}
+		params.Response = responseBody(resp.Body)
+	}
+	metrics.BatchRequestsTotal.WithLabelValues(b.Endpoint, result).Inc()
+
+	if err := w.db.FinishBatchRequest(ctx, params); err != nil {
+		return true, fmt.Errorf("save batch request: %w", err)
+	}
+	return true, execErr
+}
+
+// Maintain requeues requests abandoned by dead replicas, expires requests
+// past their batch's completion window and finalizes finished batches.
+func (w *Worker) Maintain(ctx context.Context) error {
+	if n, err := w.db.RequeueStaleBatchRequests(ctx, workerpool.StaleBefore()); err != nil {
+		return fmt.Errorf("requeue stale batch requests: %w", err)
+	} else if n > 0 {
+		slog.WarnContext(ctx, "Requeued abandoned batch requests", "count", n)
+	}
+	if _, err := w.db.ExpireBatchRequests(ctx); err != nil {
+		return fmt.Errorf("expire batch requests: %w", err)
+	}
+
+	batches, err := w.db.ListFinishedBatches(ctx)
+	if err != nil {
+		return fmt.Errorf("list finished batches: %w", err)
+	}
+	for _, b := range batches {
+		if err := w.finalize(ctx, b); err != nil {
+			return fmt.Errorf("finalize batch %s: %w", uuid.UUID(b.ID.Bytes), err)
+		}
+	}
+	return nil
+}
+
+// finalize writes the result files of a batch whose requests have all
+// finished and sets its final status.
+func (w *Worker) finalize(ctx context.Context, b database.Batch) error {
+	var finished database.Batch
+	err := w.db.ExecTx(ctx, func(q database.Querier) error {
+		requests, err := q.ListBatchRequests(ctx, b.ID)
+		if err != nil {
+			return err
+		}
+		output, errs, err := EncodeResults(requests)
+		if err != nil {
+			return err
+		}
+
+		params := database.FinishBatchParams{ID: b.ID, CurrentStatus: b.Status, Status: StatusCompleted}
+		if params.OutputFileID, err = createFile(ctx, q, b, "output", output); err != nil {
+			return err
+		}
+		if params.ErrorFileID, err = createFile(ctx, q, b, "error", errs); err != nil {
+			return err
+		}
+		if b.Status == StatusCancelling {
+			params.Status = StatusCancelled
+		} else {
+			for _, r := range requests {
+				if r.Status == RequestExpired {
+					params.Status = StatusExpired
+					break
+				}
+			}
+		}
+
+		finished, err = q.FinishBatch(ctx, params)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errAlreadyFinished
+		}
+		return err
+	})
+	if errors.Is(err, errAlreadyFinished) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Batch finished", "batch_id", uuid.UUID(finished.ID.Bytes), "status", finished.Status)
+	return nil
+}
+
+// createFile stores a result file; empty results get no file.
+func createFile(ctx context.Context, q database.Querier, b database.Batch, kind string, content []byte) (pgtype.UUID, error) {
+	if len(content) == 0 {
+		return pgtype.UUID{}, nil
+	}
+	f, err := q.CreateFile(ctx, database.CreateFileParams{
+		ID:       pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:   b.UserID,
+		Filename: fmt.Sprintf("batch_%s_%s.jsonl", uuid.UUID(b.ID.Bytes), kind),
+		Purpose:  PurposeOutput,
+		Bytes:    int64(len(content)),
+	})
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("create %s file: %w", kind, err)
+	}
+	if err := q.CreateFileContent(ctx, database.CreateFileContentParams{FileID: f.ID, Content: content}); err != nil {
+		return pgtype.UUID{}, fmt.Errorf("store %s file: %w", kind, err)
+	}
+	return f.ID, nil
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func apiKeyCreateCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "apikey create", "-username NAME -name KEY_NAME [-allowed-models a,b] [-monthly-budget N]")
+	username := fs.String("username", "", "owner of the key")
+	name := fs.String("name", "", "name of the key")
+	allowedModels := fs.String("allowed-models", "", "comma-separated proxy model IDs the key may use (default: all)")
+	monthlyBudget := fs.Float64("monthly-budget", 0, "spend cap per calendar month (default: unlimited)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username, "name": *name}); err != nil {
+		return err
+	}
+	if *monthlyBudget < 0 {
+		return fmt.Errorf("-monthly-budget must not be negative")
+	}
+
+	params := database.CreateAPIKeyParams{Name: *name}
+	for _, m := range strings.Split(*allowedModels, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			params.AllowedModels = append(params.AllowedModels, m)
+		}
+	}
+	if *monthlyBudget > 0 {
+		budget, err := toNumeric(*monthlyBudget)
+		if err != nil {
+			return fmt.Errorf("invalid -monthly-budget: %w", err)
+		}
+		params.MonthlyBudget = budget
+	}
+
+	apiKeyBytes := make([]byte, 32)
+	if _, err := rand.Read(apiKeyBytes); err != nil {
+		return fmt.Errorf("failed to generate api key: %w", err)
+	}
+	apiKey := hex.EncodeToString(apiKeyBytes)
+	hash := sha256.Sum256([]byte(apiKey))
+	params.KeyHash = hex.EncodeToString(hash[:])
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	if params.UserID, err = lookupUserID(ctx, db, *username); err != nil {
+		return err
+	}
+
+	created, err := db.CreateAPIKey(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	recordAudit(ctx, db, params.UserID, actionCreate, kindAPIKey, created.ID, nil, apiKeySnapshot(created))
+
+	// The key itself is the only thing on stdout so scripts can capture it.
+	fmt.Fprintln(env.Stdout, apiKey)
+	return nil
+}
+
+func apiKeyRevokeCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "apikey revoke", "-username NAME (-name KEY_NAME | -id ID)")
+	username := fs.String("username", "", "owner of the key")
+	name := fs.String("name", "", "name of the key")
+	idStr := fs.String("id", "", "ID of the key")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username}); err != nil {
+		return err
+	}
+	if (*name == "") == (*idStr == "") {
+		fmt.Fprintln(env.Stderr, "exactly one of -name or -id is required")
+		fs.Usage()
+		return errUsage
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	userID, err := lookupUserID(ctx, db, *username)
+	if err != nil {
+		return err
+	}
+
+	var keyID pgtype.UUID
+	if *idStr != "" {
+		parsed, err := uuid.Parse(*idStr)
+		if err != nil {
+			return fmt.Errorf("invalid -id: %w", err)
+		}
+		keyID = pgtype.UUID{Bytes: parsed, Valid: true}
+	} else {
+		keys, err := db.ListAPIKeys(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if k.Name != *name {
+				continue
+			}
+			if keyID.Valid {
+				return fmt.Errorf("several keys are named %q; revoke by -id instead", *name)
+			}
+			keyID = k.ID
+		}
+		if !keyID.Valid {
+			return fmt.Errorf("api key %q not found", *name)
+		}
+	}
+
+	before, err := db.GetAPIKeyByID(ctx, database.GetAPIKeyByIDParams{ID: keyID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("api key not found: %w", err)
+	}
+	if before.Managed {
+		return fmt.Errorf("api key %q is managed by the declarative configuration file; remove it there instead", before.Name)
+	}
+	if err := db.DeleteAPIKey(ctx, database.DeleteAPIKeyParams{ID: keyID, UserID: userID}); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	recordAudit(ctx, db, userID, actionDelete, kindAPIKey, keyID, apiKeySnapshot(before), nil)
+
+	fmt.Fprintf(env.Stdout, "revoked api key %s (%s)\n", before.Name, keyID.String())
+	return nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"gen-ai-proxy/src/database"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Audit log values for changes made from the command line. They match the
+// resource types the HTTP API records.
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+
+	kindAPIKey     = "api_key"
+	kindConnection = "connection"
+	kindModel      = "model"
+	kindProvider   = "provider"
+	kindUser       = "user"
+)
+
+// auditUserAgent marks audit entries written by the CLI rather than an API call.
+const auditUserAgent = "gen-ai-proxy/cli"
+
+// recordAudit appends an entry to the audit trail. Snapshots must not contain
+// secrets. A failure is logged and does not fail the command, because the
+// change has already been made.
+func recordAudit(ctx context.Context, q database.Querier, actorID pgtype.UUID, action, kind string, id pgtype.UUID, before, after any) {
+	params := database.CreateAuditLogParams{
+		ActorID:      actorID,
+		Action:       action,
+		ResourceType: kind,
+		ResourceID:   id.String(),
+		UserAgent:    auditUserAgent,
+	}
+	if before != nil {
+		params.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		params.After, _ = json.Marshal(after)
+	}
+	if _, err := q.CreateAuditLog(ctx, params); err != nil {
+		slog.ErrorContext(ctx, "Error writing audit log", "action", action, "resource_type", kind, "resource_id", id.String(), "error", err)
+	}
+}
+
+// apiKeySnapshot is the audit view of an API key, without its hash.
+func apiKeySnapshot(k database.ApiKey) map[string]any {
+	snapshot := map[string]any{
+		"id":             k.ID.String(),
+		"name":           k.Name,
+		"managed":        k.Managed,
+		"allowed_models": k.AllowedModels,
+	}
+	if k.MonthlyBudget.Valid {
+		snapshot["monthly_budget"] = numericFloat(k.MonthlyBudget)
+	}
+	return snapshot
+}
+
+func toNumeric(f float64) (pgtype.Numeric, error) {
+	var n pgtype.Numeric
+	err := n.Scan(strconv.FormatFloat(f, 'f', -1, 64))
+	return n, err
+}
+
+func numericFloat(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil {
+		return 0
+	}
+	return f.Float64
+}
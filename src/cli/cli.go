@@ -0,0 +1,360 @@
+// Package cli implements the gen-ai-proxy subcommands operators use to script
+// setup and recovery: migrations, users, API keys, declarative configuration,
+// log export and replay fixtures, encryption key rotation and usage reports.
+// Commands talk to the database through the same query layer as the HTTP API.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+
+	"gen-ai-proxy/src/assets"
+	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/database/sqlite"
+	"gen-ai-proxy/src/logging"
+	"github.com/joho/godotenv"
+)
+
+// Env is what every command runs with: the loaded configuration, a lazily
+// opened database connection and the process streams.
+type Env struct {
+	Config *config.Config
+	// Assets holds migrations, templates and static files; see package assets.
+	Assets fs.FS
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	store database.Store
+	close func()
+	// dotenv holds the variables taken from .env rather than the process
+	// environment; only those are updated by ReloadConfig.
+	dotenv map[string]bool
+}
+
+// Store connects to the database selected by DB_DRIVER on first use.
+func (e *Env) Store() (database.Store, error) {
+	if e.store != nil {
+		return e.store, nil
+	}
+	switch e.Config.DBDriver {
+	case "sqlite":
+		db, err := sqlite.Open(e.Config.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		e.store, e.close = sqlite.NewStore(db), func() { db.Close() }
+	default:
+		pool, err := database.Connect(e.Config)
+		if err != nil {
+			return nil, err
+		}
+		e.store, e.close = database.NewStore(pool), pool.Close
+	}
+	return e.store, nil
+}
+
+// Close releases the database connection, if one was opened.
+func (e *Env) Close() {
+	if e.close != nil {
+		e.close()
+	}
+}
+
+// ReloadConfig reads .env again and loads a fresh configuration. Variables
+// set in the process environment still take precedence over the file.
+func (e *Env) ReloadConfig() (*config.Config, error) {
+	values, err := godotenv.Read()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read .env: %w", err)
+	}
+	for key := range e.dotenv {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(e.dotenv, key)
+		}
+	}
+	for key, val := range values {
+		if _, inProcess := os.LookupEnv(key); inProcess && !e.dotenv[key] {
+			continue
+		}
+		os.Setenv(key, val)
+		e.dotenv[key] = true
+	}
+
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// dotenvKeys returns the variables .env would add to the process environment.
+func dotenvKeys() map[string]bool {
+	keys := map[string]bool{}
+	values, _ := godotenv.Read()
+	for key := range values {
+		if _, ok := os.LookupEnv(key); !ok {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// RunFunc runs a command with its remaining arguments.
+type RunFunc func(ctx context.Context, env *Env, args []string) error
+
+type command struct {
+	path    string
+	summary string
+	run     RunFunc
+}
+
+// errUsage reports invalid arguments; the command has already printed why.
+var errUsage = errors.New("invalid usage")
+
+func commands(serve RunFunc) []command {
+	return []command{
+		{"serve", "Run migrations and start the HTTP server (default)", serve},
+		{"migrate up", "Apply all pending migrations", migrateUpCmd},
+		{"migrate down", "Roll back migrations", migrateDownCmd},
+		{"migrate status", "Show the current migration version", migrateStatusCmd},
+		{"user create", "Create a user", userCreateCmd},
+		{"user reset-password", "Set a new password for a user", userResetPasswordCmd},
+		{"apikey create", "Create a proxy API key and print it", apiKeyCreateCmd},
+		{"apikey revoke", "Delete a proxy API key", apiKeyRevokeCmd},
+		{"provider export", "Write a user's providers as YAML", providerExportCmd},
+		{"provider import", "Create or update providers from YAML", providerImportCmd},
+		{"connection export", "Write a user's connections as YAML, without secrets", connectionExportCmd},
+		{"connection import", "Create or update connections from YAML", connectionImportCmd},
+		{"model export", "Write a user's models as YAML", modelExportCmd},
+		{"model import", "Create or update models from YAML", modelImportCmd},
+		{"logs export", "Write conversation logs as JSON lines", logsExportCmd},
+		{"logs reindex-search", "Recompute the search vectors of stored conversation logs", logsReindexSearchCmd},
+		{"recording from-logs", "Write conversation logs as replay fixtures", recordingFromLogsCmd},
+		{"rotate-encryption-key", "Re-encrypt connection secrets with a new ENCRYPTION_KEY", rotateEncryptionKeyCmd},
+		{"usage report", "Print token usage and cost per model", usageReportCmd},
+	}
+}
+
+// Run executes the command named by args and returns the process exit code.
+// Without arguments it serves, so existing deployments keep working. embedded
+// holds the files compiled into the binary.
+func Run(args []string, embedded fs.FS, serve RunFunc) int {
+	cmds := commands(serve)
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && !isHelpFlag(args[0]) {
+		args = append([]string{"serve"}, args...)
+	}
+	if args[0] == "help" || isHelpFlag(args[0]) {
+		printUsage(os.Stdout, cmds, "")
+		return 0
+	}
+
+	cmd, rest, ok := lookup(cmds, args)
+	if !ok {
+		if !isGroup(cmds, args[0]) {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		}
+		printUsage(os.Stderr, cmds, args[0])
+		return 2
+	}
+
+	// Flag help needs no configuration or database.
+	if slices.ContainsFunc(rest, isHelpFlag) {
+		env := &Env{Config: &config.Config{}, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+		if err := cmd.run(context.Background(), env, rest); err != nil && !errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		return 0
+	}
+
+	dotenv := dotenvKeys()
+	dotenvErr := godotenv.Load()
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "could not load config:", err)
+		return 1
+	}
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		fmt.Fprintln(os.Stderr, "could not configure logging:", err)
+		return 1
+	}
+	if dotenvErr != nil {
+		slog.Debug("No .env file found, relying on environment variables")
+	}
+
+	env := &Env{
+		Config: &cfg,
+		Assets: assets.New(embedded, cfg.AssetsDir),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		dotenv: dotenv,
+	}
+	defer env.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, env, rest); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.path, err)
+		return 1
+	}
+	return 0
+}
+
+// lookup matches the longest command path that prefixes args.
+func lookup(cmds []command, args []string) (command, []string, bool) {
+	var best command
+	bestLen := 0
+	for _, c := range cmds {
+		words := strings.Fields(c.path)
+		if len(words) > len(args) || len(words) <= bestLen {
+			continue
+		}
+		match := true
+		for i, w := range words {
+			if args[i] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			best, bestLen = c, len(words)
+		}
+	}
+	return best, args[bestLen:], bestLen > 0
+}
+
+func isGroup(cmds []command, name string) bool {
+	for _, c := range cmds {
+		if strings.HasPrefix(c.path, name+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func printUsage(w io.Writer, cmds []command, prefix string) {
+	fmt.Fprintln(w, "Usage: gen-ai-proxy <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	sorted := make([]command, 0, len(cmds))
+	for _, c := range cmds {
+		if prefix == "" || strings.HasPrefix(c.path, prefix+" ") {
+			sorted = append(sorted, c)
+		}
+	}
+	if len(sorted) == 0 {
+		sorted = cmds
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].path < sorted[j].path })
+	for _, c := range sorted {
+		fmt.Fprintf(w, "  %-24s %s\n", c.path, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gen-ai-proxy <command> -h' for the flags of a command.")
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting.
+func newFlagSet(env *Env, path, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.Stderr, "Usage: gen-ai-proxy %s %s\n", path, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and rejects stray positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// required prints the usage when a mandatory flag is empty.
+func required(fs *flag.FlagSet, values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if values[name] == "" {
+			fmt.Fprintf(fs.Output(), "-%s is required\n", name)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+// readSecret takes a secret from the named environment variable, or else
+// from the first line of stdin, so it never appears in the process list.
+func readSecret(env *Env, envName, what string) (string, error) {
+	if envName != "" {
+		val := os.Getenv(envName)
+		if val == "" {
+			return "", fmt.Errorf("environment variable %s is not set", envName)
+		}
+		return val, nil
+	}
+
+	line, err := readLine(env.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from stdin: %w", what, err)
+	}
+	if line == "" {
+		return "", fmt.Errorf("empty %s on stdin", what)
+	}
+	return line, nil
+}
+
+func readLine(r io.Reader) (string, error) {
+	var b strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			b.WriteByte(buf[0])
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(b.String(), "\r"), nil
+}
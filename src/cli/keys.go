@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/encryption"
+)
+
+var errRotateDryRun = errors.New("dry run")
+
+// rotateEncryptionKeyCmd re-encrypts every connection secret, including those
+// of deleted connections, with a new key in one transaction. The old key is
+// the configured ENCRYPTION_KEY; after the command succeeds, deploy the new
+// key as ENCRYPTION_KEY before restarting the server.
+func rotateEncryptionKeyCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "rotate-encryption-key", "-new-key-env VAR [-dry-run]")
+	newKeyEnv := fs.String("new-key-env", "", "environment variable holding the new base64 encoded 32-byte key")
+	dryRun := fs.Bool("dry-run", false, "check every secret decrypts without writing anything")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"new-key-env": *newKeyEnv}); err != nil {
+		return err
+	}
+
+	oldKey, err := decodeEncryptionKey("ENCRYPTION_KEY", env.Config.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	newKey, err := decodeEncryptionKey(*newKeyEnv, os.Getenv(*newKeyEnv))
+	if err != nil {
+		return err
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+
+	rotated := 0
+	err = db.ExecTx(ctx, func(q database.Querier) error {
+		connections, err := q.ListAllConnections(ctx)
+		if err != nil {
+			return err
+		}
+		for _, c := range connections {
+			plaintext, err := encryption.Decrypt(oldKey, c.EncryptedApiKey)
+			if err != nil {
+				return fmt.Errorf("connection %q (%s) does not decrypt with the current key: %w", c.Name, c.ID.String(), err)
+			}
+			encrypted, err := encryption.Encrypt(newKey, plaintext)
+			if err != nil {
+				return fmt.Errorf("connection %q (%s): %w", c.Name, c.ID.String(), err)
+			}
+			if _, err := q.UpdateConnection(ctx, database.UpdateConnectionParams{
+				ID:              c.ID,
+				UserID:          c.UserID,
+				ProviderID:      c.ProviderID,
+				EncryptedApiKey: encrypted,
+			}); err != nil {
+				return fmt.Errorf("connection %q (%s): %w", c.Name, c.ID.String(), err)
+			}
+			rotated++
+		}
+		if *dryRun {
+			return errRotateDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errRotateDryRun) {
+		fmt.Fprintf(env.Stdout, "%d connection secrets would be re-encrypted\n", rotated)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "re-encrypted %d connection secrets; set ENCRYPTION_KEY to the new key and restart the server\n", rotated)
+	return nil
+}
+
+func decodeEncryptionKey(name, value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("%s is not set", name)
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s must be 32 bytes long after base64 decoding", name)
+	}
+	return key, nil
+}
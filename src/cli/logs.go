@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gen-ai-proxy/src/database"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// logsExportPageSize is how many logs each query of logs export fetches.
+const logsExportPageSize = 500
+
+// logRecord is one line of logs export output.
+type logRecord struct {
+	ID               string          `json:"id"`
+	CreatedAt        time.Time       `json:"created_at"`
+	Model            string          `json:"model"`
+	Type             string          `json:"type"`
+	RequestID        string          `json:"request_id,omitempty"`
+	StatusCode       *int32          `json:"status_code,omitempty"`
+	PromptTokens     int64           `json:"prompt_tokens"`
+	CompletionTokens int64           `json:"completion_tokens"`
+	Cost             float64         `json:"cost"`
+	Request          json.RawMessage `json:"request,omitempty"`
+	Response         json.RawMessage `json:"response,omitempty"`
+}
+
+func logsExportCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "logs export", "-username NAME [-since T] [-until T] [-model ID] [-o FILE]")
+	username := fs.String("username", "", "owner of the logs")
+	since := fs.String("since", "", "only logs at or after this time (RFC3339 or YYYY-MM-DD)")
+	until := fs.String("until", "", "only logs before this time (RFC3339 or YYYY-MM-DD)")
+	model := fs.String("model", "", "only logs of this proxy model ID")
+	output := fs.String("o", "", "write to FILE instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username}); err != nil {
+		return err
+	}
+
+	params := database.ListLogsParams{Limit: logsExportPageSize}
+	var err error
+	if params.Since, err = parseTimeFlag("since", *since); err != nil {
+		return err
+	}
+	if params.Until, err = parseTimeFlag("until", *until); err != nil {
+		return err
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	if params.UserID, err = lookupUserID(ctx, db, *username); err != nil {
+		return err
+	}
+	models, err := db.ListModels(ctx, params.UserID)
+	if err != nil {
+		return err
+	}
+	modelNames := map[string]string{}
+	for _, m := range models {
+		modelNames[m.ID.String()] = m.ProxyModelID
+		if m.ProxyModelID == *model {
+			params.ModelID = m.ID
+		}
+	}
+	if *model != "" && !params.ModelID.Valid {
+		return fmt.Errorf("model %q not found", *model)
+	}
+
+	var out io.Writer = env.Stdout
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+
+	count := 0
+	for {
+		logs, err := db.ListLogs(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, l := range logs {
+			if err := enc.Encode(newLogRecord(l, modelNames)); err != nil {
+				return err
+			}
+		}
+		count += len(logs)
+		if len(logs) < logsExportPageSize {
+			break
+		}
+		last := logs[len(logs)-1]
+		params.CursorCreatedAt, params.CursorID = last.CreatedAt, last.ID
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stderr, "exported %d logs\n", count)
+	return nil
+}
+
+func newLogRecord(l database.ListLogsRow, modelNames map[string]string) logRecord {
+	rec := logRecord{
+		ID:               l.ID.String(),
+		CreatedAt:        l.CreatedAt.Time,
+		Model:            modelNames[l.ModelID.String()],
+		Type:             l.Type,
+		RequestID:        l.RequestID.String,
+		PromptTokens:     l.PromptTokens.Int64,
+		CompletionTokens: l.CompletionTokens.Int64,
+		Cost:             numericFloat(l.Cost),
+		Request:          rawJSON(l.RequestPayload),
+		Response:         rawJSON(l.ResponsePayload),
+	}
+	if l.StatusCode.Valid {
+		rec.StatusCode = &l.StatusCode.Int32
+	}
+	return rec
+}
+
+// logsReindexSearchCmd rewrites every log in batches so its search vector
+// matches the current log_search_vector, after an upgrade taught search a new
+// payload format. Each batch is its own statement, so the command can be
+// interrupted and run again.
+func logsReindexSearchCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "logs reindex-search", "[-batch-size N]")
+	batchSize := fs.Int("batch-size", 1000, "logs rewritten per statement")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *batchSize <= 0 {
+		fmt.Fprintln(env.Stderr, "-batch-size must be positive")
+		fs.Usage()
+		return errUsage
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	params := database.ReindexLogSearchParams{After: pgtype.UUID{Valid: true}, BatchSize: int32(*batchSize)}
+	count := 0
+	for {
+		ids, err := db.ReindexLogSearch(ctx, params)
+		if err != nil {
+			return err
+		}
+		count += len(ids)
+		if len(ids) < *batchSize {
+			break
+		}
+		// RETURNING does not keep the order of the batch.
+		for _, id := range ids {
+			if bytes.Compare(id.Bytes[:], params.After.Bytes[:]) > 0 {
+				params.After = id
+			}
+		}
+	}
+	fmt.Fprintf(env.Stderr, "reindexed %d logs\n", count)
+	return nil
+}
+
+// rawJSON embeds a stored payload as-is, or as a string when it is not JSON
+// (a streamed response, for instance).
+func rawJSON(payload []byte) json.RawMessage {
+	if len(payload) == 0 {
+		return nil
+	}
+	if json.Valid(payload) {
+		return payload
+	}
+	quoted, _ := json.Marshal(string(payload))
+	return quoted
+}
+
+// parseTimeFlag accepts an RFC3339 timestamp or a date, read as midnight UTC.
+func parseTimeFlag(name, value string) (pgtype.Timestamptz, error) {
+	if value == "" {
+		return pgtype.Timestamptz{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return pgtype.Timestamptz{Time: t, Valid: true}, nil
+		}
+	}
+	return pgtype.Timestamptz{}, fmt.Errorf("invalid -%s %q: expected RFC3339 or YYYY-MM-DD", name, value)
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+
+	"gen-ai-proxy/src/assets"
+	"gen-ai-proxy/src/config"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationSource picks the migrations and database URL for DB_DRIVER.
+func migrationSource(cfg *config.Config) (dir, databaseURL string) {
+	if cfg.DBDriver == "sqlite" {
+		return assets.SQLiteMigrationsDir, "sqlite://" + cfg.SQLitePath
+	}
+	return assets.MigrationsDir, fmt.Sprintf("pgx5://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
+}
+
+func openMigrations(cfg *config.Config, fsys fs.FS) (source.Driver, error) {
+	dir, _ := migrationSource(cfg)
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not open migrations: %w", err)
+	}
+	return src, nil
+}
+
+func newMigrate(cfg *config.Config, fsys fs.FS) (*migrate.Migrate, error) {
+	src, err := openMigrations(cfg, fsys)
+	if err != nil {
+		return nil, err
+	}
+	_, databaseURL := migrationSource(cfg)
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not create migrate instance: %w", err)
+	}
+	return m, nil
+}
+
+// MigrateUp applies all pending migrations found in fsys.
+func MigrateUp(cfg *config.Config, fsys fs.FS) error {
+	m, err := newMigrate(cfg, fsys)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not run migrations: %w", err)
+	}
+	slog.Info("Database migrations applied successfully")
+	return nil
+}
+
+// MigrationChecker reports whether the database schema is at the latest
+// migration shipped with the binary. It keeps one connection open, so
+// readiness probes do not reconnect every time.
+type MigrationChecker struct {
+	mu     sync.Mutex
+	m      *migrate.Migrate
+	latest uint
+}
+
+func NewMigrationChecker(cfg *config.Config, fsys fs.FS) (*MigrationChecker, error) {
+	latest, err := latestMigration(cfg, fsys)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMigrate(cfg, fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationChecker{m: m, latest: latest}, nil
+}
+
+// Check fails when migrations are pending or the last one failed.
+func (c *MigrationChecker) Check(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	version, dirty, err := c.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return errors.New("no migrations applied")
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed", version)
+	}
+	if version < c.latest {
+		return fmt.Errorf("schema at version %d, expected %d", version, c.latest)
+	}
+	return nil
+}
+
+func (c *MigrationChecker) Close() error {
+	sourceErr, dbErr := c.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+func latestMigration(cfg *config.Config, fsys fs.FS) (uint, error) {
+	src, err := openMigrations(cfg, fsys)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("could not read migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("could not read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+func migrateUpCmd(_ context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "migrate up", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return MigrateUp(env.Config, env.Assets)
+}
+
+func migrateDownCmd(_ context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "migrate down", "-steps N | -all")
+	steps := fs.Int("steps", 0, "number of migrations to roll back")
+	all := fs.Bool("all", false, "roll back every migration, dropping all data")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if (*steps > 0) == *all {
+		fmt.Fprintln(env.Stderr, "exactly one of -steps or -all is required")
+		fs.Usage()
+		return errUsage
+	}
+
+	m, err := newMigrate(env.Config, env.Assets)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if *all {
+		err = m.Down()
+	} else {
+		err = m.Steps(-*steps)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not roll back migrations: %w", err)
+	}
+	return printMigrationVersion(env, m)
+}
+
+func migrateStatusCmd(_ context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "migrate status", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	m, err := newMigrate(env.Config, env.Assets)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return printMigrationVersion(env, m)
+}
+
+func printMigrationVersion(env *Env, m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintln(env.Stdout, "version: none")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read migration version: %w", err)
+	}
+	fmt.Fprintf(env.Stdout, "version: %d\n", version)
+	if dirty {
+		fmt.Fprintln(env.Stdout, "dirty: true (a migration failed; fix the schema and force the version)")
+	}
+	return nil
+}
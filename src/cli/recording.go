@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/recording"
+	"gen-ai-proxy/src/redaction"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// recordingFromLogsCmd turns conversation logs into replay fixtures, so a
+// reported request can be reproduced with UPSTREAM_MODE=replay. Logs hold
+// the upstream request and response but not the path or the stream timing,
+// so the fixtures match any path and answer streams at once.
+func recordingFromLogsCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "recording from-logs", "-username NAME -ids ID[,ID...] [-dir DIR]")
+	username := fs.String("username", "", "owner of the logs")
+	ids := fs.String("ids", "", "comma-separated conversation log IDs")
+	dir := fs.String("dir", env.Config.RecordingsDir, "fixture directory (default RECORDINGS_DIR)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username, "ids": *ids}); err != nil {
+		return err
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	userID, err := lookupUserID(ctx, db, *username)
+	if err != nil {
+		return err
+	}
+
+	for _, idStr := range strings.Split(*ids, ",") {
+		parsed, err := uuid.Parse(strings.TrimSpace(idStr))
+		if err != nil {
+			return fmt.Errorf("invalid log ID %q: %w", idStr, err)
+		}
+		l, err := db.GetLog(ctx, database.GetLogParams{ID: pgtype.UUID{Bytes: parsed, Valid: true}, UserID: userID})
+		if err != nil {
+			return fmt.Errorf("log %s not found: %w", idStr, err)
+		}
+		if metadataOnly(l.RequestPayload) {
+			return fmt.Errorf("log %s has no payloads (log policy metadata)", idStr)
+		}
+		host, err := logUpstreamHost(ctx, db, userID, l.ConnectionID)
+		if err != nil {
+			return fmt.Errorf("log %s: %w", idStr, err)
+		}
+
+		exchange := &recording.Exchange{
+			Method:      http.MethodPost,
+			Request:     recording.RequestJSON(l.RequestPayload),
+			Status:      http.StatusOK,
+			ContentType: payloadContentType(l.ResponsePayload),
+		}
+		if l.StatusCode.Valid {
+			exchange.Status = int(l.StatusCode.Int32)
+		}
+		exchange.SetBody(l.ResponsePayload)
+		if err := recording.Append(*dir, host, exchange); err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Stdout, "%s -> %s\n", idStr, recording.FileName(host))
+	}
+	return nil
+}
+
+// logUpstreamHost is the host of the provider a log's connection calls.
+func logUpstreamHost(ctx context.Context, db database.Store, userID pgtype.UUID, connectionID pgtype.UUID) (string, error) {
+	connection, err := db.GetConnection(ctx, database.GetConnectionParams{ID: connectionID, UserID: userID})
+	if err != nil {
+		return "", fmt.Errorf("connection not found: %w", err)
+	}
+	providerID, err := uuid.Parse(connection.ProviderID)
+	if err != nil {
+		return "", fmt.Errorf("invalid provider ID: %w", err)
+	}
+	provider, err := db.GetProvider(ctx, database.GetProviderParams{ID: pgtype.UUID{Bytes: providerID, Valid: true}, UserID: userID})
+	if err != nil {
+		return "", fmt.Errorf("provider not found: %w", err)
+	}
+	u, err := url.Parse(provider.BaseUrl)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("provider %q has no usable base_url", provider.Name)
+	}
+	return u.Host, nil
+}
+
+func metadataOnly(payload []byte) bool {
+	var summary struct {
+		LogPolicy string `json:"log_policy"`
+	}
+	return json.Unmarshal(payload, &summary) == nil && summary.LogPolicy == string(redaction.PolicyMetadata)
+}
+
+// payloadContentType guesses the content type of a logged response: JSON,
+// server-sent events, or JSON lines as Ollama streams them.
+func payloadContentType(payload []byte) string {
+	switch {
+	case json.Valid(payload):
+		return "application/json"
+	case bytes.HasPrefix(bytes.TrimSpace(payload), []byte("data:")):
+		return "text/event-stream"
+	default:
+		return "application/x-ndjson"
+	}
+}
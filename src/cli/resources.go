@@ -0,0 +1,621 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/declarative"
+	"gen-ai-proxy/src/encryption"
+	"gen-ai-proxy/src/mockprovider"
+	"gen-ai-proxy/src/parampolicy"
+	"gen-ai-proxy/src/redaction"
+	"gen-ai-proxy/src/shadow"
+	"gen-ai-proxy/src/structuredoutput"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gopkg.in/yaml.v3"
+)
+
+// resourceFile is what provider, connection and model export write and import
+// reads. Its sections use the declarative configuration schema, so exports can
+// be pasted into a RESOURCES_FILE.
+type resourceFile struct {
+	Providers   []declarative.Provider   `yaml:"providers,omitempty"`
+	Connections []declarative.Connection `yaml:"connections,omitempty"`
+	Models      []declarative.Model      `yaml:"models,omitempty"`
+}
+
+var errImportDryRun = errors.New("dry run")
+
+// importer upserts resources by name for one user inside a transaction.
+// Managed resources belong to the declarative configuration and are refused.
+type importer struct {
+	q      database.Querier
+	env    *Env
+	userID pgtype.UUID
+}
+
+func (im *importer) report(symbol, kind, name string) {
+	fmt.Fprintf(im.env.Stdout, "%s %s %s\n", symbol, kind, name)
+}
+
+func providerExportCmd(ctx context.Context, env *Env, args []string) error {
+	return exportResources(ctx, env, args, "provider", func(ctx context.Context, q database.Querier, userID pgtype.UUID) (resourceFile, error) {
+		providers, err := q.ListProviders(ctx, userID)
+		if err != nil {
+			return resourceFile{}, err
+		}
+		var f resourceFile
+		for _, p := range providers {
+			f.Providers = append(f.Providers, declarative.Provider{Name: p.Name, Type: p.Type, BaseURL: p.BaseUrl, APIVersion: p.ApiVersion, Region: p.Region})
+		}
+		return f, nil
+	})
+}
+
+func connectionExportCmd(ctx context.Context, env *Env, args []string) error {
+	return exportResources(ctx, env, args, "connection", func(ctx context.Context, q database.Querier, userID pgtype.UUID) (resourceFile, error) {
+		providerNames, err := providerNamesByID(ctx, q, userID)
+		if err != nil {
+			return resourceFile{}, err
+		}
+		connections, err := q.ListConnections(ctx, userID)
+		if err != nil {
+			return resourceFile{}, err
+		}
+		var f resourceFile
+		for _, c := range connections {
+			f.Connections = append(f.Connections, declarative.Connection{
+				Name:     c.Name,
+				Provider: providerNames[c.ProviderID],
+				// Secrets are never exported; the importer supplies them.
+				APIKey: declarative.Secret{Env: secretEnvName(c.Name)},
+			})
+		}
+		return f, nil
+	})
+}
+
+func modelExportCmd(ctx context.Context, env *Env, args []string) error {
+	return exportResources(ctx, env, args, "model", func(ctx context.Context, q database.Querier, userID pgtype.UUID) (resourceFile, error) {
+		connections, err := q.ListConnections(ctx, userID)
+		if err != nil {
+			return resourceFile{}, err
+		}
+		connectionNames := map[string]string{}
+		for _, c := range connections {
+			connectionNames[c.ID.String()] = c.Name
+		}
+		templates, err := q.ListPromptTemplates(ctx, userID)
+		if err != nil {
+			return resourceFile{}, err
+		}
+		templateNames := map[string]string{}
+		for _, t := range templates {
+			templateNames[t.ID.String()] = t.Name
+		}
+		models, err := q.ListModels(ctx, userID)
+		if err != nil {
+			return resourceFile{}, err
+		}
+		var f resourceFile
+		for _, m := range models {
+			f.Models = append(f.Models, declarative.Model{
+				ProxyModelID:     m.ProxyModelID,
+				Connection:       connectionNames[m.ConnectionID.String()],
+				ProviderModelID:  m.ProviderModelID,
+				Type:             m.Type,
+				PriceInput:       numericFloat(m.PriceInput),
+				PriceOutput:      numericFloat(m.PriceOutput),
+				Thinking:         m.Thinking,
+				ToolsUsage:       m.ToolsUsage,
+				LogPolicy:        m.LogPolicy,
+				ParamPolicy:      parampolicy.Decode(m.ParamPolicy),
+				PromptTemplate:   templateNames[m.PromptTemplateID.String()],
+				StructuredOutput: structuredoutput.Decode(m.StructuredOutput),
+				Shadow: declarative.ShadowOf(shadow.Decode(m.Shadow), func(id string) string {
+					return connectionNames[id]
+				}),
+			})
+		}
+		return f, nil
+	})
+}
+
+func exportResources(ctx context.Context, env *Env, args []string, kind string, collect func(context.Context, database.Querier, pgtype.UUID) (resourceFile, error)) error {
+	fs := newFlagSet(env, kind+" export", "-username NAME [-o FILE]")
+	username := fs.String("username", "", "owner of the resources")
+	output := fs.String("o", "", "write to FILE instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username}); err != nil {
+		return err
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	userID, err := lookupUserID(ctx, db, *username)
+	if err != nil {
+		return err
+	}
+	f, err := collect(ctx, db, userID)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return writeOutput(env, *output, buf.Bytes())
+}
+
+func providerImportCmd(ctx context.Context, env *Env, args []string) error {
+	return importResources(ctx, env, args, "provider", func(ctx context.Context, im *importer, f *resourceFile) error {
+		if len(f.Providers) == 0 {
+			return errors.New("the file has no providers section")
+		}
+		existing, err := im.q.ListProviders(ctx, im.userID)
+		if err != nil {
+			return err
+		}
+		byName := map[string]database.Provider{}
+		for _, p := range existing {
+			byName[p.Name] = p
+		}
+
+		for _, spec := range f.Providers {
+			if spec.Name == "" || spec.Type == "" || spec.BaseURL == "" {
+				return fmt.Errorf("provider %q: name, type and base_url are required", spec.Name)
+			}
+			if spec.Type == "azure-openai" && spec.APIVersion == "" {
+				return fmt.Errorf("provider %q: api_version is required for azure-openai", spec.Name)
+			}
+			if spec.Type == "bedrock" && spec.Region == "" {
+				return fmt.Errorf("provider %q: region is required for bedrock", spec.Name)
+			}
+			if spec.Type == "mock" {
+				if err := mockprovider.ValidateURL(spec.BaseURL); err != nil {
+					return fmt.Errorf("provider %q: %w", spec.Name, err)
+				}
+			}
+			current, ok := byName[spec.Name]
+			if !ok {
+				created, err := im.q.CreateProvider(ctx, database.CreateProviderParams{
+					ID:         pgtype.UUID{Bytes: uuid.New(), Valid: true},
+					UserID:     im.userID,
+					Name:       spec.Name,
+					BaseUrl:    spec.BaseURL,
+					Type:       spec.Type,
+					ApiVersion: spec.APIVersion,
+					Region:     spec.Region,
+				})
+				if err != nil {
+					return fmt.Errorf("provider %q: %w", spec.Name, err)
+				}
+				recordAudit(ctx, im.q, im.userID, actionCreate, kindProvider, created.ID, nil, spec)
+				im.report("+", kindProvider, spec.Name)
+				continue
+			}
+			if current.Managed {
+				return fmt.Errorf("provider %q is managed by the declarative configuration file", spec.Name)
+			}
+			if current.Type == spec.Type && current.BaseUrl == spec.BaseURL && current.ApiVersion == spec.APIVersion && current.Region == spec.Region {
+				continue
+			}
+			if _, err := im.q.UpdateProvider(ctx, database.UpdateProviderParams{
+				ID:         current.ID,
+				UserID:     im.userID,
+				Name:       spec.Name,
+				BaseUrl:    spec.BaseURL,
+				Type:       spec.Type,
+				ApiVersion: spec.APIVersion,
+				Region:     spec.Region,
+			}); err != nil {
+				return fmt.Errorf("provider %q: %w", spec.Name, err)
+			}
+			before := declarative.Provider{Name: current.Name, Type: current.Type, BaseURL: current.BaseUrl, APIVersion: current.ApiVersion, Region: current.Region}
+			recordAudit(ctx, im.q, im.userID, actionUpdate, kindProvider, current.ID, before, spec)
+			im.report("~", kindProvider, spec.Name)
+		}
+		return nil
+	})
+}
+
+func connectionImportCmd(ctx context.Context, env *Env, args []string) error {
+	return importResources(ctx, env, args, "connection", func(ctx context.Context, im *importer, f *resourceFile) error {
+		if len(f.Connections) == 0 {
+			return errors.New("the file has no connections section")
+		}
+		key, err := decodeEncryptionKey("ENCRYPTION_KEY", env.Config.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		providers, err := im.q.ListProviders(ctx, im.userID)
+		if err != nil {
+			return err
+		}
+		providerIDs := map[string]string{}
+		for _, p := range providers {
+			providerIDs[p.Name] = p.ID.String()
+		}
+		existing, err := im.q.ListConnections(ctx, im.userID)
+		if err != nil {
+			return err
+		}
+		byName := map[string]database.ListConnectionsRow{}
+		for _, c := range existing {
+			byName[c.Name] = c
+		}
+
+		for _, spec := range f.Connections {
+			if spec.Name == "" {
+				return errors.New("connection name is required")
+			}
+			providerID, ok := providerIDs[spec.Provider]
+			if !ok {
+				return fmt.Errorf("connection %q: unknown provider %q", spec.Name, spec.Provider)
+			}
+			apiKey, err := spec.APIKey.Resolve()
+			if err != nil {
+				return fmt.Errorf("connection %q: api_key: %w", spec.Name, err)
+			}
+
+			current, ok := byName[spec.Name]
+			if !ok {
+				encrypted, err := encryption.Encrypt(key, []byte(apiKey))
+				if err != nil {
+					return fmt.Errorf("connection %q: %w", spec.Name, err)
+				}
+				created, err := im.q.CreateConnection(ctx, database.CreateConnectionParams{
+					UserID:          im.userID,
+					ProviderID:      providerID,
+					EncryptedApiKey: encrypted,
+					Name:            spec.Name,
+				})
+				if err != nil {
+					return fmt.Errorf("connection %q: %w", spec.Name, err)
+				}
+				recordAudit(ctx, im.q, im.userID, actionCreate, kindConnection, created.ID, nil, spec)
+				im.report("+", kindConnection, spec.Name)
+				continue
+			}
+			if current.Managed {
+				return fmt.Errorf("connection %q is managed by the declarative configuration file", spec.Name)
+			}
+
+			stored, err := im.q.GetConnection(ctx, database.GetConnectionParams{ID: current.ID, UserID: im.userID})
+			if err != nil {
+				return fmt.Errorf("connection %q: %w", spec.Name, err)
+			}
+			plaintext, err := encryption.Decrypt(key, stored.EncryptedApiKey)
+			sameKey := err == nil && string(plaintext) == apiKey
+			if current.ProviderID == providerID && sameKey {
+				continue
+			}
+			encrypted := stored.EncryptedApiKey
+			if !sameKey {
+				if encrypted, err = encryption.Encrypt(key, []byte(apiKey)); err != nil {
+					return fmt.Errorf("connection %q: %w", spec.Name, err)
+				}
+			}
+			if _, err := im.q.UpdateConnection(ctx, database.UpdateConnectionParams{
+				ID:              current.ID,
+				UserID:          im.userID,
+				ProviderID:      providerID,
+				EncryptedApiKey: encrypted,
+			}); err != nil {
+				return fmt.Errorf("connection %q: %w", spec.Name, err)
+			}
+			before := declarative.Connection{Name: current.Name, Provider: spec.Provider}
+			if current.ProviderID != providerID {
+				before.Provider = current.ProviderID
+			}
+			recordAudit(ctx, im.q, im.userID, actionUpdate, kindConnection, current.ID, before, spec)
+			im.report("~", kindConnection, spec.Name)
+		}
+		return nil
+	})
+}
+
+func modelImportCmd(ctx context.Context, env *Env, args []string) error {
+	return importResources(ctx, env, args, "model", func(ctx context.Context, im *importer, f *resourceFile) error {
+		if len(f.Models) == 0 {
+			return errors.New("the file has no models section")
+		}
+		connections, err := im.q.ListConnections(ctx, im.userID)
+		if err != nil {
+			return err
+		}
+		connectionIDs := map[string]pgtype.UUID{}
+		connectionNames := map[string]string{}
+		for _, c := range connections {
+			connectionIDs[c.Name] = c.ID
+			connectionNames[c.ID.String()] = c.Name
+		}
+		// Prompt templates are not exported; models refer to existing ones by name.
+		templates, err := im.q.ListPromptTemplates(ctx, im.userID)
+		if err != nil {
+			return err
+		}
+		templateIDs := map[string]pgtype.UUID{}
+		for _, t := range templates {
+			templateIDs[t.Name] = t.ID
+		}
+
+		for _, spec := range f.Models {
+			if spec.ProxyModelID == "" {
+				return errors.New("model proxy_model_id is required")
+			}
+			connectionID, ok := connectionIDs[spec.Connection]
+			if !ok {
+				return fmt.Errorf("model %q: unknown connection %q", spec.ProxyModelID, spec.Connection)
+			}
+			var promptTemplateID pgtype.UUID
+			if spec.PromptTemplate != "" {
+				if promptTemplateID, ok = templateIDs[spec.PromptTemplate]; !ok {
+					return fmt.Errorf("model %q: unknown prompt template %q", spec.ProxyModelID, spec.PromptTemplate)
+				}
+			}
+			if spec.ProviderModelID == "" {
+				spec.ProviderModelID = spec.ProxyModelID
+			}
+			if spec.Type == "" {
+				spec.Type = "llm"
+			}
+			if spec.Type != "llm" && spec.Type != "embedding" {
+				return fmt.Errorf("model %q: unknown type %q (expected llm or embedding)", spec.ProxyModelID, spec.Type)
+			}
+			if _, err := redaction.ParsePolicy(spec.LogPolicy, ""); err != nil {
+				return fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+			if err := spec.ParamPolicy.Validate(); err != nil {
+				return fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+			paramPolicy, err := spec.ParamPolicy.Marshal()
+			if err != nil {
+				return fmt.Errorf("model %q: param_policy: %w", spec.ProxyModelID, err)
+			}
+			if err := spec.StructuredOutput.Validate(); err != nil {
+				return fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+			structuredOutput, err := spec.StructuredOutput.Marshal()
+			if err != nil {
+				return fmt.Errorf("model %q: structured_output: %w", spec.ProxyModelID, err)
+			}
+			if spec.Shadow != nil {
+				if _, ok := connectionIDs[spec.Shadow.Connection]; !ok {
+					return fmt.Errorf("model %q: shadow: unknown connection %q", spec.ProxyModelID, spec.Shadow.Connection)
+				}
+			}
+			if err := spec.Shadow.Policy(connectionIDs).Validate(connectionID, spec.ProviderModelID); err != nil {
+				return fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+			shadowPolicy, err := spec.Shadow.Policy(connectionIDs).Marshal()
+			if err != nil {
+				return fmt.Errorf("model %q: shadow: %w", spec.ProxyModelID, err)
+			}
+			priceInput, err := toNumeric(spec.PriceInput)
+			if err != nil {
+				return fmt.Errorf("model %q: price_input: %w", spec.ProxyModelID, err)
+			}
+			priceOutput, err := toNumeric(spec.PriceOutput)
+			if err != nil {
+				return fmt.Errorf("model %q: price_output: %w", spec.ProxyModelID, err)
+			}
+
+			current, err := im.q.GetModelByProxyModelID(ctx, database.GetModelByProxyModelIDParams{ProxyModelID: spec.ProxyModelID, UserID: im.userID})
+			if errors.Is(err, sql.ErrNoRows) {
+				created, err := im.q.CreateModel(ctx, database.CreateModelParams{
+					ID:               pgtype.UUID{Bytes: uuid.New(), Valid: true},
+					UserID:           im.userID,
+					ConnectionID:     connectionID,
+					ProxyModelID:     spec.ProxyModelID,
+					ProviderModelID:  spec.ProviderModelID,
+					Thinking:         spec.Thinking,
+					ToolsUsage:       spec.ToolsUsage,
+					PriceInput:       priceInput,
+					PriceOutput:      priceOutput,
+					Type:             spec.Type,
+					LogPolicy:        spec.LogPolicy,
+					ParamPolicy:      paramPolicy,
+					PromptTemplateID: promptTemplateID,
+					StructuredOutput: structuredOutput,
+					Shadow:           shadowPolicy,
+				})
+				if err != nil {
+					return fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+				}
+				recordAudit(ctx, im.q, im.userID, actionCreate, kindModel, created.ID, nil, spec)
+				im.report("+", kindModel, spec.ProxyModelID)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+			if current.Managed {
+				return fmt.Errorf("model %q is managed by the declarative configuration file", spec.ProxyModelID)
+			}
+
+			before := declarative.Model{
+				ProxyModelID:     current.ProxyModelID,
+				Connection:       spec.Connection,
+				ProviderModelID:  current.ProviderModelID,
+				Type:             current.Type,
+				PriceInput:       numericFloat(current.PriceInput),
+				PriceOutput:      numericFloat(current.PriceOutput),
+				Thinking:         current.Thinking,
+				ToolsUsage:       current.ToolsUsage,
+				LogPolicy:        current.LogPolicy,
+				ParamPolicy:      parampolicy.Decode(current.ParamPolicy),
+				PromptTemplate:   spec.PromptTemplate,
+				StructuredOutput: structuredoutput.Decode(current.StructuredOutput),
+				Shadow: declarative.ShadowOf(shadow.Decode(current.Shadow), func(id string) string {
+					if name, ok := connectionNames[id]; ok {
+						return name
+					}
+					return id
+				}),
+			}
+			if current.ConnectionID != connectionID {
+				before.Connection = current.ConnectionID.String()
+			}
+			if current.PromptTemplateID != promptTemplateID {
+				before.PromptTemplate = current.PromptTemplateID.String()
+				if !current.PromptTemplateID.Valid {
+					before.PromptTemplate = ""
+				}
+			}
+			if sameModel(before, spec) {
+				continue
+			}
+			if _, err := im.q.UpdateModel(ctx, database.UpdateModelParams{
+				ID:               current.ID,
+				UserID:           im.userID,
+				ProxyModelID:     spec.ProxyModelID,
+				ProviderModelID:  spec.ProviderModelID,
+				Thinking:         spec.Thinking,
+				ToolsUsage:       spec.ToolsUsage,
+				PriceInput:       priceInput,
+				PriceOutput:      priceOutput,
+				Type:             spec.Type,
+				LogPolicy:        spec.LogPolicy,
+				ConnectionID:     connectionID,
+				ParamPolicy:      paramPolicy,
+				PromptTemplateID: promptTemplateID,
+				StructuredOutput: structuredOutput,
+				Shadow:           shadowPolicy,
+			}); err != nil {
+				return fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+			recordAudit(ctx, im.q, im.userID, actionUpdate, kindModel, current.ID, before, spec)
+			im.report("~", kindModel, spec.ProxyModelID)
+		}
+		return nil
+	})
+}
+
+// sameModel compares model specs, parameter, structured output and shadow
+// policies by content.
+func sameModel(a, b declarative.Model) bool {
+	if !parampolicy.Equal(a.ParamPolicy, b.ParamPolicy) || !structuredoutput.Equal(a.StructuredOutput, b.StructuredOutput) || !declarative.EqualShadow(a.Shadow, b.Shadow) {
+		return false
+	}
+	a.ParamPolicy, b.ParamPolicy = nil, nil
+	a.StructuredOutput, b.StructuredOutput = nil, nil
+	a.Shadow, b.Shadow = nil, nil
+	return a == b
+}
+
+// importResources creates or updates the resources of one kind from a file in
+// a single transaction. With -dry-run the transaction is rolled back after
+// printing what would change.
+func importResources(ctx context.Context, env *Env, args []string, kind string, apply func(context.Context, *importer, *resourceFile) error) error {
+	fs := newFlagSet(env, kind+" import", "-username NAME -f FILE [-dry-run]")
+	username := fs.String("username", "", "owner of the resources")
+	path := fs.String("f", "", "YAML file as written by export (- for stdin)")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username, "f": *path}); err != nil {
+		return err
+	}
+
+	f, err := readResourceFile(env, *path)
+	if err != nil {
+		return err
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	err = db.ExecTx(ctx, func(q database.Querier) error {
+		userID, err := lookupUserID(ctx, q, *username)
+		if err != nil {
+			return err
+		}
+		if err := apply(ctx, &importer{q: q, env: env, userID: userID}, f); err != nil {
+			return err
+		}
+		if *dryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return err
+	}
+	return nil
+}
+
+func readResourceFile(env *Env, path string) (*resourceFile, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(env.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f resourceFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+func providerNamesByID(ctx context.Context, q database.Querier, userID pgtype.UUID) (map[string]string, error) {
+	providers, err := q.ListProviders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, p := range providers {
+		names[p.ID.String()] = p.Name
+	}
+	return names, nil
+}
+
+var nonEnvChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// secretEnvName suggests an environment variable for a connection's API key,
+// e.g. "openai-prod" becomes CONNECTION_OPENAI_PROD_API_KEY.
+func secretEnvName(connection string) string {
+	name := strings.Trim(nonEnvChars.ReplaceAllString(strings.ToUpper(connection), "_"), "_")
+	return "CONNECTION_" + name + "_API_KEY"
+}
+
+// writeOutput writes data to path, or to stdout when path is empty.
+func writeOutput(env *Env, path string, data []byte) error {
+	if path == "" {
+		_, err := env.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"gen-ai-proxy/src/database"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func usageReportCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "usage report", "-username NAME [-since T] [-until T]")
+	username := fs.String("username", "", "user to report on")
+	since := fs.String("since", "", "start of the period (RFC3339 or YYYY-MM-DD, default: first day of this month)")
+	until := fs.String("until", "", "end of the period, exclusive (RFC3339 or YYYY-MM-DD, default: now)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username}); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	params := database.GetUsageReportParams{
+		Since: pgtype.Timestamptz{Time: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		Until: pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	if *since != "" {
+		t, err := parseTimeFlag("since", *since)
+		if err != nil {
+			return err
+		}
+		params.Since = t
+	}
+	if *until != "" {
+		t, err := parseTimeFlag("until", *until)
+		if err != nil {
+			return err
+		}
+		params.Until = t
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	if params.UserID, err = lookupUserID(ctx, db, *username); err != nil {
+		return err
+	}
+	rows, err := db.GetUsageReport(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Stdout, "Usage of %s from %s to %s\n\n", *username,
+		params.Since.Time.Format(time.RFC3339), params.Until.Time.Format(time.RFC3339))
+	w := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "MODEL\tTYPE\tREQUESTS\tPROMPT TOKENS\tCOMPLETION TOKENS\tCOST\t")
+	var total database.GetUsageReportRow
+	var totalCost float64
+	for _, r := range rows {
+		cost := numericFloat(r.Cost)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.4f\t\n", r.ModelName, r.Type, r.RequestCount, r.PromptTokens, r.CompletionTokens, cost)
+		total.RequestCount += r.RequestCount
+		total.PromptTokens += r.PromptTokens
+		total.CompletionTokens += r.CompletionTokens
+		totalCost += cost
+	}
+	fmt.Fprintf(w, "TOTAL\t\t%d\t%d\t%d\t%.4f\t\n", total.RequestCount, total.PromptTokens, total.CompletionTokens, totalCost)
+	return w.Flush()
+}
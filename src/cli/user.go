@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"gen-ai-proxy/src/database"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func userCreateCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "user create", "-username NAME [-password-env VAR]")
+	username := fs.String("username", "", "name of the user to create")
+	passwordEnv := fs.String("password-env", "", "environment variable holding the password (default: read from stdin)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username}); err != nil {
+		return err
+	}
+
+	password, err := readSecret(env, *passwordEnv, "password")
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	if _, err := db.GetUserByUsername(ctx, *username); err == nil {
+		return fmt.Errorf("user %q already exists", *username)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	user, err := db.CreateUser(ctx, database.CreateUserParams{Username: *username, PasswordHash: string(hash)})
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	recordAudit(ctx, db, user.ID, actionCreate, kindUser, user.ID, nil, map[string]string{"username": user.Username})
+	fmt.Fprintf(env.Stdout, "created user %s (%s)\n", user.Username, user.ID.String())
+	return nil
+}
+
+func userResetPasswordCmd(ctx context.Context, env *Env, args []string) error {
+	fs := newFlagSet(env, "user reset-password", "-username NAME [-password-env VAR]")
+	username := fs.String("username", "", "name of the user")
+	passwordEnv := fs.String("password-env", "", "environment variable holding the new password (default: read from stdin)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, map[string]string{"username": *username}); err != nil {
+		return err
+	}
+
+	password, err := readSecret(env, *passwordEnv, "password")
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	db, err := env.Store()
+	if err != nil {
+		return err
+	}
+	userID, err := lookupUserID(ctx, db, *username)
+	if err != nil {
+		return err
+	}
+	n, err := db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{Username: *username, PasswordHash: string(hash)})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("user %q not found", *username)
+	}
+	recordAudit(ctx, db, userID, actionUpdate, kindUser, userID, nil, map[string]any{"username": *username, "fields": []string{"password"}})
+	fmt.Fprintf(env.Stdout, "password updated for %s\n", *username)
+	return nil
+}
+
+// lookupUserID resolves the user a command acts on.
+func lookupUserID(ctx context.Context, db database.Querier, username string) (pgtype.UUID, error) {
+	user, err := db.GetUserByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgtype.UUID{}, fmt.Errorf("user %q not found", username)
+	}
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	return user.ID, nil
+}
@@ -3,17 +3,17 @@ package config
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
-// ProviderConfig holds configuration for a specific LLM provider.
-type ProviderConfig struct {
-	BaseURL string `mapstructure:"BASE_URL"`
-	Type    string `mapstructure:"TYPE"`
-}
-
 type Config struct {
+	// Storage backend: "postgres" (default) or "sqlite"
+	DBDriver   string `mapstructure:"DB_DRIVER"`
+	SQLitePath string `mapstructure:"SQLITE_PATH"`
+
 	DBUser        string `mapstructure:"POSTGRES_USER"`
 	DBPassword    string `mapstructure:"POSTGRES_PASSWORD"`
 	DBName        string `mapstructure:"POSTGRES_DB"`
@@ -22,16 +22,154 @@ type Config struct {
 	ServerPort    string `mapstructure:"SERVER_PORT"`
 	EncryptionKey string `mapstructure:"ENCRYPTION_KEY"`
 	JWTSecret     string `mapstructure:"JWT_SECRET"`
-	Providers map[string]ProviderConfig `mapstructure:"PROVIDERS"`
+
+	// Directory whose files override the embedded migrations, templates and static files
+	AssetsDir string `mapstructure:"ASSETS_DIR"`
+
+	// Graceful shutdown and health checks
+	ShutdownDelay      time.Duration `mapstructure:"SHUTDOWN_DELAY"`
+	ShutdownTimeout    time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	ReadinessUpstreams string        `mapstructure:"READINESS_UPSTREAMS"`
+
+	// Upstream provider calls, streamed responses included; 0 waits forever
+	UpstreamTimeout time.Duration `mapstructure:"UPSTREAM_TIMEOUT"`
+
+	// Upstream exchanges recorded to fixture files, or replayed from them:
+	// "record", "replay" or empty; see package recording
+	UpstreamMode  string `mapstructure:"UPSTREAM_MODE"`
+	RecordingsDir string `mapstructure:"RECORDINGS_DIR"`
+	ReplayTiming  bool   `mapstructure:"REPLAY_TIMING"`
+
+	// Built-in mock provider, off unless enabled; fixture files named by
+	// mock base URLs are read from MockFixturesDir only
+	MockProviders   bool   `mapstructure:"MOCK_PROVIDERS"`
+	MockFixturesDir string `mapstructure:"MOCK_FIXTURES_DIR"`
+
+	// Retries of chat outputs that do not match their JSON Schema, for models
+	// whose structured output policy does not set max_retries
+	StructuredOutputMaxRetries int `mapstructure:"STRUCTURED_OUTPUT_MAX_RETRIES"`
+
+	// Batch API workers on this instance (0 leaves batches to other replicas)
+	// and attempts per request on errors, 429 and 5xx responses
+	BatchWorkers     int `mapstructure:"BATCH_WORKERS"`
+	BatchMaxAttempts int `mapstructure:"BATCH_MAX_ATTEMPTS"`
+
+	// Evaluation run workers on this instance and attempts per replayed
+	// request, like the batch workers
+	EvalWorkers     int `mapstructure:"EVAL_WORKERS"`
+	EvalMaxAttempts int `mapstructure:"EVAL_MAX_ATTEMPTS"`
+
+	// Routing table refresh when change notifications are unavailable or missed
+	RoutingPollInterval time.Duration `mapstructure:"ROUTING_POLL_INTERVAL"`
+
+	// Declarative configuration of providers, connections, models and API keys
+	ResourcesFile   string `mapstructure:"RESOURCES_FILE"`
+	ResourcesDryRun bool   `mapstructure:"RESOURCES_DRY_RUN"`
+
+	// Process logging
+	LogLevel    string `mapstructure:"LOG_LEVEL"`
+	LogFormat   string `mapstructure:"LOG_FORMAT"`
+	LogPayloads bool   `mapstructure:"LOG_PAYLOADS"`
+
+	// Conversation log persistence
+	LogPolicyDefault   string `mapstructure:"LOG_POLICY_DEFAULT"`
+	RedactionDetectors string `mapstructure:"REDACTION_DETECTORS"`
+	RedactionRules     string `mapstructure:"REDACTION_RULES"`
+
+	// Conversation log retention and archival
+	RetentionEnabled   bool          `mapstructure:"RETENTION_ENABLED"`
+	RetentionInterval  time.Duration `mapstructure:"RETENTION_INTERVAL"`
+	RetentionBatchSize int           `mapstructure:"RETENTION_BATCH_SIZE"`
+	ArchiveTarget      string        `mapstructure:"ARCHIVE_TARGET"`
+	ArchiveDir         string        `mapstructure:"ARCHIVE_DIR"`
+	ArchivePrefix      string        `mapstructure:"ARCHIVE_PREFIX"`
+	ArchiveS3Endpoint  string        `mapstructure:"ARCHIVE_S3_ENDPOINT"`
+	ArchiveS3Bucket    string        `mapstructure:"ARCHIVE_S3_BUCKET"`
+	ArchiveS3Region    string        `mapstructure:"ARCHIVE_S3_REGION"`
+	ArchiveS3AccessKey string        `mapstructure:"ARCHIVE_S3_ACCESS_KEY"`
+	ArchiveS3SecretKey string        `mapstructure:"ARCHIVE_S3_SECRET_KEY"`
+
+	// OpenTelemetry tracing
+	TracingEnabled     bool    `mapstructure:"TRACING_ENABLED"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+	OTelServiceName    string  `mapstructure:"OTEL_SERVICE_NAME"`
+	OTLPEndpoint       string  `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTLPProtocol       string  `mapstructure:"OTEL_EXPORTER_OTLP_PROTOCOL"`
+	OTLPInsecure       bool    `mapstructure:"OTEL_EXPORTER_OTLP_INSECURE"`
+}
+
+// optionalEnvs lists settings that may be omitted, with their defaults.
+var optionalEnvs = map[string]string{
+	"DB_DRIVER":   "postgres",
+	"SQLITE_PATH": "gen-ai-proxy.db",
+
+	"LOG_LEVEL":    "info",
+	"LOG_FORMAT":   "text",
+	"LOG_PAYLOADS": "false",
+
+	"ASSETS_DIR": "",
+
+	"SHUTDOWN_DELAY":      "0s",
+	"SHUTDOWN_TIMEOUT":    "30s",
+	"READINESS_UPSTREAMS": "",
+
+	"UPSTREAM_TIMEOUT": "0s",
+
+	"UPSTREAM_MODE":  "",
+	"RECORDINGS_DIR": "recordings",
+	"REPLAY_TIMING":  "true",
+
+	"MOCK_PROVIDERS":    "false",
+	"MOCK_FIXTURES_DIR": "",
+
+	"STRUCTURED_OUTPUT_MAX_RETRIES": "1",
+
+	"BATCH_WORKERS":      "4",
+	"BATCH_MAX_ATTEMPTS": "3",
+
+	"EVAL_WORKERS":      "2",
+	"EVAL_MAX_ATTEMPTS": "3",
+
+	"ROUTING_POLL_INTERVAL": "5s",
+
+	"RESOURCES_FILE":    "",
+	"RESOURCES_DRY_RUN": "false",
+
+	"LOG_POLICY_DEFAULT":  "full",
+	"REDACTION_DETECTORS": "email,phone,credit_card,iban,api_key",
+	"REDACTION_RULES":     "",
+
+	"RETENTION_ENABLED":     "true",
+	"RETENTION_INTERVAL":    "1h",
+	"RETENTION_BATCH_SIZE":  "500",
+	"ARCHIVE_TARGET":        "",
+	"ARCHIVE_DIR":           "archive",
+	"ARCHIVE_PREFIX":        "conversation-logs",
+	"ARCHIVE_S3_ENDPOINT":   "https://s3.amazonaws.com",
+	"ARCHIVE_S3_BUCKET":     "",
+	"ARCHIVE_S3_REGION":     "us-east-1",
+	"ARCHIVE_S3_ACCESS_KEY": "",
+	"ARCHIVE_S3_SECRET_KEY": "",
+
+	"TRACING_ENABLED":             "false",
+	"TRACING_SAMPLE_RATIO":        "1.0",
+	"OTEL_SERVICE_NAME":           "gen-ai-proxy",
+	"OTEL_EXPORTER_OTLP_ENDPOINT": "",
+	"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf",
+	"OTEL_EXPORTER_OTLP_INSECURE": "false",
 }
 
 func LoadConfig(path string) (config Config, err error) {
 	viper.SetEnvPrefix("PROVIDER")
 	viper.AutomaticEnv()
 
-	requiredEnvs := []string{
-		"DB_HOST", "DB_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB",
-		"JWT_SECRET", "ENCRYPTION_KEY", "SERVER_PORT",
+	requiredEnvs := []string{"JWT_SECRET", "ENCRYPTION_KEY", "SERVER_PORT"}
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", "postgres":
+		requiredEnvs = append(requiredEnvs, "DB_HOST", "DB_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB")
+	case "sqlite":
+	default:
+		return config, fmt.Errorf("DB_DRIVER must be postgres or sqlite, got %q", driver)
 	}
 
 	for _, env := range requiredEnvs {
@@ -44,6 +182,13 @@ func LoadConfig(path string) (config Config, err error) {
 		viper.Set(env, os.Getenv(env))
 	}
 
+	for env, def := range optionalEnvs {
+		viper.SetDefault(env, def)
+		if val, ok := os.LookupEnv(env); ok {
+			viper.Set(env, val)
+		}
+	}
+
 	err = viper.Unmarshal(&config)
 	if err != nil {
 		return config, err
@@ -51,3 +196,16 @@ func LoadConfig(path string) (config Config, err error) {
 
 	return
 }
+
+// Changed returns the environment variable names whose values differ between
+// two configurations.
+func Changed(old, new Config) []string {
+	var names []string
+	o, n := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < o.NumField(); i++ {
+		if !reflect.DeepEqual(o.Field(i).Interface(), n.Field(i).Interface()) {
+			names = append(names, o.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return names
+}
@@ -15,20 +15,33 @@ const createAPIKey = `-- name: CreateAPIKey :one
 INSERT INTO api_keys (
     user_id,
     key_hash,
-    name
+    name,
+    managed,
+    allowed_models,
+    monthly_budget
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, user_id, key_hash, name, created_at, last_used_at
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget
 `
 
 type CreateAPIKeyParams struct {
-	UserID  pgtype.UUID `json:"user_id"`
-	KeyHash string      `json:"key_hash"`
-	Name    string      `json:"name"`
+	UserID        pgtype.UUID    `json:"user_id"`
+	KeyHash       string         `json:"key_hash"`
+	Name          string         `json:"name"`
+	Managed       bool           `json:"managed"`
+	AllowedModels []string       `json:"allowed_models"`
+	MonthlyBudget pgtype.Numeric `json:"monthly_budget"`
 }
 
 func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
-	row := q.db.QueryRow(ctx, createAPIKey, arg.UserID, arg.KeyHash, arg.Name)
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.UserID,
+		arg.KeyHash,
+		arg.Name,
+		arg.Managed,
+		arg.AllowedModels,
+		arg.MonthlyBudget,
+	)
 	var i ApiKey
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +50,9 @@ func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (Api
 		&i.Name,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Managed,
+		&i.AllowedModels,
+		&i.MonthlyBudget,
 	)
 	return i, err
 }
@@ -57,7 +73,7 @@ func (q *Queries) DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) erro
 }
 
 const getAPIKey = `-- name: GetAPIKey :one
-SELECT id, user_id, key_hash, name, created_at, last_used_at FROM api_keys
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
 WHERE key_hash = $1 AND user_id = $2
 `
 
@@ -76,12 +92,15 @@ func (q *Queries) GetAPIKey(ctx context.Context, arg GetAPIKeyParams) (ApiKey, e
 		&i.Name,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Managed,
+		&i.AllowedModels,
+		&i.MonthlyBudget,
 	)
 	return i, err
 }
 
 const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
-SELECT id, user_id, key_hash, name, created_at, last_used_at FROM api_keys
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
 WHERE key_hash = $1
 `
 
@@ -95,21 +114,54 @@ func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey,
 		&i.Name,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Managed,
+		&i.AllowedModels,
+		&i.MonthlyBudget,
+	)
+	return i, err
+}
+
+const getAPIKeyByID = `-- name: GetAPIKeyByID :one
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE id = $1 AND user_id = $2
+`
+
+type GetAPIKeyByIDParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetAPIKeyByID(ctx context.Context, arg GetAPIKeyByIDParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, getAPIKeyByID, arg.ID, arg.UserID)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.KeyHash,
+		&i.Name,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.Managed,
+		&i.AllowedModels,
+		&i.MonthlyBudget,
 	)
 	return i, err
 }
 
 const listAPIKeys = `-- name: ListAPIKeys :many
-SELECT id, user_id, name, created_at, last_used_at FROM api_keys
+SELECT id, user_id, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
 WHERE user_id = $1
 `
 
 type ListAPIKeysRow struct {
-	ID         pgtype.UUID        `json:"id"`
-	UserID     pgtype.UUID        `json:"user_id"`
-	Name       string             `json:"name"`
-	CreatedAt  pgtype.Timestamptz `json:"created_at"`
-	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	ID            pgtype.UUID        `json:"id"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	Name          string             `json:"name"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	LastUsedAt    pgtype.Timestamptz `json:"last_used_at"`
+	Managed       bool               `json:"managed"`
+	AllowedModels []string           `json:"allowed_models"`
+	MonthlyBudget pgtype.Numeric     `json:"monthly_budget"`
 }
 
 func (q *Queries) ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]ListAPIKeysRow, error) {
@@ -127,6 +179,44 @@ func (q *Queries) ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]ListAP
 			&i.Name,
 			&i.CreatedAt,
 			&i.LastUsedAt,
+			&i.Managed,
+			&i.AllowedModels,
+			&i.MonthlyBudget,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listManagedAPIKeys = `-- name: ListManagedAPIKeys :many
+SELECT id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget FROM api_keys
+WHERE user_id = $1 AND managed
+`
+
+func (q *Queries) ListManagedAPIKeys(ctx context.Context, userID pgtype.UUID) ([]ApiKey, error) {
+	rows, err := q.db.Query(ctx, listManagedAPIKeys, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiKey
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.KeyHash,
+			&i.Name,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.Managed,
+			&i.AllowedModels,
+			&i.MonthlyBudget,
 		); err != nil {
 			return nil, err
 		}
@@ -143,7 +233,7 @@ UPDATE api_keys
 SET
     name = $2
 WHERE name = $1 AND user_id = $3
-RETURNING id, user_id, key_hash, name, created_at, last_used_at
+RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget
 `
 
 type UpdateAPIKeyParams struct {
@@ -162,6 +252,9 @@ func (q *Queries) UpdateAPIKey(ctx context.Context, arg UpdateAPIKeyParams) (Api
 		&i.Name,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Managed,
+		&i.AllowedModels,
+		&i.MonthlyBudget,
 	)
 	return i, err
 }
@@ -177,3 +270,44 @@ func (q *Queries) UpdateAPIKeyLastUsed(ctx context.Context, id pgtype.UUID) erro
 	_, err := q.db.Exec(ctx, updateAPIKeyLastUsed, id)
 	return err
 }
+
+const updateAPIKeyPolicy = `-- name: UpdateAPIKeyPolicy :one
+UPDATE api_keys
+SET
+    key_hash = $3,
+    allowed_models = $4,
+    monthly_budget = $5
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, key_hash, name, created_at, last_used_at, managed, allowed_models, monthly_budget
+`
+
+type UpdateAPIKeyPolicyParams struct {
+	ID            pgtype.UUID    `json:"id"`
+	UserID        pgtype.UUID    `json:"user_id"`
+	KeyHash       string         `json:"key_hash"`
+	AllowedModels []string       `json:"allowed_models"`
+	MonthlyBudget pgtype.Numeric `json:"monthly_budget"`
+}
+
+func (q *Queries) UpdateAPIKeyPolicy(ctx context.Context, arg UpdateAPIKeyPolicyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, updateAPIKeyPolicy,
+		arg.ID,
+		arg.UserID,
+		arg.KeyHash,
+		arg.AllowedModels,
+		arg.MonthlyBudget,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.KeyHash,
+		&i.Name,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.Managed,
+		&i.AllowedModels,
+		&i.MonthlyBudget,
+	)
+	return i, err
+}
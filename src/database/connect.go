@@ -4,34 +4,46 @@ import (
 	"context"
 	"fmt"
 	"gen-ai-proxy/src/config"
+	"gen-ai-proxy/src/telemetry"
+	"log/slog"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func Connect(cfg *config.Config) (*pgx.Conn, error) {
+// Connect opens a connection pool; handlers and background jobs share it
+// concurrently, which a single pgx.Conn does not allow.
+func Connect(cfg *config.Config) (*pgxpool.Pool, error) {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s",
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
 
-	pgxConfig, err := pgx.ParseConfig(connStr)
+	pgxConfig, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database config: %w", err)
 	}
 
 	// Explicitly disable TLS
-	pgxConfig.TLSConfig = nil
+	pgxConfig.ConnConfig.TLSConfig = nil
+
+	// Trace every query as a child of the request span that issued it.
+	pgxConfig.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithSpanNameFunc(telemetry.SQLSpanName))
 
 	maxAttempts := 5
 	initialDelay := 1 * time.Second
 
 	for i := 0; i < maxAttempts; i++ {
-		conn, err := pgx.ConnectConfig(context.Background(), pgxConfig)
+		pool, err := pgxpool.NewWithConfig(context.Background(), pgxConfig)
 		if err == nil {
-			return conn, nil // Successfully connected
+			err = pool.Ping(context.Background())
+			if err == nil {
+				return pool, nil // Successfully connected
+			}
+			pool.Close()
 		}
 
 		// Log the error for debugging
-		fmt.Printf("Attempt %d to connect to database failed: %v\n", i+1, err)
+		slog.Warn("Database connection attempt failed", "attempt", i+1, "error", err)
 
 		if i < maxAttempts-1 {
 			// Exponential backoff
@@ -16,10 +16,11 @@ INSERT INTO connections (
     user_id,
     provider_id,
     encrypted_api_key,
-    name
+    name,
+    managed
 ) VALUES (
-    $1, $2, $3, $4
-) RETURNING id, user_id, provider_id, encrypted_api_key, name, created_at
+    $1, $2, $3, $4, $5
+) RETURNING id, user_id, provider_id, encrypted_api_key, name, created_at, managed
 `
 
 type CreateConnectionParams struct {
@@ -27,6 +28,7 @@ type CreateConnectionParams struct {
 	ProviderID      string      `json:"provider_id"`
 	EncryptedApiKey string      `json:"encrypted_api_key"`
 	Name            string      `json:"name"`
+	Managed         bool        `json:"managed"`
 }
 
 type CreateConnectionRow struct {
@@ -36,6 +38,7 @@ type CreateConnectionRow struct {
 	EncryptedApiKey string             `json:"encrypted_api_key"`
 	Name            string             `json:"name"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	Managed         bool               `json:"managed"`
 }
 
 func (q *Queries) CreateConnection(ctx context.Context, arg CreateConnectionParams) (CreateConnectionRow, error) {
@@ -44,6 +47,7 @@ func (q *Queries) CreateConnection(ctx context.Context, arg CreateConnectionPara
 		arg.ProviderID,
 		arg.EncryptedApiKey,
 		arg.Name,
+		arg.Managed,
 	)
 	var i CreateConnectionRow
 	err := row.Scan(
@@ -53,12 +57,13 @@ func (q *Queries) CreateConnection(ctx context.Context, arg CreateConnectionPara
 		&i.EncryptedApiKey,
 		&i.Name,
 		&i.CreatedAt,
+		&i.Managed,
 	)
 	return i, err
 }
 
 const getConnection = `-- name: GetConnection :one
-SELECT c.id, c.user_id, c.provider_id, c.encrypted_api_key, c.name, c.created_at, p.type as provider_type, c.deleted_at
+SELECT c.id, c.user_id, c.provider_id, c.encrypted_api_key, c.name, c.created_at, p.type as provider_type, c.deleted_at, c.managed
 FROM connections c
 JOIN providers p ON p.id = c.provider_id::uuid
 WHERE c.id = $1 AND c.user_id = $2 AND c.deleted_at IS NULL
@@ -78,6 +83,7 @@ type GetConnectionRow struct {
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	ProviderType    string             `json:"provider_type"`
 	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+	Managed         bool               `json:"managed"`
 }
 
 func (q *Queries) GetConnection(ctx context.Context, arg GetConnectionParams) (GetConnectionRow, error) {
@@ -92,12 +98,13 @@ func (q *Queries) GetConnection(ctx context.Context, arg GetConnectionParams) (G
 		&i.CreatedAt,
 		&i.ProviderType,
 		&i.DeletedAt,
+		&i.Managed,
 	)
 	return i, err
 }
 
 const getConnectionByProvider = `-- name: GetConnectionByProvider :one
-SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at FROM connections
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
 WHERE user_id = $1 AND provider_id = $2 AND deleted_at IS NULL
 `
 
@@ -117,12 +124,47 @@ func (q *Queries) GetConnectionByProvider(ctx context.Context, arg GetConnection
 		&i.Name,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Managed,
 	)
 	return i, err
 }
 
+const listAllConnections = `-- name: ListAllConnections :many
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
+ORDER BY created_at
+`
+
+func (q *Queries) ListAllConnections(ctx context.Context) ([]Connection, error) {
+	rows, err := q.db.Query(ctx, listAllConnections)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Connection
+	for rows.Next() {
+		var i Connection
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ProviderID,
+			&i.EncryptedApiKey,
+			&i.Name,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Managed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listConnections = `-- name: ListConnections :many
-SELECT id, user_id, provider_id, name, created_at, deleted_at FROM connections
+SELECT id, user_id, provider_id, name, created_at, deleted_at, managed FROM connections
 WHERE user_id = $1 AND deleted_at IS NULL
 `
 
@@ -133,6 +175,7 @@ type ListConnectionsRow struct {
 	Name       string             `json:"name"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
+	Managed    bool               `json:"managed"`
 }
 
 func (q *Queries) ListConnections(ctx context.Context, userID pgtype.UUID) ([]ListConnectionsRow, error) {
@@ -151,6 +194,7 @@ func (q *Queries) ListConnections(ctx context.Context, userID pgtype.UUID) ([]Li
 			&i.Name,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.Managed,
 		); err != nil {
 			return nil, err
 		}
@@ -163,7 +207,7 @@ func (q *Queries) ListConnections(ctx context.Context, userID pgtype.UUID) ([]Li
 }
 
 const listConnectionsByProviderID = `-- name: ListConnectionsByProviderID :many
-SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at FROM connections
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
 WHERE provider_id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
@@ -189,6 +233,41 @@ func (q *Queries) ListConnectionsByProviderID(ctx context.Context, arg ListConne
 			&i.Name,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.Managed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listManagedConnections = `-- name: ListManagedConnections :many
+SELECT id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed FROM connections
+WHERE user_id = $1 AND managed AND deleted_at IS NULL
+`
+
+func (q *Queries) ListManagedConnections(ctx context.Context, userID pgtype.UUID) ([]Connection, error) {
+	rows, err := q.db.Query(ctx, listManagedConnections, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Connection
+	for rows.Next() {
+		var i Connection
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ProviderID,
+			&i.EncryptedApiKey,
+			&i.Name,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Managed,
 		); err != nil {
 			return nil, err
 		}
@@ -215,3 +294,40 @@ func (q *Queries) SoftDeleteConnection(ctx context.Context, arg SoftDeleteConnec
 	_, err := q.db.Exec(ctx, softDeleteConnection, arg.ID, arg.UserID)
 	return err
 }
+
+const updateConnection = `-- name: UpdateConnection :one
+UPDATE connections
+SET
+    provider_id = $3,
+    encrypted_api_key = $4
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, provider_id, encrypted_api_key, name, created_at, deleted_at, managed
+`
+
+type UpdateConnectionParams struct {
+	ID              pgtype.UUID `json:"id"`
+	UserID          pgtype.UUID `json:"user_id"`
+	ProviderID      string      `json:"provider_id"`
+	EncryptedApiKey string      `json:"encrypted_api_key"`
+}
+
+func (q *Queries) UpdateConnection(ctx context.Context, arg UpdateConnectionParams) (Connection, error) {
+	row := q.db.QueryRow(ctx, updateConnection,
+		arg.ID,
+		arg.UserID,
+		arg.ProviderID,
+		arg.EncryptedApiKey,
+	)
+	var i Connection
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProviderID,
+		&i.EncryptedApiKey,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Managed,
+	)
+	return i, err
+}
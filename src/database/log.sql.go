@@ -13,21 +13,77 @@ import (
 
 const countLogs = `-- name: CountLogs :one
 SELECT COUNT(*)
-FROM logs
+FROM logs l
+LEFT JOIN models m ON m.id = l.model_id
+LEFT JOIN connections conn ON conn.id = l.connection_id
 WHERE
-    ($1::UUID IS NULL OR user_id = $1) AND
-    ($2::UUID IS NULL OR model_id = $2) AND
-    ($3::UUID IS NULL OR connection_id = $3)
+    ($1::UUID IS NULL OR l.user_id = $1) AND
+    ($2::UUID IS NULL OR l.model_id = $2) AND
+    ($3::UUID IS NULL OR l.connection_id = $3) AND
+    ($4::UUID IS NULL OR conn.provider_id = $4::UUID::TEXT) AND
+    ($5::UUID IS NULL OR l.api_key_id = $5) AND
+    ($6::TEXT IS NULL OR l.type = $6) AND
+    ($7::TEXT IS NULL OR l.request_id = $7) AND
+    ($8::UUID IS NULL OR l.prompt_template_id = $8) AND
+    ($9::INTEGER IS NULL OR l.prompt_template_version = $9) AND
+    ($10::TEXT IS NULL OR l.schema_validation = $10) AND
+    ($11::UUID IS NULL OR l.batch_id = $11) AND
+    ($12::TIMESTAMPTZ IS NULL OR l.created_at >= $12) AND
+    ($13::TIMESTAMPTZ IS NULL OR l.created_at < $13) AND
+    ($14::TEXT IS NULL OR
+        ($14 = 'success' AND l.status_code < 400) OR
+        ($14 = 'error' AND l.status_code >= 400)) AND
+    ($15::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) >= $15) AND
+    ($16::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) <= $16) AND
+    ($17::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) >= $17) AND
+    ($18::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) <= $18) AND
+    ($19::TEXT IS NULL OR l.search_vector @@ websearch_to_tsquery('simple', $19))
 `
 
 type CountLogsParams struct {
-	UserID       pgtype.UUID `json:"user_id"`
-	ModelID      pgtype.UUID `json:"model_id"`
-	ConnectionID pgtype.UUID `json:"connection_id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	ModelID               pgtype.UUID        `json:"model_id"`
+	ConnectionID          pgtype.UUID        `json:"connection_id"`
+	ProviderID            pgtype.UUID        `json:"provider_id"`
+	ApiKeyID              pgtype.UUID        `json:"api_key_id"`
+	Type                  pgtype.Text        `json:"type"`
+	RequestID             pgtype.Text        `json:"request_id"`
+	PromptTemplateID      pgtype.UUID        `json:"prompt_template_id"`
+	PromptTemplateVersion pgtype.Int4        `json:"prompt_template_version"`
+	SchemaValidation      pgtype.Text        `json:"schema_validation"`
+	BatchID               pgtype.UUID        `json:"batch_id"`
+	Since                 pgtype.Timestamptz `json:"since"`
+	Until                 pgtype.Timestamptz `json:"until"`
+	Status                pgtype.Text        `json:"status"`
+	MinTokens             pgtype.Int8        `json:"min_tokens"`
+	MaxTokens             pgtype.Int8        `json:"max_tokens"`
+	MinCost               pgtype.Numeric     `json:"min_cost"`
+	MaxCost               pgtype.Numeric     `json:"max_cost"`
+	Search                pgtype.Text        `json:"search"`
 }
 
 func (q *Queries) CountLogs(ctx context.Context, arg CountLogsParams) (int64, error) {
-	row := q.db.QueryRow(ctx, countLogs, arg.UserID, arg.ModelID, arg.ConnectionID)
+	row := q.db.QueryRow(ctx, countLogs,
+		arg.UserID,
+		arg.ModelID,
+		arg.ConnectionID,
+		arg.ProviderID,
+		arg.ApiKeyID,
+		arg.Type,
+		arg.RequestID,
+		arg.PromptTemplateID,
+		arg.PromptTemplateVersion,
+		arg.SchemaValidation,
+		arg.BatchID,
+		arg.Since,
+		arg.Until,
+		arg.Status,
+		arg.MinTokens,
+		arg.MaxTokens,
+		arg.MinCost,
+		arg.MaxCost,
+		arg.Search,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -35,6 +91,7 @@ func (q *Queries) CountLogs(ctx context.Context, arg CountLogsParams) (int64, er
 
 const createLog = `-- name: CreateLog :one
 INSERT INTO logs (
+    id,
     user_id,
     model_id,
     request_payload,
@@ -42,38 +99,85 @@ INSERT INTO logs (
     prompt_tokens,
     completion_tokens,
     connection_id,
-    type
+    type,
+    api_key_id,
+    status_code,
+    request_id,
+    prompt_template_id,
+    prompt_template_version,
+    schema_validation,
+    schema_attempt,
+    schema_errors,
+    batch_id,
+    reasoning_tokens,
+    content_filter,
+    latency_ms,
+    eval_run_id,
+    shadow_of,
+    provider_model_id,
+    price_input,
+    price_output
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
-) RETURNING id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
+) RETURNING id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type, api_key_id, status_code, request_id, prompt_template_id, prompt_template_version, schema_validation, schema_attempt, schema_errors, batch_id, reasoning_tokens, content_filter
 `
 
 type CreateLogParams struct {
-	UserID           pgtype.UUID `json:"user_id"`
-	ModelID          pgtype.UUID `json:"model_id"`
-	RequestPayload   []byte      `json:"request_payload"`
-	ResponsePayload  []byte      `json:"response_payload"`
-	PromptTokens     pgtype.Int8 `json:"prompt_tokens"`
-	CompletionTokens pgtype.Int8 `json:"completion_tokens"`
-	ConnectionID     pgtype.UUID `json:"connection_id"`
-	Type             string      `json:"type"`
+	ID                    pgtype.UUID    `json:"id"`
+	UserID                pgtype.UUID    `json:"user_id"`
+	ModelID               pgtype.UUID    `json:"model_id"`
+	RequestPayload        []byte         `json:"request_payload"`
+	ResponsePayload       []byte         `json:"response_payload"`
+	PromptTokens          pgtype.Int8    `json:"prompt_tokens"`
+	CompletionTokens      pgtype.Int8    `json:"completion_tokens"`
+	ConnectionID          pgtype.UUID    `json:"connection_id"`
+	Type                  string         `json:"type"`
+	ApiKeyID              pgtype.UUID    `json:"api_key_id"`
+	StatusCode            pgtype.Int4    `json:"status_code"`
+	RequestID             pgtype.Text    `json:"request_id"`
+	PromptTemplateID      pgtype.UUID    `json:"prompt_template_id"`
+	PromptTemplateVersion pgtype.Int4    `json:"prompt_template_version"`
+	SchemaValidation      pgtype.Text    `json:"schema_validation"`
+	SchemaAttempt         pgtype.Int4    `json:"schema_attempt"`
+	SchemaErrors          pgtype.Text    `json:"schema_errors"`
+	BatchID               pgtype.UUID    `json:"batch_id"`
+	ReasoningTokens       pgtype.Int8    `json:"reasoning_tokens"`
+	ContentFilter         []byte         `json:"content_filter"`
+	LatencyMs             pgtype.Int8    `json:"latency_ms"`
+	EvalRunID             pgtype.UUID    `json:"eval_run_id"`
+	ShadowOf              pgtype.UUID    `json:"shadow_of"`
+	ProviderModelID       pgtype.Text    `json:"provider_model_id"`
+	PriceInput            pgtype.Numeric `json:"price_input"`
+	PriceOutput           pgtype.Numeric `json:"price_output"`
 }
 
 type CreateLogRow struct {
-	ID               pgtype.UUID        `json:"id"`
-	UserID           pgtype.UUID        `json:"user_id"`
-	ModelID          pgtype.UUID        `json:"model_id"`
-	RequestPayload   []byte             `json:"request_payload"`
-	ResponsePayload  []byte             `json:"response_payload"`
-	CreatedAt        pgtype.Timestamptz `json:"created_at"`
-	PromptTokens     pgtype.Int8        `json:"prompt_tokens"`
-	CompletionTokens pgtype.Int8        `json:"completion_tokens"`
-	ConnectionID     pgtype.UUID        `json:"connection_id"`
-	Type             string             `json:"type"`
+	ID                    pgtype.UUID        `json:"id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	ModelID               pgtype.UUID        `json:"model_id"`
+	RequestPayload        []byte             `json:"request_payload"`
+	ResponsePayload       []byte             `json:"response_payload"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	PromptTokens          pgtype.Int8        `json:"prompt_tokens"`
+	CompletionTokens      pgtype.Int8        `json:"completion_tokens"`
+	ConnectionID          pgtype.UUID        `json:"connection_id"`
+	Type                  string             `json:"type"`
+	ApiKeyID              pgtype.UUID        `json:"api_key_id"`
+	StatusCode            pgtype.Int4        `json:"status_code"`
+	RequestID             pgtype.Text        `json:"request_id"`
+	PromptTemplateID      pgtype.UUID        `json:"prompt_template_id"`
+	PromptTemplateVersion pgtype.Int4        `json:"prompt_template_version"`
+	SchemaValidation      pgtype.Text        `json:"schema_validation"`
+	SchemaAttempt         pgtype.Int4        `json:"schema_attempt"`
+	SchemaErrors          pgtype.Text        `json:"schema_errors"`
+	BatchID               pgtype.UUID        `json:"batch_id"`
+	ReasoningTokens       pgtype.Int8        `json:"reasoning_tokens"`
+	ContentFilter         []byte             `json:"content_filter"`
 }
 
 func (q *Queries) CreateLog(ctx context.Context, arg CreateLogParams) (CreateLogRow, error) {
 	row := q.db.QueryRow(ctx, createLog,
+		arg.ID,
 		arg.UserID,
 		arg.ModelID,
 		arg.RequestPayload,
@@ -82,6 +186,23 @@ func (q *Queries) CreateLog(ctx context.Context, arg CreateLogParams) (CreateLog
 		arg.CompletionTokens,
 		arg.ConnectionID,
 		arg.Type,
+		arg.ApiKeyID,
+		arg.StatusCode,
+		arg.RequestID,
+		arg.PromptTemplateID,
+		arg.PromptTemplateVersion,
+		arg.SchemaValidation,
+		arg.SchemaAttempt,
+		arg.SchemaErrors,
+		arg.BatchID,
+		arg.ReasoningTokens,
+		arg.ContentFilter,
+		arg.LatencyMs,
+		arg.EvalRunID,
+		arg.ShadowOf,
+		arg.ProviderModelID,
+		arg.PriceInput,
+		arg.PriceOutput,
 	)
 	var i CreateLogRow
 	err := row.Scan(
@@ -95,6 +216,111 @@ func (q *Queries) CreateLog(ctx context.Context, arg CreateLogParams) (CreateLog
 		&i.CompletionTokens,
 		&i.ConnectionID,
 		&i.Type,
+		&i.ApiKeyID,
+		&i.StatusCode,
+		&i.RequestID,
+		&i.PromptTemplateID,
+		&i.PromptTemplateVersion,
+		&i.SchemaValidation,
+		&i.SchemaAttempt,
+		&i.SchemaErrors,
+		&i.BatchID,
+		&i.ReasoningTokens,
+		&i.ContentFilter,
+	)
+	return i, err
+}
+
+const getAPIKeySpend = `-- name: GetAPIKeySpend :one
+SELECT COALESCE(SUM(u.prompt_tokens * COALESCE(m.price_input, 0) + u.completion_tokens * COALESCE(m.price_output, 0)), 0)::NUMERIC AS spend
+FROM
+    (
+        SELECT l.model_id, COALESCE(l.prompt_tokens, 0) AS prompt_tokens, COALESCE(l.completion_tokens, 0) AS completion_tokens
+        FROM logs l
+        WHERE l.api_key_id = $1 AND l.created_at >= $2 AND l.shadow_of IS NULL
+        UNION ALL
+        SELECT d.model_id, d.prompt_tokens, d.completion_tokens
+        FROM log_daily_usage d
+        WHERE d.api_key_id = $1 AND d.day >= $2::DATE AND NOT d.shadow
+    ) u
+LEFT JOIN models m ON m.id = u.model_id
+`
+
+type GetAPIKeySpendParams struct {
+	ApiKeyID pgtype.UUID        `json:"api_key_id"`
+	Since    pgtype.Timestamptz `json:"since"`
+}
+
+// Spend of logs purged by retention is read from their daily rollup. since
+// is expected at the start of a UTC day. Mirrored (shadow) requests are not
+// charged to the key.
+func (q *Queries) GetAPIKeySpend(ctx context.Context, arg GetAPIKeySpendParams) (pgtype.Numeric, error) {
+	row := q.db.QueryRow(ctx, getAPIKeySpend, arg.ApiKeyID, arg.Since)
+	var spend pgtype.Numeric
+	err := row.Scan(&spend)
+	return spend, err
+}
+
+const getLog = `-- name: GetLog :one
+SELECT id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type, api_key_id, status_code, request_id, prompt_template_id, prompt_template_version, schema_validation, schema_attempt, schema_errors, batch_id, reasoning_tokens, content_filter
+FROM logs
+WHERE id = $1 AND user_id = $2
+`
+
+type GetLogParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+type GetLogRow struct {
+	ID                    pgtype.UUID        `json:"id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	ModelID               pgtype.UUID        `json:"model_id"`
+	RequestPayload        []byte             `json:"request_payload"`
+	ResponsePayload       []byte             `json:"response_payload"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	PromptTokens          pgtype.Int8        `json:"prompt_tokens"`
+	CompletionTokens      pgtype.Int8        `json:"completion_tokens"`
+	ConnectionID          pgtype.UUID        `json:"connection_id"`
+	Type                  string             `json:"type"`
+	ApiKeyID              pgtype.UUID        `json:"api_key_id"`
+	StatusCode            pgtype.Int4        `json:"status_code"`
+	RequestID             pgtype.Text        `json:"request_id"`
+	PromptTemplateID      pgtype.UUID        `json:"prompt_template_id"`
+	PromptTemplateVersion pgtype.Int4        `json:"prompt_template_version"`
+	SchemaValidation      pgtype.Text        `json:"schema_validation"`
+	SchemaAttempt         pgtype.Int4        `json:"schema_attempt"`
+	SchemaErrors          pgtype.Text        `json:"schema_errors"`
+	BatchID               pgtype.UUID        `json:"batch_id"`
+	ReasoningTokens       pgtype.Int8        `json:"reasoning_tokens"`
+	ContentFilter         []byte             `json:"content_filter"`
+}
+
+func (q *Queries) GetLog(ctx context.Context, arg GetLogParams) (GetLogRow, error) {
+	row := q.db.QueryRow(ctx, getLog, arg.ID, arg.UserID)
+	var i GetLogRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ModelID,
+		&i.RequestPayload,
+		&i.ResponsePayload,
+		&i.CreatedAt,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.ConnectionID,
+		&i.Type,
+		&i.ApiKeyID,
+		&i.StatusCode,
+		&i.RequestID,
+		&i.PromptTemplateID,
+		&i.PromptTemplateVersion,
+		&i.SchemaValidation,
+		&i.SchemaAttempt,
+		&i.SchemaErrors,
+		&i.BatchID,
+		&i.ReasoningTokens,
+		&i.ContentFilter,
 	)
 	return i, err
 }
@@ -109,13 +335,17 @@ SELECT
     conn.name AS connection_name,
     SUM(cl.prompt_tokens) AS total_input_tokens
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -177,13 +407,17 @@ SELECT
     conn.name AS connection_name,
     SUM(cl.completion_tokens) AS total_output_tokens
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -244,17 +478,22 @@ SELECT
     cl.connection_id,
     conn.name AS connection_name,
     SUM(
-        (cl.prompt_tokens * m.price_input) +
-        (cl.completion_tokens * m.price_output)
+        COALESCE(cl.cost,
+            (cl.prompt_tokens * m.price_input) +
+            (cl.completion_tokens * m.price_output))
     )::NUMERIC AS total_price
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens, prompt_tokens * price_input + completion_tokens * price_output AS cost FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens, cost FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -278,6 +517,7 @@ type GetTotalPriceByProviderModelConnectionRow struct {
 	TotalPrice     pgtype.Numeric `json:"total_price"`
 }
 
+// Mirrored requests are priced by their shadow policy, not their model.
 func (q *Queries) GetTotalPriceByProviderModelConnection(ctx context.Context) ([]GetTotalPriceByProviderModelConnectionRow, error) {
 	rows, err := q.db.Query(ctx, getTotalPriceByProviderModelConnection)
 	if err != nil {
@@ -316,13 +556,17 @@ SELECT
     conn.name AS connection_name,
     SUM(cl.prompt_tokens + cl.completion_tokens) AS total_tokens
 FROM
-    logs cl
+    (
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM logs
+        UNION ALL
+        SELECT model_id, connection_id, prompt_tokens, completion_tokens FROM log_daily_usage
+    ) cl
 JOIN
     models m ON cl.model_id = m.id
 JOIN
-    connections conn ON m.connection_id = conn.id
+    connections conn ON conn.id = COALESCE(cl.connection_id, m.connection_id)
 JOIN
-    providers p ON conn.provider_id::uuid = p.id
+    providers p ON p.id::TEXT = conn.provider_id
 GROUP BY
     p.id,
     p.name,
@@ -374,38 +618,181 @@ func (q *Queries) GetTotalTokensByProviderModelConnection(ctx context.Context) (
 	return items, nil
 }
 
+const getUsageReport = `-- name: GetUsageReport :many
+SELECT
+    m.proxy_model_id AS model_name,
+    u.type,
+    SUM(u.request_count)::BIGINT AS request_count,
+    SUM(u.prompt_tokens)::BIGINT AS prompt_tokens,
+    SUM(u.completion_tokens)::BIGINT AS completion_tokens,
+    SUM(COALESCE(u.cost, u.prompt_tokens * m.price_input + u.completion_tokens * m.price_output))::NUMERIC AS cost
+FROM
+    (
+        SELECT l.model_id, l.type, 1::BIGINT AS request_count, COALESCE(l.prompt_tokens, 0) AS prompt_tokens, COALESCE(l.completion_tokens, 0) AS completion_tokens,
+            COALESCE(l.prompt_tokens, 0) * l.price_input + COALESCE(l.completion_tokens, 0) * l.price_output AS cost
+        FROM logs l
+        WHERE l.user_id = $1 AND l.created_at >= $2 AND l.created_at < $3
+        UNION ALL
+        SELECT d.model_id, d.type, d.request_count, d.prompt_tokens, d.completion_tokens, d.cost
+        FROM log_daily_usage d
+        WHERE d.user_id = $1 AND d.day >= $2::DATE AND d.day < $3::DATE
+    ) u
+JOIN models m ON m.id = u.model_id
+GROUP BY m.proxy_model_id, u.type
+ORDER BY m.proxy_model_id, u.type
+`
+
+type GetUsageReportParams struct {
+	UserID pgtype.UUID        `json:"user_id"`
+	Since  pgtype.Timestamptz `json:"since"`
+	Until  pgtype.Timestamptz `json:"until"`
+}
+
+type GetUsageReportRow struct {
+	ModelName        string         `json:"model_name"`
+	Type             string         `json:"type"`
+	RequestCount     int64          `json:"request_count"`
+	PromptTokens     int64          `json:"prompt_tokens"`
+	CompletionTokens int64          `json:"completion_tokens"`
+	Cost             pgtype.Numeric `json:"cost"`
+}
+
+// Mirrored requests are priced by their shadow policy, not their model.
+func (q *Queries) GetUsageReport(ctx context.Context, arg GetUsageReportParams) ([]GetUsageReportRow, error) {
+	rows, err := q.db.Query(ctx, getUsageReport, arg.UserID, arg.Since, arg.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsageReportRow
+	for rows.Next() {
+		var i GetUsageReportRow
+		if err := rows.Scan(
+			&i.ModelName,
+			&i.Type,
+			&i.RequestCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listLogs = `-- name: ListLogs :many
-SELECT id, user_id, model_id, request_payload, response_payload, created_at, prompt_tokens, completion_tokens, connection_id, type
-FROM logs
+SELECT
+    l.id,
+    l.user_id,
+    l.model_id,
+    l.request_payload,
+    l.response_payload,
+    l.created_at,
+    l.prompt_tokens,
+    l.completion_tokens,
+    l.connection_id,
+    l.type,
+    l.api_key_id,
+    l.status_code,
+    l.request_id,
+    l.prompt_template_id,
+    l.prompt_template_version,
+    l.schema_validation,
+    l.schema_attempt,
+    l.schema_errors,
+    l.batch_id,
+    l.reasoning_tokens,
+    l.content_filter,
+    l.shadow_of,
+    l.provider_model_id,
+    conn.provider_id,
+    (COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0))::NUMERIC AS cost
+FROM logs l
+LEFT JOIN models m ON m.id = l.model_id
+LEFT JOIN connections conn ON conn.id = l.connection_id
 WHERE
-    ($1::UUID IS NULL OR user_id = $1) AND
-    ($2::UUID IS NULL OR model_id = $2) AND
-    ($3::UUID IS NULL OR connection_id = $3) AND
-    ($4::TEXT IS NULL OR type = $4)
-ORDER BY created_at DESC
-LIMIT $6::BIGINT OFFSET $5::BIGINT
+    ($1::UUID IS NULL OR l.user_id = $1) AND
+    ($2::UUID IS NULL OR l.model_id = $2) AND
+    ($3::UUID IS NULL OR l.connection_id = $3) AND
+    ($4::UUID IS NULL OR conn.provider_id = $4::UUID::TEXT) AND
+    ($5::UUID IS NULL OR l.api_key_id = $5) AND
+    ($6::TEXT IS NULL OR l.type = $6) AND
+    ($7::TEXT IS NULL OR l.request_id = $7) AND
+    ($8::UUID IS NULL OR l.prompt_template_id = $8) AND
+    ($9::INTEGER IS NULL OR l.prompt_template_version = $9) AND
+    ($10::TEXT IS NULL OR l.schema_validation = $10) AND
+    ($11::UUID IS NULL OR l.batch_id = $11) AND
+    ($12::TIMESTAMPTZ IS NULL OR l.created_at >= $12) AND
+    ($13::TIMESTAMPTZ IS NULL OR l.created_at < $13) AND
+    ($14::TEXT IS NULL OR
+        ($14 = 'success' AND l.status_code < 400) OR
+        ($14 = 'error' AND l.status_code >= 400)) AND
+    ($15::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) >= $15) AND
+    ($16::BIGINT IS NULL OR COALESCE(l.prompt_tokens, 0) + COALESCE(l.completion_tokens, 0) <= $16) AND
+    ($17::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) >= $17) AND
+    ($18::NUMERIC IS NULL OR COALESCE(l.prompt_tokens, 0) * COALESCE(l.price_input, m.price_input, 0) + COALESCE(l.completion_tokens, 0) * COALESCE(l.price_output, m.price_output, 0) <= $18) AND
+    ($19::TEXT IS NULL OR l.search_vector @@ websearch_to_tsquery('simple', $19)) AND
+    ($20::TIMESTAMPTZ IS NULL OR (l.created_at, l.id) < ($20, $21::UUID))
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT $22::BIGINT
 `
 
 type ListLogsParams struct {
-	UserID       pgtype.UUID `json:"user_id"`
-	ModelID      pgtype.UUID `json:"model_id"`
-	ConnectionID pgtype.UUID `json:"connection_id"`
-	Type         pgtype.Text `json:"type"`
-	Offset       pgtype.Int8 `json:"offset"`
-	Limit        pgtype.Int8 `json:"limit"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	ModelID               pgtype.UUID        `json:"model_id"`
+	ConnectionID          pgtype.UUID        `json:"connection_id"`
+	ProviderID            pgtype.UUID        `json:"provider_id"`
+	ApiKeyID              pgtype.UUID        `json:"api_key_id"`
+	Type                  pgtype.Text        `json:"type"`
+	RequestID             pgtype.Text        `json:"request_id"`
+	PromptTemplateID      pgtype.UUID        `json:"prompt_template_id"`
+	PromptTemplateVersion pgtype.Int4        `json:"prompt_template_version"`
+	SchemaValidation      pgtype.Text        `json:"schema_validation"`
+	BatchID               pgtype.UUID        `json:"batch_id"`
+	Since                 pgtype.Timestamptz `json:"since"`
+	Until                 pgtype.Timestamptz `json:"until"`
+	Status                pgtype.Text        `json:"status"`
+	MinTokens             pgtype.Int8        `json:"min_tokens"`
+	MaxTokens             pgtype.Int8        `json:"max_tokens"`
+	MinCost               pgtype.Numeric     `json:"min_cost"`
+	MaxCost               pgtype.Numeric     `json:"max_cost"`
+	Search                pgtype.Text        `json:"search"`
+	CursorCreatedAt       pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorID              pgtype.UUID        `json:"cursor_id"`
+	Limit                 int64              `json:"limit"`
 }
 
 type ListLogsRow struct {
-	ID               pgtype.UUID        `json:"id"`
-	UserID           pgtype.UUID        `json:"user_id"`
-	ModelID          pgtype.UUID        `json:"model_id"`
-	RequestPayload   []byte             `json:"request_payload"`
-	ResponsePayload  []byte             `json:"response_payload"`
-	CreatedAt        pgtype.Timestamptz `json:"created_at"`
-	PromptTokens     pgtype.Int8        `json:"prompt_tokens"`
-	CompletionTokens pgtype.Int8        `json:"completion_tokens"`
-	ConnectionID     pgtype.UUID        `json:"connection_id"`
-	Type             string             `json:"type"`
+	ID                    pgtype.UUID        `json:"id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	ModelID               pgtype.UUID        `json:"model_id"`
+	RequestPayload        []byte             `json:"request_payload"`
+	ResponsePayload       []byte             `json:"response_payload"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	PromptTokens          pgtype.Int8        `json:"prompt_tokens"`
+	CompletionTokens      pgtype.Int8        `json:"completion_tokens"`
+	ConnectionID          pgtype.UUID        `json:"connection_id"`
+	Type                  string             `json:"type"`
+	ApiKeyID              pgtype.UUID        `json:"api_key_id"`
+	StatusCode            pgtype.Int4        `json:"status_code"`
+	RequestID             pgtype.Text        `json:"request_id"`
+	PromptTemplateID      pgtype.UUID        `json:"prompt_template_id"`
+	PromptTemplateVersion pgtype.Int4        `json:"prompt_template_version"`
+	SchemaValidation      pgtype.Text        `json:"schema_validation"`
+	SchemaAttempt         pgtype.Int4        `json:"schema_attempt"`
+	SchemaErrors          pgtype.Text        `json:"schema_errors"`
+	BatchID               pgtype.UUID        `json:"batch_id"`
+	ReasoningTokens       pgtype.Int8        `json:"reasoning_tokens"`
+	ContentFilter         []byte             `json:"content_filter"`
+	ShadowOf              pgtype.UUID        `json:"shadow_of"`
+	ProviderModelID       pgtype.Text        `json:"provider_model_id"`
+	ProviderID            pgtype.Text        `json:"provider_id"`
+	Cost                  pgtype.Numeric     `json:"cost"`
 }
 
 func (q *Queries) ListLogs(ctx context.Context, arg ListLogsParams) ([]ListLogsRow, error) {
@@ -413,8 +800,24 @@ func (q *Queries) ListLogs(ctx context.Context, arg ListLogsParams) ([]ListLogsR
 		arg.UserID,
 		arg.ModelID,
 		arg.ConnectionID,
+		arg.ProviderID,
+		arg.ApiKeyID,
 		arg.Type,
-		arg.Offset,
+		arg.RequestID,
+		arg.PromptTemplateID,
+		arg.PromptTemplateVersion,
+		arg.SchemaValidation,
+		arg.BatchID,
+		arg.Since,
+		arg.Until,
+		arg.Status,
+		arg.MinTokens,
+		arg.MaxTokens,
+		arg.MinCost,
+		arg.MaxCost,
+		arg.Search,
+		arg.CursorCreatedAt,
+		arg.CursorID,
 		arg.Limit,
 	)
 	if err != nil {
@@ -435,6 +838,21 @@ func (q *Queries) ListLogs(ctx context.Context, arg ListLogsParams) ([]ListLogsR
 			&i.CompletionTokens,
 			&i.ConnectionID,
 			&i.Type,
+			&i.ApiKeyID,
+			&i.StatusCode,
+			&i.RequestID,
+			&i.PromptTemplateID,
+			&i.PromptTemplateVersion,
+			&i.SchemaValidation,
+			&i.SchemaAttempt,
+			&i.SchemaErrors,
+			&i.BatchID,
+			&i.ReasoningTokens,
+			&i.ContentFilter,
+			&i.ShadowOf,
+			&i.ProviderModelID,
+			&i.ProviderID,
+			&i.Cost,
 		); err != nil {
 			return nil, err
 		}
@@ -445,3 +863,36 @@ func (q *Queries) ListLogs(ctx context.Context, arg ListLogsParams) ([]ListLogsR
 	}
 	return items, nil
 }
+
+const reindexLogSearch = `-- name: ReindexLogSearch :many
+UPDATE logs l SET request_payload = l.request_payload
+WHERE l.id IN (SELECT b.id FROM logs b WHERE b.id > $1::UUID ORDER BY b.id LIMIT $2::INTEGER)
+RETURNING l.id
+`
+
+type ReindexLogSearchParams struct {
+	After     pgtype.UUID `json:"after"`
+	BatchSize int32       `json:"batch_size"`
+}
+
+// Rewrites the batch of logs following after in ID order, so their search
+// vector is computed again by the current log_search_vector.
+func (q *Queries) ReindexLogSearch(ctx context.Context, arg ReindexLogSearchParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, reindexLogSearch, arg.After, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
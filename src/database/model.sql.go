@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -22,23 +23,35 @@ INSERT INTO models (
     tools_usage,
     price_input,
     price_output,
-    type
+    type,
+    log_policy,
+    managed,
+    param_policy,
+    prompt_template_id,
+    structured_output,
+    shadow
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
-) RETURNING id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
+) RETURNING id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type, log_policy, managed, param_policy, prompt_template_id, structured_output, shadow
 `
 
 type CreateModelParams struct {
-	ID              pgtype.UUID    `json:"id"`
-	UserID          pgtype.UUID    `json:"user_id"`
-	ConnectionID    pgtype.UUID    `json:"connection_id"`
-	ProxyModelID    string         `json:"proxy_model_id"`
-	ProviderModelID string         `json:"provider_model_id"`
-	Thinking        bool           `json:"thinking"`
-	ToolsUsage      bool           `json:"tools_usage"`
-	PriceInput      pgtype.Numeric `json:"price_input"`
-	PriceOutput     pgtype.Numeric `json:"price_output"`
-	Type            string         `json:"type"`
+	ID               pgtype.UUID     `json:"id"`
+	UserID           pgtype.UUID     `json:"user_id"`
+	ConnectionID     pgtype.UUID     `json:"connection_id"`
+	ProxyModelID     string          `json:"proxy_model_id"`
+	ProviderModelID  string          `json:"provider_model_id"`
+	Thinking         bool            `json:"thinking"`
+	ToolsUsage       bool            `json:"tools_usage"`
+	PriceInput       pgtype.Numeric  `json:"price_input"`
+	PriceOutput      pgtype.Numeric  `json:"price_output"`
+	Type             string          `json:"type"`
+	LogPolicy        string          `json:"log_policy"`
+	Managed          bool            `json:"managed"`
+	ParamPolicy      json.RawMessage `json:"param_policy"`
+	PromptTemplateID pgtype.UUID     `json:"prompt_template_id"`
+	StructuredOutput json.RawMessage `json:"structured_output"`
+	Shadow           json.RawMessage `json:"shadow"`
 }
 
 func (q *Queries) CreateModel(ctx context.Context, arg CreateModelParams) (Model, error) {
@@ -53,6 +66,12 @@ func (q *Queries) CreateModel(ctx context.Context, arg CreateModelParams) (Model
 		arg.PriceInput,
 		arg.PriceOutput,
 		arg.Type,
+		arg.LogPolicy,
+		arg.Managed,
+		arg.ParamPolicy,
+		arg.PromptTemplateID,
+		arg.StructuredOutput,
+		arg.Shadow,
 	)
 	var i Model
 	err := row.Scan(
@@ -67,12 +86,18 @@ func (q *Queries) CreateModel(ctx context.Context, arg CreateModelParams) (Model
 		&i.PriceOutput,
 		&i.DeletedAt,
 		&i.Type,
+		&i.LogPolicy,
+		&i.Managed,
+		&i.ParamPolicy,
+		&i.PromptTemplateID,
+		&i.StructuredOutput,
+		&i.Shadow,
 	)
 	return i, err
 }
 
 const getModel = `-- name: GetModel :one
-SELECT id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type FROM models WHERE id = $1 AND user_id = $2
+SELECT id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type, log_policy, managed, param_policy, prompt_template_id, structured_output, shadow FROM models WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type GetModelParams struct {
@@ -95,12 +120,18 @@ func (q *Queries) GetModel(ctx context.Context, arg GetModelParams) (Model, erro
 		&i.PriceOutput,
 		&i.DeletedAt,
 		&i.Type,
+		&i.LogPolicy,
+		&i.Managed,
+		&i.ParamPolicy,
+		&i.PromptTemplateID,
+		&i.StructuredOutput,
+		&i.Shadow,
 	)
 	return i, err
 }
 
 const getModelByProxyModelID = `-- name: GetModelByProxyModelID :one
-SELECT id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type FROM models WHERE proxy_model_id = $1 AND user_id = $2
+SELECT id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type, log_policy, managed, param_policy, prompt_template_id, structured_output, shadow FROM models WHERE proxy_model_id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1
 `
 
 type GetModelByProxyModelIDParams struct {
@@ -123,12 +154,60 @@ func (q *Queries) GetModelByProxyModelID(ctx context.Context, arg GetModelByProx
 		&i.PriceOutput,
 		&i.DeletedAt,
 		&i.Type,
+		&i.LogPolicy,
+		&i.Managed,
+		&i.ParamPolicy,
+		&i.PromptTemplateID,
+		&i.StructuredOutput,
+		&i.Shadow,
 	)
 	return i, err
 }
 
+const listManagedModels = `-- name: ListManagedModels :many
+SELECT id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type, log_policy, managed, param_policy, prompt_template_id, structured_output, shadow FROM models WHERE user_id = $1 AND managed AND deleted_at IS NULL
+`
+
+func (q *Queries) ListManagedModels(ctx context.Context, userID pgtype.UUID) ([]Model, error) {
+	rows, err := q.db.Query(ctx, listManagedModels, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Model
+	for rows.Next() {
+		var i Model
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ConnectionID,
+			&i.ProxyModelID,
+			&i.ProviderModelID,
+			&i.Thinking,
+			&i.ToolsUsage,
+			&i.PriceInput,
+			&i.PriceOutput,
+			&i.DeletedAt,
+			&i.Type,
+			&i.LogPolicy,
+			&i.Managed,
+			&i.ParamPolicy,
+			&i.PromptTemplateID,
+			&i.StructuredOutput,
+			&i.Shadow,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listModels = `-- name: ListModels :many
-SELECT id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type FROM models WHERE user_id = $1 AND deleted_at IS NULL
+SELECT id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type, log_policy, managed, param_policy, prompt_template_id, structured_output, shadow FROM models WHERE user_id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) ListModels(ctx context.Context, userID pgtype.UUID) ([]Model, error) {
@@ -152,6 +231,12 @@ func (q *Queries) ListModels(ctx context.Context, userID pgtype.UUID) ([]Model,
 			&i.PriceOutput,
 			&i.DeletedAt,
 			&i.Type,
+			&i.LogPolicy,
+			&i.Managed,
+			&i.ParamPolicy,
+			&i.PromptTemplateID,
+			&i.StructuredOutput,
+			&i.Shadow,
 		); err != nil {
 			return nil, err
 		}
@@ -188,21 +273,33 @@ SET
     tools_usage = $6,
     price_input = $7,
     price_output = $8,
-    type = $9
+    type = $9,
+    log_policy = $10,
+    connection_id = $11,
+    param_policy = $12,
+    prompt_template_id = $13,
+    structured_output = $14,
+    shadow = $15
 WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type
+RETURNING id, user_id, connection_id, proxy_model_id, provider_model_id, thinking, tools_usage, price_input, price_output, deleted_at, type, log_policy, managed, param_policy, prompt_template_id, structured_output, shadow
 `
 
 type UpdateModelParams struct {
-	ID              pgtype.UUID    `json:"id"`
-	UserID          pgtype.UUID    `json:"user_id"`
-	ProxyModelID    string         `json:"proxy_model_id"`
-	ProviderModelID string         `json:"provider_model_id"`
-	Thinking        bool           `json:"thinking"`
-	ToolsUsage      bool           `json:"tools_usage"`
-	PriceInput      pgtype.Numeric `json:"price_input"`
-	PriceOutput     pgtype.Numeric `json:"price_output"`
-	Type            string         `json:"type"`
+	ID               pgtype.UUID     `json:"id"`
+	UserID           pgtype.UUID     `json:"user_id"`
+	ProxyModelID     string          `json:"proxy_model_id"`
+	ProviderModelID  string          `json:"provider_model_id"`
+	Thinking         bool            `json:"thinking"`
+	ToolsUsage       bool            `json:"tools_usage"`
+	PriceInput       pgtype.Numeric  `json:"price_input"`
+	PriceOutput      pgtype.Numeric  `json:"price_output"`
+	Type             string          `json:"type"`
+	LogPolicy        string          `json:"log_policy"`
+	ConnectionID     pgtype.UUID     `json:"connection_id"`
+	ParamPolicy      json.RawMessage `json:"param_policy"`
+	PromptTemplateID pgtype.UUID     `json:"prompt_template_id"`
+	StructuredOutput json.RawMessage `json:"structured_output"`
+	Shadow           json.RawMessage `json:"shadow"`
 }
 
 func (q *Queries) UpdateModel(ctx context.Context, arg UpdateModelParams) (Model, error) {
@@ -216,6 +313,12 @@ func (q *Queries) UpdateModel(ctx context.Context, arg UpdateModelParams) (Model
 		arg.PriceInput,
 		arg.PriceOutput,
 		arg.Type,
+		arg.LogPolicy,
+		arg.ConnectionID,
+		arg.ParamPolicy,
+		arg.PromptTemplateID,
+		arg.StructuredOutput,
+		arg.Shadow,
 	)
 	var i Model
 	err := row.Scan(
@@ -230,6 +333,12 @@ func (q *Queries) UpdateModel(ctx context.Context, arg UpdateModelParams) (Model
 		&i.PriceOutput,
 		&i.DeletedAt,
 		&i.Type,
+		&i.LogPolicy,
+		&i.Managed,
+		&i.ParamPolicy,
+		&i.PromptTemplateID,
+		&i.StructuredOutput,
+		&i.Shadow,
 	)
 	return i, err
 }
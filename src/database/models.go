@@ -5,16 +5,68 @@
 package database
 
 import (
+	"encoding/json"
+
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type ApiKey struct {
-	ID         pgtype.UUID        `json:"id"`
-	UserID     pgtype.UUID        `json:"user_id"`
-	KeyHash    string             `json:"key_hash"`
-	Name       string             `json:"name"`
-	CreatedAt  pgtype.Timestamptz `json:"created_at"`
-	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	ID            pgtype.UUID        `json:"id"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	KeyHash       string             `json:"key_hash"`
+	Name          string             `json:"name"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	LastUsedAt    pgtype.Timestamptz `json:"last_used_at"`
+	Managed       bool               `json:"managed"`
+	AllowedModels []string           `json:"allowed_models"`
+	MonthlyBudget pgtype.Numeric     `json:"monthly_budget"`
+}
+
+type AuditLog struct {
+	ID           pgtype.UUID        `json:"id"`
+	ActorID      pgtype.UUID        `json:"actor_id"`
+	Action       string             `json:"action"`
+	ResourceType string             `json:"resource_type"`
+	ResourceID   string             `json:"resource_id"`
+	Before       []byte             `json:"before"`
+	After        []byte             `json:"after"`
+	SourceIp     string             `json:"source_ip"`
+	UserAgent    string             `json:"user_agent"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type Batch struct {
+	ID               pgtype.UUID        `json:"id"`
+	UserID           pgtype.UUID        `json:"user_id"`
+	ApiKeyID         pgtype.UUID        `json:"api_key_id"`
+	Endpoint         string             `json:"endpoint"`
+	InputFileID      pgtype.UUID        `json:"input_file_id"`
+	OutputFileID     pgtype.UUID        `json:"output_file_id"`
+	ErrorFileID      pgtype.UUID        `json:"error_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	Metadata         []byte             `json:"metadata"`
+	Errors           []byte             `json:"errors"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	ExpiresAt        pgtype.Timestamptz `json:"expires_at"`
+	CancellingAt     pgtype.Timestamptz `json:"cancelling_at"`
+	FinishedAt       pgtype.Timestamptz `json:"finished_at"`
+}
+
+type BatchRequest struct {
+	ID            pgtype.UUID        `json:"id"`
+	BatchID       pgtype.UUID        `json:"batch_id"`
+	Line          int32              `json:"line"`
+	CustomID      string             `json:"custom_id"`
+	Body          []byte             `json:"body"`
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	ClaimedAt     pgtype.Timestamptz `json:"claimed_at"`
+	StatusCode    pgtype.Int4        `json:"status_code"`
+	RequestID     pgtype.Text        `json:"request_id"`
+	Response      []byte             `json:"response"`
+	Error         pgtype.Text        `json:"error"`
 }
 
 type Connection struct {
@@ -25,42 +77,183 @@ type Connection struct {
 	Name            string             `json:"name"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+	Managed         bool               `json:"managed"`
+}
+
+type EvalResult struct {
+	ID                       pgtype.UUID        `json:"id"`
+	RunID                    pgtype.UUID        `json:"run_id"`
+	Line                     int32              `json:"line"`
+	LogID                    pgtype.UUID        `json:"log_id"`
+	BaselineModelID          pgtype.UUID        `json:"baseline_model_id"`
+	Request                  []byte             `json:"request"`
+	BaselineOutput           pgtype.Text        `json:"baseline_output"`
+	BaselinePromptTokens     pgtype.Int8        `json:"baseline_prompt_tokens"`
+	BaselineCompletionTokens pgtype.Int8        `json:"baseline_completion_tokens"`
+	BaselineLatencyMs        pgtype.Int8        `json:"baseline_latency_ms"`
+	Status                   string             `json:"status"`
+	Attempts                 int32              `json:"attempts"`
+	NextAttemptAt            pgtype.Timestamptz `json:"next_attempt_at"`
+	ClaimedAt                pgtype.Timestamptz `json:"claimed_at"`
+	StatusCode               pgtype.Int4        `json:"status_code"`
+	RequestID                pgtype.Text        `json:"request_id"`
+	Output                   pgtype.Text        `json:"output"`
+	PromptTokens             pgtype.Int8        `json:"prompt_tokens"`
+	CompletionTokens         pgtype.Int8        `json:"completion_tokens"`
+	LatencyMs                pgtype.Int8        `json:"latency_ms"`
+	JudgeScore               pgtype.Int4        `json:"judge_score"`
+	JudgeReason              pgtype.Text        `json:"judge_reason"`
+	Error                    pgtype.Text        `json:"error"`
+}
+
+type EvalRun struct {
+	ID               pgtype.UUID        `json:"id"`
+	UserID           pgtype.UUID        `json:"user_id"`
+	Name             string             `json:"name"`
+	CandidateModel   string             `json:"candidate_model"`
+	CandidateModelID pgtype.UUID        `json:"candidate_model_id"`
+	JudgeModel       pgtype.Text        `json:"judge_model"`
+	JudgePrompt      pgtype.Text        `json:"judge_prompt"`
+	Filter           []byte             `json:"filter"`
+	Status           string             `json:"status"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	CancellingAt     pgtype.Timestamptz `json:"cancelling_at"`
+	FinishedAt       pgtype.Timestamptz `json:"finished_at"`
+}
+
+type File struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Filename  string             `json:"filename"`
+	Purpose   string             `json:"purpose"`
+	Bytes     int64              `json:"bytes"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type FileContent struct {
+	FileID  pgtype.UUID `json:"file_id"`
+	Content []byte      `json:"content"`
 }
 
 type Log struct {
+	ID                    pgtype.UUID        `json:"id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	ModelID               pgtype.UUID        `json:"model_id"`
+	ConnectionID          pgtype.UUID        `json:"connection_id"`
+	RequestPayload        []byte             `json:"request_payload"`
+	ResponsePayload       []byte             `json:"response_payload"`
+	PromptTokens          pgtype.Int8        `json:"prompt_tokens"`
+	CompletionTokens      pgtype.Int8        `json:"completion_tokens"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	Type                  string             `json:"type"`
+	PayloadPurgedAt       pgtype.Timestamptz `json:"payload_purged_at"`
+	ApiKeyID              pgtype.UUID        `json:"api_key_id"`
+	StatusCode            pgtype.Int4        `json:"status_code"`
+	SearchVector          interface{}        `json:"search_vector"`
+	RequestID             pgtype.Text        `json:"request_id"`
+	PromptTemplateID      pgtype.UUID        `json:"prompt_template_id"`
+	PromptTemplateVersion pgtype.Int4        `json:"prompt_template_version"`
+	SchemaValidation      pgtype.Text        `json:"schema_validation"`
+	SchemaAttempt         pgtype.Int4        `json:"schema_attempt"`
+	SchemaErrors          pgtype.Text        `json:"schema_errors"`
+	BatchID               pgtype.UUID        `json:"batch_id"`
+	ReasoningTokens       pgtype.Int8        `json:"reasoning_tokens"`
+	ContentFilter         []byte             `json:"content_filter"`
+	LatencyMs             pgtype.Int8        `json:"latency_ms"`
+	EvalRunID             pgtype.UUID        `json:"eval_run_id"`
+	ShadowOf              pgtype.UUID        `json:"shadow_of"`
+	ProviderModelID       pgtype.Text        `json:"provider_model_id"`
+	PriceInput            pgtype.Numeric     `json:"price_input"`
+	PriceOutput           pgtype.Numeric     `json:"price_output"`
+}
+
+type LogDailyUsage struct {
+	ID               pgtype.UUID    `json:"id"`
+	UserID           pgtype.UUID    `json:"user_id"`
+	ModelID          pgtype.UUID    `json:"model_id"`
+	ConnectionID     pgtype.UUID    `json:"connection_id"`
+	Type             string         `json:"type"`
+	Day              pgtype.Date    `json:"day"`
+	RequestCount     int64          `json:"request_count"`
+	PromptTokens     int64          `json:"prompt_tokens"`
+	CompletionTokens int64          `json:"completion_tokens"`
+	Cost             pgtype.Numeric `json:"cost"`
+	ApiKeyID         pgtype.UUID    `json:"api_key_id"`
+	Shadow           bool           `json:"shadow"`
+}
+
+type Model struct {
 	ID               pgtype.UUID        `json:"id"`
 	UserID           pgtype.UUID        `json:"user_id"`
-	ModelID          pgtype.UUID        `json:"model_id"`
 	ConnectionID     pgtype.UUID        `json:"connection_id"`
-	RequestPayload   []byte             `json:"request_payload"`
-	ResponsePayload  []byte             `json:"response_payload"`
-	PromptTokens     pgtype.Int8        `json:"prompt_tokens"`
-	CompletionTokens pgtype.Int8        `json:"completion_tokens"`
-	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	ProxyModelID     string             `json:"proxy_model_id"`
+	ProviderModelID  string             `json:"provider_model_id"`
+	Thinking         bool               `json:"thinking"`
+	ToolsUsage       bool               `json:"tools_usage"`
+	PriceInput       pgtype.Numeric     `json:"price_input"`
+	PriceOutput      pgtype.Numeric     `json:"price_output"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
 	Type             string             `json:"type"`
+	LogPolicy        string             `json:"log_policy"`
+	Managed          bool               `json:"managed"`
+	ParamPolicy      json.RawMessage    `json:"param_policy"`
+	PromptTemplateID pgtype.UUID        `json:"prompt_template_id"`
+	StructuredOutput json.RawMessage    `json:"structured_output"`
+	Shadow           json.RawMessage    `json:"shadow"`
 }
 
-type Model struct {
-	ID              pgtype.UUID        `json:"id"`
-	UserID          pgtype.UUID        `json:"user_id"`
-	ConnectionID    pgtype.UUID        `json:"connection_id"`
-	ProxyModelID    string             `json:"proxy_model_id"`
-	ProviderModelID string             `json:"provider_model_id"`
-	Thinking        bool               `json:"thinking"`
-	ToolsUsage      bool               `json:"tools_usage"`
-	PriceInput      pgtype.Numeric     `json:"price_input"`
-	PriceOutput     pgtype.Numeric     `json:"price_output"`
-	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
-	Type            string             `json:"type"`
+type PromptTemplate struct {
+	ID            pgtype.UUID        `json:"id"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	Name          string             `json:"name"`
+	ActiveVersion int32              `json:"active_version"`
+	Managed       bool               `json:"managed"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	DeletedAt     pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type PromptTemplateVersion struct {
+	TemplateID   pgtype.UUID        `json:"template_id"`
+	Version      int32              `json:"version"`
+	Mode         string             `json:"mode"`
+	SystemPrompt string             `json:"system_prompt"`
+	Messages     []byte             `json:"messages"`
+	Variables    []byte             `json:"variables"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
 
 type Provider struct {
-	ID        pgtype.UUID        `json:"id"`
+	ID         pgtype.UUID        `json:"id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Name       string             `json:"name"`
+	BaseUrl    string             `json:"base_url"`
+	Type       string             `json:"type"`
+	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
+	Managed    bool               `json:"managed"`
+	ApiVersion string             `json:"api_version"`
+	Region     string             `json:"region"`
+}
+
+type Response struct {
+	ID        string             `json:"id"`
 	UserID    pgtype.UUID        `json:"user_id"`
-	Name      string             `json:"name"`
-	BaseUrl   string             `json:"base_url"`
-	Type      string             `json:"type"`
-	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	Messages  []byte             `json:"messages"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type RetentionPolicy struct {
+	ID             pgtype.UUID        `json:"id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	ModelID        pgtype.UUID        `json:"model_id"`
+	PayloadTtlDays int32              `json:"payload_ttl_days"`
+	RowTtlDays     pgtype.Int4        `json:"row_ttl_days"`
+	Archive        bool               `json:"archive"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+type RoutingVersion struct {
+	ID      int32 `json:"id"`
+	Version int64 `json:"version"`
 }
 
 type User struct {
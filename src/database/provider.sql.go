@@ -17,26 +17,35 @@ INSERT INTO providers (
     user_id,
     name,
     base_url,
-    type
+    type,
+    managed,
+    api_version,
+    region
 ) VALUES (
-    $1, $2, $3, $4, $5
-) RETURNING id, user_id, name, base_url, type
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, user_id, name, base_url, type, managed, api_version, region
 `
 
 type CreateProviderParams struct {
-	ID      pgtype.UUID `json:"id"`
-	UserID  pgtype.UUID `json:"user_id"`
-	Name    string      `json:"name"`
-	BaseUrl string      `json:"base_url"`
-	Type    string      `json:"type"`
+	ID         pgtype.UUID `json:"id"`
+	UserID     pgtype.UUID `json:"user_id"`
+	Name       string      `json:"name"`
+	BaseUrl    string      `json:"base_url"`
+	Type       string      `json:"type"`
+	Managed    bool        `json:"managed"`
+	ApiVersion string      `json:"api_version"`
+	Region     string      `json:"region"`
 }
 
 type CreateProviderRow struct {
-	ID      pgtype.UUID `json:"id"`
-	UserID  pgtype.UUID `json:"user_id"`
-	Name    string      `json:"name"`
-	BaseUrl string      `json:"base_url"`
-	Type    string      `json:"type"`
+	ID         pgtype.UUID `json:"id"`
+	UserID     pgtype.UUID `json:"user_id"`
+	Name       string      `json:"name"`
+	BaseUrl    string      `json:"base_url"`
+	Type       string      `json:"type"`
+	Managed    bool        `json:"managed"`
+	ApiVersion string      `json:"api_version"`
+	Region     string      `json:"region"`
 }
 
 func (q *Queries) CreateProvider(ctx context.Context, arg CreateProviderParams) (CreateProviderRow, error) {
@@ -46,6 +55,9 @@ func (q *Queries) CreateProvider(ctx context.Context, arg CreateProviderParams)
 		arg.Name,
 		arg.BaseUrl,
 		arg.Type,
+		arg.Managed,
+		arg.ApiVersion,
+		arg.Region,
 	)
 	var i CreateProviderRow
 	err := row.Scan(
@@ -54,12 +66,15 @@ func (q *Queries) CreateProvider(ctx context.Context, arg CreateProviderParams)
 		&i.Name,
 		&i.BaseUrl,
 		&i.Type,
+		&i.Managed,
+		&i.ApiVersion,
+		&i.Region,
 	)
 	return i, err
 }
 
 const getProvider = `-- name: GetProvider :one
-SELECT id, user_id, name, base_url, type, deleted_at FROM providers WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type GetProviderParams struct {
@@ -77,12 +92,49 @@ func (q *Queries) GetProvider(ctx context.Context, arg GetProviderParams) (Provi
 		&i.BaseUrl,
 		&i.Type,
 		&i.DeletedAt,
+		&i.Managed,
+		&i.ApiVersion,
+		&i.Region,
 	)
 	return i, err
 }
 
+const listManagedProviders = `-- name: ListManagedProviders :many
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE user_id = $1 AND managed AND deleted_at IS NULL
+`
+
+func (q *Queries) ListManagedProviders(ctx context.Context, userID pgtype.UUID) ([]Provider, error) {
+	rows, err := q.db.Query(ctx, listManagedProviders, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Provider
+	for rows.Next() {
+		var i Provider
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.BaseUrl,
+			&i.Type,
+			&i.DeletedAt,
+			&i.Managed,
+			&i.ApiVersion,
+			&i.Region,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProviders = `-- name: ListProviders :many
-SELECT id, user_id, name, base_url, type, deleted_at FROM providers WHERE user_id = $1 AND deleted_at IS NULL
+SELECT id, user_id, name, base_url, type, deleted_at, managed, api_version, region FROM providers WHERE user_id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) ListProviders(ctx context.Context, userID pgtype.UUID) ([]Provider, error) {
@@ -101,6 +153,9 @@ func (q *Queries) ListProviders(ctx context.Context, userID pgtype.UUID) ([]Prov
 			&i.BaseUrl,
 			&i.Type,
 			&i.DeletedAt,
+			&i.Managed,
+			&i.ApiVersion,
+			&i.Region,
 		); err != nil {
 			return nil, err
 		}
@@ -127,3 +182,50 @@ func (q *Queries) SoftDeleteProvider(ctx context.Context, arg SoftDeleteProvider
 	_, err := q.db.Exec(ctx, softDeleteProvider, arg.ID, arg.UserID)
 	return err
 }
+
+const updateProvider = `-- name: UpdateProvider :one
+UPDATE providers
+SET
+    name = $3,
+    base_url = $4,
+    type = $5,
+    api_version = $6,
+    region = $7
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, base_url, type, deleted_at, managed, api_version, region
+`
+
+type UpdateProviderParams struct {
+	ID         pgtype.UUID `json:"id"`
+	UserID     pgtype.UUID `json:"user_id"`
+	Name       string      `json:"name"`
+	BaseUrl    string      `json:"base_url"`
+	Type       string      `json:"type"`
+	ApiVersion string      `json:"api_version"`
+	Region     string      `json:"region"`
+}
+
+func (q *Queries) UpdateProvider(ctx context.Context, arg UpdateProviderParams) (Provider, error) {
+	row := q.db.QueryRow(ctx, updateProvider,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.BaseUrl,
+		arg.Type,
+		arg.ApiVersion,
+		arg.Region,
+	)
+	var i Provider
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.BaseUrl,
+		&i.Type,
+		&i.DeletedAt,
+		&i.Managed,
+		&i.ApiVersion,
+		&i.Region,
+	)
+	return i, err
+}
@@ -11,39 +11,147 @@ import (
 )
 
 type Querier interface {
+	// Lines already running finish; the batch is finalized once they have.
+	CancelBatch(ctx context.Context, arg CancelBatchParams) (Batch, error)
+	CancelBatchRequests(ctx context.Context, batchID pgtype.UUID) (int64, error)
+	CancelEvalResults(ctx context.Context, runID pgtype.UUID) (int64, error)
+	// Results already running finish; the run is finalized once they have.
+	CancelEvalRun(ctx context.Context, arg CancelEvalRunParams) (EvalRun, error)
+	// Replicas skip lines claimed by others instead of waiting for their lock.
+	ClaimBatchRequest(ctx context.Context) (BatchRequest, error)
+	// Replicas skip results claimed by others instead of waiting for their lock.
+	ClaimEvalResult(ctx context.Context) (EvalResult, error)
+	CountAuditLogs(ctx context.Context, arg CountAuditLogsParams) (int64, error)
+	CountBatchRequests(ctx context.Context, batchID pgtype.UUID) ([]CountBatchRequestsRow, error)
+	CountEvalResults(ctx context.Context, runID pgtype.UUID) ([]CountEvalResultsRow, error)
 	CountLogs(ctx context.Context, arg CountLogsParams) (int64, error)
+	CountModelsUsingPromptTemplate(ctx context.Context, arg CountModelsUsingPromptTemplateParams) (int64, error)
 	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateBatch(ctx context.Context, arg CreateBatchParams) (Batch, error)
+	CreateBatchRequest(ctx context.Context, arg CreateBatchRequestParams) error
 	CreateConnection(ctx context.Context, arg CreateConnectionParams) (CreateConnectionRow, error)
+	CreateEvalResult(ctx context.Context, arg CreateEvalResultParams) error
+	CreateEvalRun(ctx context.Context, arg CreateEvalRunParams) (EvalRun, error)
+	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
+	CreateFileContent(ctx context.Context, arg CreateFileContentParams) error
 	CreateLog(ctx context.Context, arg CreateLogParams) (CreateLogRow, error)
 	CreateModel(ctx context.Context, arg CreateModelParams) (Model, error)
+	CreatePromptTemplate(ctx context.Context, arg CreatePromptTemplateParams) (PromptTemplate, error)
+	// Versions are numbered per template; two concurrent writers get a unique
+	// violation rather than the same number.
+	CreatePromptTemplateVersion(ctx context.Context, arg CreatePromptTemplateVersionParams) (PromptTemplateVersion, error)
 	CreateProvider(ctx context.Context, arg CreateProviderParams) (CreateProviderRow, error)
+	CreateResponse(ctx context.Context, arg CreateResponseParams) error
+	CreateRetentionPolicy(ctx context.Context, arg CreateRetentionPolicyParams) (RetentionPolicy, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) error
+	DeleteFile(ctx context.Context, arg DeleteFileParams) (int64, error)
+	DeleteLogs(ctx context.Context, ids []pgtype.UUID) (int64, error)
+	DeleteRetentionPolicy(ctx context.Context, arg DeleteRetentionPolicyParams) error
+	ExpireBatchRequests(ctx context.Context) (int64, error)
+	// The status guard makes finalization happen once when replicas race.
+	FinishBatch(ctx context.Context, arg FinishBatchParams) (Batch, error)
+	FinishBatchRequest(ctx context.Context, arg FinishBatchRequestParams) error
+	FinishEvalResult(ctx context.Context, arg FinishEvalResultParams) error
+	// The status guard makes finalization happen once when replicas race.
+	FinishEvalRun(ctx context.Context, arg FinishEvalRunParams) (EvalRun, error)
 	GetAPIKey(ctx context.Context, arg GetAPIKeyParams) (ApiKey, error)
 	GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error)
+	GetAPIKeyByID(ctx context.Context, arg GetAPIKeyByIDParams) (ApiKey, error)
+	// Spend of logs purged by retention is read from their daily rollup. since
+	// is expected at the start of a UTC day. Mirrored (shadow) requests are not
+	// charged to the key.
+	GetAPIKeySpend(ctx context.Context, arg GetAPIKeySpendParams) (pgtype.Numeric, error)
+	GetBatch(ctx context.Context, arg GetBatchParams) (Batch, error)
+	GetBatchByID(ctx context.Context, id pgtype.UUID) (Batch, error)
 	GetConnection(ctx context.Context, arg GetConnectionParams) (GetConnectionRow, error)
 	GetConnectionByProvider(ctx context.Context, arg GetConnectionByProviderParams) (Connection, error)
+	GetEvalRun(ctx context.Context, arg GetEvalRunParams) (EvalRun, error)
+	GetEvalRunByID(ctx context.Context, id pgtype.UUID) (EvalRun, error)
+	GetFile(ctx context.Context, arg GetFileParams) (File, error)
+	GetFileContent(ctx context.Context, arg GetFileContentParams) ([]byte, error)
+	GetLog(ctx context.Context, arg GetLogParams) (GetLogRow, error)
 	GetModel(ctx context.Context, arg GetModelParams) (Model, error)
 	GetModelByProxyModelID(ctx context.Context, arg GetModelByProxyModelIDParams) (Model, error)
+	GetPromptTemplate(ctx context.Context, arg GetPromptTemplateParams) (PromptTemplate, error)
+	GetPromptTemplateByName(ctx context.Context, arg GetPromptTemplateByNameParams) (PromptTemplate, error)
+	GetPromptTemplateVersion(ctx context.Context, arg GetPromptTemplateVersionParams) (PromptTemplateVersion, error)
 	GetProvider(ctx context.Context, arg GetProviderParams) (Provider, error)
+	GetResponse(ctx context.Context, arg GetResponseParams) (Response, error)
+	GetRetentionPolicy(ctx context.Context, arg GetRetentionPolicyParams) (RetentionPolicy, error)
+	GetRoutingVersion(ctx context.Context) (int64, error)
 	GetTotalInputTokensByProviderModelConnection(ctx context.Context) ([]GetTotalInputTokensByProviderModelConnectionRow, error)
 	GetTotalOutputTokensByProviderModelConnection(ctx context.Context) ([]GetTotalOutputTokensByProviderModelConnectionRow, error)
+	// Mirrored requests are priced by their shadow policy, not their model.
 	GetTotalPriceByProviderModelConnection(ctx context.Context) ([]GetTotalPriceByProviderModelConnectionRow, error)
 	GetTotalTokensByProviderModelConnection(ctx context.Context) ([]GetTotalTokensByProviderModelConnectionRow, error)
+	// Mirrored requests are priced by their shadow policy, not their model.
+	GetUsageReport(ctx context.Context, arg GetUsageReportParams) ([]GetUsageReportRow, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
 	GetUserPasswordHash(ctx context.Context, username string) (string, error)
 	ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]ListAPIKeysRow, error)
+	ListActivePromptTemplates(ctx context.Context) ([]ListActivePromptTemplatesRow, error)
+	ListAllConnections(ctx context.Context) ([]Connection, error)
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
+	ListBatchRequests(ctx context.Context, batchID pgtype.UUID) ([]BatchRequest, error)
+	ListBatches(ctx context.Context, arg ListBatchesParams) ([]Batch, error)
 	ListConnections(ctx context.Context, userID pgtype.UUID) ([]ListConnectionsRow, error)
 	ListConnectionsByProviderID(ctx context.Context, arg ListConnectionsByProviderIDParams) ([]Connection, error)
+	// Costs are priced like conversation logs, with the models' current prices.
+	ListEvalResults(ctx context.Context, arg ListEvalResultsParams) ([]ListEvalResultsRow, error)
+	ListEvalRuns(ctx context.Context, arg ListEvalRunsParams) ([]EvalRun, error)
+	ListFiles(ctx context.Context, arg ListFilesParams) ([]File, error)
+	ListFinishedBatches(ctx context.Context) ([]Batch, error)
+	ListFinishedEvalRuns(ctx context.Context) ([]EvalRun, error)
 	ListLogs(ctx context.Context, arg ListLogsParams) ([]ListLogsRow, error)
+	// A row whose payload was purged was archived at the payload stage, if at
+	// all, so it is only rolled up.
+	ListLogsPastRowTTL(ctx context.Context, limit int32) ([]ListLogsPastRowTTLRow, error)
+	ListLogsWithExpiredPayloads(ctx context.Context, limit int32) ([]ListLogsWithExpiredPayloadsRow, error)
+	ListManagedAPIKeys(ctx context.Context, userID pgtype.UUID) ([]ApiKey, error)
+	ListManagedConnections(ctx context.Context, userID pgtype.UUID) ([]Connection, error)
+	ListManagedModels(ctx context.Context, userID pgtype.UUID) ([]Model, error)
+	ListManagedPromptTemplates(ctx context.Context, userID pgtype.UUID) ([]PromptTemplate, error)
+	ListManagedProviders(ctx context.Context, userID pgtype.UUID) ([]Provider, error)
 	ListModels(ctx context.Context, userID pgtype.UUID) ([]Model, error)
+	ListPromptTemplateVersions(ctx context.Context, templateID pgtype.UUID) ([]PromptTemplateVersion, error)
+	ListPromptTemplates(ctx context.Context, userID pgtype.UUID) ([]PromptTemplate, error)
 	ListProviders(ctx context.Context, userID pgtype.UUID) ([]Provider, error)
+	ListRetentionPolicies(ctx context.Context, userID pgtype.UUID) ([]RetentionPolicy, error)
+	// Connections shadow traffic can be mirrored to, with their provider.
+	ListRouteConnections(ctx context.Context) ([]ListRouteConnectionsRow, error)
+	ListRoutes(ctx context.Context) ([]ListRoutesRow, error)
+	// Mirrored requests next to the requests they mirror, per model and shadow
+	// target. Only the first upstream attempt of either side is linked, so
+	// structured output retries are not compared.
+	ListShadowComparisons(ctx context.Context, arg ListShadowComparisonsParams) ([]ListShadowComparisonsRow, error)
+	PurgeLogPayloads(ctx context.Context, ids []pgtype.UUID) (int64, error)
+	// Rewrites the batch of logs following after in ID order, so their search
+	// vector is computed again by the current log_search_vector.
+	ReindexLogSearch(ctx context.Context, arg ReindexLogSearchParams) ([]pgtype.UUID, error)
+	ReleaseBatchRequest(ctx context.Context, id pgtype.UUID) error
+	ReleaseEvalResult(ctx context.Context, id pgtype.UUID) error
+	RequeueStaleBatchRequests(ctx context.Context, claimedBefore pgtype.Timestamptz) (int64, error)
+	RequeueStaleEvalResults(ctx context.Context, claimedBefore pgtype.Timestamptz) (int64, error)
+	// Only the logs of mirrored requests carry prices, so only shadow buckets
+	// get a cost; other buckets are priced by their model when read.
+	RollupLogs(ctx context.Context, ids []pgtype.UUID) error
+	// Successful chat logs with their payloads, in random order. Requests made
+	// by evaluation runs are left out.
+	SampleEvalLogs(ctx context.Context, arg SampleEvalLogsParams) ([]SampleEvalLogsRow, error)
+	SetPromptTemplateActiveVersion(ctx context.Context, arg SetPromptTemplateActiveVersionParams) (PromptTemplate, error)
 	SoftDeleteConnection(ctx context.Context, arg SoftDeleteConnectionParams) error
 	SoftDeleteModel(ctx context.Context, arg SoftDeleteModelParams) error
+	SoftDeletePromptTemplate(ctx context.Context, arg SoftDeletePromptTemplateParams) error
 	SoftDeleteProvider(ctx context.Context, arg SoftDeleteProviderParams) error
 	UpdateAPIKey(ctx context.Context, arg UpdateAPIKeyParams) (ApiKey, error)
 	UpdateAPIKeyLastUsed(ctx context.Context, id pgtype.UUID) error
+	UpdateAPIKeyPolicy(ctx context.Context, arg UpdateAPIKeyPolicyParams) (ApiKey, error)
+	UpdateConnection(ctx context.Context, arg UpdateConnectionParams) (Connection, error)
 	UpdateModel(ctx context.Context, arg UpdateModelParams) (Model, error)
+	UpdateProvider(ctx context.Context, arg UpdateProviderParams) (Provider, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
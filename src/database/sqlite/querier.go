@@ -0,0 +1,1047 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gen-ai-proxy/src/database"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// querier implements database.Querier on top of the generated queries. Most
+// parameter and row types are identical to the Postgres ones and are
+// converted directly; the rest differ where SQLite has no equivalent type.
+type querier struct {
+	q *Queries
+}
+
+var _ database.Querier = querier{}
+
+// all converts every row of a generated :many query.
+func all[From, To any](items []From, err error, convert func(From) To) ([]To, error) {
+	if err != nil || items == nil {
+		return nil, err
+	}
+	out := make([]To, len(items))
+	for i, item := range items {
+		out[i] = convert(item)
+	}
+	return out, nil
+}
+
+// timestamp passes a time argument as text; the queries normalise it to the
+// stored UTC layout before comparing.
+func timestamp(t pgtype.Timestamptz) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC().Format(time.RFC3339Nano)
+}
+
+// decimal passes a numeric argument to a query that compares it with
+// computed costs, which SQLite evaluates as floating point.
+func decimal(n pgtype.Numeric) sql.NullFloat64 {
+	if !n.Valid {
+		return sql.NullFloat64{}
+	}
+	f, err := n.Float64Value()
+	return sql.NullFloat64{Float64: f.Float64, Valid: err == nil}
+}
+
+// numeric converts a computed cost back to the type Postgres returns.
+func numeric(f float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	if err := n.ScanScientific(strconv.FormatFloat(f, 'f', -1, 64)); err != nil {
+		return pgtype.Numeric{}
+	}
+	return n
+}
+
+// uuidText matches a UUID against a column that stores it as plain text.
+func uuidText(id pgtype.UUID) pgtype.Text {
+	return pgtype.Text{String: id.String(), Valid: id.Valid}
+}
+
+// allowedModels and modelList store an API key's model allow-list as a JSON
+// array, since SQLite has no array type. NULL allows every model.
+func allowedModels(models []string) (sql.NullString, error) {
+	if models == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(models)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func modelList(s sql.NullString) ([]string, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	var models []string
+	if err := json.Unmarshal([]byte(s.String), &models); err != nil {
+		return nil, fmt.Errorf("invalid allowed_models %q: %w", s.String, err)
+	}
+	return models, nil
+}
+
+func apiKey(k ApiKey, err error) (database.ApiKey, error) {
+	if err != nil {
+		return database.ApiKey{}, err
+	}
+	models, err := modelList(k.AllowedModels)
+	if err != nil {
+		return database.ApiKey{}, err
+	}
+	return database.ApiKey{
+		ID:            k.ID,
+		UserID:        k.UserID,
+		KeyHash:       k.KeyHash,
+		Name:          k.Name,
+		CreatedAt:     k.CreatedAt,
+		LastUsedAt:    k.LastUsedAt,
+		Managed:       k.Managed,
+		AllowedModels: models,
+		MonthlyBudget: k.MonthlyBudget,
+	}, nil
+}
+
+func apiKeys(keys []ApiKey, err error) ([]database.ApiKey, error) {
+	if err != nil || keys == nil {
+		return nil, err
+	}
+	out := make([]database.ApiKey, len(keys))
+	for i, k := range keys {
+		if out[i], err = apiKey(k, nil); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// API keys
+
+func (s querier) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	models, err := allowedModels(arg.AllowedModels)
+	if err != nil {
+		return database.ApiKey{}, err
+	}
+	return apiKey(s.q.CreateAPIKey(ctx, CreateAPIKeyParams{
+		UserID:        arg.UserID,
+		KeyHash:       arg.KeyHash,
+		Name:          arg.Name,
+		Managed:       arg.Managed,
+		AllowedModels: models,
+		MonthlyBudget: arg.MonthlyBudget,
+	}))
+}
+
+func (s querier) DeleteAPIKey(ctx context.Context, arg database.DeleteAPIKeyParams) error {
+	return s.q.DeleteAPIKey(ctx, DeleteAPIKeyParams(arg))
+}
+
+func (s querier) GetAPIKey(ctx context.Context, arg database.GetAPIKeyParams) (database.ApiKey, error) {
+	return apiKey(s.q.GetAPIKey(ctx, GetAPIKeyParams(arg)))
+}
+
+func (s querier) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+	return apiKey(s.q.GetAPIKeyByHash(ctx, keyHash))
+}
+
+func (s querier) GetAPIKeyByID(ctx context.Context, arg database.GetAPIKeyByIDParams) (database.ApiKey, error) {
+	return apiKey(s.q.GetAPIKeyByID(ctx, GetAPIKeyByIDParams(arg)))
+}
+
+func (s querier) ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]database.ListAPIKeysRow, error) {
+	rows, err := s.q.ListAPIKeys(ctx, userID)
+	if err != nil || rows == nil {
+		return nil, err
+	}
+	out := make([]database.ListAPIKeysRow, len(rows))
+	for i, r := range rows {
+		models, err := modelList(r.AllowedModels)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = database.ListAPIKeysRow{
+			ID:            r.ID,
+			UserID:        r.UserID,
+			Name:          r.Name,
+			CreatedAt:     r.CreatedAt,
+			LastUsedAt:    r.LastUsedAt,
+			Managed:       r.Managed,
+			AllowedModels: models,
+			MonthlyBudget: r.MonthlyBudget,
+		}
+	}
+	return out, nil
+}
+
+func (s querier) ListManagedAPIKeys(ctx context.Context, userID pgtype.UUID) ([]database.ApiKey, error) {
+	return apiKeys(s.q.ListManagedAPIKeys(ctx, userID))
+}
+
+func (s querier) UpdateAPIKey(ctx context.Context, arg database.UpdateAPIKeyParams) (database.ApiKey, error) {
+	return apiKey(s.q.UpdateAPIKey(ctx, UpdateAPIKeyParams(arg)))
+}
+
+func (s querier) UpdateAPIKeyLastUsed(ctx context.Context, id pgtype.UUID) error {
+	return s.q.UpdateAPIKeyLastUsed(ctx, id)
+}
+
+func (s querier) UpdateAPIKeyPolicy(ctx context.Context, arg database.UpdateAPIKeyPolicyParams) (database.ApiKey, error) {
+	models, err := allowedModels(arg.AllowedModels)
+	if err != nil {
+		return database.ApiKey{}, err
+	}
+	return apiKey(s.q.UpdateAPIKeyPolicy(ctx, UpdateAPIKeyPolicyParams{
+		ID:            arg.ID,
+		UserID:        arg.UserID,
+		KeyHash:       arg.KeyHash,
+		AllowedModels: models,
+		MonthlyBudget: arg.MonthlyBudget,
+	}))
+}
+
+// Audit logs
+
+func (s querier) CountAuditLogs(ctx context.Context, arg database.CountAuditLogsParams) (int64, error) {
+	return s.q.CountAuditLogs(ctx, CountAuditLogsParams{
+		ActorID:      arg.ActorID,
+		Action:       arg.Action,
+		ResourceType: arg.ResourceType,
+		ResourceID:   arg.ResourceID,
+		Since:        timestamp(arg.Since),
+		Until:        timestamp(arg.Until),
+	})
+}
+
+func (s querier) CreateAuditLog(ctx context.Context, arg database.CreateAuditLogParams) (database.AuditLog, error) {
+	log, err := s.q.CreateAuditLog(ctx, CreateAuditLogParams(arg))
+	return database.AuditLog(log), err
+}
+
+func (s querier) ListAuditLogs(ctx context.Context, arg database.ListAuditLogsParams) ([]database.AuditLog, error) {
+	logs, err := s.q.ListAuditLogs(ctx, ListAuditLogsParams{
+		ActorID:      arg.ActorID,
+		Action:       arg.Action,
+		ResourceType: arg.ResourceType,
+		ResourceID:   arg.ResourceID,
+		Since:        timestamp(arg.Since),
+		Until:        timestamp(arg.Until),
+		Offset:       arg.Offset,
+		Limit:        arg.Limit,
+	})
+	return all(logs, err, func(l AuditLog) database.AuditLog { return database.AuditLog(l) })
+}
+
+// Batches
+
+func (s querier) CancelBatch(ctx context.Context, arg database.CancelBatchParams) (database.Batch, error) {
+	batch, err := s.q.CancelBatch(ctx, CancelBatchParams(arg))
+	return database.Batch(batch), err
+}
+
+func (s querier) CancelBatchRequests(ctx context.Context, batchID pgtype.UUID) (int64, error) {
+	return s.q.CancelBatchRequests(ctx, batchID)
+}
+
+func (s querier) ClaimBatchRequest(ctx context.Context) (database.BatchRequest, error) {
+	request, err := s.q.ClaimBatchRequest(ctx)
+	return database.BatchRequest(request), err
+}
+
+func (s querier) CountBatchRequests(ctx context.Context, batchID pgtype.UUID) ([]database.CountBatchRequestsRow, error) {
+	rows, err := s.q.CountBatchRequests(ctx, batchID)
+	return all(rows, err, func(r CountBatchRequestsRow) database.CountBatchRequestsRow { return database.CountBatchRequestsRow(r) })
+}
+
+func (s querier) CreateBatch(ctx context.Context, arg database.CreateBatchParams) (database.Batch, error) {
+	batch, err := s.q.CreateBatch(ctx, CreateBatchParams{
+		ID:               arg.ID,
+		UserID:           arg.UserID,
+		ApiKeyID:         arg.ApiKeyID,
+		Endpoint:         arg.Endpoint,
+		InputFileID:      arg.InputFileID,
+		CompletionWindow: arg.CompletionWindow,
+		Status:           arg.Status,
+		Metadata:         arg.Metadata,
+		Errors:           arg.Errors,
+		ExpiresAt:        timestamp(arg.ExpiresAt),
+		FinishedAt:       timestamp(arg.FinishedAt),
+	})
+	return database.Batch(batch), err
+}
+
+func (s querier) CreateBatchRequest(ctx context.Context, arg database.CreateBatchRequestParams) error {
+	return s.q.CreateBatchRequest(ctx, CreateBatchRequestParams(arg))
+}
+
+func (s querier) ExpireBatchRequests(ctx context.Context) (int64, error) {
+	return s.q.ExpireBatchRequests(ctx)
+}
+
+func (s querier) FinishBatch(ctx context.Context, arg database.FinishBatchParams) (database.Batch, error) {
+	batch, err := s.q.FinishBatch(ctx, FinishBatchParams(arg))
+	return database.Batch(batch), err
+}
+
+func (s querier) FinishBatchRequest(ctx context.Context, arg database.FinishBatchRequestParams) error {
+	return s.q.FinishBatchRequest(ctx, FinishBatchRequestParams{
+		Status:        arg.Status,
+		NextAttemptAt: timestamp(arg.NextAttemptAt),
+		StatusCode:    arg.StatusCode,
+		RequestID:     arg.RequestID,
+		Response:      arg.Response,
+		Error:         arg.Error,
+		ID:            arg.ID,
+	})
+}
+
+func (s querier) GetBatch(ctx context.Context, arg database.GetBatchParams) (database.Batch, error) {
+	batch, err := s.q.GetBatch(ctx, GetBatchParams(arg))
+	return database.Batch(batch), err
+}
+
+func (s querier) GetBatchByID(ctx context.Context, id pgtype.UUID) (database.Batch, error) {
+	batch, err := s.q.GetBatchByID(ctx, id)
+	return database.Batch(batch), err
+}
+
+func (s querier) ListBatchRequests(ctx context.Context, batchID pgtype.UUID) ([]database.BatchRequest, error) {
+	requests, err := s.q.ListBatchRequests(ctx, batchID)
+	return all(requests, err, func(r BatchRequest) database.BatchRequest { return database.BatchRequest(r) })
+}
+
+func (s querier) ListBatches(ctx context.Context, arg database.ListBatchesParams) ([]database.Batch, error) {
+	batches, err := s.q.ListBatches(ctx, ListBatchesParams{
+		UserID:          arg.UserID,
+		CursorCreatedAt: timestamp(arg.CursorCreatedAt),
+		CursorID:        arg.CursorID,
+		Limit:           arg.Limit,
+	})
+	return all(batches, err, func(b Batch) database.Batch { return database.Batch(b) })
+}
+
+func (s querier) ListFinishedBatches(ctx context.Context) ([]database.Batch, error) {
+	batches, err := s.q.ListFinishedBatches(ctx)
+	return all(batches, err, func(b Batch) database.Batch { return database.Batch(b) })
+}
+
+func (s querier) ReleaseBatchRequest(ctx context.Context, id pgtype.UUID) error {
+	return s.q.ReleaseBatchRequest(ctx, id)
+}
+
+func (s querier) RequeueStaleBatchRequests(ctx context.Context, claimedBefore pgtype.Timestamptz) (int64, error) {
+	return s.q.RequeueStaleBatchRequests(ctx, timestamp(claimedBefore))
+}
+
+// Connections
+
+func (s querier) CreateConnection(ctx context.Context, arg database.CreateConnectionParams) (database.CreateConnectionRow, error) {
+	row, err := s.q.CreateConnection(ctx, CreateConnectionParams(arg))
+	return database.CreateConnectionRow(row), err
+}
+
+func (s querier) GetConnection(ctx context.Context, arg database.GetConnectionParams) (database.GetConnectionRow, error) {
+	row, err := s.q.GetConnection(ctx, GetConnectionParams(arg))
+	return database.GetConnectionRow(row), err
+}
+
+func (s querier) GetConnectionByProvider(ctx context.Context, arg database.GetConnectionByProviderParams) (database.Connection, error) {
+	conn, err := s.q.GetConnectionByProvider(ctx, GetConnectionByProviderParams(arg))
+	return database.Connection(conn), err
+}
+
+func (s querier) ListAllConnections(ctx context.Context) ([]database.Connection, error) {
+	conns, err := s.q.ListAllConnections(ctx)
+	return all(conns, err, func(c Connection) database.Connection { return database.Connection(c) })
+}
+
+func (s querier) ListConnections(ctx context.Context, userID pgtype.UUID) ([]database.ListConnectionsRow, error) {
+	rows, err := s.q.ListConnections(ctx, userID)
+	return all(rows, err, func(r ListConnectionsRow) database.ListConnectionsRow { return database.ListConnectionsRow(r) })
+}
+
+func (s querier) ListConnectionsByProviderID(ctx context.Context, arg database.ListConnectionsByProviderIDParams) ([]database.Connection, error) {
+	conns, err := s.q.ListConnectionsByProviderID(ctx, ListConnectionsByProviderIDParams(arg))
+	return all(conns, err, func(c Connection) database.Connection { return database.Connection(c) })
+}
+
+func (s querier) ListManagedConnections(ctx context.Context, userID pgtype.UUID) ([]database.Connection, error) {
+	conns, err := s.q.ListManagedConnections(ctx, userID)
+	return all(conns, err, func(c Connection) database.Connection { return database.Connection(c) })
+}
+
+func (s querier) SoftDeleteConnection(ctx context.Context, arg database.SoftDeleteConnectionParams) error {
+	return s.q.SoftDeleteConnection(ctx, SoftDeleteConnectionParams(arg))
+}
+
+func (s querier) UpdateConnection(ctx context.Context, arg database.UpdateConnectionParams) (database.Connection, error) {
+	conn, err := s.q.UpdateConnection(ctx, UpdateConnectionParams(arg))
+	return database.Connection(conn), err
+}
+
+// Conversation logs
+
+func (s querier) CountLogs(ctx context.Context, arg database.CountLogsParams) (int64, error) {
+	return s.q.CountLogs(ctx, CountLogsParams{
+		UserID:                arg.UserID,
+		ModelID:               arg.ModelID,
+		ConnectionID:          arg.ConnectionID,
+		ProviderID:            uuidText(arg.ProviderID),
+		ApiKeyID:              arg.ApiKeyID,
+		Type:                  arg.Type,
+		RequestID:             arg.RequestID,
+		Since:                 timestamp(arg.Since),
+		Until:                 timestamp(arg.Until),
+		Status:                arg.Status,
+		MinTokens:             arg.MinTokens,
+		MaxTokens:             arg.MaxTokens,
+		MinCost:               decimal(arg.MinCost),
+		MaxCost:               decimal(arg.MaxCost),
+		Search:                arg.Search,
+		PromptTemplateID:      arg.PromptTemplateID,
+		PromptTemplateVersion: arg.PromptTemplateVersion,
+		SchemaValidation:      arg.SchemaValidation,
+		BatchID:               arg.BatchID,
+	})
+}
+
+func (s querier) CreateLog(ctx context.Context, arg database.CreateLogParams) (database.CreateLogRow, error) {
+	row, err := s.q.CreateLog(ctx, CreateLogParams(arg))
+	return database.CreateLogRow(row), err
+}
+
+func (s querier) GetAPIKeySpend(ctx context.Context, arg database.GetAPIKeySpendParams) (pgtype.Numeric, error) {
+	spend, err := s.q.GetAPIKeySpend(ctx, GetAPIKeySpendParams{
+		ApiKeyID: arg.ApiKeyID,
+		Since:    timestamp(arg.Since),
+	})
+	if err != nil {
+		return pgtype.Numeric{}, err
+	}
+	return numeric(spend), nil
+}
+
+func (s querier) GetLog(ctx context.Context, arg database.GetLogParams) (database.GetLogRow, error) {
+	row, err := s.q.GetLog(ctx, GetLogParams(arg))
+	return database.GetLogRow(row), err
+}
+
+func (s querier) GetTotalInputTokensByProviderModelConnection(ctx context.Context) ([]database.GetTotalInputTokensByProviderModelConnectionRow, error) {
+	rows, err := s.q.GetTotalInputTokensByProviderModelConnection(ctx)
+	return all(rows, err, func(r GetTotalInputTokensByProviderModelConnectionRow) database.GetTotalInputTokensByProviderModelConnectionRow {
+		return database.GetTotalInputTokensByProviderModelConnectionRow(r)
+	})
+}
+
+func (s querier) GetTotalOutputTokensByProviderModelConnection(ctx context.Context) ([]database.GetTotalOutputTokensByProviderModelConnectionRow, error) {
+	rows, err := s.q.GetTotalOutputTokensByProviderModelConnection(ctx)
+	return all(rows, err, func(r GetTotalOutputTokensByProviderModelConnectionRow) database.GetTotalOutputTokensByProviderModelConnectionRow {
+		return database.GetTotalOutputTokensByProviderModelConnectionRow(r)
+	})
+}
+
+func (s querier) GetTotalPriceByProviderModelConnection(ctx context.Context) ([]database.GetTotalPriceByProviderModelConnectionRow, error) {
+	rows, err := s.q.GetTotalPriceByProviderModelConnection(ctx)
+	return all(rows, err, func(r GetTotalPriceByProviderModelConnectionRow) database.GetTotalPriceByProviderModelConnectionRow {
+		return database.GetTotalPriceByProviderModelConnectionRow{
+			ProviderID:     r.ProviderID,
+			ProviderName:   r.ProviderName,
+			ModelID:        r.ModelID,
+			ModelName:      r.ModelName,
+			ConnectionID:   r.ConnectionID,
+			ConnectionName: r.ConnectionName,
+			TotalPrice:     numeric(r.TotalPrice),
+		}
+	})
+}
+
+func (s querier) GetTotalTokensByProviderModelConnection(ctx context.Context) ([]database.GetTotalTokensByProviderModelConnectionRow, error) {
+	rows, err := s.q.GetTotalTokensByProviderModelConnection(ctx)
+	return all(rows, err, func(r GetTotalTokensByProviderModelConnectionRow) database.GetTotalTokensByProviderModelConnectionRow {
+		return database.GetTotalTokensByProviderModelConnectionRow(r)
+	})
+}
+
+func (s querier) GetUsageReport(ctx context.Context, arg database.GetUsageReportParams) ([]database.GetUsageReportRow, error) {
+	rows, err := s.q.GetUsageReport(ctx, GetUsageReportParams{
+		UserID: arg.UserID,
+		Since:  timestamp(arg.Since),
+		Until:  timestamp(arg.Until),
+	})
+	return all(rows, err, func(r GetUsageReportRow) database.GetUsageReportRow {
+		return database.GetUsageReportRow{
+			ModelName:        r.ModelName,
+			Type:             r.Type,
+			RequestCount:     r.RequestCount,
+			PromptTokens:     r.PromptTokens,
+			CompletionTokens: r.CompletionTokens,
+			Cost:             numeric(r.Cost),
+		}
+	})
+}
+
+func (s querier) ListLogs(ctx context.Context, arg database.ListLogsParams) ([]database.ListLogsRow, error) {
+	rows, err := s.q.ListLogs(ctx, ListLogsParams{
+		UserID:                arg.UserID,
+		ModelID:               arg.ModelID,
+		ConnectionID:          arg.ConnectionID,
+		ProviderID:            uuidText(arg.ProviderID),
+		ApiKeyID:              arg.ApiKeyID,
+		Type:                  arg.Type,
+		RequestID:             arg.RequestID,
+		Since:                 timestamp(arg.Since),
+		Until:                 timestamp(arg.Until),
+		Status:                arg.Status,
+		MinTokens:             arg.MinTokens,
+		MaxTokens:             arg.MaxTokens,
+		MinCost:               decimal(arg.MinCost),
+		MaxCost:               decimal(arg.MaxCost),
+		Search:                arg.Search,
+		CursorCreatedAt:       timestamp(arg.CursorCreatedAt),
+		CursorID:              arg.CursorID,
+		Limit:                 arg.Limit,
+		PromptTemplateID:      arg.PromptTemplateID,
+		PromptTemplateVersion: arg.PromptTemplateVersion,
+		SchemaValidation:      arg.SchemaValidation,
+		BatchID:               arg.BatchID,
+	})
+	return all(rows, err, func(r ListLogsRow) database.ListLogsRow {
+		return database.ListLogsRow{
+			ID:                    r.ID,
+			UserID:                r.UserID,
+			ModelID:               r.ModelID,
+			RequestPayload:        r.RequestPayload,
+			ResponsePayload:       r.ResponsePayload,
+			CreatedAt:             r.CreatedAt,
+			PromptTokens:          r.PromptTokens,
+			CompletionTokens:      r.CompletionTokens,
+			ConnectionID:          r.ConnectionID,
+			Type:                  r.Type,
+			ApiKeyID:              r.ApiKeyID,
+			StatusCode:            r.StatusCode,
+			RequestID:             r.RequestID,
+			ProviderID:            r.ProviderID,
+			Cost:                  numeric(r.Cost),
+			PromptTemplateID:      r.PromptTemplateID,
+			PromptTemplateVersion: r.PromptTemplateVersion,
+			SchemaValidation:      r.SchemaValidation,
+			SchemaAttempt:         r.SchemaAttempt,
+			SchemaErrors:          r.SchemaErrors,
+			BatchID:               r.BatchID,
+			ReasoningTokens:       r.ReasoningTokens,
+			ContentFilter:         r.ContentFilter,
+			ShadowOf:              r.ShadowOf,
+			ProviderModelID:       r.ProviderModelID,
+		}
+	})
+}
+
+// ReindexLogSearch has nothing to do: SQLite searches the payloads
+// themselves rather than a stored vector.
+func (s querier) ReindexLogSearch(ctx context.Context, arg database.ReindexLogSearchParams) ([]pgtype.UUID, error) {
+	return nil, nil
+}
+
+// Evaluation runs
+
+func (s querier) CancelEvalResults(ctx context.Context, runID pgtype.UUID) (int64, error) {
+	return s.q.CancelEvalResults(ctx, runID)
+}
+
+func (s querier) CancelEvalRun(ctx context.Context, arg database.CancelEvalRunParams) (database.EvalRun, error) {
+	run, err := s.q.CancelEvalRun(ctx, CancelEvalRunParams(arg))
+	return database.EvalRun(run), err
+}
+
+func (s querier) ClaimEvalResult(ctx context.Context) (database.EvalResult, error) {
+	result, err := s.q.ClaimEvalResult(ctx)
+	return database.EvalResult(result), err
+}
+
+func (s querier) CountEvalResults(ctx context.Context, runID pgtype.UUID) ([]database.CountEvalResultsRow, error) {
+	rows, err := s.q.CountEvalResults(ctx, runID)
+	return all(rows, err, func(r CountEvalResultsRow) database.CountEvalResultsRow { return database.CountEvalResultsRow(r) })
+}
+
+func (s querier) CreateEvalResult(ctx context.Context, arg database.CreateEvalResultParams) error {
+	return s.q.CreateEvalResult(ctx, CreateEvalResultParams(arg))
+}
+
+func (s querier) CreateEvalRun(ctx context.Context, arg database.CreateEvalRunParams) (database.EvalRun, error) {
+	run, err := s.q.CreateEvalRun(ctx, CreateEvalRunParams(arg))
+	return database.EvalRun(run), err
+}
+
+func (s querier) FinishEvalResult(ctx context.Context, arg database.FinishEvalResultParams) error {
+	return s.q.FinishEvalResult(ctx, FinishEvalResultParams{
+		Status:           arg.Status,
+		NextAttemptAt:    timestamp(arg.NextAttemptAt),
+		StatusCode:       arg.StatusCode,
+		RequestID:        arg.RequestID,
+		Output:           arg.Output,
+		PromptTokens:     arg.PromptTokens,
+		CompletionTokens: arg.CompletionTokens,
+		LatencyMs:        arg.LatencyMs,
+		JudgeScore:       arg.JudgeScore,
+		JudgeReason:      arg.JudgeReason,
+		Error:            arg.Error,
+		ID:               arg.ID,
+	})
+}
+
+func (s querier) FinishEvalRun(ctx context.Context, arg database.FinishEvalRunParams) (database.EvalRun, error) {
+	run, err := s.q.FinishEvalRun(ctx, FinishEvalRunParams(arg))
+	return database.EvalRun(run), err
+}
+
+func (s querier) GetEvalRun(ctx context.Context, arg database.GetEvalRunParams) (database.EvalRun, error) {
+	run, err := s.q.GetEvalRun(ctx, GetEvalRunParams(arg))
+	return database.EvalRun(run), err
+}
+
+func (s querier) GetEvalRunByID(ctx context.Context, id pgtype.UUID) (database.EvalRun, error) {
+	run, err := s.q.GetEvalRunByID(ctx, id)
+	return database.EvalRun(run), err
+}
+
+func (s querier) ListEvalResults(ctx context.Context, arg database.ListEvalResultsParams) ([]database.ListEvalResultsRow, error) {
+	rows, err := s.q.ListEvalResults(ctx, ListEvalResultsParams{
+		RunID:     arg.RunID,
+		AfterLine: arg.AfterLine,
+		Status:    sql.NullString{String: arg.Status.String, Valid: arg.Status.Valid},
+		Limit:     arg.Limit,
+	})
+	return all(rows, err, func(r ListEvalResultsRow) database.ListEvalResultsRow {
+		return database.ListEvalResultsRow{
+			ID:                       r.ID,
+			Line:                     r.Line,
+			LogID:                    r.LogID,
+			BaselineModelID:          r.BaselineModelID,
+			BaselineModel:            r.BaselineModel,
+			Request:                  r.Request,
+			BaselineOutput:           r.BaselineOutput,
+			BaselinePromptTokens:     r.BaselinePromptTokens,
+			BaselineCompletionTokens: r.BaselineCompletionTokens,
+			BaselineLatencyMs:        r.BaselineLatencyMs,
+			BaselineCost:             numeric(r.BaselineCost),
+			Status:                   r.Status,
+			Attempts:                 r.Attempts,
+			StatusCode:               r.StatusCode,
+			RequestID:                r.RequestID,
+			Output:                   r.Output,
+			PromptTokens:             r.PromptTokens,
+			CompletionTokens:         r.CompletionTokens,
+			LatencyMs:                r.LatencyMs,
+			Cost:                     numeric(r.Cost),
+			JudgeScore:               r.JudgeScore,
+			JudgeReason:              r.JudgeReason,
+			Error:                    r.Error,
+		}
+	})
+}
+
+func (s querier) ListEvalRuns(ctx context.Context, arg database.ListEvalRunsParams) ([]database.EvalRun, error) {
+	runs, err := s.q.ListEvalRuns(ctx, ListEvalRunsParams{
+		UserID:          arg.UserID,
+		CursorCreatedAt: timestamp(arg.CursorCreatedAt),
+		CursorID:        arg.CursorID,
+		Limit:           arg.Limit,
+	})
+	return all(runs, err, func(r EvalRun) database.EvalRun { return database.EvalRun(r) })
+}
+
+func (s querier) ListFinishedEvalRuns(ctx context.Context) ([]database.EvalRun, error) {
+	runs, err := s.q.ListFinishedEvalRuns(ctx)
+	return all(runs, err, func(r EvalRun) database.EvalRun { return database.EvalRun(r) })
+}
+
+func (s querier) ReleaseEvalResult(ctx context.Context, id pgtype.UUID) error {
+	return s.q.ReleaseEvalResult(ctx, id)
+}
+
+func (s querier) RequeueStaleEvalResults(ctx context.Context, claimedBefore pgtype.Timestamptz) (int64, error) {
+	return s.q.RequeueStaleEvalResults(ctx, timestamp(claimedBefore))
+}
+
+func (s querier) SampleEvalLogs(ctx context.Context, arg database.SampleEvalLogsParams) ([]database.SampleEvalLogsRow, error) {
+	rows, err := s.q.SampleEvalLogs(ctx, SampleEvalLogsParams{
+		UserID:   arg.UserID,
+		ModelID:  arg.ModelID,
+		ApiKeyID: arg.ApiKeyID,
+		Since:    timestamp(arg.Since),
+		Until:    timestamp(arg.Until),
+		Limit:    arg.Limit,
+	})
+	return all(rows, err, func(r SampleEvalLogsRow) database.SampleEvalLogsRow { return database.SampleEvalLogsRow(r) })
+}
+
+// Files
+
+func (s querier) CreateFile(ctx context.Context, arg database.CreateFileParams) (database.File, error) {
+	file, err := s.q.CreateFile(ctx, CreateFileParams(arg))
+	return database.File(file), err
+}
+
+func (s querier) CreateFileContent(ctx context.Context, arg database.CreateFileContentParams) error {
+	return s.q.CreateFileContent(ctx, CreateFileContentParams(arg))
+}
+
+func (s querier) DeleteFile(ctx context.Context, arg database.DeleteFileParams) (int64, error) {
+	return s.q.DeleteFile(ctx, DeleteFileParams(arg))
+}
+
+func (s querier) GetFile(ctx context.Context, arg database.GetFileParams) (database.File, error) {
+	file, err := s.q.GetFile(ctx, GetFileParams(arg))
+	return database.File(file), err
+}
+
+func (s querier) GetFileContent(ctx context.Context, arg database.GetFileContentParams) ([]byte, error) {
+	return s.q.GetFileContent(ctx, GetFileContentParams(arg))
+}
+
+func (s querier) ListFiles(ctx context.Context, arg database.ListFilesParams) ([]database.File, error) {
+	files, err := s.q.ListFiles(ctx, ListFilesParams{
+		UserID:          arg.UserID,
+		Purpose:         sql.NullString{String: arg.Purpose.String, Valid: arg.Purpose.Valid},
+		CursorCreatedAt: timestamp(arg.CursorCreatedAt),
+		CursorID:        arg.CursorID,
+		Limit:           arg.Limit,
+	})
+	return all(files, err, func(f File) database.File { return database.File(f) })
+}
+
+// Models
+
+// model converts a model row; param_policy, structured_output and shadow are
+// BLOBs here and JSONB in Postgres.
+func model(m Model) database.Model {
+	return database.Model{
+		ID:               m.ID,
+		UserID:           m.UserID,
+		ConnectionID:     m.ConnectionID,
+		ProxyModelID:     m.ProxyModelID,
+		ProviderModelID:  m.ProviderModelID,
+		Thinking:         m.Thinking,
+		ToolsUsage:       m.ToolsUsage,
+		PriceInput:       m.PriceInput,
+		PriceOutput:      m.PriceOutput,
+		DeletedAt:        m.DeletedAt,
+		Type:             m.Type,
+		LogPolicy:        m.LogPolicy,
+		Managed:          m.Managed,
+		ParamPolicy:      json.RawMessage(m.ParamPolicy),
+		PromptTemplateID: m.PromptTemplateID,
+		StructuredOutput: json.RawMessage(m.StructuredOutput),
+		Shadow:           json.RawMessage(m.Shadow),
+	}
+}
+
+func (s querier) CreateModel(ctx context.Context, arg database.CreateModelParams) (database.Model, error) {
+	m, err := s.q.CreateModel(ctx, CreateModelParams{
+		ID:               arg.ID,
+		UserID:           arg.UserID,
+		ConnectionID:     arg.ConnectionID,
+		ProxyModelID:     arg.ProxyModelID,
+		ProviderModelID:  arg.ProviderModelID,
+		Thinking:         arg.Thinking,
+		ToolsUsage:       arg.ToolsUsage,
+		PriceInput:       arg.PriceInput,
+		PriceOutput:      arg.PriceOutput,
+		Type:             arg.Type,
+		LogPolicy:        arg.LogPolicy,
+		Managed:          arg.Managed,
+		ParamPolicy:      arg.ParamPolicy,
+		PromptTemplateID: arg.PromptTemplateID,
+		StructuredOutput: arg.StructuredOutput,
+		Shadow:           arg.Shadow,
+	})
+	return model(m), err
+}
+
+func (s querier) GetModel(ctx context.Context, arg database.GetModelParams) (database.Model, error) {
+	m, err := s.q.GetModel(ctx, GetModelParams(arg))
+	return model(m), err
+}
+
+func (s querier) GetModelByProxyModelID(ctx context.Context, arg database.GetModelByProxyModelIDParams) (database.Model, error) {
+	m, err := s.q.GetModelByProxyModelID(ctx, GetModelByProxyModelIDParams(arg))
+	return model(m), err
+}
+
+func (s querier) ListManagedModels(ctx context.Context, userID pgtype.UUID) ([]database.Model, error) {
+	models, err := s.q.ListManagedModels(ctx, userID)
+	return all(models, err, model)
+}
+
+func (s querier) ListModels(ctx context.Context, userID pgtype.UUID) ([]database.Model, error) {
+	models, err := s.q.ListModels(ctx, userID)
+	return all(models, err, model)
+}
+
+func (s querier) SoftDeleteModel(ctx context.Context, arg database.SoftDeleteModelParams) error {
+	return s.q.SoftDeleteModel(ctx, SoftDeleteModelParams(arg))
+}
+
+func (s querier) UpdateModel(ctx context.Context, arg database.UpdateModelParams) (database.Model, error) {
+	m, err := s.q.UpdateModel(ctx, UpdateModelParams{
+		ID:               arg.ID,
+		UserID:           arg.UserID,
+		ProxyModelID:     arg.ProxyModelID,
+		ProviderModelID:  arg.ProviderModelID,
+		Thinking:         arg.Thinking,
+		ToolsUsage:       arg.ToolsUsage,
+		PriceInput:       arg.PriceInput,
+		PriceOutput:      arg.PriceOutput,
+		Type:             arg.Type,
+		LogPolicy:        arg.LogPolicy,
+		ConnectionID:     arg.ConnectionID,
+		ParamPolicy:      arg.ParamPolicy,
+		PromptTemplateID: arg.PromptTemplateID,
+		StructuredOutput: arg.StructuredOutput,
+		Shadow:           arg.Shadow,
+	})
+	return model(m), err
+}
+
+// Prompt templates
+
+func (s querier) CountModelsUsingPromptTemplate(ctx context.Context, arg database.CountModelsUsingPromptTemplateParams) (int64, error) {
+	return s.q.CountModelsUsingPromptTemplate(ctx, CountModelsUsingPromptTemplateParams(arg))
+}
+
+func (s querier) CreatePromptTemplate(ctx context.Context, arg database.CreatePromptTemplateParams) (database.PromptTemplate, error) {
+	template, err := s.q.CreatePromptTemplate(ctx, CreatePromptTemplateParams(arg))
+	return database.PromptTemplate(template), err
+}
+
+func (s querier) CreatePromptTemplateVersion(ctx context.Context, arg database.CreatePromptTemplateVersionParams) (database.PromptTemplateVersion, error) {
+	version, err := s.q.CreatePromptTemplateVersion(ctx, CreatePromptTemplateVersionParams(arg))
+	return database.PromptTemplateVersion(version), err
+}
+
+func (s querier) GetPromptTemplate(ctx context.Context, arg database.GetPromptTemplateParams) (database.PromptTemplate, error) {
+	template, err := s.q.GetPromptTemplate(ctx, GetPromptTemplateParams(arg))
+	return database.PromptTemplate(template), err
+}
+
+func (s querier) GetPromptTemplateByName(ctx context.Context, arg database.GetPromptTemplateByNameParams) (database.PromptTemplate, error) {
+	template, err := s.q.GetPromptTemplateByName(ctx, GetPromptTemplateByNameParams(arg))
+	return database.PromptTemplate(template), err
+}
+
+func (s querier) GetPromptTemplateVersion(ctx context.Context, arg database.GetPromptTemplateVersionParams) (database.PromptTemplateVersion, error) {
+	version, err := s.q.GetPromptTemplateVersion(ctx, GetPromptTemplateVersionParams(arg))
+	return database.PromptTemplateVersion(version), err
+}
+
+func (s querier) ListActivePromptTemplates(ctx context.Context) ([]database.ListActivePromptTemplatesRow, error) {
+	rows, err := s.q.ListActivePromptTemplates(ctx)
+	return all(rows, err, func(r ListActivePromptTemplatesRow) database.ListActivePromptTemplatesRow {
+		return database.ListActivePromptTemplatesRow{
+			Name:                  r.Name,
+			PromptTemplateVersion: database.PromptTemplateVersion(r.PromptTemplateVersion),
+		}
+	})
+}
+
+func (s querier) ListManagedPromptTemplates(ctx context.Context, userID pgtype.UUID) ([]database.PromptTemplate, error) {
+	templates, err := s.q.ListManagedPromptTemplates(ctx, userID)
+	return all(templates, err, func(t PromptTemplate) database.PromptTemplate { return database.PromptTemplate(t) })
+}
+
+func (s querier) ListPromptTemplateVersions(ctx context.Context, templateID pgtype.UUID) ([]database.PromptTemplateVersion, error) {
+	versions, err := s.q.ListPromptTemplateVersions(ctx, templateID)
+	return all(versions, err, func(v PromptTemplateVersion) database.PromptTemplateVersion { return database.PromptTemplateVersion(v) })
+}
+
+func (s querier) ListPromptTemplates(ctx context.Context, userID pgtype.UUID) ([]database.PromptTemplate, error) {
+	templates, err := s.q.ListPromptTemplates(ctx, userID)
+	return all(templates, err, func(t PromptTemplate) database.PromptTemplate { return database.PromptTemplate(t) })
+}
+
+func (s querier) SetPromptTemplateActiveVersion(ctx context.Context, arg database.SetPromptTemplateActiveVersionParams) (database.PromptTemplate, error) {
+	template, err := s.q.SetPromptTemplateActiveVersion(ctx, SetPromptTemplateActiveVersionParams(arg))
+	return database.PromptTemplate(template), err
+}
+
+func (s querier) SoftDeletePromptTemplate(ctx context.Context, arg database.SoftDeletePromptTemplateParams) error {
+	return s.q.SoftDeletePromptTemplate(ctx, SoftDeletePromptTemplateParams(arg))
+}
+
+// Providers
+
+func (s querier) CreateProvider(ctx context.Context, arg database.CreateProviderParams) (database.CreateProviderRow, error) {
+	row, err := s.q.CreateProvider(ctx, CreateProviderParams(arg))
+	return database.CreateProviderRow(row), err
+}
+
+func (s querier) GetProvider(ctx context.Context, arg database.GetProviderParams) (database.Provider, error) {
+	provider, err := s.q.GetProvider(ctx, GetProviderParams(arg))
+	return database.Provider(provider), err
+}
+
+func (s querier) ListManagedProviders(ctx context.Context, userID pgtype.UUID) ([]database.Provider, error) {
+	providers, err := s.q.ListManagedProviders(ctx, userID)
+	return all(providers, err, func(p Provider) database.Provider { return database.Provider(p) })
+}
+
+func (s querier) ListProviders(ctx context.Context, userID pgtype.UUID) ([]database.Provider, error) {
+	providers, err := s.q.ListProviders(ctx, userID)
+	return all(providers, err, func(p Provider) database.Provider { return database.Provider(p) })
+}
+
+func (s querier) SoftDeleteProvider(ctx context.Context, arg database.SoftDeleteProviderParams) error {
+	return s.q.SoftDeleteProvider(ctx, SoftDeleteProviderParams(arg))
+}
+
+func (s querier) UpdateProvider(ctx context.Context, arg database.UpdateProviderParams) (database.Provider, error) {
+	provider, err := s.q.UpdateProvider(ctx, UpdateProviderParams(arg))
+	return database.Provider(provider), err
+}
+
+// Responses
+
+func (s querier) CreateResponse(ctx context.Context, arg database.CreateResponseParams) error {
+	return s.q.CreateResponse(ctx, CreateResponseParams(arg))
+}
+
+func (s querier) GetResponse(ctx context.Context, arg database.GetResponseParams) (database.Response, error) {
+	response, err := s.q.GetResponse(ctx, GetResponseParams(arg))
+	return database.Response(response), err
+}
+
+// Retention
+
+func (s querier) CreateRetentionPolicy(ctx context.Context, arg database.CreateRetentionPolicyParams) (database.RetentionPolicy, error) {
+	policy, err := s.q.CreateRetentionPolicy(ctx, CreateRetentionPolicyParams(arg))
+	return database.RetentionPolicy(policy), err
+}
+
+func (s querier) DeleteLogs(ctx context.Context, ids []pgtype.UUID) (int64, error) {
+	return s.q.DeleteLogs(ctx, ids)
+}
+
+func (s querier) DeleteRetentionPolicy(ctx context.Context, arg database.DeleteRetentionPolicyParams) error {
+	return s.q.DeleteRetentionPolicy(ctx, DeleteRetentionPolicyParams(arg))
+}
+
+func (s querier) GetRetentionPolicy(ctx context.Context, arg database.GetRetentionPolicyParams) (database.RetentionPolicy, error) {
+	policy, err := s.q.GetRetentionPolicy(ctx, GetRetentionPolicyParams(arg))
+	return database.RetentionPolicy(policy), err
+}
+
+func (s querier) ListLogsPastRowTTL(ctx context.Context, limit int32) ([]database.ListLogsPastRowTTLRow, error) {
+	rows, err := s.q.ListLogsPastRowTTL(ctx, int64(limit))
+	return all(rows, err, func(r ListLogsPastRowTTLRow) database.ListLogsPastRowTTLRow { return database.ListLogsPastRowTTLRow(r) })
+}
+
+func (s querier) ListLogsWithExpiredPayloads(ctx context.Context, limit int32) ([]database.ListLogsWithExpiredPayloadsRow, error) {
+	rows, err := s.q.ListLogsWithExpiredPayloads(ctx, int64(limit))
+	return all(rows, err, func(r ListLogsWithExpiredPayloadsRow) database.ListLogsWithExpiredPayloadsRow {
+		return database.ListLogsWithExpiredPayloadsRow(r)
+	})
+}
+
+func (s querier) ListRetentionPolicies(ctx context.Context, userID pgtype.UUID) ([]database.RetentionPolicy, error) {
+	policies, err := s.q.ListRetentionPolicies(ctx, userID)
+	return all(policies, err, func(p RetentionPolicy) database.RetentionPolicy { return database.RetentionPolicy(p) })
+}
+
+func (s querier) PurgeLogPayloads(ctx context.Context, ids []pgtype.UUID) (int64, error) {
+	return s.q.PurgeLogPayloads(ctx, ids)
+}
+
+func (s querier) RollupLogs(ctx context.Context, ids []pgtype.UUID) error {
+	return s.q.RollupLogs(ctx, ids)
+}
+
+// Routing
+
+func (s querier) GetRoutingVersion(ctx context.Context) (int64, error) {
+	return s.q.GetRoutingVersion(ctx)
+}
+
+func (s querier) ListRouteConnections(ctx context.Context) ([]database.ListRouteConnectionsRow, error) {
+	rows, err := s.q.ListRouteConnections(ctx)
+	return all(rows, err, func(r ListRouteConnectionsRow) database.ListRouteConnectionsRow {
+		return database.ListRouteConnectionsRow{
+			ID:              r.ID,
+			UserID:          r.UserID,
+			ConnectionName:  r.ConnectionName,
+			EncryptedApiKey: r.EncryptedApiKey,
+			Provider:        database.Provider(r.Provider),
+		}
+	})
+}
+
+func (s querier) ListRoutes(ctx context.Context) ([]database.ListRoutesRow, error) {
+	rows, err := s.q.ListRoutes(ctx)
+	return all(rows, err, func(r ListRoutesRow) database.ListRoutesRow {
+		return database.ListRoutesRow{
+			Model:           model(r.Model),
+			Provider:        database.Provider(r.Provider),
+			ConnectionName:  r.ConnectionName,
+			EncryptedApiKey: r.EncryptedApiKey,
+		}
+	})
+}
+
+// Shadow traffic
+
+func (s querier) ListShadowComparisons(ctx context.Context, arg database.ListShadowComparisonsParams) ([]database.ListShadowComparisonsRow, error) {
+	rows, err := s.q.ListShadowComparisons(ctx, ListShadowComparisonsParams{
+		UserID:  arg.UserID,
+		ModelID: arg.ModelID,
+		Since:   timestamp(arg.Since),
+		Until:   timestamp(arg.Until),
+	})
+	return all(rows, err, func(r ListShadowComparisonsRow) database.ListShadowComparisonsRow {
+		return database.ListShadowComparisonsRow{
+			PrimaryModelID:          r.PrimaryModelID,
+			PrimaryModel:            r.PrimaryModel,
+			ShadowConnectionID:      r.ShadowConnectionID,
+			ShadowConnection:        r.ShadowConnection,
+			ShadowProviderModel:     r.ShadowProviderModel,
+			Requests:                r.Requests,
+			PrimaryErrors:           r.PrimaryErrors,
+			ShadowErrors:            r.ShadowErrors,
+			PrimaryPromptTokens:     r.PrimaryPromptTokens,
+			PrimaryCompletionTokens: r.PrimaryCompletionTokens,
+			ShadowPromptTokens:      r.ShadowPromptTokens,
+			ShadowCompletionTokens:  r.ShadowCompletionTokens,
+			PrimaryAvgLatencyMs:     r.PrimaryAvgLatencyMs,
+			ShadowAvgLatencyMs:      r.ShadowAvgLatencyMs,
+			PrimaryCost:             numeric(r.PrimaryCost),
+			ShadowCost:              numeric(r.ShadowCost),
+		}
+	})
+}
+
+// Users
+
+func (s querier) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	user, err := s.q.CreateUser(ctx, CreateUserParams(arg))
+	return database.User(user), err
+}
+
+func (s querier) GetUserByUsername(ctx context.Context, username string) (database.User, error) {
+	user, err := s.q.GetUserByUsername(ctx, username)
+	return database.User(user), err
+}
+
+func (s querier) GetUserPasswordHash(ctx context.Context, username string) (string, error) {
+	return s.q.GetUserPasswordHash(ctx, username)
+}
+
+func (s querier) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) (int64, error) {
+	return s.q.UpdateUserPassword(ctx, UpdateUserPasswordParams(arg))
+}
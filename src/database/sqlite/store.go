@@ -0,0 +1,72 @@
+// Package sqlite backs database.Store with an SQLite file, for single-node
+// deployments that do not want to run Postgres. The queries in this package
+// are generated by sqlc from db/sqlite; Store adapts them to the Querier the
+// rest of the proxy uses.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	"gen-ai-proxy/src/database"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store is a database.Store backed by an SQLite database.
+type Store struct {
+	querier
+	db *sql.DB
+}
+
+var _ database.Store = (*Store)(nil)
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		querier: querier{q: New(tracedDB{db})},
+		db:      db,
+	}
+}
+
+// Open opens the database file at path, creating it if needed. Writers wait
+// for each other instead of failing with SQLITE_BUSY, and transactions take
+// the write lock up front so they cannot deadlock upgrading a read lock.
+func Open(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_pragma": {
+			"foreign_keys(1)",
+			"journal_mode(WAL)",
+			"busy_timeout(5000)",
+		},
+		"_txlock":      {"immediate"},
+		"_time_format": {"sqlite"},
+	}.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to open sqlite database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+func (s *Store) ExecTx(ctx context.Context, fn func(q database.Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(querier{q: New(tracedDB{tx})}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
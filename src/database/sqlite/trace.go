@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"gen-ai-proxy/src/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var dbSystemSQLite = attribute.String("db.system", "sqlite")
+
+// tracedDB starts a client span around each query, named after the sqlc
+// query like the spans of the Postgres pool. Spans of single-row queries end
+// before the row is scanned.
+type tracedDB struct {
+	db DBTX
+}
+
+func (t tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, telemetry.SQLSpanName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbSystemSQLite, attribute.String("db.statement", query)),
+	)
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	res, err := t.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		telemetry.RecordError(span, err)
+	}
+	return res, err
+}
+
+func (t tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	stmt, err := t.db.PrepareContext(ctx, query)
+	if err != nil {
+		telemetry.RecordError(span, err)
+	}
+	return stmt, err
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		telemetry.RecordError(span, err)
+	}
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	return t.db.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store is the generated Querier plus transaction support.
+type Store interface {
+	Querier
+	// ExecTx runs fn inside a transaction, committing when fn returns nil.
+	ExecTx(ctx context.Context, fn func(q Querier) error) error
+	// Ping checks the database is reachable.
+	Ping(ctx context.Context) error
+}
+
+// Listener is implemented by stores that push change notifications, so
+// callers can react without polling.
+type Listener interface {
+	// Listen calls fn with the payload of every notification sent on channel
+	// until ctx is cancelled or the connection fails.
+	Listen(ctx context.Context, channel string, fn func(payload string)) error
+}
+
+// PgStore is a Store backed by a Postgres connection pool.
+type PgStore struct {
+	*Queries
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *PgStore {
+	return &PgStore{
+		Queries: New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PgStore) ExecTx(ctx context.Context, fn func(q Querier) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(s.WithTx(tx))
+	})
+}
+
+func (s *PgStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Listen uses LISTEN on a connection taken out of the pool for good, since it
+// stays subscribed to the channel.
+func (s *PgStore) Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	pooled, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		fn(n.Payload)
+	}
+}
@@ -67,3 +67,22 @@ func (q *Queries) GetUserPasswordHash(ctx context.Context, username string) (str
 	err := row.Scan(&hash_value)
 	return hash_value, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2
+WHERE username = $1
+`
+
+type UpdateUserPasswordParams struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserPassword, arg.Username, arg.PasswordHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,335 @@
+// Package declarative reconciles providers, connections, prompt templates,
+// models and API keys declared in a YAML file into the database, so an
+// environment can be reproduced from git. Resources created this way are
+// marked as managed and are read-only through the management API.
+package declarative
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gen-ai-proxy/src/mockprovider"
+	"gen-ai-proxy/src/parampolicy"
+	"gen-ai-proxy/src/prompttemplate"
+	"gen-ai-proxy/src/redaction"
+	"gen-ai-proxy/src/shadow"
+	"gen-ai-proxy/src/structuredoutput"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the root of the declarative configuration.
+type File struct {
+	User        User         `yaml:"user"`
+	Providers   []Provider   `yaml:"providers"`
+	Connections []Connection `yaml:"connections"`
+	// PromptTemplates come before models, which reference them by name.
+	PromptTemplates []PromptTemplate `yaml:"prompt_templates"`
+	Models          []Model          `yaml:"models"`
+	APIKeys         []APIKey         `yaml:"api_keys"`
+}
+
+// User owns every resource in the file. The password is only used to create
+// the user when it does not exist yet.
+type User struct {
+	Username string  `yaml:"username"`
+	Password *Secret `yaml:"password"`
+}
+
+// Secret references a value kept outside the file, either in an environment
+// variable or in a file such as a mounted Kubernetes or Docker secret.
+type Secret struct {
+	Env  string `yaml:"env" json:"env,omitempty"`
+	File string `yaml:"file" json:"file,omitempty"`
+}
+
+type Provider struct {
+	Name    string `yaml:"name" json:"name"`
+	Type    string `yaml:"type" json:"type"`
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	// APIVersion is required for azure-openai providers.
+	APIVersion string `yaml:"api_version" json:"api_version,omitempty"`
+	// Region is required for bedrock providers.
+	Region string `yaml:"region" json:"region,omitempty"`
+}
+
+type Connection struct {
+	Name     string `yaml:"name" json:"name"`
+	Provider string `yaml:"provider" json:"provider"`
+	APIKey   Secret `yaml:"api_key" json:"api_key"`
+}
+
+// PromptTemplate declares the content of a prompt template. A change to the
+// content is applied as a new version.
+type PromptTemplate struct {
+	Name                   string `yaml:"name" json:"name"`
+	prompttemplate.Content `yaml:",inline"`
+}
+
+// Model routes ProxyModelID to ProviderModelID on the named connection.
+type Model struct {
+	ProxyModelID    string  `yaml:"proxy_model_id" json:"proxy_model_id"`
+	Connection      string  `yaml:"connection" json:"connection"`
+	ProviderModelID string  `yaml:"provider_model_id" json:"provider_model_id"`
+	Type            string  `yaml:"type" json:"type"`
+	PriceInput      float64 `yaml:"price_input" json:"price_input"`
+	PriceOutput     float64 `yaml:"price_output" json:"price_output"`
+	Thinking        bool    `yaml:"thinking" json:"thinking"`
+	ToolsUsage      bool    `yaml:"tools_usage" json:"tools_usage"`
+	LogPolicy       string  `yaml:"log_policy" json:"log_policy"`
+	// ParamPolicy sets defaults, overrides and allowed ranges for request parameters.
+	ParamPolicy *parampolicy.Policy `yaml:"param_policy" json:"param_policy,omitempty"`
+	// PromptTemplate names a template declared in the same file.
+	PromptTemplate string `yaml:"prompt_template" json:"prompt_template,omitempty"`
+	// StructuredOutput is the JSON Schema chat outputs must match.
+	StructuredOutput *structuredoutput.Policy `yaml:"structured_output" json:"structured_output,omitempty"`
+	// Shadow mirrors a percentage of requests to a candidate provider model.
+	Shadow *Shadow `yaml:"shadow" json:"shadow,omitempty"`
+}
+
+// Shadow mirrors Percent of a model's requests to ProviderModelID on the
+// named connection. Mirrored requests are priced at PriceInput and
+// PriceOutput.
+type Shadow struct {
+	Connection      string  `yaml:"connection" json:"connection"`
+	ProviderModelID string  `yaml:"provider_model_id" json:"provider_model_id"`
+	PriceInput      float64 `yaml:"price_input" json:"price_input"`
+	PriceOutput     float64 `yaml:"price_output" json:"price_output"`
+	Percent         float64 `yaml:"percent" json:"percent"`
+}
+
+// Policy resolves the shadow connection by name; a nil Shadow is no policy.
+func (s *Shadow) Policy(connectionIDs map[string]pgtype.UUID) *shadow.Policy {
+	if s == nil {
+		return nil
+	}
+	return &shadow.Policy{
+		ConnectionID:    connectionIDs[s.Connection],
+		ProviderModelID: s.ProviderModelID,
+		PriceInput:      s.PriceInput,
+		PriceOutput:     s.PriceOutput,
+		Percent:         s.Percent,
+	}
+}
+
+// ShadowOf describes a stored policy, naming its connection with
+// connectionName; a nil policy is no Shadow.
+func ShadowOf(p *shadow.Policy, connectionName func(id string) string) *Shadow {
+	if p == nil {
+		return nil
+	}
+	return &Shadow{
+		Connection:      connectionName(p.ConnectionID.String()),
+		ProviderModelID: p.ProviderModelID,
+		PriceInput:      p.PriceInput,
+		PriceOutput:     p.PriceOutput,
+		Percent:         p.Percent,
+	}
+}
+
+// EqualShadow reports whether two shadow specs are the same.
+func EqualShadow(a, b *Shadow) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// APIKey declares a proxy API key. AllowedModels restricts it to the listed
+// proxy model IDs (all models when empty) and MonthlyBudget caps its spend
+// per calendar month in the currency of the model prices.
+type APIKey struct {
+	Name          string   `yaml:"name" json:"name"`
+	Key           Secret   `yaml:"key" json:"key"`
+	AllowedModels []string `yaml:"allowed_models" json:"allowed_models,omitempty"`
+	MonthlyBudget *float64 `yaml:"monthly_budget" json:"monthly_budget,omitempty"`
+}
+
+// minAPIKeyLength rejects declared keys that are too short to be secret.
+const minAPIKeyLength = 16
+
+// Load reads and validates a declarative configuration file. Unknown keys are
+// rejected so typos do not silently drop settings.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Validate checks names are unique and every reference resolves within the file.
+func (f *File) Validate() error {
+	if f.User.Username == "" {
+		return errors.New("user.username is required")
+	}
+
+	providers := map[string]bool{}
+	for i, p := range f.Providers {
+		if p.Name == "" {
+			return fmt.Errorf("providers[%d]: name is required", i)
+		}
+		if providers[p.Name] {
+			return fmt.Errorf("providers[%d]: duplicate name %q", i, p.Name)
+		}
+		if p.Type == "" || p.BaseURL == "" {
+			return fmt.Errorf("provider %q: type and base_url are required", p.Name)
+		}
+		if p.Type == "azure-openai" && p.APIVersion == "" {
+			return fmt.Errorf("provider %q: api_version is required for azure-openai", p.Name)
+		}
+		if p.Type == "bedrock" && p.Region == "" {
+			return fmt.Errorf("provider %q: region is required for bedrock", p.Name)
+		}
+		if p.Type == "mock" {
+			if err := mockprovider.ValidateURL(p.BaseURL); err != nil {
+				return fmt.Errorf("provider %q: %w", p.Name, err)
+			}
+		}
+		providers[p.Name] = true
+	}
+
+	connections := map[string]bool{}
+	connectionIDs := map[string]pgtype.UUID{}
+	for i, c := range f.Connections {
+		if c.Name == "" {
+			return fmt.Errorf("connections[%d]: name is required", i)
+		}
+		if connections[c.Name] {
+			return fmt.Errorf("connections[%d]: duplicate name %q", i, c.Name)
+		}
+		if !providers[c.Provider] {
+			return fmt.Errorf("connection %q: unknown provider %q", c.Name, c.Provider)
+		}
+		if err := c.APIKey.validate(); err != nil {
+			return fmt.Errorf("connection %q: api_key: %w", c.Name, err)
+		}
+		connections[c.Name] = true
+		// Shadow policies refer to connections by ID; until the connections
+		// exist, any distinct IDs tell them apart.
+		connectionIDs[c.Name] = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	}
+
+	templates := map[string]bool{}
+	for i := range f.PromptTemplates {
+		t := &f.PromptTemplates[i]
+		if t.Name == "" {
+			return fmt.Errorf("prompt_templates[%d]: name is required", i)
+		}
+		if templates[t.Name] {
+			return fmt.Errorf("prompt_templates[%d]: duplicate name %q", i, t.Name)
+		}
+		if err := t.Content.Validate(); err != nil {
+			return fmt.Errorf("prompt template %q: %w", t.Name, err)
+		}
+		t.Content = t.Content.Normalize()
+		templates[t.Name] = true
+	}
+
+	models := map[string]bool{}
+	for i := range f.Models {
+		m := &f.Models[i]
+		if m.ProxyModelID == "" {
+			return fmt.Errorf("models[%d]: proxy_model_id is required", i)
+		}
+		if models[m.ProxyModelID] {
+			return fmt.Errorf("models[%d]: duplicate proxy_model_id %q", i, m.ProxyModelID)
+		}
+		if !connections[m.Connection] {
+			return fmt.Errorf("model %q: unknown connection %q", m.ProxyModelID, m.Connection)
+		}
+		if m.ProviderModelID == "" {
+			m.ProviderModelID = m.ProxyModelID
+		}
+		if m.Type == "" {
+			m.Type = "llm"
+		}
+		if m.Type != "llm" && m.Type != "embedding" {
+			return fmt.Errorf("model %q: unknown type %q (expected llm or embedding)", m.ProxyModelID, m.Type)
+		}
+		if m.PriceInput < 0 || m.PriceOutput < 0 {
+			return fmt.Errorf("model %q: prices must not be negative", m.ProxyModelID)
+		}
+		if _, err := redaction.ParsePolicy(m.LogPolicy, ""); err != nil {
+			return fmt.Errorf("model %q: %w", m.ProxyModelID, err)
+		}
+		if err := m.ParamPolicy.Validate(); err != nil {
+			return fmt.Errorf("model %q: %w", m.ProxyModelID, err)
+		}
+		if err := m.StructuredOutput.Validate(); err != nil {
+			return fmt.Errorf("model %q: %w", m.ProxyModelID, err)
+		}
+		if m.Shadow != nil {
+			if !connections[m.Shadow.Connection] {
+				return fmt.Errorf("model %q: shadow: unknown connection %q", m.ProxyModelID, m.Shadow.Connection)
+			}
+			if err := m.Shadow.Policy(connectionIDs).Validate(connectionIDs[m.Connection], m.ProviderModelID); err != nil {
+				return fmt.Errorf("model %q: %w", m.ProxyModelID, err)
+			}
+		}
+		if m.PromptTemplate != "" && !templates[m.PromptTemplate] {
+			return fmt.Errorf("model %q: unknown prompt template %q", m.ProxyModelID, m.PromptTemplate)
+		}
+		models[m.ProxyModelID] = true
+	}
+
+	keys := map[string]bool{}
+	for i, k := range f.APIKeys {
+		if k.Name == "" {
+			return fmt.Errorf("api_keys[%d]: name is required", i)
+		}
+		if keys[k.Name] {
+			return fmt.Errorf("api_keys[%d]: duplicate name %q", i, k.Name)
+		}
+		if err := k.Key.validate(); err != nil {
+			return fmt.Errorf("api key %q: key: %w", k.Name, err)
+		}
+		if k.MonthlyBudget != nil && *k.MonthlyBudget < 0 {
+			return fmt.Errorf("api key %q: monthly_budget must not be negative", k.Name)
+		}
+		keys[k.Name] = true
+	}
+	return nil
+}
+
+func (s Secret) validate() error {
+	if (s.Env == "") == (s.File == "") {
+		return errors.New("exactly one of env or file is required")
+	}
+	return nil
+}
+
+// Resolve reads the secret value. Trailing newlines from secret files are trimmed.
+func (s Secret) Resolve() (string, error) {
+	if s.Env != "" {
+		val, ok := os.LookupEnv(s.Env)
+		if !ok || val == "" {
+			return "", fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+		return val, nil
+	}
+	data, err := os.ReadFile(s.File)
+	if err != nil {
+		return "", err
+	}
+	val := strings.TrimRight(string(data), "\r\n")
+	if val == "" {
+		return "", fmt.Errorf("secret file %s is empty", s.File)
+	}
+	return val, nil
+}
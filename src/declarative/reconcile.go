@@ -0,0 +1,856 @@
+package declarative
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/encryption"
+	"gen-ai-proxy/src/parampolicy"
+	"gen-ai-proxy/src/prompttemplate"
+	"gen-ai-proxy/src/shadow"
+	"gen-ai-proxy/src/structuredoutput"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Resource kinds. They double as audit log resource types.
+const (
+	KindUser           = "user"
+	KindProvider       = "provider"
+	KindConnection     = "connection"
+	KindModel          = "model"
+	KindAPIKey         = "api_key"
+	KindPromptTemplate = "prompt_template"
+)
+
+// auditUserAgent marks audit entries written by the reconciler rather than an API call.
+const auditUserAgent = "gen-ai-proxy/declarative-config"
+
+// Decimal scales of the price and budget columns, used to compare amounts.
+const (
+	priceScale  = 8
+	budgetScale = 4
+)
+
+// Change is one create, update or delete the reconciler makes. Fields lists
+// the settings an update changes; secret values are only reported as changed.
+type Change struct {
+	Action Action   `json:"action"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+func (c Change) String() string {
+	symbol := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[c.Action]
+	s := fmt.Sprintf("%s %s %s", symbol, c.Kind, c.Name)
+	if len(c.Fields) > 0 {
+		s += " (" + strings.Join(c.Fields, ", ") + ")"
+	}
+	return s
+}
+
+// Plan is the ordered list of changes needed to make the database match a file.
+type Plan []Change
+
+// String renders the plan as a diff, one change per line.
+func (p Plan) String() string {
+	if len(p) == 0 {
+		return "no changes\n"
+	}
+	var b strings.Builder
+	for _, c := range p {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+var errDryRun = errors.New("dry run")
+
+// Reconciler applies declarative files to the database.
+type Reconciler struct {
+	db            database.Store
+	encryptionKey []byte
+}
+
+// NewReconciler takes the base64 ENCRYPTION_KEY used for connection secrets.
+func NewReconciler(db database.Store, encryptionKey string) (*Reconciler, error) {
+	key, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes long after base64 decoding")
+	}
+	return &Reconciler{db: db, encryptionKey: key}, nil
+}
+
+// Plan returns the changes Apply would make without making them. It runs the
+// reconciliation in a transaction that is rolled back, so the diff is exact.
+func (r *Reconciler) Plan(ctx context.Context, f *File) (Plan, error) {
+	return r.run(ctx, f, true)
+}
+
+// Apply makes the managed resources of the file's user match f in a single
+// transaction: declared resources are created or updated and managed
+// resources missing from the file are deleted. Unmanaged resources are left alone.
+func (r *Reconciler) Apply(ctx context.Context, f *File) (Plan, error) {
+	return r.run(ctx, f, false)
+}
+
+// Sync loads the file at path and applies it, or only logs the plan when dryRun is set.
+func (r *Reconciler) Sync(ctx context.Context, path string, dryRun bool) error {
+	f, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	plan, err := r.run(ctx, f, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if len(plan) == 0 {
+		slog.InfoContext(ctx, "Declarative config is up to date", "file", path)
+		return nil
+	}
+	for _, c := range plan {
+		slog.InfoContext(ctx, "Declarative config change", "action", c.Action, "kind", c.Kind, "name", c.Name, "fields", c.Fields, "dry_run", dryRun)
+	}
+	return nil
+}
+
+func (r *Reconciler) run(ctx context.Context, f *File, dryRun bool) (Plan, error) {
+	var plan Plan
+	err := r.db.ExecTx(ctx, func(q database.Querier) error {
+		rn := &run{q: q, key: r.encryptionKey, file: f}
+		if err := rn.reconcile(ctx); err != nil {
+			return err
+		}
+		plan = rn.plan
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// run holds the state of one reconciliation inside its transaction.
+type run struct {
+	q    database.Querier
+	key  []byte
+	file *File
+
+	userID pgtype.UUID
+	plan   Plan
+
+	// Name to ID of the managed resources after reconciliation.
+	providerIDs       map[string]pgtype.UUID
+	connectionIDs     map[string]pgtype.UUID
+	promptTemplateIDs map[string]pgtype.UUID
+}
+
+func (rn *run) reconcile(ctx context.Context) error {
+	if err := rn.reconcileUser(ctx); err != nil {
+		return err
+	}
+
+	staleProviders, err := rn.reconcileProviders(ctx)
+	if err != nil {
+		return err
+	}
+	staleConnections, err := rn.reconcileConnections(ctx)
+	if err != nil {
+		return err
+	}
+	staleTemplates, err := rn.reconcilePromptTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	staleModels, err := rn.reconcileModels(ctx)
+	if err != nil {
+		return err
+	}
+	staleKeys, err := rn.reconcileAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Delete dependents before what they depend on.
+	for _, k := range staleKeys {
+		if err := rn.q.DeleteAPIKey(ctx, database.DeleteAPIKeyParams{ID: k.ID, UserID: rn.userID}); err != nil {
+			return fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		rn.record(ctx, ActionDelete, KindAPIKey, k.Name, k.ID, nil, apiKeySnapshot(k), nil)
+	}
+	for _, m := range staleModels {
+		if err := rn.q.SoftDeleteModel(ctx, database.SoftDeleteModelParams{ID: m.ID, UserID: rn.userID}); err != nil {
+			return fmt.Errorf("model %q: %w", m.ProxyModelID, err)
+		}
+		rn.record(ctx, ActionDelete, KindModel, m.ProxyModelID, m.ID, nil, rn.modelSnapshot(m), nil)
+	}
+	for _, t := range staleTemplates {
+		inUse, err := rn.q.CountModelsUsingPromptTemplate(ctx, database.CountModelsUsingPromptTemplateParams{PromptTemplateID: t.ID, UserID: rn.userID})
+		if err != nil {
+			return fmt.Errorf("prompt template %q: %w", t.Name, err)
+		}
+		if inUse > 0 {
+			return fmt.Errorf("prompt template %q is still used by %d model(s) created through the API", t.Name, inUse)
+		}
+		if err := rn.q.SoftDeletePromptTemplate(ctx, database.SoftDeletePromptTemplateParams{ID: t.ID, UserID: rn.userID}); err != nil {
+			return fmt.Errorf("prompt template %q: %w", t.Name, err)
+		}
+		rn.record(ctx, ActionDelete, KindPromptTemplate, t.Name, t.ID, nil, PromptTemplate{Name: t.Name}, nil)
+	}
+	for _, c := range staleConnections {
+		if err := rn.q.SoftDeleteConnection(ctx, database.SoftDeleteConnectionParams{ID: c.ID, UserID: rn.userID}); err != nil {
+			return fmt.Errorf("connection %q: %w", c.Name, err)
+		}
+		rn.record(ctx, ActionDelete, KindConnection, c.Name, c.ID, nil, rn.connectionSnapshot(c), nil)
+	}
+	for _, p := range staleProviders {
+		if err := rn.q.SoftDeleteProvider(ctx, database.SoftDeleteProviderParams{ID: p.ID, UserID: rn.userID}); err != nil {
+			return fmt.Errorf("provider %q: %w", p.Name, err)
+		}
+		rn.record(ctx, ActionDelete, KindProvider, p.Name, p.ID, nil, providerSnapshot(p), nil)
+	}
+	return nil
+}
+
+func (rn *run) reconcileUser(ctx context.Context) error {
+	spec := rn.file.User
+	user, err := rn.q.GetUserByUsername(ctx, spec.Username)
+	if err == nil {
+		rn.userID = user.ID
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user %q: %w", spec.Username, err)
+	}
+	if spec.Password == nil {
+		return fmt.Errorf("user %q does not exist and no password is configured to create it", spec.Username)
+	}
+
+	password, err := spec.Password.Resolve()
+	if err != nil {
+		return fmt.Errorf("user %q: password: %w", spec.Username, err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("user %q: %w", spec.Username, err)
+	}
+	created, err := rn.q.CreateUser(ctx, database.CreateUserParams{Username: spec.Username, PasswordHash: string(hash)})
+	if err != nil {
+		return fmt.Errorf("user %q: %w", spec.Username, err)
+	}
+	rn.userID = created.ID
+	rn.record(ctx, ActionCreate, KindUser, spec.Username, created.ID, nil, nil, map[string]string{"username": spec.Username})
+	return nil
+}
+
+func (rn *run) reconcileProviders(ctx context.Context) ([]database.Provider, error) {
+	existing, err := rn.q.ListManagedProviders(ctx, rn.userID)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]database.Provider{}
+	for _, p := range existing {
+		byName[p.Name] = p
+	}
+
+	rn.providerIDs = map[string]pgtype.UUID{}
+	for _, spec := range rn.file.Providers {
+		current, ok := byName[spec.Name]
+		delete(byName, spec.Name)
+
+		if !ok {
+			created, err := rn.q.CreateProvider(ctx, database.CreateProviderParams{
+				ID:         pgtype.UUID{Bytes: uuid.New(), Valid: true},
+				UserID:     rn.userID,
+				Name:       spec.Name,
+				BaseUrl:    spec.BaseURL,
+				Type:       spec.Type,
+				Managed:    true,
+				ApiVersion: spec.APIVersion,
+				Region:     spec.Region,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("provider %q: %w", spec.Name, err)
+			}
+			rn.providerIDs[spec.Name] = created.ID
+			rn.record(ctx, ActionCreate, KindProvider, spec.Name, created.ID, nil, nil, spec)
+			continue
+		}
+
+		rn.providerIDs[spec.Name] = current.ID
+		var fields []string
+		if current.Type != spec.Type {
+			fields = append(fields, "type")
+		}
+		if current.BaseUrl != spec.BaseURL {
+			fields = append(fields, "base_url")
+		}
+		if current.ApiVersion != spec.APIVersion {
+			fields = append(fields, "api_version")
+		}
+		if current.Region != spec.Region {
+			fields = append(fields, "region")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := rn.q.UpdateProvider(ctx, database.UpdateProviderParams{
+			ID:         current.ID,
+			UserID:     rn.userID,
+			Name:       spec.Name,
+			BaseUrl:    spec.BaseURL,
+			Type:       spec.Type,
+			ApiVersion: spec.APIVersion,
+			Region:     spec.Region,
+		}); err != nil {
+			return nil, fmt.Errorf("provider %q: %w", spec.Name, err)
+		}
+		rn.record(ctx, ActionUpdate, KindProvider, spec.Name, current.ID, fields, providerSnapshot(current), spec)
+	}
+	return sortedValues(byName), nil
+}
+
+func (rn *run) reconcileConnections(ctx context.Context) ([]database.Connection, error) {
+	existing, err := rn.q.ListManagedConnections(ctx, rn.userID)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]database.Connection{}
+	for _, c := range existing {
+		byName[c.Name] = c
+	}
+
+	rn.connectionIDs = map[string]pgtype.UUID{}
+	for _, spec := range rn.file.Connections {
+		current, ok := byName[spec.Name]
+		delete(byName, spec.Name)
+
+		apiKey, err := spec.APIKey.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("connection %q: api_key: %w", spec.Name, err)
+		}
+		providerID := rn.providerIDs[spec.Provider].String()
+
+		if !ok {
+			encrypted, err := encryption.Encrypt(rn.key, []byte(apiKey))
+			if err != nil {
+				return nil, fmt.Errorf("connection %q: %w", spec.Name, err)
+			}
+			created, err := rn.q.CreateConnection(ctx, database.CreateConnectionParams{
+				UserID:          rn.userID,
+				ProviderID:      providerID,
+				EncryptedApiKey: encrypted,
+				Name:            spec.Name,
+				Managed:         true,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("connection %q: %w", spec.Name, err)
+			}
+			rn.connectionIDs[spec.Name] = created.ID
+			rn.record(ctx, ActionCreate, KindConnection, spec.Name, created.ID, nil, nil, spec)
+			continue
+		}
+
+		rn.connectionIDs[spec.Name] = current.ID
+		var fields []string
+		if current.ProviderID != providerID {
+			fields = append(fields, "provider")
+		}
+		encrypted := current.EncryptedApiKey
+		// Ciphertexts are salted, so compare plaintexts.
+		if plain, err := encryption.Decrypt(rn.key, current.EncryptedApiKey); err != nil || string(plain) != apiKey {
+			fields = append(fields, "api_key")
+			if encrypted, err = encryption.Encrypt(rn.key, []byte(apiKey)); err != nil {
+				return nil, fmt.Errorf("connection %q: %w", spec.Name, err)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := rn.q.UpdateConnection(ctx, database.UpdateConnectionParams{
+			ID:              current.ID,
+			UserID:          rn.userID,
+			ProviderID:      providerID,
+			EncryptedApiKey: encrypted,
+		}); err != nil {
+			return nil, fmt.Errorf("connection %q: %w", spec.Name, err)
+		}
+		rn.record(ctx, ActionUpdate, KindConnection, spec.Name, current.ID, fields, rn.connectionSnapshot(current), spec)
+	}
+	return sortedValues(byName), nil
+}
+
+func (rn *run) reconcileModels(ctx context.Context) ([]database.Model, error) {
+	existing, err := rn.q.ListManagedModels(ctx, rn.userID)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]database.Model{}
+	for _, m := range existing {
+		byName[m.ProxyModelID] = m
+	}
+
+	for _, spec := range rn.file.Models {
+		current, ok := byName[spec.ProxyModelID]
+		delete(byName, spec.ProxyModelID)
+
+		priceInput, err := toNumeric(spec.PriceInput)
+		if err != nil {
+			return nil, fmt.Errorf("model %q: price_input: %w", spec.ProxyModelID, err)
+		}
+		priceOutput, err := toNumeric(spec.PriceOutput)
+		if err != nil {
+			return nil, fmt.Errorf("model %q: price_output: %w", spec.ProxyModelID, err)
+		}
+		paramPolicy, err := spec.ParamPolicy.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("model %q: param_policy: %w", spec.ProxyModelID, err)
+		}
+		structuredOutput, err := spec.StructuredOutput.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("model %q: structured_output: %w", spec.ProxyModelID, err)
+		}
+		shadowPolicy, err := spec.Shadow.Policy(rn.connectionIDs).Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("model %q: shadow: %w", spec.ProxyModelID, err)
+		}
+		connectionID := rn.connectionIDs[spec.Connection]
+		promptTemplateID := rn.promptTemplateIDs[spec.PromptTemplate]
+
+		if !ok {
+			// Proxy model IDs must stay unique per user, so do not shadow a
+			// model created through the API.
+			if _, err := rn.q.GetModelByProxyModelID(ctx, database.GetModelByProxyModelIDParams{
+				ProxyModelID: spec.ProxyModelID,
+				UserID:       rn.userID,
+			}); err == nil {
+				return nil, fmt.Errorf("model %q already exists and is not managed by the configuration file; delete it first", spec.ProxyModelID)
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+
+			created, err := rn.q.CreateModel(ctx, database.CreateModelParams{
+				ID:               pgtype.UUID{Bytes: uuid.New(), Valid: true},
+				UserID:           rn.userID,
+				ConnectionID:     connectionID,
+				ProxyModelID:     spec.ProxyModelID,
+				ProviderModelID:  spec.ProviderModelID,
+				Thinking:         spec.Thinking,
+				ToolsUsage:       spec.ToolsUsage,
+				PriceInput:       priceInput,
+				PriceOutput:      priceOutput,
+				Type:             spec.Type,
+				LogPolicy:        spec.LogPolicy,
+				ParamPolicy:      paramPolicy,
+				PromptTemplateID: promptTemplateID,
+				StructuredOutput: structuredOutput,
+				Shadow:           shadowPolicy,
+				Managed:          true,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+			}
+			rn.record(ctx, ActionCreate, KindModel, spec.ProxyModelID, created.ID, nil, nil, spec)
+			continue
+		}
+
+		var fields []string
+		if current.ConnectionID != connectionID {
+			fields = append(fields, "connection")
+		}
+		if current.ProviderModelID != spec.ProviderModelID {
+			fields = append(fields, "provider_model_id")
+		}
+		if current.Type != spec.Type {
+			fields = append(fields, "type")
+		}
+		if !sameAmount(current.PriceInput, spec.PriceInput, priceScale) {
+			fields = append(fields, "price_input")
+		}
+		if !sameAmount(current.PriceOutput, spec.PriceOutput, priceScale) {
+			fields = append(fields, "price_output")
+		}
+		if current.Thinking != spec.Thinking {
+			fields = append(fields, "thinking")
+		}
+		if current.ToolsUsage != spec.ToolsUsage {
+			fields = append(fields, "tools_usage")
+		}
+		if current.LogPolicy != spec.LogPolicy {
+			fields = append(fields, "log_policy")
+		}
+		if !parampolicy.Equal(parampolicy.Decode(current.ParamPolicy), spec.ParamPolicy) {
+			fields = append(fields, "param_policy")
+		}
+		if current.PromptTemplateID != promptTemplateID {
+			fields = append(fields, "prompt_template")
+		}
+		if !structuredoutput.Equal(structuredoutput.Decode(current.StructuredOutput), spec.StructuredOutput) {
+			fields = append(fields, "structured_output")
+		}
+		if !shadow.Equal(shadow.Decode(current.Shadow), spec.Shadow.Policy(rn.connectionIDs)) {
+			fields = append(fields, "shadow")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := rn.q.UpdateModel(ctx, database.UpdateModelParams{
+			ID:               current.ID,
+			UserID:           rn.userID,
+			ProxyModelID:     spec.ProxyModelID,
+			ProviderModelID:  spec.ProviderModelID,
+			Thinking:         spec.Thinking,
+			ToolsUsage:       spec.ToolsUsage,
+			PriceInput:       priceInput,
+			PriceOutput:      priceOutput,
+			Type:             spec.Type,
+			LogPolicy:        spec.LogPolicy,
+			ConnectionID:     connectionID,
+			ParamPolicy:      paramPolicy,
+			PromptTemplateID: promptTemplateID,
+			StructuredOutput: structuredOutput,
+			Shadow:           shadowPolicy,
+		}); err != nil {
+			return nil, fmt.Errorf("model %q: %w", spec.ProxyModelID, err)
+		}
+		rn.record(ctx, ActionUpdate, KindModel, spec.ProxyModelID, current.ID, fields, rn.modelSnapshot(current), spec)
+	}
+	return sortedValues(byName), nil
+}
+
+func (rn *run) reconcilePromptTemplates(ctx context.Context) ([]database.PromptTemplate, error) {
+	existing, err := rn.q.ListManagedPromptTemplates(ctx, rn.userID)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]database.PromptTemplate{}
+	for _, t := range existing {
+		byName[t.Name] = t
+	}
+
+	rn.promptTemplateIDs = map[string]pgtype.UUID{}
+	for _, spec := range rn.file.PromptTemplates {
+		current, ok := byName[spec.Name]
+		delete(byName, spec.Name)
+
+		messages, variables, err := spec.Content.Encode()
+		if err != nil {
+			return nil, fmt.Errorf("prompt template %q: %w", spec.Name, err)
+		}
+		version := database.CreatePromptTemplateVersionParams{
+			Mode:         string(spec.Mode),
+			SystemPrompt: spec.SystemPrompt,
+			Messages:     messages,
+			Variables:    variables,
+		}
+
+		if !ok {
+			// Do not shadow a template created through the API.
+			if _, err := rn.q.GetPromptTemplateByName(ctx, database.GetPromptTemplateByNameParams{
+				Name:   spec.Name,
+				UserID: rn.userID,
+			}); err == nil {
+				return nil, fmt.Errorf("prompt template %q already exists and is not managed by the configuration file; delete it first", spec.Name)
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("prompt template %q: %w", spec.Name, err)
+			}
+
+			created, err := rn.q.CreatePromptTemplate(ctx, database.CreatePromptTemplateParams{
+				ID:      pgtype.UUID{Bytes: uuid.New(), Valid: true},
+				UserID:  rn.userID,
+				Name:    spec.Name,
+				Managed: true,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("prompt template %q: %w", spec.Name, err)
+			}
+			version.TemplateID = created.ID
+			if _, err := rn.q.CreatePromptTemplateVersion(ctx, version); err != nil {
+				return nil, fmt.Errorf("prompt template %q: %w", spec.Name, err)
+			}
+			rn.promptTemplateIDs[spec.Name] = created.ID
+			rn.record(ctx, ActionCreate, KindPromptTemplate, spec.Name, created.ID, nil, nil, spec)
+			continue
+		}
+
+		rn.promptTemplateIDs[spec.Name] = current.ID
+		active, err := rn.q.GetPromptTemplateVersion(ctx, database.GetPromptTemplateVersionParams{
+			TemplateID: current.ID,
+			Version:    current.ActiveVersion,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("prompt template %q: %w", spec.Name, err)
+		}
+		// An undecodable version is replaced like any other difference.
+		activeContent, _ := prompttemplate.Decode(active.Mode, active.SystemPrompt, active.Messages, active.Variables)
+		if prompttemplate.Equal(activeContent, spec.Content) {
+			continue
+		}
+
+		// Versions are immutable: a change is a new version, which becomes active.
+		version.TemplateID = current.ID
+		created, err := rn.q.CreatePromptTemplateVersion(ctx, version)
+		if err != nil {
+			return nil, fmt.Errorf("prompt template %q: %w", spec.Name, err)
+		}
+		if _, err := rn.q.SetPromptTemplateActiveVersion(ctx, database.SetPromptTemplateActiveVersionParams{
+			ID:            current.ID,
+			UserID:        rn.userID,
+			ActiveVersion: created.Version,
+		}); err != nil {
+			return nil, fmt.Errorf("prompt template %q: %w", spec.Name, err)
+		}
+		rn.record(ctx, ActionUpdate, KindPromptTemplate, spec.Name, current.ID, promptTemplateFields(activeContent, spec.Content),
+			PromptTemplate{Name: spec.Name, Content: activeContent}, spec)
+	}
+	return sortedValues(byName), nil
+}
+
+// promptTemplateFields lists the parts of a template's content that differ.
+func promptTemplateFields(a, b prompttemplate.Content) []string {
+	a, b = a.Normalize(), b.Normalize()
+	var fields []string
+	if a.Mode != b.Mode {
+		fields = append(fields, "mode")
+	}
+	if a.SystemPrompt != b.SystemPrompt {
+		fields = append(fields, "system_prompt")
+	}
+	if !prompttemplate.Equal(prompttemplate.Content{Messages: a.Messages}, prompttemplate.Content{Messages: b.Messages}) {
+		fields = append(fields, "messages")
+	}
+	if !prompttemplate.Equal(prompttemplate.Content{Variables: a.Variables}, prompttemplate.Content{Variables: b.Variables}) {
+		fields = append(fields, "variables")
+	}
+	return fields
+}
+
+func (rn *run) reconcileAPIKeys(ctx context.Context) ([]database.ApiKey, error) {
+	existing, err := rn.q.ListManagedAPIKeys(ctx, rn.userID)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]database.ApiKey{}
+	for _, k := range existing {
+		byName[k.Name] = k
+	}
+
+	for _, spec := range rn.file.APIKeys {
+		current, ok := byName[spec.Name]
+		delete(byName, spec.Name)
+
+		key, err := spec.Key.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("api key %q: key: %w", spec.Name, err)
+		}
+		if len(key) < minAPIKeyLength {
+			return nil, fmt.Errorf("api key %q: key must be at least %d characters long", spec.Name, minAPIKeyLength)
+		}
+		hash := sha256.Sum256([]byte(key))
+		keyHash := hex.EncodeToString(hash[:])
+
+		var budget pgtype.Numeric
+		if spec.MonthlyBudget != nil {
+			if budget, err = toNumeric(*spec.MonthlyBudget); err != nil {
+				return nil, fmt.Errorf("api key %q: monthly_budget: %w", spec.Name, err)
+			}
+		}
+		var allowed []string
+		if len(spec.AllowedModels) > 0 {
+			allowed = spec.AllowedModels
+		}
+
+		if !ok {
+			created, err := rn.q.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+				UserID:        rn.userID,
+				KeyHash:       keyHash,
+				Name:          spec.Name,
+				Managed:       true,
+				AllowedModels: allowed,
+				MonthlyBudget: budget,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("api key %q: %w", spec.Name, err)
+			}
+			rn.record(ctx, ActionCreate, KindAPIKey, spec.Name, created.ID, nil, nil, spec)
+			continue
+		}
+
+		var fields []string
+		if current.KeyHash != keyHash {
+			fields = append(fields, "key")
+		}
+		if !sameSet(current.AllowedModels, allowed) {
+			fields = append(fields, "allowed_models")
+		}
+		if spec.MonthlyBudget == nil && current.MonthlyBudget.Valid ||
+			spec.MonthlyBudget != nil && !sameAmount(current.MonthlyBudget, *spec.MonthlyBudget, budgetScale) {
+			fields = append(fields, "monthly_budget")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := rn.q.UpdateAPIKeyPolicy(ctx, database.UpdateAPIKeyPolicyParams{
+			ID:            current.ID,
+			UserID:        rn.userID,
+			KeyHash:       keyHash,
+			AllowedModels: allowed,
+			MonthlyBudget: budget,
+		}); err != nil {
+			return nil, fmt.Errorf("api key %q: %w", spec.Name, err)
+		}
+		rn.record(ctx, ActionUpdate, KindAPIKey, spec.Name, current.ID, fields, apiKeySnapshot(current), spec)
+	}
+	return sortedValues(byName), nil
+}
+
+// record adds a change to the plan and appends it to the audit trail. The
+// snapshots are declarative specs, which only ever reference secrets.
+func (rn *run) record(ctx context.Context, action Action, kind, name string, id pgtype.UUID, fields []string, before, after any) {
+	rn.plan = append(rn.plan, Change{Action: action, Kind: kind, Name: name, Fields: fields})
+
+	params := database.CreateAuditLogParams{
+		ActorID:      rn.userID,
+		Action:       string(action),
+		ResourceType: kind,
+		ResourceID:   id.String(),
+		UserAgent:    auditUserAgent,
+	}
+	if before != nil {
+		params.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		params.After, _ = json.Marshal(after)
+	}
+	if _, err := rn.q.CreateAuditLog(ctx, params); err != nil {
+		slog.ErrorContext(ctx, "Error writing audit log", "action", action, "resource_type", kind, "resource_id", id.String(), "error", err)
+	}
+}
+
+func providerSnapshot(p database.Provider) Provider {
+	return Provider{Name: p.Name, Type: p.Type, BaseURL: p.BaseUrl, APIVersion: p.ApiVersion, Region: p.Region}
+}
+
+func (rn *run) connectionSnapshot(c database.Connection) Connection {
+	return Connection{Name: c.Name, Provider: nameOf(rn.providerIDs, c.ProviderID)}
+}
+
+func (rn *run) modelSnapshot(m database.Model) Model {
+	return Model{
+		ProxyModelID:     m.ProxyModelID,
+		Connection:       nameOf(rn.connectionIDs, m.ConnectionID.String()),
+		ProviderModelID:  m.ProviderModelID,
+		Type:             m.Type,
+		PriceInput:       numericFloat(m.PriceInput),
+		PriceOutput:      numericFloat(m.PriceOutput),
+		Thinking:         m.Thinking,
+		ToolsUsage:       m.ToolsUsage,
+		LogPolicy:        m.LogPolicy,
+		ParamPolicy:      parampolicy.Decode(m.ParamPolicy),
+		PromptTemplate:   rn.promptTemplateName(m.PromptTemplateID),
+		StructuredOutput: structuredoutput.Decode(m.StructuredOutput),
+		Shadow:           ShadowOf(shadow.Decode(m.Shadow), func(id string) string { return nameOf(rn.connectionIDs, id) }),
+	}
+}
+
+func (rn *run) promptTemplateName(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return nameOf(rn.promptTemplateIDs, id.String())
+}
+
+func apiKeySnapshot(k database.ApiKey) APIKey {
+	snapshot := APIKey{Name: k.Name, AllowedModels: k.AllowedModels}
+	if k.MonthlyBudget.Valid {
+		budget := numericFloat(k.MonthlyBudget)
+		snapshot.MonthlyBudget = &budget
+	}
+	return snapshot
+}
+
+// nameOf finds the declared name of a resource ID, falling back to the ID
+// for resources that are no longer in the file.
+func nameOf(ids map[string]pgtype.UUID, id string) string {
+	for name, candidate := range ids {
+		if candidate.String() == id {
+			return name
+		}
+	}
+	return id
+}
+
+// sortedValues returns the stale resources in a stable order so plans are reproducible.
+func sortedValues[T any](m map[string]T) []T {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	values := make([]T, 0, len(m))
+	for _, name := range names {
+		values = append(values, m[name])
+	}
+	return values
+}
+
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func toNumeric(f float64) (pgtype.Numeric, error) {
+	var n pgtype.Numeric
+	err := n.Scan(strconv.FormatFloat(f, 'f', -1, 64))
+	return n, err
+}
+
+func numericFloat(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil {
+		return 0
+	}
+	return f.Float64
+}
+
+// sameAmount compares a stored amount with a declared one at the column's scale.
+func sameAmount(n pgtype.Numeric, f float64, scale int) bool {
+	if !n.Valid {
+		return false
+	}
+	factor := math.Pow10(scale)
+	return math.Round(numericFloat(n)*factor) == math.Round(f*factor)
+}
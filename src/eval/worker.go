@@ -0,0 +1,190 @@
+// Package eval runs evaluation runs: a sample of logged chat requests is
+// replayed against a candidate model by a pool of workers, through the
+// regular proxy endpoints, and each answer is stored next to the logged one,
+// optionally scored by a judge model.
+package eval
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/metrics"
+	"gen-ai-proxy/src/workerpool"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Run statuses.
+const (
+	StatusInProgress = "in_progress"
+	StatusCancelling = "cancelling"
+	StatusCompleted  = "completed"
+	StatusCancelled  = "cancelled"
+)
+
+// Result statuses.
+const (
+	ResultPending   = "pending"
+	ResultRunning   = "running"
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultCancelled = "cancelled"
+)
+
+// Result is the outcome of replaying one logged request.
+type Result struct {
+	StatusCode int
+	RequestID  string
+	// Output is the candidate's answer; Error the error message of responses
+	// that are not successful.
+	Output           string
+	Error            string
+	PromptTokens     pgtype.Int8
+	CompletionTokens pgtype.Int8
+	LatencyMs        int64
+
+	// The judge's verdict, when the run has a judge. A judge that fails
+	// leaves the result succeeded, with JudgeError as its error.
+	JudgeScore  pgtype.Int4
+	JudgeReason string
+	JudgeError  string
+}
+
+// Executor replays one logged request. An error means the request could not
+// be sent at all and is retried like a 5xx response.
+type Executor func(ctx context.Context, run database.EvalRun, r database.EvalResult) (Result, error)
+
+// Worker executes pending evaluation results and finalizes finished runs.
+// Several replicas can run workers against the same database.
+type Worker struct {
+	db          database.Store
+	exec        Executor
+	workers     int
+	maxAttempts int32
+}
+
+// NewWorker creates a worker replaying up to workers requests at once, each
+// attempted up to maxAttempts times on errors, 429 and 5xx responses.
+func NewWorker(db database.Store, exec Executor, workers, maxAttempts int) *Worker {
+	if workers <= 0 {
+		workers = 1
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &Worker{db: db, exec: exec, workers: workers, maxAttempts: int32(maxAttempts)}
+}
+
+// Run executes results and finalizes runs until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	workerpool.Pool{Name: "Evaluation", Workers: w.workers, RunOnce: w.RunOnce, Maintain: w.Maintain}.Run(ctx)
+}
+
+// RunOnce claims and executes one pending result. It reports false when
+// there was nothing to run.
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	r, err := w.db.ClaimEvalResult(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claim evaluation result: %w", err)
+	}
+
+	run, err := w.db.GetEvalRunByID(ctx, r.RunID)
+	if err != nil {
+		workerpool.Release(w.db.ReleaseEvalResult, r.ID, "eval_result_id")
+		return true, fmt.Errorf("load evaluation run: %w", err)
+	}
+
+	// The timeout covers the judge too.
+	execCtx, cancel := context.WithTimeout(ctx, workerpool.RequestTimeout)
+	res, execErr := w.exec(execCtx, run, r)
+	cancel()
+	if ctx.Err() != nil {
+		// Shutting down: leave the result to the next worker.
+		workerpool.Release(w.db.ReleaseEvalResult, r.ID, "eval_result_id")
+		return true, ctx.Err()
+	}
+
+	params := database.FinishEvalResultParams{
+		ID:            r.ID,
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	switch {
+	case execErr == nil && res.StatusCode < 300:
+		params.Status = ResultSucceeded
+	case workerpool.Retry(res.StatusCode, execErr, r.Attempts, w.maxAttempts):
+		params.Status = ResultPending
+		params.NextAttemptAt.Time = params.NextAttemptAt.Time.Add(workerpool.Backoff(r.Attempts))
+	default:
+		params.Status = ResultFailed
+	}
+	switch {
+	case execErr != nil:
+		params.Error = text(execErr.Error())
+	case params.Status != ResultPending:
+		params.StatusCode = pgtype.Int4{Int32: int32(res.StatusCode), Valid: true}
+		params.RequestID = text(res.RequestID)
+		params.Output = text(res.Output)
+		params.PromptTokens = res.PromptTokens
+		params.CompletionTokens = res.CompletionTokens
+		params.LatencyMs = pgtype.Int8{Int64: res.LatencyMs, Valid: true}
+		params.JudgeScore = res.JudgeScore
+		params.JudgeReason = text(res.JudgeReason)
+		params.Error = text(res.Error)
+		if res.JudgeError != "" {
+			params.Error = text("judge: " + res.JudgeError)
+		}
+	}
+	result := params.Status
+	if result == ResultPending {
+		result = "retried"
+	}
+	metrics.EvalRequestsTotal.WithLabelValues(result).Inc()
+
+	if err := w.db.FinishEvalResult(ctx, params); err != nil {
+		return true, fmt.Errorf("save evaluation result: %w", err)
+	}
+	return true, execErr
+}
+
+// Maintain requeues results abandoned by dead replicas and finalizes runs
+// whose results have all finished.
+func (w *Worker) Maintain(ctx context.Context) error {
+	if n, err := w.db.RequeueStaleEvalResults(ctx, workerpool.StaleBefore()); err != nil {
+		return fmt.Errorf("requeue stale evaluation results: %w", err)
+	} else if n > 0 {
+		slog.WarnContext(ctx, "Requeued abandoned evaluation results", "count", n)
+	}
+
+	runs, err := w.db.ListFinishedEvalRuns(ctx)
+	if err != nil {
+		return fmt.Errorf("list finished evaluation runs: %w", err)
+	}
+	for _, run := range runs {
+		status := StatusCompleted
+		if run.Status == StatusCancelling {
+			status = StatusCancelled
+		}
+		finished, err := w.db.FinishEvalRun(ctx, database.FinishEvalRunParams{ID: run.ID, CurrentStatus: run.Status, Status: status})
+		if errors.Is(err, sql.ErrNoRows) {
+			// Another replica finalized it first.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("finalize evaluation run %s: %w", uuid.UUID(run.ID.Bytes), err)
+		}
+		slog.InfoContext(ctx, "Evaluation run finished", "eval_run_id", uuid.UUID(finished.ID.Bytes), "status", finished.Status)
+	}
+	return nil
+}
+
+func text(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
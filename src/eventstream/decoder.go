@@ -0,0 +1,138 @@
+// Package eventstream decodes the AWS event stream binary framing
+// (application/vnd.amazon.eventstream) used by streaming AWS APIs such as
+// Bedrock ConverseStream.
+package eventstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	preludeLength = 12
+	crcLength     = 4
+	// maxMessageLength bounds a message, as AWS does.
+	maxMessageLength = 16 << 20
+)
+
+// Message is one frame of an event stream. String header values are kept;
+// headers of other types are skipped.
+type Message struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// Header returns a string header such as :event-type.
+func (m Message) Header(name string) string {
+	return m.Headers[name]
+}
+
+// Decoder reads messages from an event stream.
+type Decoder struct {
+	r io.Reader
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Next returns the next message, or io.EOF at the end of the stream. A
+// truncated or corrupt message is an error.
+func (d *Decoder) Next() (Message, error) {
+	prelude := make([]byte, preludeLength)
+	if _, err := io.ReadFull(d.r, prelude); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Message{}, errors.New("eventstream: truncated prelude")
+		}
+		return Message{}, err
+	}
+	totalLength := binary.BigEndian.Uint32(prelude[0:4])
+	headersLength := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return Message{}, errors.New("eventstream: prelude checksum mismatch")
+	}
+	if totalLength > maxMessageLength || totalLength < preludeLength+crcLength {
+		return Message{}, fmt.Errorf("eventstream: invalid message length %d", totalLength)
+	}
+	// Compared by subtraction: the sum would wrap for a huge headers length.
+	if headersLength > totalLength-preludeLength-crcLength {
+		return Message{}, fmt.Errorf("eventstream: invalid headers length %d", headersLength)
+	}
+
+	rest := make([]byte, totalLength-preludeLength)
+	if _, err := io.ReadFull(d.r, rest); err != nil {
+		return Message{}, errors.New("eventstream: truncated message")
+	}
+	body, checksum := rest[:len(rest)-crcLength], rest[len(rest)-crcLength:]
+	crc := crc32.NewIEEE()
+	crc.Write(prelude)
+	crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(checksum) {
+		return Message{}, errors.New("eventstream: message checksum mismatch")
+	}
+
+	headers, err := decodeHeaders(body[:headersLength])
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Headers: headers, Payload: body[headersLength:]}, nil
+}
+
+// Sizes of the fixed-length header value types, by type number.
+var headerValueLengths = map[byte]int{
+	0: 0,  // true
+	1: 0,  // false
+	2: 1,  // byte
+	3: 2,  // short
+	4: 4,  // integer
+	5: 8,  // long
+	8: 8,  // timestamp
+	9: 16, // uuid
+}
+
+const (
+	headerTypeBytes  = 6
+	headerTypeString = 7
+)
+
+func decodeHeaders(b []byte) (map[string]string, error) {
+	errTruncated := errors.New("eventstream: truncated header")
+	headers := map[string]string{}
+	for len(b) > 0 {
+		nameLength := int(b[0])
+		if len(b) < 1+nameLength+1 {
+			return nil, errTruncated
+		}
+		name := string(b[1 : 1+nameLength])
+		valueType := b[1+nameLength]
+		b = b[2+nameLength:]
+
+		switch valueType {
+		case headerTypeBytes, headerTypeString:
+			if len(b) < 2 {
+				return nil, errTruncated
+			}
+			valueLength := int(binary.BigEndian.Uint16(b))
+			if len(b) < 2+valueLength {
+				return nil, errTruncated
+			}
+			if valueType == headerTypeString {
+				headers[name] = string(b[2 : 2+valueLength])
+			}
+			b = b[2+valueLength:]
+		default:
+			valueLength, ok := headerValueLengths[valueType]
+			if !ok {
+				return nil, fmt.Errorf("eventstream: unknown header type %d", valueType)
+			}
+			if len(b) < valueLength {
+				return nil, errTruncated
+			}
+			b = b[valueLength:]
+		}
+	}
+	return headers, nil
+}
@@ -0,0 +1,44 @@
+package eventstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// frame encodes one message with the given prelude headers length, which
+// need not match the headers actually written.
+func frame(headersLength uint32, headers, payload []byte) []byte {
+	total := uint32(preludeLength + len(headers) + len(payload) + crcLength)
+	b := binary.BigEndian.AppendUint32(nil, total)
+	b = binary.BigEndian.AppendUint32(b, headersLength)
+	b = binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b))
+	b = append(b, headers...)
+	b = append(b, payload...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b))
+}
+
+func TestDecoderNext(t *testing.T) {
+	headers := []byte{11}
+	headers = append(headers, ":event-type"...)
+	headers = append(headers, headerTypeString, 0, 8)
+	headers = append(headers, "metadata"...)
+	msg, err := NewDecoder(bytes.NewReader(frame(uint32(len(headers)), headers, []byte(`{}`)))).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header(":event-type") != "metadata" || string(msg.Payload) != `{}` {
+		t.Fatalf("message = %+v", msg)
+	}
+}
+
+func TestDecoderRejectsHeadersLongerThanMessage(t *testing.T) {
+	// 0xfffffff4 + 16 wraps to 4 in uint32, which once passed the length check.
+	for _, headersLength := range []uint32{3, 0xfffffff4} {
+		b := frame(headersLength, nil, []byte(`{}`))
+		if _, err := NewDecoder(bytes.NewReader(b)).Next(); err == nil {
+			t.Errorf("headers length %#x: no error", headersLength)
+		}
+	}
+}
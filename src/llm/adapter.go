@@ -0,0 +1,239 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Protocol is the API a provider speaks, which decides the proxy endpoints
+// it can be reached through without translation.
+type Protocol string
+
+const (
+	ProtocolOpenAI Protocol = "openai"
+	ProtocolOllama Protocol = "ollama"
+)
+
+// Operation is an upstream API call.
+type Operation string
+
+const (
+	OperationChat       Operation = "chat"
+	OperationEmbeddings Operation = "embeddings"
+	OperationResponses  Operation = "responses"
+)
+
+// ErrUnsupportedOperation is returned when a provider does not serve an
+// operation.
+var ErrUnsupportedOperation = errors.New("operation not supported by the provider")
+
+// Target is where an upstream call goes: a provider's endpoint, the model as
+// the provider names it, and the connection's key.
+type Target struct {
+	BaseURL    string
+	APIVersion string
+	Model      string
+	APIKey     string
+}
+
+// Usage is the token usage reported by a response. Model is the model that
+// answered, when the provider reports it.
+type Usage struct {
+	Model            string
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// Error is an upstream error response in a common form.
+type Error struct {
+	Status  int
+	Type    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Type, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.Status, e.Message)
+}
+
+// RemoteModel is a model listed by a provider.
+type RemoteModel struct {
+	ID      string `json:"id"`
+	OwnedBy string `json:"owned_by,omitempty"`
+}
+
+// StreamDecoder splits a streamed response into the data of its events.
+type StreamDecoder interface {
+	// Next returns the next event, or io.EOF at the end of the stream.
+	Next() ([]byte, error)
+}
+
+// Adapter is what the proxy needs to know about a provider type to call it.
+type Adapter interface {
+	Protocol() Protocol
+	// Supports reports whether the provider serves an operation.
+	Supports(op Operation) bool
+	// NewRequest builds the authenticated POST request of an operation.
+	NewRequest(ctx context.Context, target Target, op Operation, body []byte) (*http.Request, error)
+	// Authenticate adds the connection's credentials to a request.
+	Authenticate(req *http.Request, target Target)
+	// ParseUsage reads the token usage of a response, or of an event of a
+	// stream. It reports false when there is none.
+	ParseUsage(body []byte) (Usage, bool)
+	// StreamDecoder reads the events of a streamed response.
+	StreamDecoder(r io.Reader) StreamDecoder
+	// NormalizeError reads an error response, whatever its shape.
+	NormalizeError(status int, body []byte) *Error
+	// ListModels lists the models the provider serves.
+	ListModels(ctx context.Context, client *http.Client, target Target) ([]RemoteModel, error)
+}
+
+// Transporter is implemented by adapters that answer requests themselves
+// instead of sending them over the network.
+type Transporter interface {
+	Transport() http.RoundTripper
+}
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   = map[ProviderType]Adapter{}
+)
+
+// Register makes an adapter available for a provider type, replacing any
+// previous one.
+func Register(providerType ProviderType, adapter Adapter) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+	adapters[providerType] = adapter
+}
+
+// AdapterFor returns the adapter of a provider type. Providers whose API
+// the proxy translates itself have none.
+func AdapterFor(providerType ProviderType) (Adapter, bool) {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+	adapter, ok := adapters[providerType]
+	return adapter, ok
+}
+
+// Registered lists the provider types with an adapter.
+func Registered() []ProviderType {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+	types := make([]ProviderType, 0, len(adapters))
+	for t := range adapters {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func init() {
+	Register(ProviderOpenAI, OpenAI{})
+	Register(ProviderAzureOpenAI, AzureOpenAI{})
+	Register(ProviderOllama, Ollama{})
+	Register(ProviderMock, Mock{})
+}
+
+// StreamUsage reads the token usage of a streamed response: the last usage
+// reported by one of its events.
+func StreamUsage(adapter Adapter, body []byte) (Usage, bool) {
+	var usage Usage
+	var found bool
+	decoder := adapter.StreamDecoder(bytes.NewReader(body))
+	for {
+		event, err := decoder.Next()
+		if err != nil {
+			return usage, found
+		}
+		if u, ok := adapter.ParseUsage(event); ok {
+			usage, found = u, true
+		}
+	}
+}
+
+// newJSONRequest builds a POST request with a JSON body.
+func newJSONRequest(ctx context.Context, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// getJSON sends an authenticated GET request and returns the body of a
+// successful response; any other response is the adapter's error.
+func getJSON(ctx context.Context, client *http.Client, adapter Adapter, target Target, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	adapter.Authenticate(req, target)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, adapter.NormalizeError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// lineDecoder returns the non-empty lines of a stream, optionally only those
+// with a prefix, which is removed.
+type lineDecoder struct {
+	scanner *bufio.Scanner
+	prefix  string
+	skip    string
+}
+
+func newLineDecoder(r io.Reader, prefix, skip string) *lineDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	return &lineDecoder{scanner: scanner, prefix: prefix, skip: skip}
+}
+
+func (d *lineDecoder) Next() ([]byte, error) {
+	for d.scanner.Scan() {
+		line := strings.TrimSpace(d.scanner.Text())
+		if d.prefix != "" {
+			data, ok := strings.CutPrefix(line, d.prefix)
+			if !ok {
+				continue
+			}
+			line = strings.TrimSpace(data)
+		}
+		if line == "" || line == d.skip {
+			continue
+		}
+		return []byte(line), nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// errorMessage is the message of an error body that is not JSON.
+func errorMessage(status int, body []byte) string {
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return message
+	}
+	return http.StatusText(status)
+}
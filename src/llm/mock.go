@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"gen-ai-proxy/src/mockprovider"
+)
+
+// mockTransport is shared by all mock providers, so scripted answers keep
+// their place between requests.
+var mockTransport = &mockprovider.Transport{}
+
+// ConfigureMock enables or disables the mock provider, which refuses every
+// request until an operator enables it, and sets its fixtures directory.
+func ConfigureMock(enabled bool, fixturesDir string) {
+	mockTransport.Configure(enabled, fixturesDir)
+}
+
+// Mock is the adapter of the built-in mock provider, which speaks the OpenAI
+// API in process. Its base URL configures it, and defaults to mock://local.
+type Mock struct{}
+
+func (Mock) Protocol() Protocol { return ProtocolOpenAI }
+
+func (Mock) Supports(op Operation) bool {
+	return op == OperationChat || op == OperationEmbeddings
+}
+
+func (a Mock) NewRequest(ctx context.Context, target Target, op Operation, body []byte) (*http.Request, error) {
+	if !a.Supports(op) {
+		return nil, ErrUnsupportedOperation
+	}
+	requestURL, err := mockURL(target, openAIPaths[op])
+	if err != nil {
+		return nil, err
+	}
+	return newJSONRequest(ctx, requestURL, body)
+}
+
+// Authenticate does nothing: the mock provider accepts any key.
+func (Mock) Authenticate(req *http.Request, target Target) {}
+
+func (Mock) ParseUsage(body []byte) (Usage, bool) {
+	return parseOpenAIUsage(body)
+}
+
+func (Mock) StreamDecoder(r io.Reader) StreamDecoder {
+	return newLineDecoder(r, "data:", "[DONE]")
+}
+
+func (Mock) NormalizeError(status int, body []byte) *Error {
+	return normalizeOpenAIError(status, body)
+}
+
+func (a Mock) ListModels(ctx context.Context, client *http.Client, target Target) ([]RemoteModel, error) {
+	modelsURL, err := mockURL(target, "/models")
+	if err != nil {
+		return nil, err
+	}
+	return listOpenAIModels(ctx, client, a, target, modelsURL)
+}
+
+func (Mock) Transport() http.RoundTripper { return mockTransport }
+
+// mockURL is the URL of an API path of a mock provider, keeping the base
+// URL's settings.
+func mockURL(target Target, path string) (string, error) {
+	base := target.BaseURL
+	if base == "" {
+		base = mockprovider.Scheme + "://local"
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	u.Path += path
+	return u.String(), nil
+}
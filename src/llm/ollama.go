@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// ollamaPaths are the API paths of the operations.
+var ollamaPaths = map[Operation]string{
+	OperationChat:       "/api/chat",
+	OperationEmbeddings: "/api/embed",
+}
+
+// Ollama is the adapter of Ollama. It is usually not authenticated; a
+// connection key, when set, is sent as a bearer token for Ollama behind a
+// gateway.
+type Ollama struct{}
+
+func (Ollama) Protocol() Protocol { return ProtocolOllama }
+
+func (Ollama) Supports(op Operation) bool {
+	_, ok := ollamaPaths[op]
+	return ok
+}
+
+func (a Ollama) NewRequest(ctx context.Context, target Target, op Operation, body []byte) (*http.Request, error) {
+	path, ok := ollamaPaths[op]
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+	req, err := newJSONRequest(ctx, target.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	a.Authenticate(req, target)
+	return req, nil
+}
+
+func (Ollama) Authenticate(req *http.Request, target Target) {
+	if target.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+target.APIKey)
+	}
+}
+
+// ParseUsage reads the counts of a response, or of the last message of a
+// stream: prompt_eval_count and eval_count.
+func (Ollama) ParseUsage(body []byte) (Usage, bool) {
+	var resp struct {
+		Model           string `json:"model"`
+		PromptEvalCount *int64 `json:"prompt_eval_count"`
+		EvalCount       *int64 `json:"eval_count"`
+	}
+	if json.Unmarshal(body, &resp) != nil || (resp.PromptEvalCount == nil && resp.EvalCount == nil) {
+		return Usage{}, false
+	}
+	usage := Usage{Model: resp.Model}
+	if resp.PromptEvalCount != nil {
+		usage.PromptTokens = *resp.PromptEvalCount
+	}
+	if resp.EvalCount != nil {
+		usage.CompletionTokens = *resp.EvalCount
+	}
+	return usage, true
+}
+
+// StreamDecoder reads newline-delimited JSON messages.
+func (Ollama) StreamDecoder(r io.Reader) StreamDecoder {
+	return newLineDecoder(r, "", "")
+}
+
+// NormalizeError reads {"error": "..."}.
+func (Ollama) NormalizeError(status int, body []byte) *Error {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error != "" {
+		return &Error{Status: status, Message: resp.Error}
+	}
+	return &Error{Status: status, Message: errorMessage(status, body)}
+}
+
+// ListModels lists the local models.
+func (a Ollama) ListModels(ctx context.Context, client *http.Client, target Target) ([]RemoteModel, error) {
+	body, err := getJSON(ctx, client, a, target, target.BaseURL+"/api/tags")
+	if err != nil {
+		return nil, err
+	}
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, err
+	}
+	models := make([]RemoteModel, len(tags.Models))
+	for i, m := range tags.Models {
+		models[i] = RemoteModel{ID: m.Name}
+	}
+	return models, nil
+}
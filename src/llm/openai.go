@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// openAIPaths are the API paths of the operations.
+var openAIPaths = map[Operation]string{
+	OperationChat:       "/chat/completions",
+	OperationEmbeddings: "/embeddings",
+	OperationResponses:  "/responses",
+}
+
+// OpenAI is the adapter of OpenAI and compatible providers, authenticated
+// with a bearer token.
+type OpenAI struct{}
+
+func (OpenAI) Protocol() Protocol { return ProtocolOpenAI }
+
+func (OpenAI) Supports(op Operation) bool {
+	_, ok := openAIPaths[op]
+	return ok
+}
+
+func (a OpenAI) NewRequest(ctx context.Context, target Target, op Operation, body []byte) (*http.Request, error) {
+	path, ok := openAIPaths[op]
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+	req, err := newJSONRequest(ctx, target.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	a.Authenticate(req, target)
+	return req, nil
+}
+
+func (OpenAI) Authenticate(req *http.Request, target Target) {
+	req.Header.Set("Authorization", "Bearer "+target.APIKey)
+}
+
+func (OpenAI) ParseUsage(body []byte) (Usage, bool) {
+	return parseOpenAIUsage(body)
+}
+
+// StreamDecoder reads the data of server-sent events, up to [DONE].
+func (OpenAI) StreamDecoder(r io.Reader) StreamDecoder {
+	return newLineDecoder(r, "data:", "[DONE]")
+}
+
+func (OpenAI) NormalizeError(status int, body []byte) *Error {
+	return normalizeOpenAIError(status, body)
+}
+
+func (a OpenAI) ListModels(ctx context.Context, client *http.Client, target Target) ([]RemoteModel, error) {
+	return listOpenAIModels(ctx, client, a, target, target.BaseURL+"/models")
+}
+
+// AzureOpenAI is the adapter of Azure OpenAI, which serves the OpenAI API per
+// deployment (the model) and API version, authenticated with an api-key
+// header. The Responses API is not served.
+type AzureOpenAI struct{}
+
+func (AzureOpenAI) Protocol() Protocol { return ProtocolOpenAI }
+
+func (AzureOpenAI) Supports(op Operation) bool {
+	return op == OperationChat || op == OperationEmbeddings
+}
+
+func (a AzureOpenAI) NewRequest(ctx context.Context, target Target, op Operation, body []byte) (*http.Request, error) {
+	if !a.Supports(op) {
+		return nil, ErrUnsupportedOperation
+	}
+	requestURL := target.BaseURL + "/openai/deployments/" + url.PathEscape(target.Model) + openAIPaths[op] +
+		"?api-version=" + url.QueryEscape(target.APIVersion)
+	req, err := newJSONRequest(ctx, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	a.Authenticate(req, target)
+	return req, nil
+}
+
+func (AzureOpenAI) Authenticate(req *http.Request, target Target) {
+	req.Header.Set("api-key", target.APIKey)
+}
+
+func (AzureOpenAI) ParseUsage(body []byte) (Usage, bool) {
+	return parseOpenAIUsage(body)
+}
+
+func (AzureOpenAI) StreamDecoder(r io.Reader) StreamDecoder {
+	return newLineDecoder(r, "data:", "[DONE]")
+}
+
+func (AzureOpenAI) NormalizeError(status int, body []byte) *Error {
+	return normalizeOpenAIError(status, body)
+}
+
+// ListModels lists the models of the resource, which deployments are made of.
+func (a AzureOpenAI) ListModels(ctx context.Context, client *http.Client, target Target) ([]RemoteModel, error) {
+	return listOpenAIModels(ctx, client, a, target, target.BaseURL+"/openai/models?api-version="+url.QueryEscape(target.APIVersion))
+}
+
+// parseOpenAIUsage reads the usage of chat completions and embeddings
+// (prompt and completion tokens) and of the Responses API (input and output
+// tokens).
+func parseOpenAIUsage(body []byte) (Usage, bool) {
+	var resp struct {
+		Model string `json:"model"`
+		Usage *struct {
+			PromptTokens     int64 `json:"prompt_tokens"`
+			CompletionTokens int64 `json:"completion_tokens"`
+			InputTokens      int64 `json:"input_tokens"`
+			OutputTokens     int64 `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Usage == nil {
+		return Usage{}, false
+	}
+	return Usage{
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens + resp.Usage.InputTokens,
+		CompletionTokens: resp.Usage.CompletionTokens + resp.Usage.OutputTokens,
+	}, true
+}
+
+// normalizeOpenAIError reads {"error": {"message", "type"}}, or an error
+// given as a string.
+func normalizeOpenAIError(status int, body []byte) *Error {
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && len(resp.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		}
+		if json.Unmarshal(resp.Error, &detail) == nil && detail.Message != "" {
+			return &Error{Status: status, Type: detail.Type, Message: detail.Message}
+		}
+		var message string
+		if json.Unmarshal(resp.Error, &message) == nil && message != "" {
+			return &Error{Status: status, Message: message}
+		}
+	}
+	return &Error{Status: status, Message: errorMessage(status, body)}
+}
+
+func listOpenAIModels(ctx context.Context, client *http.Client, adapter Adapter, target Target, modelsURL string) ([]RemoteModel, error) {
+	body, err := getJSON(ctx, client, adapter, target, modelsURL)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Data []RemoteModel `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	return list.Data, nil
+}
@@ -5,4 +5,8 @@ type ProviderType string
 const (
 	ProviderOpenAI  ProviderType = "openai"
 	ProviderOllama ProviderType = "ollama"
+	ProviderGemini ProviderType = "gemini"
+	ProviderAzureOpenAI ProviderType = "azure-openai"
+	ProviderBedrock ProviderType = "bedrock"
+	ProviderMock ProviderType = "mock"
 )
@@ -0,0 +1,71 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// RedactionsTotal counts values masked before conversation logs are persisted.
+var RedactionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gen_ai_proxy_redactions_total",
+		Help: "Total number of sensitive values redacted from conversation logs by detector and model.",
+	},
+	[]string{"detector", "model_name"},
+)
+
+// LogPayloadsTotal counts persisted conversation logs by the log policy applied.
+var LogPayloadsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gen_ai_proxy_log_payloads_total",
+		Help: "Total number of conversation logs persisted by log policy.",
+	},
+	[]string{"policy"},
+)
+
+// StructuredOutputValidationsTotal counts chat outputs checked against a JSON
+// Schema, one per upstream attempt.
+var StructuredOutputValidationsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gen_ai_proxy_structured_output_validations_total",
+		Help: "Total number of chat outputs validated against a JSON Schema by model and result (valid or invalid).",
+	},
+	[]string{"model_name", "result"},
+)
+
+// StructuredOutputFailuresTotal counts requests answered with an error because
+// the output still did not match its schema after the allowed retries.
+var StructuredOutputFailuresTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gen_ai_proxy_structured_output_failures_total",
+		Help: "Total number of requests failed because the output did not match its JSON Schema after all retries.",
+	},
+	[]string{"model_name"},
+)
+
+// BatchRequestsTotal counts executions of batch requests by outcome: retried
+// executions are scheduled again, the others are final.
+var BatchRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gen_ai_proxy_batch_requests_total",
+		Help: "Total number of batch request executions by endpoint and result (succeeded, failed or retried).",
+	},
+	[]string{"endpoint", "result"},
+)
+
+// EvalRequestsTotal counts replays of logged requests by evaluation runs, by
+// outcome like BatchRequestsTotal.
+var EvalRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gen_ai_proxy_eval_requests_total",
+		Help: "Total number of evaluation run request executions by result (succeeded, failed or retried).",
+	},
+	[]string{"result"},
+)
+
+// ShadowRequestsTotal counts requests mirrored to the shadow target of their
+// model, by outcome of the mirror.
+var ShadowRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gen_ai_proxy_shadow_requests_total",
+		Help: "Total number of mirrored requests by model, shadow connection, shadow provider model and result (success or error).",
+	},
+	[]string{"model_name", "shadow_connection", "shadow_model", "result"},
+)
@@ -4,20 +4,20 @@ import (
 	"context"
 
 	"gen-ai-proxy/src/database"
-	"log"
+	"log/slog"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type MetricsCollector struct {
-	db *database.Queries
+	db database.Querier
 	totalTokens *prometheus.Desc
 	totalPrice *prometheus.Desc
 	totalInputTokensByModel *prometheus.Desc
 	totalOutputTokensByModel *prometheus.Desc
 }
 
-func NewMetricsCollector(db *database.Queries) *MetricsCollector {
+func NewMetricsCollector(db database.Querier) *MetricsCollector {
 	return &MetricsCollector{
 		db: db,
 		totalTokens: prometheus.NewDesc(
@@ -61,13 +61,12 @@ func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 
 	tokenStats, err := c.db.GetTotalTokensByProviderModelConnection(ctx)
 	if err != nil {
-		log.Printf("Error querying total tokens: %v", err)
+		slog.Error("Error querying total tokens", "error", err)
 		return
 	}
-	log.Printf("Retrieved token stats: %+v", tokenStats)
+	slog.Debug("Retrieved token stats", "rows", len(tokenStats))
 
 	for _, stat := range tokenStats {
-		log.Printf("Collecting totalTokens: provider_id=%s, provider_name=%s, model_id=%s, model_name=%s, connection_id=%s, connection_name=%s, value=%f", stat.ProviderID.String(), stat.ProviderName, stat.ModelID.String(), stat.ModelName, stat.ConnectionID.String(), stat.ConnectionName, float64(stat.TotalTokens))
 		ch <- prometheus.MustNewConstMetric(
 			c.totalTokens,
 			prometheus.CounterValue,
@@ -83,24 +82,20 @@ func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 
 	priceStats, err := c.db.GetTotalPriceByProviderModelConnection(ctx)
 	if err != nil {
-		log.Printf("Error querying total price: %v", err)
+		slog.Error("Error querying total price", "error", err)
 		return
 	}
-	// log.Printf("Retrieved price stats: %+v", priceStats)
 
 	for _, stat := range priceStats {
 		var priceValue float64
 		if stat.TotalPrice.Valid {
 			pgFloat8Value, err := stat.TotalPrice.Float64Value()
 			if err != nil {
-				log.Printf("Error converting TotalPrice to float64: %v", err)
+				slog.Error("Error converting TotalPrice to float64", "error", err)
 				continue
 			}
 			priceValue = pgFloat8Value.Float64
 		}
-		// log.Printf("Collecting totalPrice: provider_id=%s, provider_name=%s, model_id=%s, model_name=%s, connection_id=%s, connection_name=%s, value=%f",
-		// 	stat.ProviderID.String(), stat.ProviderName, stat.ModelID.String(), stat.ModelName, stat.ConnectionID.String(), stat.ConnectionName, priceValue,
-		// )
 		ch <- prometheus.MustNewConstMetric(
 			c.totalPrice,
 			prometheus.CounterValue,
@@ -116,13 +111,11 @@ func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 
 	inputTokenStats, err := c.db.GetTotalInputTokensByProviderModelConnection(ctx)
 	if err != nil {
-		log.Printf("Error querying total input tokens by model: %v", err)
+		slog.Error("Error querying total input tokens by model", "error", err)
 		return
 	}
-	// log.Printf("Retrieved input token stats by model: %+v", inputTokenStats)
 
 	for _, stat := range inputTokenStats {
-		// log.Printf("Collecting totalInputTokensByModel: provider_id=%s, provider_name=%s, model_id=%s, model_name=%s, connection_id=%s, connection_name=%s, value=%f", stat.ProviderID.String(), stat.ProviderName, stat.ModelID.String(), stat.ModelName, stat.ConnectionID.String(), stat.ConnectionName, float64(stat.TotalInputTokens))
 		ch <- prometheus.MustNewConstMetric(
 			c.totalInputTokensByModel,
 			prometheus.CounterValue,
@@ -138,13 +131,12 @@ func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 
 	outputTokenStats, err := c.db.GetTotalOutputTokensByProviderModelConnection(ctx)
 	if err != nil {
-		log.Printf("Error querying total output tokens by model: %v", err)
+		slog.Error("Error querying total output tokens by model", "error", err)
 		return
 	}
-	log.Printf("Retrieved output token stats by model: %+v", outputTokenStats)
+	slog.Debug("Retrieved output token stats by model", "rows", len(outputTokenStats))
 
 	for _, stat := range outputTokenStats {
-		// log.Printf("Collecting totalOutputTokensByModel: provider_id=%s, provider_name=%s, model_id=%s, model_name=%s, connection_id=%s, connection_name=%s, value=%f", stat.ProviderID.String(), stat.ProviderName, stat.ModelID.String(), stat.ModelName, stat.ConnectionID.String(), stat.ConnectionName, float64(stat.TotalOutputTokens))
 		ch <- prometheus.MustNewConstMetric(
 			c.totalOutputTokensByModel,
 			prometheus.CounterValue,
@@ -0,0 +1,171 @@
+// Package mockprovider simulates an OpenAI compatible provider in process,
+// for testing clients and the proxy itself without a real upstream.
+package mockprovider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures configure the answers of the mock provider. Rules are tried in
+// order against the last user message; a prompt no rule matches is echoed.
+type Fixtures struct {
+	// Latency delays every response, or the first chunk of a stream.
+	Latency time.Duration `yaml:"latency"`
+	// ChunkDelay separates the chunks of a stream.
+	ChunkDelay time.Duration `yaml:"chunk_delay"`
+	Rules      []*Rule       `yaml:"rules"`
+}
+
+// Rule is a canned or scripted answer, or a failure, for prompts matching a
+// regular expression.
+type Rule struct {
+	Match string `yaml:"match"`
+	// Content is the answer. Contents are answered in turn, one per
+	// request, starting over after the last.
+	Content  string   `yaml:"content"`
+	Contents []string `yaml:"contents"`
+	// Status fails the request with an OpenAI error of that status, such as
+	// 429 or 500, and Error is its message.
+	Status int    `yaml:"status"`
+	Error  string `yaml:"error"`
+	// Timeout never answers, so the request ends with the caller's timeout.
+	Timeout bool `yaml:"timeout"`
+	// Malformed answers a truncated JSON body.
+	Malformed bool `yaml:"malformed"`
+	// Latency and ChunkDelay override the fixtures' defaults.
+	Latency    *time.Duration `yaml:"latency"`
+	ChunkDelay *time.Duration `yaml:"chunk_delay"`
+	// Usage overrides the token counts, which otherwise count words.
+	Usage *Usage `yaml:"usage"`
+
+	pattern *regexp.Regexp
+	mu      sync.Mutex
+	next    int
+}
+
+type Usage struct {
+	PromptTokens     int64 `yaml:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int64 `yaml:"completion_tokens" json:"completion_tokens"`
+}
+
+// LoadFixtures reads fixtures from a YAML file.
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFixtures(data)
+}
+
+// ParseFixtures reads fixtures from YAML and compiles their rules.
+func ParseFixtures(data []byte) (*Fixtures, error) {
+	var f Fixtures
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("mock fixtures: %w", err)
+	}
+	for i, rule := range f.Rules {
+		pattern, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("mock fixtures: rules[%d].match: %w", i, err)
+		}
+		rule.pattern = pattern
+	}
+	return &f, nil
+}
+
+// rule returns the first rule matching a prompt, or nil.
+func (f *Fixtures) rule(prompt string) *Rule {
+	for _, rule := range f.Rules {
+		if rule.pattern.MatchString(prompt) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// content is the rule's answer to this request.
+func (r *Rule) content() string {
+	if len(r.Contents) == 0 {
+		return r.Content
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	content := r.Contents[r.next%len(r.Contents)]
+	r.next++
+	return content
+}
+
+// fixtureCache keeps loaded fixture files, reloaded when they change, so
+// scripted rules keep their place between requests.
+type fixtureCache struct {
+	mu      sync.Mutex
+	entries map[string]fixtureEntry
+}
+
+type fixtureEntry struct {
+	modTime  time.Time
+	fixtures *Fixtures
+}
+
+// load reads the fixture file name within dir. Files are opened through an
+// os.Root, so neither the name nor symbolic links can reach outside dir.
+func (c *fixtureCache) load(dir, name string) (*Fixtures, error) {
+	if err := checkFixturesName(name); err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return nil, errors.New("mock fixtures are disabled: MOCK_FIXTURES_DIR is not set")
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	info, err := root.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	key := filepath.Join(dir, name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.fixtures, nil
+	}
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	fixtures, err := ParseFixtures(data)
+	if err != nil {
+		return nil, err
+	}
+	if c.entries == nil {
+		c.entries = map[string]fixtureEntry{}
+	}
+	c.entries[key] = fixtureEntry{modTime: info.ModTime(), fixtures: fixtures}
+	return fixtures, nil
+}
+
+// checkFixturesName accepts fixture file names relative to the fixtures
+// directory that stay within it.
+func checkFixturesName(name string) error {
+	if filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return fmt.Errorf("mock fixtures %q must be a relative path within MOCK_FIXTURES_DIR", name)
+	}
+	return nil
+}
@@ -0,0 +1,481 @@
+package mockprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Scheme is the URL scheme of mock provider base URLs:
+// mock://local?fixtures=fixtures.yaml&latency=100ms&chunk_delay=20ms, where
+// fixtures is relative to the configured fixtures directory.
+const Scheme = "mock"
+
+// embeddingDimensions is the default size of mock embeddings.
+const embeddingDimensions = 8
+
+// Transport answers OpenAI API requests to mock:// URLs: chat completions,
+// streamed or not, embeddings and the model list. It refuses every request
+// until Configure enables it.
+type Transport struct {
+	cache    fixtureCache
+	settings atomic.Pointer[settings]
+}
+
+type settings struct {
+	enabled     bool
+	fixturesDir string
+}
+
+// Configure enables or disables the transport and sets the directory fixture
+// files are read from; without one, base URLs cannot name fixtures.
+func (t *Transport) Configure(enabled bool, fixturesDir string) {
+	t.settings.Store(&settings{enabled: enabled, fixturesDir: fixturesDir})
+}
+
+// ValidateURL checks a mock provider base URL: its scheme, that its fixtures
+// stay within the fixtures directory and its durations.
+func ValidateURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme != Scheme {
+		return errors.New("base_url of mock providers must be a mock:// URL")
+	}
+	query := u.Query()
+	if name := query.Get("fixtures"); name != "" {
+		if err := checkFixturesName(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{"latency", "chunk_delay"} {
+		if v := query.Get(name); v != "" {
+			if _, err := time.ParseDuration(v); err != nil {
+				return fmt.Errorf("mock %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	settings := t.settings.Load()
+	if settings == nil || !settings.enabled {
+		return errorResponse(req, http.StatusForbidden, "permission_error", "mock providers are disabled on this server"), nil
+	}
+	fixtures, err := t.fixtures(req, settings.fixturesDir)
+	if err != nil {
+		// The cause can tell which server files exist, so it is only logged.
+		slog.ErrorContext(req.Context(), "Failed to load mock fixtures", "error", err)
+		return errorResponse(req, http.StatusInternalServerError, "server_error", "mock fixtures could not be loaded"), nil
+	}
+
+	switch {
+	case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/models"):
+		return jsonResponse(req, http.StatusOK, map[string]any{
+			"object": "list",
+			"data":   []map[string]any{{"id": "mock", "object": "model", "owned_by": "gen-ai-proxy"}},
+		}), nil
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/chat/completions"):
+		return t.chat(req, fixtures)
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/embeddings"):
+		return t.embeddings(req, fixtures)
+	default:
+		return errorResponse(req, http.StatusNotFound, "invalid_request_error", "unknown mock endpoint "+req.Method+" "+req.URL.Path), nil
+	}
+}
+
+// fixtures are those of the file named by the URL within dir, with the
+// URL's latency and chunk delay as defaults.
+func (t *Transport) fixtures(req *http.Request, dir string) (*Fixtures, error) {
+	query := req.URL.Query()
+	fixtures := &Fixtures{}
+	if name := query.Get("fixtures"); name != "" {
+		loaded, err := t.cache.load(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		copied := *loaded
+		fixtures = &copied
+	}
+	for name, d := range map[string]*time.Duration{"latency": &fixtures.Latency, "chunk_delay": &fixtures.ChunkDelay} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("mock %s: %w", name, err)
+			}
+			*d = parsed
+		}
+	}
+	return fixtures, nil
+}
+
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	Stream        bool `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// answer is what a rule, or echoing, makes of a request.
+type answer struct {
+	rule       *Rule
+	content    string
+	latency    time.Duration
+	chunkDelay time.Duration
+	usage      Usage
+}
+
+func (t *Transport) chat(req *http.Request, fixtures *Fixtures) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	var chat chatRequest
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return errorResponse(req, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: "+err.Error()), nil
+	}
+
+	var prompt string
+	var promptTokens int64
+	for _, m := range chat.Messages {
+		text := messageText(m.Content)
+		promptTokens += countTokens(text)
+		if m.Role == "user" {
+			prompt = text
+		}
+	}
+	a := resolve(fixtures, prompt)
+	if a.usage.PromptTokens == 0 {
+		a.usage.PromptTokens = promptTokens
+	}
+
+	if resp, err := t.fail(req, a); resp != nil || err != nil {
+		return resp, err
+	}
+
+	id := "chatcmpl-mock-" + digest(body)
+	created := time.Now().Unix()
+	if !chat.Stream {
+		if err := sleep(req.Context(), a.latency); err != nil {
+			return nil, err
+		}
+		return jsonResponse(req, http.StatusOK, map[string]any{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   chat.Model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": a.content},
+				"finish_reason": "stop",
+			}},
+			"usage": usageJSON(a.usage),
+		}), nil
+	}
+
+	chunk := func(delta map[string]any, finishReason any) map[string]any {
+		return map[string]any{
+			"id": id, "object": "chat.completion.chunk", "created": created, "model": chat.Model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+	events := []any{chunk(map[string]any{"role": "assistant", "content": ""}, nil)}
+	for _, piece := range splitWords(a.content) {
+		events = append(events, chunk(map[string]any{"content": piece}, nil))
+	}
+	events = append(events, chunk(map[string]any{}, "stop"))
+	if chat.StreamOptions != nil && chat.StreamOptions.IncludeUsage {
+		events = append(events, map[string]any{
+			"id": id, "object": "chat.completion.chunk", "created": created, "model": chat.Model,
+			"choices": []any{}, "usage": usageJSON(a.usage),
+		})
+	}
+	return streamResponse(req, a, events), nil
+}
+
+// fail answers the failures a rule injects; it returns nothing otherwise.
+func (t *Transport) fail(req *http.Request, a answer) (*http.Response, error) {
+	if a.rule == nil {
+		return nil, nil
+	}
+	switch {
+	case a.rule.Timeout:
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	case a.rule.Status >= 300:
+		if err := sleep(req.Context(), a.latency); err != nil {
+			return nil, err
+		}
+		message := a.rule.Error
+		if message == "" {
+			message = "mock " + strings.ToLower(http.StatusText(a.rule.Status))
+		}
+		errorType := "server_error"
+		switch {
+		case a.rule.Status == http.StatusTooManyRequests:
+			errorType = "rate_limit_exceeded"
+		case a.rule.Status < 500:
+			errorType = "invalid_request_error"
+		}
+		resp := errorResponse(req, a.rule.Status, errorType, message)
+		if a.rule.Status == http.StatusTooManyRequests {
+			resp.Header.Set("Retry-After", "1")
+		}
+		return resp, nil
+	case a.rule.Malformed:
+		if err := sleep(req.Context(), a.latency); err != nil {
+			return nil, err
+		}
+		return rawResponse(req, http.StatusOK, "application/json", []byte(`{"id":"chatcmpl-mock","object":"chat.completion","choices":[{"index":0,"message":{"role":"assis`)), nil
+	}
+	return nil, nil
+}
+
+func resolve(fixtures *Fixtures, prompt string) answer {
+	a := answer{content: prompt, latency: fixtures.Latency, chunkDelay: fixtures.ChunkDelay}
+	rule := fixtures.rule(prompt)
+	if rule == nil {
+		a.usage.CompletionTokens = countTokens(a.content)
+		return a
+	}
+	a.rule = rule
+	if rule.Content != "" || len(rule.Contents) > 0 {
+		a.content = rule.content()
+	}
+	if rule.Latency != nil {
+		a.latency = *rule.Latency
+	}
+	if rule.ChunkDelay != nil {
+		a.chunkDelay = *rule.ChunkDelay
+	}
+	a.usage.CompletionTokens = countTokens(a.content)
+	if rule.Usage != nil {
+		a.usage = *rule.Usage
+	}
+	return a
+}
+
+type embeddingRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"`
+	EncodingFormat string          `json:"encoding_format"`
+	Dimensions     int             `json:"dimensions"`
+}
+
+func (t *Transport) embeddings(req *http.Request, fixtures *Fixtures) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	var embed embeddingRequest
+	if err := json.Unmarshal(body, &embed); err != nil {
+		return errorResponse(req, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: "+err.Error()), nil
+	}
+	var inputs []string
+	if err := json.Unmarshal(embed.Input, &inputs); err != nil {
+		var input string
+		if err := json.Unmarshal(embed.Input, &input); err != nil {
+			return errorResponse(req, http.StatusBadRequest, "invalid_request_error", "input must be a string or an array of strings"), nil
+		}
+		inputs = []string{input}
+	}
+
+	a := resolve(fixtures, strings.Join(inputs, "\n"))
+	if resp, err := t.fail(req, a); resp != nil || err != nil {
+		return resp, err
+	}
+	if err := sleep(req.Context(), a.latency); err != nil {
+		return nil, err
+	}
+
+	dimensions := embed.Dimensions
+	if dimensions <= 0 {
+		dimensions = embeddingDimensions
+	}
+	var promptTokens int64
+	data := make([]map[string]any, len(inputs))
+	for i, input := range inputs {
+		promptTokens += countTokens(input)
+		vector := embedding(input, dimensions)
+		var encoded any = vector
+		if embed.EncodingFormat == "base64" {
+			buf := make([]byte, 4*len(vector))
+			for j, v := range vector {
+				binary.LittleEndian.PutUint32(buf[4*j:], math.Float32bits(v))
+			}
+			encoded = base64.StdEncoding.EncodeToString(buf)
+		}
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": encoded}
+	}
+	if a.rule != nil && a.rule.Usage != nil {
+		promptTokens = a.rule.Usage.PromptTokens
+	}
+	return jsonResponse(req, http.StatusOK, map[string]any{
+		"object": "list",
+		"data":   data,
+		"model":  embed.Model,
+		"usage":  map[string]int64{"prompt_tokens": promptTokens, "total_tokens": promptTokens},
+	}), nil
+}
+
+// embedding derives a unit vector from the text, the same every time.
+func embedding(text string, dimensions int) []float32 {
+	vector := make([]float32, dimensions)
+	var norm float64
+	for i := range vector {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", i, text)))
+		v := float64(binary.BigEndian.Uint32(sum[:4]))/math.MaxUint32*2 - 1
+		vector[i] = float32(v)
+		norm += v * v
+	}
+	if norm = math.Sqrt(norm); norm > 0 {
+		for i := range vector {
+			vector[i] = float32(float64(vector[i]) / norm)
+		}
+	}
+	return vector
+}
+
+// messageText is the text of a message's content, given as a string or as
+// parts.
+func messageText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(content, &parts) != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+// countTokens counts words, which is close enough to tokens for tests.
+func countTokens(text string) int64 {
+	return int64(len(strings.Fields(text)))
+}
+
+// splitWords splits text in pieces of one word with the spaces after it, so
+// the pieces join back to the text.
+func splitWords(text string) []string {
+	var pieces []string
+	start := 0
+	for i := 1; i <= len(text); i++ {
+		if i == len(text) || (text[i-1] == ' ' && text[i] != ' ') {
+			pieces = append(pieces, text[start:i])
+			start = i
+		}
+	}
+	return pieces
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:12])
+}
+
+func usageJSON(u Usage) map[string]int64 {
+	return map[string]int64{
+		"prompt_tokens":     u.PromptTokens,
+		"completion_tokens": u.CompletionTokens,
+		"total_tokens":      u.PromptTokens + u.CompletionTokens,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// streamResponse sends server-sent events after the latency, separated by
+// the chunk delay, ending with [DONE].
+func streamResponse(req *http.Request, a answer, events []any) *http.Response {
+	reader, writer := io.Pipe()
+	go func() {
+		ctx := req.Context()
+		if err := sleep(ctx, a.latency); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		for i, event := range events {
+			if i > 0 {
+				if err := sleep(ctx, a.chunkDelay); err != nil {
+					writer.CloseWithError(err)
+					return
+				}
+			}
+			data, _ := json.Marshal(event)
+			if _, err := writer.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+				return
+			}
+		}
+		writer.Write([]byte("data: [DONE]\n\n"))
+		writer.Close()
+	}()
+	resp := rawResponse(req, http.StatusOK, "text/event-stream", nil)
+	resp.Body = reader
+	resp.ContentLength = -1
+	return resp
+}
+
+func errorResponse(req *http.Request, status int, errorType, message string) *http.Response {
+	return jsonResponse(req, status, map[string]any{
+		"error": map[string]any{"message": message, "type": errorType, "code": nil},
+	})
+}
+
+func jsonResponse(req *http.Request, status int, v any) *http.Response {
+	data, _ := json.Marshal(v)
+	return rawResponse(req, status, "application/json", data)
+}
+
+func rawResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
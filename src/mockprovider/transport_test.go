@@ -0,0 +1,75 @@
+package mockprovider
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateURLKeepsFixturesInDir(t *testing.T) {
+	for _, tc := range []struct {
+		url string
+		ok  bool
+	}{
+		{"mock://local", true},
+		{"mock://local?fixtures=support.yaml&latency=10ms", true},
+		{"mock://local?fixtures=teams/support.yaml", true},
+		{"mock://local?fixtures=/etc/passwd", false},
+		{"mock://local?fixtures=../secrets.yaml", false},
+		{"mock://local?fixtures=teams/../../secrets.yaml", false},
+		{"mock://local?latency=soon", false},
+		{"http://local", false},
+	} {
+		if err := ValidateURL(tc.url); (err == nil) != tc.ok {
+			t.Errorf("ValidateURL(%q) = %v, want ok %v", tc.url, err, tc.ok)
+		}
+	}
+}
+
+func TestTransportReadsFixturesFromDirOnly(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fixtures.yaml"), []byte("rules:\n  - match: hello\n    content: canned\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(outside, []byte("key: s3cr3t-value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	chat := func(tr *Transport, fixtures string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, "mock://local/chat/completions?fixtures="+fixtures,
+			strings.NewReader(`{"model":"m","messages":[{"role":"user","content":"hello"}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	tr := &Transport{}
+	if status, _ := chat(tr, "fixtures.yaml"); status != http.StatusForbidden {
+		t.Errorf("disabled transport answered %d, want 403", status)
+	}
+
+	tr.Configure(true, dir)
+	if status, body := chat(tr, "fixtures.yaml"); status != http.StatusOK || !strings.Contains(body, "canned") {
+		t.Errorf("fixture in dir: status %d, body %s", status, body)
+	}
+	for _, name := range []string{outside, "../" + filepath.Base(outside), "link.yaml", "missing.yaml"} {
+		status, body := chat(tr, name)
+		if status != http.StatusInternalServerError || strings.Contains(body, "s3cr3t") || strings.Contains(body, name) {
+			t.Errorf("fixtures %q: status %d, body %s; want a generic 500", name, status, body)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Package parampolicy applies per-model rules to the parameters of chat
+// requests before they are sent upstream: defaults for parameters the client
+// omitted, forced overrides, allowed ranges, and removal of tools and
+// reasoning parameters the model is not configured for.
+//
+// Parameters use OpenAI names (temperature, max_tokens, reasoning_effort, ...).
+// Handlers for other APIs translate to and from those names.
+package parampolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// kind is the value type a parameter accepts.
+type kind int
+
+const (
+	number kind = iota
+	stopSequences
+	effort
+)
+
+// Parameters lists the parameters a policy can set or bound.
+var Parameters = map[string]kind{
+	"temperature":       number,
+	"top_p":             number,
+	"max_tokens":        number,
+	"seed":              number,
+	"presence_penalty":  number,
+	"frequency_penalty": number,
+	"stop":              stopSequences,
+	"reasoning_effort":  effort,
+}
+
+// ReasoningEfforts are the accepted values of reasoning_effort; "none"
+// disables reasoning.
+var ReasoningEfforts = []string{"none", "minimal", "low", "medium", "high"}
+
+// toolParameters and reasoningParameters are removed from requests to models
+// without tools_usage or thinking.
+var (
+	toolParameters      = []string{"tools", "tool_choice", "parallel_tool_calls"}
+	reasoningParameters = []string{"reasoning_effort"}
+)
+
+// Policy constrains the parameters of requests to one model.
+type Policy struct {
+	// Defaults are applied when the client omits the parameter.
+	Defaults map[string]any `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	// Overrides replace whatever the client sent.
+	Overrides map[string]any `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	// Ranges bound numeric parameters.
+	Ranges map[string]Range `json:"ranges,omitempty" yaml:"ranges,omitempty"`
+}
+
+// Range bounds a numeric parameter. Values outside it are rejected, or moved
+// to the nearest bound when Clamp is set.
+type Range struct {
+	Min   *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	Clamp bool     `json:"clamp,omitempty" yaml:"clamp,omitempty"`
+}
+
+// Violation is returned by Apply when a parameter is outside its range and the
+// request must be rejected.
+type Violation struct {
+	Parameter string
+	Range     Range
+}
+
+func (v *Violation) Error() string {
+	switch {
+	case v.Range.Min != nil && v.Range.Max != nil:
+		return fmt.Sprintf("%s must be between %g and %g", v.Parameter, *v.Range.Min, *v.Range.Max)
+	case v.Range.Min != nil:
+		return fmt.Sprintf("%s must be at least %g", v.Parameter, *v.Range.Min)
+	default:
+		return fmt.Sprintf("%s must be at most %g", v.Parameter, *v.Range.Max)
+	}
+}
+
+// Parse decodes and validates a stored policy. Empty input is no policy.
+func Parse(data []byte) (*Policy, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid parameter policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Decode is Parse for display and comparison: an invalid stored policy
+// decodes as none.
+func Decode(data []byte) *Policy {
+	p, err := Parse(data)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// Marshal encodes the policy for storage; a nil or empty policy is stored as NULL.
+func (p *Policy) Marshal() (json.RawMessage, error) {
+	if p.empty() {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Equal reports whether two policies have the same effect, however they were
+// decoded.
+func Equal(a, b *Policy) bool {
+	if a.empty() || b.empty() {
+		return a.empty() && b.empty()
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	var na, nb Policy
+	if json.Unmarshal(ja, &na) != nil || json.Unmarshal(jb, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+func (p *Policy) empty() bool {
+	return p == nil || len(p.Defaults) == 0 && len(p.Overrides) == 0 && len(p.Ranges) == 0
+}
+
+// Validate checks every parameter is known, has a value of the right type and
+// that defaults and overrides are within their ranges.
+func (p *Policy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, name := range sortedKeys(p.Ranges) {
+		r := p.Ranges[name]
+		if k, ok := Parameters[name]; !ok || k != number {
+			return fmt.Errorf("param_policy.ranges: %q is not a numeric parameter", name)
+		}
+		if r.Min == nil && r.Max == nil {
+			return fmt.Errorf("param_policy.ranges.%s: min or max is required", name)
+		}
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			return fmt.Errorf("param_policy.ranges.%s: min is greater than max", name)
+		}
+	}
+	sections := []struct {
+		name   string
+		values map[string]any
+	}{{"defaults", p.Defaults}, {"overrides", p.Overrides}}
+	for _, section := range sections {
+		for _, name := range sortedKeys(section.values) {
+			value := section.values[name]
+			if err := validateValue(name, value); err != nil {
+				return fmt.Errorf("param_policy.%s.%s: %w", section.name, name, err)
+			}
+			if r, ok := p.Ranges[name]; ok {
+				if f, _ := toFloat(value); !r.contains(f) {
+					return fmt.Errorf("param_policy.%s.%s: %w", section.name, name, &Violation{Parameter: name, Range: r})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateValue(name string, value any) error {
+	k, ok := Parameters[name]
+	if !ok {
+		return fmt.Errorf("unknown parameter (expected one of %s)", strings.Join(sortedKeys(Parameters), ", "))
+	}
+	switch k {
+	case number:
+		if _, ok := toFloat(value); !ok {
+			return fmt.Errorf("must be a number")
+		}
+	case stopSequences:
+		if _, ok := value.(string); ok {
+			return nil
+		}
+		list, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("must be a string or a list of strings")
+		}
+		for _, item := range list {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("must be a string or a list of strings")
+			}
+		}
+	case effort:
+		if s, ok := value.(string); !ok || !slices.Contains(ReasoningEfforts, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(ReasoningEfforts, ", "))
+		}
+	}
+	return nil
+}
+
+// Apply rewrites the request parameters in place: defaults, then overrides,
+// then ranges. Tools are removed unless tools is set and reasoning parameters
+// unless thinking is set. It returns the names of the parameters it removed,
+// or a *Violation when a value is out of range and may not be clamped.
+func (p *Policy) Apply(params map[string]any, tools, thinking bool) ([]string, error) {
+	if p != nil {
+		for name, value := range p.Defaults {
+			if _, ok := params[name]; !ok {
+				params[name] = value
+			}
+		}
+		for name, value := range p.Overrides {
+			params[name] = value
+		}
+		for _, name := range sortedKeys(p.Ranges) {
+			value, ok := params[name]
+			if !ok {
+				continue
+			}
+			r := p.Ranges[name]
+			f, ok := toFloat(value)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a number", name)
+			}
+			if r.contains(f) {
+				continue
+			}
+			if !r.Clamp {
+				return nil, &Violation{Parameter: name, Range: r}
+			}
+			params[name] = r.clamp(f)
+		}
+	}
+
+	var removed []string
+	strip := func(names []string) {
+		for _, name := range names {
+			if _, ok := params[name]; ok {
+				delete(params, name)
+				removed = append(removed, name)
+			}
+		}
+	}
+	if !tools {
+		strip(toolParameters)
+	}
+	if !thinking {
+		strip(reasoningParameters)
+	}
+	return removed, nil
+}
+
+func (r Range) contains(f float64) bool {
+	return (r.Min == nil || f >= *r.Min) && (r.Max == nil || f <= *r.Max)
+}
+
+func (r Range) clamp(f float64) float64 {
+	if r.Min != nil && f < *r.Min {
+		return *r.Min
+	}
+	if r.Max != nil && f > *r.Max {
+		return *r.Max
+	}
+	return f
+}
+
+// toFloat accepts the number types JSON and YAML decoding produce.
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,213 @@
+// Package prompttemplate renders managed system prompts and few-shot messages
+// into chat requests, so clients of a "virtual model" do not each carry a copy
+// of its prompt.
+//
+// Template text refers to variables as {{name}}. Variables are declared with a
+// default value, or with none when every request must supply them. Only the
+// template's own text is rendered; client messages are never interpreted.
+package prompttemplate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Mode decides how the template's system prompt combines with system
+// messages sent by the client.
+type Mode string
+
+const (
+	// ModePrepend puts the template's system prompt and examples before the
+	// client's messages, which are kept unchanged.
+	ModePrepend Mode = "prepend"
+	// ModeMerge appends the client's system messages to the template's system
+	// prompt, so the upstream sees a single system message.
+	ModeMerge Mode = "merge"
+)
+
+// Message is a chat message of a template.
+type Message struct {
+	Role    string `json:"role" yaml:"role"`
+	Content string `json:"content" yaml:"content"`
+}
+
+// Content is what one version of a template adds to requests.
+type Content struct {
+	Mode         Mode      `json:"mode,omitempty" yaml:"mode,omitempty"`
+	SystemPrompt string    `json:"system_prompt,omitempty" yaml:"system_prompt,omitempty"`
+	Messages     []Message `json:"messages,omitempty" yaml:"messages,omitempty"`
+	// Variables maps each variable to its default value; nil makes it required.
+	Variables map[string]*string `json:"variables,omitempty" yaml:"variables,omitempty"`
+}
+
+// Template is the active version of a template, as used for routing.
+type Template struct {
+	ID      [16]byte
+	Name    string
+	Version int32
+	Content
+}
+
+var (
+	placeholder  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// exampleRoles are the roles allowed in few-shot messages; the system prompt
+// has its own field.
+var exampleRoles = []string{"user", "assistant"}
+
+// Validate checks the mode, the roles of the example messages and that every
+// placeholder refers to a declared variable.
+func (c *Content) Validate() error {
+	switch c.Mode {
+	case "", ModePrepend, ModeMerge:
+	default:
+		return fmt.Errorf("mode must be %s or %s", ModePrepend, ModeMerge)
+	}
+	if c.SystemPrompt == "" && len(c.Messages) == 0 {
+		return errors.New("system_prompt or messages is required")
+	}
+	for name := range c.Variables {
+		if !variableName.MatchString(name) {
+			return fmt.Errorf("variables: %q is not a valid variable name", name)
+		}
+	}
+	if err := c.checkPlaceholders("system_prompt", c.SystemPrompt); err != nil {
+		return err
+	}
+	for i, m := range c.Messages {
+		if !slices.Contains(exampleRoles, m.Role) {
+			return fmt.Errorf("messages[%d]: role must be %s", i, strings.Join(exampleRoles, " or "))
+		}
+		if err := c.checkPlaceholders(fmt.Sprintf("messages[%d]", i), m.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Content) checkPlaceholders(field, text string) error {
+	for _, match := range placeholder.FindAllStringSubmatch(text, -1) {
+		if _, ok := c.Variables[match[1]]; !ok {
+			return fmt.Errorf("%s: variable %q is not declared", field, match[1])
+		}
+	}
+	return nil
+}
+
+// Normalize fills in the default mode so stored versions compare equal
+// however they were written.
+func (c Content) Normalize() Content {
+	if c.Mode == "" {
+		c.Mode = ModePrepend
+	}
+	return c
+}
+
+// Equal reports whether two contents render the same requests.
+func Equal(a, b Content) bool {
+	ja, errA := json.Marshal(a.Normalize())
+	jb, errB := json.Marshal(b.Normalize())
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// Decode builds the content of a stored version from its columns.
+func Decode(mode, systemPrompt string, messages, variables []byte) (Content, error) {
+	c := Content{Mode: Mode(mode), SystemPrompt: systemPrompt}
+	if len(messages) > 0 {
+		if err := json.Unmarshal(messages, &c.Messages); err != nil {
+			return Content{}, fmt.Errorf("invalid template messages: %w", err)
+		}
+	}
+	if len(variables) > 0 {
+		if err := json.Unmarshal(variables, &c.Variables); err != nil {
+			return Content{}, fmt.Errorf("invalid template variables: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Encode returns the messages and variables columns of a version.
+func (c Content) Encode() (messages, variables []byte, err error) {
+	msgs := c.Messages
+	if msgs == nil {
+		msgs = []Message{}
+	}
+	vars := c.Variables
+	if vars == nil {
+		vars = map[string]*string{}
+	}
+	if messages, err = json.Marshal(msgs); err != nil {
+		return nil, nil, err
+	}
+	if variables, err = json.Marshal(vars); err != nil {
+		return nil, nil, err
+	}
+	return messages, variables, nil
+}
+
+// Apply renders the template with values, falling back to the variables'
+// defaults, and combines it with the client's messages. Values for variables
+// the template does not declare are ignored, so clients keep working across
+// versions.
+func (c Content) Apply(messages []Message, values map[string]string) ([]Message, error) {
+	vars, err := c.resolve(values)
+	if err != nil {
+		return nil, err
+	}
+	render := func(text string) string {
+		return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+			return vars[placeholder.FindStringSubmatch(match)[1]]
+		})
+	}
+
+	system := render(c.SystemPrompt)
+	rest := messages
+	if c.Mode == ModeMerge {
+		rest = make([]Message, 0, len(messages))
+		for _, m := range messages {
+			if m.Role != "system" {
+				rest = append(rest, m)
+				continue
+			}
+			if system != "" && m.Content != "" {
+				system += "\n\n"
+			}
+			system += m.Content
+		}
+	}
+
+	out := make([]Message, 0, 1+len(c.Messages)+len(rest))
+	if system != "" {
+		out = append(out, Message{Role: "system", Content: system})
+	}
+	for _, m := range c.Messages {
+		out = append(out, Message{Role: m.Role, Content: render(m.Content)})
+	}
+	return append(out, rest...), nil
+}
+
+func (c Content) resolve(values map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(c.Variables))
+	var missing []string
+	for name, def := range c.Variables {
+		if v, ok := values[name]; ok {
+			vars[name] = v
+		} else if def != nil {
+			vars[name] = *def
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing prompt variables: %s", strings.Join(missing, ", "))
+	}
+	return vars, nil
+}
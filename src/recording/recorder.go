@@ -0,0 +1,91 @@
+package recording
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Recorder sends requests on and appends every completed exchange to the
+// fixture file of its host in Dir. Streamed responses are kept as the chunks
+// they were read in, with their timing. Request headers, credentials
+// included, are not recorded.
+type Recorder struct {
+	Dir  string
+	Next http.RoundTripper
+
+	mu sync.Mutex
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, req, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		recorder:   r,
+		host:       req.URL.Host,
+		streamed:   streamed(contentType),
+		last:       start,
+		exchange: &Exchange{
+			Method:      req.Method,
+			Path:        req.URL.Path,
+			Request:     RequestJSON(body),
+			Status:      resp.StatusCode,
+			ContentType: contentType,
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(host string, exchange *Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := Append(r.Dir, host, exchange); err != nil {
+		slog.Warn("Failed to record upstream exchange", "host", host, "error", err)
+	}
+}
+
+// recordingBody records a response as it is read. Responses that are not
+// read to the end are not recorded.
+type recordingBody struct {
+	io.ReadCloser
+	recorder *Recorder
+	host     string
+	exchange *Exchange
+	streamed bool
+	buf      bytes.Buffer
+	last     time.Time
+	saved    bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if b.streamed {
+			now := time.Now()
+			b.exchange.Chunks = append(b.exchange.Chunks, newChunk(now.Sub(b.last).Milliseconds(), p[:n]))
+			b.last = now
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.saved {
+		b.saved = true
+		if !b.streamed {
+			b.exchange.SetBody(b.buf.Bytes())
+		}
+		b.recorder.save(b.host, b.exchange)
+	}
+	return n, err
+}
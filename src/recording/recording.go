@@ -0,0 +1,183 @@
+// Package recording captures upstream provider exchanges to fixture files and
+// serves them back, so the proxy can be tested and bug reports reproduced
+// without a network. Fixtures are JSON lines, one file per upstream host.
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Exchange is a recorded request and its response.
+type Exchange struct {
+	Method string `json:"method"`
+	// Path is matched when set; exchanges imported from conversation logs
+	// have none and match any path.
+	Path string `json:"path,omitempty"`
+	// Request is the request body: JSON as-is, anything else as a string.
+	Request json.RawMessage `json:"request,omitempty"`
+
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	// Body is the response, unless it was streamed in Chunks. Bodies that are
+	// not UTF-8 are kept in BinaryBody.
+	Body       string  `json:"body,omitempty"`
+	BinaryBody []byte  `json:"binary_body,omitempty"`
+	Chunks     []Chunk `json:"chunks,omitempty"`
+}
+
+// Chunk is a piece of a streamed response as it was read.
+type Chunk struct {
+	// DelayMS is the time since the previous chunk, or since the request for
+	// the first one.
+	DelayMS int64  `json:"delay_ms"`
+	Data    string `json:"data,omitempty"`
+	Binary  []byte `json:"binary,omitempty"`
+}
+
+func newChunk(delayMS int64, data []byte) Chunk {
+	if utf8.Valid(data) {
+		return Chunk{DelayMS: delayMS, Data: string(data)}
+	}
+	return Chunk{DelayMS: delayMS, Binary: bytes.Clone(data)}
+}
+
+func (c Chunk) bytes() []byte {
+	if c.Binary != nil {
+		return c.Binary
+	}
+	return []byte(c.Data)
+}
+
+// SetBody keeps a whole response body.
+func (e *Exchange) SetBody(body []byte) {
+	if utf8.Valid(body) {
+		e.Body, e.BinaryBody = string(body), nil
+	} else {
+		e.Body, e.BinaryBody = "", bytes.Clone(body)
+	}
+}
+
+func (e *Exchange) body() []byte {
+	if e.BinaryBody != nil {
+		return e.BinaryBody
+	}
+	return []byte(e.Body)
+}
+
+// RequestJSON is how a request body is kept in an exchange.
+func RequestJSON(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// requestKey is what requests are matched on: the method and the body, with
+// JSON compared regardless of formatting and key order.
+func requestKey(method string, request json.RawMessage) string {
+	if len(request) == 0 {
+		return method
+	}
+	decoder := json.NewDecoder(bytes.NewReader(request))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return method + " " + string(request)
+	}
+	normalized, _ := json.Marshal(v)
+	return method + " " + string(normalized)
+}
+
+// FileName is the fixture file of an upstream host.
+func FileName(host string) string {
+	return strings.NewReplacer(":", "_", "/", "_").Replace(host) + ".jsonl"
+}
+
+// Append adds an exchange to the fixture file of a host in dir.
+func Append(dir, host string, exchange *Exchange) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, FileName(host)), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the exchanges of a fixture file.
+func Load(path string) ([]*Exchange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var exchanges []*Exchange
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for line := 1; ; line++ {
+		var e Exchange
+		if err := decoder.Decode(&e); err == io.EOF {
+			return exchanges, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: exchange %d: %w", filepath.Base(path), line, err)
+		}
+		exchanges = append(exchanges, &e)
+	}
+}
+
+// requestBody reads a request's body and returns the request with an unread
+// copy of it, leaving the original untouched.
+func requestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	var body []byte
+	var err error
+	if req.GetBody != nil {
+		var copied io.ReadCloser
+		if copied, err = req.GetBody(); err != nil {
+			return nil, nil, err
+		}
+		body, err = io.ReadAll(copied)
+		copied.Close()
+		return body, req, err
+	}
+	body, err = io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	return body, clone, nil
+}
+
+// streamed reports whether responses of a content type are recorded in
+// chunks: server-sent events, JSON lines and AWS event streams.
+func streamed(contentType string) bool {
+	for _, t := range []string{"text/event-stream", "ndjson", "jsonl", "vnd.amazon.eventstream"} {
+		if strings.Contains(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Replayer answers requests from the fixture file of their host in Dir,
+// without a network. A request is answered by the exchanges with the same
+// method, body and (when recorded) path, in turn, starting over after the
+// last. Streamed responses keep their recorded chunk timing unless Timing is
+// off. Requests nothing matches get a 404.
+type Replayer struct {
+	Dir    string
+	Timing bool
+
+	mu    sync.Mutex
+	files map[string]*replayFile
+}
+
+// replayFile is a loaded fixture file, reloaded when it changes.
+type replayFile struct {
+	modTime   time.Time
+	exchanges map[string][]*Exchange
+	next      map[string]int
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, req, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	name := FileName(req.URL.Host)
+	exchange, err := r.match(name, req.Method, req.URL.Path, RequestJSON(body))
+	if err != nil {
+		return nil, err
+	}
+	if exchange == nil {
+		return response(req, http.StatusNotFound, "application/json", errorBody(fmt.Sprintf("no recorded exchange for %s %s in %s", req.Method, req.URL.Path, name))), nil
+	}
+
+	resp := response(req, exchange.Status, exchange.ContentType, exchange.body())
+	if len(exchange.Chunks) > 0 {
+		resp.Body, resp.ContentLength = r.stream(req.Context(), exchange.Chunks), -1
+	}
+	return resp, nil
+}
+
+// match returns the next exchange answering a request, or nil.
+func (r *Replayer) match(name, method, path string, request json.RawMessage) (*Exchange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	file, err := r.load(name)
+	if err != nil || file == nil {
+		return nil, err
+	}
+	key := requestKey(method, request)
+	var candidates []*Exchange
+	for _, e := range file.exchanges[key] {
+		if e.Path == "" || e.Path == path {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	key += " " + path
+	exchange := candidates[file.next[key]%len(candidates)]
+	file.next[key]++
+	return exchange, nil
+}
+
+func (r *Replayer) load(name string) (*replayFile, error) {
+	path := filepath.Join(r.Dir, name)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if file, ok := r.files[name]; ok && file.modTime.Equal(info.ModTime()) {
+		return file, nil
+	}
+	exchanges, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &replayFile{modTime: info.ModTime(), exchanges: map[string][]*Exchange{}, next: map[string]int{}}
+	for _, e := range exchanges {
+		key := requestKey(e.Method, e.Request)
+		file.exchanges[key] = append(file.exchanges[key], e)
+	}
+	if r.files == nil {
+		r.files = map[string]*replayFile{}
+	}
+	r.files[name] = file
+	return file, nil
+}
+
+// stream sends recorded chunks, with their delays when timing is on.
+func (r *Replayer) stream(ctx context.Context, chunks []Chunk) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		for _, chunk := range chunks {
+			if r.Timing && chunk.DelayMS > 0 {
+				timer := time.NewTimer(time.Duration(chunk.DelayMS) * time.Millisecond)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					writer.CloseWithError(ctx.Err())
+					return
+				}
+			}
+			if _, err := writer.Write(chunk.bytes()); err != nil {
+				return
+			}
+		}
+		writer.Close()
+	}()
+	return reader
+}
+
+func errorBody(message string) []byte {
+	body, _ := json.Marshal(map[string]any{"error": map[string]string{"message": message, "type": "replay_miss"}})
+	return body
+}
+
+func response(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
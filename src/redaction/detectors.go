@@ -0,0 +1,120 @@
+package redaction
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorCreditCard = "credit_card"
+	DetectorIBAN       = "iban"
+	DetectorAPIKey     = "api_key"
+)
+
+// Detector finds one kind of sensitive value. Validate, when set, filters out
+// regex matches that are not real values (e.g. card numbers failing Luhn).
+type Detector struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// BuiltinDetectors returns the detectors shipped with the proxy, keyed by name.
+func BuiltinDetectors() map[string]Detector {
+	return map[string]Detector{
+		DetectorEmail: {
+			Name:    DetectorEmail,
+			Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		DetectorPhone: {
+			Name:     DetectorPhone,
+			Pattern:  regexp.MustCompile(`\+?\(?\d{1,4}\)?[\s.\-]?\(?\d{2,4}\)?[\s.\-]?\d{3,4}[\s.\-]?\d{3,4}`),
+			Validate: validPhone,
+		},
+		DetectorCreditCard: {
+			Name:     DetectorCreditCard,
+			Pattern:  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+			Validate: validLuhn,
+		},
+		DetectorIBAN: {
+			Name:     DetectorIBAN,
+			Pattern:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?:[ ]?[A-Z0-9]{4}){2,7}(?:[ ]?[A-Z0-9]{1,4})?\b`),
+			Validate: validIBAN,
+		},
+		DetectorAPIKey: {
+			Name: DetectorAPIKey,
+			Pattern: regexp.MustCompile(`\b(?:sk-(?:proj-|ant-)?[A-Za-z0-9_\-]{20,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_\-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|xox[baprs]-[A-Za-z0-9\-]{10,}|eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+)` +
+				`|(?i:bearer\s+[A-Za-z0-9._\-]{20,})`),
+		},
+	}
+}
+
+func digitsOnly(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// validPhone accepts numbers with a plausible digit count for E.164.
+func validPhone(match string) bool {
+	n := len(digitsOnly(match))
+	return n >= 9 && n <= 15
+}
+
+// validLuhn reports whether the digits in match pass the Luhn checksum.
+func validLuhn(match string) bool {
+	digits := digitsOnly(match)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN reports whether match passes the ISO 13616 mod-97 check.
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	rearranged := iban[4:] + iban[:4]
+	var numeric strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
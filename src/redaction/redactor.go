@@ -0,0 +1,152 @@
+package redaction
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Policy controls what is persisted for a conversation log payload.
+type Policy string
+
+const (
+	// PolicyFull stores payloads verbatim.
+	PolicyFull Policy = "full"
+	// PolicyRedacted stores payloads with detected values masked.
+	PolicyRedacted Policy = "redacted"
+	// PolicyMetadata stores only a size summary instead of the payload.
+	PolicyMetadata Policy = "metadata"
+)
+
+// detectorOrder applies the most specific detectors first so that, for
+// example, card numbers are not partially consumed by the phone detector.
+var detectorOrder = []string{DetectorAPIKey, DetectorEmail, DetectorIBAN, DetectorCreditCard, DetectorPhone}
+
+// ParsePolicy validates a policy name. An empty name yields def.
+func ParsePolicy(name string, def Policy) (Policy, error) {
+	switch Policy(strings.ToLower(strings.TrimSpace(name))) {
+	case "":
+		return def, nil
+	case PolicyFull:
+		return PolicyFull, nil
+	case PolicyRedacted:
+		return PolicyRedacted, nil
+	case PolicyMetadata:
+		return PolicyMetadata, nil
+	default:
+		return "", fmt.Errorf("unknown log policy %q (expected full, redacted or metadata)", name)
+	}
+}
+
+// Counts maps detector names to the number of values they masked.
+type Counts map[string]int
+
+// Redactor masks sensitive values in log payloads.
+type Redactor struct {
+	detectors []Detector
+}
+
+// New builds a Redactor from the enabled built-in detector names and custom
+// rules. Rules use the "name=regex" form; an empty name list enables all
+// built-in detectors.
+func New(enabled []string, rules []string) (*Redactor, error) {
+	builtins := BuiltinDetectors()
+	r := &Redactor{}
+
+	if len(enabled) == 0 {
+		enabled = detectorOrder
+	}
+	wanted := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := builtins[name]; !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q", name)
+		}
+		wanted[name] = true
+	}
+	for _, name := range detectorOrder {
+		if wanted[name] {
+			r.detectors = append(r.detectors, builtins[name])
+		}
+	}
+
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, pattern, ok := strings.Cut(rule, "=")
+		if !ok || name == "" || pattern == "" {
+			return nil, fmt.Errorf("invalid redaction rule %q, expected name=regex", rule)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %q: %w", name, err)
+		}
+		r.detectors = append(r.detectors, Detector{Name: name, Pattern: re})
+	}
+
+	return r, nil
+}
+
+// RedactString masks all detected values in s and records them in counts.
+func (r *Redactor) RedactString(s string, counts Counts) string {
+	for _, d := range r.detectors {
+		s = d.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if d.Validate != nil && !d.Validate(match) {
+				return match
+			}
+			counts[d.Name]++
+			return "[REDACTED:" + strings.ToUpper(d.Name) + "]"
+		})
+	}
+	return s
+}
+
+// RedactPayload masks string values inside a JSON payload. Payloads that are
+// not a single JSON document (such as captured SSE streams) are redacted as
+// plain text.
+func (r *Redactor) RedactPayload(payload []byte, counts Counts) []byte {
+	var data any
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return []byte(r.RedactString(string(payload), counts))
+	}
+
+	redacted, err := json.Marshal(r.redactValue(data, counts))
+	if err != nil {
+		return []byte(r.RedactString(string(payload), counts))
+	}
+	return redacted
+}
+
+func (r *Redactor) redactValue(v any, counts Counts) any {
+	switch val := v.(type) {
+	case string:
+		return r.RedactString(val, counts)
+	case map[string]any:
+		for k, field := range val {
+			val[k] = r.redactValue(field, counts)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = r.redactValue(item, counts)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+// MetadataPayload replaces a payload with a summary that keeps no content.
+func MetadataPayload(payload []byte) []byte {
+	summary, _ := json.Marshal(map[string]any{
+		"log_policy": PolicyMetadata,
+		"size_bytes": len(payload),
+	})
+	return summary
+}
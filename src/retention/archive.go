@@ -0,0 +1,163 @@
+package retention
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gen-ai-proxy/src/awssig"
+	"gen-ai-proxy/src/config"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Store persists archive objects. Keys use "/" separators.
+type Store interface {
+	Put(ctx context.Context, key string, body []byte) error
+}
+
+// ArchivedLog is one line of an archive file.
+type ArchivedLog struct {
+	ID               pgtype.UUID     `json:"id"`
+	UserID           pgtype.UUID     `json:"user_id"`
+	ModelID          pgtype.UUID     `json:"model_id"`
+	ConnectionID     pgtype.UUID     `json:"connection_id"`
+	Type             string          `json:"type"`
+	CreatedAt        time.Time       `json:"created_at"`
+	PromptTokens     int64           `json:"prompt_tokens"`
+	CompletionTokens int64           `json:"completion_tokens"`
+	RequestPayload   json.RawMessage `json:"request_payload"`
+	ResponsePayload  json.RawMessage `json:"response_payload"`
+}
+
+// EncodeArchive renders logs as gzip-compressed JSONL.
+func EncodeArchive(logs []ArchivedLog) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, l := range logs {
+		if !json.Valid(l.RequestPayload) {
+			l.RequestPayload = mustMarshalString(l.RequestPayload)
+		}
+		if !json.Valid(l.ResponsePayload) {
+			l.ResponsePayload = mustMarshalString(l.ResponsePayload)
+		}
+		if err := enc.Encode(l); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mustMarshalString wraps a non-JSON payload (e.g. a captured SSE stream) as a JSON string.
+func mustMarshalString(raw []byte) json.RawMessage {
+	b, _ := json.Marshal(string(raw))
+	return b
+}
+
+// archiveKey builds a date-partitioned object key for an archive batch.
+func archiveKey(prefix, stage string, now time.Time) string {
+	name := fmt.Sprintf("%s/%s/logs-%s-%d.jsonl.gz", now.UTC().Format("2006/01/02"), stage, now.UTC().Format("150405"), now.UnixNano())
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + name
+}
+
+// LocalStore writes archives under a directory on the local filesystem.
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) Put(_ context.Context, key string, body []byte) error {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("create archive directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o640); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// S3Store uploads archives to an S3-compatible bucket using path-style URLs,
+// which works with AWS S3 as well as MinIO and similar servers.
+type S3Store struct {
+	Endpoint    string
+	Bucket      string
+	Region      string
+	Credentials awssig.Credentials
+	Client      *http.Client
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body []byte) error {
+	endpoint, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	endpoint.Path = endpoint.Path + "/" + s.Bucket + "/" + key
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/gzip")
+	awssig.Sign(req, body, s.Credentials, s.Region, "s3", time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload archive: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload archive: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// NewStoreFromConfig builds the archive store selected by ARCHIVE_TARGET.
+// It returns nil when archival is disabled.
+func NewStoreFromConfig(cfg *config.Config) (Store, error) {
+	switch cfg.ArchiveTarget {
+	case "":
+		return nil, nil
+	case "local":
+		return &LocalStore{Dir: cfg.ArchiveDir}, nil
+	case "s3":
+		if cfg.ArchiveS3Bucket == "" {
+			return nil, fmt.Errorf("ARCHIVE_S3_BUCKET is required for the s3 archive target")
+		}
+		return &S3Store{
+			Endpoint: cfg.ArchiveS3Endpoint,
+			Bucket:   cfg.ArchiveS3Bucket,
+			Region:   cfg.ArchiveS3Region,
+			Credentials: awssig.Credentials{
+				AccessKeyID:     cfg.ArchiveS3AccessKey,
+				SecretAccessKey: cfg.ArchiveS3SecretKey,
+			},
+			Client: &http.Client{Timeout: time.Minute},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown ARCHIVE_TARGET %q (expected local or s3)", cfg.ArchiveTarget)
+	}
+}
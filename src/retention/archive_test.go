@@ -0,0 +1,89 @@
+package retention
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gen-ai-proxy/src/awssig"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestS3StorePut(t *testing.T) {
+	creds := awssig.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	var path string
+	var lines []ArchivedLog
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPut {
+			t.Errorf("method %s, want PUT", r.Method)
+		}
+		if err := awssig.Verify(r, body, creds, "eu-central-1", "s3"); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("SignatureDoesNotMatch"))
+			return
+		}
+		path = r.URL.Path
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			var l ArchivedLog
+			if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+				t.Errorf("line %q: %v", scanner.Text(), err)
+			}
+			lines = append(lines, l)
+		}
+	}))
+	defer server.Close()
+
+	logs := []ArchivedLog{
+		{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Type: "llm", PromptTokens: 9, RequestPayload: json.RawMessage(`{"model":"gpt"}`), ResponsePayload: []byte("data: [DONE]\n\n")},
+		{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Type: "embedding", PromptTokens: 2},
+	}
+	body, err := EncodeArchive(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := archiveKey("archive/", "rows", time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC))
+	store := &S3Store{Endpoint: server.URL + "/", Bucket: "logs", Region: "eu-central-1", Credentials: creds}
+	if err := store.Put(context.Background(), key, body); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "/logs/" + key; path != want {
+		t.Errorf("PUT %s, want %s", path, want)
+	}
+	if len(lines) != 2 || lines[0].ID != logs[0].ID || lines[1].PromptTokens != 2 {
+		t.Fatalf("archived %+v", lines)
+	}
+	// A payload that is not JSON, such as a stream, is kept as a string.
+	if string(lines[0].ResponsePayload) != `"data: [DONE]\n\n"` {
+		t.Errorf("response payload %s", lines[0].ResponsePayload)
+	}
+}
+
+func TestS3StorePutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("SignatureDoesNotMatch"))
+	}))
+	defer server.Close()
+
+	store := &S3Store{Endpoint: server.URL, Bucket: "logs", Region: "us-east-1"}
+	if err := store.Put(context.Background(), "a.jsonl.gz", nil); err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("error %v, want the S3 error message", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package retention enforces conversation log retention policies: payloads are
+// dropped after their TTL, old rows are rolled up into daily usage aggregates,
+// and expiring data can be archived to a Store first.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrNoArchiveStore is returned when a policy asks for archival but no store is configured.
+var ErrNoArchiveStore = errors.New("retention policy requests archival but no archive target is configured")
+
+// Result summarizes a single retention pass.
+type Result struct {
+	PayloadsPurged int64 `json:"payloads_purged"`
+	RowsRolledUp   int64 `json:"rows_rolled_up"`
+	Archived       int64 `json:"archived"`
+}
+
+type Worker struct {
+	db        database.Store
+	store     Store
+	prefix    string
+	batchSize int32
+	interval  time.Duration
+}
+
+// NewWorker creates a retention worker. store may be nil when archival is disabled.
+func NewWorker(db database.Store, store Store, prefix string, batchSize int, interval time.Duration) *Worker {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &Worker{
+		db:        db,
+		store:     store,
+		prefix:    prefix,
+		batchSize: int32(batchSize),
+		interval:  interval,
+	}
+}
+
+// Run executes retention passes on the configured interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		res, err := w.RunOnce(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "Retention pass failed", "error", err)
+		} else if res.PayloadsPurged > 0 || res.RowsRolledUp > 0 {
+			slog.InfoContext(ctx, "Retention pass complete", "payloads_purged", res.PayloadsPurged, "rows_rolled_up", res.RowsRolledUp, "archived", res.Archived)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges expired payloads and rolls up expired rows in batches.
+func (w *Worker) RunOnce(ctx context.Context) (Result, error) {
+	var res Result
+
+	for {
+		n, err := w.purgePayloadsBatch(ctx, &res)
+		if err != nil {
+			return res, fmt.Errorf("purge payloads: %w", err)
+		}
+		if n < int(w.batchSize) {
+			break
+		}
+	}
+
+	for {
+		n, err := w.rollupRowsBatch(ctx, &res)
+		if err != nil {
+			return res, fmt.Errorf("roll up rows: %w", err)
+		}
+		if n < int(w.batchSize) {
+			break
+		}
+	}
+
+	return res, nil
+}
+
+func (w *Worker) purgePayloadsBatch(ctx context.Context, res *Result) (int, error) {
+	var batch, archived int
+	var purged int64
+
+	err := w.db.ExecTx(ctx, func(q database.Querier) error {
+		rows, err := q.ListLogsWithExpiredPayloads(ctx, w.batchSize)
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		ids := make([]pgtype.UUID, len(rows))
+		var toArchive []ArchivedLog
+		for i, r := range rows {
+			ids[i] = r.ID
+			if r.Archive {
+				toArchive = append(toArchive, archivedLog(r.ID, r.UserID, r.ModelID, r.ConnectionID, r.Type, r.CreatedAt, r.PromptTokens, r.CompletionTokens, r.RequestPayload, r.ResponsePayload))
+			}
+		}
+
+		if err := w.archive(ctx, "payloads", toArchive); err != nil {
+			return err
+		}
+
+		purged, err = q.PurgeLogPayloads(ctx, ids)
+		batch, archived = len(rows), len(toArchive)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	res.PayloadsPurged += purged
+	res.Archived += int64(archived)
+	return batch, nil
+}
+
+func (w *Worker) rollupRowsBatch(ctx context.Context, res *Result) (int, error) {
+	var batch, archived int
+	var deleted int64
+
+	err := w.db.ExecTx(ctx, func(q database.Querier) error {
+		rows, err := q.ListLogsPastRowTTL(ctx, w.batchSize)
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		ids := make([]pgtype.UUID, len(rows))
+		var toArchive []ArchivedLog
+		for i, r := range rows {
+			ids[i] = r.ID
+			if r.Archive {
+				toArchive = append(toArchive, archivedLog(r.ID, r.UserID, r.ModelID, r.ConnectionID, r.Type, r.CreatedAt, r.PromptTokens, r.CompletionTokens, r.RequestPayload, r.ResponsePayload))
+			}
+		}
+
+		if err := w.archive(ctx, "rows", toArchive); err != nil {
+			return err
+		}
+
+		if err := q.RollupLogs(ctx, ids); err != nil {
+			return err
+		}
+		deleted, err = q.DeleteLogs(ctx, ids)
+		batch, archived = len(rows), len(toArchive)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	res.RowsRolledUp += deleted
+	res.Archived += int64(archived)
+	return batch, nil
+}
+
+// archive uploads logs before the transaction that removes them commits, so
+// a failed commit can only lead to a duplicate archive, never to data loss.
+func (w *Worker) archive(ctx context.Context, stage string, logs []ArchivedLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	if w.store == nil {
+		return ErrNoArchiveStore
+	}
+
+	body, err := EncodeArchive(logs)
+	if err != nil {
+		return fmt.Errorf("encode archive: %w", err)
+	}
+	return w.store.Put(ctx, archiveKey(w.prefix, stage, time.Now()), body)
+}
+
+func archivedLog(id, userID, modelID, connectionID pgtype.UUID, logType string, createdAt pgtype.Timestamptz, promptTokens, completionTokens pgtype.Int8, request, response []byte) ArchivedLog {
+	return ArchivedLog{
+		ID:               id,
+		UserID:           userID,
+		ModelID:          modelID,
+		ConnectionID:     connectionID,
+		Type:             logType,
+		CreatedAt:        createdAt.Time,
+		PromptTokens:     promptTokens.Int64,
+		CompletionTokens: completionTokens.Int64,
+		RequestPayload:   request,
+		ResponsePayload:  response,
+	}
+}
@@ -0,0 +1,371 @@
+// Package routing keeps an in-memory table from proxy model names to the
+// model, connection, provider and decrypted credential that serve them, so
+// proxied requests do not query the database to find their upstream.
+//
+// Every change to providers, connections, models or prompt templates bumps a
+// version in the database. The table reloads when that version moves: immediately on a
+// Postgres notification, and otherwise on the next poll.
+package routing
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gen-ai-proxy/src/database"
+	"gen-ai-proxy/src/encryption"
+	"gen-ai-proxy/src/parampolicy"
+	"gen-ai-proxy/src/prompttemplate"
+	"gen-ai-proxy/src/shadow"
+	"gen-ai-proxy/src/structuredoutput"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/sync/singleflight"
+)
+
+// Channel is the Postgres notification channel the database triggers publish
+// the new routing version on.
+const Channel = "routing_changed"
+
+// listenRetryDelay is how long to wait before reconnecting a failed listener.
+const listenRetryDelay = 5 * time.Second
+
+// missRefreshInterval is the minimum time between refreshes triggered by
+// lookup misses, so requests for unknown models cannot query the database on
+// every call.
+const missRefreshInterval = time.Second
+
+// Route is everything needed to forward a request for one proxy model.
+type Route struct {
+	Model          database.Model
+	Provider       database.Provider
+	ConnectionName string
+	// APIKey is the decrypted credential of the connection.
+	APIKey string
+	// ParamPolicy is the model's parsed parameter policy, nil when it has none.
+	ParamPolicy *parampolicy.Policy
+	// PromptTemplate is the active version of the model's prompt template,
+	// nil when it has none.
+	PromptTemplate *prompttemplate.Template
+	// StructuredOutput is the model's JSON Schema for chat outputs, nil when
+	// it has none.
+	StructuredOutput *structuredoutput.Policy
+	// Shadow is the model's shadow traffic policy, nil when it mirrors
+	// nothing.
+	Shadow *shadow.Policy
+	// ShadowTarget is the route mirrored requests take: the model's route
+	// with the shadow connection, provider model and prices in place of its
+	// own. It is nil when the model mirrors nothing.
+	ShadowTarget *Route
+}
+
+// Snapshot is an immutable copy of the routing table.
+type Snapshot struct {
+	Version  int64
+	LoadedAt time.Time
+	routes   map[[16]byte]map[string]Route
+}
+
+// Routes returns the routes of a user ordered by proxy model name.
+func (s *Snapshot) Routes(userID pgtype.UUID) []Route {
+	routes := make([]Route, 0, len(s.routes[userID.Bytes]))
+	for _, route := range s.routes[userID.Bytes] {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Model.ProxyModelID < routes[j].Model.ProxyModelID })
+	return routes
+}
+
+// Table serves lookups from the current snapshot and swaps in a new one when
+// the routing version changes.
+type Table struct {
+	db            database.Store
+	encryptionKey []byte
+	pollInterval  time.Duration
+
+	current atomic.Pointer[Snapshot]
+	// reload serialises reloads so concurrent refreshes query the database once.
+	reload sync.Mutex
+	// missRefresh shares one database check between concurrent lookup misses.
+	missRefresh singleflight.Group
+	// lastMissRefresh is when a lookup miss last checked the database, in
+	// Unix nanoseconds.
+	lastMissRefresh atomic.Int64
+}
+
+// NewTable loads the routing table. A pollInterval of 0 disables polling,
+// leaving only notifications and lookup misses to trigger reloads.
+func NewTable(ctx context.Context, db database.Store, encryptionKey string, pollInterval time.Duration) (*Table, error) {
+	key, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes long after base64 decoding")
+	}
+
+	t := &Table{db: db, encryptionKey: key, pollInterval: pollInterval}
+	if _, err := t.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load routing table: %w", err)
+	}
+	return t, nil
+}
+
+// Snapshot returns the routing table currently in use.
+func (t *Table) Snapshot() *Snapshot {
+	return t.current.Load()
+}
+
+// Lookup finds the route of a user's proxy model. A miss checks the database
+// for a newer version first, so a model created a moment ago on another
+// replica is found without waiting for the next poll. Misses arriving while
+// a check is in flight wait for it; otherwise misses check at most once per
+// missRefreshInterval, and the others fail without querying.
+func (t *Table) Lookup(ctx context.Context, userID pgtype.UUID, proxyModelID string) (Route, bool) {
+	if route, ok := t.Snapshot().routes[userID.Bytes][proxyModelID]; ok {
+		return route, true
+	}
+	// The check is shared, so it must not end when the caller that started
+	// it goes away.
+	_, err, _ := t.missRefresh.Do("", func() (any, error) {
+		now := time.Now().UnixNano()
+		if now-t.lastMissRefresh.Load() < int64(missRefreshInterval) {
+			return nil, nil
+		}
+		t.lastMissRefresh.Store(now)
+		_, err := t.Refresh(context.WithoutCancel(ctx))
+		return nil, err
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to refresh routing table", "error", err)
+		return Route{}, false
+	}
+	route, ok := t.Snapshot().routes[userID.Bytes][proxyModelID]
+	return route, ok
+}
+
+// Refresh reloads the table if the routing version in the database differs
+// from the loaded one, and reports whether it did.
+func (t *Table) Refresh(ctx context.Context) (bool, error) {
+	t.reload.Lock()
+	defer t.reload.Unlock()
+
+	version, err := t.db.GetRoutingVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	if current := t.Snapshot(); current != nil && current.Version == version {
+		return false, nil
+	}
+
+	// Rows are read outside a transaction, so a change racing this read may be
+	// half applied; it also bumps the version, and the next refresh fixes it.
+	rows, err := t.db.ListRoutes(ctx)
+	if err != nil {
+		return false, err
+	}
+	templates, err := t.loadTemplates(ctx)
+	if err != nil {
+		return false, err
+	}
+	connections, err := t.db.ListRouteConnections(ctx)
+	if err != nil {
+		return false, err
+	}
+	shadowConnections := make(map[[16]byte]database.ListRouteConnectionsRow, len(connections))
+	for _, conn := range connections {
+		shadowConnections[conn.ID.Bytes] = conn
+	}
+
+	snapshot := &Snapshot{Version: version, LoadedAt: time.Now().UTC(), routes: map[[16]byte]map[string]Route{}}
+	credentials := map[string]string{}
+	for _, row := range rows {
+		user := snapshot.routes[row.Model.UserID.Bytes]
+		if user == nil {
+			user = map[string]Route{}
+			snapshot.routes[row.Model.UserID.Bytes] = user
+		}
+		// Rows are ordered by ID, so duplicate names resolve the same way on every replica.
+		if _, ok := user[row.Model.ProxyModelID]; ok {
+			continue
+		}
+
+		policy, err := parampolicy.Parse(row.Model.ParamPolicy)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid parameter policy, model is not routable", "model", row.Model.ProxyModelID, "error", err)
+			continue
+		}
+
+		output, err := structuredoutput.Parse(row.Model.StructuredOutput)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid structured output policy, model is not routable", "model", row.Model.ProxyModelID, "error", err)
+			continue
+		}
+
+		mirror, err := shadow.Parse(row.Model.Shadow)
+		if err != nil {
+			// Mirroring is optional, so the model stays routable without it.
+			slog.ErrorContext(ctx, "Invalid shadow policy, requests are not mirrored", "model", row.Model.ProxyModelID, "error", err)
+		}
+
+		var template *prompttemplate.Template
+		if row.Model.PromptTemplateID.Valid {
+			if template = templates[row.Model.PromptTemplateID.Bytes]; template == nil {
+				slog.ErrorContext(ctx, "Prompt template not found or invalid, model is not routable",
+					"model", row.Model.ProxyModelID, "prompt_template_id", row.Model.PromptTemplateID.String())
+				continue
+			}
+		}
+
+		apiKey, ok := credentials[row.EncryptedApiKey]
+		if !ok {
+			decrypted, err := encryption.Decrypt(t.encryptionKey, row.EncryptedApiKey)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to decrypt connection API key, model is not routable",
+					"model", row.Model.ProxyModelID, "connection_id", row.Model.ConnectionID.String(), "error", err)
+				continue
+			}
+			apiKey = string(decrypted)
+			credentials[row.EncryptedApiKey] = apiKey
+		}
+
+		route := Route{
+			Model:            row.Model,
+			Provider:         row.Provider,
+			ConnectionName:   row.ConnectionName,
+			APIKey:           apiKey,
+			ParamPolicy:      policy,
+			PromptTemplate:   template,
+			StructuredOutput: output,
+		}
+		if mirror != nil {
+			route.Shadow, route.ShadowTarget = t.shadowTarget(ctx, route, mirror, shadowConnections, credentials)
+		}
+		user[row.Model.ProxyModelID] = route
+	}
+
+	t.current.Store(snapshot)
+	slog.InfoContext(ctx, "Routing table loaded", "version", version, "models", len(rows))
+	return true, nil
+}
+
+// shadowTarget builds the route requests mirrored by policy take from route.
+// A shadow connection that is missing, owned by another user or whose
+// credential does not decrypt leaves the model unmirrored rather than
+// unroutable, so both returns are nil then.
+func (t *Table) shadowTarget(ctx context.Context, route Route, policy *shadow.Policy, connections map[[16]byte]database.ListRouteConnectionsRow, credentials map[string]string) (*shadow.Policy, *Route) {
+	conn, ok := connections[policy.ConnectionID.Bytes]
+	if !ok || conn.UserID != route.Model.UserID {
+		slog.ErrorContext(ctx, "Shadow connection not found, requests are not mirrored",
+			"model", route.Model.ProxyModelID, "connection_id", policy.ConnectionID.String())
+		return nil, nil
+	}
+	apiKey, ok := credentials[conn.EncryptedApiKey]
+	if !ok {
+		decrypted, err := encryption.Decrypt(t.encryptionKey, conn.EncryptedApiKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to decrypt shadow connection API key, requests are not mirrored",
+				"model", route.Model.ProxyModelID, "connection_id", policy.ConnectionID.String(), "error", err)
+			return nil, nil
+		}
+		apiKey = string(decrypted)
+		credentials[conn.EncryptedApiKey] = apiKey
+	}
+
+	target := route
+	target.Model.ConnectionID = policy.ConnectionID
+	target.Model.ProviderModelID = policy.ProviderModelID
+	target.Model.PriceInput = price(policy.PriceInput)
+	target.Model.PriceOutput = price(policy.PriceOutput)
+	target.Provider = conn.Provider
+	target.ConnectionName = conn.ConnectionName
+	target.APIKey = apiKey
+	return policy, &target
+}
+
+// price converts a shadow policy price to the type of model prices.
+func price(f float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	if err := n.ScanScientific(strconv.FormatFloat(f, 'f', -1, 64)); err != nil {
+		return pgtype.Numeric{}
+	}
+	return n
+}
+
+// loadTemplates returns the active version of every prompt template by ID.
+// Templates that fail to decode are left out, so their models are not routed
+// rather than routed without their prompt.
+func (t *Table) loadTemplates(ctx context.Context) (map[[16]byte]*prompttemplate.Template, error) {
+	rows, err := t.db.ListActivePromptTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[[16]byte]*prompttemplate.Template, len(rows))
+	for _, row := range rows {
+		v := row.PromptTemplateVersion
+		content, err := prompttemplate.Decode(v.Mode, v.SystemPrompt, v.Messages, v.Variables)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid prompt template version", "prompt_template", row.Name, "version", v.Version, "error", err)
+			continue
+		}
+		templates[v.TemplateID.Bytes] = &prompttemplate.Template{
+			ID:      v.TemplateID.Bytes,
+			Name:    row.Name,
+			Version: v.Version,
+			Content: content,
+		}
+	}
+	return templates, nil
+}
+
+// Run keeps the table current until ctx is cancelled: it listens for
+// notifications when the store supports them and polls the version otherwise,
+// or as a fallback for notifications missed while reconnecting.
+func (t *Table) Run(ctx context.Context) {
+	if listener, ok := t.db.(database.Listener); ok {
+		go t.listen(ctx, listener)
+	}
+	if t.pollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.refresh(ctx, "poll")
+		}
+	}
+}
+
+func (t *Table) listen(ctx context.Context, listener database.Listener) {
+	for {
+		err := listener.Listen(ctx, Channel, func(string) { t.refresh(ctx, "notification") })
+		if ctx.Err() != nil {
+			return
+		}
+		slog.WarnContext(ctx, "Routing change listener disconnected, reconnecting", "error", err, "delay", listenRetryDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+		// Changes made while disconnected were not notified.
+		t.refresh(ctx, "reconnect")
+	}
+}
+
+func (t *Table) refresh(ctx context.Context, trigger string) {
+	if _, err := t.Refresh(ctx); err != nil && ctx.Err() == nil {
+		slog.ErrorContext(ctx, "Failed to refresh routing table", "trigger", trigger, "error", err)
+	}
+}
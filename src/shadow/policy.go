@@ -0,0 +1,107 @@
+// Package shadow configures shadow traffic: a percentage of a model's live
+// requests is mirrored to a candidate provider model on one of the user's
+// connections, after the client has been answered, so the candidate can be
+// compared on real traffic before the model's upstream is switched to it.
+// Mirrored answers are logged and otherwise discarded.
+package shadow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Policy is the shadow traffic configuration of a model. The candidate is
+// not a proxy model, so clients cannot call it directly.
+type Policy struct {
+	// ConnectionID is the connection requests are mirrored through.
+	ConnectionID pgtype.UUID `json:"connection_id"`
+	// ProviderModelID is the upstream model mirrored requests are sent to.
+	ProviderModelID string `json:"provider_model_id"`
+	// PriceInput and PriceOutput give the cost of mirrored requests per
+	// prompt and completion token.
+	PriceInput  float64 `json:"price_input"`
+	PriceOutput float64 `json:"price_output"`
+	// Percent is the share of requests mirrored, above 0 and up to 100.
+	Percent float64 `json:"percent"`
+}
+
+// Parse decodes and validates a stored policy. Empty input is no policy.
+func Parse(data []byte) (*Policy, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid shadow policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Decode is Parse for display and comparison: an invalid stored policy
+// decodes as none.
+func Decode(data []byte) *Policy {
+	p, err := Parse(data)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// Marshal encodes the policy for storage; a nil policy is stored as NULL.
+func (p *Policy) Marshal() (json.RawMessage, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Equal reports whether two policies are the same.
+func Equal(a, b *Policy) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// Validate checks the policy of a model served by providerModelID on
+// connectionID, which cannot mirror its requests to its own upstream.
+func (p *Policy) Validate(connectionID pgtype.UUID, providerModelID string) error {
+	if p == nil {
+		return nil
+	}
+	if err := p.validate(); err != nil {
+		return err
+	}
+	if p.ConnectionID == connectionID && p.ProviderModelID == providerModelID {
+		return errors.New("shadow: a model cannot mirror its requests to its own upstream")
+	}
+	return nil
+}
+
+func (p *Policy) validate() error {
+	if !p.ConnectionID.Valid {
+		return errors.New("shadow: connection_id is required")
+	}
+	if p.ProviderModelID == "" {
+		return errors.New("shadow: provider_model_id is required")
+	}
+	if p.PriceInput < 0 || p.PriceOutput < 0 {
+		return errors.New("shadow: prices cannot be negative")
+	}
+	if p.Percent <= 0 || p.Percent > 100 {
+		return errors.New("shadow: percent must be above 0 and at most 100")
+	}
+	return nil
+}
+
+// Sample reports whether a request should be mirrored.
+func (p *Policy) Sample() bool {
+	return p != nil && rand.Float64()*100 < p.Percent
+}
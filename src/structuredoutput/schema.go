@@ -0,0 +1,205 @@
+// Package structuredoutput checks chat completion outputs against a JSON
+// Schema. The schema comes from the request (OpenAI response_format, Ollama
+// format) or from the model's policy. It is passed to providers that support
+// it natively, but some upstreams ignore it, so the final output is validated
+// as well and invalid outputs can be sent back to the model with the errors.
+package structuredoutput
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// DefaultName names schemas sent to providers that require a name.
+const DefaultName = "output"
+
+// MaxRetriesLimit bounds the retries a policy can configure, each of which is
+// a full upstream call.
+const MaxRetriesLimit = 5
+
+// Policy is the structured output configuration of a model.
+type Policy struct {
+	// Name identifies the schema to providers; defaults to "output".
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Schema is the JSON Schema outputs must match.
+	Schema map[string]any `json:"schema" yaml:"schema"`
+	// MaxRetries is how many times an invalid output is sent back to the model
+	// before the request fails; nil uses the server default.
+	MaxRetries *int `json:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+
+	compiled *Schema
+}
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	Name string
+	// Document is the schema as sent to providers.
+	Document map[string]any
+
+	schema *jsonschema.Schema
+}
+
+// ValidationError lists why an output does not match its schema.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Errors, "; ")
+}
+
+// Parse decodes and validates a stored policy. Empty input is no policy.
+func Parse(data []byte) (*Policy, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid structured output policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Decode is Parse for display and comparison: an invalid stored policy
+// decodes as none.
+func Decode(data []byte) *Policy {
+	p, err := Parse(data)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// Marshal encodes the policy for storage; a nil policy is stored as NULL.
+func (p *Policy) Marshal() (json.RawMessage, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Equal reports whether two policies are the same, however they were decoded.
+func Equal(a, b *Policy) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	var na, nb any
+	if json.Unmarshal(ja, &na) != nil || json.Unmarshal(jb, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+// Validate compiles the schema and checks the number of retries.
+func (p *Policy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if len(p.Schema) == 0 {
+		return errors.New("structured_output: schema is required")
+	}
+	if p.MaxRetries != nil && (*p.MaxRetries < 0 || *p.MaxRetries > MaxRetriesLimit) {
+		return fmt.Errorf("structured_output: max_retries must be between 0 and %d", MaxRetriesLimit)
+	}
+	compiled, err := Compile(p.Name, p.Schema)
+	if err != nil {
+		return fmt.Errorf("structured_output: %w", err)
+	}
+	p.compiled = compiled
+	return nil
+}
+
+// Compiled returns the policy's schema. The policy must have been validated.
+func (p *Policy) Compiled() *Schema {
+	return p.compiled
+}
+
+// Compile compiles a JSON Schema document. References to other documents are
+// refused, so a schema cannot make the proxy read files or fetch URLs.
+func Compile(name string, document map[string]any) (*Schema, error) {
+	if name == "" {
+		name = DefaultName
+	}
+	// Round-trip through JSON so numbers have the type the compiler expects,
+	// however the document was decoded.
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+
+	const location = "mem:///schema.json"
+	c := jsonschema.NewCompiler()
+	c.UseLoader(noLoader{})
+	if err := c.AddResource(location, doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	compiled, err := c.Compile(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	return &Schema{Name: name, Document: document, schema: compiled}, nil
+}
+
+type noLoader struct{}
+
+func (noLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("references to other schemas are not supported: %s", url)
+}
+
+// Validate checks that output is a single JSON value matching the schema. The
+// error is a *ValidationError.
+func (s *Schema) Validate(output string) error {
+	value, err := jsonschema.UnmarshalJSON(strings.NewReader(output))
+	if err != nil {
+		return &ValidationError{Errors: []string{"output is not valid JSON: " + err.Error()}}
+	}
+	err = s.schema.Validate(value)
+	if err == nil {
+		return nil
+	}
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return &ValidationError{Errors: []string{err.Error()}}
+	}
+	var messages []string
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		messages = append(messages, fmt.Sprintf("at %s: %s", location, unit.Error))
+	}
+	if len(messages) == 0 {
+		messages = []string{verr.Error()}
+	}
+	return &ValidationError{Errors: messages}
+}
+
+// Feedback is the message sent back to the model after an invalid output. It
+// repeats the schema for upstreams that ignored it.
+func (s *Schema) Feedback(err error) string {
+	schema, _ := json.Marshal(s.Document)
+	return fmt.Sprintf("Your previous reply does not match the required JSON Schema: %s\n"+
+		"Reply again with only a JSON value that matches this schema, without any other text:\n%s", err, schema)
+}
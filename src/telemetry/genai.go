@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GenAI operation names used by the proxy endpoints.
+const (
+	OperationChat       = "chat"
+	OperationEmbeddings = "embeddings"
+)
+
+// Proxy-specific attributes linking a span to the configuration that served it.
+const (
+	ProxyModelKey   = attribute.Key("gen_ai_proxy.model")
+	ModelIDKey      = attribute.Key("gen_ai_proxy.model.id")
+	ConnectionIDKey = attribute.Key("gen_ai_proxy.connection.id")
+	ProviderIDKey   = attribute.Key("gen_ai_proxy.provider.id")
+)
+
+// StartModelCall starts a client span for one call to an upstream model,
+// named "<operation> <model>" as the GenAI conventions recommend. system is
+// the provider type (openai, ollama, ...) and model the provider's model ID.
+func StartModelCall(ctx context.Context, operation, system, model string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append([]attribute.KeyValue{
+		semconv.GenAIOperationNameKey.String(operation),
+		semconv.GenAISystemKey.String(system),
+		semconv.GenAIRequestModelKey.String(model),
+	}, attrs...)
+	return Tracer().Start(ctx, operation+" "+model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// SetResponse records the model that answered and its token usage.
+func SetResponse(span trace.Span, model string, inputTokens, outputTokens int64) {
+	if model != "" {
+		span.SetAttributes(semconv.GenAIResponseModelKey.String(model))
+	}
+	span.SetAttributes(
+		semconv.GenAIUsageInputTokensKey.Int64(inputTokens),
+		semconv.GenAIUsageOutputTokensKey.Int64(outputTokens),
+	)
+}
+
+// SetHTTPStatus marks the span as failed when the upstream answered with an error.
+func SetHTTPStatus(span trace.Span, status int) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= 400 {
+		span.SetStatus(codes.Error, "upstream returned an error status")
+	}
+}
+
+// RecordError records err on span and marks it as failed.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// SQLSpanName names database spans after the sqlc query ("-- name: GetModel
+// :one") instead of the full statement, keeping span names low-cardinality.
+func SQLSpanName(stmt string) string {
+	if rest, ok := strings.CutPrefix(strings.TrimSpace(stmt), "-- name: "); ok {
+		if name, _, ok := strings.Cut(rest, " "); ok {
+			return name
+		}
+	}
+	if verb, _, _ := strings.Cut(strings.TrimSpace(stmt), " "); verb != "" {
+		return strings.ToUpper(verb)
+	}
+	return "query"
+}
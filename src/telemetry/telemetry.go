@@ -0,0 +1,122 @@
+// Package telemetry wires OpenTelemetry tracing through the proxy: the
+// tracer provider and OTLP exporter, W3C trace context propagation, the
+// instrumented upstream HTTP client, and GenAI semantic-convention
+// attributes for proxied model calls.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gen-ai-proxy/src/config"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies spans created by the proxy itself.
+const InstrumentationName = "gen-ai-proxy"
+
+// Setup installs the global propagator and, when tracing is enabled, a tracer
+// provider exporting over OTLP. The returned function flushes and stops the
+// exporter; it is safe to call when tracing is disabled.
+func Setup(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	if !cfg.TracingEnabled {
+		// Propagate trace context even when we do not export, so a caller's
+		// trace continues through to the upstream provider.
+		setPropagator()
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return SetupWithExporter(exporter, cfg.OTelServiceName, cfg.TracingSampleRatio)
+}
+
+// SetupWithExporter installs a tracer provider exporting to exporter as the
+// global one, and the global propagator. Setup uses it for the OTLP exporter;
+// tests can pass an in-memory exporter from
+// go.opentelemetry.io/otel/sdk/trace/tracetest. The returned function flushes
+// and stops the exporter.
+func SetupWithExporter(exporter sdktrace.SpanExporter, serviceName string, sampleRatio float64) (func(context.Context) error, error) {
+	tp, err := NewTracerProvider(exporter, serviceName, sampleRatio)
+	if err != nil {
+		return nil, err
+	}
+	setPropagator()
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// setPropagator installs W3C trace context and baggage propagation as the
+// global propagator.
+func setPropagator() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// NewTracerProvider builds a batching tracer provider for exporter. Call
+// ForceFlush before reading spans recorded by an in-memory exporter.
+func NewTracerProvider(exporter sdktrace.SpanExporter, serviceName string, sampleRatio float64) (*sdktrace.TracerProvider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	), nil
+}
+
+// Tracer returns the proxy's tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// NewHTTPClient returns a client whose requests get a client span and carry
+// the W3C traceparent header to the upstream provider.
+func NewHTTPClient() *http.Client {
+	return &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+}
+
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.OTLPProtocol)) {
+	case "", "http/protobuf", "http":
+		var opts []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case "grpc":
+		var opts []otlptracegrpc.Option
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q (expected http/protobuf or grpc)", cfg.OTLPProtocol)
+	}
+}
@@ -96,6 +96,8 @@ export const handleCreateProvider = async (event) => {
     const name = document.getElementById('provider_name').value;
     const type = document.getElementById('provider_type').value;
     const base_url = document.getElementById('provider_base_url').value;
+    const api_version = document.getElementById('provider_api_version').value;
+    const region = document.getElementById('provider_region').value;
     const token = localStorage.getItem('jwt_token');
 
     try {
@@ -105,7 +107,7 @@ export const handleCreateProvider = async (event) => {
                 'Content-Type': 'application/json',
                 'Authorization': `Bearer ${token}`
             },
-            body: JSON.stringify({ name, type, base_url })
+            body: JSON.stringify({ name, type, base_url, api_version, region })
         });
 
         if (response.ok) {
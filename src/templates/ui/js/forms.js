@@ -53,6 +53,10 @@ export const createProviderFormHtml = `
                 <option value="">Select a type</option>
                 <option value="ollama">Ollama</option>
                 <option value="openai">OpenAI (compatible)</option>
+                <option value="gemini">Google Gemini</option>
+                <option value="azure-openai">Azure OpenAI</option>
+                <option value="bedrock">AWS Bedrock</option>
+                <option value="mock">Mock (offline testing)</option>
             </select>
         </div>
         <div class="mb-4">
@@ -60,6 +64,18 @@ export const createProviderFormHtml = `
             <input type="url" id="provider_base_url" name="base_url"
                    class="mt-1 block w-full px-3 py-2 bg-gray-800 border border-gray-600 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm">
         </div>
+        <div class="mb-4">
+            <label for="provider_api_version" class="block text-sm font-medium text-gray-300">API Version</label>
+            <input type="text" id="provider_api_version" name="api_version" placeholder="2024-10-21"
+                   class="mt-1 block w-full px-3 py-2 bg-gray-800 border border-gray-600 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm">
+            <p class="mt-2 text-xs text-gray-400">Required for Azure OpenAI.</p>
+        </div>
+        <div class="mb-4">
+            <label for="provider_region" class="block text-sm font-medium text-gray-300">Region</label>
+            <input type="text" id="provider_region" name="region" placeholder="eu-central-1"
+                   class="mt-1 block w-full px-3 py-2 bg-gray-800 border border-gray-600 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm">
+            <p class="mt-2 text-xs text-gray-400">Required for AWS Bedrock.</p>
+        </div>
         <button type="submit" class="px-4 py-2 bg-indigo-600 text-white font-semibold rounded-md shadow-sm hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500">
             Create Provider
         </button>
@@ -0,0 +1,105 @@
+// Package workerpool holds what the batch and evaluation workers share: a
+// pool of goroutines executing requests claimed from the database, periodic
+// maintenance, and the retry policy of failed requests.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// RequestTimeout bounds a single execution; requests running for twice
+	// as long belong to a dead replica and are queued again.
+	RequestTimeout = 10 * time.Minute
+	idleDelay      = time.Second
+	maintenance    = 30 * time.Second
+	retryBase      = 5 * time.Second
+	retryMax       = 5 * time.Minute
+)
+
+// Pool runs RunOnce on Workers goroutines, and Maintain periodically.
+// Several replicas can run pools against the same database.
+type Pool struct {
+	// Name starts the pool's log messages, such as "Batch".
+	Name    string
+	Workers int
+	// RunOnce claims and executes one request. It reports false when there
+	// was nothing to run.
+	RunOnce  func(ctx context.Context) (bool, error)
+	Maintain func(ctx context.Context) error
+}
+
+// Run executes requests and maintenance until ctx is cancelled.
+func (p Pool) Run(ctx context.Context) {
+	for range max(p.Workers, 1) {
+		go p.loop(ctx)
+	}
+
+	ticker := time.NewTicker(maintenance)
+	defer ticker.Stop()
+	for {
+		if err := p.Maintain(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, p.Name+" maintenance failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p Pool) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := p.RunOnce(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, p.Name+" request failed", "error", err)
+		}
+		if ran && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(idleDelay):
+		}
+	}
+}
+
+// Release hands a claimed request back without counting the attempt. It
+// does not use the worker's context, which is cancelled on shutdown; a
+// failure is logged with the request ID under idKey.
+func Release(release func(context.Context, pgtype.UUID) error, id pgtype.UUID, idKey string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := release(ctx, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to release claimed request", idKey, id, "error", err)
+	}
+}
+
+// StaleBefore is the claim time before which a running request was
+// abandoned by a dead replica.
+func StaleBefore() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().Add(-2 * RequestTimeout), Valid: true}
+}
+
+// Retry reports whether a request is attempted again after its attempts-th
+// attempt answered statusCode, or failed with err when it could not be sent
+// at all. Errors, 429 and 5xx responses are retried up to maxAttempts.
+func Retry(statusCode int, err error, attempts, maxAttempts int32) bool {
+	retryable := err != nil || statusCode == 429 || statusCode >= 500
+	return retryable && attempts < maxAttempts
+}
+
+// Backoff is the delay before the attempt following the given one.
+func Backoff(attempt int32) time.Duration {
+	d := retryBase
+	for i := int32(1); i < attempt && d < retryMax; i++ {
+		d *= 2
+	}
+	return min(d, retryMax)
+}
@@ -0,0 +1,36 @@
+package workerpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		err        error
+		attempts   int32
+		want       bool
+	}{
+		{statusCode: 200, attempts: 1, want: false},
+		{statusCode: 400, attempts: 1, want: false},
+		{statusCode: 429, attempts: 1, want: true},
+		{statusCode: 503, attempts: 2, want: true},
+		{statusCode: 503, attempts: 3, want: false},
+		{err: errors.New("connection refused"), attempts: 1, want: true},
+	}
+	for _, tt := range tests {
+		if got := Retry(tt.statusCode, tt.err, tt.attempts, 3); got != tt.want {
+			t.Errorf("Retry(%d, %v, %d, 3) = %v, want %v", tt.statusCode, tt.err, tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, want := range map[int32]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 4: 40 * time.Second, 10: 5 * time.Minute} {
+		if got := Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}